@@ -23,6 +23,8 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"runtime"
+	"syscall"
+	"time"
 
 	"github.com/urfave/cli"
 	"mosn.io/mosn/pkg/admin/store"
@@ -31,6 +33,8 @@ import (
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/metrics"
 	"mosn.io/mosn/pkg/mosn"
+	"mosn.io/mosn/pkg/server"
+	"mosn.io/mosn/pkg/server/keeper"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -98,21 +102,130 @@ var (
 
 	cmdStop = cli.Command{
 		Name:  "stop",
-		Usage: "stop mosn proxy",
+		Usage: "stop mosn proxy gracefully",
+		Flags: controlFlags,
 		Action: func(c *cli.Context) error {
+			pid, err := keeper.RunningPid(getPidFile(c))
+			if err != nil {
+				return cli.NewExitError(fmt.Sprintf("[mosn] [stop] %v", err), exitCodeFailed)
+			}
+			if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+				return cli.NewExitError(fmt.Sprintf("[mosn] [stop] send signal to process %d failed: %v", pid, err), exitCodeFailed)
+			}
+			fmt.Printf("[mosn] [stop] process %d is stopping, waiting for connections drained\n", pid)
+
+			timeout := c.Duration("timeout")
+			if !keeper.WaitProcessExit(pid, timeout) {
+				return cli.NewExitError(fmt.Sprintf("[mosn] [stop] process %d is still running after %s", pid, timeout), exitCodeTimeout)
+			}
+			fmt.Printf("[mosn] [stop] process %d stopped\n", pid)
 			return nil
 		},
 	}
 
 	cmdReload = cli.Command{
 		Name:  "reload",
-		Usage: "reconfiguration",
+		Usage: "reconfiguration, start a new mosn and transfer the listeners and connections to it",
+		Flags: controlFlags,
 		Action: func(c *cli.Context) error {
+			pidFile := getPidFile(c)
+			pid, err := keeper.RunningPid(pidFile)
+			if err != nil {
+				return cli.NewExitError(fmt.Sprintf("[mosn] [reload] %v", err), exitCodeFailed)
+			}
+			if err := syscall.Kill(pid, syscall.SIGHUP); err != nil {
+				return cli.NewExitError(fmt.Sprintf("[mosn] [reload] send signal to process %d failed: %v", pid, err), exitCodeFailed)
+			}
+			fmt.Printf("[mosn] [reload] process %d is reloading\n", pid)
+
+			timeout := c.Duration("timeout")
+			newPid, err := keeper.WaitReload(pidFile, pid, timeout)
+			if err != nil {
+				code := exitCodeTimeout
+				// the old mosn keeps running if the new one failed, take the pid file back
+				if err != keeper.ErrWaitTimeout {
+					code = exitCodeFailed
+					if restoreErr := keeper.RestorePidFile(pidFile, pid); restoreErr != nil {
+						return cli.NewExitError(fmt.Sprintf("[mosn] [reload] reload process %d failed: %v, restore pid file %s failed: %v",
+							pid, err, pidFile, restoreErr), code)
+					}
+				}
+				return cli.NewExitError(fmt.Sprintf("[mosn] [reload] reload process %d failed: %v", pid, err), code)
+			}
+			fmt.Printf("[mosn] [reload] reload success, process %d is replaced by process %d\n", pid, newPid)
 			return nil
 		},
 	}
+
+	cmdValidate = cli.Command{
+		Name:  "validate",
+		Usage: "validate the configuration without starting mosn proxy",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "config, c",
+				Usage:  "Validate configuration from `FILE`",
+				EnvVar: "MOSN_CONFIG",
+				Value:  "configs/mosn_config.json",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			configPath := c.String("config")
+			// only the problems are reported
+			log.StartLogger.SetLogLevel(log.ERROR)
+			log.DefaultLogger.SetLogLevel(log.ERROR)
+			problems := 0
+			for _, err := range config.Validate(configPath) {
+				fmt.Printf("[mosn] [validate] %v\n", err)
+				if !err.Warning {
+					problems++
+				}
+			}
+			if problems == 0 {
+				fmt.Printf("[mosn] [validate] configuration %s is ok\n", configPath)
+				return nil
+			}
+			return cli.NewExitError(fmt.Sprintf("[mosn] [validate] configuration %s has %d problems", configPath, problems), exitCodeFailed)
+		},
+	}
+
+	controlFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "config, c",
+			Usage:  "Load configuration from `FILE`, the running mosn is found by the pid file in it",
+			EnvVar: "MOSN_CONFIG",
+			Value:  "configs/mosn_config.json",
+		}, cli.StringFlag{
+			Name:  "pid, p",
+			Usage: "Read the running mosn pid from `FILE`, overwrite the pid file in configuration",
+		}, cli.DurationFlag{
+			Name:  "timeout, t",
+			Usage: "Max time to wait for the running mosn",
+			// the running mosn waits two graceful timeout and two read timeout for connections
+			Value: 2*server.GracefulTimeout + 2*types.DefaultConnReadTimeout + 10*time.Second,
+		},
+	}
+)
+
+const (
+	exitCodeFailed  = 1
+	exitCodeTimeout = 2
 )
 
+// getPidFile returns the pid file of the running mosn, it is the same as the
+// one keeper.SetPid written
+func getPidFile(c *cli.Context) string {
+	if pid := c.String("pid"); pid != "" {
+		return pid
+	}
+	configPath := c.String("config")
+	conf := config.Load(configPath)
+	if conf.Pid != "" {
+		return conf.Pid
+	}
+	types.InitDefaultPath(configPath)
+	return types.MosnPidDefaultFileName
+}
+
 func initXdsFlags(serviceCluster, serviceNode string) {
 	info := types.GetGlobalXdsInfo()
 	info.ServiceCluster = serviceCluster
@@ -26,15 +26,22 @@ import (
 	"github.com/urfave/cli"
 	_ "mosn.io/mosn/pkg/buffer"
 	_ "mosn.io/mosn/pkg/filter/network/proxy"
+	_ "mosn.io/mosn/pkg/filter/network/redisproxy"
 	_ "mosn.io/mosn/pkg/filter/network/tcpproxy"
+	_ "mosn.io/mosn/pkg/filter/network/udpproxy"
 	_ "mosn.io/mosn/pkg/filter/stream/faultinject"
+	_ "mosn.io/mosn/pkg/filter/stream/grpcjson"
+	_ "mosn.io/mosn/pkg/filter/stream/grpcweb"
 	_ "mosn.io/mosn/pkg/filter/stream/healthcheck/sofarpc"
 	_ "mosn.io/mosn/pkg/filter/stream/mixer"
 	_ "mosn.io/mosn/pkg/filter/stream/payloadlimit"
 	_ "mosn.io/mosn/pkg/metrics/sink"
+	_ "mosn.io/mosn/pkg/metrics/sink/otlp"
 	_ "mosn.io/mosn/pkg/metrics/sink/prometheus"
+	_ "mosn.io/mosn/pkg/metrics/sink/statsd"
 	_ "mosn.io/mosn/pkg/network"
 	_ "mosn.io/mosn/pkg/protocol"
+	_ "mosn.io/mosn/pkg/protocol/bridge"
 	_ "mosn.io/mosn/pkg/protocol/http/conv"
 	_ "mosn.io/mosn/pkg/protocol/http2/conv"
 	_ "mosn.io/mosn/pkg/protocol/rpc/sofarpc/codec"
@@ -48,9 +55,11 @@ import (
 	_ "mosn.io/mosn/pkg/upstream/healthcheck"
 	_ "mosn.io/mosn/pkg/xds"
 
+	_ "mosn.io/mosn/pkg/trace/opentelemetry"
 	_ "mosn.io/mosn/pkg/trace/sofa/http"
 	_ "mosn.io/mosn/pkg/trace/sofa/rpc"
 	_ "mosn.io/mosn/pkg/trace/sofa/rpc/ext"
+	_ "mosn.io/mosn/pkg/trace/zipkin"
 )
 
 var Version = "0.4.0"
@@ -68,6 +77,7 @@ func main() {
 		cmdStart,
 		cmdStop,
 		cmdReload,
+		cmdValidate,
 	}
 
 	//action
@@ -28,6 +28,7 @@ import (
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/metrics"
 	"mosn.io/mosn/pkg/metrics/sink/console"
+	"mosn.io/mosn/pkg/runtime"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -191,3 +192,52 @@ func getState(w http.ResponseWriter, r *http.Request) {
 	msg := fmt.Sprintf("pid=%d&state=%d\n", pid, state)
 	fmt.Fprint(w, msg)
 }
+
+// returns the runtime values of each layer
+func getRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: invalid method: %s", "get runtime", r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	buf, err := json.MarshalIndent(runtime.GetDump(), "", "  ")
+	if err != nil {
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: %v", "get runtime", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		msg := fmt.Sprintf(errMsgFmt, "internal error")
+		fmt.Fprint(w, msg)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf)
+}
+
+// post data:
+// {"key": "value"}, an empty value removes the override of the key
+func modifyRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: invalid method: %s", "modify runtime", r.Method)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, error: read body failed, %v", "modify runtime", err)
+		w.WriteHeader(http.StatusBadRequest)
+		msg := fmt.Sprintf(errMsgFmt, "read body error")
+		fmt.Fprint(w, msg)
+		return
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(body, &values); err != nil || len(values) == 0 {
+		log.DefaultLogger.Alertf(types.ErrorKeyAdmin, "api: %s, modify runtime failed with bad request data: %s", "modify runtime", string(body))
+		w.WriteHeader(http.StatusBadRequest)
+		msg := fmt.Sprintf(errMsgFmt, "modify runtime failed")
+		fmt.Fprint(w, msg)
+		return
+	}
+	runtime.SetOverrides(values)
+	log.DefaultLogger.Infof("[admin api] [modify runtime] modify runtime: %v", values)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "modify runtime success\n")
+}
@@ -36,6 +36,7 @@ import (
 	"mosn.io/mosn/pkg/admin/store"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/runtime"
 )
 
 func getEffectiveConfig(port uint32) (string, error) {
@@ -347,6 +348,53 @@ func TestGetState(t *testing.T) {
 	}
 }
 
+func TestRuntime(t *testing.T) {
+	time.Sleep(time.Second)
+	server := Server{}
+	config := &mockMOSNConfig{
+		Name: "mock",
+		Port: 8889,
+	}
+	server.Start(config)
+	store.StartService(nil)
+	defer store.StopService()
+
+	time.Sleep(time.Second) //wait server start
+
+	modify := func(data string) int {
+		resp, err := http.Post(fmt.Sprintf("http://localhost:%d/api/v1/runtime_modify", config.Port), "application/json", strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := modify(`{"test.admin.enabled": "true"}`); code != http.StatusOK {
+		t.Fatalf("modify runtime failed, status: %d", code)
+	}
+	defer runtime.SetOverrides(map[string]string{"test.admin.enabled": ""})
+	if !runtime.GetBool("test.admin.enabled", false) {
+		t.Error("runtime is not modified")
+	}
+	if code := modify(`invalid`); code != http.StatusBadRequest {
+		t.Errorf("modify runtime with invalid data, expected bad request, but got %d", code)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/api/v1/runtime", config.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	dump := runtime.Dump{}
+	if err := rawjson.NewDecoder(resp.Body).Decode(&dump); err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := dump.Entry("test.admin.enabled")
+	if !ok || entry.FinalValue != "true" || entry.LayerValues[1] != "true" {
+		t.Errorf("unexpected runtime dump: %+v", dump)
+	}
+}
+
 func TestRegisterNewAPI(t *testing.T) {
 	// register api before start
 	newAPI := func(w http.ResponseWriter, r *http.Request) {
@@ -30,7 +30,7 @@ type HealthCheckConfig struct {
 }
 
 type HostConfig struct {
-	Address        string          `json:"address,omitempty"`
+	Address        string          `json:"address,omitempty"` // ip:port, or unix:///path and unix://@name for unix domain socket
 	Hostname       string          `json:"hostname,omitempty"`
 	Weight         uint32          `json:"weight,omitempty"`
 	MetaDataConfig *MetadataConfig `json:"metadata,omitempty"`
@@ -43,17 +43,48 @@ type ListenerType string
 const EGRESS ListenerType = "egress"
 const INGRESS ListenerType = "ingress"
 
+// Listener network types
+const (
+	NetworkTCP = "tcp"
+	NetworkUDP = "udp"
+)
+
 type ListenerConfig struct {
-	Name                  string          `json:"name,omitempty"`
-	Type                  ListenerType    `json:"type,omitempty"`
-	AddrConfig            string          `json:"address,omitempty"`
-	BindToPort            bool            `json:"bind_port,omitempty"`
-	UseOriginalDst        bool            `json:"use_original_dst,omitempty"`
-	AccessLogs            []AccessLog     `json:"access_logs,omitempty"`
-	FilterChains          []FilterChain   `json:"filter_chains,omitempty"` // only one filterchains at this time
-	StreamFilters         []Filter        `json:"stream_filters,omitempty"`
-	Inspector             bool            `json:"inspector,omitempty"`
-	ConnectionIdleTimeout *DurationConfig `json:"connection_idle_timeout,omitempty"`
+	Name                  string            `json:"name,omitempty"`
+	Type                  ListenerType      `json:"type,omitempty"`
+	AddrConfig            string            `json:"address,omitempty"` // ip:port, or unix:///path and unix://@name for unix domain socket
+	Network               string            `json:"network,omitempty"` // tcp or udp, default is tcp
+	UnixSocket            *UnixSocketConfig `json:"unix_socket,omitempty"`
+	BindToPort            bool              `json:"bind_port,omitempty"`
+	ReusePort             bool              `json:"reuse_port,omitempty"`
+	UseOriginalDst        bool              `json:"use_original_dst,omitempty"`
+	UseProxyProtocol      bool              `json:"use_proxy_protocol,omitempty"`
+	AccessLogs            []AccessLog       `json:"access_logs,omitempty"`
+	FilterChains          []FilterChain     `json:"filter_chains,omitempty"` // only one filterchains at this time
+	StreamFilters         []Filter          `json:"stream_filters,omitempty"`
+	Inspector             bool              `json:"inspector,omitempty"`
+	ConnectionIdleTimeout *DurationConfig   `json:"connection_idle_timeout,omitempty"`
+	RequestID             *RequestIDConfig  `json:"request_id,omitempty"`
+}
+
+// UnixSocketConfig sets the file permission and the owner of the unix domain socket path,
+// it is ignored by the sockets in the abstract namespace, which have no file.
+type UnixSocketConfig struct {
+	// Mode is the octal file mode of the socket path, such as 0660
+	Mode string `json:"mode,omitempty"`
+	// Owner is "user" or "user:group", the user and the group can be names or ids
+	Owner string `json:"owner,omitempty"`
+}
+
+// RequestIDConfig configures the x-request-id of the http requests received by the listener.
+// A UUIDv4 request id is generated if the request has none, it is propagated to the upstream
+// and returned in the response headers.
+type RequestIDConfig struct {
+	// Disable disables the request id generation
+	Disable bool `json:"disable,omitempty"`
+	// Regenerate ignores the request id from the downstream and always generates a new one,
+	// it is used by the edge listeners that do not trust the downstream
+	Regenerate bool `json:"regenerate,omitempty"`
 }
 
 type TCPRouteConfig struct {
@@ -42,6 +42,8 @@ const (
 	CONNECTION_MANAGER          = "connection_manager"
 	DEFAULT_NETWORK_FILTER      = "proxy"
 	TCP_PROXY                   = "tcp_proxy"
+	UDP_PROXY                   = "udp_proxy"
+	REDIS_PROXY                 = "redis_proxy"
 	FAULT_INJECT_NETWORK_FILTER = "fault_inject"
 	RPC_PROXY                   = "rpc_proxy"
 	X_PROXY                     = "x_proxy"
@@ -52,6 +54,9 @@ const (
 	MIXER        = "mixer"
 	FaultStream  = "fault"
 	PayloadLimit = "payload_limit"
+	GRPCWeb      = "grpc_web"
+
+	GRPCJSONTranscoder = "grpc_json_transcoder"
 )
 
 // ClusterType
@@ -63,6 +68,9 @@ const (
 	SIMPLE_CLUSTER  ClusterType = "SIMPLE"
 	DYNAMIC_CLUSTER ClusterType = "DYNAMIC"
 	EDS_CLUSTER     ClusterType = "EDS"
+	// ORIGINALDST_CLUSTER has no configured hosts, the upstream host is created from the
+	// request authority or the original destination of the downstream connection.
+	ORIGINALDST_CLUSTER ClusterType = "ORIGINAL_DST"
 )
 
 // LbType
@@ -89,8 +97,23 @@ type Cluster struct {
 	TLS                  TLSConfig       `json:"tls_context,omitempty"`
 	Hosts                []Host          `json:"hosts,omitempty"`
 	ConnectTimeout       *DurationConfig `json:"connect_timeout,omitempty"`
+	ProxyProtocol        *ProxyProtocol  `json:"proxy_protocol,omitempty"`
+}
+
+// ProxyProtocol configures the PROXY protocol header sent to the upstream hosts.
+// The header carries the downstream addresses of the tcp proxy, other connections
+// such as the pooled ones send a LOCAL header
+type ProxyProtocol struct {
+	// Version is the PROXY protocol version, "v1" or "v2"
+	Version string `json:"version,omitempty"`
 }
 
+// PROXY protocol versions
+const (
+	ProxyProtocolV1 = "v1"
+	ProxyProtocolV2 = "v2"
+)
+
 // HealthCheck is a configuration of health check
 // use DurationConfig to parse string to time.Duration
 type HealthCheck struct {
@@ -140,12 +163,12 @@ func (h *Host) UnmarshalJSON(b []byte) error {
 // Listener contains the listener's information
 type Listener struct {
 	ListenerConfig
-	Addr                    net.Addr         `json:"-"`
-	ListenerTag             uint64           `json:"-"`
-	ListenerScope           string           `json:"-"`
-	PerConnBufferLimitBytes uint32           `json:"-"` // do not support config
-	InheritListener         *net.TCPListener `json:"-"`
-	Remain                  bool             `json:"-"`
+	Addr                    net.Addr     `json:"-"`
+	ListenerTag             uint64       `json:"-"`
+	ListenerScope           string       `json:"-"`
+	PerConnBufferLimitBytes uint32       `json:"-"` // do not support config
+	InheritListener         net.Listener `json:"-"` // *net.TCPListener or *net.UnixListener
+	Remain                  bool         `json:"-"`
 }
 
 // TCPRoute
@@ -195,6 +218,15 @@ type StreamPayloadLimit struct {
 	HttpStatus    int32 `json:"http_status"`
 }
 
+// StreamGRPCJSONTranscoder is the config of the gRPC-JSON transcoder filter
+type StreamGRPCJSONTranscoder struct {
+	// ProtoDescriptor is the path of the protobuf descriptor set file,
+	// which is generated by protoc with --include_imports --descriptor_set_out
+	ProtoDescriptor string `json:"proto_descriptor"`
+	// Services are the full names of the gRPC services to be transcoded, all services are transcoded if empty
+	Services []string `json:"services,omitempty"`
+}
+
 func (f FaultInject) Marshal() (b []byte, err error) {
 	f.FaultInjectConfig.DelayDurationConfig.Duration = time.Duration(f.DelayDuration)
 	return json.Marshal(f.FaultInjectConfig)
@@ -214,6 +246,9 @@ type StreamFaultInject struct {
 	Abort           *AbortInject    `json:"abort,omitempty"`
 	UpstreamCluster string          `json:"upstream_cluster,omitempty"`
 	Headers         []HeaderMatcher `json:"headers,omitempty"`
+	// the runtime keys override the delay and abort percentages
+	DelayPercentRuntime string `json:"delay_percent_runtime,omitempty"`
+	AbortPercentRuntime string `json:"abort_percent_runtime,omitempty"`
 }
 
 type DelayInject struct {
@@ -425,6 +460,37 @@ func (c *SdsConfig) Valid() bool {
 type AccessLog struct {
 	Path   string `json:"log_path,omitempty"`
 	Format string `json:"log_format,omitempty"`
+	// JSONFormat makes the access log in json, the key is the field name
+	// and the value is a variable such as %bytes_sent% or a literal text
+	JSONFormat map[string]string `json:"log_json_format,omitempty"`
+	// GRPCService streams the access logs to a gRPC access log service instead of the file
+	GRPCService *GRPCAccessLog `json:"grpc_service,omitempty"`
+	// Filter decides whether a request is logged, all the requests are logged if it is nil
+	Filter *AccessLogFilter `json:"filter,omitempty"`
+}
+
+// AccessLogFilter is the config of an access log filter. The filters of type and, or and not
+// are composed by the sub filters, the others are configured by the config
+type AccessLogFilter struct {
+	Type    string                 `json:"type"`
+	Config  map[string]interface{} `json:"config,omitempty"`
+	Filters []AccessLogFilter      `json:"filters,omitempty"`
+}
+
+// GRPCAccessLog is the config of an envoy compatible gRPC access log service
+type GRPCAccessLog struct {
+	Address string `json:"address"`
+	LogName string `json:"log_name"`
+	// LogType is http or tcp, default is http
+	LogType                   string   `json:"log_type,omitempty"`
+	AdditionalRequestHeaders  []string `json:"additional_request_headers_to_log,omitempty"`
+	AdditionalResponseHeaders []string `json:"additional_response_headers_to_log,omitempty"`
+	// the entries are dropped if the queue is full
+	QueueSize     int            `json:"queue_size,omitempty"`
+	BatchSize     int            `json:"batch_size,omitempty"`
+	FlushInterval DurationConfig `json:"flush_interval,omitempty"`
+	// TLS is used when the status is true, otherwise the connection is plaintext
+	TLS TLSConfig `json:"tls,omitempty"`
 }
 
 // FilterChain wraps a set of match criteria, an option TLS context,
@@ -479,11 +545,55 @@ type TCPProxy struct {
 	Routes             []*TCPRoute    `json:"routes,omitempty"`
 }
 
-// WebSocketProxy
+// UDPProxy proxies the datagrams of the udp listener, a session is created for each downstream
+// client address, and the datagrams of a session are sent to the same upstream host
+type UDPProxy struct {
+	StatPrefix string `json:"stat_prefix,omitempty"`
+	Cluster    string `json:"cluster,omitempty"`
+	// IdleTimeout closes the session if no datagrams are received or sent for a while, default is 60s
+	IdleTimeout *DurationConfig `json:"idle_timeout,omitempty"`
+	// MaxSessions limits the active sessions of the proxy, zero means no limit
+	MaxSessions uint32 `json:"max_sessions,omitempty"`
+}
+
+// RedisProxy proxies the redis commands (RESP2 and RESP3) to the hosts of a cluster.
+// The commands are routed by the hash of the key, the multi-key commands such as MGET and DEL
+// are split by the hosts of the keys, and the replies are merged in the order of the keys.
+type RedisProxy struct {
+	StatPrefix string `json:"stat_prefix,omitempty"`
+	Cluster    string `json:"cluster,omitempty"`
+	// Sharding is consistent_hash or redis_cluster, default is consistent_hash.
+	// The consistent_hash shards the keys by a hash ring of the cluster hosts, the redis_cluster
+	// routes the keys by the slot map of a redis cluster, which is discovered from the cluster hosts
+	Sharding string `json:"sharding,omitempty"`
+	// AllowedCommands limits the commands can be sent by the clients, empty means all the supported commands
+	AllowedCommands []string `json:"allowed_commands,omitempty"`
+	// OpTimeout is the timeout of a command, default is 5s
+	OpTimeout *DurationConfig `json:"op_timeout,omitempty"`
+	// MaxRedirections limits the MOVED and ASK redirections of a command in redis_cluster, default is 3
+	MaxRedirections uint32 `json:"max_redirections,omitempty"`
+}
+
+// RedisProxy sharding
+const (
+	RedisShardingConsistentHash = "consistent_hash"
+	RedisShardingCluster        = "redis_cluster"
+)
+
+// WebSocketProxy configures the HTTP/1.1 Upgrade requests, such as WebSocket.
+// An upgrade request is routed as a normal request, after the upstream responds 101 Switching Protocols,
+// the downstream and the upstream connections are switched into raw bytes relaying.
 type WebSocketProxy struct {
-	StatPrefix         string
-	IdleTimeout        *time.Duration
-	MaxConnectAttempts uint32
+	// IdleTimeout closes the tunnel if no bytes are relayed for a while, zero means no idle timeout
+	IdleTimeout *DurationConfig `json:"idle_timeout,omitempty"`
+}
+
+// ConnectProxy configures the HTTP CONNECT requests.
+// A CONNECT request is routed by its authority (host:port), so the allowed targets are the virtual host domains,
+// the matched cluster dials the target and the payload of the request is relayed to the upstream connection.
+type ConnectProxy struct {
+	// IdleTimeout closes the tunnel if no bytes are relayed for a while, zero means no idle timeout
+	IdleTimeout *DurationConfig `json:"idle_timeout,omitempty"`
 }
 
 // Proxy
@@ -494,6 +604,21 @@ type Proxy struct {
 	RouterConfigName   string                 `json:"router_config_name,omitempty"`
 	ValidateClusters   bool                   `json:"validate_clusters,omitempty"`
 	ExtendConfig       map[string]interface{} `json:"extend_config,omitempty"`
+	Debug              *ProxyDebug            `json:"debug,omitempty"`
+	WebSocket          *WebSocketProxy        `json:"websocket,omitempty"`
+	Connect            *ConnectProxy          `json:"connect,omitempty"`
+}
+
+// ProxyDebug enables the per-request debug mode.
+// A request carries the header with the token is logged at debug level regardless of the global log level,
+// and the routing decisions of the request are returned in the response header.
+type ProxyDebug struct {
+	// Header is the request header triggers the debug mode, default is x-mosn-debug
+	Header string `json:"header,omitempty"`
+	// Token is the expected header value, it is required, the debug mode is never turned on without a token
+	Token string `json:"token,omitempty"`
+	// ResponseHeader is the response header returns the routing decisions, default is x-mosn-debug-trace
+	ResponseHeader string `json:"response_header,omitempty"`
 }
 
 // HeaderValueOption is header name/value pair plus option to control append behavior.
@@ -604,10 +729,31 @@ type VirtualHost struct {
 
 // RouterMatch represents the route matching parameters
 type RouterMatch struct {
-	Prefix  string          `json:"prefix,omitempty"`  // Match request's Path with Prefix Comparing
-	Path    string          `json:"path,omitempty"`    // Match request's Path with Exact Comparing
-	Regex   string          `json:"regex,omitempty"`   // Match request's Path with Regex Comparing
-	Headers []HeaderMatcher `json:"headers,omitempty"` // Match request's Headers
+	Prefix          string                    `json:"prefix,omitempty"`           // Match request's Path with Prefix Comparing
+	Path            string                    `json:"path,omitempty"`             // Match request's Path with Exact Comparing
+	Regex           string                    `json:"regex,omitempty"`            // Match request's Path with Regex Comparing
+	Headers         []HeaderMatcher           `json:"headers,omitempty"`          // Match request's Headers
+	RuntimeFraction *RuntimeFractionalPercent `json:"runtime_fraction,omitempty"` // Match the fraction of the requests
+	ConnectMatcher  *ConnectMatcher           `json:"connect_matcher,omitempty"`  // Match the CONNECT requests
+}
+
+// ConnectMatcher matches the HTTP CONNECT requests, which have no path.
+// The target of a CONNECT request is its authority, so the allowed targets are configured by the virtual host domains.
+type ConnectMatcher struct {
+}
+
+// FractionalPercent is the fraction of the numerator and the denominator,
+// the denominator is one of HUNDRED, TEN_THOUSAND and MILLION, default is HUNDRED
+type FractionalPercent struct {
+	Numerator   uint32 `json:"numerator"`
+	Denominator string `json:"denominator,omitempty"`
+}
+
+// RuntimeFractionalPercent is the fractional percent can be changed by the runtime key,
+// the runtime value is the numerator of the default value's denominator
+type RuntimeFractionalPercent struct {
+	DefaultValue FractionalPercent `json:"default_value"`
+	RuntimeKey   string            `json:"runtime_key,omitempty"`
 }
 
 // DirectResponseAction represents the direct response parameters
@@ -683,6 +829,15 @@ type StatsMatcher struct {
 	ExclusionKeys   []string `json:"exclusion_keys,omitempty"`
 }
 
+// HistogramConfig is a configuration for the fixed buckets histograms
+type HistogramConfig struct {
+	// Buckets are the bucket bounds of the histograms, the key is a metrics type, e.g. 'upstream',
+	// or a metrics type and a key, e.g. 'upstream.request_duration_time'
+	Buckets map[string][]float64 `json:"buckets,omitempty"`
+	// Shm stores the histograms in the metrics shm zone, so they survive hot upgrade
+	Shm bool `json:"shm,omitempty"`
+}
+
 // ServerConfig for making up server for mosn
 type ServerConfig struct {
 	//default logger
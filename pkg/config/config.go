@@ -40,14 +40,26 @@ type TracingConfig struct {
 	Tracer string                 `json:"tracer"`
 	Driver string                 `json:"driver"`
 	Config map[string]interface{} `json:"config,omitempty"`
+	// the trace context formats propagated from downstream to upstream, such as uber-trace-id and sw8
+	Propagation []string `json:"propagation,omitempty"`
 }
 
 // MetricsConfig for metrics sinks
 type MetricsConfig struct {
-	SinkConfigs  []v2.Filter       `json:"sinks"`
-	StatsMatcher v2.StatsMatcher   `json:"stats_matcher"`
-	ShmZone      string            `json:"shm_zone"`
-	ShmSize      datasize.ByteSize `json:"shm_size"`
+	SinkConfigs  []v2.Filter        `json:"sinks"`
+	StatsMatcher v2.StatsMatcher    `json:"stats_matcher"`
+	Histogram    v2.HistogramConfig `json:"histogram"`
+	ShmZone      string             `json:"shm_zone"`
+	ShmSize      datasize.ByteSize  `json:"shm_size"`
+}
+
+// RuntimeConfig for the runtime key-value layer
+type RuntimeConfig struct {
+	// Path is the directory of the runtime disk layer, the relative path of a file
+	// with "/" replaced by "." is the runtime key, and the trimmed content is the value
+	Path string `json:"path,omitempty"`
+	// RefreshInterval is the interval of reloading the directory, default is 5s
+	RefreshInterval v2.DurationConfig `json:"refresh_interval,omitempty"`
 }
 
 // ClusterManagerConfig for making up cluster manager
@@ -151,6 +163,7 @@ type MOSNConfig struct {
 	//tracing config
 	Tracing             TracingConfig   `json:"tracing"`
 	Metrics             MetricsConfig   `json:"metrics"`
+	Runtime             RuntimeConfig   `json:"runtime,omitempty"`
 	RawDynamicResources json.RawMessage `json:"dynamic_resources,omitempty"` //dynamic_resources raw message
 	RawStaticResources  json.RawMessage `json:"static_resources,omitempty"`  //static_resources raw message
 	RawAdmin            json.RawMessage `json:"admin,omitempty"`             // admin raw message
@@ -32,6 +32,7 @@ import (
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
 )
 
 var protocolsSupported = map[string]bool{
@@ -171,6 +172,26 @@ func ParseListenerConfig(lc *v2.Listener, inheritListeners []net.Listener) *v2.L
 	if lc.AddrConfig == "" {
 		log.StartLogger.Fatalln("[config] [parse listener] Address is required in listener config")
 	}
+	if lc.Network == v2.NetworkUDP {
+		addr, err := net.ResolveUDPAddr("udp", lc.AddrConfig)
+		if err != nil {
+			log.StartLogger.Fatalln("[config] [parse listener] Address not valid:", lc.AddrConfig)
+		}
+		// the udp listeners are not inherited, the port is bound with reuse_port by both processes on hot upgrade
+		if !lc.ReusePort {
+			log.StartLogger.Warnf("[config] [parse listener] reuse_port is forced on for udp listener %s, the udp listener is not inherited on hot upgrade", lc.AddrConfig)
+			lc.ReusePort = true
+		}
+		lc.Addr = addr
+		lc.PerConnBufferLimitBytes = 1 << 15
+		return lc
+	}
+	if addr, ok := utils.ParseUnixAddr(lc.AddrConfig); ok {
+		lc.Addr = addr
+		lc.PerConnBufferLimitBytes = 1 << 15
+		lc.InheritListener = inheritUnixListener(addr, inheritListeners)
+		return lc
+	}
 	addr, err := net.ResolveTCPAddr("tcp", lc.AddrConfig)
 	if err != nil {
 		log.StartLogger.Fatalln("[config] [parse listener] Address not valid:", lc.AddrConfig)
@@ -179,10 +200,10 @@ func ParseListenerConfig(lc *v2.Listener, inheritListeners []net.Listener) *v2.L
 	var old *net.TCPListener
 
 	for i, il := range inheritListeners {
-		if il == nil {
+		tl, ok := il.(*net.TCPListener)
+		if !ok {
 			continue
 		}
-		tl := il.(*net.TCPListener)
 		ilAddr, err := net.ResolveTCPAddr("tcp", tl.Addr().String())
 		if err != nil {
 			log.StartLogger.Fatalln("[config] [parse listener] inheritListener not valid:", tl.Addr().String())
@@ -204,10 +225,28 @@ func ParseListenerConfig(lc *v2.Listener, inheritListeners []net.Listener) *v2.L
 
 	lc.Addr = addr
 	lc.PerConnBufferLimitBytes = 1 << 15
-	lc.InheritListener = old
+	if old != nil {
+		lc.InheritListener = old
+	}
 	return lc
 }
 
+// inheritUnixListener finds the legacy unix domain socket listener by the socket path
+func inheritUnixListener(addr *net.UnixAddr, inheritListeners []net.Listener) net.Listener {
+	for i, il := range inheritListeners {
+		ul, ok := il.(*net.UnixListener)
+		if !ok {
+			continue
+		}
+		if ul.Addr().String() == addr.Name {
+			log.StartLogger.Infof("[config] [parse listener] inherit unix listener addr: %s", addr.Name)
+			inheritListeners[i] = nil
+			return ul
+		}
+	}
+	return nil
+}
+
 // ParseRouterConfiguration used to get virtualhosts from filter
 func ParseRouterConfiguration(c *v2.FilterChain) *v2.RouterConfiguration {
 	routerConfiguration := &v2.RouterConfiguration{}
@@ -243,6 +282,10 @@ func ParseProxyFilter(cfg map[string]interface{}) *v2.Proxy {
 	} else if _, ok := protocolsSupported[proxyConfig.UpstreamProtocol]; !ok {
 		log.StartLogger.Fatal("[config] [parse proxy] Invalid Upstream Protocol = ", proxyConfig.UpstreamProtocol)
 	}
+	// the debug mode logs at every level and returns the routing details, it must be protected by a token
+	if proxyConfig.Debug != nil && proxyConfig.Debug.Token == "" {
+		log.StartLogger.Fatal("[config] [parse proxy] Token is required in proxy debug config")
+	}
 
 	return proxyConfig
 }
@@ -271,6 +314,22 @@ func ParseStreamPayloadLimitFilter(cfg map[string]interface{}) (*v2.StreamPayloa
 	return filterConfig, nil
 }
 
+// ParseStreamGRPCJSONTranscoderFilter
+func ParseStreamGRPCJSONTranscoderFilter(cfg map[string]interface{}) (*v2.StreamGRPCJSONTranscoder, error) {
+	filterConfig := &v2.StreamGRPCJSONTranscoder{}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, filterConfig); err != nil {
+		return nil, err
+	}
+	if filterConfig.ProtoDescriptor == "" {
+		return nil, fmt.Errorf("[config] grpc json transcoder config has no proto descriptor")
+	}
+	return filterConfig, nil
+}
+
 // ParseStreamFaultInjectFilter
 func ParseStreamFaultInjectFilter(cfg map[string]interface{}) (*v2.StreamFaultInject, error) {
 	filterConfig := &v2.StreamFaultInject{}
@@ -326,6 +385,43 @@ func ParseTCPProxy(cfg map[string]interface{}) (*v2.TCPProxy, error) {
 	return proxy, nil
 }
 
+// ParseUDPProxy
+func ParseUDPProxy(cfg map[string]interface{}) (*v2.UDPProxy, error) {
+	proxy := &v2.UDPProxy{}
+	if data, err := json.Marshal(cfg); err == nil {
+		if err := json.Unmarshal(data, proxy); err != nil {
+			return nil, fmt.Errorf("[config] config is not a udp proxy config: %v", err)
+		}
+	} else {
+		return nil, fmt.Errorf("[config] config is not a udp proxy config: %v", err)
+	}
+	if proxy.Cluster == "" {
+		return nil, fmt.Errorf("[config] cluster is required in udp proxy config")
+	}
+	return proxy, nil
+}
+
+// ParseRedisProxy
+func ParseRedisProxy(cfg map[string]interface{}) (*v2.RedisProxy, error) {
+	proxy := &v2.RedisProxy{}
+	if data, err := json.Marshal(cfg); err == nil {
+		if err := json.Unmarshal(data, proxy); err != nil {
+			return nil, fmt.Errorf("[config] config is not a redis proxy config: %v", err)
+		}
+	} else {
+		return nil, fmt.Errorf("[config] config is not a redis proxy config: %v", err)
+	}
+	if proxy.Cluster == "" {
+		return nil, fmt.Errorf("[config] cluster is required in redis proxy config")
+	}
+	switch proxy.Sharding {
+	case "", v2.RedisShardingConsistentHash, v2.RedisShardingCluster:
+	default:
+		return nil, fmt.Errorf("[config] unknown redis proxy sharding %s", proxy.Sharding)
+	}
+	return proxy, nil
+}
+
 func ParseServiceRegistry(src v2.ServiceRegistryInfo) {
 	//trigger all callbacks
 	if cbs, ok := configParsedCBMaps[ParseCallbackKeyServiceRgtInfo]; ok {
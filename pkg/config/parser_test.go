@@ -19,7 +19,10 @@ package config
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -242,6 +245,68 @@ func TestParseListenerConfig(t *testing.T) {
 	}
 }
 
+func TestParseUnixListenerConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn_unix_listener")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mosn.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpListener.Close()
+	inherit := []net.Listener{tcpListener, listener}
+
+	lc := &v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			AddrConfig: "unix://" + path,
+		},
+	}
+	ln := ParseListenerConfig(lc, inherit)
+	addr, ok := ln.Addr.(*net.UnixAddr)
+	if !ok || addr.Name != path || ln.InheritListener != listener {
+		t.Fatalf("unix listener parse unexpected: %v, %v", ln.Addr, ln.InheritListener)
+	}
+	if inherit[0] == nil || inherit[1] != nil {
+		t.Error("inherit listener unexpected")
+	}
+
+	// the abstract socket is not inherited by a path listener
+	lc = &v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			AddrConfig: "unix://@mosn",
+		},
+	}
+	ln = ParseListenerConfig(lc, inherit)
+	if addr, ok := ln.Addr.(*net.UnixAddr); !ok || addr.Name != "@mosn" || ln.InheritListener != nil {
+		t.Fatalf("abstract unix listener parse unexpected: %v, %v", ln.Addr, ln.InheritListener)
+	}
+}
+
+func TestParseUDPListenerConfig(t *testing.T) {
+	lc := &v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			AddrConfig: "127.0.0.1:5353",
+			Network:    v2.NetworkUDP,
+		},
+	}
+	ln := ParseListenerConfig(lc, nil)
+	if addr, ok := ln.Addr.(*net.UDPAddr); !ok || addr.String() != "127.0.0.1:5353" || ln.InheritListener != nil {
+		t.Fatalf("udp listener parse unexpected: %v, %v", ln.Addr, ln.InheritListener)
+	}
+	// the udp listener is not inherited, reuse_port is required by hot upgrade
+	if !ln.ReusePort {
+		t.Error("reuse_port should be enabled for udp listener")
+	}
+}
+
 func TestParseProxyFilter(t *testing.T) {
 	proxyConfigStr := `{
 		"name": "proxy",
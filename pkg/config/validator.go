@@ -0,0 +1,590 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/filter"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/metrics/sink"
+	"mosn.io/mosn/pkg/mtls"
+	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/trace/propagation"
+	"mosn.io/mosn/pkg/utils"
+)
+
+// ValidationError is a problem found in the configuration
+// Path is the json path of the problem, such as $.servers[0].listeners[0].address
+// Warning means the problem does not stop mosn from starting, such as a setting overridden when parsed
+type ValidationError struct {
+	Path    string
+	Err     error
+	Warning bool
+}
+
+func (e *ValidationError) Error() string {
+	if e.Warning {
+		return fmt.Sprintf("%s: warning: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Validate checks the config file in the same way as NewMosn does, but without
+// opening any sockets. All the problems found are returned, the config is valid if
+// there is no problem other than warnings
+func Validate(path string) []*ValidationError {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []*ValidationError{{Path: "$", Err: err}}
+	}
+	return ValidateContent(content)
+}
+
+// ValidateContent checks the config content, see Validate
+func ValidateContent(content []byte) []*ValidationError {
+	v := &validator{
+		clusters: make(map[string]bool),
+		routers:  make(map[string]bool),
+	}
+	v.validate(content)
+	return v.errs
+}
+
+// clusterReference is a cluster name used in route or tcp proxy
+type clusterReference struct {
+	path string
+	name string
+}
+
+type validator struct {
+	errs []*ValidationError
+	// dynamic means clusters or routers may be added after start, the references are not checked
+	dynamic     bool
+	clusters    map[string]bool
+	routers     map[string]bool
+	clusterRefs []clusterReference
+	routerRefs  []clusterReference
+}
+
+func (v *validator) report(path string, err error) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Err: err})
+}
+
+func (v *validator) reportf(path string, format string, args ...interface{}) {
+	v.report(path, fmt.Errorf(format, args...))
+}
+
+func (v *validator) warnf(path string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Err: fmt.Errorf(format, args...), Warning: true})
+}
+
+// decode unmarshals the data, and reports the error with the json path if failed
+func (v *validator) decode(path string, data []byte, value interface{}) bool {
+	err := json.Unmarshal(data, value)
+	if err == nil {
+		return true
+	}
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		if e.Field != "" {
+			path = path + "." + e.Field
+		}
+		v.reportf(path, "cannot unmarshal %s into %s", e.Value, e.Type)
+	case *json.SyntaxError:
+		line, column := position(data, e.Offset)
+		v.reportf(path, "syntax error at line %d, column %d: %v", line, column, e)
+	default:
+		v.report(path, err)
+	}
+	return false
+}
+
+// position returns the line and column of the offset in data
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// mosnConfigSections shadows the sections which are validated one by one
+type mosnConfigSections struct {
+	MOSNConfig
+	Servers        []json.RawMessage `json:"servers,omitempty"`
+	ClusterManager json.RawMessage   `json:"cluster_manager,omitempty"`
+}
+
+type clusterManagerSections struct {
+	ClusterManagerConfigJson
+	ClustersJson []json.RawMessage `json:"clusters,omitempty"`
+}
+
+type serverSections struct {
+	v2.ServerConfig
+	Listeners []json.RawMessage `json:"listeners,omitempty"`
+}
+
+func (v *validator) validate(content []byte) {
+	cfg := &mosnConfigSections{}
+	if !v.decode("$", content, cfg) {
+		return
+	}
+	// the mode is decided by servers and raw resources only
+	cfg.MOSNConfig.Servers = make([]v2.ServerConfig, len(cfg.Servers))
+	mode := cfg.Mode()
+
+	autoDiscovery := v.validateClusterManager("$.cluster_manager", cfg.ClusterManager, mode)
+	v.dynamic = autoDiscovery || mode != File
+
+	if mode != Xds {
+		if len(cfg.Servers) == 0 {
+			v.reportf("$.servers", "no server found")
+		} else if len(cfg.Servers) > 1 {
+			v.reportf("$.servers", "multiple server not supported yet, got %d", len(cfg.Servers))
+		}
+	}
+	for i, data := range cfg.Servers {
+		v.validateServer(fmt.Sprintf("$.servers[%d]", i), data)
+	}
+
+	v.validateTracing("$.tracing", cfg.Tracing)
+	v.validateMetrics("$.metrics", cfg.Metrics)
+	v.validateReferences()
+}
+
+// validateClusterManager returns whether the cluster manager supports auto discovery
+func (v *validator) validateClusterManager(path string, data json.RawMessage, mode Mode) bool {
+	cm := &clusterManagerSections{}
+	if len(data) > 0 && !v.decode(path, data, cm) {
+		return false
+	}
+	if len(cm.ClustersJson) > 0 && cm.ClusterConfigPath != "" {
+		v.report(path, v2.ErrDuplicateStaticAndDynamic)
+	}
+
+	count := 0
+	for i, data := range cm.ClustersJson {
+		clusterPath := fmt.Sprintf("%s.clusters[%d]", path, i)
+		c := v2.Cluster{}
+		if v.decode(clusterPath, data, &c) {
+			v.validateCluster(clusterPath, &c)
+			count++
+		}
+	}
+	// assume all the files in the path are available json file, and no sub path
+	if cm.ClusterConfigPath != "" {
+		dirPath := path + ".clusters_configs"
+		files, err := ioutil.ReadDir(cm.ClusterConfigPath)
+		if err != nil {
+			v.report(dirPath, err)
+		}
+		for _, f := range files {
+			if filepath.Ext(f.Name()) != utils.JsonExt {
+				continue
+			}
+			clusterPath := fmt.Sprintf("%s[%s]", dirPath, f.Name())
+			content, err := ioutil.ReadFile(filepath.Join(cm.ClusterConfigPath, f.Name()))
+			if err != nil {
+				v.report(clusterPath, err)
+				continue
+			}
+			if len(content) == 0 {
+				continue
+			}
+			c := v2.Cluster{}
+			if v.decode(clusterPath, content, &c) {
+				v.validateCluster(clusterPath, &c)
+				count++
+			}
+		}
+	}
+
+	if count == 0 && !cm.AutoDiscovery && mode != Xds {
+		v.reportf(path, "no cluster found and cluster manager doesn't support auto discovery")
+	}
+	return cm.AutoDiscovery
+}
+
+// validateCluster checks the cluster as ParseClusterConfig does
+func (v *validator) validateCluster(path string, c *v2.Cluster) {
+	if c.Name == "" {
+		v.reportf(path+".name", "name is required in cluster config")
+	} else if v.clusters[c.Name] {
+		v.reportf(path+".name", "duplicate cluster name %s", c.Name)
+	} else {
+		v.clusters[c.Name] = true
+	}
+	if c.LBSubSetConfig.FallBackPolicy > 2 {
+		v.reportf(path+".lb_subset_config.fall_back_policy", "unknown fall back policy %d", c.LBSubSetConfig.FallBackPolicy)
+	}
+	if _, ok := protocolsSupported[c.HealthCheck.Protocol]; !ok && c.HealthCheck.Protocol != "" {
+		v.reportf(path+".health_check.protocol", "unsupported health check protocol: %v", c.HealthCheck.Protocol)
+	}
+	for i, h := range c.Hosts {
+		if addr, ok := utils.ParseUnixAddr(h.Address); ok {
+			if addr.Name == "" {
+				v.reportf(fmt.Sprintf("%s.hosts[%d].address", path, i), "unix domain socket path is empty")
+			}
+		} else if _, _, err := net.SplitHostPort(h.Address); err != nil {
+			v.report(fmt.Sprintf("%s.hosts[%d].address", path, i), err)
+		}
+	}
+	if c.ProxyProtocol != nil && c.ProxyProtocol.Version != v2.ProxyProtocolV1 && c.ProxyProtocol.Version != v2.ProxyProtocolV2 {
+		v.reportf(path+".proxy_protocol.version", "unknown proxy protocol version %s", c.ProxyProtocol.Version)
+	}
+	v.validateTLS(path+".tls_context", &c.TLS, false)
+}
+
+// validateTLS loads the certificates in the tls config, sds config is not loaded
+// because it needs to connect to the sds server
+func (v *validator) validateTLS(path string, cfg *v2.TLSConfig, server bool) {
+	if !cfg.Status {
+		return
+	}
+	if cfg.SdsConfig != nil {
+		if !cfg.SdsConfig.Valid() {
+			v.reportf(path+".sds_source", "invalid sds config")
+		}
+		return
+	}
+	provider, err := mtls.NewProvider(cfg)
+	if err != nil {
+		v.report(path, err)
+		return
+	}
+	// a server without certificate is failed if fallback is not supported
+	if server && provider.Empty() && !cfg.Fallback {
+		v.report(path, mtls.ErrorNoCertConfigure)
+	}
+}
+
+func (v *validator) validateServer(path string, data json.RawMessage) {
+	srv := &serverSections{}
+	if !v.decode(path, data, srv) {
+		return
+	}
+	if srv.DefaultLogLevel != "" {
+		if _, ok := logLevelMap[srv.DefaultLogLevel]; !ok {
+			v.reportf(path+".default_log_level", "unknown log level %s", srv.DefaultLogLevel)
+		}
+	}
+	addrs := make(map[string]string, len(srv.Listeners))
+	for i, data := range srv.Listeners {
+		listenerPath := fmt.Sprintf("%s.listeners[%d]", path, i)
+		ln := &v2.Listener{}
+		if !v.decode(listenerPath, data, ln) {
+			continue
+		}
+		v.validateListener(listenerPath, ln)
+		if ln.AddrConfig != "" {
+			if name, ok := addrs[ln.AddrConfig]; ok {
+				v.reportf(listenerPath+".address", "address %s is already used by listener %s", ln.AddrConfig, name)
+			}
+			addrs[ln.AddrConfig] = ln.Name
+		}
+	}
+}
+
+// validateUnixSocket checks the unix domain socket address and the socket path settings
+func (v *validator) validateUnixSocket(path string, addr *net.UnixAddr, cfg *v2.UnixSocketConfig) {
+	if addr.Name == "" || addr.Name == "@" {
+		v.reportf(path+".address", "unix domain socket path is empty")
+	}
+	if cfg == nil {
+		return
+	}
+	if cfg.Mode != "" {
+		if _, err := utils.ParseFileMode(cfg.Mode); err != nil {
+			v.report(path+".unix_socket.mode", err)
+		}
+	}
+	if cfg.Owner != "" {
+		if _, _, err := utils.LookupOwner(cfg.Owner); err != nil {
+			v.reportf(path+".unix_socket.owner", "invalid owner %s: %v", cfg.Owner, err)
+		}
+	}
+}
+
+// validateListener checks the listener as ParseListenerConfig does, and creates the filters
+func (v *validator) validateListener(path string, ln *v2.Listener) {
+	if ln.AddrConfig == "" {
+		v.reportf(path+".address", "address is required in listener config")
+	} else if ln.Network != "" && ln.Network != v2.NetworkTCP && ln.Network != v2.NetworkUDP {
+		v.reportf(path+".network", "unknown network %s", ln.Network)
+	} else if addr, ok := utils.ParseUnixAddr(ln.AddrConfig); ok {
+		if ln.Network == v2.NetworkUDP {
+			v.reportf(path+".network", "udp is not supported by unix domain socket address %s", ln.AddrConfig)
+		}
+		v.validateUnixSocket(path, addr, ln.UnixSocket)
+	} else if ln.Network == v2.NetworkUDP {
+		if _, err := net.ResolveUDPAddr("udp", ln.AddrConfig); err != nil {
+			v.reportf(path+".address", "address not valid: %v", err)
+		}
+		// the udp listeners are not inherited on hot upgrade, the new process binds the port with reuse_port
+		if !ln.ReusePort {
+			v.warnf(path+".reuse_port", "reuse_port is forced on for udp listener")
+		}
+	} else if _, err := net.ResolveTCPAddr("tcp", ln.AddrConfig); err != nil {
+		v.reportf(path+".address", "address not valid: %v", err)
+	}
+
+	if len(ln.FilterChains) == 0 {
+		v.reportf(path+".filter_chains", "no filter chain found")
+	}
+	for i := range ln.FilterChains {
+		fc := &ln.FilterChains[i]
+		chainPath := fmt.Sprintf("%s.filter_chains[%d]", path, i)
+		if len(fc.TLSConfigs) > 0 {
+			for k := range fc.TLSConfigs {
+				v.validateTLS(fmt.Sprintf("%s.tls_context_set[%d]", chainPath, k), &fc.TLSConfigs[k], true)
+			}
+		} else if fc.TLSConfig != nil {
+			v.validateTLS(chainPath+".tls_context", fc.TLSConfig, true)
+		}
+		// the filters are not used by original dst listener
+		if !ln.UseOriginalDst {
+			for k := range fc.Filters {
+				v.validateNetworkFilter(fmt.Sprintf("%s.filters[%d]", chainPath, k), &fc.Filters[k])
+			}
+		}
+	}
+
+	for i := range ln.AccessLogs {
+		if f := ln.AccessLogs[i].Filter; f != nil {
+			if _, err := log.NewAccessLogFilter(f); err != nil {
+				v.report(fmt.Sprintf("%s.access_logs[%d].filter", path, i), err)
+			}
+		}
+	}
+
+	if !ln.UseOriginalDst {
+		for i, f := range ln.StreamFilters {
+			if _, err := filter.CreateStreamFilterChainFactory(f.Type, f.Config); err != nil {
+				v.report(fmt.Sprintf("%s.stream_filters[%d]", path, i), err)
+			}
+		}
+	}
+}
+
+func (v *validator) validateNetworkFilter(path string, f *v2.Filter) {
+	data, err := json.Marshal(f.Config)
+	if err != nil {
+		v.report(path+".config", err)
+		return
+	}
+	configPath := path + ".config"
+	switch f.Type {
+	case v2.CONNECTION_MANAGER:
+		v.validateRouterConfiguration(configPath, data)
+	case v2.DEFAULT_NETWORK_FILTER:
+		// the proxy filter factory exits if the protocol is invalid, so it is checked first
+		if !v.validateProxy(configPath, data) {
+			return
+		}
+	case v2.TCP_PROXY:
+		v.validateTCPProxy(configPath, data)
+	case v2.UDP_PROXY:
+		v.validateUDPProxy(configPath, data)
+	case v2.REDIS_PROXY:
+		v.validateRedisProxy(configPath, data)
+	}
+	if _, err := filter.CreateNetworkFilterChainFactory(f.Type, f.Config); err != nil {
+		v.report(path, err)
+	}
+}
+
+func (v *validator) validateRouterConfiguration(path string, data []byte) {
+	rc := &v2.RouterConfiguration{}
+	if !v.decode(path, data, rc) {
+		return
+	}
+	if rc.RouterConfigName == "" {
+		v.reportf(path+".router_config_name", "router config name is required")
+	} else if v.routers[rc.RouterConfigName] {
+		v.reportf(path+".router_config_name", "duplicate router config name %s", rc.RouterConfigName)
+	} else {
+		v.routers[rc.RouterConfigName] = true
+	}
+	if len(rc.VirtualHosts) == 0 {
+		return
+	}
+	if _, err := router.NewRouters(rc); err != nil {
+		v.report(path, err)
+	}
+	for i, vh := range rc.VirtualHosts {
+		vhPath := fmt.Sprintf("%s.virtual_hosts[%d]", path, i)
+		if rc.RouterConfigPath != "" {
+			vhPath = fmt.Sprintf("%s.router_configs[%s]", path, vh.Name)
+		}
+		for k, r := range vh.Routers {
+			// direct response and cluster header routes have no static cluster
+			if r.DirectResponse != nil || r.Route.ClusterHeader != "" {
+				continue
+			}
+			routePath := fmt.Sprintf("%s.routers[%d].route", vhPath, k)
+			if len(r.Route.WeightedClusters) > 0 {
+				for w, wc := range r.Route.WeightedClusters {
+					v.clusterRefs = append(v.clusterRefs, clusterReference{
+						path: fmt.Sprintf("%s.weighted_clusters[%d].cluster.name", routePath, w),
+						name: wc.Cluster.Name,
+					})
+				}
+			} else {
+				v.clusterRefs = append(v.clusterRefs, clusterReference{
+					path: routePath + ".cluster_name",
+					name: r.Route.ClusterName,
+				})
+			}
+		}
+	}
+}
+
+func (v *validator) validateProxy(path string, data []byte) bool {
+	p := &v2.Proxy{}
+	if !v.decode(path, data, p) {
+		return false
+	}
+	valid := true
+	for _, proto := range []struct {
+		key, value string
+	}{
+		{"downstream_protocol", p.DownstreamProtocol},
+		{"upstream_protocol", p.UpstreamProtocol},
+	} {
+		if proto.value == "" {
+			v.reportf(path+"."+proto.key, "protocol is required in proxy")
+			valid = false
+		} else if _, ok := protocolsSupported[proto.value]; !ok {
+			v.reportf(path+"."+proto.key, "invalid protocol %s", proto.value)
+			valid = false
+		}
+	}
+	if p.Debug != nil && p.Debug.Token == "" {
+		v.reportf(path+".debug.token", "token is required in proxy debug config")
+	}
+	if p.RouterConfigName != "" {
+		v.routerRefs = append(v.routerRefs, clusterReference{
+			path: path + ".router_config_name",
+			name: p.RouterConfigName,
+		})
+	}
+	return valid
+}
+
+func (v *validator) validateTCPProxy(path string, data []byte) {
+	p := &v2.TCPProxy{}
+	if !v.decode(path, data, p) {
+		return
+	}
+	if p.Cluster != "" {
+		v.clusterRefs = append(v.clusterRefs, clusterReference{
+			path: path + ".cluster",
+			name: p.Cluster,
+		})
+	}
+	for i, r := range p.Routes {
+		if r != nil && r.Cluster != "" {
+			v.clusterRefs = append(v.clusterRefs, clusterReference{
+				path: fmt.Sprintf("%s.routes[%d].cluster", path, i),
+				name: r.Cluster,
+			})
+		}
+	}
+}
+
+func (v *validator) validateUDPProxy(path string, data []byte) {
+	p := &v2.UDPProxy{}
+	if !v.decode(path, data, p) {
+		return
+	}
+	if p.Cluster != "" {
+		v.clusterRefs = append(v.clusterRefs, clusterReference{
+			path: path + ".cluster",
+			name: p.Cluster,
+		})
+	}
+}
+
+func (v *validator) validateRedisProxy(path string, data []byte) {
+	p := &v2.RedisProxy{}
+	if !v.decode(path, data, p) {
+		return
+	}
+	if p.Cluster != "" {
+		v.clusterRefs = append(v.clusterRefs, clusterReference{
+			path: path + ".cluster",
+			name: p.Cluster,
+		})
+	}
+}
+
+func (v *validator) validateTracing(path string, cfg TracingConfig) {
+	if cfg.Enable && cfg.Driver != "" && !trace.HasDriver(cfg.Driver) {
+		v.report(path+".driver", trace.ErrNoSuchDriver)
+	}
+	if err := propagation.Validate(cfg.Propagation); err != nil {
+		v.report(path+".propagation", err)
+	}
+}
+
+// validateMetrics checks the sink type only, creates a sink may listen a port
+func (v *validator) validateMetrics(path string, cfg MetricsConfig) {
+	for i, s := range cfg.SinkConfigs {
+		if !sink.HasSink(s.Type) {
+			v.reportf(fmt.Sprintf("%s.sinks[%d].type", path, i), "unsupported metrics sink type: %v", s.Type)
+		}
+	}
+	if err := metrics.ValidateHistogramBuckets(cfg.Histogram.Buckets); err != nil {
+		v.report(path+".histogram.buckets", err)
+	}
+}
+
+// validateReferences checks the route-to-cluster and proxy-to-router references,
+// like Proxy.ValidateClusters. references are not checked if they can be added dynamically
+func (v *validator) validateReferences() {
+	if v.dynamic {
+		return
+	}
+	for _, ref := range v.routerRefs {
+		if !v.routers[ref.name] {
+			v.reportf(ref.path, "router config %s not found", ref.name)
+		}
+	}
+	for _, ref := range v.clusterRefs {
+		if ref.name == "" {
+			v.report(ref.path, errEmptyClusterName)
+		} else if !v.clusters[ref.name] {
+			v.reportf(ref.path, "cluster %s not found", ref.name)
+		}
+	}
+}
+
+var errEmptyClusterName = errors.New("cluster name is required")
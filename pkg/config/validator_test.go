@@ -0,0 +1,267 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/filter"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockNetworkFilterFactory struct{}
+
+func (f *mockNetworkFilterFactory) CreateFilterChain(context context.Context, clusterManager types.ClusterManager, callbacks types.NetWorkFilterChainFactoryCallbacks) {
+}
+
+func init() {
+	creator := func(conf map[string]interface{}) (types.NetworkFilterChainFactory, error) {
+		return &mockNetworkFilterFactory{}, nil
+	}
+	filter.RegisterNetwork(v2.DEFAULT_NETWORK_FILTER, creator)
+	filter.RegisterNetwork(v2.CONNECTION_MANAGER, creator)
+	filter.RegisterNetwork(v2.UDP_PROXY, creator)
+}
+
+const validatorConfig = `{
+	"servers": [{
+		"listeners": [{
+			"name": "listener",
+			"address": "%s",
+			"filter_chains": [{
+				"filters": [{
+					"type": "proxy",
+					"config": {
+						"downstream_protocol": "%s",
+						"upstream_protocol": "Http1",
+						"router_config_name": "router"
+					}
+				}, {
+					"type": "connection_manager",
+					"config": {
+						"router_config_name": "router",
+						"virtual_hosts": [{
+							"name": "vh",
+							"domains": ["*"],
+							"routers": [{
+								"match": {"prefix": "/"},
+								"route": {"cluster_name": "%s"}
+							}]
+						}]
+					}
+				}]
+			}]
+		}]
+	}],
+	"cluster_manager": {
+		"clusters": [{
+			"name": "cluster",
+			"max_request_per_conn": %s,
+			"hosts": [{"address": "127.0.0.1:8080"}]
+		}]
+	}
+}`
+
+const udpValidatorConfig = `{
+	"servers": [{
+		"listeners": [{
+			"name": "udp_listener",
+			"address": "%s",
+			"network": "udp",
+			"reuse_port": %s,
+			"filter_chains": [{
+				"filters": [{
+					"type": "udp_proxy",
+					"config": {
+						"cluster": "cluster"
+					}
+				}]
+			}]
+		}]
+	}],
+	"cluster_manager": {
+		"clusters": [{
+			"name": "cluster",
+			"hosts": [{"address": "127.0.0.1:5353"}]
+		}]
+	}
+}`
+
+// withProxyDebug adds the debug config to the proxy of the validator config
+func withProxyDebug(config string, debug string) string {
+	return strings.Replace(config, `"upstream_protocol": "Http1",`, `"upstream_protocol": "Http1", "debug": `+debug+`,`, 1)
+}
+
+func validatePaths(errs []*ValidationError) []string {
+	paths := make([]string, 0, len(errs))
+	for _, err := range errs {
+		paths = append(paths, err.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn_validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name   string
+		config string
+		paths  []string
+	}{
+		{
+			name:   "valid",
+			config: fmt.Sprintf(validatorConfig, "127.0.0.1:2045", "Http1", "cluster", "1024"),
+		},
+		{
+			name:   "type error",
+			config: fmt.Sprintf(validatorConfig, "127.0.0.1:2045", "Http1", "cluster", `"1024"`),
+			paths: []string{
+				"$.cluster_manager",
+				"$.cluster_manager.clusters[0].max_request_per_conn",
+				"$.servers[0].listeners[0].filter_chains[0].filters[1].config.virtual_hosts[0].routers[0].route.cluster_name",
+			},
+		},
+		{
+			name:   "invalid listener",
+			config: fmt.Sprintf(validatorConfig, "127.0.0.1", "Unknown", "cluster", "1024"),
+			paths: []string{
+				"$.servers[0].listeners[0].address",
+				"$.servers[0].listeners[0].filter_chains[0].filters[0].config.downstream_protocol",
+			},
+		},
+		{
+			name:   "unix listener",
+			config: fmt.Sprintf(validatorConfig, "unix:///tmp/mosn.sock", "Http1", "cluster", "1024"),
+		},
+		{
+			name:   "empty unix path",
+			config: fmt.Sprintf(validatorConfig, "unix://", "Http1", "cluster", "1024"),
+			paths:  []string{"$.servers[0].listeners[0].address"},
+		},
+		{
+			name:   "cluster not found",
+			config: fmt.Sprintf(validatorConfig, "127.0.0.1:2045", "Http1", "unknown", "1024"),
+			paths: []string{
+				"$.servers[0].listeners[0].filter_chains[0].filters[1].config.virtual_hosts[0].routers[0].route.cluster_name",
+			},
+		},
+		{
+			name:   "debug token",
+			config: withProxyDebug(fmt.Sprintf(validatorConfig, "127.0.0.1:2045", "Http1", "cluster", "1024"), `{"token": "token"}`),
+		},
+		{
+			name:   "no debug token",
+			config: withProxyDebug(fmt.Sprintf(validatorConfig, "127.0.0.1:2045", "Http1", "cluster", "1024"), `{}`),
+			paths:  []string{"$.servers[0].listeners[0].filter_chains[0].filters[0].config.debug.token"},
+		},
+		{
+			name:   "syntax error",
+			config: "{\n\"servers\": [}",
+			paths:  []string{"$"},
+		},
+	}
+	for _, tc := range testCases {
+		file := filepath.Join(dir, tc.name+".json")
+		if err := ioutil.WriteFile(file, []byte(tc.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		errs := Validate(file)
+		paths := validatePaths(errs)
+		if len(paths) != len(tc.paths) {
+			t.Errorf("%s: expected problems %v, but got %v", tc.name, tc.paths, errs)
+			continue
+		}
+		for i := range paths {
+			if paths[i] != tc.paths[i] {
+				t.Errorf("%s: expected problems %v, but got %v", tc.name, tc.paths, errs)
+				break
+			}
+		}
+	}
+}
+
+func TestValidateUDPListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn_validate_udp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name     string
+		config   string
+		paths    []string
+		warnings []string
+	}{
+		{
+			name:   "valid",
+			config: fmt.Sprintf(udpValidatorConfig, "127.0.0.1:5353", "true"),
+		},
+		{
+			// reuse_port is forced on when parsed, so it is only a warning
+			name:     "no reuse port",
+			config:   fmt.Sprintf(udpValidatorConfig, "127.0.0.1:5353", "false"),
+			warnings: []string{"$.servers[0].listeners[0].reuse_port"},
+		},
+		{
+			name:   "invalid address",
+			config: fmt.Sprintf(udpValidatorConfig, "127.0.0.1:99999", "true"),
+			paths:  []string{"$.servers[0].listeners[0].address"},
+		},
+	}
+	for _, tc := range testCases {
+		file := filepath.Join(dir, tc.name+".json")
+		if err := ioutil.WriteFile(file, []byte(tc.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var problems, warnings []*ValidationError
+		for _, err := range Validate(file) {
+			if err.Warning {
+				warnings = append(warnings, err)
+			} else {
+				problems = append(problems, err)
+			}
+		}
+		if fmt.Sprint(validatePaths(problems)) != fmt.Sprint(tc.paths) {
+			t.Errorf("%s: expected problems %v, but got %v", tc.name, tc.paths, problems)
+		}
+		if fmt.Sprint(validatePaths(warnings)) != fmt.Sprint(tc.warnings) {
+			t.Errorf("%s: expected warnings %v, but got %v", tc.name, tc.warnings, warnings)
+		}
+	}
+}
+
+func TestValidateMissingFile(t *testing.T) {
+	errs := Validate("/tmp/not_exists_mosn_config.json")
+	if len(errs) != 1 || errs[0].Path != "$" {
+		t.Errorf("expected a file error, but got %v", errs)
+	}
+}
@@ -0,0 +1,349 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	// v1MaxLength is the max length of a v1 header including the CRLF
+	v1MaxLength = 107
+	// v1MinLength is the length of the shortest v1 header "PROXY UNKNOWN\r\n",
+	// it is also shorter than any v2 header
+	v1MinLength = 15
+	// v2HeaderLength is the length of the fixed part of a v2 header
+	v2HeaderLength = 16
+
+	v2VersionMask = 0xf0
+	v2CommandMask = 0x0f
+
+	v2FamilyUnspec = 0x0
+	v2FamilyInet   = 0x1
+	v2FamilyInet6  = 0x2
+	v2FamilyUnix   = 0x3
+
+	v2TransportUnspec = 0x0
+	v2TransportStream = 0x1
+	v2TransportDgram  = 0x2
+
+	v2AddrLengthInet  = 12
+	v2AddrLengthInet6 = 36
+	v2AddrLengthUnix  = 216
+)
+
+// NewHeader creates a header of the version, a Local header is created if any of the addresses is nil
+func NewHeader(version int, src, dst net.Addr) *Header {
+	h := &Header{
+		Version: version,
+		Command: Local,
+	}
+	if src != nil && dst != nil {
+		h.Command = Proxy
+		h.SourceAddr = src
+		h.DestinationAddr = dst
+	}
+	return h
+}
+
+// ReadHeader reads a PROXY protocol header from the reader.
+// It never reads more bytes than the header, so the reader can be used by the application after it.
+func ReadHeader(r io.Reader) (*Header, error) {
+	buf := make([]byte, v1MinLength, v1MaxLength)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(buf, v1Prefix):
+		return readV1(r, buf)
+	case bytes.HasPrefix(buf, v2Signature):
+		return readV2(r, buf)
+	}
+	return nil, ErrNoProxyProtocol
+}
+
+func readV1(r io.Reader, buf []byte) (*Header, error) {
+	// the v1 header has no length, read byte by byte until the LF to avoid consuming application data
+	b := make([]byte, 1)
+	for buf[len(buf)-1] != '\n' {
+		if len(buf) == v1MaxLength {
+			return nil, ErrHeaderTooLong
+		}
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		buf = append(buf, b[0])
+	}
+	return parseV1(buf)
+}
+
+func parseV1(buf []byte) (*Header, error) {
+	if !bytes.HasSuffix(buf, []byte("\r\n")) {
+		return nil, ErrInvalidHeader
+	}
+	fields := strings.Split(string(buf[len(v1Prefix):len(buf)-2]), " ")
+	h := &Header{
+		Version: Version1,
+		Command: Proxy,
+	}
+	switch fields[0] {
+	case "UNKNOWN":
+		// the rest of the line should be ignored
+		return h, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, ErrInvalidHeader
+	}
+	if len(fields) != 5 {
+		return nil, ErrInvalidHeader
+	}
+	ipv4 := fields[0] == "TCP4"
+	src, err := parseV1Addr(fields[1], fields[3], ipv4)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := parseV1Addr(fields[2], fields[4], ipv4)
+	if err != nil {
+		return nil, err
+	}
+	h.SourceAddr = src
+	h.DestinationAddr = dst
+	return h, nil
+}
+
+func parseV1Addr(host, port string, ipv4 bool) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil || strings.Contains(host, ":") == ipv4 {
+		return nil, ErrInvalidAddresses
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || (len(port) > 1 && port[0] == '0') {
+		return nil, ErrInvalidAddresses
+	}
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+func readV2(r io.Reader, buf []byte) (*Header, error) {
+	buf = buf[:v2HeaderLength]
+	if _, err := io.ReadFull(r, buf[v1MinLength:]); err != nil {
+		return nil, err
+	}
+	if buf[12]&v2VersionMask != Version2<<4 {
+		return nil, ErrUnknownVersion
+	}
+	h := &Header{
+		Version: Version2,
+		Command: Command(buf[12] & v2CommandMask),
+	}
+	if h.Command != Local && h.Command != Proxy {
+		return nil, ErrInvalidHeader
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(buf[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	family, transport := buf[13]>>4, buf[13]&0x0f
+	if transport > v2TransportDgram {
+		return nil, ErrInvalidHeader
+	}
+	var length int
+	switch family {
+	case v2FamilyUnspec:
+	case v2FamilyInet:
+		length = v2AddrLengthInet
+	case v2FamilyInet6:
+		length = v2AddrLengthInet6
+	case v2FamilyUnix:
+		length = v2AddrLengthUnix
+	default:
+		return nil, ErrInvalidHeader
+	}
+	if len(payload) < length {
+		return nil, ErrInvalidAddresses
+	}
+	// the addresses are ignored with the LOCAL command, but the TLVs are still parsed
+	if h.Command == Proxy && family != v2FamilyUnspec && transport != v2TransportUnspec {
+		h.SourceAddr, h.DestinationAddr = parseV2Addrs(family, transport, payload[:length])
+	}
+	tlvs, err := parseTLVs(payload[length:])
+	if err != nil {
+		return nil, err
+	}
+	h.TLVs = tlvs
+	return h, nil
+}
+
+func parseV2Addrs(family, transport byte, b []byte) (net.Addr, net.Addr) {
+	if family == v2FamilyUnix {
+		network := "unix"
+		if transport == v2TransportDgram {
+			network = "unixgram"
+		}
+		return &net.UnixAddr{Name: unixPath(b[:108]), Net: network},
+			&net.UnixAddr{Name: unixPath(b[108:]), Net: network}
+	}
+	size := net.IPv4len
+	if family == v2FamilyInet6 {
+		size = net.IPv6len
+	}
+	srcIP := net.IP(append([]byte(nil), b[:size]...))
+	dstIP := net.IP(append([]byte(nil), b[size:2*size]...))
+	srcPort := int(binary.BigEndian.Uint16(b[2*size:]))
+	dstPort := int(binary.BigEndian.Uint16(b[2*size+2:]))
+	if transport == v2TransportDgram {
+		return &net.UDPAddr{IP: srcIP, Port: srcPort}, &net.UDPAddr{IP: dstIP, Port: dstPort}
+	}
+	return &net.TCPAddr{IP: srcIP, Port: srcPort}, &net.TCPAddr{IP: dstIP, Port: dstPort}
+}
+
+func unixPath(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func parseTLVs(b []byte) ([]TLV, error) {
+	var tlvs []TLV
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, ErrInvalidHeader
+		}
+		length := int(binary.BigEndian.Uint16(b[1:3]))
+		if len(b) < 3+length {
+			return nil, ErrInvalidHeader
+		}
+		tlvs = append(tlvs, TLV{
+			Type:  b[0],
+			Value: append([]byte(nil), b[3:3+length]...),
+		})
+		b = b[3+length:]
+	}
+	return tlvs, nil
+}
+
+// TLV returns the value of the first TLV of the type
+func (h *Header) TLV(typ byte) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == typ {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Format encodes the header. Only the tcp addresses are supported, the header without
+// addresses is encoded as the v1 UNKNOWN header or the v2 LOCAL header
+func (h *Header) Format() ([]byte, error) {
+	switch h.Version {
+	case Version1:
+		return h.formatV1()
+	case Version2:
+		return h.formatV2()
+	}
+	return nil, ErrUnknownVersion
+}
+
+func (h *Header) tcpAddrs() (src, dst *net.TCPAddr, ipv4 bool, err error) {
+	if h.Command == Local || h.SourceAddr == nil || h.DestinationAddr == nil {
+		return nil, nil, false, nil
+	}
+	var ok bool
+	if src, ok = h.SourceAddr.(*net.TCPAddr); !ok {
+		return nil, nil, false, ErrInvalidAddresses
+	}
+	if dst, ok = h.DestinationAddr.(*net.TCPAddr); !ok {
+		return nil, nil, false, ErrInvalidAddresses
+	}
+	if src.IP.To16() == nil || dst.IP.To16() == nil {
+		return nil, nil, false, ErrInvalidAddresses
+	}
+	return src, dst, src.IP.To4() != nil && dst.IP.To4() != nil, nil
+}
+
+func (h *Header) formatV1() ([]byte, error) {
+	src, dst, ipv4, err := h.tcpAddrs()
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		return []byte("PROXY UNKNOWN\r\n"), nil
+	}
+	proto, srcIP, dstIP := "TCP4", src.IP.To4().String(), dst.IP.To4().String()
+	if !ipv4 {
+		// the ipv4 address is mapped into ipv6 when the families are mixed
+		proto, srcIP, dstIP = "TCP6", ipv6String(src.IP), ipv6String(dst.IP)
+	}
+	line := "PROXY " + proto + " " + srcIP + " " + dstIP + " " + strconv.Itoa(src.Port) + " " + strconv.Itoa(dst.Port) + "\r\n"
+	return []byte(line), nil
+}
+
+func ipv6String(ip net.IP) string {
+	if ip.To4() != nil {
+		return "::ffff:" + ip.To4().String()
+	}
+	return ip.String()
+}
+
+func (h *Header) formatV2() ([]byte, error) {
+	src, dst, ipv4, err := h.tcpAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var addrs []byte
+	command, family := Local, byte(v2FamilyUnspec<<4|v2TransportUnspec)
+	if src != nil {
+		command = Proxy
+		if ipv4 {
+			family = v2FamilyInet<<4 | v2TransportStream
+			addrs = append(addrs, src.IP.To4()...)
+			addrs = append(addrs, dst.IP.To4()...)
+		} else {
+			family = v2FamilyInet6<<4 | v2TransportStream
+			addrs = append(addrs, src.IP.To16()...)
+			addrs = append(addrs, dst.IP.To16()...)
+		}
+		addrs = append(addrs, byte(src.Port>>8), byte(src.Port), byte(dst.Port>>8), byte(dst.Port))
+	}
+	for _, tlv := range h.TLVs {
+		if len(tlv.Value) > 0xffff {
+			return nil, ErrInvalidHeader
+		}
+		addrs = append(addrs, tlv.Type, byte(len(tlv.Value)>>8), byte(len(tlv.Value)))
+		addrs = append(addrs, tlv.Value...)
+	}
+	if len(addrs) > 0xffff {
+		return nil, ErrInvalidHeader
+	}
+	buf := make([]byte, v2HeaderLength, v2HeaderLength+len(addrs))
+	copy(buf, v2Signature)
+	buf[12] = Version2<<4 | byte(command)
+	buf[13] = family
+	binary.BigEndian.PutUint16(buf[14:16], uint16(len(addrs)))
+	return append(buf, addrs...), nil
+}
@@ -0,0 +1,232 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func v2Header(command, family byte, payload []byte) []byte {
+	b := append([]byte(nil), v2Signature...)
+	b = append(b, Version2<<4|command, family, byte(len(payload)>>8), byte(len(payload)))
+	return append(b, payload...)
+}
+
+func TestReadHeaderV1(t *testing.T) {
+	testCases := []struct {
+		data string
+		src  string
+		dst  string
+	}{
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n", "192.168.0.1:56324", "192.168.0.11:443"},
+		{"PROXY TCP6 2001:db8::1 ::1 56324 443\r\n", "[2001:db8::1]:56324", "[::1]:443"},
+		{"PROXY UNKNOWN\r\n", "", ""},
+		{"PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n", "", ""},
+	}
+	for i, tc := range testCases {
+		r := bytes.NewReader([]byte(tc.data + "GET / HTTP/1.1\r\n"))
+		h, err := ReadHeader(r)
+		if err != nil {
+			t.Errorf("#%d read header failed: %v", i, err)
+			continue
+		}
+		if h.Version != Version1 || h.Command != Proxy {
+			t.Errorf("#%d unexpected header: %+v", i, h)
+		}
+		if tc.src == "" {
+			if h.SourceAddr != nil || h.DestinationAddr != nil {
+				t.Errorf("#%d expected no addresses, but got %v %v", i, h.SourceAddr, h.DestinationAddr)
+			}
+		} else if h.SourceAddr.String() != tc.src || h.DestinationAddr.String() != tc.dst {
+			t.Errorf("#%d expected %s %s, but got %v %v", i, tc.src, tc.dst, h.SourceAddr, h.DestinationAddr)
+		}
+		// the application data is not consumed
+		if rest, _ := ioutil.ReadAll(r); string(rest) != "GET / HTTP/1.1\r\n" {
+			t.Errorf("#%d unexpected remaining data: %q", i, rest)
+		}
+	}
+}
+
+func TestReadHeaderV1Invalid(t *testing.T) {
+	testCases := []struct {
+		data string
+		err  error
+	}{
+		{"GET / HTTP/1.1\r\nHost: a\r\n", ErrNoProxyProtocol},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n", ErrInvalidHeader},
+		{"PROXY UDP4 192.168.0.1 192.168.0.11 56324 443\r\n", ErrInvalidHeader},
+		{"PROXY TCP4 ::1 ::1 56324 443\r\n", ErrInvalidAddresses},
+		{"PROXY TCP6 127.0.0.1 127.0.0.1 56324 443\r\n", ErrInvalidAddresses},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 65536 443\r\n", ErrInvalidAddresses},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 0443 443\r\n", ErrInvalidAddresses},
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\n", ErrInvalidHeader},
+		{"PROXY UNKNOWN " + string(bytes.Repeat([]byte("a"), 100)) + "\r\n", ErrHeaderTooLong},
+	}
+	for i, tc := range testCases {
+		if _, err := ReadHeader(bytes.NewReader([]byte(tc.data))); err != tc.err {
+			t.Errorf("#%d expected error %v, but got %v", i, tc.err, err)
+		}
+	}
+}
+
+func TestReadHeaderV2(t *testing.T) {
+	inet := []byte{10, 0, 0, 1, 10, 0, 0, 2, 0xdc, 0x04, 0x01, 0xbb}
+	inet6 := append(append(net.ParseIP("2001:db8::1").To16(), net.ParseIP("2001:db8::2").To16()...), 0xdc, 0x04, 0x01, 0xbb)
+	unix := make([]byte, v2AddrLengthUnix)
+	copy(unix, "/var/run/src.sock")
+	copy(unix[108:], "/var/run/dst.sock")
+	tlvs := []byte{TLVTypeAuthority, 0x00, 0x0b}
+	tlvs = append(tlvs, "example.com"...)
+	tlvs = append(tlvs, TLVTypeNoop, 0x00, 0x00)
+
+	testCases := []struct {
+		data    []byte
+		command Command
+		src     string
+		dst     string
+		tlvs    int
+	}{
+		{v2Header(0x1, 0x11, inet), Proxy, "10.0.0.1:56324", "10.0.0.2:443", 0},
+		{v2Header(0x1, 0x12, inet), Proxy, "10.0.0.1:56324", "10.0.0.2:443", 0},
+		{v2Header(0x1, 0x21, append(inet6, tlvs...)), Proxy, "[2001:db8::1]:56324", "[2001:db8::2]:443", 2},
+		{v2Header(0x1, 0x31, unix), Proxy, "/var/run/src.sock", "/var/run/dst.sock", 0},
+		{v2Header(0x0, 0x11, append(inet, tlvs...)), Local, "", "", 2},
+		{v2Header(0x0, 0x00, nil), Local, "", "", 0},
+		{v2Header(0x1, 0x00, tlvs), Proxy, "", "", 2},
+	}
+	for i, tc := range testCases {
+		r := bytes.NewReader(append(tc.data, "data"...))
+		h, err := ReadHeader(r)
+		if err != nil {
+			t.Errorf("#%d read header failed: %v", i, err)
+			continue
+		}
+		if h.Version != Version2 || h.Command != tc.command || len(h.TLVs) != tc.tlvs {
+			t.Errorf("#%d unexpected header: %+v", i, h)
+		}
+		if tc.src == "" {
+			if h.SourceAddr != nil || h.DestinationAddr != nil {
+				t.Errorf("#%d expected no addresses, but got %v %v", i, h.SourceAddr, h.DestinationAddr)
+			}
+		} else if h.SourceAddr.String() != tc.src || h.DestinationAddr.String() != tc.dst {
+			t.Errorf("#%d expected %s %s, but got %v %v", i, tc.src, tc.dst, h.SourceAddr, h.DestinationAddr)
+		}
+		if tc.tlvs > 0 {
+			if v, ok := h.TLV(TLVTypeAuthority); !ok || string(v) != "example.com" {
+				t.Errorf("#%d unexpected authority tlv: %q", i, v)
+			}
+		}
+		if rest, _ := ioutil.ReadAll(r); string(rest) != "data" {
+			t.Errorf("#%d unexpected remaining data: %q", i, rest)
+		}
+	}
+	// the transport of the udp addresses is kept
+	h, _ := ReadHeader(bytes.NewReader(v2Header(0x1, 0x12, inet)))
+	if _, ok := h.SourceAddr.(*net.UDPAddr); !ok {
+		t.Errorf("expected udp address, but got %T", h.SourceAddr)
+	}
+}
+
+func TestReadHeaderV2Invalid(t *testing.T) {
+	inet := []byte{10, 0, 0, 1, 10, 0, 0, 2, 0xdc, 0x04, 0x01, 0xbb}
+	badVersion := v2Header(0x1, 0x11, inet)
+	badVersion[12] = 0x11
+	truncated := v2Header(0x1, 0x11, inet)
+	binary.BigEndian.PutUint16(truncated[14:16], 20)
+	testCases := []struct {
+		data []byte
+		err  error
+	}{
+		{badVersion, ErrUnknownVersion},
+		{v2Header(0x2, 0x11, inet), ErrInvalidHeader},
+		{v2Header(0x1, 0x41, inet), ErrInvalidHeader},
+		{v2Header(0x1, 0x13, inet), ErrInvalidHeader},
+		{v2Header(0x1, 0x21, inet), ErrInvalidAddresses},
+		{v2Header(0x1, 0x11, append(inet, TLVTypeNoop, 0x00, 0x05, 0x00)), ErrInvalidHeader},
+	}
+	for i, tc := range testCases {
+		if _, err := ReadHeader(bytes.NewReader(tc.data)); err != tc.err {
+			t.Errorf("#%d expected error %v, but got %v", i, tc.err, err)
+		}
+	}
+	if _, err := ReadHeader(bytes.NewReader(truncated)); err == nil {
+		t.Error("expected error of the truncated header")
+	}
+}
+
+func TestFormatHeader(t *testing.T) {
+	src4 := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	dst4 := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+	src6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324}
+	testCases := []struct {
+		header *Header
+		v1     string
+		src    string
+		dst    string
+	}{
+		{NewHeader(Version1, src4, dst4), "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n", "192.168.0.1:56324", "192.168.0.11:443"},
+		{NewHeader(Version1, src6, dst4), "PROXY TCP6 2001:db8::1 ::ffff:192.168.0.11 56324 443\r\n", "[2001:db8::1]:56324", "192.168.0.11:443"},
+		{NewHeader(Version1, nil, dst4), "PROXY UNKNOWN\r\n", "", ""},
+	}
+	for i, tc := range testCases {
+		b, err := tc.header.Format()
+		if err != nil || string(b) != tc.v1 {
+			t.Errorf("#%d expected %q, but got %q %v", i, tc.v1, b, err)
+		}
+		// format as v2 and read it back
+		tc.header.Version = Version2
+		tc.header.TLVs = []TLV{{Type: TLVTypeUniqueID, Value: []byte("id")}}
+		b, err = tc.header.Format()
+		if err != nil {
+			t.Errorf("#%d format v2 failed: %v", i, err)
+			continue
+		}
+		h, err := ReadHeader(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("#%d read v2 failed: %v", i, err)
+			continue
+		}
+		if tc.src == "" {
+			if h.Command != Local || h.SourceAddr != nil {
+				t.Errorf("#%d expected LOCAL header, but got %+v", i, h)
+			}
+		} else if h.Command != Proxy || h.SourceAddr.(*net.TCPAddr).IP.String() != hostOf(tc.src) {
+			t.Errorf("#%d unexpected header %+v", i, h)
+		} else if port := h.DestinationAddr.(*net.TCPAddr).Port; port != 443 {
+			t.Errorf("#%d unexpected destination port %d", i, port)
+		}
+		if v, ok := h.TLV(TLVTypeUniqueID); !ok || string(v) != "id" {
+			t.Errorf("#%d unexpected unique id tlv: %q", i, v)
+		}
+	}
+	if _, err := NewHeader(3, src4, dst4).Format(); err != ErrUnknownVersion {
+		t.Errorf("expected unknown version error, but got %v", err)
+	}
+	if _, err := NewHeader(Version2, &net.UnixAddr{Name: "/a"}, dst4).Format(); err != ErrInvalidAddresses {
+		t.Errorf("expected invalid addresses error, but got %v", err)
+	}
+}
+
+func hostOf(addr string) string {
+	host, _, _ := net.SplitHostPort(addr)
+	return host
+}
@@ -0,0 +1,69 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+// ProxyProtocol filter reads the PROXY protocol header sent by the L4 load balancers,
+// and replaces the remote address of the connection with the real client address
+
+type proxyProtocol struct {
+	timeout time.Duration
+}
+
+// NewProxyProtocol new a proxy protocol filter
+func NewProxyProtocol() ProxyProtocol {
+	return &proxyProtocol{
+		timeout: DefaultTimeout,
+	}
+}
+
+// OnAccept called when connection accept
+func (filter *proxyProtocol) OnAccept(cb types.ListenerFilterCallbacks) types.FilterStatus {
+	conn := cb.Conn()
+	if err := conn.SetReadDeadline(time.Now().Add(filter.timeout)); err != nil {
+		log.DefaultLogger.Errorf("[proxyprotocol] set read deadline failed: %v", err)
+		conn.Close()
+		return types.Stop
+	}
+	header, err := ReadHeader(conn)
+	if err != nil {
+		log.DefaultLogger.Errorf("[proxyprotocol] read header from %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return types.Stop
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		log.DefaultLogger.Errorf("[proxyprotocol] reset read deadline failed: %v", err)
+		conn.Close()
+		return types.Stop
+	}
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[proxyprotocol] received v%d header from %s, source: %v, destination: %v, tlvs: %d",
+			header.Version, conn.RemoteAddr(), header.SourceAddr, header.DestinationAddr, len(header.TLVs))
+	}
+	// the addresses of the LOCAL command are ignored, the connection is kept as is
+	if header.Command == Proxy && header.SourceAddr != nil {
+		cb.SetRemoteAddr(header.SourceAddr)
+	}
+	return types.Continue
+}
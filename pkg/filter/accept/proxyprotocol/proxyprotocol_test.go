@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockListenerFilterCallbacks struct {
+	conn       net.Conn
+	remoteAddr net.Addr
+}
+
+func (cb *mockListenerFilterCallbacks) Conn() net.Conn {
+	return cb.conn
+}
+
+func (cb *mockListenerFilterCallbacks) ContinueFilterChain(ctx context.Context, success bool) {}
+
+func (cb *mockListenerFilterCallbacks) SetOriginalAddr(ip string, port int) {}
+
+func (cb *mockListenerFilterCallbacks) SetRemoteAddr(addr net.Addr) {
+	cb.remoteAddr = addr
+}
+
+func TestProxyProtocolOnAccept(t *testing.T) {
+	testCases := []struct {
+		header string
+		status types.FilterStatus
+		remote string
+	}{
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n", types.Continue, "192.168.0.1:56324"},
+		{"PROXY UNKNOWN\r\n", types.Continue, ""},
+		{string(v2Header(0x0, 0x00, nil)), types.Continue, ""},
+		{"GET / HTTP/1.1\r\nHost: a\r\n", types.Stop, ""},
+	}
+	for i, tc := range testCases {
+		client, server := net.Pipe()
+		go func() {
+			client.Write([]byte(tc.header + "ping"))
+		}()
+		cb := &mockListenerFilterCallbacks{conn: server}
+		if status := NewProxyProtocol().OnAccept(cb); status != tc.status {
+			t.Errorf("#%d expected status %s, but got %s", i, tc.status, status)
+		}
+		if tc.remote == "" {
+			if cb.remoteAddr != nil {
+				t.Errorf("#%d expected remote addr not set, but got %v", i, cb.remoteAddr)
+			}
+		} else if cb.remoteAddr == nil || cb.remoteAddr.String() != tc.remote {
+			t.Errorf("#%d expected remote addr %s, but got %v", i, tc.remote, cb.remoteAddr)
+		}
+		if tc.status == types.Continue {
+			// the data after the header is kept for the connection
+			b := make([]byte, 4)
+			if _, err := io.ReadFull(server, b); err != nil || string(b) != "ping" {
+				t.Errorf("#%d expected data ping, but got %q %v", i, b, err)
+			}
+		} else if _, err := server.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+			t.Errorf("#%d expected connection closed, but got %v", i, err)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestProxyProtocolTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	filter := &proxyProtocol{timeout: 50 * time.Millisecond}
+	cb := &mockListenerFilterCallbacks{conn: server}
+	if status := filter.OnAccept(cb); status != types.Stop {
+		t.Errorf("expected status Stop, but got %s", status)
+	}
+	if cb.remoteAddr != nil {
+		t.Errorf("expected remote addr not set, but got %v", cb.remoteAddr)
+	}
+}
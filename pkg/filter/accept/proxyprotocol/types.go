@@ -0,0 +1,90 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxyprotocol
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// ProxyProtocol interface contains just one method: OnAccept
+type ProxyProtocol interface {
+	OnAccept(cb types.ListenerFilterCallbacks) types.FilterStatus
+}
+
+// PROXY protocol versions
+const (
+	Version1 = 1
+	Version2 = 2
+)
+
+// Command is the command of a PROXY protocol header
+type Command byte
+
+// Commands
+const (
+	// Local means the connection was established on purpose by the proxy without being relayed,
+	// the addresses in the header should be ignored
+	Local Command = 0x0
+	// Proxy means the connection was established on behalf of another node
+	Proxy Command = 0x1
+)
+
+// TLV types defined by the PROXY protocol specification
+const (
+	TLVTypeALPN      = 0x01
+	TLVTypeAuthority = 0x02
+	TLVTypeCRC32C    = 0x03
+	TLVTypeNoop      = 0x04
+	TLVTypeUniqueID  = 0x05
+	TLVTypeSSL       = 0x20
+	TLVTypeNetNS     = 0x30
+)
+
+// DefaultTimeout is the max duration to wait for the PROXY protocol header
+const DefaultTimeout = 5 * time.Second
+
+// TLV is a type-length-value vector carried by the PROXY protocol v2 header
+type TLV struct {
+	Type  byte
+	Value []byte
+}
+
+// Header is the PROXY protocol header
+type Header struct {
+	Version int
+	Command Command
+	// SourceAddr and DestinationAddr are nil if the header carries no addresses, for example
+	// the v1 UNKNOWN header, the v2 LOCAL command or the v2 UNSPEC family
+	SourceAddr      net.Addr
+	DestinationAddr net.Addr
+	// TLVs only exists in the v2 header
+	TLVs []TLV
+}
+
+// Errors
+var (
+	ErrNoProxyProtocol  = errors.New("proxy protocol signature not present")
+	ErrInvalidHeader    = errors.New("invalid proxy protocol header")
+	ErrHeaderTooLong    = errors.New("proxy protocol v1 header too long")
+	ErrUnknownVersion   = errors.New("unknown proxy protocol version")
+	ErrInvalidAddresses = errors.New("invalid proxy protocol addresses")
+)
@@ -0,0 +1,227 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+// minRefreshInterval limits the frequency of the slots refreshing
+const minRefreshInterval = time.Second
+
+var errNoHost = errors.New("no upstream host")
+
+// router chooses the upstream address of a key
+type router interface {
+	// route returns the upstream address of the key and the shard of the key,
+	// the keys of a multi-key command in the same shard are sent in one command
+	route(snapshot types.ClusterSnapshot, key []byte) (address string, shard string, err error)
+}
+
+// hashRouter routes the keys by a consistent hash ring of the healthy hosts
+type hashRouter struct {
+	ring atomic.Value // *hashRing
+}
+
+func (r *hashRouter) route(snapshot types.ClusterSnapshot, key []byte) (string, string, error) {
+	hosts := snapshot.HostSet().HealthyHosts()
+	if len(hosts) == 0 {
+		return "", "", errNoHost
+	}
+	// the ring is rebuilt when the healthy hosts changed
+	k := hostsKey(hosts)
+	ring, _ := r.ring.Load().(*hashRing)
+	if ring == nil || ring.hostsKey != k {
+		ring = newHashRing(k, hosts)
+		r.ring.Store(ring)
+	}
+	address := ring.get(key).AddressString()
+	return address, address, nil
+}
+
+// slotRouter routes the keys by the slot map of a redis cluster. The slot map is discovered
+// by CLUSTER SLOTS from the cluster hosts, and is updated by the MOVED replies.
+// Before the slot map is discovered, the keys are sent to the cluster hosts, which reply MOVED
+// if the slot is served by another node.
+type slotRouter struct {
+	pool  *clientPool
+	stats *proxyStats
+
+	mutex sync.RWMutex
+	slots []string
+
+	refreshing  uint32
+	lastRefresh int64
+}
+
+func newSlotRouter(pool *clientPool, stats *proxyStats) *slotRouter {
+	return &slotRouter{
+		pool:  pool,
+		stats: stats,
+		slots: make([]string, SlotCount),
+	}
+}
+
+func (r *slotRouter) route(snapshot types.ClusterSnapshot, key []byte) (string, string, error) {
+	slot := Slot(key)
+	r.mutex.RLock()
+	address := r.slots[slot]
+	r.mutex.RUnlock()
+	if address == "" {
+		hosts := snapshot.HostSet().HealthyHosts()
+		if len(hosts) == 0 {
+			return "", "", errNoHost
+		}
+		address = hosts[slot%len(hosts)].AddressString()
+		r.refresh(snapshot)
+	}
+	return address, strconv.Itoa(slot), nil
+}
+
+// moved updates the slot map by a MOVED reply, and refreshes the whole slot map in background
+func (r *slotRouter) moved(snapshot types.ClusterSnapshot, slot int, address string) {
+	r.mutex.Lock()
+	r.slots[slot] = address
+	r.mutex.Unlock()
+	r.refresh(snapshot)
+}
+
+// refresh discovers the slot map by CLUSTER SLOTS, the seeds are tried in turn until one succeeds
+func (r *slotRouter) refresh(snapshot types.ClusterSnapshot) {
+	if time.Since(time.Unix(0, atomic.LoadInt64(&r.lastRefresh))) < minRefreshInterval {
+		return
+	}
+	if !atomic.CompareAndSwapUint32(&r.refreshing, 0, 1) {
+		return
+	}
+	atomic.StoreInt64(&r.lastRefresh, time.Now().UnixNano())
+
+	var seeds []string
+	for _, host := range snapshot.HostSet().HealthyHosts() {
+		seeds = append(seeds, host.AddressString())
+	}
+	r.refreshFrom(seeds, snapshot.ClusterInfo().ConnectTimeout())
+}
+
+func (r *slotRouter) refreshFrom(seeds []string, connectTimeout time.Duration) {
+	if len(seeds) == 0 {
+		log.DefaultLogger.Errorf("[redisproxy] refresh slots failed: no seed is available")
+		r.stats.SlotsRefreshFailed.Inc(1)
+		atomic.StoreUint32(&r.refreshing, 0)
+		return
+	}
+	seed := seeds[0]
+	client, err := r.pool.get(seed, 2, connectTimeout)
+	if err != nil {
+		log.DefaultLogger.Warnf("[redisproxy] refresh slots from %s failed: %v", seed, err)
+		r.refreshFrom(seeds[1:], connectTimeout)
+		return
+	}
+	client.send(NewCommand([]byte("CLUSTER"), []byte("SLOTS")), func(reply *Value) {
+		slots, err := parseClusterSlots(reply, seed)
+		if err != nil {
+			log.DefaultLogger.Warnf("[redisproxy] refresh slots from %s failed: %v", seed, err)
+			r.refreshFrom(seeds[1:], connectTimeout)
+			return
+		}
+		r.mutex.Lock()
+		r.slots = slots
+		r.mutex.Unlock()
+		r.stats.SlotsRefreshSuccess.Inc(1)
+		atomic.StoreUint32(&r.refreshing, 0)
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[redisproxy] refresh slots from %s: %s", seed, reply)
+		}
+	}, false)
+}
+
+// parseClusterSlots parses the reply of CLUSTER SLOTS into the master address of each slot,
+// each element of the reply is [start slot, end slot, [ip, port, ...], replicas...]
+func parseClusterSlots(reply *Value, seed string) ([]string, error) {
+	if reply.IsError() {
+		return nil, errors.New(string(reply.Str))
+	}
+	if reply.Type != TypeArray || len(reply.Elems) == 0 {
+		return nil, errors.New("no slot is served")
+	}
+	seedHost, _, _ := net.SplitHostPort(seed)
+	slots := make([]string, SlotCount)
+	for _, e := range reply.Elems {
+		if e.Type != TypeArray || len(e.Elems) < 3 {
+			return nil, errors.New("invalid cluster slots reply")
+		}
+		start, end, master := e.Elems[0], e.Elems[1], e.Elems[2]
+		if start.Type != TypeInteger || end.Type != TypeInteger || start.Int < 0 || end.Int >= SlotCount || start.Int > end.Int {
+			return nil, errors.New("invalid slot range in cluster slots reply")
+		}
+		if master.Type != TypeArray || len(master.Elems) < 2 || master.Elems[1].Type != TypeInteger {
+			return nil, errors.New("invalid node in cluster slots reply")
+		}
+		ip := string(master.Elems[0].Str)
+		if ip == "" || ip == "?" {
+			// the node does not know its own ip
+			ip = seedHost
+		}
+		address := net.JoinHostPort(ip, strconv.FormatInt(master.Elems[1].Int, 10))
+		for slot := start.Int; slot <= end.Int; slot++ {
+			slots[slot] = address
+		}
+	}
+	return slots, nil
+}
+
+// redirection is a MOVED or ASK reply of redis cluster, such as "MOVED 3999 127.0.0.1:6381"
+type redirection struct {
+	ask     bool
+	slot    int
+	address string
+}
+
+func parseRedirection(reply *Value) (*redirection, bool) {
+	if reply.Type != TypeError {
+		return nil, false
+	}
+	fields := strings.Fields(string(reply.Str))
+	if len(fields) != 3 {
+		return nil, false
+	}
+	r := &redirection{}
+	switch fields[0] {
+	case "MOVED":
+	case "ASK":
+		r.ask = true
+	default:
+		return nil, false
+	}
+	slot, err := strconv.Atoi(fields[1])
+	if err != nil || slot < 0 || slot >= SlotCount {
+		return nil, false
+	}
+	r.slot = slot
+	r.address = fields[2]
+	return r, true
+}
@@ -0,0 +1,122 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"fmt"
+	"testing"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestSlot(t *testing.T) {
+	cases := map[string]int{
+		"":              0,
+		"foo":           12182,
+		"bar":           5061,
+		"123456789":     12739,
+		"{user1000}.a":  Slot([]byte("user1000")),
+		"foo{}{bar}":    Slot([]byte("foo{}{bar}")),
+		"foo{{bar}}zap": Slot([]byte("{bar")),
+		"foo{bar}{zap}": Slot([]byte("bar")),
+	}
+	for key, slot := range cases {
+		if got := Slot([]byte(key)); got != slot {
+			t.Errorf("slot of %q expected %d, but got %d", key, slot, got)
+		}
+	}
+}
+
+func TestHashRing(t *testing.T) {
+	var hosts []types.Host
+	for i := 0; i < 4; i++ {
+		hosts = append(hosts, &mockHost{address: fmt.Sprintf("127.0.0.1:%d", 6379+i)})
+	}
+	ring := newHashRing(hostsKey(hosts), hosts)
+	counts := map[string]int{}
+	mapping := map[string]string{}
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		address := ring.get([]byte(key)).AddressString()
+		counts[address]++
+		mapping[key] = address
+	}
+	for address, count := range counts {
+		if count < 1500 || count > 3500 {
+			t.Errorf("host %s got %d keys of 10000", address, count)
+		}
+	}
+	if ring.get([]byte("{tag}a")) != ring.get([]byte("{tag}b")) {
+		t.Errorf("the keys with the same hash tag are routed to different hosts")
+	}
+	// removing a host only moves the keys of the host
+	ring = newHashRing(hostsKey(hosts[1:]), hosts[1:])
+	for key, address := range mapping {
+		if address != hosts[0].AddressString() && ring.get([]byte(key)).AddressString() != address {
+			t.Fatalf("key %s is moved from %s", key, address)
+		}
+	}
+}
+
+func TestParseClusterSlots(t *testing.T) {
+	node := func(ip string, port int64) *Value {
+		return NewArray([]*Value{NewBulkString([]byte(ip)), NewInteger(port), NewBulkString([]byte("id"))})
+	}
+	reply := NewArray([]*Value{
+		NewArray([]*Value{NewInteger(0), NewInteger(8191), node("10.0.0.1", 7000), node("10.0.0.2", 7001)}),
+		NewArray([]*Value{NewInteger(8192), NewInteger(16383), node("", 7002)}),
+	})
+	slots, err := parseClusterSlots(reply, "10.0.0.3:7002")
+	if err != nil {
+		t.Fatalf("parse cluster slots failed: %v", err)
+	}
+	if slots[0] != "10.0.0.1:7000" || slots[8191] != "10.0.0.1:7000" || slots[8192] != "10.0.0.3:7002" || slots[16383] != "10.0.0.3:7002" {
+		t.Errorf("parse cluster slots got unexpected slots")
+	}
+	invalid := []*Value{
+		NewError("ERR This instance has cluster support disabled"),
+		NewArray(nil),
+		NewArray([]*Value{NewArray([]*Value{NewInteger(0), NewInteger(16384), node("10.0.0.1", 7000)})}),
+		NewArray([]*Value{NewArray([]*Value{NewInteger(0), NewInteger(1)})}),
+	}
+	for _, r := range invalid {
+		if _, err := parseClusterSlots(r, "10.0.0.3:7002"); err == nil {
+			t.Errorf("parse cluster slots %s expected error", r)
+		}
+	}
+}
+
+func TestParseRedirection(t *testing.T) {
+	r, ok := parseRedirection(NewError("MOVED 3999 127.0.0.1:6381"))
+	if !ok || r.ask || r.slot != 3999 || r.address != "127.0.0.1:6381" {
+		t.Errorf("parse MOVED got %+v %v", r, ok)
+	}
+	r, ok = parseRedirection(NewError("ASK 3999 127.0.0.1:6381"))
+	if !ok || !r.ask {
+		t.Errorf("parse ASK got %+v %v", r, ok)
+	}
+	for _, v := range []*Value{
+		NewError("ERR MOVED"),
+		NewError("MOVED 16384 127.0.0.1:6381"),
+		NewSimpleString("MOVED 3999 127.0.0.1:6381"),
+	} {
+		if _, ok := parseRedirection(v); ok {
+			t.Errorf("parse %s expected not a redirection", v)
+		}
+	}
+}
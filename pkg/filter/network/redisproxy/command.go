@@ -0,0 +1,124 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+type commandKind int
+
+const (
+	// keyCommand is routed by the key, which is the first argument
+	keyCommand commandKind = iota
+	// evalCommand is routed by the first key after the numkeys
+	evalCommand
+	// mgetCommand is split by the keys, and the replies are merged into an array in the order of the keys
+	mgetCommand
+	// msetCommand is split by the key value pairs, and replies OK if all the splits succeed
+	msetCommand
+	// sumCommand is split by the keys, and the integer replies are summed
+	sumCommand
+	// localCommand is replied by the proxy
+	localCommand
+)
+
+// command describes a supported redis command
+type command struct {
+	name string
+	kind commandKind
+	// arity is the number of the arguments includes the command name, a negative arity means at least -arity
+	arity int
+}
+
+func (c *command) checkArity(n int) bool {
+	if c.arity < 0 {
+		return n >= -c.arity
+	}
+	return n == c.arity
+}
+
+// commands are the supported commands
+var commands = map[string]*command{}
+
+func registerCommands(kind commandKind, arity int, names ...string) {
+	for _, name := range names {
+		commands[name] = &command{
+			name:  name,
+			kind:  kind,
+			arity: arity,
+		}
+	}
+}
+
+func init() {
+	// the commands that have a single key as the first argument
+	registerCommands(keyCommand, -2,
+		// strings
+		"GET", "SET", "SETNX", "SETEX", "PSETEX", "GETSET", "GETDEL", "GETEX", "APPEND", "STRLEN",
+		"INCR", "INCRBY", "INCRBYFLOAT", "DECR", "DECRBY", "GETRANGE", "SETRANGE", "SUBSTR",
+		"GETBIT", "SETBIT", "BITCOUNT", "BITPOS", "BITFIELD",
+		// keys
+		"EXPIRE", "EXPIREAT", "PEXPIRE", "PEXPIREAT", "PERSIST", "TTL", "PTTL", "TYPE", "DUMP", "RESTORE",
+		// hashes
+		"HGET", "HSET", "HSETNX", "HMGET", "HMSET", "HDEL", "HEXISTS", "HGETALL", "HKEYS", "HVALS",
+		"HLEN", "HINCRBY", "HINCRBYFLOAT", "HSTRLEN", "HSCAN", "HRANDFIELD",
+		// lists
+		"LPUSH", "RPUSH", "LPUSHX", "RPUSHX", "LPOP", "RPOP", "LLEN", "LRANGE", "LINDEX", "LSET",
+		"LREM", "LTRIM", "LINSERT", "LPOS",
+		// sets
+		"SADD", "SREM", "SMEMBERS", "SISMEMBER", "SMISMEMBER", "SCARD", "SPOP", "SRANDMEMBER", "SSCAN",
+		// sorted sets
+		"ZADD", "ZREM", "ZSCORE", "ZMSCORE", "ZINCRBY", "ZCARD", "ZCOUNT", "ZRANGE", "ZRANGEBYSCORE",
+		"ZREVRANGE", "ZREVRANGEBYSCORE", "ZRANK", "ZREVRANK", "ZREMRANGEBYRANK", "ZREMRANGEBYSCORE",
+		"ZLEXCOUNT", "ZRANGEBYLEX", "ZREVRANGEBYLEX", "ZREMRANGEBYLEX", "ZSCAN", "ZPOPMIN", "ZPOPMAX",
+		"ZRANDMEMBER",
+		// hyperloglog and geo
+		"PFADD", "GEOADD", "GEODIST", "GEOHASH", "GEOPOS", "GEOSEARCH",
+	)
+	registerCommands(evalCommand, -3, "EVAL", "EVALSHA")
+	registerCommands(mgetCommand, -2, "MGET")
+	registerCommands(msetCommand, -3, "MSET")
+	registerCommands(sumCommand, -2, "DEL", "UNLINK", "EXISTS", "TOUCH")
+	registerCommands(localCommand, -1, "PING", "ECHO", "HELLO", "QUIT")
+}
+
+// commandName returns the upper case name of a command
+func commandName(cmd *Value) string {
+	if len(cmd.Elems) == 0 {
+		return ""
+	}
+	return strings.ToUpper(string(cmd.Elems[0].Str))
+}
+
+// parseAllowedCommands returns the set of the allowed commands, nil means all the supported commands are allowed
+func parseAllowedCommands(names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		if _, ok := commands[name]; !ok {
+			return nil, fmt.Errorf("redis command %s is not supported", name)
+		}
+		allowed[name] = true
+	}
+	return allowed, nil
+}
@@ -0,0 +1,56 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"context"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/config"
+	"mosn.io/mosn/pkg/filter"
+	"mosn.io/mosn/pkg/types"
+)
+
+func init() {
+	filter.RegisterNetwork(v2.REDIS_PROXY, CreateRedisProxyFactory)
+}
+
+type redisProxyFilterConfigFactory struct {
+	config *proxyConfig
+}
+
+func (f *redisProxyFilterConfigFactory) CreateFilterChain(context context.Context, clusterManager types.ClusterManager, callbacks types.NetWorkFilterChainFactoryCallbacks) {
+	rf := newSession(f.config, clusterManager)
+	callbacks.AddReadFilter(rf)
+}
+
+// CreateRedisProxyFactory creates the redis proxy filter factory, the sessions created by
+// a factory share the upstream connections, the slot map and the stats
+func CreateRedisProxyFactory(conf map[string]interface{}) (types.NetworkFilterChainFactory, error) {
+	p, err := config.ParseRedisProxy(conf)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := newProxyConfig(p)
+	if err != nil {
+		return nil, err
+	}
+	return &redisProxyFilterConfigFactory{
+		config: pc,
+	}, nil
+}
@@ -0,0 +1,146 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// SlotCount is the number of the hash slots of a redis cluster
+const SlotCount = 16384
+
+// virtualNodes is the number of the points of a host on the hash ring
+const virtualNodes = 160
+
+// hashTag returns the part of the key to be hashed, if the key contains a non-empty {...},
+// only the content between the first { and the following } is hashed, so the keys with
+// the same hash tag are routed to the same host, see https://redis.io/topics/cluster-spec
+func hashTag(key []byte) []byte {
+	start := bytes.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := bytes.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+// Slot returns the hash slot of the key in a redis cluster
+func Slot(key []byte) int {
+	return int(crc16(hashTag(key)) & (SlotCount - 1))
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) used by the redis cluster
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^b]
+	}
+	return crc
+}
+
+var crc16Table = func() [256]uint16 {
+	var table [256]uint16
+	for i := 0; i < 256; i++ {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+type ringPoint struct {
+	hash uint64
+	host types.Host
+}
+
+// hashRing is a consistent hash ring of the hosts, each host has virtualNodes points on the ring
+type hashRing struct {
+	// hostsKey identifies the hosts the ring is built from
+	hostsKey string
+	points   []ringPoint
+}
+
+func newHashRing(hostsKey string, hosts []types.Host) *hashRing {
+	r := &hashRing{
+		hostsKey: hostsKey,
+		points:   make([]ringPoint, 0, len(hosts)*virtualNodes),
+	}
+	for _, host := range hosts {
+		address := host.AddressString()
+		for i := 0; i < virtualNodes; i++ {
+			r.points = append(r.points, ringPoint{
+				hash: hash64([]byte(address + "-" + strconv.Itoa(i))),
+				host: host,
+			})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r
+}
+
+// get returns the host of the key, which is the first point not less than the hash of the key
+func (r *hashRing) get(key []byte) types.Host {
+	if len(r.points) == 0 {
+		return nil
+	}
+	h := hash64(hashTag(key))
+	idx := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if idx == len(r.points) {
+		idx = 0
+	}
+	return r.points[idx].host
+}
+
+func hash64(data []byte) uint64 {
+	sum := md5.Sum(data)
+	return binary.LittleEndian.Uint64(sum[:8])
+}
+
+// hostsKey returns a key identifies the addresses of the hosts
+func hostsKey(hosts []types.Host) string {
+	addresses := make([]string, len(hosts))
+	for i, host := range hosts {
+		addresses[i] = host.AddressString()
+	}
+	sort.Strings(addresses)
+	var buf bytes.Buffer
+	for _, address := range addresses {
+		buf.WriteString(address)
+		buf.WriteByte(',')
+	}
+	return buf.String()
+}
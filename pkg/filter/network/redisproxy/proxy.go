@@ -0,0 +1,592 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	// DefaultOpTimeout is the timeout of a command if it is not configured
+	DefaultOpTimeout = 5 * time.Second
+	// DefaultMaxRedirections is the max redirections of a command if it is not configured
+	DefaultMaxRedirections = 3
+)
+
+type proxyStats struct {
+	CommandTotal        gometrics.Counter
+	CommandSuccess      gometrics.Counter
+	CommandError        gometrics.Counter
+	CommandTime         gometrics.Histogram
+	CommandUnsupported  gometrics.Counter
+	CommandNotAllowed   gometrics.Counter
+	CommandTimeout      gometrics.Counter
+	UpstreamMoved       gometrics.Counter
+	UpstreamAsk         gometrics.Counter
+	UpstreamFailed      gometrics.Counter
+	SlotsRefreshSuccess gometrics.Counter
+	SlotsRefreshFailed  gometrics.Counter
+}
+
+func newProxyStats(statPrefix string) *proxyStats {
+	s := metrics.NewRedisProxyStats(statPrefix)
+	return &proxyStats{
+		CommandTotal:        s.Counter(metrics.RedisCommandTotal),
+		CommandSuccess:      s.Counter(metrics.RedisCommandSuccess),
+		CommandError:        s.Counter(metrics.RedisCommandError),
+		CommandTime:         s.Histogram(metrics.RedisCommandTime),
+		CommandUnsupported:  s.Counter(metrics.RedisCommandUnsupported),
+		CommandNotAllowed:   s.Counter(metrics.RedisCommandNotAllowed),
+		CommandTimeout:      s.Counter(metrics.RedisCommandTimeout),
+		UpstreamMoved:       s.Counter(metrics.RedisUpstreamMoved),
+		UpstreamAsk:         s.Counter(metrics.RedisUpstreamAsk),
+		UpstreamFailed:      s.Counter(metrics.RedisUpstreamFailed),
+		SlotsRefreshSuccess: s.Counter(metrics.RedisSlotsRefreshSuccess),
+		SlotsRefreshFailed:  s.Counter(metrics.RedisSlotsRefreshFailed),
+	}
+}
+
+// commandStats is the stats of a command
+type commandStats struct {
+	Total   gometrics.Counter
+	Success gometrics.Counter
+	Error   gometrics.Counter
+	Time    gometrics.Histogram
+}
+
+func newCommandStats(statPrefix, command string) *commandStats {
+	s := metrics.NewRedisCommandStats(statPrefix, command)
+	return &commandStats{
+		Total:   s.Counter(metrics.RedisCommandTotal),
+		Success: s.Counter(metrics.RedisCommandSuccess),
+		Error:   s.Counter(metrics.RedisCommandError),
+		Time:    s.Histogram(metrics.RedisCommandTime),
+	}
+}
+
+// proxyConfig is shared by the sessions of a redis proxy
+type proxyConfig struct {
+	cluster         string
+	statPrefix      string
+	redisCluster    bool
+	allowed         map[string]bool
+	opTimeout       time.Duration
+	maxRedirections int
+	pool            *clientPool
+	router          router
+	stats           *proxyStats
+	commandStats    sync.Map // command name -> *commandStats
+}
+
+func newProxyConfig(config *v2.RedisProxy) (*proxyConfig, error) {
+	allowed, err := parseAllowedCommands(config.AllowedCommands)
+	if err != nil {
+		return nil, err
+	}
+	statPrefix := config.StatPrefix
+	if statPrefix == "" {
+		statPrefix = config.Cluster
+	}
+	pc := &proxyConfig{
+		cluster:         config.Cluster,
+		statPrefix:      statPrefix,
+		redisCluster:    config.Sharding == v2.RedisShardingCluster,
+		allowed:         allowed,
+		opTimeout:       DefaultOpTimeout,
+		maxRedirections: DefaultMaxRedirections,
+		pool:            newClientPool(),
+		stats:           newProxyStats(statPrefix),
+	}
+	if config.OpTimeout != nil && config.OpTimeout.Duration > 0 {
+		pc.opTimeout = config.OpTimeout.Duration
+	}
+	if config.MaxRedirections > 0 {
+		pc.maxRedirections = int(config.MaxRedirections)
+	}
+	if pc.redisCluster {
+		pc.router = newSlotRouter(pc.pool, pc.stats)
+	} else {
+		pc.router = &hashRouter{}
+	}
+	return pc, nil
+}
+
+func (pc *proxyConfig) getCommandStats(name string) *commandStats {
+	if s, ok := pc.commandStats.Load(name); ok {
+		return s.(*commandStats)
+	}
+	s, _ := pc.commandStats.LoadOrStore(name, newCommandStats(pc.statPrefix, strings.ToLower(name)))
+	return s.(*commandStats)
+}
+
+// request is a command received from the downstream, the replies are written to the downstream
+// in the order of the requests
+type request struct {
+	session  *session
+	name     string
+	kind     commandKind
+	start    time.Time
+	timer    *time.Timer
+	snapshot types.ClusterSnapshot
+	// protover is the RESP version of the downstream when the request is received
+	protover int
+	// quit closes the downstream connection after the reply is written
+	quit bool
+
+	// the following fields are guarded by the session mutex
+	done  bool
+	reply *Value
+	// pending is the number of the splits have not replied
+	pending  int
+	firstErr *Value
+	// elems are the merged replies of MGET
+	elems []*Value
+	// sum is the sum of the integer replies
+	sum int64
+}
+
+// split is a part of a request that is sent to an upstream
+type split struct {
+	req *request
+	cmd *Value
+	// positions are the indexes of the keys of the split in the request, it is used by MGET
+	positions    []int
+	redirections int
+}
+
+// session is the read filter of a downstream redis connection. The commands are decoded
+// and dispatched to the upstreams in pipeline, and the replies are written back in order.
+type session struct {
+	config         *proxyConfig
+	clusterManager types.ClusterManager
+	readCallbacks  types.ReadFilterCallbacks
+	// protover is the RESP version of the downstream, switched by HELLO
+	protover int
+
+	mutex    sync.Mutex
+	requests []*request
+	closed   bool
+}
+
+func newSession(config *proxyConfig, clusterManager types.ClusterManager) *session {
+	return &session{
+		config:         config,
+		clusterManager: clusterManager,
+		protover:       2,
+	}
+}
+
+func (s *session) InitializeReadFilterCallbacks(cb types.ReadFilterCallbacks) {
+	s.readCallbacks = cb
+	s.readCallbacks.Connection().AddConnectionEventListener(s)
+}
+
+func (s *session) OnNewConnection() types.FilterStatus {
+	return types.Continue
+}
+
+// OnData decodes the pipelined commands and dispatches them
+func (s *session) OnData(buf types.IoBuffer) types.FilterStatus {
+	for buf.Len() > 0 {
+		cmd, n, err := DecodeCommand(buf.Bytes())
+		if err == ErrIncomplete {
+			break
+		}
+		if err != nil {
+			log.DefaultLogger.Errorf("[redisproxy] decode command from %s failed: %v", s.readCallbacks.Connection().RemoteAddr(), err)
+			buf.Drain(buf.Len())
+			s.readCallbacks.Connection().Write(buffer.NewIoBufferBytes(NewError("ERR Protocol error").AppendTo(nil)))
+			s.readCallbacks.Connection().Close(types.FlushWrite, types.LocalClose)
+			break
+		}
+		buf.Drain(n)
+		if len(cmd.Elems) == 0 {
+			continue
+		}
+		if s.handle(cmd) {
+			// the commands after QUIT are ignored
+			buf.Drain(buf.Len())
+			break
+		}
+	}
+	return types.Stop
+}
+
+// handle dispatches a command, returns true if the command is QUIT
+func (s *session) handle(cmd *Value) bool {
+	name := commandName(cmd)
+	req := &request{
+		session:  s,
+		name:     name,
+		start:    time.Now(),
+		protover: s.protover,
+	}
+	s.mutex.Lock()
+	s.requests = append(s.requests, req)
+	s.mutex.Unlock()
+
+	c, ok := commands[name]
+	if !ok {
+		s.config.stats.CommandUnsupported.Inc(1)
+		req.finish(NewError(fmt.Sprintf("ERR unsupported command '%s'", cmd.Elems[0].Str)))
+		return false
+	}
+	req.kind = c.kind
+	s.config.stats.CommandTotal.Inc(1)
+	s.config.getCommandStats(name).Total.Inc(1)
+	// the local commands are always allowed, the clients require them to setup the connections
+	if c.kind != localCommand && s.config.allowed != nil && !s.config.allowed[name] {
+		s.config.stats.CommandNotAllowed.Inc(1)
+		req.finish(NewError(fmt.Sprintf("ERR command '%s' is not allowed", strings.ToLower(name))))
+		return false
+	}
+	if !c.checkArity(len(cmd.Elems)) || (c.kind == msetCommand && len(cmd.Elems)%2 != 1) {
+		req.finish(wrongArgs(name))
+		return false
+	}
+	if c.kind == localCommand {
+		req.quit = name == "QUIT"
+		req.finish(s.handleLocal(name, cmd))
+		return req.quit
+	}
+
+	req.snapshot = s.clusterManager.GetClusterSnapshot(context.Background(), s.config.cluster)
+	if req.snapshot == nil || reflect.ValueOf(req.snapshot).IsNil() {
+		log.DefaultLogger.Errorf("[redisproxy] cluster %s is not found", s.config.cluster)
+		s.config.stats.UpstreamFailed.Inc(1)
+		req.finish(NewError("ERR no upstream host"))
+		return false
+	}
+	s.mutex.Lock()
+	if !req.done {
+		req.timer = time.AfterFunc(s.config.opTimeout, req.onTimeout)
+	}
+	s.mutex.Unlock()
+
+	splits, err := s.split(req, c, cmd)
+	if err != nil {
+		s.config.stats.UpstreamFailed.Inc(1)
+		req.finish(NewError("ERR " + err.Error()))
+		return false
+	}
+	s.mutex.Lock()
+	req.pending = len(splits)
+	if c.kind == mgetCommand {
+		req.elems = make([]*Value, len(cmd.Elems)-1)
+	}
+	s.mutex.Unlock()
+	for _, sp := range splits {
+		s.send(sp.split, sp.address, false)
+	}
+	return false
+}
+
+func wrongArgs(name string) *Value {
+	return NewError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// handleLocal replies the commands handled by the proxy
+func (s *session) handleLocal(name string, cmd *Value) *Value {
+	args := cmd.Elems[1:]
+	switch name {
+	case "PING":
+		switch len(args) {
+		case 0:
+			return NewSimpleString("PONG")
+		case 1:
+			return NewBulkString(args[0].Str)
+		}
+		return wrongArgs(name)
+	case "ECHO":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		return NewBulkString(args[0].Str)
+	case "HELLO":
+		// HELLO [protover], the authentication and the client name are not supported by the proxy
+		if len(args) > 0 {
+			protover, err := strconv.Atoi(string(args[0].Str))
+			if err != nil {
+				return NewError("ERR Protocol version is not an integer or out of range")
+			}
+			if protover != 2 && protover != 3 {
+				return NewError("NOPROTO unsupported protocol version")
+			}
+			s.protover = protover
+		}
+		fields := []*Value{
+			NewBulkString([]byte("server")), NewBulkString([]byte("redis")),
+			NewBulkString([]byte("version")), NewBulkString([]byte("6.0.0")),
+			NewBulkString([]byte("proto")), NewInteger(int64(s.protover)),
+			NewBulkString([]byte("mode")), NewBulkString([]byte("proxy")),
+			NewBulkString([]byte("role")), NewBulkString([]byte("master")),
+			NewBulkString([]byte("modules")), NewArray(nil),
+		}
+		if s.protover == 3 {
+			return &Value{Type: TypeMap, Elems: fields}
+		}
+		return NewArray(fields)
+	case "QUIT":
+		return NewSimpleString("OK")
+	}
+	return NewError(fmt.Sprintf("ERR unsupported command '%s'", strings.ToLower(name)))
+}
+
+type routedSplit struct {
+	*split
+	address string
+}
+
+// split splits the command by the shards of the keys
+func (s *session) split(req *request, c *command, cmd *Value) ([]*routedSplit, error) {
+	args := cmd.Elems
+	switch c.kind {
+	case keyCommand:
+		address, _, err := s.config.router.route(req.snapshot, args[1].Str)
+		if err != nil {
+			return nil, err
+		}
+		return []*routedSplit{{split: &split{req: req, cmd: cmd}, address: address}}, nil
+	case evalCommand:
+		numkeys, err := strconv.Atoi(string(args[2].Str))
+		if err != nil || numkeys < 0 {
+			return nil, fmt.Errorf("value is not an integer or out of range")
+		}
+		if numkeys > len(args)-3 {
+			return nil, fmt.Errorf("Number of keys can't be greater than number of args")
+		}
+		var key []byte
+		if numkeys > 0 {
+			key = args[3].Str
+		}
+		address, _, err := s.config.router.route(req.snapshot, key)
+		if err != nil {
+			return nil, err
+		}
+		return []*routedSplit{{split: &split{req: req, cmd: cmd}, address: address}}, nil
+	}
+
+	// the multi-key commands, the keys of the same shard are sent in one command
+	step := 1
+	if c.kind == msetCommand {
+		step = 2
+	}
+	var splits []*routedSplit
+	shards := make(map[string]*routedSplit)
+	for i := 1; i < len(args); i += step {
+		address, shard, err := s.config.router.route(req.snapshot, args[i].Str)
+		if err != nil {
+			return nil, err
+		}
+		sp, ok := shards[shard]
+		if !ok {
+			sp = &routedSplit{
+				split: &split{
+					req: req,
+					cmd: NewArray([]*Value{args[0]}),
+				},
+				address: address,
+			}
+			shards[shard] = sp
+			splits = append(splits, sp)
+		}
+		sp.cmd.Elems = append(sp.cmd.Elems, args[i:i+step]...)
+		sp.positions = append(sp.positions, (i-1)/step)
+	}
+	return splits, nil
+}
+
+// send sends a split to the upstream address
+func (s *session) send(sp *split, address string, asking bool) {
+	client, err := s.config.pool.get(address, sp.req.protover, sp.req.snapshot.ClusterInfo().ConnectTimeout())
+	if err != nil {
+		log.DefaultLogger.Errorf("[redisproxy] connect to upstream %s failed: %v", address, err)
+		s.config.stats.UpstreamFailed.Inc(1)
+		s.onReply(sp, NewError("ERR upstream connection failed"))
+		return
+	}
+	client.send(sp.cmd, func(reply *Value) {
+		s.onReply(sp, reply)
+	}, asking)
+}
+
+// onReply handles the reply of a split, the MOVED and ASK replies are redirected in redis_cluster
+func (s *session) onReply(sp *split, reply *Value) {
+	if s.config.redisCluster && sp.redirections < s.config.maxRedirections {
+		if r, ok := parseRedirection(reply); ok {
+			sp.redirections++
+			if r.ask {
+				s.config.stats.UpstreamAsk.Inc(1)
+			} else {
+				s.config.stats.UpstreamMoved.Inc(1)
+				s.config.router.(*slotRouter).moved(sp.req.snapshot, r.slot, r.address)
+			}
+			if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+				log.DefaultLogger.Debugf("[redisproxy] command %s is redirected: %s", sp.req.name, reply.Str)
+			}
+			s.send(sp, r.address, r.ask)
+			return
+		}
+	}
+	sp.req.merge(sp, reply)
+}
+
+// merge merges the reply of a split into the request, the request is finished
+// after all the splits replied
+func (req *request) merge(sp *split, reply *Value) {
+	s := req.session
+	s.mutex.Lock()
+	if req.done {
+		s.mutex.Unlock()
+		return
+	}
+	if reply.IsError() {
+		if req.firstErr == nil {
+			req.firstErr = reply
+		}
+	} else {
+		switch req.kind {
+		case mgetCommand:
+			for i, pos := range sp.positions {
+				if i < len(reply.Elems) {
+					req.elems[pos] = reply.Elems[i]
+				}
+			}
+		case sumCommand:
+			req.sum += reply.Int
+		case keyCommand, evalCommand:
+			req.reply = reply
+		}
+	}
+	req.pending--
+	pending := req.pending
+	s.mutex.Unlock()
+	if pending > 0 {
+		return
+	}
+
+	switch {
+	case req.firstErr != nil:
+		reply = req.firstErr
+	case req.kind == mgetCommand:
+		for i, e := range req.elems {
+			if e == nil {
+				req.elems[i] = NewNullBulkString()
+			}
+		}
+		reply = NewArray(req.elems)
+	case req.kind == msetCommand:
+		reply = NewSimpleString("OK")
+	case req.kind == sumCommand:
+		reply = NewInteger(req.sum)
+	default:
+		reply = req.reply
+	}
+	req.finish(reply)
+}
+
+func (req *request) onTimeout() {
+	req.session.config.stats.CommandTimeout.Inc(1)
+	req.finish(NewError("ERR operation timed out"))
+}
+
+// finish sets the reply of the request and flushes the replies in order
+func (req *request) finish(reply *Value) {
+	s := req.session
+	s.mutex.Lock()
+	if req.done {
+		s.mutex.Unlock()
+		return
+	}
+	req.done = true
+	req.reply = reply
+	if req.timer != nil {
+		req.timer.Stop()
+	}
+	s.mutex.Unlock()
+
+	if c, ok := commands[req.name]; ok {
+		cost := time.Since(req.start).Nanoseconds()
+		stats := s.config.getCommandStats(c.name)
+		if reply.IsError() {
+			s.config.stats.CommandError.Inc(1)
+			stats.Error.Inc(1)
+		} else {
+			s.config.stats.CommandSuccess.Inc(1)
+			stats.Success.Inc(1)
+		}
+		s.config.stats.CommandTime.Update(cost)
+		stats.Time.Update(cost)
+	}
+	s.flush()
+}
+
+// flush writes the replies of the finished requests at the head of the queue
+func (s *session) flush() {
+	var data []byte
+	quit := false
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return
+	}
+	i := 0
+	for ; i < len(s.requests) && s.requests[i].done; i++ {
+		data = s.requests[i].reply.AppendTo(data)
+		quit = quit || s.requests[i].quit
+		s.requests[i] = nil
+	}
+	s.requests = s.requests[i:]
+	if len(data) > 0 {
+		s.readCallbacks.Connection().Write(buffer.NewIoBufferBytes(data))
+	}
+	s.mutex.Unlock()
+	if quit {
+		s.readCallbacks.Connection().Close(types.FlushWrite, types.LocalClose)
+	}
+}
+
+// OnEvent drops the pending requests when the downstream connection is closed
+func (s *session) OnEvent(event types.ConnectionEvent) {
+	if !event.IsClose() {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	for _, req := range s.requests {
+		req.done = true
+		if req.timer != nil {
+			req.timer.Stop()
+		}
+	}
+	s.requests = nil
+}
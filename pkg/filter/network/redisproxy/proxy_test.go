@@ -0,0 +1,661 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockClusterManager struct {
+	types.ClusterManager
+	hosts []types.Host
+}
+
+func (m *mockClusterManager) GetClusterSnapshot(ctx context.Context, name string) types.ClusterSnapshot {
+	return &mockClusterSnapshot{hosts: m.hosts}
+}
+
+type mockClusterSnapshot struct {
+	types.ClusterSnapshot
+	hosts []types.Host
+}
+
+func (s *mockClusterSnapshot) HostSet() types.HostSet {
+	return s
+}
+
+func (s *mockClusterSnapshot) Hosts() []types.Host {
+	return s.hosts
+}
+
+func (s *mockClusterSnapshot) HealthyHosts() []types.Host {
+	return s.hosts
+}
+
+func (s *mockClusterSnapshot) ClusterInfo() types.ClusterInfo {
+	return &mockClusterInfo{}
+}
+
+type mockClusterInfo struct {
+	types.ClusterInfo
+}
+
+func (ci *mockClusterInfo) ConnectTimeout() time.Duration {
+	return time.Second
+}
+
+type mockHost struct {
+	types.Host
+	address string
+}
+
+func (h *mockHost) AddressString() string {
+	return h.address
+}
+
+type mockReadFilterCallbacks struct {
+	types.ReadFilterCallbacks
+	conn *mockConnection
+}
+
+func (cb *mockReadFilterCallbacks) Connection() types.Connection {
+	return cb.conn
+}
+
+// mockConnection records the written replies
+type mockConnection struct {
+	types.Connection
+	mutex     sync.Mutex
+	listeners []types.ConnectionEventListener
+	written   []byte
+	closed    bool
+}
+
+func (c *mockConnection) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53000}
+}
+
+func (c *mockConnection) AddConnectionEventListener(listener types.ConnectionEventListener) {
+	c.listeners = append(c.listeners, listener)
+}
+
+func (c *mockConnection) Write(buffers ...types.IoBuffer) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, b := range buffers {
+		c.written = append(c.written, b.Bytes()...)
+	}
+	return nil
+}
+
+func (c *mockConnection) Close(ccType types.ConnectionCloseType, eventType types.ConnectionEvent) error {
+	c.mutex.Lock()
+	c.closed = true
+	c.mutex.Unlock()
+	for _, l := range c.listeners {
+		l.OnEvent(eventType)
+	}
+	return nil
+}
+
+// replies waits for n replies written to the connection
+func (c *mockConnection) replies(t *testing.T, n int) []*Value {
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mutex.Lock()
+		data := c.written
+		c.mutex.Unlock()
+		var values []*Value
+		for len(data) > 0 {
+			v, consumed, err := Decode(data)
+			if err != nil {
+				break
+			}
+			values = append(values, v)
+			data = data[consumed:]
+		}
+		if len(values) >= n {
+			return values
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("wait for %d replies timeout, written: %q", n, c.written)
+	return nil
+}
+
+// fakeRedis is a redis compatible server supports a few commands. In cluster mode, it serves
+// the slots in [slotStart, slotEnd], and replies MOVED for the other slots,
+// the keys of the migrating slots that do not exist are replied ASK.
+type fakeRedis struct {
+	listener net.Listener
+	address  string
+
+	mutex     sync.Mutex
+	data      map[string]string
+	commands  []string
+	cluster   bool
+	slotStart int
+	slotEnd   int
+	peer      *fakeRedis
+	migrating map[int]*fakeRedis
+	delay     time.Duration
+}
+
+// newFakeRedis creates a fake redis, it serves after start is called
+func newFakeRedis(t *testing.T) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("start fake redis failed: %v", err)
+	}
+	r := &fakeRedis{
+		listener:  l,
+		address:   l.Addr().String(),
+		data:      make(map[string]string),
+		migrating: make(map[int]*fakeRedis),
+	}
+	return r
+}
+
+func (r *fakeRedis) start() {
+	go func() {
+		for {
+			conn, err := r.listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	r := newFakeRedis(t)
+	r.start()
+	return r
+}
+
+func (r *fakeRedis) close() {
+	r.listener.Close()
+}
+
+func (r *fakeRedis) get(key string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.data[key]
+}
+
+func (r *fakeRedis) received() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.commands...)
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	var data []byte
+	buf := make([]byte, 4096)
+	protover := 2
+	asking := false
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		data = append(data, buf[:n]...)
+		for {
+			cmd, consumed, err := DecodeCommand(data)
+			if err != nil {
+				break
+			}
+			data = data[consumed:]
+			var args []string
+			for _, e := range cmd.Elems {
+				args = append(args, string(e.Str))
+			}
+			reply := r.execute(args, &protover, asking)
+			asking = strings.ToUpper(args[0]) == "ASKING"
+			if _, err := conn.Write(reply.AppendTo(nil)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (r *fakeRedis) execute(args []string, protover *int, asking bool) *Value {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.commands = append(r.commands, strings.Join(args, " "))
+	name := strings.ToUpper(args[0])
+	nullValue := NewNullBulkString()
+	if *protover == 3 {
+		nullValue = &Value{Type: TypeNull}
+	}
+	if r.delay > 0 && name == "GET" {
+		r.mutex.Unlock()
+		time.Sleep(r.delay)
+		r.mutex.Lock()
+	}
+	if r.cluster {
+		var keys []string
+		switch name {
+		case "GET", "SET", "INCR":
+			keys = args[1:2]
+		case "MGET", "DEL", "EXISTS":
+			keys = args[1:]
+		case "MSET":
+			for i := 1; i < len(args); i += 2 {
+				keys = append(keys, args[i])
+			}
+		}
+		if len(keys) > 0 {
+			slot := Slot([]byte(keys[0]))
+			for _, key := range keys[1:] {
+				if Slot([]byte(key)) != slot {
+					return NewError("CROSSSLOT Keys in request don't hash to the same slot")
+				}
+			}
+			if target, ok := r.migrating[slot]; ok {
+				if _, exist := r.data[keys[0]]; !exist {
+					return NewError(fmt.Sprintf("ASK %d %s", slot, target.address))
+				}
+			}
+			if (slot < r.slotStart || slot > r.slotEnd) && !asking {
+				return NewError(fmt.Sprintf("MOVED %d %s", slot, r.peer.address))
+			}
+		}
+	}
+	switch name {
+	case "PING":
+		return NewSimpleString("PONG")
+	case "ASKING":
+		return NewSimpleString("OK")
+	case "HELLO":
+		if len(args) > 1 {
+			*protover, _ = strconv.Atoi(args[1])
+		}
+		return &Value{Type: TypeMap, Elems: []*Value{NewBulkString([]byte("proto")), NewInteger(int64(*protover))}}
+	case "GET":
+		if v, ok := r.data[args[1]]; ok {
+			return NewBulkString([]byte(v))
+		}
+		return nullValue
+	case "SET":
+		r.data[args[1]] = args[2]
+		return NewSimpleString("OK")
+	case "INCR":
+		i, _ := strconv.ParseInt(r.data[args[1]], 10, 64)
+		r.data[args[1]] = strconv.FormatInt(i+1, 10)
+		return NewInteger(i + 1)
+	case "MGET":
+		var elems []*Value
+		for _, key := range args[1:] {
+			if v, ok := r.data[key]; ok {
+				elems = append(elems, NewBulkString([]byte(v)))
+			} else {
+				elems = append(elems, nullValue)
+			}
+		}
+		return NewArray(elems)
+	case "MSET":
+		for i := 1; i < len(args); i += 2 {
+			r.data[args[i]] = args[i+1]
+		}
+		return NewSimpleString("OK")
+	case "DEL", "EXISTS":
+		var n int64
+		for _, key := range args[1:] {
+			if _, ok := r.data[key]; ok {
+				n++
+				if name == "DEL" {
+					delete(r.data, key)
+				}
+			}
+		}
+		return NewInteger(n)
+	case "CLUSTER":
+		node := func(s *fakeRedis) *Value {
+			host, port, _ := net.SplitHostPort(s.address)
+			p, _ := strconv.ParseInt(port, 10, 64)
+			return NewArray([]*Value{NewBulkString([]byte(host)), NewInteger(p)})
+		}
+		return NewArray([]*Value{
+			NewArray([]*Value{NewInteger(int64(r.slotStart)), NewInteger(int64(r.slotEnd)), node(r)}),
+			NewArray([]*Value{NewInteger(int64(r.peer.slotStart)), NewInteger(int64(r.peer.slotEnd)), node(r.peer)}),
+		})
+	}
+	return NewError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+}
+
+func newTestSession(t *testing.T, conf *v2.RedisProxy, servers ...*fakeRedis) (*session, *mockConnection) {
+	pc, err := newProxyConfig(conf)
+	if err != nil {
+		t.Fatalf("create proxy config failed: %v", err)
+	}
+	cm := &mockClusterManager{}
+	for _, s := range servers {
+		cm.hosts = append(cm.hosts, &mockHost{address: s.address})
+	}
+	conn := &mockConnection{}
+	s := newSession(pc, cm)
+	s.InitializeReadFilterCallbacks(&mockReadFilterCallbacks{conn: conn})
+	s.OnNewConnection()
+	return s, conn
+}
+
+func sendCommands(s *session, cmds ...[]string) {
+	var data []byte
+	for _, cmd := range cmds {
+		var args [][]byte
+		for _, arg := range cmd {
+			args = append(args, []byte(arg))
+		}
+		data = NewCommand(args...).AppendTo(data)
+	}
+	s.OnData(buffer.NewIoBufferBytes(data))
+}
+
+func TestNewProxyConfig(t *testing.T) {
+	pc, err := newProxyConfig(&v2.RedisProxy{
+		Cluster:         "redis",
+		Sharding:        v2.RedisShardingCluster,
+		AllowedCommands: []string{"get", "SET"},
+		OpTimeout:       &v2.DurationConfig{Duration: time.Second},
+		MaxRedirections: 5,
+	})
+	if err != nil {
+		t.Fatalf("create proxy config failed: %v", err)
+	}
+	if !pc.redisCluster || pc.opTimeout != time.Second || pc.maxRedirections != 5 || pc.statPrefix != "redis" ||
+		!reflect.DeepEqual(pc.allowed, map[string]bool{"GET": true, "SET": true}) {
+		t.Errorf("unexpected proxy config: %+v", pc)
+	}
+	if _, ok := pc.router.(*slotRouter); !ok {
+		t.Errorf("expected slot router in redis_cluster")
+	}
+	pc, _ = newProxyConfig(&v2.RedisProxy{Cluster: "redis"})
+	if pc.opTimeout != DefaultOpTimeout || pc.maxRedirections != DefaultMaxRedirections || pc.allowed != nil {
+		t.Errorf("unexpected default proxy config: %+v", pc)
+	}
+	if _, err := newProxyConfig(&v2.RedisProxy{Cluster: "redis", AllowedCommands: []string{"KEYS"}}); err == nil {
+		t.Errorf("expected error for unsupported allowed command")
+	}
+}
+
+func TestProxyPipeline(t *testing.T) {
+	servers := []*fakeRedis{startFakeRedis(t), startFakeRedis(t), startFakeRedis(t)}
+	for _, server := range servers {
+		defer server.close()
+	}
+	s, conn := newTestSession(t, &v2.RedisProxy{Cluster: "pipeline"}, servers...)
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+	var cmds [][]string
+	mset := []string{"MSET"}
+	for i, key := range keys {
+		mset = append(mset, key, strconv.Itoa(i))
+	}
+	cmds = append(cmds, mset)
+	cmds = append(cmds, append([]string{"MGET", "missing"}, keys...))
+	cmds = append(cmds, []string{"INCR", "key-3"}, []string{"GET", "key-3"})
+	cmds = append(cmds, append([]string{"EXISTS", "missing"}, keys[:10]...))
+	cmds = append(cmds, append([]string{"DEL"}, keys[:5]...))
+	cmds = append(cmds, []string{"PING"}, []string{"ECHO", "hello"})
+	sendCommands(s, cmds...)
+
+	replies := conn.replies(t, len(cmds))
+	if replies[0].Type != TypeSimpleString || string(replies[0].Str) != "OK" {
+		t.Errorf("MSET got %s", replies[0])
+	}
+	mget := replies[1]
+	if len(mget.Elems) != len(keys)+1 || !mget.Elems[0].IsNull {
+		t.Fatalf("MGET got %s", mget)
+	}
+	for i := range keys {
+		if string(mget.Elems[i+1].Str) != strconv.Itoa(i) {
+			t.Errorf("MGET key %d got %s", i, mget.Elems[i+1])
+		}
+	}
+	if replies[2].Int != 4 || string(replies[3].Str) != "4" {
+		t.Errorf("INCR and GET got %s %s", replies[2], replies[3])
+	}
+	if replies[4].Int != 10 || replies[5].Int != 5 {
+		t.Errorf("EXISTS and DEL got %s %s", replies[4], replies[5])
+	}
+	if string(replies[6].Str) != "PONG" || string(replies[7].Str) != "hello" {
+		t.Errorf("PING and ECHO got %s %s", replies[6], replies[7])
+	}
+	// the keys are spread over the servers
+	for _, server := range servers {
+		server.mutex.Lock()
+		n := len(server.data)
+		server.mutex.Unlock()
+		if n == 0 || n == 15 {
+			t.Errorf("server %s has %d keys", server.address, n)
+		}
+	}
+	stats := s.config.getCommandStats("MGET")
+	if stats.Total.Count() != 1 || stats.Success.Count() != 1 || stats.Time.Count() != 1 {
+		t.Errorf("unexpected MGET stats %d %d %d", stats.Total.Count(), stats.Success.Count(), stats.Time.Count())
+	}
+}
+
+func TestProxyLocalCommands(t *testing.T) {
+	server := startFakeRedis(t)
+	defer server.close()
+	s, conn := newTestSession(t, &v2.RedisProxy{Cluster: "local", AllowedCommands: []string{"GET"}}, server)
+
+	sendCommands(s,
+		[]string{"HELLO", "3"},
+		[]string{"GET", "foo"},
+		[]string{"SET", "foo", "bar"},
+		[]string{"KEYS", "*"},
+		[]string{"GET"},
+		[]string{"HELLO", "4"},
+	)
+	replies := conn.replies(t, 6)
+	if replies[0].Type != TypeMap {
+		t.Errorf("HELLO 3 got %s", replies[0])
+	}
+	// the upstream connection speaks RESP3 too
+	if replies[1].Type != TypeNull {
+		t.Errorf("GET in RESP3 got %s", replies[1])
+	}
+	if string(replies[2].Str) != "ERR command 'set' is not allowed" {
+		t.Errorf("SET got %s", replies[2])
+	}
+	if string(replies[3].Str) != "ERR unsupported command 'KEYS'" {
+		t.Errorf("KEYS got %s", replies[3])
+	}
+	if string(replies[4].Str) != "ERR wrong number of arguments for 'get' command" {
+		t.Errorf("GET without key got %s", replies[4])
+	}
+	if !strings.HasPrefix(string(replies[5].Str), "NOPROTO") {
+		t.Errorf("HELLO 4 got %s", replies[5])
+	}
+	if s.config.stats.CommandNotAllowed.Count() != 1 || s.config.stats.CommandUnsupported.Count() != 1 {
+		t.Errorf("unexpected stats")
+	}
+
+	s.OnData(buffer.NewIoBufferString("QUIT\r\nPING\r\n"))
+	replies = conn.replies(t, 7)
+	if len(replies) != 7 || string(replies[6].Str) != "OK" || !conn.closed {
+		t.Errorf("QUIT got %v, closed %v", replies, conn.closed)
+	}
+}
+
+func TestProxyTimeout(t *testing.T) {
+	server := newFakeRedis(t)
+	defer server.close()
+	server.delay = 500 * time.Millisecond
+	server.start()
+	s, conn := newTestSession(t, &v2.RedisProxy{
+		Cluster:   "timeout",
+		OpTimeout: &v2.DurationConfig{Duration: 100 * time.Millisecond},
+	}, server)
+
+	sendCommands(s, []string{"GET", "foo"}, []string{"PING"})
+	replies := conn.replies(t, 2)
+	if string(replies[0].Str) != "ERR operation timed out" || string(replies[1].Str) != "PONG" {
+		t.Errorf("timeout got %v", replies)
+	}
+	if s.config.stats.CommandTimeout.Count() != 1 {
+		t.Errorf("unexpected timeout stats")
+	}
+	// the late reply is dropped
+	time.Sleep(600 * time.Millisecond)
+	if replies := conn.replies(t, 2); len(replies) != 2 {
+		t.Errorf("unexpected replies %v", replies)
+	}
+}
+
+func TestProxyUpstreamFailed(t *testing.T) {
+	server := startFakeRedis(t)
+	server.close()
+	s, conn := newTestSession(t, &v2.RedisProxy{Cluster: "failed"}, server)
+	sendCommands(s, []string{"GET", "foo"})
+	replies := conn.replies(t, 1)
+	if string(replies[0].Str) != "ERR upstream connection failed" {
+		t.Errorf("got %s", replies[0])
+	}
+
+	s, conn = newTestSession(t, &v2.RedisProxy{Cluster: "nohost"})
+	sendCommands(s, []string{"GET", "foo"})
+	replies = conn.replies(t, 1)
+	if string(replies[0].Str) != "ERR no upstream host" {
+		t.Errorf("got %s", replies[0])
+	}
+}
+
+// newFakeRedisCluster creates two nodes, a serves the slots 0-8191 and b serves the slots 8192-16383
+func newFakeRedisCluster(t *testing.T) (*fakeRedis, *fakeRedis) {
+	a, b := newFakeRedis(t), newFakeRedis(t)
+	a.cluster, a.slotStart, a.slotEnd, a.peer = true, 0, 8191, b
+	b.cluster, b.slotStart, b.slotEnd, b.peer = true, 8192, 16383, a
+	return a, b
+}
+
+func TestProxyRedisCluster(t *testing.T) {
+	a, b := newFakeRedisCluster(t)
+	defer a.close()
+	defer b.close()
+	a.start()
+	b.start()
+	// only a is configured, the slots of b are discovered
+	s, conn := newTestSession(t, &v2.RedisProxy{Cluster: "rediscluster", Sharding: v2.RedisShardingCluster}, a)
+
+	// foo is in slot 12182 of b, bar is in slot 5061 of a
+	sendCommands(s, []string{"SET", "foo", "1"}, []string{"SET", "bar", "2"})
+	replies := conn.replies(t, 2)
+	if string(replies[0].Str) != "OK" || string(replies[1].Str) != "OK" {
+		t.Fatalf("SET got %v", replies)
+	}
+	if b.get("foo") != "1" || a.get("bar") != "2" {
+		t.Errorf("the keys are not stored by the slots")
+	}
+
+	// wait for the slots refreshed
+	router := s.config.router.(*slotRouter)
+	deadline := time.Now().Add(3 * time.Second)
+	for s.config.stats.SlotsRefreshSuccess.Count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	router.mutex.RLock()
+	if router.slots[0] != a.address || router.slots[16383] != b.address {
+		t.Errorf("unexpected slots after refreshing")
+	}
+	router.mutex.RUnlock()
+
+	// MGET across the slots is split by the slots
+	sendCommands(s, []string{"MGET", "foo", "bar", "{foo}x", "{bar}y"}, []string{"DEL", "foo", "bar"})
+	replies = conn.replies(t, 4)
+	mget := replies[2]
+	if len(mget.Elems) != 4 || string(mget.Elems[0].Str) != "1" || string(mget.Elems[1].Str) != "2" || !mget.Elems[2].IsNull {
+		t.Errorf("MGET got %s", mget)
+	}
+	if replies[3].Int != 2 {
+		t.Errorf("DEL got %s", replies[3])
+	}
+}
+
+func TestProxyRedisClusterAsk(t *testing.T) {
+	a, b := newFakeRedisCluster(t)
+	defer a.close()
+	defer b.close()
+	// slot 5061 of bar is migrating from a to b
+	a.migrating[Slot([]byte("bar"))] = b
+	a.start()
+	b.start()
+	s, conn := newTestSession(t, &v2.RedisProxy{Cluster: "rediscluster-ask", Sharding: v2.RedisShardingCluster}, a, b)
+
+	sendCommands(s, []string{"SET", "bar", "1"}, []string{"GET", "bar"})
+	replies := conn.replies(t, 2)
+	if string(replies[0].Str) != "OK" || string(replies[1].Str) != "1" {
+		t.Fatalf("got %v", replies)
+	}
+	if b.get("bar") != "1" {
+		t.Errorf("the key is not stored in the importing node")
+	}
+	commands := b.received()
+	found := false
+	for i, cmd := range commands {
+		if cmd == "SET bar 1" && i > 0 && commands[i-1] == "ASKING" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("ASKING is not sent before the command: %v", commands)
+	}
+	if s.config.stats.UpstreamAsk.Count() != 2 {
+		t.Errorf("unexpected ask stats %d", s.config.stats.UpstreamAsk.Count())
+	}
+}
+
+func TestProxyRedisClusterMaxRedirections(t *testing.T) {
+	a, b := newFakeRedisCluster(t)
+	defer a.close()
+	defer b.close()
+	// both nodes claim foo is served by the other one
+	b.slotStart, b.slotEnd = 0, 0
+	a.start()
+	b.start()
+	s, conn := newTestSession(t, &v2.RedisProxy{Cluster: "rediscluster-loop", Sharding: v2.RedisShardingCluster, MaxRedirections: 2}, a)
+
+	sendCommands(s, []string{"GET", "foo"})
+	replies := conn.replies(t, 1)
+	if !strings.HasPrefix(string(replies[0].Str), "MOVED") {
+		t.Errorf("got %s", replies[0])
+	}
+	if s.config.stats.UpstreamMoved.Count() != 2 {
+		t.Errorf("unexpected moved stats %d", s.config.stats.UpstreamMoved.Count())
+	}
+}
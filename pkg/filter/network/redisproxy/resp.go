@@ -0,0 +1,340 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// RESP value types, the first five types are defined by RESP2, the others are added by RESP3
+const (
+	TypeSimpleString   byte = '+'
+	TypeError          byte = '-'
+	TypeInteger        byte = ':'
+	TypeBulkString     byte = '$'
+	TypeArray          byte = '*'
+	TypeNull           byte = '_'
+	TypeBoolean        byte = '#'
+	TypeDouble         byte = ','
+	TypeBigNumber      byte = '('
+	TypeBulkError      byte = '!'
+	TypeVerbatimString byte = '='
+	TypeMap            byte = '%'
+	TypeSet            byte = '~'
+	TypeAttribute      byte = '|'
+	TypePush           byte = '>'
+)
+
+const (
+	// maxBulkLength is the max length of a bulk string, the same as the proto-max-bulk-len of redis
+	maxBulkLength = 512 * 1024 * 1024
+	// maxElements is the max number of the elements of an aggregate value
+	maxElements = 1024 * 1024
+	// maxDepth is the max nesting depth of the aggregate values
+	maxDepth = 32
+	// maxInlineLength is the max length of an inline command
+	maxInlineLength = 64 * 1024
+)
+
+var (
+	// ErrIncomplete means more bytes are required to decode a value
+	ErrIncomplete = errors.New("incomplete resp value")
+	// ErrProtocol means the bytes are not a valid resp value
+	ErrProtocol = errors.New("invalid resp value")
+)
+
+var crlf = []byte("\r\n")
+
+// Value is a RESP value
+type Value struct {
+	Type byte
+	// Str is the content of the string types and the errors, the text of the double and the big number,
+	// and t or f of the boolean
+	Str []byte
+	// Int is the value of the integer
+	Int int64
+	// Elems are the elements of the aggregate types, the keys and the values of a map are in turn
+	Elems []*Value
+	// IsNull means the null bulk string or the null array of RESP2
+	IsNull bool
+	// Attrs is the attribute of RESP3 that precedes the value, the keys and the values are in turn
+	Attrs []*Value
+}
+
+// NewSimpleString returns a simple string value
+func NewSimpleString(s string) *Value {
+	return &Value{Type: TypeSimpleString, Str: []byte(s)}
+}
+
+// NewError returns an error value
+func NewError(msg string) *Value {
+	return &Value{Type: TypeError, Str: []byte(msg)}
+}
+
+// NewInteger returns an integer value
+func NewInteger(i int64) *Value {
+	return &Value{Type: TypeInteger, Int: i}
+}
+
+// NewBulkString returns a bulk string value
+func NewBulkString(b []byte) *Value {
+	return &Value{Type: TypeBulkString, Str: b}
+}
+
+// NewNullBulkString returns a null bulk string of RESP2
+func NewNullBulkString() *Value {
+	return &Value{Type: TypeBulkString, IsNull: true}
+}
+
+// NewArray returns an array value
+func NewArray(elems []*Value) *Value {
+	return &Value{Type: TypeArray, Elems: elems}
+}
+
+// NewCommand returns a command, which is an array of bulk strings
+func NewCommand(args ...[]byte) *Value {
+	elems := make([]*Value, len(args))
+	for i, arg := range args {
+		elems[i] = NewBulkString(arg)
+	}
+	return NewArray(elems)
+}
+
+// IsError returns true if the value is a simple error or a bulk error
+func (v *Value) IsError() bool {
+	return v.Type == TypeError || v.Type == TypeBulkError
+}
+
+// String returns the text of the value for the logs
+func (v *Value) String() string {
+	return string(v.AppendTo(nil))
+}
+
+// AppendTo appends the wire format of the value to dst
+func (v *Value) AppendTo(dst []byte) []byte {
+	if len(v.Attrs) > 0 {
+		dst = appendHeader(dst, TypeAttribute, int64(len(v.Attrs)/2))
+		for _, e := range v.Attrs {
+			dst = e.AppendTo(dst)
+		}
+	}
+	switch v.Type {
+	case TypeSimpleString, TypeError, TypeDouble, TypeBigNumber:
+		dst = append(dst, v.Type)
+		dst = append(dst, v.Str...)
+		dst = append(dst, crlf...)
+	case TypeBoolean:
+		dst = append(dst, v.Type)
+		if len(v.Str) > 0 && v.Str[0] == 't' {
+			dst = append(dst, 't')
+		} else {
+			dst = append(dst, 'f')
+		}
+		dst = append(dst, crlf...)
+	case TypeInteger:
+		dst = appendHeader(dst, v.Type, v.Int)
+	case TypeNull:
+		dst = append(dst, TypeNull, '\r', '\n')
+	case TypeBulkString, TypeBulkError, TypeVerbatimString:
+		if v.IsNull {
+			return append(dst, v.Type, '-', '1', '\r', '\n')
+		}
+		dst = appendHeader(dst, v.Type, int64(len(v.Str)))
+		dst = append(dst, v.Str...)
+		dst = append(dst, crlf...)
+	case TypeArray, TypeSet, TypePush:
+		if v.IsNull {
+			return append(dst, v.Type, '-', '1', '\r', '\n')
+		}
+		dst = appendHeader(dst, v.Type, int64(len(v.Elems)))
+		for _, e := range v.Elems {
+			dst = e.AppendTo(dst)
+		}
+	case TypeMap:
+		dst = appendHeader(dst, v.Type, int64(len(v.Elems)/2))
+		for _, e := range v.Elems {
+			dst = e.AppendTo(dst)
+		}
+	}
+	return dst
+}
+
+func appendHeader(dst []byte, t byte, n int64) []byte {
+	dst = append(dst, t)
+	dst = strconv.AppendInt(dst, n, 10)
+	return append(dst, crlf...)
+}
+
+// Decode decodes a value from the head of data, returns the value and the number of bytes consumed.
+// ErrIncomplete is returned if data does not contain a whole value.
+func Decode(data []byte) (*Value, int, error) {
+	return decode(data, 0, 0)
+}
+
+func decode(data []byte, pos int, depth int) (*Value, int, error) {
+	if depth > maxDepth {
+		return nil, 0, ErrProtocol
+	}
+	line, next, err := readLine(data, pos)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(line) == 0 {
+		return nil, 0, ErrProtocol
+	}
+	v := &Value{Type: line[0]}
+	body := line[1:]
+	switch v.Type {
+	case TypeSimpleString, TypeError, TypeDouble, TypeBigNumber:
+		v.Str = copyBytes(body)
+	case TypeBoolean:
+		if len(body) != 1 || (body[0] != 't' && body[0] != 'f') {
+			return nil, 0, ErrProtocol
+		}
+		v.Str = copyBytes(body)
+	case TypeNull:
+		if len(body) != 0 {
+			return nil, 0, ErrProtocol
+		}
+	case TypeInteger:
+		i, err := strconv.ParseInt(string(body), 10, 64)
+		if err != nil {
+			return nil, 0, ErrProtocol
+		}
+		v.Int = i
+	case TypeBulkString, TypeBulkError, TypeVerbatimString:
+		n, err := parseLength(body, maxBulkLength)
+		if err != nil {
+			return nil, 0, err
+		}
+		if n < 0 {
+			v.IsNull = true
+			return v, next - pos, nil
+		}
+		end := next + int(n)
+		if len(data) < end+2 {
+			return nil, 0, ErrIncomplete
+		}
+		if data[end] != '\r' || data[end+1] != '\n' {
+			return nil, 0, ErrProtocol
+		}
+		v.Str = copyBytes(data[next:end])
+		next = end + 2
+	case TypeArray, TypeSet, TypePush, TypeMap, TypeAttribute:
+		n, err := parseLength(body, maxElements)
+		if err != nil {
+			return nil, 0, err
+		}
+		if n < 0 {
+			if v.Type != TypeArray {
+				return nil, 0, ErrProtocol
+			}
+			v.IsNull = true
+			return v, next - pos, nil
+		}
+		if v.Type == TypeMap || v.Type == TypeAttribute {
+			n *= 2
+		}
+		v.Elems = make([]*Value, 0, n)
+		for i := int64(0); i < n; i++ {
+			e, consumed, err := decode(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			v.Elems = append(v.Elems, e)
+			next += consumed
+		}
+		if v.Type == TypeAttribute {
+			// the attribute is attached to the value follows it
+			attrs := v.Elems
+			v, consumed, err := decode(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			v.Attrs = attrs
+			return v, next + consumed - pos, nil
+		}
+	default:
+		return nil, 0, ErrProtocol
+	}
+	return v, next - pos, nil
+}
+
+// DecodeCommand decodes a command from the head of data, the command is an array of
+// bulk strings, or an inline command separated by the spaces.
+func DecodeCommand(data []byte) (*Value, int, error) {
+	if len(data) == 0 {
+		return nil, 0, ErrIncomplete
+	}
+	if data[0] == TypeArray {
+		v, n, err := Decode(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		if v.IsNull {
+			return nil, 0, ErrProtocol
+		}
+		for _, e := range v.Elems {
+			if e.Type != TypeBulkString || e.IsNull {
+				return nil, 0, ErrProtocol
+			}
+		}
+		return v, n, nil
+	}
+	idx := bytes.IndexByte(data, '\n')
+	if idx < 0 {
+		if len(data) > maxInlineLength {
+			return nil, 0, ErrProtocol
+		}
+		return nil, 0, ErrIncomplete
+	}
+	line := bytes.TrimRight(data[:idx], "\r")
+	fields := bytes.Fields(line)
+	args := make([][]byte, len(fields))
+	for i, f := range fields {
+		args[i] = copyBytes(f)
+	}
+	return NewCommand(args...), idx + 1, nil
+}
+
+func readLine(data []byte, pos int) ([]byte, int, error) {
+	idx := bytes.Index(data[pos:], crlf)
+	if idx < 0 {
+		if len(data)-pos > maxInlineLength {
+			return nil, 0, ErrProtocol
+		}
+		return nil, 0, ErrIncomplete
+	}
+	return data[pos : pos+idx], pos + idx + 2, nil
+}
+
+func parseLength(b []byte, max int64) (int64, error) {
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || n < -1 || n > max {
+		return 0, ErrProtocol
+	}
+	return n, nil
+}
+
+func copyBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
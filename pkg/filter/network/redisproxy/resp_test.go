@@ -0,0 +1,148 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeRESP2(t *testing.T) {
+	cases := []struct {
+		data  string
+		value *Value
+	}{
+		{"+OK\r\n", NewSimpleString("OK")},
+		{"-ERR unknown\r\n", NewError("ERR unknown")},
+		{":-42\r\n", NewInteger(-42)},
+		{"$5\r\nhello\r\n", NewBulkString([]byte("hello"))},
+		{"$0\r\n\r\n", NewBulkString([]byte{})},
+		{"$-1\r\n", NewNullBulkString()},
+		{"*-1\r\n", &Value{Type: TypeArray, IsNull: true}},
+		{"*2\r\n$3\r\nfoo\r\n:1\r\n", NewArray([]*Value{NewBulkString([]byte("foo")), NewInteger(1)})},
+	}
+	for _, c := range cases {
+		v, n, err := Decode([]byte(c.data))
+		if err != nil || n != len(c.data) {
+			t.Errorf("decode %q failed: %d %v", c.data, n, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, c.value) {
+			t.Errorf("decode %q expected %+v, but got %+v", c.data, c.value, v)
+		}
+		if encoded := string(v.AppendTo(nil)); encoded != c.data {
+			t.Errorf("encode %q got %q", c.data, encoded)
+		}
+	}
+}
+
+func TestDecodeRESP3(t *testing.T) {
+	cases := []string{
+		"_\r\n",
+		"#t\r\n",
+		",3.14\r\n",
+		"(3492890328409238509324850943850943825024385\r\n",
+		"!21\r\nSYNTAX invalid syntax\r\n",
+		"=15\r\ntxt:Some string\r\n",
+		"%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n",
+		"~2\r\n+a\r\n+b\r\n",
+		">3\r\n+message\r\n+channel\r\n+hello\r\n",
+		"|1\r\n+ttl\r\n:3600\r\n$3\r\nfoo\r\n",
+	}
+	for _, c := range cases {
+		v, n, err := Decode([]byte(c))
+		if err != nil || n != len(c) {
+			t.Errorf("decode %q failed: %d %v", c, n, err)
+			continue
+		}
+		if encoded := string(v.AppendTo(nil)); encoded != c {
+			t.Errorf("encode %q got %q", c, encoded)
+		}
+	}
+	v, _, _ := Decode([]byte("%1\r\n+k\r\n*1\r\n:1\r\n"))
+	if v.Type != TypeMap || len(v.Elems) != 2 || v.Elems[1].Type != TypeArray {
+		t.Errorf("decode map got %+v", v)
+	}
+	v, _, _ = Decode([]byte("|1\r\n+ttl\r\n:3600\r\n$3\r\nfoo\r\n"))
+	if v.Type != TypeBulkString || string(v.Str) != "foo" || len(v.Attrs) != 2 {
+		t.Errorf("decode attribute got %+v", v)
+	}
+}
+
+func TestDecodeIncomplete(t *testing.T) {
+	data := []byte("*2\r\n$3\r\nfoo\r\n$5\r\nhello\r\n")
+	for i := 0; i < len(data); i++ {
+		if _, _, err := Decode(data[:i]); err != ErrIncomplete {
+			t.Errorf("decode %q expected incomplete, but got %v", data[:i], err)
+		}
+	}
+	if _, n, err := Decode(data); err != nil || n != len(data) {
+		t.Errorf("decode failed: %d %v", n, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	cases := []string{
+		"?foo\r\n",
+		":abc\r\n",
+		"$3\r\nfoobar\r\n",
+		"$-2\r\n",
+		"#x\r\n",
+		"_x\r\n",
+		"%-1\r\n",
+		"*2\r\n+a\r\n?\r\n",
+	}
+	for _, c := range cases {
+		if _, _, err := Decode([]byte(c)); err != ErrProtocol {
+			t.Errorf("decode %q expected protocol error, but got %v", c, err)
+		}
+	}
+	// too deep
+	data := bytes.Repeat([]byte("*1\r\n"), maxDepth+2)
+	data = append(data, ":1\r\n"...)
+	if _, _, err := Decode(data); err != ErrProtocol {
+		t.Errorf("decode deep array expected protocol error, but got %v", err)
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	data := []byte("*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\nSET  bar 1\r\nPING\n")
+	var names []string
+	for len(data) > 0 {
+		cmd, n, err := DecodeCommand(data)
+		if err != nil {
+			t.Fatalf("decode command failed: %v", err)
+		}
+		names = append(names, commandName(cmd))
+		data = data[n:]
+	}
+	if !reflect.DeepEqual(names, []string{"GET", "SET", "PING"}) {
+		t.Errorf("decode commands got %v", names)
+	}
+	cmd, _, _ := DecodeCommand([]byte("SET  bar 1\r\n"))
+	if len(cmd.Elems) != 3 || string(cmd.Elems[1].Str) != "bar" {
+		t.Errorf("decode inline command got %s", cmd)
+	}
+	if _, _, err := DecodeCommand([]byte("*1\r\n:1\r\n")); err != ErrProtocol {
+		t.Errorf("decode command of integer expected protocol error, but got %v", err)
+	}
+	if _, _, err := DecodeCommand([]byte("GET foo")); err != ErrIncomplete {
+		t.Errorf("decode inline command expected incomplete, but got %v", err)
+	}
+}
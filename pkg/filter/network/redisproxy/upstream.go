@@ -0,0 +1,219 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redisproxy
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+// replyCallback is called with the reply of a command, or an error value if the command fails
+type replyCallback func(reply *Value)
+
+func ignoreReply(reply *Value) {}
+
+// clientPool keeps a pipelined connection to each upstream address, the connections
+// are shared by all the sessions of a redis proxy
+type clientPool struct {
+	mutex   sync.Mutex
+	clients map[string]*upstreamClient
+}
+
+func newClientPool() *clientPool {
+	return &clientPool{
+		clients: make(map[string]*upstreamClient),
+	}
+}
+
+// get returns the client of the address speaks the protocol version, creates it if it does not exist
+func (p *clientPool) get(address string, protover int, connectTimeout time.Duration) (*upstreamClient, error) {
+	key := address + "#" + strconv.Itoa(protover)
+	p.mutex.Lock()
+	c, ok := p.clients[key]
+	p.mutex.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	c, err := newUpstreamClient(p, key, address, protover, connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	p.mutex.Lock()
+	if exist, ok := p.clients[key]; ok {
+		p.mutex.Unlock()
+		// created by another session concurrently
+		c.conn.Close(types.NoFlush, types.LocalClose)
+		return exist, nil
+	}
+	p.clients[key] = c
+	p.mutex.Unlock()
+	return c, nil
+}
+
+func (p *clientPool) remove(c *upstreamClient) {
+	p.mutex.Lock()
+	if p.clients[c.key] == c {
+		delete(p.clients, c.key)
+	}
+	p.mutex.Unlock()
+}
+
+// upstreamClient is a pipelined connection to an upstream redis, the replies are
+// matched to the commands in order
+type upstreamClient struct {
+	pool    *clientPool
+	key     string
+	address string
+	conn    types.ClientConnection
+
+	mutex   sync.Mutex
+	pending []replyCallback
+	closed  bool
+}
+
+func newUpstreamClient(pool *clientPool, key, address string, protover int, connectTimeout time.Duration) (*upstreamClient, error) {
+	addr, err := resolveAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	c := &upstreamClient{
+		pool:    pool,
+		key:     key,
+		address: address,
+		conn:    network.NewClientConnection(nil, connectTimeout, nil, addr, nil),
+	}
+	c.conn.FilterManager().AddReadFilter(c)
+	c.conn.AddConnectionEventListener(c)
+	if err := c.conn.Connect(); err != nil {
+		return nil, err
+	}
+	if protover == 3 {
+		// switch the connection to RESP3 before any command is sent
+		c.send(NewCommand([]byte("HELLO"), []byte("3")), func(reply *Value) {
+			if reply.IsError() {
+				log.DefaultLogger.Errorf("[redisproxy] switch upstream %s to RESP3 failed: %s", address, reply.Str)
+			}
+		}, false)
+	}
+	return c, nil
+}
+
+func resolveAddress(address string) (net.Addr, error) {
+	if addr, ok := utils.ParseUnixAddr(address); ok {
+		return addr, nil
+	}
+	return net.ResolveTCPAddr("tcp", address)
+}
+
+// send writes the command to the upstream, the callback is called with the reply.
+// If asking is true, an ASKING is sent before the command, as the redirection of an ASK reply requires.
+func (c *upstreamClient) send(cmd *Value, cb replyCallback, asking bool) {
+	var data []byte
+	if asking {
+		data = NewCommand([]byte("ASKING")).AppendTo(data)
+	}
+	data = cmd.AppendTo(data)
+
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		cb(NewError("ERR upstream connection closed"))
+		return
+	}
+	if asking {
+		c.pending = append(c.pending, ignoreReply)
+	}
+	c.pending = append(c.pending, cb)
+	err := c.conn.Write(buffer.NewIoBufferBytes(data))
+	c.mutex.Unlock()
+
+	if err != nil {
+		log.DefaultLogger.Errorf("[redisproxy] write to upstream %s failed: %v", c.address, err)
+		c.conn.Close(types.NoFlush, types.LocalClose)
+	}
+}
+
+func (c *upstreamClient) popCallback() replyCallback {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.pending) == 0 {
+		return nil
+	}
+	cb := c.pending[0]
+	c.pending[0] = nil
+	c.pending = c.pending[1:]
+	return cb
+}
+
+// OnData decodes the replies and calls the callbacks in order
+func (c *upstreamClient) OnData(buf types.IoBuffer) types.FilterStatus {
+	for buf.Len() > 0 {
+		reply, n, err := Decode(buf.Bytes())
+		if err == ErrIncomplete {
+			break
+		}
+		if err != nil {
+			log.DefaultLogger.Errorf("[redisproxy] decode reply from upstream %s failed: %v", c.address, err)
+			buf.Drain(buf.Len())
+			c.conn.Close(types.NoFlush, types.LocalClose)
+			break
+		}
+		buf.Drain(n)
+		// the push messages are out of band, they are not replies of the commands
+		if reply.Type == TypePush {
+			continue
+		}
+		if cb := c.popCallback(); cb != nil {
+			cb(reply)
+		} else {
+			log.DefaultLogger.Warnf("[redisproxy] unexpected reply from upstream %s: %s", c.address, reply)
+		}
+	}
+	return types.Stop
+}
+
+func (c *upstreamClient) OnNewConnection() types.FilterStatus {
+	return types.Continue
+}
+
+func (c *upstreamClient) InitializeReadFilterCallbacks(cb types.ReadFilterCallbacks) {}
+
+// OnEvent fails the pending commands when the connection is closed
+func (c *upstreamClient) OnEvent(event types.ConnectionEvent) {
+	if !event.IsClose() && !event.ConnectFailure() {
+		return
+	}
+	c.pool.remove(c)
+	c.mutex.Lock()
+	c.closed = true
+	pending := c.pending
+	c.pending = nil
+	c.mutex.Unlock()
+	for _, cb := range pending {
+		cb(NewError("ERR upstream connection closed"))
+	}
+}
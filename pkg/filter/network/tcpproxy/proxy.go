@@ -420,6 +420,7 @@ func (c *LbContext) DownstreamHeaders() types.HeaderMap {
 	return nil
 }
 
+// DownstreamContext carries the downstream connection, which is used to send the PROXY protocol header
 func (c *LbContext) DownstreamContext() context.Context {
-	return nil
+	return mosnctx.WithValue(context.Background(), types.ContextKeyDownstreamConnection, c.conn.Connection())
 }
@@ -0,0 +1,339 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package udpproxy
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+// DefaultIdleTimeout is the idle timeout of a session if it is not configured
+const DefaultIdleTimeout = 60 * time.Second
+
+type proxyStats struct {
+	SessionTotal       gometrics.Counter
+	SessionActive      gometrics.Counter
+	SessionOverflow    gometrics.Counter
+	SessionIdleTimeout gometrics.Counter
+	SessionFailed      gometrics.Counter
+	DatagramsReceived  gometrics.Counter
+	DatagramsSent      gometrics.Counter
+	DatagramsDropped   gometrics.Counter
+	BytesReceived      gometrics.Counter
+	BytesSent          gometrics.Counter
+}
+
+func newProxyStats(statPrefix string) *proxyStats {
+	s := metrics.NewUDPProxyStats(statPrefix)
+	return &proxyStats{
+		SessionTotal:       s.Counter(metrics.UDPSessionTotal),
+		SessionActive:      s.Counter(metrics.UDPSessionActive),
+		SessionOverflow:    s.Counter(metrics.UDPSessionOverflow),
+		SessionIdleTimeout: s.Counter(metrics.UDPSessionIdleTimeout),
+		SessionFailed:      s.Counter(metrics.UDPSessionFailed),
+		DatagramsReceived:  s.Counter(metrics.UDPDatagramsReceived),
+		DatagramsSent:      s.Counter(metrics.UDPDatagramsSent),
+		DatagramsDropped:   s.Counter(metrics.UDPDatagramsDropped),
+		BytesReceived:      s.Counter(metrics.UDPBytesReceived),
+		BytesSent:          s.Counter(metrics.UDPBytesSent),
+	}
+}
+
+// proxyConfig is shared by the sessions of a udp proxy
+type proxyConfig struct {
+	cluster     string
+	idleTimeout time.Duration
+	maxSessions int64
+	// activeSessions counts the sessions that hold an upstream socket
+	activeSessions int64
+	stats          *proxyStats
+}
+
+func newProxyConfig(config *v2.UDPProxy) *proxyConfig {
+	idleTimeout := DefaultIdleTimeout
+	if config.IdleTimeout != nil && config.IdleTimeout.Duration > 0 {
+		idleTimeout = config.IdleTimeout.Duration
+	}
+	statPrefix := config.StatPrefix
+	if statPrefix == "" {
+		statPrefix = config.Cluster
+	}
+	return &proxyConfig{
+		cluster:     config.Cluster,
+		idleTimeout: idleTimeout,
+		maxSessions: int64(config.MaxSessions),
+		stats:       newProxyStats(statPrefix),
+	}
+}
+
+// acquire reserves a session, returns false if the max sessions is reached
+func (pc *proxyConfig) acquire() bool {
+	active := atomic.AddInt64(&pc.activeSessions, 1)
+	if pc.maxSessions > 0 && active > pc.maxSessions {
+		atomic.AddInt64(&pc.activeSessions, -1)
+		return false
+	}
+	return true
+}
+
+func (pc *proxyConfig) release() {
+	atomic.AddInt64(&pc.activeSessions, -1)
+}
+
+// session is the read filter of a udp session, it relays the datagrams between the
+// downstream client and an upstream host through a connected udp socket.
+// The datagrams received from the upstream socket are written to the downstream
+// connection one buffer per datagram, so the datagram boundaries are kept.
+type session struct {
+	ctx            context.Context
+	config         *proxyConfig
+	clusterManager types.ClusterManager
+	readCallbacks  types.ReadFilterCallbacks
+
+	upstream *net.UDPConn
+	host     types.Host
+	acquired bool
+
+	// per-session stats
+	datagramsReceived uint64
+	datagramsSent     uint64
+	bytesReceived     uint64
+	bytesSent         uint64
+
+	lastActive int64
+	timerMutex sync.Mutex
+	idleTimer  *time.Timer
+	closed     uint32
+}
+
+func newSession(ctx context.Context, config *proxyConfig, clusterManager types.ClusterManager) *session {
+	return &session{
+		ctx:            ctx,
+		config:         config,
+		clusterManager: clusterManager,
+	}
+}
+
+func (s *session) InitializeReadFilterCallbacks(cb types.ReadFilterCallbacks) {
+	s.readCallbacks = cb
+	s.readCallbacks.Connection().AddConnectionEventListener(s)
+}
+
+func (s *session) OnNewConnection() types.FilterStatus {
+	if !s.config.acquire() {
+		log.DefaultLogger.Warnf("[udpproxy] max sessions %d reached, drop the session from %s", s.config.maxSessions, s.readCallbacks.Connection().RemoteAddr())
+		s.config.stats.SessionOverflow.Inc(1)
+		s.onInitFailure()
+		return types.Stop
+	}
+	s.acquired = true
+
+	if !s.initializeUpstream() {
+		s.config.stats.SessionFailed.Inc(1)
+		s.onInitFailure()
+		return types.Stop
+	}
+
+	s.config.stats.SessionTotal.Inc(1)
+	s.config.stats.SessionActive.Inc(1)
+	s.touch()
+	s.timerMutex.Lock()
+	s.idleTimer = time.AfterFunc(s.config.idleTimeout, s.onIdleTimeout)
+	s.timerMutex.Unlock()
+
+	utils.GoWithRecover(s.readUpstream, nil)
+
+	return types.Continue
+}
+
+func (s *session) initializeUpstream() bool {
+	clusterSnapshot := s.clusterManager.GetClusterSnapshot(context.Background(), s.config.cluster)
+	if clusterSnapshot == nil || reflect.ValueOf(clusterSnapshot).IsNil() {
+		log.DefaultLogger.Errorf("[udpproxy] cluster %s is not found", s.config.cluster)
+		return false
+	}
+
+	connections := clusterSnapshot.ClusterInfo().ResourceManager().Connections()
+	if !connections.CanCreate() {
+		return false
+	}
+
+	host := clusterSnapshot.LoadBalancer().ChooseHost(s)
+	if host == nil {
+		log.DefaultLogger.Errorf("[udpproxy] no healthy upstream in cluster %s", s.config.cluster)
+		return false
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", host.AddressString())
+	if err != nil {
+		log.DefaultLogger.Errorf("[udpproxy] resolve upstream address %s failed: %v", host.AddressString(), err)
+		return false
+	}
+	upstream, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		log.DefaultLogger.Errorf("[udpproxy] create upstream socket to %s failed: %v", host.AddressString(), err)
+		return false
+	}
+
+	connections.Increase()
+	s.host = host
+	s.upstream = upstream
+	s.readCallbacks.SetUpstreamHost(host)
+
+	if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+		log.DefaultLogger.Debugf("[udpproxy] new session %s -> %s", s.readCallbacks.Connection().RemoteAddr(), upstream.RemoteAddr())
+	}
+	return true
+}
+
+func (s *session) onInitFailure() {
+	s.readCallbacks.Connection().Close(types.NoFlush, types.LocalClose)
+}
+
+// OnData is called with a whole datagram received from the downstream client
+func (s *session) OnData(buf types.IoBuffer) types.FilterStatus {
+	n := buf.Len()
+	if s.upstream == nil || atomic.LoadUint32(&s.closed) == 1 {
+		buf.Drain(n)
+		return types.Stop
+	}
+	if _, err := s.upstream.Write(buf.Bytes()); err != nil {
+		log.DefaultLogger.Debugf("[udpproxy] send datagram to %s failed: %v", s.upstream.RemoteAddr(), err)
+		s.config.stats.DatagramsDropped.Inc(1)
+	} else {
+		atomic.AddUint64(&s.datagramsReceived, 1)
+		atomic.AddUint64(&s.bytesReceived, uint64(n))
+		s.config.stats.DatagramsReceived.Inc(1)
+		s.config.stats.BytesReceived.Inc(int64(n))
+	}
+	buf.Drain(n)
+	s.touch()
+	return types.Stop
+}
+
+// readUpstream relays the datagrams from the upstream socket until the socket is closed
+func (s *session) readUpstream() {
+	buf := make([]byte, network.MaxDatagramSize)
+	for {
+		n, err := s.upstream.Read(buf)
+		if err != nil {
+			if atomic.LoadUint32(&s.closed) == 0 {
+				log.DefaultLogger.Debugf("[udpproxy] read datagram from %s failed: %v", s.upstream.RemoteAddr(), err)
+				s.readCallbacks.Connection().Close(types.FlushWrite, types.LocalClose)
+			}
+			return
+		}
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+		if err := s.readCallbacks.Connection().Write(buffer.NewIoBufferBytes(datagram)); err != nil {
+			s.config.stats.DatagramsDropped.Inc(1)
+			continue
+		}
+		atomic.AddUint64(&s.datagramsSent, 1)
+		atomic.AddUint64(&s.bytesSent, uint64(n))
+		s.config.stats.DatagramsSent.Inc(1)
+		s.config.stats.BytesSent.Inc(int64(n))
+		s.touch()
+	}
+}
+
+func (s *session) touch() {
+	atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+}
+
+func (s *session) onIdleTimeout() {
+	if atomic.LoadUint32(&s.closed) == 1 {
+		return
+	}
+	idle := time.Since(time.Unix(0, atomic.LoadInt64(&s.lastActive)))
+	if idle < s.config.idleTimeout {
+		s.timerMutex.Lock()
+		s.idleTimer.Reset(s.config.idleTimeout - idle)
+		s.timerMutex.Unlock()
+		return
+	}
+	log.DefaultLogger.Debugf("[udpproxy] session %s is idle for %s, close it", s.readCallbacks.Connection().RemoteAddr(), idle)
+	s.config.stats.SessionIdleTimeout.Inc(1)
+	s.readCallbacks.Connection().Close(types.NoFlush, types.LocalClose)
+}
+
+// OnEvent closes the session when the downstream connection is closed
+func (s *session) OnEvent(event types.ConnectionEvent) {
+	if event.IsClose() {
+		s.onClose()
+	}
+}
+
+func (s *session) onClose() {
+	if !atomic.CompareAndSwapUint32(&s.closed, 0, 1) {
+		return
+	}
+	s.timerMutex.Lock()
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+	}
+	s.timerMutex.Unlock()
+
+	if s.upstream != nil {
+		s.upstream.Close()
+		s.host.ClusterInfo().ResourceManager().Connections().Decrease()
+		s.config.stats.SessionActive.Dec(1)
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[udpproxy] session %s -> %s closed, datagrams received %d, datagrams sent %d, bytes received %d, bytes sent %d",
+				s.readCallbacks.Connection().RemoteAddr(), s.upstream.RemoteAddr(),
+				atomic.LoadUint64(&s.datagramsReceived), atomic.LoadUint64(&s.datagramsSent),
+				atomic.LoadUint64(&s.bytesReceived), atomic.LoadUint64(&s.bytesSent))
+		}
+	}
+	if s.acquired {
+		s.config.release()
+	}
+}
+
+// MetadataMatchCriteria implements types.LoadBalancerContext
+func (s *session) MetadataMatchCriteria() types.MetadataMatchCriteria {
+	return nil
+}
+
+// DownstreamConnection implements types.LoadBalancerContext
+func (s *session) DownstreamConnection() net.Conn {
+	return s.readCallbacks.Connection().RawConn()
+}
+
+// DownstreamHeaders implements types.LoadBalancerContext, the datagrams have no header
+func (s *session) DownstreamHeaders() types.HeaderMap {
+	return nil
+}
+
+// DownstreamContext implements types.LoadBalancerContext
+func (s *session) DownstreamContext() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,349 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package udpproxy
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockClusterManager struct {
+	types.ClusterManager
+	host types.Host
+}
+
+func (m *mockClusterManager) GetClusterSnapshot(ctx context.Context, name string) types.ClusterSnapshot {
+	return &mockClusterSnapshot{host: m.host}
+}
+
+func (m *mockClusterManager) PutClusterSnapshot(snapshot types.ClusterSnapshot) {
+}
+
+type mockClusterSnapshot struct {
+	types.ClusterSnapshot
+	host types.Host
+}
+
+func (s *mockClusterSnapshot) ClusterInfo() types.ClusterInfo {
+	return mockInfo
+}
+
+func (s *mockClusterSnapshot) LoadBalancer() types.LoadBalancer {
+	return &mockLoadBalancer{host: s.host}
+}
+
+type mockLoadBalancer struct {
+	types.LoadBalancer
+	host types.Host
+}
+
+func (lb *mockLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	if lb.host == nil {
+		return nil
+	}
+	return lb.host
+}
+
+var (
+	mockResources = &mockResourceManager{}
+	mockInfo      = &mockClusterInfo{}
+)
+
+type mockClusterInfo struct {
+	types.ClusterInfo
+}
+
+func (ci *mockClusterInfo) ResourceManager() types.ResourceManager {
+	return mockResources
+}
+
+type mockResourceManager struct {
+	types.ResourceManager
+	connections int64
+}
+
+func (rm *mockResourceManager) Connections() types.Resource {
+	return rm
+}
+
+func (rm *mockResourceManager) CanCreate() bool {
+	return true
+}
+
+func (rm *mockResourceManager) Increase() {
+	atomic.AddInt64(&rm.connections, 1)
+}
+
+func (rm *mockResourceManager) Decrease() {
+	atomic.AddInt64(&rm.connections, -1)
+}
+
+func (rm *mockResourceManager) Max() uint64 {
+	return 0
+}
+
+type mockHost struct {
+	types.Host
+	address string
+}
+
+func (h *mockHost) AddressString() string {
+	return h.address
+}
+
+func (h *mockHost) ClusterInfo() types.ClusterInfo {
+	return mockInfo
+}
+
+type mockReadFilterCallbacks struct {
+	types.ReadFilterCallbacks
+	conn *mockConnection
+}
+
+func (cb *mockReadFilterCallbacks) Connection() types.Connection {
+	return cb.conn
+}
+
+func (cb *mockReadFilterCallbacks) SetUpstreamHost(host types.HostInfo) {
+}
+
+// mockConnection records the written datagrams
+type mockConnection struct {
+	types.Connection
+	mutex     sync.Mutex
+	listeners []types.ConnectionEventListener
+	written   chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newMockConnection() *mockConnection {
+	return &mockConnection{
+		written: make(chan []byte, 16),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (c *mockConnection) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53000}
+}
+
+func (c *mockConnection) AddConnectionEventListener(listener types.ConnectionEventListener) {
+	c.mutex.Lock()
+	c.listeners = append(c.listeners, listener)
+	c.mutex.Unlock()
+}
+
+func (c *mockConnection) Write(buffers ...types.IoBuffer) error {
+	for _, b := range buffers {
+		c.written <- b.Bytes()
+	}
+	return nil
+}
+
+func (c *mockConnection) Close(ccType types.ConnectionCloseType, eventType types.ConnectionEvent) error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.mutex.Lock()
+		listeners := c.listeners
+		c.mutex.Unlock()
+		for _, l := range listeners {
+			l.OnEvent(eventType)
+		}
+	})
+	return nil
+}
+
+func (c *mockConnection) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// startEchoServer starts an udp server that sends back the received datagrams
+func startEchoServer(t *testing.T) *net.UDPConn {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("start echo server failed: %v", err)
+	}
+	go func() {
+		buf := make([]byte, 1<<16)
+		for {
+			n, addr, err := server.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			server.WriteToUDP(buf[:n], addr)
+		}
+	}()
+	return server
+}
+
+func newTestSession(config *proxyConfig, host types.Host) (*session, *mockConnection) {
+	conn := newMockConnection()
+	s := newSession(context.Background(), config, &mockClusterManager{host: host})
+	s.InitializeReadFilterCallbacks(&mockReadFilterCallbacks{conn: conn})
+	return s, conn
+}
+
+func TestNewProxyConfig(t *testing.T) {
+	pc := newProxyConfig(&v2.UDPProxy{
+		Cluster: "dns",
+	})
+	if pc.idleTimeout != DefaultIdleTimeout || pc.maxSessions != 0 {
+		t.Fatalf("unexpected default config: %+v", pc)
+	}
+	pc = newProxyConfig(&v2.UDPProxy{
+		Cluster:     "dns",
+		IdleTimeout: &v2.DurationConfig{Duration: time.Second},
+		MaxSessions: 1,
+	})
+	if pc.idleTimeout != time.Second || pc.maxSessions != 1 {
+		t.Fatalf("unexpected config: %+v", pc)
+	}
+	if !pc.acquire() || pc.acquire() {
+		t.Fatal("max sessions is not limited")
+	}
+	pc.release()
+	if !pc.acquire() {
+		t.Fatal("session is not released")
+	}
+}
+
+func TestSessionRelay(t *testing.T) {
+	server := startEchoServer(t)
+	defer server.Close()
+	config := newProxyConfig(&v2.UDPProxy{StatPrefix: "test_relay", Cluster: "dns"})
+	s, conn := newTestSession(config, &mockHost{address: server.LocalAddr().String()})
+	if status := s.OnNewConnection(); status != types.Continue {
+		t.Fatalf("new session failed: %v", status)
+	}
+	for _, datagram := range []string{"hello", "world"} {
+		if status := s.OnData(buffer.NewIoBufferString(datagram)); status != types.Stop {
+			t.Fatalf("on data returns %v", status)
+		}
+		select {
+		case data := <-conn.written:
+			if string(data) != datagram {
+				t.Fatalf("downstream received %q, expected %q", data, datagram)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no datagram is relayed to downstream")
+		}
+	}
+	if atomic.LoadUint64(&s.datagramsReceived) != 2 || atomic.LoadUint64(&s.bytesReceived) != 10 {
+		t.Fatal("unexpected received stats")
+	}
+	// the sent stats are updated after the datagram is written
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadUint64(&s.datagramsSent) != 2 || atomic.LoadUint64(&s.bytesSent) != 10 {
+		t.Fatal("unexpected sent stats")
+	}
+	if config.stats.SessionActive.Count() != 1 || config.stats.DatagramsReceived.Count() != 2 {
+		t.Fatalf("unexpected proxy stats")
+	}
+
+	conn.Close(types.NoFlush, types.RemoteClose)
+	if atomic.LoadInt64(&config.activeSessions) != 0 || config.stats.SessionActive.Count() != 0 {
+		t.Fatal("session is not released")
+	}
+	if atomic.LoadInt64(&mockResources.connections) != 0 {
+		t.Fatal("upstream connection resource is not released")
+	}
+	if _, err := s.upstream.Write([]byte("closed")); err == nil {
+		t.Fatal("upstream socket is not closed")
+	}
+}
+
+func TestSessionMaxSessions(t *testing.T) {
+	server := startEchoServer(t)
+	defer server.Close()
+	config := newProxyConfig(&v2.UDPProxy{StatPrefix: "test_max_sessions", Cluster: "dns", MaxSessions: 1})
+	host := &mockHost{address: server.LocalAddr().String()}
+
+	s1, conn1 := newTestSession(config, host)
+	if status := s1.OnNewConnection(); status != types.Continue {
+		t.Fatalf("new session failed: %v", status)
+	}
+	s2, conn2 := newTestSession(config, host)
+	if status := s2.OnNewConnection(); status != types.Stop || !conn2.isClosed() {
+		t.Fatal("the session over max sessions is not closed")
+	}
+	if config.stats.SessionOverflow.Count() != 1 {
+		t.Fatal("overflow is not counted")
+	}
+
+	conn1.Close(types.NoFlush, types.LocalClose)
+	s3, conn3 := newTestSession(config, host)
+	if status := s3.OnNewConnection(); status != types.Continue {
+		t.Fatalf("new session failed: %v", status)
+	}
+	conn3.Close(types.NoFlush, types.LocalClose)
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	server := startEchoServer(t)
+	defer server.Close()
+	config := newProxyConfig(&v2.UDPProxy{
+		StatPrefix:  "test_idle",
+		Cluster:     "dns",
+		IdleTimeout: &v2.DurationConfig{Duration: 200 * time.Millisecond},
+	})
+	s, conn := newTestSession(config, &mockHost{address: server.LocalAddr().String()})
+	if status := s.OnNewConnection(); status != types.Continue {
+		t.Fatalf("new session failed: %v", status)
+	}
+	// the active session is not closed
+	for i := 0; i < 4; i++ {
+		time.Sleep(100 * time.Millisecond)
+		s.OnData(buffer.NewIoBufferString("keepalive"))
+	}
+	if conn.isClosed() {
+		t.Fatal("active session is closed")
+	}
+	select {
+	case <-conn.closed:
+	case <-time.After(time.Second):
+		t.Fatal("idle session is not closed")
+	}
+	if config.stats.SessionIdleTimeout.Count() != 1 || atomic.LoadInt64(&config.activeSessions) != 0 {
+		t.Fatal("unexpected idle timeout stats")
+	}
+}
+
+func TestSessionNoHealthyUpstream(t *testing.T) {
+	config := newProxyConfig(&v2.UDPProxy{StatPrefix: "test_no_upstream", Cluster: "dns", MaxSessions: 1})
+	s, conn := newTestSession(config, nil)
+	if status := s.OnNewConnection(); status != types.Stop || !conn.isClosed() {
+		t.Fatal("the session without upstream is not closed")
+	}
+	if config.stats.SessionFailed.Count() != 1 || atomic.LoadInt64(&config.activeSessions) != 0 {
+		t.Fatal("unexpected failed session stats")
+	}
+}
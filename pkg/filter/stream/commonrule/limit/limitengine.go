@@ -19,44 +19,75 @@ package limit
 
 import (
 	"errors"
+	"sync"
 
 	"mosn.io/mosn/pkg/filter/stream/commonrule/model"
 	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/runtime"
 )
 
 // LimitEngine limit
 type LimitEngine struct {
 	RuleConfig *model.RuleConfig
 	limiter    Limiter
+	// maxAllows is the max allows of the limiter, it may be changed by the runtime
+	maxAllows int
+	mux       sync.Mutex
 }
 
 // NewLimitEngine limit
 func NewLimitEngine(ruleConfig *model.RuleConfig) (*LimitEngine, error) {
-	l := &LimitEngine{
-		RuleConfig: ruleConfig,
-	}
 	config := ruleConfig.LimitConfig
+	maxAllows := int(runtime.GetInt(config.RuntimeKey, uint64(config.MaxAllows)))
+	limiter, err := newLimiter(config, maxAllows)
+	if err != nil {
+		return nil, err
+	}
+	return &LimitEngine{
+		RuleConfig: ruleConfig,
+		limiter:    limiter,
+		maxAllows:  maxAllows,
+	}, nil
+}
+
+func newLimiter(config model.LimitConfig, maxAllows int) (Limiter, error) {
 	if config.LimitStrategy == QPSStrategy {
-		limiter, err := NewQPSLimiter(int64(config.MaxAllows), int64(config.PeriodMs))
+		limiter, err := NewQPSLimiter(int64(maxAllows), int64(config.PeriodMs))
 		if err != nil {
 			log.DefaultLogger.Errorf("create NewQPSLimiter error, err: %s", err)
 			return nil, err
 		}
-		l.limiter = limiter
-		return l, nil
+		return limiter, nil
 	} else if config.LimitStrategy == RateLimiterStrategy {
-		limiter, err := NewRateLimiter(int64(config.MaxAllows), int64(config.PeriodMs), float64(config.MaxBurstRatio))
+		limiter, err := NewRateLimiter(int64(maxAllows), int64(config.PeriodMs), float64(config.MaxBurstRatio))
 		if err != nil {
 			log.DefaultLogger.Errorf("create NewRateLimiter error, err: %s", err)
 			return nil, err
 		}
-		l.limiter = limiter
-		return l, nil
+		return limiter, nil
 	}
 	return nil, errors.New("Unknown LimitStrategy type:" + config.LimitStrategy)
 }
 
 // OverLimit check limit
 func (engine *LimitEngine) OverLimit() bool {
-	return !engine.limiter.TryAcquire()
+	return !engine.getLimiter().TryAcquire()
+}
+
+// getLimiter returns the limiter, it is recreated if the max allows is changed by the runtime
+func (engine *LimitEngine) getLimiter() Limiter {
+	config := engine.RuleConfig.LimitConfig
+	if config.RuntimeKey == "" {
+		return engine.limiter
+	}
+	engine.mux.Lock()
+	defer engine.mux.Unlock()
+	maxAllows := int(runtime.GetInt(config.RuntimeKey, uint64(config.MaxAllows)))
+	if maxAllows != engine.maxAllows {
+		if limiter, err := newLimiter(config, maxAllows); err == nil {
+			engine.limiter = limiter
+			engine.maxAllows = maxAllows
+		}
+	}
+	return engine.limiter
 }
@@ -62,4 +62,6 @@ type LimitConfig struct {
 	MaxBurstRatio float64 `json:"max_burst_ratio"`
 	PeriodMs      int     `json:"period_ms"`
 	MaxAllows     int     `json:"max_allows"`
+	// RuntimeKey overrides the max allows by the runtime value
+	RuntimeKey string `json:"runtime_key,omitempty"`
 }
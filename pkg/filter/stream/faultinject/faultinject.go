@@ -27,6 +27,7 @@ import (
 	"mosn.io/mosn/pkg/config"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/runtime"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -40,12 +41,17 @@ type faultInjectConfig struct {
 	abortPercent uint32
 	upstream     string
 	headers      []*types.HeaderData
+	// runtime keys of the percentages
+	delayPercentRuntime string
+	abortPercentRuntime string
 }
 
 func makefaultInjectConfig(cfg *v2.StreamFaultInject) *faultInjectConfig {
 	faultConfig := &faultInjectConfig{
-		upstream: cfg.UpstreamCluster,
-		headers:  router.GetRouterHeaders(cfg.Headers),
+		upstream:            cfg.UpstreamCluster,
+		headers:             router.GetRouterHeaders(cfg.Headers),
+		delayPercentRuntime: cfg.DelayPercentRuntime,
+		abortPercentRuntime: cfg.AbortPercentRuntime,
 	}
 	if cfg.Delay != nil {
 		faultConfig.fixedDelay = cfg.Delay.Delay
@@ -87,7 +93,7 @@ type streamFaultInjectFilter struct {
 }
 
 func NewFilter(ctx context.Context, cfg *v2.StreamFaultInject) types.StreamReceiverFilter {
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(ctx) {
 		log.Proxy.Debugf(ctx, "[stream filter] [fault inject] create a new fault inject filter")
 	}
 	return &streamFaultInjectFilter{
@@ -105,7 +111,7 @@ func (f *streamFaultInjectFilter) ReadPerRouteConfig(cfg map[string]interface{})
 	}
 	if fault, ok := cfg[v2.FaultStream]; ok {
 		if config, ok := parseStreamFaultInjectConfig(fault); ok {
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(f.ctx) {
 				log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] use router config to replace stream filter config, config: %v", fault)
 			}
 			f.config = config
@@ -118,7 +124,7 @@ func (f *streamFaultInjectFilter) SetReceiveFilterHandler(handler types.StreamRe
 }
 
 func (f *streamFaultInjectFilter) OnReceive(ctx context.Context, headers types.HeaderMap, buf types.IoBuffer, trailers types.HeaderMap) types.StreamFilterStatus {
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(f.ctx) {
 		log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] fault inject filter do receive headers")
 	}
 	if route := f.handler.Route(); route != nil {
@@ -126,7 +132,7 @@ func (f *streamFaultInjectFilter) OnReceive(ctx context.Context, headers types.H
 		f.ReadPerRouteConfig(route.RouteRule().PerFilterConfig())
 	}
 	if !f.matchUpstream() {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(f.ctx) {
 			log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] upstream is not matched")
 		}
 		return types.StreamFilterContinue
@@ -136,21 +142,21 @@ func (f *streamFaultInjectFilter) OnReceive(ctx context.Context, headers types.H
 	//	return types.StreamHeadersFilterContinue
 	//}
 	if !router.ConfigUtilityInst.MatchHeaders(headers, f.config.headers) {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(f.ctx) {
 			log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] header is not matched, request headers: %v, config headers: %v", headers, f.config.headers)
 		}
 		return types.StreamFilterContinue
 	}
 	// TODO: some parameters can get from request header
 	if delay := f.getDelayDuration(); delay > 0 {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(f.ctx) {
 			log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] start a delay timer")
 		}
 		f.handler.RequestInfo().SetResponseFlag(types.DelayInjected)
 		select {
 		case <-time.After(delay):
 		case <-f.stop:
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(f.ctx) {
 				log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] timer is stopped")
 			}
 			return types.StreamFilterStop
@@ -172,13 +178,13 @@ func (f *streamFaultInjectFilter) OnDestroy() {
 func (f *streamFaultInjectFilter) matchUpstream() bool {
 	if f.config.upstream != "" {
 		if route := f.handler.Route(); route != nil {
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(f.ctx) {
 				log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] current cluster name %s, fault inject cluster name %s", route.RouteRule().ClusterName(), f.config.upstream)
 			}
 			return route.RouteRule().ClusterName() == f.config.upstream
 		}
 	}
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(f.ctx) {
 		log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] no upstream in config, returns true")
 	}
 	return true
@@ -186,15 +192,16 @@ func (f *streamFaultInjectFilter) matchUpstream() bool {
 
 func (f *streamFaultInjectFilter) getDelayDuration() time.Duration {
 	// percent is 0 or delay is 0 means no delay
-	if f.config.delayPercent == 0 || f.config.fixedDelay == 0 {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+	delayPercent := uint32(runtime.GetInt(f.config.delayPercentRuntime, uint64(f.config.delayPercent)))
+	if delayPercent == 0 || f.config.fixedDelay == 0 {
+		if log.DebugEnabled(f.ctx) {
 			log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] no delay inject")
 		}
 		return 0
 	}
 	// rander generates 0~99, if greater than percent means no delay
-	if (f.rander.Uint32() % 100) >= f.config.delayPercent {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if (f.rander.Uint32() % 100) >= delayPercent {
+		if log.DebugEnabled(f.ctx) {
 			log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] delay percent is not matched")
 		}
 		return 0
@@ -204,14 +211,15 @@ func (f *streamFaultInjectFilter) getDelayDuration() time.Duration {
 
 func (f *streamFaultInjectFilter) isAbort() bool {
 	// percent is 0 means no abort
-	if f.config.abortPercent == 0 {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+	abortPercent := uint32(runtime.GetInt(f.config.abortPercentRuntime, uint64(f.config.abortPercent)))
+	if abortPercent == 0 {
+		if log.DebugEnabled(f.ctx) {
 			log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] no abort inject")
 		}
 		return false
 	}
-	if (f.rander.Uint32() % 100) >= f.config.abortPercent {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if (f.rander.Uint32() % 100) >= abortPercent {
+		if log.DebugEnabled(f.ctx) {
 			log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] abort percent is not matched")
 		}
 		return false
@@ -221,7 +229,7 @@ func (f *streamFaultInjectFilter) isAbort() bool {
 
 // TODO: make a header
 func (f *streamFaultInjectFilter) abort(headers types.HeaderMap) {
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(f.ctx) {
 		log.Proxy.Debugf(f.ctx, "[stream filter] [fault inject] abort inject")
 	}
 	f.handler.RequestInfo().SetResponseFlag(types.FaultInjected)
@@ -0,0 +1,596 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// the protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// jsonObject is a json object keeps the fields order
+type jsonObject []jsonField
+
+type jsonField struct {
+	name  string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// get returns the value of the json name
+func (o jsonObject) get(name string) (interface{}, bool) {
+	for _, f := range o {
+		if f.name == name {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// ~~~ json to protobuf
+
+// encodeMessage encodes the json object into a protobuf message, the json is decoded with UseNumber
+func encodeMessage(mt *messageType, obj map[string]interface{}) ([]byte, error) {
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := proto.NewBuffer(nil)
+	for _, name := range names {
+		f, ok := mt.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %s in %s", name, mt.name)
+		}
+		if err := encodeField(buf, f, obj[name]); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeField(buf *proto.Buffer, f *field, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	switch {
+	case f.isMap():
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("field %s should be an object", f.name)
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		keyField, valueField := f.message.byNumber[1], f.message.byNumber[2]
+		for _, k := range keys {
+			entry := proto.NewBuffer(nil)
+			if err := encodeValue(entry, keyField, k); err != nil {
+				return err
+			}
+			if err := encodeValue(entry, valueField, obj[k]); err != nil {
+				return err
+			}
+			buf.EncodeVarint(uint64(f.number)<<3 | wireBytes)
+			buf.EncodeRawBytes(entry.Bytes())
+		}
+		return nil
+	case f.repeated:
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("field %s should be an array", f.name)
+		}
+		for _, item := range list {
+			if err := encodeValue(buf, f, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return encodeValue(buf, f, v)
+	}
+}
+
+func encodeValue(buf *proto.Buffer, f *field, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	tag := func(wire uint64) {
+		buf.EncodeVarint(uint64(f.number)<<3 | wire)
+	}
+	invalid := func(err error) error {
+		return fmt.Errorf("invalid value %v for field %s: %v", v, f.name, err)
+	}
+
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("field %s should be an object", f.name)
+		}
+		data, err := encodeMessage(f.message, obj)
+		if err != nil {
+			return err
+		}
+		tag(wireBytes)
+		buf.EncodeRawBytes(data)
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("field %s should be a string", f.name)
+		}
+		tag(wireBytes)
+		buf.EncodeStringBytes(s)
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("field %s should be a base64 string", f.name)
+		}
+		data, err := decodeBase64(s)
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireBytes)
+		buf.EncodeRawBytes(data)
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		var b bool
+		switch value := v.(type) {
+		case bool:
+			b = value
+		case string:
+			var err error
+			if b, err = strconv.ParseBool(value); err != nil {
+				return invalid(err)
+			}
+		default:
+			return fmt.Errorf("field %s should be a bool", f.name)
+		}
+		tag(wireVarint)
+		if b {
+			buf.EncodeVarint(1)
+		} else {
+			buf.EncodeVarint(0)
+		}
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		var n int64
+		if name, ok := v.(string); ok {
+			number, ok := f.enum.byName[name]
+			if !ok {
+				// maybe a quoted number
+				parsed, err := parseInt(name, 32)
+				if err != nil {
+					return fmt.Errorf("unknown enum value %s for field %s", name, f.name)
+				}
+				number = int32(parsed)
+			}
+			n = int64(number)
+		} else {
+			parsed, err := parseInt(v, 32)
+			if err != nil {
+				return invalid(err)
+			}
+			n = parsed
+		}
+		tag(wireVarint)
+		buf.EncodeVarint(uint64(n))
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_INT64:
+		n, err := parseInt(v, bitSize(f.kind))
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireVarint)
+		buf.EncodeVarint(uint64(n))
+	case descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SINT64:
+		n, err := parseInt(v, bitSize(f.kind))
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireVarint)
+		buf.EncodeVarint(uint64(n<<1) ^ uint64(n>>63))
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_UINT64:
+		n, err := parseUint(v, bitSize(f.kind))
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireVarint)
+		buf.EncodeVarint(n)
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		n, err := parseUint(v, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireFixed32)
+		buf.EncodeFixed32(n)
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		n, err := parseInt(v, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireFixed32)
+		buf.EncodeFixed32(uint64(uint32(n)))
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		n, err := parseUint(v, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireFixed64)
+		buf.EncodeFixed64(n)
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		n, err := parseInt(v, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireFixed64)
+		buf.EncodeFixed64(uint64(n))
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		n, err := parseFloat(v, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireFixed32)
+		buf.EncodeFixed32(uint64(math.Float32bits(float32(n))))
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		n, err := parseFloat(v, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		tag(wireFixed64)
+		buf.EncodeFixed64(math.Float64bits(n))
+	default:
+		return fmt.Errorf("field %s has an unsupported type %v", f.name, f.kind)
+	}
+	return nil
+}
+
+func bitSize(kind descriptor.FieldDescriptorProto_Type) int {
+	switch kind {
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_UINT32:
+		return 32
+	default:
+		return 64
+	}
+}
+
+// numberString returns the json number or the quoted number
+func numberString(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case json.Number:
+		return string(value), true
+	case string:
+		return value, true
+	default:
+		return "", false
+	}
+}
+
+func parseInt(v interface{}, bitSize int) (int64, error) {
+	s, ok := numberString(v)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+	n, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		// the number maybe in the exponent notation, such as 1e3
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil || f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+			return 0, err
+		}
+		return strconv.ParseInt(strconv.FormatFloat(f, 'f', -1, 64), 10, bitSize)
+	}
+	return n, nil
+}
+
+func parseUint(v interface{}, bitSize int) (uint64, error) {
+	s, ok := numberString(v)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+	n, err := strconv.ParseUint(s, 10, bitSize)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil || f != math.Trunc(f) || f < 0 || f > math.MaxUint64 {
+			return 0, err
+		}
+		return strconv.ParseUint(strconv.FormatFloat(f, 'f', -1, 64), 10, bitSize)
+	}
+	return n, nil
+}
+
+func parseFloat(v interface{}, bitSize int) (float64, error) {
+	s, ok := numberString(v)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(s, bitSize)
+}
+
+// decodeBase64 decodes the standard or url safe base64 string, with or without padding
+func decodeBase64(s string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if data, err := enc.DecodeString(s); err == nil {
+			return data, nil
+		}
+	}
+	return nil, errors.New("invalid base64 string")
+}
+
+// ~~~ protobuf to json
+
+// decodeMessage decodes the protobuf message into a json object, the fields are in the declared order
+func decodeMessage(mt *messageType, data []byte) (jsonObject, error) {
+	values := make(map[int32][]interface{})
+	for len(data) > 0 {
+		key, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		number, wire := int32(key>>3), int(key&7)
+
+		f, ok := mt.byNumber[number]
+		if !ok {
+			// skips the unknown field
+			_, n, err := readWire(wire, data)
+			if err != nil {
+				return nil, err
+			}
+			data = data[n:]
+			continue
+		}
+
+		raw, n, err := readWire(wire, data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+		if wire == wireBytes && f.repeated && isPackable(f.kind) {
+			packed := raw.([]byte)
+			for len(packed) > 0 {
+				v, n, err := readWire(packedWire(f.kind), packed)
+				if err != nil {
+					return nil, err
+				}
+				packed = packed[n:]
+				value, err := decodeValue(f, v)
+				if err != nil {
+					return nil, err
+				}
+				values[number] = append(values[number], value)
+			}
+			continue
+		}
+		if expected := wireType(f.kind); expected != wire {
+			return nil, fmt.Errorf("field %s has wire type %d, expected %d", f.name, wire, expected)
+		}
+		value, err := decodeValue(f, raw)
+		if err != nil {
+			return nil, err
+		}
+		values[number] = append(values[number], value)
+	}
+
+	obj := make(jsonObject, 0, len(values))
+	for _, f := range mt.fields {
+		vs, ok := values[f.number]
+		if !ok {
+			continue
+		}
+		var value interface{}
+		switch {
+		case f.isMap():
+			entries := make(jsonObject, 0, len(vs))
+			for _, v := range vs {
+				entry := v.(jsonObject)
+				keyField, valueField := f.message.byNumber[1], f.message.byNumber[2]
+				key, ok := entry.get(keyField.jsonName)
+				if !ok {
+					key = defaultValue(keyField)
+				}
+				value, ok := entry.get(valueField.jsonName)
+				if !ok {
+					value = defaultValue(valueField)
+				}
+				entries = append(entries, jsonField{name: fmt.Sprint(key), value: value})
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].name < entries[j].name
+			})
+			value = entries
+		case f.repeated:
+			value = vs
+		default:
+			// the last one wins for a non-repeated field
+			value = vs[len(vs)-1]
+		}
+		obj = append(obj, jsonField{name: f.jsonName, value: value})
+	}
+	return obj, nil
+}
+
+// readWire reads a value of the wire type, returns uint64 for the numbers, []byte for the length delimited
+func readWire(wire int, data []byte) (interface{}, int, error) {
+	switch wire {
+	case wireVarint:
+		v, n := proto.DecodeVarint(data)
+		if n == 0 {
+			return nil, 0, errTruncated
+		}
+		return v, n, nil
+	case wireFixed64:
+		if len(data) < 8 {
+			return nil, 0, errTruncated
+		}
+		return binary.LittleEndian.Uint64(data), 8, nil
+	case wireFixed32:
+		if len(data) < 4 {
+			return nil, 0, errTruncated
+		}
+		return uint64(binary.LittleEndian.Uint32(data)), 4, nil
+	case wireBytes:
+		l, n := proto.DecodeVarint(data)
+		if n == 0 || uint64(len(data)-n) < l {
+			return nil, 0, errTruncated
+		}
+		return data[n : n+int(l)], n + int(l), nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported wire type %d", wire)
+	}
+}
+
+func wireType(kind descriptor.FieldDescriptorProto_Type) int {
+	switch kind {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_STRING,
+		descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return wireBytes
+	default:
+		return packedWire(kind)
+	}
+}
+
+func packedWire(kind descriptor.FieldDescriptorProto_Type) int {
+	switch kind {
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64, descriptor.FieldDescriptorProto_TYPE_SFIXED64,
+		descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return wireFixed64
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32, descriptor.FieldDescriptorProto_TYPE_SFIXED32,
+		descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return wireFixed32
+	default:
+		return wireVarint
+	}
+}
+
+func isPackable(kind descriptor.FieldDescriptorProto_Type) bool {
+	return wireType(kind) != wireBytes
+}
+
+// decodeValue converts the wire value into the json value by the proto3 json mapping
+func decodeValue(f *field, raw interface{}) (interface{}, error) {
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		return decodeMessage(f.message, raw.([]byte))
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return string(raw.([]byte)), nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return base64.StdEncoding.EncodeToString(raw.([]byte)), nil
+	}
+
+	v := raw.(uint64)
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return v != 0, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if name, ok := f.enum.byNumber[int32(v)]; ok {
+			return name, nil
+		}
+		return json.Number(strconv.FormatInt(int64(int32(v)), 10)), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return json.Number(strconv.FormatInt(int64(int32(v)), 10)), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return json.Number(strconv.FormatUint(uint64(uint32(v)), 10)), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT32:
+		return json.Number(strconv.FormatInt(int64(int32(uint32(v>>1)^-uint32(v&1))), 10)), nil
+	// the 64 bits integers are encoded as strings in json
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return strconv.FormatInt(int64(v), 10), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return strconv.FormatUint(v, 10), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT64:
+		return strconv.FormatInt(int64(v>>1)^-int64(v&1), 10), nil
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return formatFloat(float64(math.Float32frombits(uint32(v))), 32), nil
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return formatFloat(math.Float64frombits(v), 64), nil
+	}
+	return nil, fmt.Errorf("field %s has an unsupported type %v", f.name, f.kind)
+}
+
+// defaultValue returns the json value of a field which is not present
+func defaultValue(f *field) interface{} {
+	switch f.kind {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		return jsonObject{}
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return ""
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	}
+	value, _ := decodeValue(f, uint64(0))
+	return value
+}
+
+func formatFloat(f float64, bitSize int) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
@@ -0,0 +1,324 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+type messageType struct {
+	name   string
+	fields []*field
+	// the fields indexed by the field number
+	byNumber map[int32]*field
+	// the fields indexed by both the proto name and the json name
+	byName   map[string]*field
+	mapEntry bool
+}
+
+type field struct {
+	name     string
+	jsonName string
+	number   int32
+	kind     descriptor.FieldDescriptorProto_Type
+	repeated bool
+	typeName string
+	// resolved by the type name
+	message *messageType
+	enum    *enumType
+}
+
+func (f *field) isMap() bool {
+	return f.repeated && f.message != nil && f.message.mapEntry
+}
+
+type enumType struct {
+	byName   map[string]int32
+	byNumber map[int32]string
+}
+
+type method struct {
+	// the gRPC path, /{package}.{service}/{method}
+	path            string
+	input           *messageType
+	output          *messageType
+	clientStreaming bool
+	serverStreaming bool
+}
+
+// binding is a http rule of a method
+type binding struct {
+	httpMethod   string
+	template     *pathTemplate
+	body         string
+	responseBody string
+	method       *method
+}
+
+// registry is the gRPC methods and the http bindings in the descriptor set
+type registry struct {
+	messages map[string]*messageType
+	enums    map[string]*enumType
+	// the methods indexed by the gRPC path
+	methods  map[string]*method
+	services map[string]bool
+	bindings []*binding
+}
+
+// loadRegistry loads the descriptor set file, only the methods of the services are transcoded
+// if the services are specified
+func loadRegistry(path string, services []string) (*registry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fds := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fds); err != nil {
+		return nil, fmt.Errorf("unmarshal descriptor set %s failed: %v", path, err)
+	}
+	return newRegistry(fds, services)
+}
+
+func newRegistry(fds *descriptor.FileDescriptorSet, services []string) (*registry, error) {
+	r := &registry{
+		messages: make(map[string]*messageType),
+		enums:    make(map[string]*enumType),
+		methods:  make(map[string]*method),
+		services: make(map[string]bool),
+	}
+	for _, file := range fds.GetFile() {
+		prefix := file.GetPackage()
+		for _, msg := range file.GetMessageType() {
+			r.addMessage(prefix, msg)
+		}
+		for _, enum := range file.GetEnumType() {
+			r.addEnum(prefix, enum)
+		}
+	}
+	if err := r.resolveTypes(); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(services))
+	for _, s := range services {
+		wanted[s] = true
+	}
+	for _, file := range fds.GetFile() {
+		for _, svc := range file.GetService() {
+			name := fullName(file.GetPackage(), svc.GetName())
+			if len(wanted) > 0 && !wanted[name] {
+				continue
+			}
+			delete(wanted, name)
+			r.services[name] = true
+			for _, m := range svc.GetMethod() {
+				if err := r.addMethod(name, m); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for name := range wanted {
+		return nil, fmt.Errorf("service %s is not found in the descriptor set", name)
+	}
+	return r, nil
+}
+
+func fullName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func (r *registry) addMessage(prefix string, msg *descriptor.DescriptorProto) {
+	name := fullName(prefix, msg.GetName())
+	mt := &messageType{
+		name:     name,
+		byNumber: make(map[int32]*field, len(msg.GetField())),
+		byName:   make(map[string]*field, 2*len(msg.GetField())),
+		mapEntry: msg.GetOptions().GetMapEntry(),
+	}
+	for _, fd := range msg.GetField() {
+		f := &field{
+			name:     fd.GetName(),
+			jsonName: fd.GetJsonName(),
+			number:   fd.GetNumber(),
+			kind:     fd.GetType(),
+			repeated: fd.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED,
+			typeName: strings.TrimPrefix(fd.GetTypeName(), "."),
+		}
+		if f.jsonName == "" {
+			f.jsonName = jsonCamelCase(f.name)
+		}
+		mt.fields = append(mt.fields, f)
+		mt.byNumber[f.number] = f
+		mt.byName[f.name] = f
+		mt.byName[f.jsonName] = f
+	}
+	r.messages[name] = mt
+	for _, nested := range msg.GetNestedType() {
+		r.addMessage(name, nested)
+	}
+	for _, enum := range msg.GetEnumType() {
+		r.addEnum(name, enum)
+	}
+}
+
+func (r *registry) addEnum(prefix string, enum *descriptor.EnumDescriptorProto) {
+	et := &enumType{
+		byName:   make(map[string]int32, len(enum.GetValue())),
+		byNumber: make(map[int32]string, len(enum.GetValue())),
+	}
+	for _, v := range enum.GetValue() {
+		et.byName[v.GetName()] = v.GetNumber()
+		// the first name is used for the aliases
+		if _, ok := et.byNumber[v.GetNumber()]; !ok {
+			et.byNumber[v.GetNumber()] = v.GetName()
+		}
+	}
+	r.enums[fullName(prefix, enum.GetName())] = et
+}
+
+func (r *registry) resolveTypes() error {
+	for _, mt := range r.messages {
+		for _, f := range mt.fields {
+			switch f.kind {
+			case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+				if f.message = r.messages[f.typeName]; f.message == nil {
+					return fmt.Errorf("message type %s of %s.%s is not found", f.typeName, mt.name, f.name)
+				}
+			case descriptor.FieldDescriptorProto_TYPE_ENUM:
+				if f.enum = r.enums[f.typeName]; f.enum == nil {
+					return fmt.Errorf("enum type %s of %s.%s is not found", f.typeName, mt.name, f.name)
+				}
+			case descriptor.FieldDescriptorProto_TYPE_GROUP:
+				return fmt.Errorf("group field %s.%s is not supported", mt.name, f.name)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *registry) addMethod(service string, md *descriptor.MethodDescriptorProto) error {
+	m := &method{
+		path:            "/" + service + "/" + md.GetName(),
+		input:           r.messages[strings.TrimPrefix(md.GetInputType(), ".")],
+		output:          r.messages[strings.TrimPrefix(md.GetOutputType(), ".")],
+		clientStreaming: md.GetClientStreaming(),
+		serverStreaming: md.GetServerStreaming(),
+	}
+	if m.input == nil || m.output == nil {
+		return fmt.Errorf("the types of method %s are not found", m.path)
+	}
+	r.methods[m.path] = m
+
+	rule := getHTTPRule(md)
+	if rule == nil {
+		return nil
+	}
+	rules := append([]*httpRule{rule}, rule.AdditionalBindings...)
+	for _, rule := range rules {
+		httpMethod, path := rule.pattern()
+		if httpMethod == "" {
+			return fmt.Errorf("method %s has an invalid http rule: %v", m.path, rule)
+		}
+		template, err := parseTemplate(path)
+		if err != nil {
+			return fmt.Errorf("method %s has an invalid http rule: %v", m.path, err)
+		}
+		for _, v := range template.variables {
+			if _, err := m.input.lookup(v.fieldPath); err != nil {
+				return fmt.Errorf("method %s has an invalid http rule: %v", m.path, err)
+			}
+		}
+		if rule.Body != "" && rule.Body != "*" {
+			if _, err := m.input.lookup([]string{rule.Body}); err != nil {
+				return fmt.Errorf("method %s has an invalid http rule: %v", m.path, err)
+			}
+		}
+		if rule.ResponseBody != "" {
+			if _, err := m.output.lookup([]string{rule.ResponseBody}); err != nil {
+				return fmt.Errorf("method %s has an invalid http rule: %v", m.path, err)
+			}
+		}
+		r.bindings = append(r.bindings, &binding{
+			httpMethod:   httpMethod,
+			template:     template,
+			body:         rule.Body,
+			responseBody: rule.ResponseBody,
+			method:       m,
+		})
+	}
+	return nil
+}
+
+// lookup returns the fields of the field path
+func (mt *messageType) lookup(fieldPath []string) ([]*field, error) {
+	fields := make([]*field, 0, len(fieldPath))
+	current := mt
+	for i, name := range fieldPath {
+		if current == nil {
+			return nil, fmt.Errorf("field %s is not a message", strings.Join(fieldPath[:i], "."))
+		}
+		f, ok := current.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("field %s is not found in %s", name, current.name)
+		}
+		fields = append(fields, f)
+		current = f.message
+	}
+	return fields, nil
+}
+
+// match finds the binding of the http request
+func (r *registry) match(httpMethod, path string) (*binding, map[string]string) {
+	for _, b := range r.bindings {
+		if b.httpMethod != httpMethod {
+			continue
+		}
+		if values, ok := b.template.match(path); ok {
+			return b, values
+		}
+	}
+	return nil, nil
+}
+
+// jsonCamelCase converts the proto field name to the json name like protoc
+func jsonCamelCase(name string) string {
+	var sb strings.Builder
+	upper := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
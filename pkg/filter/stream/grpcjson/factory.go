@@ -0,0 +1,56 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"context"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/config"
+	"mosn.io/mosn/pkg/filter"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+func init() {
+	filter.RegisterStream(v2.GRPCJSONTranscoder, CreateGRPCJSONTranscoderFilterFactory)
+}
+
+type FilterConfigFactory struct {
+	registry *registry
+}
+
+func (f *FilterConfigFactory) CreateFilterChain(context context.Context, callbacks types.StreamFilterChainFactoryCallbacks) {
+	filter := NewFilter(context, f.registry)
+	// the request should be transcoded before the route matched, so the route can match the gRPC path
+	callbacks.AddStreamReceiverFilter(filter, types.DownFilter)
+	callbacks.AddStreamSenderFilter(filter)
+}
+
+func CreateGRPCJSONTranscoderFilterFactory(conf map[string]interface{}) (types.StreamFilterChainFactory, error) {
+	log.DefaultLogger.Debugf("create grpc json transcoder stream filter factory")
+	cfg, err := config.ParseStreamGRPCJSONTranscoderFilter(conf)
+	if err != nil {
+		return nil, err
+	}
+	r, err := loadRegistry(cfg.ProtoDescriptor, cfg.Services)
+	if err != nil {
+		return nil, err
+	}
+	return &FilterConfigFactory{registry: r}, nil
+}
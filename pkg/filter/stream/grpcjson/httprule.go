@@ -0,0 +1,95 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// httpRule is the google.api.HttpRule defined in google/api/http.proto,
+// the oneof pattern is declared as plain fields, which have the same wire format.
+type httpRule struct {
+	Selector           string             `protobuf:"bytes,1,opt,name=selector,proto3"`
+	Get                string             `protobuf:"bytes,2,opt,name=get,proto3"`
+	Put                string             `protobuf:"bytes,3,opt,name=put,proto3"`
+	Post               string             `protobuf:"bytes,4,opt,name=post,proto3"`
+	Delete             string             `protobuf:"bytes,5,opt,name=delete,proto3"`
+	Patch              string             `protobuf:"bytes,6,opt,name=patch,proto3"`
+	Body               string             `protobuf:"bytes,7,opt,name=body,proto3"`
+	Custom             *customHTTPPattern `protobuf:"bytes,8,opt,name=custom,proto3"`
+	AdditionalBindings []*httpRule        `protobuf:"bytes,11,rep,name=additional_bindings,json=additionalBindings,proto3"`
+	ResponseBody       string             `protobuf:"bytes,12,opt,name=response_body,json=responseBody,proto3"`
+}
+
+func (m *httpRule) Reset()         { *m = httpRule{} }
+func (m *httpRule) String() string { return proto.CompactTextString(m) }
+func (*httpRule) ProtoMessage()    {}
+
+// pattern returns the http method and the path template of the rule
+func (m *httpRule) pattern() (method string, template string) {
+	switch {
+	case m.Get != "":
+		return "GET", m.Get
+	case m.Put != "":
+		return "PUT", m.Put
+	case m.Post != "":
+		return "POST", m.Post
+	case m.Delete != "":
+		return "DELETE", m.Delete
+	case m.Patch != "":
+		return "PATCH", m.Patch
+	case m.Custom != nil:
+		return m.Custom.Kind, m.Custom.Path
+	}
+	return "", ""
+}
+
+// customHTTPPattern is the google.api.CustomHttpPattern
+type customHTTPPattern struct {
+	Kind string `protobuf:"bytes,1,opt,name=kind,proto3"`
+	Path string `protobuf:"bytes,2,opt,name=path,proto3"`
+}
+
+func (m *customHTTPPattern) Reset()         { *m = customHTTPPattern{} }
+func (m *customHTTPPattern) String() string { return proto.CompactTextString(m) }
+func (*customHTTPPattern) ProtoMessage()    {}
+
+// httpRuleExtension is the google.api.http option of the methods, defined in google/api/annotations.proto
+var httpRuleExtension = &proto.ExtensionDesc{
+	ExtendedType:  (*descriptor.MethodOptions)(nil),
+	ExtensionType: (*httpRule)(nil),
+	Field:         72295728,
+	Name:          "google.api.http",
+	Tag:           "bytes,72295728,opt,name=http",
+	Filename:      "google/api/annotations.proto",
+}
+
+// getHTTPRule returns the google.api.http option of the method
+func getHTTPRule(method *descriptor.MethodDescriptorProto) *httpRule {
+	opts := method.GetOptions()
+	if opts == nil || !proto.HasExtension(opts, httpRuleExtension) {
+		return nil
+	}
+	ext, err := proto.GetExtension(opts, httpRuleExtension)
+	if err != nil {
+		return nil
+	}
+	rule, _ := ext.(*httpRule)
+	return rule
+}
@@ -0,0 +1,196 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// the kinds of the path template segment
+const (
+	segmentLiteral = iota
+	// "*" matches a single path segment
+	segmentWildcard
+	// "**" matches zero or more path segments, it must be the last segment
+	segmentMultiWildcard
+)
+
+type segment struct {
+	kind    int
+	literal string
+}
+
+// variable binds the path segments in [start, end) to the field path
+type variable struct {
+	fieldPath []string
+	start     int
+	end       int
+}
+
+// pathTemplate is the path template of the google.api.http option:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	FieldPath = IDENT { "." IDENT } ;
+//	Verb     = ":" LITERAL ;
+type pathTemplate struct {
+	segments  []segment
+	variables []variable
+	verb      string
+}
+
+func parseTemplate(template string) (*pathTemplate, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("path template %s should start with /", template)
+	}
+	t := &pathTemplate{}
+	path := template[1:]
+	// the verb is after the last segment
+	if i := strings.LastIndex(path, ":"); i >= 0 && i > strings.LastIndex(path, "/") && i > strings.LastIndex(path, "}") {
+		t.verb = path[i+1:]
+		path = path[:i]
+	}
+	for len(path) > 0 {
+		var part string
+		if path[0] == '{' {
+			end := strings.IndexByte(path, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("path template %s has an unclosed variable", template)
+			}
+			part, path = path[:end+1], path[end+1:]
+			if err := t.parseVariable(part[1 : len(part)-1]); err != nil {
+				return nil, fmt.Errorf("path template %s is invalid: %v", template, err)
+			}
+		} else {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			part, path = path[:end], path[end:]
+			if err := t.parseSegments(part); err != nil {
+				return nil, fmt.Errorf("path template %s is invalid: %v", template, err)
+			}
+		}
+		if len(path) > 0 {
+			if path[0] != '/' {
+				return nil, fmt.Errorf("path template %s is invalid near %s", template, path)
+			}
+			path = path[1:]
+			if len(path) == 0 {
+				return nil, fmt.Errorf("path template %s has an empty segment", template)
+			}
+		}
+	}
+	for i, seg := range t.segments {
+		if seg.kind == segmentMultiWildcard && i != len(t.segments)-1 {
+			return nil, fmt.Errorf("path template %s is invalid: ** should be the last segment", template)
+		}
+	}
+	return t, nil
+}
+
+func (t *pathTemplate) parseVariable(v string) error {
+	name, pattern := v, "*"
+	if i := strings.IndexByte(v, '='); i >= 0 {
+		name, pattern = v[:i], v[i+1:]
+	}
+	if name == "" || pattern == "" {
+		return fmt.Errorf("invalid variable {%s}", v)
+	}
+	start := len(t.segments)
+	if err := t.parseSegments(pattern); err != nil {
+		return err
+	}
+	t.variables = append(t.variables, variable{
+		fieldPath: strings.Split(name, "."),
+		start:     start,
+		end:       len(t.segments),
+	})
+	return nil
+}
+
+func (t *pathTemplate) parseSegments(segments string) error {
+	for _, s := range strings.Split(segments, "/") {
+		switch s {
+		case "":
+			return fmt.Errorf("empty segment in %s", segments)
+		case "*":
+			t.segments = append(t.segments, segment{kind: segmentWildcard})
+		case "**":
+			t.segments = append(t.segments, segment{kind: segmentMultiWildcard})
+		default:
+			if strings.ContainsAny(s, "{}=") {
+				return fmt.Errorf("invalid segment %s", s)
+			}
+			t.segments = append(t.segments, segment{kind: segmentLiteral, literal: s})
+		}
+	}
+	return nil
+}
+
+// match matches the request path, returns the values of the variables
+func (t *pathTemplate) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	path = path[1:]
+	if t.verb != "" {
+		if !strings.HasSuffix(path, ":"+t.verb) {
+			return nil, false
+		}
+		path = path[:len(path)-len(t.verb)-1]
+	}
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+
+	n := len(t.segments)
+	multi := n > 0 && t.segments[n-1].kind == segmentMultiWildcard
+	if multi && len(parts) < n-1 || !multi && len(parts) != n {
+		return nil, false
+	}
+	for i, seg := range t.segments {
+		if seg.kind == segmentLiteral && parts[i] != seg.literal {
+			return nil, false
+		}
+	}
+
+	values := make(map[string]string, len(t.variables))
+	for _, v := range t.variables {
+		end := v.end
+		if multi && end == n {
+			end = len(parts)
+		}
+		matched := parts[v.start:end]
+		unescaped := make([]string, len(matched))
+		for i, p := range matched {
+			u, err := url.PathUnescape(p)
+			if err != nil {
+				return nil, false
+			}
+			unescaped[i] = u
+		}
+		values[strings.Join(v.fieldPath, ".")] = strings.Join(unescaped, "/")
+	}
+	return values, true
+}
@@ -0,0 +1,68 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathTemplate(t *testing.T) {
+	testCases := []struct {
+		template string
+		path     string
+		matched  bool
+		values   map[string]string
+	}{
+		{"/v1/books", "/v1/books", true, map[string]string{}},
+		{"/v1/books", "/v1/books/1", false, nil},
+		{"/v1/shelves/{shelf}/books/{id}", "/v1/shelves/s1/books/10", true, map[string]string{"shelf": "s1", "id": "10"}},
+		{"/v1/shelves/{shelf}/books/{id}", "/v1/shelves/s1/books", false, nil},
+		{"/v1/{name=shelves/*/books/*}", "/v1/shelves/s1/books/a%20b", true, map[string]string{"name": "shelves/s1/books/a b"}},
+		{"/v1/{book.name=**}", "/v1/a/b/c", true, map[string]string{"book.name": "a/b/c"}},
+		{"/v1/{book.name=**}", "/v1", true, map[string]string{"book.name": ""}},
+		{"/v1/*/books", "/v1/any/books", true, map[string]string{}},
+		{"/v1/books/{id}:cancel", "/v1/books/1:cancel", true, map[string]string{"id": "1"}},
+		{"/v1/books/{id}:cancel", "/v1/books/1", false, nil},
+	}
+	for _, tc := range testCases {
+		template, err := parseTemplate(tc.template)
+		if err != nil {
+			t.Fatalf("parse template %s failed: %v", tc.template, err)
+		}
+		values, matched := template.match(tc.path)
+		if matched != tc.matched || matched && !reflect.DeepEqual(values, tc.values) {
+			t.Errorf("template %s match %s, expected %v %v, but got %v %v", tc.template, tc.path, tc.matched, tc.values, matched, values)
+		}
+	}
+}
+
+func TestInvalidPathTemplate(t *testing.T) {
+	for _, template := range []string{
+		"v1/books",
+		"/v1/{id",
+		"/v1//books",
+		"/v1/**/books",
+		"/v1/{=*}",
+		"/v1/books/",
+	} {
+		if _, err := parseTemplate(template); err == nil {
+			t.Errorf("template %s should be invalid", template)
+		}
+	}
+}
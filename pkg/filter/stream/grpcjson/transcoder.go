@@ -0,0 +1,361 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/grpc"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	contentTypeJSON     = "application/json"
+	headerContentLength = "content-length"
+	headerTE            = "te"
+)
+
+var errCompressed = errors.New("compressed grpc message is not supported")
+
+// transcoderFilter converts the JSON/HTTP1 requests into gRPC requests by the google.api.http options,
+// and converts the gRPC responses back to JSON.
+// The converted request is sent to the upstream by the http1 to http2 protocol conversion.
+type transcoderFilter struct {
+	ctx            context.Context
+	registry       *registry
+	receiveHandler types.StreamReceiverFilterHandler
+	sendHandler    types.StreamSenderFilterHandler
+	// the transcoded method, nil if the request is not transcoded
+	method       *method
+	responseBody string
+}
+
+// errorBody is the structured error of the transcoded requests
+type errorBody struct {
+	Code    grpc.Status `json:"code"`
+	Message string      `json:"message"`
+}
+
+func NewFilter(ctx context.Context, r *registry) *transcoderFilter {
+	return &transcoderFilter{
+		ctx:      ctx,
+		registry: r,
+	}
+}
+
+func (f *transcoderFilter) SetReceiveFilterHandler(handler types.StreamReceiverFilterHandler) {
+	f.receiveHandler = handler
+}
+
+func (f *transcoderFilter) SetSenderFilterHandler(handler types.StreamSenderFilterHandler) {
+	f.sendHandler = handler
+}
+
+func (f *transcoderFilter) OnReceive(ctx context.Context, headers types.HeaderMap, buf types.IoBuffer, trailers types.HeaderMap) types.StreamFilterStatus {
+	if grpc.IsGrpcRequest(headers) {
+		return types.StreamFilterContinue
+	}
+	path, _ := headers.Get(protocol.MosnHeaderPathKey)
+	httpMethod, _ := headers.Get(protocol.MosnHeaderMethod)
+	if path == "" {
+		return types.StreamFilterContinue
+	}
+
+	// the gRPC method can be called by its gRPC path with the json body
+	body, responseBody := "*", ""
+	b, values := f.registry.match(httpMethod, path)
+	m := f.registry.methods[path]
+	switch {
+	case b != nil:
+		m, body, responseBody = b.method, b.body, b.responseBody
+	case m != nil && httpMethod == http.MethodPost:
+	default:
+		if i := strings.LastIndexByte(path, '/'); i > 0 && f.registry.services[path[1:i]] {
+			f.sendError(headers, grpc.Unimplemented, fmt.Sprintf("unknown method %s %s", httpMethod, path))
+			return types.StreamFilterStop
+		}
+		return types.StreamFilterContinue
+	}
+
+	var query string
+	if body != "*" {
+		query, _ = headers.Get(protocol.MosnHeaderQueryStringKey)
+	}
+	data, err := f.encodeRequest(m, buf, body, values, query)
+	if err != nil {
+		log.Proxy.Errorf(ctx, "[stream filter] [grpc json] transcode request of %s failed: %v", m.path, err)
+		f.sendError(headers, grpc.InvalidArgument, err.Error())
+		return types.StreamFilterStop
+	}
+	if log.DebugEnabled(ctx) {
+		log.Proxy.Debugf(ctx, "[stream filter] [grpc json] transcode %s %s to %s", httpMethod, path, m.path)
+	}
+
+	headers.Set(protocol.MosnHeaderPathKey, m.path)
+	headers.Set(protocol.MosnHeaderMethod, http.MethodPost)
+	headers.Del(protocol.MosnHeaderQueryStringKey)
+	headers.Set(grpc.HeaderContentType, grpc.ContentType)
+	headers.Set(headerTE, "trailers")
+	headers.Del(headerContentLength)
+	f.receiveHandler.SetRequestData(buffer.NewIoBufferBytes(data))
+
+	f.method = m
+	f.responseBody = responseBody
+	return types.StreamFilterContinue
+}
+
+// encodeRequest builds the gRPC messages from the body, the path variables and the query parameters
+func (f *transcoderFilter) encodeRequest(m *method, buf types.IoBuffer, body string, values map[string]string, query string) ([]byte, error) {
+	var requests []map[string]interface{}
+	var content interface{}
+	if body != "" && buf != nil && buf.Len() > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&content); err != nil {
+			return nil, fmt.Errorf("invalid json body: %v", err)
+		}
+	}
+	switch {
+	case body == "*" && content != nil:
+		switch v := content.(type) {
+		case map[string]interface{}:
+			requests = append(requests, v)
+		case []interface{}:
+			// a client streaming method accepts an array of messages
+			if !m.clientStreaming {
+				return nil, errors.New("json body should be an object")
+			}
+			for _, item := range v {
+				obj, ok := item.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("json body should be an array of objects")
+				}
+				requests = append(requests, obj)
+			}
+		default:
+			return nil, errors.New("json body should be an object")
+		}
+	case body != "*" && body != "" && content != nil:
+		requests = append(requests, map[string]interface{}{body: content})
+	default:
+		requests = append(requests, map[string]interface{}{})
+	}
+
+	var bound []string
+	for fieldPath, value := range values {
+		for _, req := range requests {
+			if err := setField(m.input, req, strings.Split(fieldPath, "."), []string{value}); err != nil {
+				return nil, err
+			}
+		}
+		bound = append(bound, fieldPath)
+	}
+	if query != "" {
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query string: %v", err)
+		}
+	params:
+		for key, vs := range params {
+			for _, b := range bound {
+				if key == b || strings.HasPrefix(key, b+".") {
+					continue params
+				}
+			}
+			// the unknown query parameters are ignored
+			_ = setField(m.input, requests[0], strings.Split(key, "."), vs)
+		}
+	}
+
+	var data []byte
+	for _, req := range requests {
+		msg, err := encodeMessage(m.input, req)
+		if err != nil {
+			return nil, err
+		}
+		data = appendFrame(data, msg)
+	}
+	return data, nil
+}
+
+func (f *transcoderFilter) Append(ctx context.Context, headers types.HeaderMap, buf types.IoBuffer, trailers types.HeaderMap) types.StreamFilterStatus {
+	if f.method == nil {
+		return types.StreamFilterContinue
+	}
+
+	status := grpc.ResponseStatus(headers, trailers)
+	var body []byte
+	var err error
+	if status == grpc.OK {
+		body, err = f.decodeResponse(buf)
+		if err != nil {
+			log.Proxy.Errorf(ctx, "[stream filter] [grpc json] transcode response of %s failed: %v", f.method.path, err)
+			status = grpc.Internal
+			body = encodeError(status, err.Error())
+		}
+	} else {
+		message := ""
+		if trailers != nil {
+			message, _ = trailers.Get(grpc.HeaderMessage)
+		}
+		if message == "" {
+			message, _ = headers.Get(grpc.HeaderMessage)
+		}
+		if unescaped, err := url.PathUnescape(message); err == nil {
+			message = unescaped
+		}
+		body = encodeError(status, message)
+	}
+
+	code := grpc.StatusToHTTP(status)
+	headers.Set(types.HeaderStatus, strconv.Itoa(code))
+	headers.Set(grpc.HeaderContentType, contentTypeJSON)
+	headers.Del(grpc.HeaderStatus)
+	headers.Del(grpc.HeaderMessage)
+	headers.Del(headerContentLength)
+	f.sendHandler.RequestInfo().SetResponseCode(code)
+	f.sendHandler.SetResponseData(buffer.NewIoBufferBytes(body))
+	f.sendHandler.SetResponseTrailers(nil)
+	return types.StreamFilterContinue
+}
+
+// decodeResponse converts the gRPC messages into json, the messages of a server streaming method
+// are converted into a json array
+func (f *transcoderFilter) decodeResponse(buf types.IoBuffer) ([]byte, error) {
+	var data []byte
+	if buf != nil {
+		data = buf.Bytes()
+	}
+	var messages []interface{}
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return nil, errTruncated
+		}
+		if data[0]&1 != 0 {
+			return nil, errCompressed
+		}
+		l := binary.BigEndian.Uint32(data[1:5])
+		if uint32(len(data)-5) < l {
+			return nil, errTruncated
+		}
+		obj, err := decodeMessage(f.method.output, data[5:5+l])
+		if err != nil {
+			return nil, err
+		}
+		data = data[5+l:]
+
+		var msg interface{} = obj
+		if f.responseBody != "" {
+			field := f.method.output.byName[f.responseBody]
+			if msg, _ = obj.get(field.jsonName); msg == nil {
+				msg = defaultValue(field)
+			}
+		}
+		messages = append(messages, msg)
+	}
+	if f.method.serverStreaming {
+		if messages == nil {
+			messages = []interface{}{}
+		}
+		return json.Marshal(messages)
+	}
+	if len(messages) != 1 {
+		return nil, fmt.Errorf("expected 1 response message, but got %d", len(messages))
+	}
+	return json.Marshal(messages[0])
+}
+
+func (f *transcoderFilter) OnDestroy() {}
+
+// sendError responds the structured error directly
+func (f *transcoderFilter) sendError(headers types.HeaderMap, status grpc.Status, message string) {
+	code := grpc.StatusToHTTP(status)
+	headers.Set(types.HeaderStatus, strconv.Itoa(code))
+	headers.Set(grpc.HeaderContentType, contentTypeJSON)
+	headers.Del(headerContentLength)
+	f.receiveHandler.RequestInfo().SetResponseCode(code)
+	f.receiveHandler.SendDirectResponse(headers, buffer.NewIoBufferBytes(encodeError(status, message)), nil)
+}
+
+func encodeError(status grpc.Status, message string) []byte {
+	body, _ := json.Marshal(errorBody{
+		Code:    status,
+		Message: message,
+	})
+	return body
+}
+
+// appendFrame appends the gRPC length-prefixed message
+func appendFrame(data []byte, msg []byte) []byte {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+	data = append(data, prefix[:]...)
+	return append(data, msg...)
+}
+
+// setField sets the string values into the json object by the field path
+func setField(mt *messageType, obj map[string]interface{}, fieldPath []string, values []string) error {
+	fields, err := mt.lookup(fieldPath)
+	if err != nil {
+		return err
+	}
+	key := func(obj map[string]interface{}, f *field) string {
+		if _, ok := obj[f.name]; ok {
+			return f.name
+		}
+		return f.jsonName
+	}
+	for _, f := range fields[:len(fields)-1] {
+		if f.repeated {
+			return fmt.Errorf("repeated field %s can not be in the field path", f.name)
+		}
+		k := key(obj, f)
+		child, ok := obj[k].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			obj[k] = child
+		}
+		obj = child
+	}
+	last := fields[len(fields)-1]
+	if last.message != nil {
+		return fmt.Errorf("message field %s can not be set by a string", last.name)
+	}
+	if last.repeated {
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		obj[key(obj, last)] = list
+	} else {
+		obj[key(obj, last)] = values[0]
+	}
+	return nil
+}
@@ -0,0 +1,489 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcjson
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func newField(name string, number int32, kind descriptor.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptor.FieldDescriptorProto {
+	label := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	if repeated {
+		label = descriptor.FieldDescriptorProto_LABEL_REPEATED
+	}
+	f := &descriptor.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(jsonCamelCase(name)),
+		Number:   proto.Int32(number),
+		Type:     kind.Enum(),
+		Label:    label.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func newMethod(t *testing.T, name, input, output string, serverStreaming bool, rule *httpRule) *descriptor.MethodDescriptorProto {
+	m := &descriptor.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String(input),
+		OutputType:      proto.String(output),
+		ServerStreaming: proto.Bool(serverStreaming),
+	}
+	if rule != nil {
+		m.Options = &descriptor.MethodOptions{}
+		if err := proto.SetExtension(m.Options, httpRuleExtension, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+// testDescriptorSet is the descriptor set of:
+//
+//	package library.v1;
+//	enum Kind { KIND_UNSPECIFIED = 0; KIND_NOVEL = 1; }
+//	message Author { string name = 1; }
+//	message Book {
+//	  int64 id = 1; string title = 2; repeated string tags = 3; Kind kind = 4;
+//	  map<string, int32> ratings = 5; Author author = 6; bytes cover = 7;
+//	  double price = 8; bool available = 9; sint32 delta = 10;
+//	}
+//	message GetBookRequest { int64 id = 1; string shelf_name = 2; repeated Kind kinds = 3; }
+//	message CreateBookRequest { string shelf = 1; Book book = 2; }
+//	message ListBooksResponse { repeated Book books = 1; }
+//	service Library {
+//	  rpc GetBook(GetBookRequest) returns (Book) { option (google.api.http) = { get: "/v1/shelves/{shelf_name}/books/{id}" }; }
+//	  rpc CreateBook(CreateBookRequest) returns (Book) { option (google.api.http) = { post: "/v1/shelves/{shelf}/books" body: "book" }; }
+//	  rpc ListBooks(GetBookRequest) returns (ListBooksResponse) {
+//	    option (google.api.http) = { get: "/v1/books" response_body: "books" additional_bindings { get: "/v1/shelves/{shelf_name}/books" } };
+//	  }
+//	  rpc StreamBooks(GetBookRequest) returns (stream Book) { option (google.api.http) = { get: "/v1/books:stream" }; }
+//	  rpc DeleteBook(GetBookRequest) returns (Book);
+//	}
+func testDescriptorSet(t *testing.T) *descriptor.FileDescriptorSet {
+	const (
+		typeString  = descriptor.FieldDescriptorProto_TYPE_STRING
+		typeInt64   = descriptor.FieldDescriptorProto_TYPE_INT64
+		typeInt32   = descriptor.FieldDescriptorProto_TYPE_INT32
+		typeEnum    = descriptor.FieldDescriptorProto_TYPE_ENUM
+		typeMessage = descriptor.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	file := &descriptor.FileDescriptorProto{
+		Name:    proto.String("library.proto"),
+		Package: proto.String("library.v1"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptor.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_NOVEL"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name:  proto.String("Author"),
+				Field: []*descriptor.FieldDescriptorProto{newField("name", 1, typeString, "", false)},
+			},
+			{
+				Name: proto.String("Book"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newField("id", 1, typeInt64, "", false),
+					newField("title", 2, typeString, "", false),
+					newField("tags", 3, typeString, "", true),
+					newField("kind", 4, typeEnum, ".library.v1.Kind", false),
+					newField("ratings", 5, typeMessage, ".library.v1.Book.RatingsEntry", true),
+					newField("author", 6, typeMessage, ".library.v1.Author", false),
+					newField("cover", 7, descriptor.FieldDescriptorProto_TYPE_BYTES, "", false),
+					newField("price", 8, descriptor.FieldDescriptorProto_TYPE_DOUBLE, "", false),
+					newField("available", 9, descriptor.FieldDescriptorProto_TYPE_BOOL, "", false),
+					newField("delta", 10, descriptor.FieldDescriptorProto_TYPE_SINT32, "", false),
+				},
+				NestedType: []*descriptor.DescriptorProto{{
+					Name: proto.String("RatingsEntry"),
+					Field: []*descriptor.FieldDescriptorProto{
+						newField("key", 1, typeString, "", false),
+						newField("value", 2, typeInt32, "", false),
+					},
+					Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+			},
+			{
+				Name: proto.String("GetBookRequest"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newField("id", 1, typeInt64, "", false),
+					newField("shelf_name", 2, typeString, "", false),
+					newField("kinds", 3, typeEnum, ".library.v1.Kind", true),
+				},
+			},
+			{
+				Name: proto.String("CreateBookRequest"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newField("shelf", 1, typeString, "", false),
+					newField("book", 2, typeMessage, ".library.v1.Book", false),
+				},
+			},
+			{
+				Name:  proto.String("ListBooksResponse"),
+				Field: []*descriptor.FieldDescriptorProto{newField("books", 1, typeMessage, ".library.v1.Book", true)},
+			},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Library"),
+			Method: []*descriptor.MethodDescriptorProto{
+				newMethod(t, "GetBook", ".library.v1.GetBookRequest", ".library.v1.Book", false,
+					&httpRule{Get: "/v1/shelves/{shelf_name}/books/{id}"}),
+				newMethod(t, "CreateBook", ".library.v1.CreateBookRequest", ".library.v1.Book", false,
+					&httpRule{Post: "/v1/shelves/{shelf}/books", Body: "book"}),
+				newMethod(t, "ListBooks", ".library.v1.GetBookRequest", ".library.v1.ListBooksResponse", false,
+					&httpRule{Get: "/v1/books", ResponseBody: "books", AdditionalBindings: []*httpRule{{Get: "/v1/shelves/{shelf_name}/books"}}}),
+				newMethod(t, "StreamBooks", ".library.v1.GetBookRequest", ".library.v1.Book", true,
+					&httpRule{Get: "/v1/books:stream"}),
+				newMethod(t, "DeleteBook", ".library.v1.GetBookRequest", ".library.v1.Book", false, nil),
+			},
+		}},
+	}
+	return &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{file}}
+}
+
+func testRegistry(t *testing.T) *registry {
+	r, err := newRegistry(testDescriptorSet(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+type mockReceiveHandler struct {
+	types.StreamReceiverFilterHandler
+	requestInfo types.RequestInfo
+	data        types.IoBuffer
+	// direct response
+	headers types.HeaderMap
+	body    types.IoBuffer
+}
+
+func (h *mockReceiveHandler) RequestInfo() types.RequestInfo {
+	return h.requestInfo
+}
+
+func (h *mockReceiveHandler) SetRequestData(data types.IoBuffer) {
+	h.data = data
+}
+
+func (h *mockReceiveHandler) SendDirectResponse(headers types.HeaderMap, buf types.IoBuffer, trailers types.HeaderMap) {
+	h.headers = headers
+	h.body = buf
+}
+
+type mockSendHandler struct {
+	types.StreamSenderFilterHandler
+	requestInfo types.RequestInfo
+	data        types.IoBuffer
+	trailers    types.HeaderMap
+}
+
+func (h *mockSendHandler) RequestInfo() types.RequestInfo {
+	return h.requestInfo
+}
+
+func (h *mockSendHandler) SetResponseData(data types.IoBuffer) {
+	h.data = data
+}
+
+func (h *mockSendHandler) SetResponseTrailers(trailers types.HeaderMap) {
+	h.trailers = trailers
+}
+
+func newTestFilter(t *testing.T) (*transcoderFilter, *mockReceiveHandler, *mockSendHandler) {
+	f := NewFilter(context.Background(), testRegistry(t))
+	requestInfo := network.NewRequestInfo()
+	rh := &mockReceiveHandler{requestInfo: requestInfo}
+	sh := &mockSendHandler{requestInfo: requestInfo}
+	f.SetReceiveFilterHandler(rh)
+	f.SetSenderFilterHandler(sh)
+	return f, rh, sh
+}
+
+func requestHeaders(method, path, query string) protocol.CommonHeader {
+	headers := protocol.CommonHeader{
+		protocol.MosnHeaderMethod:  method,
+		protocol.MosnHeaderPathKey: path,
+		"content-type":             "application/json",
+		"content-length":           "10",
+	}
+	if query != "" {
+		headers[protocol.MosnHeaderQueryStringKey] = query
+	}
+	return headers
+}
+
+// decodeFrames decodes the gRPC messages into json
+func decodeFrames(t *testing.T, mt *messageType, data []byte) []string {
+	var messages []string
+	for len(data) > 0 {
+		l := int(data[1])<<24 | int(data[2])<<16 | int(data[3])<<8 | int(data[4])
+		obj, err := decodeMessage(mt, data[5:5+l])
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := json.Marshal(obj)
+		messages = append(messages, string(b))
+		data = data[5+l:]
+	}
+	return messages
+}
+
+func TestTranscodeRequest(t *testing.T) {
+	testCases := []struct {
+		method   string
+		path     string
+		query    string
+		body     string
+		grpcPath string
+		expected string
+	}{
+		{"GET", "/v1/shelves/s1/books/10", "", "", "/library.v1.Library/GetBook", `{"id":"10","shelfName":"s1"}`},
+		// the path variables override the query parameters
+		{"GET", "/v1/shelves/s1/books/10", "id=20&kinds=KIND_NOVEL&kinds=0&unknown=1", "", "/library.v1.Library/GetBook", `{"id":"10","shelfName":"s1","kinds":["KIND_NOVEL","KIND_UNSPECIFIED"]}`},
+		{"POST", "/v1/shelves/s1/books", "", `{"title":"t","author":{"name":"a"},"ratings":{"x":1}}`, "/library.v1.Library/CreateBook", `{"shelf":"s1","book":{"title":"t","ratings":{"x":1},"author":{"name":"a"}}}`},
+		{"GET", "/v1/shelves/s2/books", "", "", "/library.v1.Library/ListBooks", `{"shelfName":"s2"}`},
+		// call the method by the gRPC path
+		{"POST", "/library.v1.Library/DeleteBook", "", `{"id":3}`, "/library.v1.Library/DeleteBook", `{"id":"3"}`},
+	}
+	for _, tc := range testCases {
+		f, rh, _ := newTestFilter(t)
+		headers := requestHeaders(tc.method, tc.path, tc.query)
+		var buf types.IoBuffer
+		if tc.body != "" {
+			buf = buffer.NewIoBufferString(tc.body)
+		}
+		if status := f.OnReceive(context.Background(), headers, buf, nil); status != types.StreamFilterContinue {
+			t.Fatalf("%s %s unexpected filter status %v", tc.method, tc.path, status)
+		}
+		if headers[protocol.MosnHeaderPathKey] != tc.grpcPath || headers[protocol.MosnHeaderMethod] != "POST" ||
+			headers["content-type"] != "application/grpc" || headers["te"] != "trailers" {
+			t.Errorf("%s %s unexpected headers: %v", tc.method, tc.path, headers)
+		}
+		if _, ok := headers["content-length"]; ok {
+			t.Error("content length should be removed")
+		}
+		if _, ok := headers[protocol.MosnHeaderQueryStringKey]; ok {
+			t.Error("query string should be removed")
+		}
+		messages := decodeFrames(t, f.method.input, rh.data.Bytes())
+		if len(messages) != 1 || messages[0] != tc.expected {
+			t.Errorf("%s %s expected request %s, but got %v", tc.method, tc.path, tc.expected, messages)
+		}
+	}
+}
+
+func TestTranscodeRequestError(t *testing.T) {
+	testCases := []struct {
+		method string
+		path   string
+		body   string
+		status string
+		code   float64
+	}{
+		{"GET", "/library.v1.Library/Unknown", "", "501", 12},
+		{"POST", "/v1/shelves/s1/books", `{"title":1}`, "400", 3},
+		{"POST", "/v1/shelves/s1/books", `{"unknown":1}`, "400", 3},
+		{"POST", "/v1/shelves/s1/books", `{invalid`, "400", 3},
+	}
+	for _, tc := range testCases {
+		f, rh, _ := newTestFilter(t)
+		headers := requestHeaders(tc.method, tc.path, "")
+		if status := f.OnReceive(context.Background(), headers, buffer.NewIoBufferString(tc.body), nil); status != types.StreamFilterStop {
+			t.Fatalf("%s %s expected stop, but got %v", tc.method, tc.path, status)
+		}
+		if rh.headers == nil || headers[types.HeaderStatus] != tc.status || headers["content-type"] != "application/json" {
+			t.Errorf("%s %s unexpected response headers: %v", tc.method, tc.path, headers)
+		}
+		body := map[string]interface{}{}
+		if err := json.Unmarshal(rh.body.Bytes(), &body); err != nil || body["code"] != tc.code || body["message"] == "" {
+			t.Errorf("%s %s unexpected response body: %s", tc.method, tc.path, rh.body.String())
+		}
+	}
+
+	// not a transcoded request
+	f, rh, _ := newTestFilter(t)
+	headers := requestHeaders("GET", "/other", "")
+	if status := f.OnReceive(context.Background(), headers, nil, nil); status != types.StreamFilterContinue || rh.data != nil {
+		t.Error("the request should not be transcoded")
+	}
+	respHeaders := protocol.CommonHeader{"content-type": "text/plain"}
+	f.Append(context.Background(), respHeaders, buffer.NewIoBufferString("ok"), nil)
+	if respHeaders["content-type"] != "text/plain" {
+		t.Error("the response should not be transcoded")
+	}
+}
+
+func TestTranscodeResponse(t *testing.T) {
+	r := testRegistry(t)
+	book, err := encodeMessage(r.messages["library.v1.Book"], map[string]interface{}{
+		"id":        json.Number("1"),
+		"title":     "go",
+		"tags":      []interface{}{"a", "b"},
+		"kind":      "KIND_NOVEL",
+		"available": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bookJSON := `{"id":"1","title":"go","tags":["a","b"],"kind":"KIND_NOVEL","available":true}`
+	list, err := encodeMessage(r.messages["library.v1.ListBooksResponse"], map[string]interface{}{
+		"books": []interface{}{map[string]interface{}{"id": "1"}, map[string]interface{}{"id": "2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		method   string
+		path     string
+		data     []byte
+		trailers types.HeaderMap
+		status   string
+		expected string
+	}{
+		{"GET", "/v1/shelves/s1/books/1", appendFrame(nil, book), protocol.CommonHeader{"grpc-status": "0"}, "200", bookJSON},
+		{"GET", "/v1/books", appendFrame(nil, list), protocol.CommonHeader{"grpc-status": "0"}, "200", `[{"id":"1"},{"id":"2"}]`},
+		{"GET", "/v1/books:stream", appendFrame(appendFrame(nil, book), book), protocol.CommonHeader{"grpc-status": "0"}, "200", "[" + bookJSON + "," + bookJSON + "]"},
+		{"GET", "/v1/books:stream", nil, protocol.CommonHeader{"grpc-status": "0"}, "200", "[]"},
+		{"GET", "/v1/shelves/s1/books/1", nil, protocol.CommonHeader{"grpc-status": "5", "grpc-message": "book%20not%20found"}, "404", `{"code":5,"message":"book not found"}`},
+		{"GET", "/v1/shelves/s1/books/1", []byte{1, 0, 0, 0, 0}, protocol.CommonHeader{"grpc-status": "0"}, "500", `{"code":13,"message":"compressed grpc message is not supported"}`},
+	}
+	for _, tc := range testCases {
+		f, _, sh := newTestFilter(t)
+		f.OnReceive(context.Background(), requestHeaders(tc.method, tc.path, ""), nil, nil)
+
+		headers := protocol.CommonHeader{types.HeaderStatus: "200", "content-type": "application/grpc"}
+		var buf types.IoBuffer
+		if tc.data != nil {
+			buf = buffer.NewIoBufferBytes(tc.data)
+		}
+		f.Append(context.Background(), headers, buf, tc.trailers)
+		if headers[types.HeaderStatus] != tc.status || headers["content-type"] != "application/json" {
+			t.Errorf("%s %s unexpected response headers: %v", tc.method, tc.path, headers)
+		}
+		if sh.data == nil || sh.data.String() != tc.expected {
+			t.Errorf("%s %s expected response %s, but got %v", tc.method, tc.path, tc.expected, sh.data)
+		}
+		if sh.trailers != nil {
+			t.Error("trailers should be removed")
+		}
+	}
+
+	// trailers-only response
+	f, _, sh := newTestFilter(t)
+	f.OnReceive(context.Background(), requestHeaders("GET", "/v1/books", ""), nil, nil)
+	headers := protocol.CommonHeader{types.HeaderStatus: "200", "content-type": "application/grpc", "grpc-status": "14", "grpc-message": "unavailable"}
+	f.Append(context.Background(), headers, nil, nil)
+	if headers[types.HeaderStatus] != "503" || sh.data.String() != `{"code":14,"message":"unavailable"}` {
+		t.Errorf("unexpected trailers-only response: %v %s", headers, sh.data)
+	}
+	if _, ok := headers["grpc-status"]; ok {
+		t.Error("grpc status should be removed")
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	r := testRegistry(t)
+	mt := r.messages["library.v1.Book"]
+	input := `{"id":"-9007199254740993","title":"中文\"","tags":["a"],"kind":1,"ratings":{"a":1,"b":-2},` +
+		`"author":{"name":"n"},"cover":"AQID","price":1.5,"available":false,"delta":-3}`
+	expected := `{"id":"-9007199254740993","title":"中文\"","tags":["a"],"kind":"KIND_NOVEL","ratings":{"a":1,"b":-2},` +
+		`"author":{"name":"n"},"cover":"AQID","price":1.5,"available":false,"delta":-3}`
+	obj := map[string]interface{}{}
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeMessage(mt, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeMessage(mt, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(decoded)
+	if string(b) != expected {
+		t.Errorf("expected %s, but got %s", expected, b)
+	}
+	if _, err := decodeMessage(mt, data[:len(data)-1]); err == nil {
+		t.Error("decode truncated message should be failed")
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grpcjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := proto.Marshal(testDescriptorSet(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "library.pb")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	factory, err := CreateGRPCJSONTranscoderFilterFactory(map[string]interface{}{
+		"proto_descriptor": path,
+		"services":         []interface{}{"library.v1.Library"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := factory.(*FilterConfigFactory).registry
+	if len(r.methods) != 5 || len(r.bindings) != 5 {
+		t.Errorf("unexpected registry: %d methods, %d bindings", len(r.methods), len(r.bindings))
+	}
+	if b, values := r.match("GET", "/v1/shelves/s1/books"); b == nil || b.method.path != "/library.v1.Library/ListBooks" || values["shelf_name"] != "s1" {
+		t.Error("additional binding is not loaded")
+	}
+
+	if _, err := CreateGRPCJSONTranscoderFilterFactory(map[string]interface{}{
+		"proto_descriptor": path,
+		"services":         []interface{}{"library.v1.Unknown"},
+	}); err == nil {
+		t.Error("unknown service should be failed")
+	}
+	if _, err := CreateGRPCJSONTranscoderFilterFactory(map[string]interface{}{}); err == nil {
+		t.Error("config without descriptor should be failed")
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"strings"
+
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol/grpc"
+	"mosn.io/mosn/pkg/types"
+)
+
+// gRPC-Web content types, see https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+const (
+	contentTypeGrpcWeb     = "application/grpc-web"
+	contentTypeGrpcWebText = "application/grpc-web-text"
+
+	headerContentLength = "content-length"
+	headerTE            = "te"
+
+	// the flag of the trailers frame in the response body
+	trailerFrameFlag = 0x80
+)
+
+var errInvalidText = errors.New("invalid grpc web text body")
+
+// grpcWebFilter bridges the gRPC-Web requests from HTTP/1.1 browsers to the gRPC upstreams.
+// The request is converted into a gRPC request before the route is matched, and the trailers
+// of the gRPC response are encoded into the response body.
+type grpcWebFilter struct {
+	ctx            context.Context
+	receiveHandler types.StreamReceiverFilterHandler
+	sendHandler    types.StreamSenderFilterHandler
+	// the request is a gRPC-Web request
+	enabled bool
+	// the body is base64 encoded
+	text bool
+}
+
+func NewFilter(ctx context.Context) *grpcWebFilter {
+	return &grpcWebFilter{
+		ctx: ctx,
+	}
+}
+
+func (f *grpcWebFilter) SetReceiveFilterHandler(handler types.StreamReceiverFilterHandler) {
+	f.receiveHandler = handler
+}
+
+func (f *grpcWebFilter) SetSenderFilterHandler(handler types.StreamSenderFilterHandler) {
+	f.sendHandler = handler
+}
+
+func (f *grpcWebFilter) OnReceive(ctx context.Context, headers types.HeaderMap, buf types.IoBuffer, trailers types.HeaderMap) types.StreamFilterStatus {
+	contentType, _ := headers.Get(grpc.HeaderContentType)
+	subtype, text, ok := parseContentType(contentType)
+	if !ok {
+		return types.StreamFilterContinue
+	}
+	f.enabled = true
+	f.text = text
+
+	headers.Set(grpc.HeaderContentType, grpc.ContentType+subtype)
+	headers.Set(headerTE, "trailers")
+	headers.Del(headerContentLength)
+
+	if text && buf != nil && buf.Len() > 0 {
+		data, err := decodeText(buf.Bytes())
+		if err != nil {
+			log.Proxy.Errorf(ctx, "[stream filter] [grpc web] decode request body failed: %v", err)
+			f.receiveHandler.SendHijackReply(types.CodecExceptionCode, headers)
+			return types.StreamFilterStop
+		}
+		f.receiveHandler.SetRequestData(buffer.NewIoBufferBytes(data))
+	}
+	return types.StreamFilterContinue
+}
+
+func (f *grpcWebFilter) Append(ctx context.Context, headers types.HeaderMap, buf types.IoBuffer, trailers types.HeaderMap) types.StreamFilterStatus {
+	if !f.enabled {
+		return types.StreamFilterContinue
+	}
+
+	contentType, _ := headers.Get(grpc.HeaderContentType)
+	if grpc.IsGrpcContentType(contentType) {
+		webType := contentTypeGrpcWeb
+		if f.text {
+			webType = contentTypeGrpcWebText
+		}
+		headers.Set(grpc.HeaderContentType, webType+contentType[len(grpc.ContentType):])
+	}
+	headers.Del(headerContentLength)
+
+	var body []byte
+	if buf != nil {
+		body = append(body, buf.Bytes()...)
+	}
+	if trailers != nil {
+		body = append(body, encodeTrailers(trailers)...)
+		f.sendHandler.SetResponseTrailers(nil)
+	}
+	if f.text && len(body) > 0 {
+		body = []byte(base64.StdEncoding.EncodeToString(body))
+	}
+	if trailers != nil || len(body) > 0 && f.text {
+		f.sendHandler.SetResponseData(buffer.NewIoBufferBytes(body))
+	}
+	return types.StreamFilterContinue
+}
+
+func (f *grpcWebFilter) OnDestroy() {}
+
+// parseContentType returns the subtype suffix of a gRPC-Web content type, such as "+proto"
+func parseContentType(contentType string) (subtype string, text bool, ok bool) {
+	switch {
+	case strings.HasPrefix(contentType, contentTypeGrpcWebText):
+		subtype, text = contentType[len(contentTypeGrpcWebText):], true
+	case strings.HasPrefix(contentType, contentTypeGrpcWeb):
+		subtype = contentType[len(contentTypeGrpcWeb):]
+	default:
+		return "", false, false
+	}
+	if subtype != "" && subtype[0] != '+' && subtype[0] != ';' {
+		return "", false, false
+	}
+	return subtype, text, true
+}
+
+// decodeText decodes the base64 encoded body, which may be the concatenation of several padded chunks
+func decodeText(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	decoded := make([]byte, 0, base64.StdEncoding.DecodedLen(len(data)))
+	for len(data) > 0 {
+		end := len(data)
+		if i := bytes.IndexByte(data, '='); i >= 0 {
+			end = i
+			for end < len(data) && data[end] == '=' {
+				end++
+			}
+		}
+		if end%4 != 0 {
+			return nil, errInvalidText
+		}
+		chunk := make([]byte, base64.StdEncoding.DecodedLen(end))
+		n, err := base64.StdEncoding.Decode(chunk, data[:end])
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, chunk[:n]...)
+		data = data[end:]
+	}
+	return decoded, nil
+}
+
+// encodeTrailers encodes the trailers into a gRPC-Web trailers frame
+func encodeTrailers(trailers types.HeaderMap) []byte {
+	var lines []string
+	trailers.Range(func(key, value string) bool {
+		lines = append(lines, strings.ToLower(key)+":"+value+"\r\n")
+		return true
+	})
+	sort.Strings(lines)
+	payload := strings.Join(lines, "")
+
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = trailerFrameFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return append(frame, payload...)
+}
@@ -0,0 +1,159 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockReceiveHandler struct {
+	types.StreamReceiverFilterHandler
+	data       types.IoBuffer
+	hijackCode int
+}
+
+func (h *mockReceiveHandler) SetRequestData(data types.IoBuffer) {
+	h.data = data
+}
+
+func (h *mockReceiveHandler) SendHijackReply(code int, headers types.HeaderMap) {
+	h.hijackCode = code
+}
+
+type mockSendHandler struct {
+	types.StreamSenderFilterHandler
+	data     types.IoBuffer
+	trailers types.HeaderMap
+}
+
+func (h *mockSendHandler) SetResponseData(data types.IoBuffer) {
+	h.data = data
+}
+
+func (h *mockSendHandler) SetResponseTrailers(trailers types.HeaderMap) {
+	h.trailers = trailers
+}
+
+// a gRPC message frame
+var message = []byte{0, 0, 0, 0, 3, 'a', 'b', 'c'}
+
+func TestGrpcWebBinary(t *testing.T) {
+	f := NewFilter(context.Background())
+	rh := &mockReceiveHandler{}
+	sh := &mockSendHandler{}
+	f.SetReceiveFilterHandler(rh)
+	f.SetSenderFilterHandler(sh)
+
+	headers := protocol.CommonHeader{
+		"content-type":   "application/grpc-web+proto",
+		"content-length": "8",
+	}
+	if status := f.OnReceive(context.Background(), headers, buffer.NewIoBufferBytes(message), nil); status != types.StreamFilterContinue {
+		t.Fatalf("unexpected filter status: %v", status)
+	}
+	if headers["content-type"] != "application/grpc+proto" || headers["te"] != "trailers" {
+		t.Errorf("unexpected request headers: %v", headers)
+	}
+	if _, ok := headers["content-length"]; ok {
+		t.Error("content length should be removed")
+	}
+	if rh.data != nil {
+		t.Error("binary body should not be changed")
+	}
+
+	respHeaders := protocol.CommonHeader{"content-type": "application/grpc+proto"}
+	trailers := protocol.CommonHeader{"grpc-status": "0", "grpc-message": "ok"}
+	f.Append(context.Background(), respHeaders, buffer.NewIoBufferBytes(message), trailers)
+	if respHeaders["content-type"] != "application/grpc-web+proto" {
+		t.Errorf("unexpected response headers: %v", respHeaders)
+	}
+	if sh.trailers != nil {
+		t.Error("trailers should be removed")
+	}
+	payload := "grpc-message:ok\r\ngrpc-status:0\r\n"
+	expected := append(append([]byte{}, message...), 0x80, 0, 0, 0, byte(len(payload)))
+	expected = append(expected, payload...)
+	if sh.data == nil || !bytes.Equal(sh.data.Bytes(), expected) {
+		t.Errorf("unexpected response body: %q", sh.data)
+	}
+}
+
+func TestGrpcWebText(t *testing.T) {
+	f := NewFilter(context.Background())
+	rh := &mockReceiveHandler{}
+	sh := &mockSendHandler{}
+	f.SetReceiveFilterHandler(rh)
+	f.SetSenderFilterHandler(sh)
+
+	// the body is the concatenation of two padded chunks
+	body := base64.StdEncoding.EncodeToString(message[:4]) + base64.StdEncoding.EncodeToString(message[4:])
+	headers := protocol.CommonHeader{"content-type": "application/grpc-web-text"}
+	f.OnReceive(context.Background(), headers, buffer.NewIoBufferString(body), nil)
+	if headers["content-type"] != "application/grpc" {
+		t.Errorf("unexpected request headers: %v", headers)
+	}
+	if rh.data == nil || !bytes.Equal(rh.data.Bytes(), message) {
+		t.Errorf("unexpected request body: %v", rh.data)
+	}
+
+	// trailers-only response
+	respHeaders := protocol.CommonHeader{"content-type": "application/grpc", "grpc-status": "14"}
+	f.Append(context.Background(), respHeaders, nil, nil)
+	if respHeaders["content-type"] != "application/grpc-web-text" || respHeaders["grpc-status"] != "14" {
+		t.Errorf("unexpected response headers: %v", respHeaders)
+	}
+	if sh.data != nil {
+		t.Errorf("trailers-only response should have no body, but got %v", sh.data)
+	}
+
+	f.Append(context.Background(), respHeaders, buffer.NewIoBufferBytes(message), nil)
+	if sh.data == nil || sh.data.String() != base64.StdEncoding.EncodeToString(message) {
+		t.Errorf("unexpected response body: %v", sh.data)
+	}
+}
+
+func TestGrpcWebInvalid(t *testing.T) {
+	f := NewFilter(context.Background())
+	rh := &mockReceiveHandler{}
+	f.SetReceiveFilterHandler(rh)
+
+	headers := protocol.CommonHeader{"content-type": "application/grpc-web-text"}
+	if status := f.OnReceive(context.Background(), headers, buffer.NewIoBufferString("abc"), nil); status != types.StreamFilterStop {
+		t.Errorf("invalid text body should be stopped, but got %v", status)
+	}
+	if rh.hijackCode != types.CodecExceptionCode {
+		t.Errorf("unexpected hijack code: %d", rh.hijackCode)
+	}
+
+	// not a grpc web request
+	f = NewFilter(context.Background())
+	headers = protocol.CommonHeader{"content-type": "application/grpc-webx"}
+	f.OnReceive(context.Background(), headers, nil, nil)
+	respHeaders := protocol.CommonHeader{"content-type": "application/grpc"}
+	f.Append(context.Background(), respHeaders, nil, nil)
+	if headers["content-type"] != "application/grpc-webx" || respHeaders["content-type"] != "application/grpc" {
+		t.Error("non grpc web request should not be changed")
+	}
+}
@@ -20,6 +20,7 @@ package log
 import (
 	"context"
 	"errors"
+	"io"
 
 	"mosn.io/mosn/pkg/buffer"
 	"mosn.io/mosn/pkg/types"
@@ -54,6 +55,8 @@ type accesslog struct {
 	output  string
 	entries []*logEntry
 	logger  *Logger
+	// jsonFields is not nil if the access log is in json format
+	jsonFields []*jsonField
 }
 
 type logEntry struct {
@@ -108,13 +111,26 @@ func (l *accesslog) Log(ctx context.Context, reqHeaders types.HeaderMap, respHea
 	}
 
 	buf := buffer.GetIoBuffer(AccessLogLen)
-	for idx := range l.entries {
-		l.entries[idx].log(ctx, buf)
+	if l.jsonFields != nil {
+		l.logJSON(ctx, buf)
+	} else {
+		for idx := range l.entries {
+			l.entries[idx].log(ctx, buf)
+		}
 	}
 	buf.WriteString("\n")
 	l.logger.Print(buf, true)
 }
 
+// CloseAccessLog closes the access log if it holds resources of its own, such as a grpc access log.
+// The file access logs share the underlying loggers, which are closed by CloseAll.
+func CloseAccessLog(al types.AccessLog) error {
+	if c, ok := al.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 func parseFormat(format string) ([]*logEntry, error) {
 	if format == "" {
 		return nil, ErrLogFormatUndefined
@@ -0,0 +1,374 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/runtime"
+	"mosn.io/mosn/pkg/types"
+)
+
+// access log filter types
+const (
+	FilterStatusCode   = "status_code"
+	FilterDuration     = "duration"
+	FilterResponseFlag = "response_flag"
+	FilterHeader       = "header"
+	FilterRuntime      = "runtime"
+	FilterAnd          = "and"
+	FilterOr           = "or"
+	FilterNot          = "not"
+)
+
+// AccessLogFilter decides whether a request is logged
+type AccessLogFilter interface {
+	Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool
+}
+
+type filterCreator func(cfg *v2.AccessLogFilter) (AccessLogFilter, error)
+
+var filterCreators map[string]filterCreator
+
+func init() {
+	filterCreators = map[string]filterCreator{
+		FilterStatusCode:   newStatusCodeFilter,
+		FilterDuration:     newDurationFilter,
+		FilterResponseFlag: newResponseFlagFilter,
+		FilterHeader:       newHeaderFilter,
+		FilterRuntime:      newRuntimeFilter,
+		FilterAnd:          newAndFilter,
+		FilterOr:           newOrFilter,
+		FilterNot:          newNotFilter,
+	}
+}
+
+// NewAccessLogFilter creates the filter of the config
+func NewAccessLogFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	creator, ok := filterCreators[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown access log filter type: %s", cfg.Type)
+	}
+	return creator(cfg)
+}
+
+// NewFilteredAccessLog returns an access log that only logs the requests accepted by the filter,
+// the filter is evaluated before the entry is formatted
+func NewFilteredAccessLog(al types.AccessLog, filter AccessLogFilter) types.AccessLog {
+	return &filteredAccessLog{
+		AccessLog: al,
+		filter:    filter,
+	}
+}
+
+type filteredAccessLog struct {
+	types.AccessLog
+	filter AccessLogFilter
+}
+
+func (l *filteredAccessLog) Log(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) {
+	if requestInfo == nil || !l.filter.Evaluate(ctx, reqHeaders, respHeaders, requestInfo) {
+		return
+	}
+	l.AccessLog.Log(ctx, reqHeaders, respHeaders, requestInfo)
+}
+
+// Close closes the filtered access log if it is closable
+func (l *filteredAccessLog) Close() error {
+	return CloseAccessLog(l.AccessLog)
+}
+
+// parseFilterConfig parses the config of a filter into the config struct
+func parseFilterConfig(cfg *v2.AccessLogFilter, config interface{}) error {
+	data, err := json.Marshal(cfg.Config)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("invalid %s access log filter config: %v", cfg.Type, err)
+	}
+	return nil
+}
+
+// statusCodeFilter accepts the requests whose response code is in any of the ranges
+type statusCodeFilter struct {
+	Ranges []struct {
+		Min int `json:"min"`
+		Max int `json:"max"`
+	} `json:"ranges"`
+}
+
+func newStatusCodeFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	f := &statusCodeFilter{}
+	if err := parseFilterConfig(cfg, f); err != nil {
+		return nil, err
+	}
+	if len(f.Ranges) == 0 {
+		return nil, errors.New("status code access log filter has no ranges")
+	}
+	for _, r := range f.Ranges {
+		if r.Max < r.Min {
+			return nil, fmt.Errorf("invalid status code range: [%d, %d]", r.Min, r.Max)
+		}
+	}
+	return f, nil
+}
+
+func (f *statusCodeFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	code := requestInfo.ResponseCode()
+	for _, r := range f.Ranges {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// durationFilter accepts the requests that take at least the min duration
+type durationFilter struct {
+	Min v2.DurationConfig `json:"min"`
+}
+
+func newDurationFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	f := &durationFilter{}
+	if err := parseFilterConfig(cfg, f); err != nil {
+		return nil, err
+	}
+	if f.Min.Duration <= 0 {
+		return nil, errors.New("duration access log filter min is not positive")
+	}
+	return f, nil
+}
+
+func (f *durationFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	d := requestInfo.RequestFinishedDuration()
+	if d <= 0 {
+		d = requestInfo.Duration()
+	}
+	return d >= f.Min.Duration
+}
+
+// responseFlagFilter accepts the requests with any of the response flags,
+// the requests with any response flag are accepted if no flags are configured
+type responseFlagFilter struct {
+	flags []types.ResponseFlag
+}
+
+func newResponseFlagFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	config := struct {
+		Flags []string `json:"flags"`
+	}{}
+	if err := parseFilterConfig(cfg, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Flags) == 0 {
+		return &responseFlagFilter{flags: types.AllResponseFlags()}, nil
+	}
+	f := &responseFlagFilter{}
+	for _, name := range config.Flags {
+		flag, ok := types.ResponseFlagByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown response flag: %s", name)
+		}
+		f.flags = append(f.flags, flag)
+	}
+	return f, nil
+}
+
+func (f *responseFlagFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	for _, flag := range f.flags {
+		if requestInfo.GetResponseFlag(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerFilter accepts the requests with the request header. The value is matched
+// by the exact value or the regex, the header presence is checked if neither is configured
+type headerFilter struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Regex  string `json:"regex"`
+	Invert bool   `json:"invert"`
+
+	regex *regexp.Regexp
+}
+
+func newHeaderFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	f := &headerFilter{}
+	if err := parseFilterConfig(cfg, f); err != nil {
+		return nil, err
+	}
+	if f.Name == "" {
+		return nil, errors.New("header access log filter name is empty")
+	}
+	if f.Value != "" && f.Regex != "" {
+		return nil, errors.New("header access log filter value and regex are exclusive")
+	}
+	if f.Regex != "" {
+		regex, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header access log filter regex: %v", err)
+		}
+		f.regex = regex
+	}
+	return f, nil
+}
+
+func (f *headerFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	matched := false
+	if value, ok := getHeader(reqHeaders, f.Name); ok {
+		switch {
+		case f.regex != nil:
+			matched = f.regex.MatchString(value)
+		case f.Value != "":
+			matched = value == f.Value
+		default:
+			matched = true
+		}
+	}
+	return matched != f.Invert
+}
+
+// getHeader gets the header case-insensitively, the http headers are case-insensitive but the rpc headers are not
+func getHeader(headers types.HeaderMap, key string) (string, bool) {
+	if headers == nil {
+		return "", false
+	}
+	for _, k := range []string{key, strings.ToLower(key), textproto.CanonicalMIMEHeaderKey(key)} {
+		if v, ok := headers.Get(k); ok && v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// runtimeFilter accepts the requests in the percent randomly,
+// the percent can be changed by the runtime value of the runtime key
+type runtimeFilter struct {
+	Percent    float64 `json:"percent"`
+	RuntimeKey string  `json:"runtime_key"`
+}
+
+func newRuntimeFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	f := &runtimeFilter{}
+	if err := parseFilterConfig(cfg, f); err != nil {
+		return nil, err
+	}
+	if f.Percent < 0 || f.Percent > 100 {
+		return nil, fmt.Errorf("runtime access log filter percent %v is not in [0, 100]", f.Percent)
+	}
+	return f, nil
+}
+
+func (f *runtimeFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	percent := f.Percent
+	if v, ok := runtime.Get(f.RuntimeKey); ok {
+		if p, err := strconv.ParseFloat(v, 64); err == nil {
+			percent = p
+		}
+	}
+	return rand.Float64()*100 < percent
+}
+
+func newSubFilters(cfg *v2.AccessLogFilter) ([]AccessLogFilter, error) {
+	filters := make([]AccessLogFilter, 0, len(cfg.Filters))
+	for i := range cfg.Filters {
+		f, err := NewAccessLogFilter(&cfg.Filters[i])
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// andFilter accepts the requests accepted by all the filters
+type andFilter []AccessLogFilter
+
+func newAndFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	if len(cfg.Filters) < 2 {
+		return nil, errors.New("and access log filter needs at least two filters")
+	}
+	filters, err := newSubFilters(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return andFilter(filters), nil
+}
+
+func (f andFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	for _, filter := range f {
+		if !filter.Evaluate(ctx, reqHeaders, respHeaders, requestInfo) {
+			return false
+		}
+	}
+	return true
+}
+
+// orFilter accepts the requests accepted by any of the filters
+type orFilter []AccessLogFilter
+
+func newOrFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	if len(cfg.Filters) < 2 {
+		return nil, errors.New("or access log filter needs at least two filters")
+	}
+	filters, err := newSubFilters(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return orFilter(filters), nil
+}
+
+func (f orFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	for _, filter := range f {
+		if filter.Evaluate(ctx, reqHeaders, respHeaders, requestInfo) {
+			return true
+		}
+	}
+	return false
+}
+
+// notFilter accepts the requests rejected by the filter
+type notFilter struct {
+	filter AccessLogFilter
+}
+
+func newNotFilter(cfg *v2.AccessLogFilter) (AccessLogFilter, error) {
+	if len(cfg.Filters) != 1 {
+		return nil, errors.New("not access log filter needs exactly one filter")
+	}
+	filters, err := newSubFilters(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &notFilter{filter: filters[0]}, nil
+}
+
+func (f *notFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	return !f.filter.Evaluate(ctx, reqHeaders, respHeaders, requestInfo)
+}
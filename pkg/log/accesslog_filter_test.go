@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/types"
+)
+
+// headerMap is a case-sensitive header map for test
+type headerMap map[string]string
+
+func (h headerMap) Get(key string) (string, bool) {
+	v, ok := h[key]
+	return v, ok
+}
+
+func (h headerMap) Set(key, value string) {
+	h[key] = value
+}
+
+func (h headerMap) Add(key, value string) {
+	h[key] = value
+}
+
+func (h headerMap) Del(key string) {
+	delete(h, key)
+}
+
+func (h headerMap) Range(f func(key, value string) bool) {
+	for k, v := range h {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+func (h headerMap) Clone() types.HeaderMap {
+	c := headerMap{}
+	for k, v := range h {
+		c[k] = v
+	}
+	return c
+}
+
+func (h headerMap) ByteSize() uint64 {
+	return 0
+}
+
+func newTestFilter(t *testing.T, config string) AccessLogFilter {
+	cfg := &v2.AccessLogFilter{}
+	if err := json.Unmarshal([]byte(config), cfg); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewAccessLogFilter(cfg)
+	if err != nil {
+		t.Fatalf("create filter %s failed: %v", config, err)
+	}
+	return f
+}
+
+func TestAccessLogFilter(t *testing.T) {
+	info := newRequestInfo()
+	info.SetResponseCode(503)
+	info.SetResponseFlag(types.UpstreamConnectionFailure)
+	info.SetRequestFinishedDuration(time.Now().Add(2 * time.Second))
+	headers := headerMap{"x-debug": "1", "user-agent": "curl/7.64.1"}
+
+	testCases := []struct {
+		config   string
+		expected bool
+	}{
+		{`{"type": "status_code", "config": {"ranges": [{"min": 500, "max": 599}]}}`, true},
+		{`{"type": "status_code", "config": {"ranges": [{"min": 200, "max": 299}, {"min": 429, "max": 429}]}}`, false},
+		{`{"type": "duration", "config": {"min": "1s"}}`, true},
+		{`{"type": "duration", "config": {"min": "3s"}}`, false},
+		{`{"type": "response_flag"}`, true},
+		{`{"type": "response_flag", "config": {"flags": ["UF", "UO"]}}`, true},
+		{`{"type": "response_flag", "config": {"flags": ["UH"]}}`, false},
+		{`{"type": "header", "config": {"name": "X-Debug"}}`, true},
+		{`{"type": "header", "config": {"name": "x-debug", "value": "0"}}`, false},
+		{`{"type": "header", "config": {"name": "user-agent", "regex": "^curl/"}}`, true},
+		{`{"type": "header", "config": {"name": "x-trace", "invert": true}}`, true},
+		{`{"type": "runtime", "config": {"percent": 100}}`, true},
+		{`{"type": "runtime", "config": {"percent": 0}}`, false},
+		{`{"type": "and", "filters": [
+			{"type": "status_code", "config": {"ranges": [{"min": 500, "max": 599}]}},
+			{"type": "header", "config": {"name": "x-debug"}}
+		]}`, true},
+		{`{"type": "and", "filters": [
+			{"type": "status_code", "config": {"ranges": [{"min": 500, "max": 599}]}},
+			{"type": "runtime", "config": {"percent": 0}}
+		]}`, false},
+		{`{"type": "or", "filters": [
+			{"type": "duration", "config": {"min": "3s"}},
+			{"type": "not", "filters": [{"type": "header", "config": {"name": "x-debug"}}]}
+		]}`, false},
+		{`{"type": "not", "filters": [{"type": "response_flag", "config": {"flags": ["UH"]}}]}`, true},
+	}
+	for _, tc := range testCases {
+		f := newTestFilter(t, tc.config)
+		if result := f.Evaluate(context.Background(), headers, nil, info); result != tc.expected {
+			t.Errorf("%s: expected %v, but got %v", tc.config, tc.expected, result)
+		}
+	}
+}
+
+func TestAccessLogFilterConfigError(t *testing.T) {
+	for _, config := range []string{
+		`{"type": "unknown"}`,
+		`{"type": "status_code"}`,
+		`{"type": "status_code", "config": {"ranges": [{"min": 599, "max": 500}]}}`,
+		`{"type": "status_code", "config": {"ranges": "500-599"}}`,
+		`{"type": "duration", "config": {"min": "0s"}}`,
+		`{"type": "response_flag", "config": {"flags": ["XX"]}}`,
+		`{"type": "header", "config": {"value": "1"}}`,
+		`{"type": "header", "config": {"name": "x-debug", "value": "1", "regex": "1"}}`,
+		`{"type": "header", "config": {"name": "x-debug", "regex": "("}}`,
+		`{"type": "runtime", "config": {"percent": 101}}`,
+		`{"type": "and", "filters": [{"type": "response_flag"}]}`,
+		`{"type": "or", "filters": [{"type": "response_flag"}, {"type": "unknown"}]}`,
+		`{"type": "not", "filters": []}`,
+	} {
+		cfg := &v2.AccessLogFilter{}
+		if err := json.Unmarshal([]byte(config), cfg); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewAccessLogFilter(cfg); err == nil {
+			t.Errorf("%s: expected an error", config)
+		}
+	}
+}
+
+type countAccessLog struct {
+	count int
+}
+
+func (l *countAccessLog) Log(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) {
+	l.count++
+}
+
+func TestFilteredAccessLog(t *testing.T) {
+	al := &countAccessLog{}
+	filtered := NewFilteredAccessLog(al, newTestFilter(t, `{"type": "status_code", "config": {"ranges": [{"min": 500, "max": 599}]}}`))
+	for _, code := range []int{200, 500, 404, 503} {
+		info := newRequestInfo()
+		info.SetResponseCode(code)
+		filtered.Log(context.Background(), nil, nil, info)
+	}
+	filtered.Log(context.Background(), nil, nil, nil)
+	if al.count != 2 {
+		t.Errorf("expected 2 requests logged, but got %d", al.count)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package log
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/variable"
+)
+
+// jsonField is a field of the json access log, the value is one of
+// a single variable: "%bytes_sent%", written in the value type of the variable
+// a literal text: "mosn", written as a string
+// a text with variables: "%protocol%/%response_code%", written as a string like the text access log
+type jsonField struct {
+	// the quoted key and the colon
+	key string
+	// the variable name and the variable of a single variable field,
+	// variable is nil if the variable is not defined, and the value is always null
+	name     string
+	variable variable.Variable
+	// the entries of the literal text or the text with variables
+	entries []*logEntry
+}
+
+// NewJSONAccessLog creates an access log that writes a json object per line,
+// the format is a map of the field name to the variable or the literal text.
+// The values not found, including the variables not defined, are written as null
+func NewJSONAccessLog(output string, format map[string]string) (types.AccessLog, error) {
+	fields, err := parseJSONFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	lg, err := GetOrCreateLogger(output, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &accesslog{
+		output:     output,
+		jsonFields: fields,
+		logger:     lg,
+	}
+
+	if DefaultDisableAccessLog {
+		lg.Toggle(true) // disable accesslog by default
+	}
+	// save all access logs
+	accessLogs = append(accessLogs, l)
+
+	return l, nil
+}
+
+func parseJSONFormat(format map[string]string) ([]*jsonField, error) {
+	if len(format) == 0 {
+		return nil, ErrLogFormatUndefined
+	}
+
+	// the fields are sorted by the name, so the output is stable
+	names := make([]string, 0, len(format))
+	for name := range format {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]*jsonField, 0, len(names))
+	for _, name := range names {
+		field := &jsonField{
+			key: string(appendJSONString(make([]byte, 0, len(name)+3), name)) + ":",
+		}
+		value := format[name]
+		if isSingleVariable(value) {
+			field.name = value[1 : len(value)-1]
+			if v, err := variable.AddVariable(field.name); err == nil {
+				field.variable = v
+			} else if DefaultLogger != nil {
+				DefaultLogger.Warnf("[log] [accesslog] json field %s: %v, the value is always null", name, err)
+			}
+		} else if value == "" {
+			field.entries = []*logEntry{}
+		} else {
+			entries, err := parseFormat(value)
+			if err != nil {
+				return nil, err
+			}
+			field.entries = entries
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// isSingleVariable checks the value is a variable definition like %bytes_sent%
+func isSingleVariable(value string) bool {
+	return len(value) > 2 && value[0] == '%' && value[len(value)-1] == '%' &&
+		strings.IndexByte(value[1:len(value)-1], '%') < 0
+}
+
+func (l *accesslog) logJSON(ctx context.Context, buf types.IoBuffer) {
+	b := make([]byte, 0, AccessLogLen)
+	b = append(b, '{')
+	for idx, field := range l.jsonFields {
+		if idx > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, field.key...)
+		b = field.appendValue(ctx, b)
+	}
+	b = append(b, '}')
+	buf.Write(b)
+}
+
+func (field *jsonField) appendValue(ctx context.Context, b []byte) []byte {
+	if field.entries != nil {
+		var sb strings.Builder
+		for _, entry := range field.entries {
+			if entry.text != "" {
+				sb.WriteString(entry.text)
+				continue
+			}
+			value, err := variable.GetVariableValue(ctx, entry.variable.Name())
+			if err != nil {
+				value = variable.ValueNotFound
+			}
+			sb.WriteString(value)
+		}
+		return appendJSONString(b, sb.String())
+	}
+
+	if field.variable == nil {
+		return append(b, "null"...)
+	}
+	value, err := variable.GetVariableValue(ctx, field.name)
+	if err != nil || value == variable.ValueNotFound {
+		return append(b, "null"...)
+	}
+	return appendJSONValue(b, value, variable.GetValueType(field.variable))
+}
+
+// appendJSONValue writes the value in the value type, the value is written as a string
+// if it is not valid in the type. A duration is written as a number in milliseconds
+func appendJSONValue(b []byte, value string, valueType variable.ValueType) []byte {
+	switch valueType {
+	case variable.ValueTypeNumber:
+		if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return append(b, value...)
+			}
+			return strconv.AppendFloat(b, f, 'f', -1, 64)
+		}
+	case variable.ValueTypeDuration:
+		if d, err := time.ParseDuration(value); err == nil {
+			return strconv.AppendFloat(b, float64(d)/float64(time.Millisecond), 'f', -1, 64)
+		}
+	case variable.ValueTypeBool:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return strconv.AppendBool(b, v)
+		}
+	}
+	return appendJSONString(b, value)
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString writes the quoted and escaped json string, the invalid utf-8 bytes are replaced by U+FFFD
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are escaped as encoding/json does
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
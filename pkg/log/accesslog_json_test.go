@@ -0,0 +1,126 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/variable"
+)
+
+func registerJSONTestVarDefs() {
+	getter := func(value string) variable.GetterFunc {
+		return func(ctx context.Context, iv *variable.IndexedValue, data interface{}) (string, error) {
+			return value, nil
+		}
+	}
+	for _, v := range []variable.Variable{
+		variable.NewTypedVariable("json_test_bytes", nil, getter("2048"), nil, 0, variable.ValueTypeNumber),
+		variable.NewTypedVariable("json_test_duration", nil, getter("1.5ms"), nil, 0, variable.ValueTypeDuration),
+		variable.NewTypedVariable("json_test_flag", nil, getter("false"), nil, 0, variable.ValueTypeBool),
+		variable.NewTypedVariable("json_test_not_found", nil, getter(variable.ValueNotFound), nil, 0, variable.ValueTypeNumber),
+		variable.NewBasicVariable("json_test_text", nil, getter("say \"hi\"\n\x01"), nil, 0),
+	} {
+		variable.RegisterVariable(v)
+	}
+}
+
+func TestJSONAccessLog(t *testing.T) {
+	registerJSONTestVarDefs()
+
+	logName := "/tmp/mosn_accesslog/json_access.log"
+	os.Remove(logName)
+	accessLog, err := NewJSONAccessLog(logName, map[string]string{
+		"bytes":     "%json_test_bytes%",
+		"duration":  "%json_test_duration%",
+		"flag":      "%json_test_flag%",
+		"not_found": "%json_test_not_found%",
+		"undefined": "%json_test_undefined%",
+		"text":      "%json_test_text%",
+		"literal":   "mosn",
+		"mixed":     "%json_test_bytes%/%json_test_flag%",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessLog.Log(context.Background(), nil, nil, nil)
+	time.Sleep(time.Second)
+
+	b, err := ioutil.ReadFile(logName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"bytes":2048,"duration":1.5,"flag":false,"literal":"mosn","mixed":"2048/false","not_found":null,` +
+		`"text":"say \"hi\"\n\u0001","undefined":null}` + "\n"
+	if string(b) != expected {
+		t.Fatalf("unexpected log: %s", b)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["text"] != "say \"hi\"\n\x01" {
+		t.Errorf("unexpected text field: %v", fields["text"])
+	}
+}
+
+func TestJSONAccessLogFormatError(t *testing.T) {
+	registerJSONTestVarDefs()
+
+	logName := "/tmp/mosn_accesslog/json_access.log"
+	if _, err := NewJSONAccessLog(logName, nil); err != ErrLogFormatUndefined {
+		t.Errorf("expected format undefined error, but got %v", err)
+	}
+	if _, err := NewJSONAccessLog(logName, map[string]string{"mixed": "%json_test_bytes%/%"}); err != ErrUnclosedVarDef {
+		t.Errorf("expected unclosed variable error, but got %v", err)
+	}
+}
+
+func TestAppendJSONValue(t *testing.T) {
+	testCases := []struct {
+		value     string
+		valueType variable.ValueType
+		expected  string
+	}{
+		{"200", variable.ValueTypeNumber, `200`},
+		{"0.25", variable.ValueTypeNumber, `0.25`},
+		{"NaN", variable.ValueTypeNumber, `"NaN"`},
+		{"abc", variable.ValueTypeNumber, `"abc"`},
+		{"2.00000227s", variable.ValueTypeDuration, `2000.00227`},
+		{"1.329µs", variable.ValueTypeDuration, `0.001329`},
+		{"0s", variable.ValueTypeDuration, `0`},
+		{"true", variable.ValueTypeBool, `true`},
+		{"yes", variable.ValueTypeBool, `"yes"`},
+		{"200", variable.ValueTypeString, `"200"`},
+		{"a\\b\tc\r", variable.ValueTypeString, `"a\\b\tc\r"`},
+		{"中文\u2028", variable.ValueTypeString, `"中文\u2028"`},
+		{"bad\xffutf8", variable.ValueTypeString, `"bad\ufffdutf8"`},
+	}
+	for _, tc := range testCases {
+		if got := string(appendJSONValue(nil, tc.value, tc.valueType)); got != tc.expected {
+			t.Errorf("%q: expected %s, but got %s", tc.value, tc.expected, got)
+		}
+		if !json.Valid(appendJSONValue(nil, tc.value, tc.valueType)) {
+			t.Errorf("%q: invalid json", tc.value)
+		}
+	}
+}
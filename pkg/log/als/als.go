@@ -0,0 +1,284 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package als implements an access log that streams the entries to an envoy compatible
+// gRPC access log service, see envoy/service/accesslog/v2/als.proto.
+// The messages are encoded without the generated codes, only the fields mosn knows are written.
+package als
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/otlp"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+// log types
+const (
+	LogTypeHTTP = "http"
+	LogTypeTCP  = "tcp"
+)
+
+const serviceMethod = "/envoy.service.accesslog.v2.AccessLogService/StreamAccessLogs"
+
+var streamDesc = &grpc.StreamDesc{
+	StreamName:    "StreamAccessLogs",
+	ClientStreams: true,
+}
+
+var (
+	defaultQueueSize     = 4096
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	minBackoff           = 500 * time.Millisecond
+	maxBackoff           = 30 * time.Second
+
+	errStopped = errors.New("access log stopped")
+)
+
+// accessLog implements types.AccessLog, the entries are encoded in Log and sent in batches
+// by a background goroutine. A stream is kept to the service, and reconnected with backoff if it is broken.
+type accessLog struct {
+	config    *v2.GRPCAccessLog
+	conn      *grpc.ClientConn
+	queue     chan []byte
+	stats     types.Metrics
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAccessLog creates an access log streaming to the gRPC access log service
+func NewAccessLog(config *v2.GRPCAccessLog) (types.AccessLog, error) {
+	l, err := newAccessLog(config)
+	if err != nil {
+		return nil, err
+	}
+	utils.GoWithRecover(l.run, nil)
+	return l, nil
+}
+
+func newAccessLog(config *v2.GRPCAccessLog) (*accessLog, error) {
+	if config.Address == "" {
+		return nil, errors.New("grpc access log service address is not specified")
+	}
+	if config.LogName == "" {
+		return nil, errors.New("grpc access log name is not specified")
+	}
+	switch config.LogType {
+	case "":
+		config.LogType = LogTypeHTTP
+	case LogTypeHTTP, LogTypeTCP:
+	default:
+		return nil, fmt.Errorf("unsupported grpc access log type: %s", config.LogType)
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.FlushInterval.Duration <= 0 {
+		config.FlushInterval.Duration = defaultFlushInterval
+	}
+
+	tlsConfig, err := otlp.NewTLSConfig(&config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	opt := grpc.WithInsecure()
+	if tlsConfig != nil {
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+	// the connection is established in background
+	conn, err := grpc.Dial(config.Address, opt)
+	if err != nil {
+		return nil, fmt.Errorf("dial grpc access log service %s failed: %v", config.Address, err)
+	}
+
+	return &accessLog{
+		config: config,
+		conn:   conn,
+		queue:  make(chan []byte, config.QueueSize),
+		stats:  metrics.NewAccessLogStats(config.LogName),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Log encodes the entry and puts it into the queue, the entry is dropped if the queue is full
+func (l *accessLog) Log(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) {
+	if requestInfo == nil {
+		return
+	}
+	var entry []byte
+	if l.config.LogType == LogTypeTCP {
+		entry = encodeTCPEntry(requestInfo)
+	} else {
+		entry = encodeHTTPEntry(reqHeaders, respHeaders, requestInfo,
+			l.config.AdditionalRequestHeaders, l.config.AdditionalResponseHeaders)
+	}
+	select {
+	case l.queue <- entry:
+	default:
+		l.stats.Counter(metrics.AccessLogEntryDropped).Inc(1)
+	}
+}
+
+// Close stops the background goroutine and closes the connection, the queued entries are flushed
+// before the connection is closed, the ones can not be sent in a flush interval are dropped.
+// It is called when the listener of the access log is removed or mosn exits.
+func (l *accessLog) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+		l.conn.Close()
+	})
+	return nil
+}
+
+func (l *accessLog) run() {
+	defer close(l.done)
+	defer l.dropQueued()
+	backoff := minBackoff
+	for {
+		sent, err := l.stream(context.Background())
+		if err == errStopped {
+			return
+		}
+		l.stats.Counter(metrics.AccessLogStreamError).Inc(1)
+		log.DefaultLogger.Warnf("[log] [als] stream access logs to %s failed: %v, retry after %s", l.config.Address, err, backoff)
+		if sent {
+			backoff = minBackoff
+		}
+		select {
+		case <-l.stop:
+			// the stream is broken when stopped, flush the queued entries in a new stream
+			if len(l.queue) > 0 {
+				ctx, cancel := context.WithTimeout(context.Background(), l.config.FlushInterval.Duration)
+				l.stream(ctx, grpc.WaitForReady(true))
+				cancel()
+			}
+			return
+		case <-time.After(backoff):
+		}
+		if !sent {
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// dropQueued counts the entries left in the queue after stopped as dropped
+func (l *accessLog) dropQueued() {
+	for {
+		select {
+		case <-l.queue:
+			l.stats.Counter(metrics.AccessLogEntryDropped).Inc(1)
+		default:
+			return
+		}
+	}
+}
+
+// stream opens a stream and sends the entries until the stream is broken or the access log is stopped,
+// sent reports any entries are sent in the stream
+func (l *accessLog) stream(parent context.Context, opts ...grpc.CallOption) (sent bool, err error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	opts = append(opts, grpc.ForceCodec(otlp.RawCodec{}))
+	stream, err := l.conn.NewStream(ctx, streamDesc, serviceMethod, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	ticker := time.NewTicker(l.config.FlushInterval.Duration)
+	defer ticker.Stop()
+	batch := make([][]byte, 0, l.config.BatchSize)
+	identified := false
+	send := func() error {
+		msg := l.encodeMessage(batch, !identified)
+		if err := stream.SendMsg(&msg); err != nil {
+			l.stats.Counter(metrics.AccessLogEntryDropped).Inc(int64(len(batch)))
+			batch = batch[:0]
+			if err == io.EOF {
+				// the stream is closed by the service, the status is got by RecvMsg
+				var resp []byte
+				if rerr := stream.RecvMsg(&resp); rerr != nil {
+					err = rerr
+				}
+			}
+			return err
+		}
+		l.stats.Counter(metrics.AccessLogEntrySent).Inc(int64(len(batch)))
+		batch = batch[:0]
+		identified = true
+		sent = true
+		return nil
+	}
+
+	for {
+		select {
+		case <-l.stop:
+			// flush the queued entries
+			for drained := false; !drained; {
+				select {
+				case entry := <-l.queue:
+					batch = append(batch, entry)
+				default:
+					drained = true
+				}
+				if len(batch) == l.config.BatchSize || (drained && len(batch) > 0) {
+					if err := send(); err != nil {
+						break
+					}
+				}
+			}
+			stream.CloseSend()
+			// wait for the service to receive the entries
+			timer := time.AfterFunc(l.config.FlushInterval.Duration, cancel)
+			var resp []byte
+			stream.RecvMsg(&resp)
+			timer.Stop()
+			return sent, errStopped
+		case entry := <-l.queue:
+			batch = append(batch, entry)
+			if len(batch) < l.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := send(); err != nil {
+			return sent, err
+		}
+	}
+}
@@ -0,0 +1,382 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package als
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc"
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+// message is a decoded protobuf message, for test only
+type message map[int][]interface{}
+
+func decode(t *testing.T, data []byte) message {
+	m := message{}
+	for len(data) > 0 {
+		key, n := proto.DecodeVarint(data)
+		if n == 0 {
+			t.Fatalf("decode key error")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			v, n := proto.DecodeVarint(data)
+			m[field] = append(m[field], v)
+			data = data[n:]
+		case 1:
+			m[field] = append(m[field], binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case 2:
+			l, n := proto.DecodeVarint(data)
+			m[field] = append(m[field], data[n:n+int(l)])
+			data = data[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return m
+}
+
+func (m message) message(t *testing.T, field int) message {
+	if len(m[field]) == 0 {
+		t.Fatalf("field %d not found", field)
+	}
+	return decode(t, m[field][0].([]byte))
+}
+
+func (m message) string(field int) string {
+	if len(m[field]) == 0 {
+		return ""
+	}
+	return string(m[field][0].([]byte))
+}
+
+func (m message) varint(field int) uint64 {
+	if len(m[field]) == 0 {
+		return 0
+	}
+	return m[field][0].(uint64)
+}
+
+// rawCodec is the codec of the service stub
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte{}, data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "proto"
+}
+
+// startService starts an in-process access log service stub on the listener
+func startService(ln net.Listener, ch chan []byte) func() {
+	server := grpc.NewServer(grpc.CustomCodec(rawCodec{}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "envoy.service.accesslog.v2.AccessLogService",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "StreamAccessLogs",
+			ClientStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				for {
+					var msg []byte
+					if err := stream.RecvMsg(&msg); err != nil {
+						if err == io.EOF {
+							resp := []byte{}
+							return stream.SendMsg(&resp)
+						}
+						return err
+					}
+					ch <- msg
+				}
+			},
+		}},
+	}, struct{}{})
+	go server.Serve(ln)
+	return server.Stop
+}
+
+func newTestRequestInfo() types.RequestInfo {
+	info := network.NewRequestInfo()
+	info.SetResponseCode(200)
+	info.SetBytesSent(10)
+	info.SetBytesReceived(20)
+	info.SetResponseFlag(types.NoHealthyUpstream)
+	info.SetRequestReceivedDuration(time.Now().Add(1500 * time.Millisecond))
+	info.SetDownstreamRemoteAddress(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 34567})
+	return info
+}
+
+func TestStreamAccessLogs(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan []byte, 10)
+	stop := startService(ln, ch)
+	defer stop()
+
+	l, err := newAccessLog(&v2.GRPCAccessLog{
+		Address:                  ln.Addr().String(),
+		LogName:                  "test_als",
+		AdditionalRequestHeaders: []string{"X-Custom", "x-missing"},
+		FlushInterval:            v2.DurationConfig{Duration: 50 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.run()
+	defer l.Close()
+
+	headers := protocol.CommonHeader{
+		":method":      "POST",
+		":path":        "/echo",
+		":authority":   "mosn.io",
+		"x-request-id": "abc",
+		"x-custom":     "1",
+	}
+	l.Log(nil, headers, nil, newTestRequestInfo())
+
+	var msg message
+	select {
+	case b := <-ch:
+		msg = decode(t, b)
+	case <-time.After(5 * time.Second):
+		t.Fatal("service received nothing")
+	}
+	if msg.message(t, fieldMessageIdentifier).string(fieldIdentifierLogName) != "test_als" {
+		t.Errorf("the identifier is not sent")
+	}
+	entries := msg.message(t, fieldMessageHTTPLogs)
+	if len(entries[fieldEntriesLogEntry]) != 1 {
+		t.Fatalf("expected 1 entry, but got %d", len(entries[fieldEntriesLogEntry]))
+	}
+	entry := entries.message(t, fieldEntriesLogEntry)
+
+	common := entry.message(t, fieldHTTPCommon)
+	socket := common.message(t, fieldCommonDownstreamRemoteAddress).message(t, fieldAddressSocket)
+	if socket.string(fieldSocketAddressAddress) != "10.0.0.1" || socket.varint(fieldSocketAddressPort) != 34567 {
+		t.Errorf("unexpected downstream remote address: %v", socket)
+	}
+	rx := common.message(t, fieldCommonTimeToLastRxByte)
+	if rx.varint(fieldSeconds) != 1 || rx.varint(fieldNanos) == 0 {
+		t.Errorf("unexpected time to last rx byte: %v", rx)
+	}
+	if flags := common.message(t, fieldCommonResponseFlags); flags.varint(2) != 1 || flags.varint(3) != 0 {
+		t.Errorf("unexpected response flags: %v", flags)
+	}
+
+	request := entry.message(t, fieldHTTPRequest)
+	if request.varint(fieldRequestMethod) != 3 || request.string(fieldRequestPath) != "/echo" ||
+		request.string(fieldRequestAuthority) != "mosn.io" || request.string(fieldRequestID) != "abc" ||
+		request.varint(fieldRequestBodyBytes) != 20 {
+		t.Errorf("unexpected request properties: %v", request)
+	}
+	if len(request[fieldRequestHeaders]) != 1 {
+		t.Fatalf("unexpected request headers: %v", request[fieldRequestHeaders])
+	}
+	header := request.message(t, fieldRequestHeaders)
+	if header.string(fieldMapKey) != "x-custom" || header.string(fieldMapValue) != "1" {
+		t.Errorf("unexpected request header: %v", header)
+	}
+
+	response := entry.message(t, fieldHTTPResponse)
+	if response.message(t, fieldResponseCode).varint(fieldWrapper) != 200 || response.varint(fieldResponseBodyBytes) != 10 {
+		t.Errorf("unexpected response properties: %v", response)
+	}
+
+	// the identifier is only sent in the first message
+	l.Log(nil, headers, nil, newTestRequestInfo())
+	select {
+	case b := <-ch:
+		if _, ok := decode(t, b)[fieldMessageIdentifier]; ok {
+			t.Errorf("the identifier is sent again")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service received nothing")
+	}
+}
+
+func TestStreamAccessLogsReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	l, err := newAccessLog(&v2.GRPCAccessLog{
+		Address:       addr,
+		LogName:       "test_als_reconnect",
+		LogType:       LogTypeTCP,
+		FlushInterval: v2.DurationConfig{Duration: 50 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.run()
+	defer l.Close()
+	l.Log(nil, nil, nil, newTestRequestInfo())
+
+	// the service is started after the access log, the entry is sent after reconnected
+	time.Sleep(100 * time.Millisecond)
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("listen %s again failed: %v", addr, err)
+	}
+	ch := make(chan []byte, 10)
+	stop := startService(ln, ch)
+	defer stop()
+
+	select {
+	case b := <-ch:
+		entry := decode(t, b).message(t, fieldMessageTCPLogs).message(t, fieldEntriesLogEntry)
+		connection := entry.message(t, fieldTCPConnection)
+		if connection.varint(fieldConnectionReceivedBytes) != 20 || connection.varint(fieldConnectionSentBytes) != 10 {
+			t.Errorf("unexpected connection properties: %v", connection)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("service received nothing")
+	}
+	if l.stats.Counter(metrics.AccessLogStreamError).Count() == 0 {
+		t.Errorf("stream errors are not counted")
+	}
+}
+
+func TestAccessLogDropped(t *testing.T) {
+	l, err := newAccessLog(&v2.GRPCAccessLog{
+		Address:   "127.0.0.1:1",
+		LogName:   "test_als_dropped",
+		QueueSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.conn.Close()
+	// the stats is shared by the access logs with the same name
+	base := l.stats.Counter(metrics.AccessLogEntryDropped).Count()
+	for i := 0; i < 3; i++ {
+		l.Log(nil, nil, nil, newTestRequestInfo())
+	}
+	if dropped := l.stats.Counter(metrics.AccessLogEntryDropped).Count() - base; dropped != 2 {
+		t.Errorf("expected 2 entries dropped, but got %d", dropped)
+	}
+}
+
+func TestCloseFlush(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan []byte, 10)
+	stop := startService(ln, ch)
+	defer stop()
+
+	l, err := newAccessLog(&v2.GRPCAccessLog{
+		Address:       ln.Addr().String(),
+		LogName:       "test_als_close",
+		LogType:       LogTypeTCP,
+		FlushInterval: v2.DurationConfig{Duration: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.run()
+	for i := 0; i < 3; i++ {
+		l.Log(nil, nil, nil, newTestRequestInfo())
+	}
+	// the entries are not flushed until closed
+	l.Close()
+	l.Close()
+
+	received := 0
+	for done := false; !done; {
+		select {
+		case b := <-ch:
+			received += len(decode(t, b).message(t, fieldMessageTCPLogs)[fieldEntriesLogEntry])
+		default:
+			done = true
+		}
+	}
+	if received != 3 {
+		t.Errorf("expected 3 entries flushed, but got %d", received)
+	}
+}
+
+func TestCloseDropped(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	l, err := newAccessLog(&v2.GRPCAccessLog{
+		Address:       addr,
+		LogName:       "test_als_close_dropped",
+		LogType:       LogTypeTCP,
+		FlushInterval: v2.DurationConfig{Duration: 100 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := l.stats.Counter(metrics.AccessLogEntryDropped).Count()
+	go l.run()
+	for i := 0; i < 3; i++ {
+		l.Log(nil, nil, nil, newTestRequestInfo())
+	}
+
+	// the service is unavailable, the queued entries are dropped after a flush interval
+	start := time.Now()
+	l.Close()
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("close takes too long: %s", d)
+	}
+	if dropped := l.stats.Counter(metrics.AccessLogEntryDropped).Count() - base; dropped != 3 {
+		t.Errorf("expected 3 entries dropped, but got %d", dropped)
+	}
+}
+
+func TestNewAccessLogConfig(t *testing.T) {
+	for _, cfg := range []*v2.GRPCAccessLog{
+		{LogName: "test"},
+		{Address: "127.0.0.1:1"},
+		{Address: "127.0.0.1:1", LogName: "test", LogType: "udp"},
+	} {
+		if _, err := NewAccessLog(cfg); err == nil {
+			t.Errorf("config %+v is invalid", cfg)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package als
+
+import (
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"mosn.io/mosn/pkg/otlp"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/types"
+)
+
+// Fields of the messages, see envoy/service/accesslog/v2/als.proto, envoy/data/accesslog/v2/accesslog.proto
+// and envoy/api/v2/core/address.proto
+const (
+	// StreamAccessLogsMessage
+	fieldMessageIdentifier = 1
+	fieldMessageHTTPLogs   = 2
+	fieldMessageTCPLogs    = 3
+	// Identifier
+	fieldIdentifierNode    = 1
+	fieldIdentifierLogName = 2
+	// Node
+	fieldNodeID      = 1
+	fieldNodeCluster = 2
+	// HTTPAccessLogEntries and TCPAccessLogEntries
+	fieldEntriesLogEntry = 1
+
+	// TCPAccessLogEntry
+	fieldTCPCommon     = 1
+	fieldTCPConnection = 2
+	// ConnectionProperties
+	fieldConnectionReceivedBytes = 1
+	fieldConnectionSentBytes     = 2
+
+	// HTTPAccessLogEntry
+	fieldHTTPCommon          = 1
+	fieldHTTPProtocolVersion = 2
+	fieldHTTPRequest         = 3
+	fieldHTTPResponse        = 4
+	// HTTPRequestProperties
+	fieldRequestMethod       = 1
+	fieldRequestAuthority    = 3
+	fieldRequestPath         = 5
+	fieldRequestUserAgent    = 6
+	fieldRequestReferer      = 7
+	fieldRequestForwardedFor = 8
+	fieldRequestID           = 9
+	fieldRequestBodyBytes    = 12
+	fieldRequestHeaders      = 13
+	// HTTPResponseProperties
+	fieldResponseCode      = 1
+	fieldResponseBodyBytes = 3
+	fieldResponseHeaders   = 4
+
+	// AccessLogCommon
+	fieldCommonDownstreamRemoteAddress = 2
+	fieldCommonDownstreamLocalAddress  = 3
+	fieldCommonStartTime               = 5
+	fieldCommonTimeToLastRxByte        = 6
+	fieldCommonTimeToFirstUpstreamRx   = 9
+	fieldCommonTimeToLastDownstreamTx  = 12
+	fieldCommonUpstreamRemoteAddress   = 13
+	fieldCommonUpstreamLocalAddress    = 14
+	fieldCommonUpstreamCluster         = 15
+	fieldCommonResponseFlags           = 16
+	// Address and SocketAddress
+	fieldAddressSocket        = 1
+	fieldSocketAddressAddress = 2
+	fieldSocketAddressPort    = 3
+	// Timestamp and Duration
+	fieldSeconds = 1
+	fieldNanos   = 2
+	// map entry and wrapper value
+	fieldMapKey   = 1
+	fieldMapValue = 2
+	fieldWrapper  = 1
+)
+
+// HTTPAccessLogEntry.HTTPVersion
+const (
+	httpVersionUnspecified = 0
+	httpVersion11          = 2
+	httpVersion2           = 3
+)
+
+// requestMethods is the values of envoy.api.v2.core.RequestMethod
+var requestMethods = map[string]uint64{
+	"GET":     1,
+	"HEAD":    2,
+	"POST":    3,
+	"PUT":     4,
+	"DELETE":  5,
+	"CONNECT": 6,
+	"OPTIONS": 7,
+	"TRACE":   8,
+	"PATCH":   9,
+}
+
+// responseFlagFields is the fields of ResponseFlags for the mosn response flags,
+// ReqEntityTooLarge has no equivalent
+var responseFlagFields = []struct {
+	flag  types.ResponseFlag
+	field int
+}{
+	{types.NoHealthyUpstream, 2},
+	{types.UpstreamRequestTimeout, 3},
+	{types.UpstreamLocalReset, 4},
+	{types.UpstreamRemoteReset, 5},
+	{types.UpstreamConnectionFailure, 6},
+	{types.UpstreamConnectionTermination, 7},
+	{types.UpstreamOverflow, 8},
+	{types.NoRouteFound, 9},
+	{types.DelayInjected, 10},
+	{types.FaultInjected, 11},
+	{types.RateLimited, 12},
+}
+
+// encodeMessage encodes a StreamAccessLogsMessage, the identifier is sent in the first message of a stream
+func (l *accessLog) encodeMessage(entries [][]byte, identify bool) []byte {
+	e := otlp.NewEncoder()
+	if identify {
+		e.Message(fieldMessageIdentifier, func(id *otlp.Encoder) {
+			info := types.GetGlobalXdsInfo()
+			id.Message(fieldIdentifierNode, func(node *otlp.Encoder) {
+				node.String(fieldNodeID, info.ServiceNode)
+				node.String(fieldNodeCluster, info.ServiceCluster)
+			})
+			id.String(fieldIdentifierLogName, l.config.LogName)
+		})
+	}
+	field := fieldMessageHTTPLogs
+	if l.config.LogType == LogTypeTCP {
+		field = fieldMessageTCPLogs
+	}
+	e.Message(field, func(logs *otlp.Encoder) {
+		for _, entry := range entries {
+			logs.RawBytes(fieldEntriesLogEntry, entry)
+		}
+	})
+	return e.Bytes()
+}
+
+// encodeTCPEntry encodes a TCPAccessLogEntry
+func encodeTCPEntry(info types.RequestInfo) []byte {
+	e := otlp.NewEncoder()
+	e.Message(fieldTCPCommon, func(c *otlp.Encoder) {
+		encodeCommon(c, info)
+	})
+	e.Message(fieldTCPConnection, func(c *otlp.Encoder) {
+		c.Uint64(fieldConnectionReceivedBytes, info.BytesReceived())
+		c.Uint64(fieldConnectionSentBytes, info.BytesSent())
+	})
+	return e.Bytes()
+}
+
+// encodeHTTPEntry encodes a HTTPAccessLogEntry, the headers in the lists are logged
+func encodeHTTPEntry(reqHeaders, respHeaders types.HeaderMap, info types.RequestInfo, logReqHeaders, logRespHeaders []string) []byte {
+	e := otlp.NewEncoder()
+	e.Message(fieldHTTPCommon, func(c *otlp.Encoder) {
+		encodeCommon(c, info)
+	})
+	switch info.Protocol() {
+	case protocol.HTTP1:
+		e.Uint64(fieldHTTPProtocolVersion, httpVersion11)
+	case protocol.HTTP2:
+		e.Uint64(fieldHTTPProtocolVersion, httpVersion2)
+	default:
+		e.Uint64(fieldHTTPProtocolVersion, httpVersionUnspecified)
+	}
+
+	e.Message(fieldHTTPRequest, func(r *otlp.Encoder) {
+		if reqHeaders != nil {
+			method, authority, path := requestLine(reqHeaders)
+			r.Uint64(fieldRequestMethod, requestMethods[method])
+			r.String(fieldRequestAuthority, authority)
+			r.String(fieldRequestPath, path)
+			r.String(fieldRequestUserAgent, getHeader(reqHeaders, "user-agent"))
+			r.String(fieldRequestReferer, getHeader(reqHeaders, "referer"))
+			r.String(fieldRequestForwardedFor, getHeader(reqHeaders, "x-forwarded-for"))
+			r.String(fieldRequestID, getHeader(reqHeaders, "x-request-id"))
+			encodeHeaders(r, fieldRequestHeaders, reqHeaders, logReqHeaders)
+		}
+		r.Uint64(fieldRequestBodyBytes, info.BytesReceived())
+	})
+	e.Message(fieldHTTPResponse, func(r *otlp.Encoder) {
+		if code := info.ResponseCode(); code > 0 {
+			r.Message(fieldResponseCode, func(v *otlp.Encoder) {
+				v.Uint64(fieldWrapper, uint64(code))
+			})
+		}
+		r.Uint64(fieldResponseBodyBytes, info.BytesSent())
+		if respHeaders != nil {
+			encodeHeaders(r, fieldResponseHeaders, respHeaders, logRespHeaders)
+		}
+	})
+	return e.Bytes()
+}
+
+func encodeCommon(e *otlp.Encoder, info types.RequestInfo) {
+	encodeAddress(e, fieldCommonDownstreamRemoteAddress, info.DownstreamRemoteAddress())
+	encodeAddress(e, fieldCommonDownstreamLocalAddress, info.DownstreamLocalAddress())
+	if start := info.StartTime(); !start.IsZero() {
+		e.Message(fieldCommonStartTime, func(t *otlp.Encoder) {
+			t.Int64(fieldSeconds, start.Unix())
+			t.Int64(fieldNanos, int64(start.Nanosecond()))
+		})
+	}
+	encodeDuration(e, fieldCommonTimeToLastRxByte, info.RequestReceivedDuration())
+	encodeDuration(e, fieldCommonTimeToFirstUpstreamRx, info.ResponseReceivedDuration())
+	encodeDuration(e, fieldCommonTimeToLastDownstreamTx, info.RequestFinishedDuration())
+	if host := info.UpstreamHost(); host != nil {
+		encodeSocketAddress(e, fieldCommonUpstreamRemoteAddress, host.AddressString())
+		if cluster := host.ClusterInfo(); cluster != nil {
+			e.String(fieldCommonUpstreamCluster, cluster.Name())
+		}
+	}
+	encodeSocketAddress(e, fieldCommonUpstreamLocalAddress, info.UpstreamLocalAddress())
+
+	flagged := false
+	for _, f := range responseFlagFields {
+		if info.GetResponseFlag(f.flag) {
+			flagged = true
+			break
+		}
+	}
+	if flagged {
+		e.Message(fieldCommonResponseFlags, func(flags *otlp.Encoder) {
+			for _, f := range responseFlagFields {
+				flags.Bool(f.field, info.GetResponseFlag(f.flag))
+			}
+		})
+	}
+}
+
+func encodeDuration(e *otlp.Encoder, field int, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	e.Message(field, func(v *otlp.Encoder) {
+		v.Int64(fieldSeconds, int64(d/time.Second))
+		v.Int64(fieldNanos, int64(d%time.Second))
+	})
+}
+
+func encodeAddress(e *otlp.Encoder, field int, addr net.Addr) {
+	if addr == nil {
+		return
+	}
+	encodeSocketAddress(e, field, addr.String())
+}
+
+// encodeSocketAddress encodes an Address with a socket address in host:port
+func encodeSocketAddress(e *otlp.Encoder, field int, addr string) {
+	if addr == "" {
+		return
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	portValue, err := strconv.ParseUint(port, 10, 32)
+	if err != nil {
+		return
+	}
+	e.Message(field, func(a *otlp.Encoder) {
+		a.Message(fieldAddressSocket, func(s *otlp.Encoder) {
+			s.String(fieldSocketAddressAddress, host)
+			s.Uint64(fieldSocketAddressPort, portValue)
+		})
+	})
+}
+
+// encodeHeaders encodes a map<string, string> of the headers in the keys, the missing headers are omitted
+func encodeHeaders(e *otlp.Encoder, field int, headers types.HeaderMap, keys []string) {
+	for _, key := range keys {
+		value := getHeader(headers, key)
+		if value == "" {
+			continue
+		}
+		e.Message(field, func(kv *otlp.Encoder) {
+			kv.String(fieldMapKey, strings.ToLower(key))
+			kv.String(fieldMapValue, value)
+		})
+	}
+}
+
+// requestLine returns the method, the authority and the path of the http request
+func requestLine(headers types.HeaderMap) (method, authority, path string) {
+	if req, ok := headers.(http.RequestHeader); ok && req.RequestHeader != nil {
+		return string(req.Method()), string(req.Host()), string(req.RequestURI())
+	}
+	// the http2 headers provide the pseudo headers
+	return getHeader(headers, ":method"), getHeader(headers, ":authority"), getHeader(headers, ":path")
+}
+
+// getHeader gets the header case-insensitively, the http headers are case-insensitive but the rpc headers are not
+func getHeader(headers types.HeaderMap, key string) string {
+	for _, k := range []string{key, strings.ToLower(key), textproto.CanonicalMIMEHeaderKey(key)} {
+		if v, ok := headers.Get(k); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/types"
+)
+
+// DebugTrace records the routing decisions of a request in debug mode
+type DebugTrace struct {
+	mux     sync.Mutex
+	records []string
+}
+
+// WithDebugTrace turns on the debug mode of the request context,
+// the proxy logs of the context are emitted regardless of the log level.
+func WithDebugTrace(ctx context.Context) context.Context {
+	return mosnctx.WithValue(ctx, types.ContextKeyDebugTrace, &DebugTrace{})
+}
+
+// GetDebugTrace returns the debug trace of the context, nil if the request is not in debug mode
+func GetDebugTrace(ctx context.Context) *DebugTrace {
+	if ctx == nil {
+		return nil
+	}
+	if trace, ok := mosnctx.Get(ctx, types.ContextKeyDebugTrace).(*DebugTrace); ok {
+		return trace
+	}
+	return nil
+}
+
+// DebugEnabled returns true if the proxy debug logs of the context should be emitted,
+// either the proxy log level is debug or the request is in debug mode
+func DebugEnabled(ctx context.Context) bool {
+	return Proxy.GetLogLevel() >= DEBUG || GetDebugTrace(ctx) != nil
+}
+
+// Record appends a routing decision
+func (t *DebugTrace) Record(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.mux.Lock()
+	t.records = append(t.records, fmt.Sprintf(format, args...))
+	t.mux.Unlock()
+}
+
+// String returns the routing decisions in order, separated by "; "
+func (t *DebugTrace) String() string {
+	if t == nil {
+		return ""
+	}
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return strings.Join(t.records, "; ")
+}
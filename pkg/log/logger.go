@@ -35,8 +35,6 @@ import (
 )
 
 var (
-	// localOffset is offset in seconds east of UTC
-	_, localOffset = time.Now().Zone()
 	// error
 	ErrReopenUnsupported = errors.New("reopen unsupported")
 
@@ -70,7 +68,9 @@ type Logger struct {
 	// the default value is false
 	disable bool
 	// implementation elements
-	create          time.Time
+	create time.Time
+	// rotated is the time based rotated file, which is milled after the writer is reopened
+	rotated         string
 	reopenChan      chan struct{}
 	closeChan       chan struct{}
 	writeBufferChan chan types.IoBuffer
@@ -135,6 +135,7 @@ func (l *Logger) start() error {
 				file.Close()
 				l.roller.Filename = l.output
 				l.writer = l.roller.GetLogWriter()
+				l.create = time.Time{}
 			} else {
 				// time.Now() faster than reported timestamps from filesystem (https://github.com/golang/go/issues/33510)
 				// init logger
@@ -227,6 +228,11 @@ func (l *Logger) reopen() error {
 		if err != nil {
 			return err
 		}
+		// the rotated file is closed, no more logs will be written into it
+		if l.rotated != "" {
+			go l.roller.millRotated(l.output, l.rotated)
+			l.rotated = ""
+		}
 		return l.start()
 	}
 	return ErrReopenUnsupported
@@ -315,15 +321,13 @@ func (l *Logger) Fatalln(args ...interface{}) {
 
 func (l *Logger) Write(p []byte) (n int, err error) {
 	// default roller by daily
-	if !l.create.IsZero() {
+	if !l.create.IsZero() && l.roller.MaxTime > 0 {
 		now := time.Now()
-		if (l.create.Unix()+int64(localOffset))/(l.roller.MaxTime) !=
-			(now.Unix()+int64(localOffset))/(l.roller.MaxTime) {
+		if rotatePeriod(l.create, l.roller.MaxTime) != rotatePeriod(now, l.roller.MaxTime) {
+			rotated := l.roller.backupName(l.output, l.create)
 			// ignore the rename error, in case the l.output is deleted
-			if l.roller.MaxTime == defaultRotateTime {
-				os.Rename(l.output, l.output+"."+l.create.Format("2006-01-02"))
-			} else {
-				os.Rename(l.output, l.output+"."+l.create.Format("2006-01-02_15"))
+			if err := os.Rename(l.output, rotated); err == nil {
+				l.rotated = rotated
 			}
 			l.create = now
 			//TODO: recover?
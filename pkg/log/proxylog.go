@@ -29,6 +29,7 @@ import (
 
 // proxyLogger is a default implementation of ProxyLogger
 // we use ProxyLogger to record proxy events.
+// the logs of a request in debug mode are emitted regardless of the log level, see WithDebugTrace
 type proxyLogger struct {
 	*errorLogger
 }
@@ -51,7 +52,7 @@ func (l *proxyLogger) Infof(ctx context.Context, format string, args ...interfac
 	if l.disable {
 		return
 	}
-	if l.level >= INFO {
+	if l.level >= INFO || GetDebugTrace(ctx) != nil {
 		s := l.formatter(ctx, InfoPre, format)
 		l.Printf(s, args...)
 	}
@@ -61,7 +62,7 @@ func (l *proxyLogger) Debugf(ctx context.Context, format string, args ...interfa
 	if l.disable {
 		return
 	}
-	if l.level >= DEBUG {
+	if l.level >= DEBUG || GetDebugTrace(ctx) != nil {
 		s := l.formatter(ctx, DebugPre, format)
 		l.Printf(s, args...)
 	}
@@ -71,7 +72,7 @@ func (l *proxyLogger) Warnf(ctx context.Context, format string, args ...interfac
 	if l.disable {
 		return
 	}
-	if l.level >= WARN {
+	if l.level >= WARN || GetDebugTrace(ctx) != nil {
 		s := l.formatter(ctx, WarnPre, format)
 		l.Printf(s, args...)
 	}
@@ -81,7 +82,7 @@ func (l *proxyLogger) Errorf(ctx context.Context, format string, args ...interfa
 	if l.disable {
 		return
 	}
-	if l.level >= ERROR {
+	if l.level >= ERROR || GetDebugTrace(ctx) != nil {
 		s := logTime() + " " + ErrorPre + " [" + defaultErrorCode + "] " + traceInfo(ctx) + " " + format
 		l.Printf(s, args...)
 	}
@@ -91,7 +92,7 @@ func (l *proxyLogger) Alertf(ctx context.Context, errkey types.ErrorKey, format
 	if l.disable {
 		return
 	}
-	if l.level >= ERROR {
+	if l.level >= ERROR || GetDebugTrace(ctx) != nil {
 		s := logTime() + " " + ErrorPre + " [" + string(errkey) + "] " + traceInfo(ctx) + " " + format
 		l.Printf(s, args...)
 	}
@@ -101,7 +102,7 @@ func (l *proxyLogger) Fatalf(ctx context.Context, format string, args ...interfa
 	if l.disable {
 		return
 	}
-	if l.level >= FATAL {
+	if l.level >= FATAL || GetDebugTrace(ctx) != nil {
 		s := l.formatter(ctx, FatalPre, format)
 		l.Logger.Fatalf(s, args...)
 	}
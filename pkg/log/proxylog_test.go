@@ -95,3 +95,37 @@ func BenchmarkProxyLogParallel(b *testing.B) {
 		}
 	})
 }
+
+func TestProxyLogDebugMode(t *testing.T) {
+	logName := "/tmp/mosn/proxy_log_debug.log"
+	os.Remove(logName)
+	lg, err := CreateDefaultProxyLogger(logName, WARN)
+	if err != nil {
+		t.Fatal("create logger failed")
+	}
+
+	debugCtx := WithDebugTrace(context.Background())
+	lg.Debugf(context.Background(), "[unittest] not in debug mode")
+	lg.Debugf(debugCtx, "[unittest] in debug mode")
+
+	trace := GetDebugTrace(debugCtx)
+	trace.Record("route matched: %s", "test")
+	trace.Record("host: %s", "127.0.0.1:8080")
+	if trace.String() != "route matched: test; host: 127.0.0.1:8080" {
+		t.Errorf("unexpected debug trace: %s", trace.String())
+	}
+	if GetDebugTrace(context.Background()) != nil {
+		t.Error("context without debug mode should not have debug trace")
+	}
+	// record on a nil trace is ignored
+	GetDebugTrace(context.Background()).Record("ignored")
+
+	time.Sleep(time.Second) // wait buffer flush
+	lines, err := readLines(logName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || !strings.Contains(lines[0], "in debug mode") || strings.Contains(lines[0], "not in debug mode") {
+		t.Errorf("unexpected logs: %v", lines)
+	}
+}
@@ -18,11 +18,18 @@
 package log
 
 import (
+	"compress/gzip"
 	"errors"
 	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 )
@@ -36,6 +43,9 @@ var (
 	lumberjacks = make(map[string]*lumberjack.Logger)
 
 	errInvalidRollerParameter = errors.New("invalid roller parameter")
+
+	// millMutex serializes the compression and the cleanup of the time based rotated files
+	millMutex sync.Mutex
 )
 
 const (
@@ -53,6 +63,15 @@ const (
 	directiveRotateAge      = "age"
 	directiveRotateKeep     = "keep"
 	directiveRotateCompress = "compress"
+
+	// the time directive can be hours or the aliases
+	rotateHourly = "hourly"
+	rotateDaily  = "daily"
+
+	// the suffixes of the time based rotated files
+	dailyBackupFormat  = "2006-01-02"
+	hourlyBackupFormat = "2006-01-02_15"
+	compressSuffix     = ".gz"
 )
 
 // roller implements a type that provides a rolling logger.
@@ -117,9 +136,12 @@ func DefaultRoller() *Roller {
 }
 
 // ParseRoller parses roller contents out of c.
+// The time directive makes a time based roller, the rotated files are kept
+// unless the keep or the age directive is set.
 func ParseRoller(what string) (*Roller, error) {
 	var err error
 	var value int
+	var retention bool
 	roller := DefaultRoller()
 	for _, args := range strings.Split(what, " ") {
 		v := strings.Split(args, "=")
@@ -129,9 +151,19 @@ func ParseRoller(what string) (*Roller, error) {
 		}
 		switch v[0] {
 		case directiveRotateTime:
-			value, err = strconv.Atoi(v[1])
-			if err != nil {
-				break
+			switch v[1] {
+			case rotateHourly:
+				value = 1
+			case rotateDaily:
+				value = 24
+			default:
+				value, err = strconv.Atoi(v[1])
+				if err != nil {
+					break
+				}
+				if value <= 0 {
+					err = errInvalidRollerParameter
+				}
 			}
 			roller.MaxTime = int64(value) * 60 * 60
 		case directiveRotateSize:
@@ -146,12 +178,14 @@ func ParseRoller(what string) (*Roller, error) {
 				break
 			}
 			roller.MaxAge = value
+			retention = true
 		case directiveRotateKeep:
 			value, err = strconv.Atoi(v[1])
 			if err != nil {
 				break
 			}
 			roller.MaxBackups = value
+			retention = true
 		case directiveRotateCompress:
 			if v[1] == "on" {
 				roller.Compress = true
@@ -163,18 +197,149 @@ func ParseRoller(what string) (*Roller, error) {
 		default:
 			err = errInvalidRollerParameter
 		}
+		if err != nil {
+			break
+		}
 	}
 	if err != nil {
 		return nil, err
 	}
+	if roller.MaxTime > 0 && !retention {
+		roller.MaxAge = 0
+		roller.MaxBackups = 0
+	}
 
 	return roller, nil
 }
 
 // IsLogRollerSubdirective is true if the subdirective is for the log roller.
 func IsLogRollerSubdirective(subdir string) bool {
-	return subdir == directiveRotateSize ||
+	return subdir == directiveRotateTime ||
+		subdir == directiveRotateSize ||
 		subdir == directiveRotateAge ||
 		subdir == directiveRotateKeep ||
 		subdir == directiveRotateCompress
 }
+
+// rotatePeriod returns the index of the time based rotation period of t,
+// the periods are aligned to the local time, so a daily period starts at the local midnight
+func rotatePeriod(t time.Time, maxTime int64) int64 {
+	_, offset := t.Zone()
+	return (t.Unix() + int64(offset)) / maxTime
+}
+
+// backupFormat returns the time format of the rotated file suffix
+func (l *Roller) backupFormat() string {
+	if l.MaxTime == defaultRotateTime {
+		return dailyBackupFormat
+	}
+	return hourlyBackupFormat
+}
+
+// backupName returns the rotated file name of the output created at the time
+func (l *Roller) backupName(output string, create time.Time) string {
+	return output + "." + create.Format(l.backupFormat())
+}
+
+// millRotated compresses the time based rotated file and removes the expired rotated files.
+// It is called after the rotated file is closed, and runs asynchronously so the logging is not blocked
+func (l Roller) millRotated(output, rotated string) {
+	defer func() {
+		if r := recover(); r != nil {
+			debug.PrintStack()
+		}
+	}()
+	millMutex.Lock()
+	defer millMutex.Unlock()
+
+	if l.Compress {
+		if err := compressFile(rotated); err != nil {
+			DefaultLogger.Errorf("[log] [roller] compress %s failed: %v", rotated, err)
+		}
+	}
+	if l.MaxBackups <= 0 && l.MaxAge <= 0 {
+		return
+	}
+
+	backups := l.backups(output)
+	cutoff := time.Now().Add(-time.Duration(l.MaxAge) * 24 * time.Hour)
+	for i, b := range backups {
+		if (l.MaxBackups > 0 && i >= l.MaxBackups) || (l.MaxAge > 0 && b.modTime.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				DefaultLogger.Errorf("[log] [roller] remove %s failed: %v", b.path, err)
+			}
+		}
+	}
+}
+
+type backupFile struct {
+	path    string
+	create  time.Time // parsed from the file name, used for ordering
+	modTime time.Time // the last write, used for the max age
+}
+
+// backups returns the time based rotated files of the output, newest first
+func (l *Roller) backups(output string) []backupFile {
+	files, err := ioutil.ReadDir(filepath.Dir(output))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(output) + "."
+	var backups []backupFile
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		suffix := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressSuffix)
+		for _, format := range []string{dailyBackupFormat, hourlyBackupFormat} {
+			if create, err := time.ParseInLocation(format, suffix, time.Local); err == nil {
+				backups = append(backups, backupFile{
+					path:    filepath.Join(filepath.Dir(output), name),
+					create:  create,
+					modTime: f.ModTime(),
+				})
+				break
+			}
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].create.After(backups[j].create)
+	})
+	return backups
+}
+
+// compressFile compresses the file into file.gz and removes the file
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(name+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, stat.Mode())
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(name + compressSuffix)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + compressSuffix)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(name + compressSuffix)
+		return err
+	}
+	return os.Remove(name)
+}
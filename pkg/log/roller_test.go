@@ -18,7 +18,14 @@
 package log
 
 import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/buffer"
 )
 
 func TestParseRoller(t *testing.T) {
@@ -107,3 +114,158 @@ func TestInitDefaultRoller(t *testing.T) {
 	}
 
 }
+
+func TestParseTimeRoller(t *testing.T) {
+	testCases := []struct {
+		args       string
+		maxTime    int64
+		maxBackups int
+		maxAge     int
+		compress   bool
+	}{
+		{"time=hourly", 60 * 60, 0, 0, false},
+		{"time=daily compress=on", 24 * 60 * 60, 0, 0, true},
+		{"time=6 keep=3", 6 * 60 * 60, 3, defaultRotateAge, false},
+		{"time=24 age=30", 24 * 60 * 60, defaultRotateKeep, 30, false},
+	}
+	for _, tc := range testCases {
+		roller, err := ParseRoller(tc.args)
+		if err != nil {
+			t.Errorf("%s: parse roller failed: %v", tc.args, err)
+			continue
+		}
+		if roller.MaxTime != tc.maxTime || roller.MaxBackups != tc.maxBackups ||
+			roller.MaxAge != tc.maxAge || roller.Compress != tc.compress {
+			t.Errorf("%s: unexpected roller %+v", tc.args, roller)
+		}
+	}
+
+	for _, args := range []string{"time=0", "time=weekly", "size=abc keep=10"} {
+		if _, err := ParseRoller(args); err == nil {
+			t.Errorf("%s: parse roller should be failed", args)
+		}
+	}
+}
+
+func TestRotatePeriod(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	t1 := time.Date(2018, time.December, 25, 0, 0, 1, 0, loc)
+	t2 := time.Date(2018, time.December, 25, 23, 59, 59, 0, loc)
+	t3 := time.Date(2018, time.December, 26, 0, 0, 1, 0, loc)
+	if rotatePeriod(t1, defaultRotateTime) != rotatePeriod(t2, defaultRotateTime) {
+		t.Errorf("the same local day should be in the same period")
+	}
+	if rotatePeriod(t2, defaultRotateTime)+1 != rotatePeriod(t3, defaultRotateTime) {
+		t.Errorf("the next local day should be in the next period")
+	}
+	if rotatePeriod(t2, 60*60)+1 != rotatePeriod(t3, 60*60) {
+		t.Errorf("the next local hour should be in the next period")
+	}
+}
+
+func TestMillRotated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn_roller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "access.log")
+	now := time.Now()
+	for i := 1; i <= 4; i++ {
+		name := output + "." + now.AddDate(0, 0, -i).Format(dailyBackupFormat)
+		if i > 1 {
+			name += compressSuffix
+		}
+		if err := ioutil.WriteFile(name, []byte("log"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not rotated files
+	ioutil.WriteFile(output, []byte("log"), 0644)
+	ioutil.WriteFile(output+".bak", []byte("log"), 0644)
+
+	rotated := output + "." + now.AddDate(0, 0, -1).Format(dailyBackupFormat)
+	roller := Roller{MaxTime: defaultRotateTime, Compress: true, MaxBackups: 2}
+	roller.millRotated(output, rotated)
+
+	if _, err := os.Stat(rotated); !os.IsNotExist(err) {
+		t.Errorf("rotated file should be removed after compressed")
+	}
+	f, err := os.Open(rotated + compressSuffix)
+	if err != nil {
+		t.Fatalf("open compressed file failed: %v", err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadAll(gz); err != nil || string(b) != "log" {
+		t.Errorf("unexpected compressed data: %s, %v", b, err)
+	}
+	f.Close()
+
+	files, _ := ioutil.ReadDir(dir)
+	names := map[string]bool{}
+	for _, f := range files {
+		names[f.Name()] = true
+	}
+	expected := []string{
+		"access.log",
+		"access.log.bak",
+		"access.log." + now.AddDate(0, 0, -1).Format(dailyBackupFormat) + compressSuffix,
+		"access.log." + now.AddDate(0, 0, -2).Format(dailyBackupFormat) + compressSuffix,
+	}
+	if len(names) != len(expected) {
+		t.Errorf("unexpected files: %v", names)
+	}
+	for _, name := range expected {
+		if !names[name] {
+			t.Errorf("%s is not kept: %v", name, names)
+		}
+	}
+
+	// remove the files older than the max age
+	old := now.AddDate(0, 0, -3)
+	os.Chtimes(output+"."+now.AddDate(0, 0, -2).Format(dailyBackupFormat)+compressSuffix, old, old)
+	roller = Roller{MaxTime: defaultRotateTime, MaxAge: 1}
+	roller.millRotated(output, "")
+	if backups := roller.backups(output); len(backups) != 1 {
+		t.Errorf("expected 1 backup kept, but got %v", backups)
+	}
+}
+
+func TestLogTimeRollerCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn_roller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logName := filepath.Join(dir, "compress.log")
+	// 2s
+	logger, err := GetOrCreateLogger(logName, &Roller{MaxTime: 2, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Print(buffer.NewIoBufferString("1111111"), false)
+	time.Sleep(3 * time.Second)
+	logger.Print(buffer.NewIoBufferString("2222222"), false)
+	time.Sleep(time.Second)
+	logger.Close()
+
+	backups := logger.roller.backups(logName)
+	if len(backups) != 1 || filepath.Ext(backups[0].path) != compressSuffix {
+		t.Fatalf("expected a compressed rotated file, but got %v", backups)
+	}
+	f, err := os.Open(backups[0].path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(gz); string(b) != "11111112222222" {
+		t.Errorf("unexpected rotated data: %s", b)
+	}
+}
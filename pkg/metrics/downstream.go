@@ -54,3 +54,51 @@ func NewListenerStats(listenerName string) types.Metrics {
 	metrics, _ := NewMetrics(DownstreamType, map[string]string{"listener": listenerName})
 	return metrics
 }
+
+// metrics key in udp proxy
+const (
+	UDPSessionTotal       = "udp_session_total"
+	UDPSessionActive      = "udp_session_active"
+	UDPSessionOverflow    = "udp_session_overflow"
+	UDPSessionIdleTimeout = "udp_session_idle_timeout"
+	UDPSessionFailed      = "udp_session_failed"
+	UDPDatagramsReceived  = "udp_datagrams_received"
+	UDPDatagramsSent      = "udp_datagrams_sent"
+	UDPDatagramsDropped   = "udp_datagrams_dropped"
+	UDPBytesReceived      = "udp_bytes_received"
+	UDPBytesSent          = "udp_bytes_sent"
+)
+
+// NewUDPProxyStats returns a stats with namespace prefix udp_proxy
+func NewUDPProxyStats(statPrefix string) types.Metrics {
+	metrics, _ := NewMetrics(DownstreamType, map[string]string{"udp_proxy": statPrefix})
+	return metrics
+}
+
+// metrics key in redis proxy
+const (
+	RedisCommandTotal        = "command_total"
+	RedisCommandSuccess      = "command_success"
+	RedisCommandError        = "command_error"
+	RedisCommandTime         = "command_time"
+	RedisCommandUnsupported  = "command_unsupported"
+	RedisCommandNotAllowed   = "command_not_allowed"
+	RedisCommandTimeout      = "command_timeout"
+	RedisUpstreamMoved       = "upstream_moved"
+	RedisUpstreamAsk         = "upstream_ask"
+	RedisUpstreamFailed      = "upstream_failed"
+	RedisSlotsRefreshSuccess = "slots_refresh_success"
+	RedisSlotsRefreshFailed  = "slots_refresh_failed"
+)
+
+// NewRedisProxyStats returns a stats with namespace prefix redis_proxy
+func NewRedisProxyStats(statPrefix string) types.Metrics {
+	metrics, _ := NewMetrics(DownstreamType, map[string]string{"redis_proxy": statPrefix})
+	return metrics
+}
+
+// NewRedisCommandStats returns a stats of a command in redis proxy
+func NewRedisCommandStats(statPrefix, command string) types.Metrics {
+	metrics, _ := NewMetrics(DownstreamType, map[string]string{"redis_proxy": statPrefix, "command": command})
+	return metrics
+}
@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/mosn/pkg/metrics/shm"
+)
+
+// DefaultHistogramBuckets is the default bucket bounds of histograms.
+// the durations in mosn are recorded in nanoseconds, so the default buckets are
+// 0.5ms, 1ms, 2.5ms, 5ms, 10ms, 25ms, 50ms, 100ms, 250ms, 500ms, 1s, 2.5s, 5s, 10s
+var DefaultHistogramBuckets = []float64{
+	5e5, 1e6, 2.5e6, 5e6, 1e7, 2.5e7, 5e7, 1e8, 2.5e8, 5e8, 1e9, 2.5e9, 5e9, 1e10,
+}
+
+var defaultHistogramConfig = &histogramConfig{}
+
+type histogramConfig struct {
+	// metrics type or type.key -> bucket bounds
+	buckets map[string][]float64
+	// allocates the buckets in shared memory
+	shm bool
+}
+
+// SetHistogramConfig sets the histogram bucket bounds and whether the histograms are stored in the metrics shm zone.
+// the key of buckets is a metrics type, e.g. 'upstream', or a metrics type and a key, e.g. 'upstream.request_duration_time'.
+func SetHistogramConfig(buckets map[string][]float64, useShm bool) error {
+	if err := ValidateHistogramBuckets(buckets); err != nil {
+		return err
+	}
+
+	defaultStore.mutex.Lock()
+	defer defaultStore.mutex.Unlock()
+
+	defaultStore.histogram = &histogramConfig{
+		buckets: buckets,
+		shm:     useShm,
+	}
+	return nil
+}
+
+// ValidateHistogramBuckets checks the bucket bounds are not empty and sorted
+func ValidateHistogramBuckets(buckets map[string][]float64) error {
+	for name, bounds := range buckets {
+		if len(bounds) == 0 {
+			return fmt.Errorf("histogram buckets of %s is empty", name)
+		}
+		if !sort.Float64sAreSorted(bounds) {
+			return fmt.Errorf("histogram buckets of %s is not sorted: %v", name, bounds)
+		}
+	}
+	return nil
+}
+
+// bucketsOf returns the bucket bounds of a histogram, the type.key config takes precedence of the type config
+func (c *histogramConfig) bucketsOf(typ, key string) []float64 {
+	if bounds, ok := c.buckets[typ+"."+key]; ok {
+		return bounds
+	}
+	if bounds, ok := c.buckets[typ]; ok {
+		return bounds
+	}
+	return DefaultHistogramBuckets
+}
+
+func (c *histogramConfig) newHistogramFunc(typ, key, fullName string) func() gometrics.Histogram {
+	bounds := c.bucketsOf(typ, key)
+	if c.shm {
+		return shm.NewShmHistogramFunc(fullName, bounds)
+	}
+	return func() gometrics.Histogram {
+		return shm.NewHistogram(bounds)
+	}
+}
@@ -0,0 +1,279 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package shm
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"unsafe"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/mosn/pkg/types"
+)
+
+// ShmHistogram is a histogram with fixed buckets, each bucket is an int64 slot, so it can be
+// aggregated across instances, and it survives hot upgrade when the slots are allocated in shared memory.
+//
+// bucket i counts the values in (bounds[i-1], bounds[i]], and the last bucket counts the values larger than all bounds.
+type ShmHistogram struct {
+	bounds []float64
+
+	sum *int64
+	// min and max are stored as order-preserving keys, so the zero value means not set.
+	min     *int64
+	max     *int64
+	buckets []*int64
+
+	// entries allocated in shared memory
+	entries []*hashEntry
+}
+
+// NewHistogram returns a fixed buckets histogram in heap memory
+func NewHistogram(bounds []float64) gometrics.Histogram {
+	slots := make([]int64, len(bounds)+4)
+	ptrs := make([]*int64, len(slots))
+	for i := range slots {
+		ptrs[i] = &slots[i]
+	}
+	return newShmHistogram(bounds, ptrs, nil)
+}
+
+// NewShmHistogramFunc returns a fixed buckets histogram creator, the slots are allocated in the metrics zone.
+// the slot names contains the bounds, so the data is kept only if the bounds are not changed when upgrading.
+func NewShmHistogramFunc(name string, bounds []float64) func() gometrics.Histogram {
+	return func() gometrics.Histogram {
+		if defaultZone != nil {
+			names := make([]string, 0, len(bounds)+4)
+			names = append(names, name+".sum", name+".min", name+".max")
+			for _, b := range bounds {
+				names = append(names, name+".le_"+strconv.FormatFloat(b, 'g', -1, 64))
+			}
+			names = append(names, name+".le_inf")
+
+			ptrs := make([]*int64, 0, len(names))
+			entries := make([]*hashEntry, 0, len(names))
+			for _, n := range names {
+				entry, err := defaultZone.alloc(n)
+				if err != nil {
+					for _, e := range entries {
+						defaultZone.free(e)
+					}
+					return gometrics.NilHistogram{}
+				}
+				entries = append(entries, entry)
+				ptrs = append(ptrs, &entry.value)
+			}
+			return newShmHistogram(bounds, ptrs, entries)
+		} else if fallback {
+			return NewHistogram(bounds)
+		}
+		return gometrics.NilHistogram{}
+	}
+}
+
+func newShmHistogram(bounds []float64, slots []*int64, entries []*hashEntry) *ShmHistogram {
+	return &ShmHistogram{
+		bounds:  bounds,
+		sum:     slots[0],
+		min:     slots[1],
+		max:     slots[2],
+		buckets: slots[3:],
+		entries: entries,
+	}
+}
+
+// orderKey maps the int64 value to uint64, keeps the order
+func orderKey(v int64) uint64 {
+	return uint64(v) ^ (1 << 63)
+}
+
+func fromOrderKey(k uint64) int64 {
+	return int64(k ^ (1 << 63))
+}
+
+// storeMax stores the key if it is larger than the stored one
+func storeMax(slot *int64, key uint64) {
+	p := (*uint64)(unsafe.Pointer(slot))
+	for {
+		old := atomic.LoadUint64(p)
+		if key <= old || atomic.CompareAndSwapUint64(p, old, key) {
+			return
+		}
+	}
+}
+
+// Update records a value
+func (h *ShmHistogram) Update(v int64) {
+	i := sort.SearchFloat64s(h.bounds, float64(v))
+	atomic.AddInt64(h.buckets[i], 1)
+	atomic.AddInt64(h.sum, v)
+	// the min key is inverted, so the larger key is the smaller value
+	storeMax(h.min, ^orderKey(v))
+	storeMax(h.max, orderKey(v))
+}
+
+// Clear resets the histogram
+func (h *ShmHistogram) Clear() {
+	for _, b := range h.buckets {
+		atomic.StoreInt64(b, 0)
+	}
+	atomic.StoreInt64(h.sum, 0)
+	atomic.StoreInt64(h.min, 0)
+	atomic.StoreInt64(h.max, 0)
+}
+
+// Snapshot returns a read-only copy of the histogram
+func (h *ShmHistogram) Snapshot() gometrics.Histogram {
+	s := &histogramSnapshot{
+		bounds: h.bounds,
+		counts: make([]int64, len(h.buckets)),
+		sum:    atomic.LoadInt64(h.sum),
+	}
+	for i, b := range h.buckets {
+		s.counts[i] = atomic.LoadInt64(b)
+		s.count += s.counts[i]
+	}
+	if s.count > 0 {
+		s.min = fromOrderKey(^uint64(atomic.LoadInt64(h.min)))
+		s.max = fromOrderKey(uint64(atomic.LoadInt64(h.max)))
+	}
+	return s
+}
+
+// Buckets returns the bucket bounds and counts
+func (h *ShmHistogram) Buckets() ([]float64, []int64) {
+	return h.Snapshot().(*histogramSnapshot).Buckets()
+}
+
+func (h *ShmHistogram) Count() int64                       { return h.Snapshot().Count() }
+func (h *ShmHistogram) Max() int64                         { return h.Snapshot().Max() }
+func (h *ShmHistogram) Mean() float64                      { return h.Snapshot().Mean() }
+func (h *ShmHistogram) Min() int64                         { return h.Snapshot().Min() }
+func (h *ShmHistogram) Percentile(p float64) float64       { return h.Snapshot().Percentile(p) }
+func (h *ShmHistogram) Percentiles(ps []float64) []float64 { return h.Snapshot().Percentiles(ps) }
+func (h *ShmHistogram) Sample() gometrics.Sample           { return gometrics.NilSample{} }
+func (h *ShmHistogram) StdDev() float64                    { return h.Snapshot().StdDev() }
+func (h *ShmHistogram) Sum() int64                         { return atomic.LoadInt64(h.sum) }
+func (h *ShmHistogram) Variance() float64                  { return h.Snapshot().Variance() }
+
+// stoppable
+func (h *ShmHistogram) Stop() {
+	if defaultZone != nil {
+		for _, e := range h.entries {
+			defaultZone.free(e)
+		}
+	}
+}
+
+// histogramSnapshot is a read-only copy of ShmHistogram
+type histogramSnapshot struct {
+	bounds []float64
+	counts []int64
+	count  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+var _ types.BucketHistogram = &histogramSnapshot{}
+
+func (s *histogramSnapshot) Buckets() ([]float64, []int64) { return s.bounds, s.counts }
+func (s *histogramSnapshot) Count() int64                  { return s.count }
+func (s *histogramSnapshot) Max() int64                    { return s.max }
+func (s *histogramSnapshot) Min() int64                    { return s.min }
+func (s *histogramSnapshot) Sum() int64                    { return s.sum }
+func (s *histogramSnapshot) Sample() gometrics.Sample      { return gometrics.NilSample{} }
+func (s *histogramSnapshot) Snapshot() gometrics.Histogram { return s }
+func (s *histogramSnapshot) StdDev() float64               { return math.Sqrt(s.Variance()) }
+
+// Clear panics
+func (*histogramSnapshot) Clear() {
+	panic("Clear called on a histogramSnapshot")
+}
+
+// Update panics
+func (*histogramSnapshot) Update(int64) {
+	panic("Update called on a histogramSnapshot")
+}
+
+func (s *histogramSnapshot) Mean() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return float64(s.sum) / float64(s.count)
+}
+
+// bucketRange returns the value range of bucket i, limited by min and max
+func (s *histogramSnapshot) bucketRange(i int) (lower, upper float64) {
+	lower, upper = float64(s.min), float64(s.max)
+	if i > 0 && s.bounds[i-1] > lower {
+		lower = s.bounds[i-1]
+	}
+	if i < len(s.bounds) && s.bounds[i] < upper {
+		upper = s.bounds[i]
+	}
+	return
+}
+
+// Percentile is estimated by linear interpolation in the bucket
+func (s *histogramSnapshot) Percentile(p float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	rank := p * float64(s.count)
+	var cumulative int64
+	for i, c := range s.counts {
+		if c == 0 {
+			continue
+		}
+		if float64(cumulative+c) >= rank {
+			lower, upper := s.bucketRange(i)
+			return lower + (upper-lower)*(rank-float64(cumulative))/float64(c)
+		}
+		cumulative += c
+	}
+	return float64(s.max)
+}
+
+func (s *histogramSnapshot) Percentiles(ps []float64) []float64 {
+	values := make([]float64, len(ps))
+	for i, p := range ps {
+		values[i] = s.Percentile(p)
+	}
+	return values
+}
+
+// Variance is estimated by the middle value of each bucket
+func (s *histogramSnapshot) Variance() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	mean := s.Mean()
+	var sum float64
+	for i, c := range s.counts {
+		if c == 0 {
+			continue
+		}
+		lower, upper := s.bucketRange(i)
+		d := (lower+upper)/2 - mean
+		sum += d * d * float64(c)
+	}
+	return sum / float64(s.count)
+}
@@ -0,0 +1,100 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package shm
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{10, 50, 100})
+	if h.Count() != 0 || h.Min() != 0 || h.Max() != 0 || h.Percentile(0.5) != 0 {
+		t.Fatal("empty histogram should be zero")
+	}
+
+	wg := sync.WaitGroup{}
+	for i := int64(1); i <= 100; i++ {
+		wg.Add(1)
+		go func(v int64) {
+			h.Update(v)
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	h.Update(200)
+
+	s := h.Snapshot()
+	if s.Count() != 101 || s.Sum() != 5250 || s.Min() != 1 || s.Max() != 200 {
+		t.Errorf("unexpected histogram: count %d, sum %d, min %d, max %d", s.Count(), s.Sum(), s.Min(), s.Max())
+	}
+	bounds, counts := s.(types.BucketHistogram).Buckets()
+	if !reflect.DeepEqual(bounds, []float64{10, 50, 100}) || !reflect.DeepEqual(counts, []int64{10, 40, 50, 1}) {
+		t.Errorf("unexpected buckets: %v %v", bounds, counts)
+	}
+	// the 50th value is the last one in bucket (10, 50]
+	if p := s.Percentile(50.0 / 101); p != 50 {
+		t.Errorf("unexpected p50: %f", p)
+	}
+	// the +Inf bucket is limited by max
+	if p := s.Percentile(1); p != 200 {
+		t.Errorf("unexpected p100: %f", p)
+	}
+
+	h.Clear()
+	if h.Count() != 0 || h.Sum() != 0 || h.Max() != 0 {
+		t.Error("histogram is not cleared")
+	}
+	// negative values
+	h.Update(-5)
+	h.Update(-1)
+	if h.Min() != -5 || h.Max() != -1 {
+		t.Errorf("unexpected min %d, max %d", h.Min(), h.Max())
+	}
+}
+
+func TestShmHistogram(t *testing.T) {
+	zone := InitMetricsZone("TestShmHistogram", 10*1024*1024)
+	defer func() {
+		zone.Detach()
+		Reset()
+	}()
+
+	bounds := []float64{10, 100}
+	h := NewShmHistogramFunc("TestShmHistogram", bounds)().(*ShmHistogram)
+	h.Update(5)
+	h.Update(50)
+
+	// the same name shares the slots, like the histogram in a new process after hot upgrade
+	h2 := NewShmHistogramFunc("TestShmHistogram", bounds)().(*ShmHistogram)
+	h2.Update(500)
+	if h.Count() != 3 || h.Sum() != 555 || h.Min() != 5 || h.Max() != 500 {
+		t.Errorf("unexpected histogram: count %d, sum %d, min %d, max %d", h.Count(), h.Sum(), h.Min(), h.Max())
+	}
+
+	// the slots are freed after all references stopped
+	h.Stop()
+	h2.Stop()
+	h3 := NewShmHistogramFunc("TestShmHistogram", bounds)()
+	if h3.Count() != 0 {
+		t.Errorf("histogram should be freed, but count is %d", h3.Count())
+	}
+}
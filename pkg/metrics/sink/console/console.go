@@ -29,7 +29,10 @@ import (
 )
 
 // histogram output percents
-var percents = []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+var (
+	percents      = []float64{0.5, 0.9, 0.99}
+	percentSuffix = []string{"_p50", "_p90", "_p99"}
+)
 
 // NamespaceData represents a namespace's metrics data in string format
 type NamespaceData map[string]string
@@ -66,6 +69,9 @@ func (sink *consoleSink) Flush(writer io.Writer, ms []types.Metrics) {
 				h := metric.Snapshot()
 				namespaceData[key+"_min"] = strconv.FormatInt(h.Min(), 10)
 				namespaceData[key+"_max"] = strconv.FormatInt(h.Max(), 10)
+				for i, value := range h.Percentiles(percents) {
+					namespaceData[key+percentSuffix[i]] = strconv.FormatFloat(value, 'f', 2, 64)
+				}
 			default: //unsupport metrics, ignore
 				return
 			}
@@ -116,7 +116,12 @@ func TestConsoleMetrics(t *testing.T) {
 			ns1["k3"] == "1") {
 			t.Error("count and gauge not expected")
 		}
-		//TODO: histogram value expected
+		if !(ns1["k4_min"] == "1" &&
+			ns1["k4_max"] == "4" &&
+			ns1["k4_p50"] == "2.50" &&
+			ns1["k4_p99"] == "3.97") {
+			t.Errorf("histogram not expected: %v", ns1)
+		}
 	}
 	if ns2, ok := t1Data["lbk2.lbv2"]; !ok {
 		t.Error("no lbk2.lbv2 data")
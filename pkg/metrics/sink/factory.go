@@ -48,3 +48,9 @@ func CreateMetricsSink(sinkType string, config map[string]interface{}) (types.Me
 	}
 	return nil, fmt.Errorf("unsupported metrics sink type: %v", sinkType)
 }
+
+// HasSink returns whether the sinkType is registered
+func HasSink(sinkType string) bool {
+	_, ok := metricsSinkFactory[sinkType]
+	return ok
+}
@@ -0,0 +1,339 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/metrics/sink"
+	"mosn.io/mosn/pkg/otlp"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+var (
+	sinkType             = "otlp"
+	defaultServiceName   = "mosn"
+	defaultFlushInterval = 10 * time.Second
+	scopeName            = "mosn.io/mosn/pkg/metrics"
+	// the data point attribute of the metrics type
+	typeAttribute = "type"
+)
+
+// Fields of the OTLP metrics messages, see opentelemetry/proto/metrics/v1/metrics.proto
+const (
+	// ExportMetricsServiceRequest
+	fieldRequestResourceMetrics = 1
+	// ResourceMetrics
+	fieldResourceMetricsResource     = 1
+	fieldResourceMetricsScopeMetrics = 2
+	// ScopeMetrics
+	fieldScopeMetricsScope   = 1
+	fieldScopeMetricsMetrics = 2
+	// Metric
+	fieldMetricName      = 1
+	fieldMetricGauge     = 5
+	fieldMetricSum       = 7
+	fieldMetricHistogram = 9
+	// Gauge, Sum and Histogram
+	fieldDataPoints             = 1
+	fieldAggregationTemporality = 2
+	fieldSumIsMonotonic         = 3
+	// NumberDataPoint
+	fieldNumberStartTime  = 2
+	fieldNumberTime       = 3
+	fieldNumberAsInt      = 6
+	fieldNumberAttributes = 7
+	// HistogramDataPoint
+	fieldHistogramStartTime      = 2
+	fieldHistogramTime           = 3
+	fieldHistogramCount          = 4
+	fieldHistogramSum            = 5
+	fieldHistogramBucketCounts   = 6
+	fieldHistogramExplicitBounds = 7
+	fieldHistogramAttributes     = 9
+	fieldHistogramMin            = 11
+	fieldHistogramMax            = 12
+
+	temporalityCumulative = 2
+)
+
+func init() {
+	sink.RegisterSink(sinkType, builder)
+}
+
+// otlpConfig contains config for otlp sink
+type otlpConfig struct {
+	otlp.Config
+	ServiceName        string            `json:"service_name"`
+	ResourceAttributes map[string]string `json:"resource_attributes"`
+	FlushInterval      v2.DurationConfig `json:"flush_interval"`
+	// the explicit bounds of buckets for the sample histograms,
+	// the fixed buckets histograms are exported with their own buckets
+	HistogramBuckets []float64 `json:"histogram_buckets"`
+}
+
+// otlpSink pushes the metrics to an OpenTelemetry collector periodically.
+// metrics type and labels are mapped into data point attributes, counters are
+// exported as cumulative sums, and histograms as explicit bucket histograms
+type otlpSink struct {
+	config   *otlpConfig
+	exporter *otlp.Exporter
+	resource map[string]string
+	// the start time of cumulative data points
+	startTime time.Time
+	ticker    *utils.Ticker
+	mutex     sync.Mutex
+}
+
+// NewOtlpSink returns a metrics sink that pushes metrics by OTLP
+func NewOtlpSink(config *otlpConfig) (types.MetricsSink, error) {
+	exporter, err := otlp.NewExporter(&config.Config, otlp.SignalMetrics)
+	if err != nil {
+		return nil, err
+	}
+	s := newOtlpSink(config)
+	s.exporter = exporter
+	s.ticker = utils.NewTicker(s.flushAll)
+	s.ticker.Start(config.FlushInterval.Duration)
+	return s, nil
+}
+
+func newOtlpSink(config *otlpConfig) *otlpSink {
+	resource := map[string]string{
+		"service.name": config.ServiceName,
+	}
+	for k, v := range config.ResourceAttributes {
+		resource[k] = v
+	}
+	return &otlpSink{
+		config:    config,
+		resource:  resource,
+		startTime: time.Now(),
+	}
+}
+
+func (s *otlpSink) flushAll() {
+	buf := &bytes.Buffer{}
+	s.Flush(buf, metrics.GetAll())
+	if err := s.exporter.Export(buf.Bytes()); err != nil {
+		log.DefaultLogger.Errorf("[metrics] [otlp] export to %s failed: %v", s.config.Endpoint, err)
+	}
+}
+
+// ~ MetricsSink
+// writes a serialized ExportMetricsServiceRequest
+func (s *otlpSink) Flush(writer io.Writer, ms []types.Metrics) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	group := newMetricGroup()
+	for _, m := range ms {
+		labelKeys, labelVals := m.SortedLabels()
+		if sink.IsExclusionLabels(labelKeys) {
+			continue
+		}
+		attrs := make(map[string]string, len(labelKeys)+1)
+		for i := range labelKeys {
+			attrs[labelKeys[i]] = labelVals[i]
+		}
+		attrs[typeAttribute] = m.Type()
+
+		m.Each(func(key string, i interface{}) {
+			if sink.IsExclusionKeys(key) {
+				return
+			}
+			switch metric := i.(type) {
+			case gometrics.Counter:
+				group.add(key, fieldMetricSum, s.numberPoint(attrs, now, metric.Count()))
+			case gometrics.Gauge:
+				group.add(key, fieldMetricGauge, s.numberPoint(attrs, now, metric.Value()))
+			case gometrics.Histogram:
+				snapshot := metric.Snapshot()
+				if snapshot.Count() == 0 {
+					return
+				}
+				group.add(key, fieldMetricHistogram, s.histogramPoint(attrs, now, snapshot))
+			}
+		})
+	}
+
+	e := otlp.NewEncoder()
+	e.Message(fieldRequestResourceMetrics, func(rm *otlp.Encoder) {
+		rm.Resource(fieldResourceMetricsResource, s.resource)
+		rm.Message(fieldResourceMetricsScopeMetrics, func(sm *otlp.Encoder) {
+			sm.Scope(fieldScopeMetricsScope, scopeName, "")
+			for _, metric := range group.metrics {
+				sm.Message(fieldScopeMetricsMetrics, metric.encode)
+			}
+		})
+	})
+	writer.Write(e.Bytes())
+}
+
+func (s *otlpSink) numberPoint(attrs map[string]string, now time.Time, value int64) func(*otlp.Encoder) {
+	return func(p *otlp.Encoder) {
+		p.Attributes(fieldNumberAttributes, attrs)
+		p.Fixed64(fieldNumberStartTime, uint64(s.startTime.UnixNano()))
+		p.Fixed64(fieldNumberTime, uint64(now.UnixNano()))
+		p.OptionalFixed64(fieldNumberAsInt, uint64(value))
+	}
+}
+
+func (s *otlpSink) histogramPoint(attrs map[string]string, now time.Time, snapshot gometrics.Histogram) func(*otlp.Encoder) {
+	var bounds []float64
+	var counts []uint64
+	var sum float64
+	if h, ok := snapshot.(types.BucketHistogram); ok {
+		var values []int64
+		bounds, values = h.Buckets()
+		counts = make([]uint64, len(values))
+		for i, c := range values {
+			counts[i] = uint64(c)
+		}
+		sum = float64(h.Sum())
+	} else {
+		bounds = s.config.HistogramBuckets
+		counts = bucketCounts(bounds, snapshot.Sample().Values(), snapshot.Count())
+		// the sample only keeps part of the values
+		sum = snapshot.Mean() * float64(snapshot.Count())
+	}
+	return func(p *otlp.Encoder) {
+		p.Attributes(fieldHistogramAttributes, attrs)
+		p.Fixed64(fieldHistogramStartTime, uint64(s.startTime.UnixNano()))
+		p.Fixed64(fieldHistogramTime, uint64(now.UnixNano()))
+		p.Fixed64(fieldHistogramCount, uint64(snapshot.Count()))
+		p.OptionalDouble(fieldHistogramSum, sum)
+		p.PackedFixed64(fieldHistogramBucketCounts, counts)
+		p.PackedDouble(fieldHistogramExplicitBounds, bounds)
+		p.OptionalDouble(fieldHistogramMin, float64(snapshot.Min()))
+		p.OptionalDouble(fieldHistogramMax, float64(snapshot.Max()))
+	}
+}
+
+// bucketCounts distributes the sample values into the buckets, bucket i counts the values in (bounds[i-1], bounds[i]]
+// and the last bucket counts the values larger than all bounds.
+// go-metrics histograms only keep a sample of the values, so the counts are scaled up to the total count
+func bucketCounts(bounds []float64, values []int64, total int64) []uint64 {
+	samples := make([]int64, len(bounds)+1)
+	for _, v := range values {
+		samples[sort.SearchFloat64s(bounds, float64(v))]++
+	}
+	counts := make([]uint64, len(samples))
+	if len(values) == 0 {
+		return counts
+	}
+	// scale the cumulative counts, so the sum of buckets is exactly the total
+	var cumulative, last int64
+	for i, n := range samples {
+		cumulative += n
+		scaled := int64(math.Round(float64(cumulative) * float64(total) / float64(len(values))))
+		counts[i] = uint64(scaled - last)
+		last = scaled
+	}
+	return counts
+}
+
+// metricGroup merges the data points with the same metric name, keeps the first seen order
+type metricGroup struct {
+	index   map[string]*metricData
+	metrics []*metricData
+}
+
+type metricData struct {
+	name   string
+	field  int
+	points []func(*otlp.Encoder)
+}
+
+func newMetricGroup() *metricGroup {
+	return &metricGroup{
+		index: make(map[string]*metricData),
+	}
+}
+
+func (g *metricGroup) add(name string, field int, point func(*otlp.Encoder)) {
+	id := fmt.Sprintf("%s/%d", name, field)
+	data, ok := g.index[id]
+	if !ok {
+		data = &metricData{
+			name:  name,
+			field: field,
+		}
+		g.index[id] = data
+		g.metrics = append(g.metrics, data)
+	}
+	data.points = append(data.points, point)
+}
+
+func (d *metricData) encode(m *otlp.Encoder) {
+	m.String(fieldMetricName, d.name)
+	m.Message(d.field, func(data *otlp.Encoder) {
+		for _, point := range d.points {
+			data.Message(fieldDataPoints, point)
+		}
+		switch d.field {
+		case fieldMetricSum:
+			data.Uint64(fieldAggregationTemporality, temporalityCumulative)
+			data.Bool(fieldSumIsMonotonic, true)
+		case fieldMetricHistogram:
+			data.Uint64(fieldAggregationTemporality, temporalityCumulative)
+		}
+	})
+}
+
+// factory
+func builder(cfg map[string]interface{}) (types.MetricsSink, error) {
+	// parse config
+	otlpCfg := &otlpConfig{}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parsing otlp sink error, err: %v, cfg: %v", err, cfg)
+	}
+	if err := json.Unmarshal(data, otlpCfg); err != nil {
+		return nil, fmt.Errorf("parsing otlp sink error, err: %v, cfg: %v", err, cfg)
+	}
+
+	if otlpCfg.ServiceName == "" {
+		otlpCfg.ServiceName = defaultServiceName
+	}
+	if otlpCfg.FlushInterval.Duration <= 0 {
+		otlpCfg.FlushInterval.Duration = defaultFlushInterval
+	}
+	if len(otlpCfg.HistogramBuckets) == 0 {
+		otlpCfg.HistogramBuckets = metrics.DefaultHistogramBuckets
+	}
+	if !sort.Float64sAreSorted(otlpCfg.HistogramBuckets) {
+		return nil, fmt.Errorf("otlp sink's histogram buckets must be sorted: %v", otlpCfg.HistogramBuckets)
+	}
+
+	return NewOtlpSink(otlpCfg)
+}
@@ -0,0 +1,352 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+)
+
+// message is a decoded protobuf message, for test only
+type message map[int][]interface{}
+
+func decode(t *testing.T, data []byte) message {
+	m := message{}
+	for len(data) > 0 {
+		key, n := proto.DecodeVarint(data)
+		if n == 0 {
+			t.Fatalf("decode key error")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			v, n := proto.DecodeVarint(data)
+			m[field] = append(m[field], v)
+			data = data[n:]
+		case 1:
+			m[field] = append(m[field], binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case 2:
+			l, n := proto.DecodeVarint(data)
+			data = data[n:]
+			m[field] = append(m[field], data[:l])
+			data = data[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return m
+}
+
+func (m message) sub(t *testing.T, fields ...int) []message {
+	msgs := []message{m}
+	for _, f := range fields {
+		var next []message
+		for _, msg := range msgs {
+			for _, v := range msg[f] {
+				next = append(next, decode(t, v.([]byte)))
+			}
+		}
+		msgs = next
+	}
+	return msgs
+}
+
+func (m message) str(field int) string {
+	if len(m[field]) == 0 {
+		return ""
+	}
+	return string(m[field][0].([]byte))
+}
+
+func (m message) fixed(field int) uint64 {
+	if len(m[field]) == 0 {
+		return 0
+	}
+	return m[field][0].(uint64)
+}
+
+func (m message) attrs(t *testing.T, field int) map[string]string {
+	attrs := map[string]string{}
+	for _, kv := range m.sub(t, field) {
+		value := kv.sub(t, 2)[0]
+		attrs[kv.str(1)] = value.str(1)
+	}
+	return attrs
+}
+
+// metricsByName decodes an ExportMetricsServiceRequest
+func metricsByName(t *testing.T, data []byte) (resource map[string]string, ms map[string]message) {
+	req := decode(t, data)
+	resource = req.sub(t, 1, 1)[0].attrs(t, 1)
+	ms = map[string]message{}
+	for _, m := range req.sub(t, 1, 2, 2) {
+		ms[m.str(1)] = m
+	}
+	return
+}
+
+func newTestConfig() *otlpConfig {
+	return &otlpConfig{
+		ServiceName:        "mosn",
+		ResourceAttributes: map[string]string{"zone": "gz00a"},
+		HistogramBuckets:   []float64{10, 50, 100},
+	}
+}
+
+func TestOtlpFlush(t *testing.T) {
+	metrics.ResetAll()
+	metrics.SetHistogramConfig(map[string][]float64{"downstream": {10, 50, 100}}, false)
+	m, _ := metrics.NewMetrics("downstream", map[string]string{"listener": "l1"})
+	m.Counter("request_total").Inc(3)
+	m.Gauge("active").Update(-2)
+	h := m.Histogram("request_duration")
+	for i := int64(1); i <= 100; i++ {
+		h.Update(i)
+	}
+	m2, _ := metrics.NewMetrics("downstream", map[string]string{"listener": "l2"})
+	m2.Counter("request_total").Inc(5)
+	m3, _ := metrics.NewMetrics("upstream", map[string]string{"cluster": "c1"})
+	m3.Counter("request_total").Inc(7)
+
+	s := newOtlpSink(newTestConfig())
+	buf := &bytes.Buffer{}
+	s.Flush(buf, []types.Metrics{m, m2, m3})
+
+	resource, ms := metricsByName(t, buf.Bytes())
+	if len(resource) != 2 || resource["service.name"] != "mosn" || resource["zone"] != "gz00a" {
+		t.Errorf("unexpected resource attributes: %v", resource)
+	}
+	if len(ms) != 3 {
+		t.Fatalf("expected 3 metrics, but got %d", len(ms))
+	}
+
+	// the metrics name is kept, and the metrics type is a data point attribute,
+	// counters with the same name are merged into one sum
+	sum := ms["request_total"].sub(t, 7)[0]
+	if sum[2][0].(uint64) != temporalityCumulative || sum[3][0].(uint64) != 1 {
+		t.Errorf("counter should be a cumulative monotonic sum")
+	}
+	points := sum.sub(t, 1)
+	if len(points) != 3 {
+		t.Fatalf("expected 3 data points, but got %d", len(points))
+	}
+	for _, p := range points {
+		attrs := p.attrs(t, 7)
+		value := int64(p.fixed(6))
+		switch {
+		case attrs["type"] == "downstream" && attrs["listener"] == "l1" && value == 3:
+		case attrs["type"] == "downstream" && attrs["listener"] == "l2" && value == 5:
+		case attrs["type"] == "upstream" && attrs["cluster"] == "c1" && value == 7:
+		default:
+			t.Errorf("unexpected data point: %v %d", attrs, value)
+		}
+		if p.fixed(2) == 0 || p.fixed(3) < p.fixed(2) {
+			t.Errorf("unexpected time: %d %d", p.fixed(2), p.fixed(3))
+		}
+	}
+
+	gauge := ms["active"].sub(t, 5, 1)[0]
+	if int64(gauge.fixed(6)) != -2 {
+		t.Errorf("unexpected gauge value: %d", int64(gauge.fixed(6)))
+	}
+
+	hist := ms["request_duration"].sub(t, 9)[0]
+	if hist[2][0].(uint64) != temporalityCumulative {
+		t.Errorf("histogram should be cumulative")
+	}
+	hp := hist.sub(t, 1)[0]
+	if hp.fixed(4) != 100 || math.Float64frombits(hp.fixed(5)) != 5050 {
+		t.Errorf("unexpected histogram count %d and sum %f", hp.fixed(4), math.Float64frombits(hp.fixed(5)))
+	}
+	if math.Float64frombits(hp.fixed(11)) != 1 || math.Float64frombits(hp.fixed(12)) != 100 {
+		t.Errorf("unexpected histogram min and max")
+	}
+	counts := hp[6][0].([]byte)
+	for i, expected := range []uint64{10, 40, 50, 0} {
+		if c := binary.LittleEndian.Uint64(counts[i*8:]); c != expected {
+			t.Errorf("expected bucket count %d, but got %d", expected, c)
+		}
+	}
+	if len(hp[7][0].([]byte)) != 3*8 {
+		t.Errorf("unexpected explicit bounds")
+	}
+}
+
+func TestBucketCounts(t *testing.T) {
+	bounds := []float64{10, 100}
+	// the sample is scaled to the total count
+	counts := bucketCounts(bounds, []int64{1, 10, 11, 1000}, 10)
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total != 10 || counts[0] != 5 || counts[1] != 3 || counts[2] != 2 {
+		t.Errorf("unexpected bucket counts: %v", counts)
+	}
+	counts = bucketCounts(bounds, nil, 0)
+	if len(counts) != 3 || counts[0]+counts[1]+counts[2] != 0 {
+		t.Errorf("unexpected bucket counts: %v", counts)
+	}
+}
+
+// rawCodec is the codec of the collector stub
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte{}, data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "proto"
+}
+
+type received struct {
+	body    []byte
+	headers map[string]string
+}
+
+// startGRPCCollector starts an in-process OTLP/gRPC collector stub
+func startGRPCCollector(t *testing.T, ch chan received) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.CustomCodec(rawCodec{}))
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "opentelemetry.proto.collector.metrics.v1.MetricsService",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Export",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				var body []byte
+				if err := dec(&body); err != nil {
+					return nil, err
+				}
+				r := received{body: body, headers: map[string]string{}}
+				if md, ok := metadata.FromIncomingContext(ctx); ok {
+					for k, v := range md {
+						r.headers[k] = v[0]
+					}
+				}
+				select {
+				case ch <- r:
+				default:
+				}
+				resp := []byte{}
+				return &resp, nil
+			},
+		}},
+	}, struct{}{})
+	go server.Serve(ln)
+	return ln.Addr().String(), server.Stop
+}
+
+func testExport(t *testing.T, cfg map[string]interface{}, ch chan received) {
+	metrics.ResetAll()
+	m, _ := metrics.NewMetrics("test_otlp", map[string]string{})
+	m.Counter("request_total").Inc(1)
+
+	cfg["flush_interval"] = "100ms"
+	cfg["headers"] = map[string]string{"x-token": "secret"}
+	s, err := builder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.(*otlpSink).ticker.Stop()
+		s.(*otlpSink).exporter.Close()
+	}()
+
+	select {
+	case r := <-ch:
+		if r.headers["x-token"] != "secret" {
+			t.Errorf("headers are not sent: %v", r.headers)
+		}
+		_, ms := metricsByName(t, r.body)
+		if _, ok := ms["request_total"]; !ok {
+			t.Errorf("metrics not exported: %v", ms)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector received nothing")
+	}
+}
+
+func TestOtlpGRPC(t *testing.T) {
+	ch := make(chan received, 1)
+	addr, stop := startGRPCCollector(t, ch)
+	defer stop()
+
+	testExport(t, map[string]interface{}{
+		"endpoint": addr,
+	}, ch)
+}
+
+func TestOtlpHTTP(t *testing.T) {
+	ch := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		select {
+		case ch <- received{body: body, headers: map[string]string{"x-token": r.Header.Get("x-token")}}:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	testExport(t, map[string]interface{}{
+		"protocol": "http",
+		"endpoint": server.URL,
+	}, ch)
+}
+
+func TestOtlpConfig(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{
+		{},
+		{"endpoint": "127.0.0.1:4317", "protocol": "udp"},
+		{"endpoint": "127.0.0.1:4317", "histogram_buckets": []float64{10, 1}},
+		{"endpoint": "127.0.0.1:4317", "tls": map[string]interface{}{"status": true, "ca_cert": "/tmp/not_exists_ca.pem"}},
+	} {
+		if _, err := builder(cfg); err == nil {
+			t.Errorf("config %v expected an error", cfg)
+		}
+	}
+}
@@ -130,7 +130,54 @@ func (psink *promSink) flushHistogram(tracker map[string]bool, buf types.IoBuffe
 	psink.flushGauge(tracker, buf, name+"_min", labels, float64(snapshot.Min()))
 	// max
 	psink.flushGauge(tracker, buf, name+"_max", labels, float64(snapshot.Max()))
-	// TODO: flush P90 P95 P99 if configured
+	// buckets, only fixed buckets histograms can be aggregated
+	if h, ok := snapshot.(types.BucketHistogram); ok {
+		psink.flushBuckets(tracker, buf, name, labels, h)
+	}
+}
+
+func (psink *promSink) flushBuckets(tracker map[string]bool, buf types.IoBuffer, name string, labels string, h types.BucketHistogram) {
+	// type
+	if !tracker[name] {
+		buf.WriteString("# TYPE ")
+		buf.WriteString(name)
+		buf.WriteString(" histogram\n")
+		tracker[name] = true
+	}
+	if labels != "" {
+		labels += ","
+	}
+	bounds, counts := h.Buckets()
+	var cumulative int64
+	for i, count := range counts {
+		cumulative += count
+		le := math.Inf(+1)
+		if i < len(bounds) {
+			le = bounds[i]
+		}
+		buf.WriteString(name)
+		buf.WriteString("_bucket{")
+		buf.WriteString(labels)
+		buf.WriteString("le=\"")
+		writeFloat(buf, le)
+		buf.WriteString("\"} ")
+		writeFloat(buf, float64(cumulative))
+		buf.WriteString("\n")
+	}
+	// sum
+	buf.WriteString(name)
+	buf.WriteString("_sum{")
+	buf.WriteString(strings.TrimSuffix(labels, ","))
+	buf.WriteString("} ")
+	writeFloat(buf, float64(h.Sum()))
+	buf.WriteString("\n")
+	// count
+	buf.WriteString(name)
+	buf.WriteString("_count{")
+	buf.WriteString(strings.TrimSuffix(labels, ","))
+	buf.WriteString("} ")
+	writeFloat(buf, float64(cumulative))
+	buf.WriteString("\n")
 }
 
 func (psink *promSink) flushGauge(tracker map[string]bool, buf types.IoBuffer, name string, labels string, val float64) {
@@ -0,0 +1,326 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statsd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/metrics/sink"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+var (
+	sinkType             = "statsd"
+	defaultNetwork       = "udp"
+	defaultFlushInterval = 10 * time.Second
+	// the max payload of a udp packet that avoids fragmentation in most networks
+	defaultUDPMTU = 1432
+	defaultTCPMTU = 8932
+	dialTimeout   = 3 * time.Second
+	// histogram output percentiles
+	percentiles = []float64{0.5, 0.9, 0.99}
+)
+
+// tag formats
+const (
+	// TagFormatStatsd writes the labels into the metrics name
+	TagFormatStatsd = "statsd"
+	// TagFormatDogStatsd writes the labels as DogStatsD tags
+	TagFormatDogStatsd = "dogstatsd"
+)
+
+func init() {
+	sink.RegisterSink(sinkType, builder)
+}
+
+// statsdConfig contains config for statsd sink
+type statsdConfig struct {
+	Address       string            `json:"address"`
+	Network       string            `json:"network"` // udp or tcp, default is udp
+	Prefix        string            `json:"prefix"`
+	TagFormat     string            `json:"tag_format"` // statsd or dogstatsd, default is statsd
+	FlushInterval v2.DurationConfig `json:"flush_interval"`
+	MTU           int               `json:"mtu"` // max bytes in a packet
+}
+
+// statsdSink pushes the metrics to statsd periodically.
+// counters are sent as deltas since last flush, gauges are sent as values,
+// and histograms are sent as gauges of summaries
+type statsdSink struct {
+	config *statsdConfig
+
+	mutex sync.Mutex
+	conn  net.Conn
+	// last counter values, used to calculate the deltas
+	counters map[string]int64
+	ticker   *utils.Ticker
+}
+
+// NewStatsdSink returns a metrics sink that pushes metrics to statsd
+func NewStatsdSink(config *statsdConfig) types.MetricsSink {
+	s := &statsdSink{
+		config:   config,
+		counters: make(map[string]int64),
+	}
+	s.ticker = utils.NewTicker(s.flushAll)
+	s.ticker.Start(config.FlushInterval.Duration)
+	return s
+}
+
+func (s *statsdSink) flushAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.config.Network, s.config.Address, dialTimeout)
+		if err != nil {
+			log.DefaultLogger.Errorf("[metrics] [statsd] connect to %s failed: %v", s.config.Address, err)
+			return
+		}
+		s.conn = conn
+	}
+
+	w := &errorWriter{w: s.conn}
+	s.flush(w, metrics.GetAll())
+	// reconnect on next flush
+	if w.err != nil {
+		log.DefaultLogger.Errorf("[metrics] [statsd] write to %s failed: %v", s.config.Address, w.err)
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// ~ MetricsSink
+// each Write called on the writer contains a batch of lines no more than mtu
+func (s *statsdSink) Flush(writer io.Writer, ms []types.Metrics) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.flush(writer, ms)
+}
+
+func (s *statsdSink) flush(writer io.Writer, ms []types.Metrics) {
+	b := &batcher{
+		w:   writer,
+		mtu: s.config.MTU,
+		buf: buffer.GetIoBuffer(s.config.MTU),
+	}
+	defer buffer.PutIoBuffer(b.buf)
+
+	for _, m := range ms {
+		labelKeys, labelVals := m.SortedLabels()
+		if sink.IsExclusionLabels(labelKeys) {
+			continue
+		}
+		prefix, tags := s.namespace(m.Type(), labelKeys, labelVals)
+
+		m.Each(func(key string, i interface{}) {
+			if sink.IsExclusionKeys(key) {
+				return
+			}
+			name := prefix + sanitize(key)
+			switch metric := i.(type) {
+			case gometrics.Counter:
+				s.flushCounter(b, name, tags, metric.Count())
+			case gometrics.Gauge:
+				flushGauge(b, name, tags, strconv.FormatInt(metric.Value(), 10), metric.Value() < 0)
+			case gometrics.Histogram:
+				flushHistogram(b, name, tags, metric.Snapshot())
+			}
+		})
+	}
+	b.flush()
+}
+
+// namespace returns the name prefix and tags of the metrics
+func (s *statsdSink) namespace(typ string, keys, values []string) (prefix string, tags string) {
+	if s.config.Prefix != "" {
+		prefix = sanitize(s.config.Prefix) + "."
+	}
+	prefix += sanitize(typ) + "."
+	if s.config.TagFormat == TagFormatDogStatsd {
+		pairs := make([]string, 0, len(keys))
+		for i := range keys {
+			pairs = append(pairs, sanitizeTag(keys[i])+":"+sanitizeTag(values[i]))
+		}
+		if len(pairs) > 0 {
+			tags = "|#" + strings.Join(pairs, ",")
+		}
+		return
+	}
+	for i := range keys {
+		prefix += sanitize(keys[i]) + "." + sanitize(values[i]) + "."
+	}
+	return
+}
+
+func (s *statsdSink) flushCounter(b *batcher, name, tags string, count int64) {
+	id := name + tags
+	last, ok := s.counters[id]
+	s.counters[id] = count
+	delta := count - last
+	// the counter is reset
+	if delta < 0 {
+		delta = count
+	}
+	if ok && delta == 0 {
+		return
+	}
+	b.write(name, strconv.FormatInt(delta, 10), "|c", tags)
+}
+
+// flushGauge sends a gauge value, a negative value is treated as a decrement in statsd,
+// so the gauge should be set to zero first
+func flushGauge(b *batcher, name, tags, value string, negative bool) {
+	if negative {
+		b.write(name, "0", "|g", tags)
+	}
+	b.write(name, value, "|g", tags)
+}
+
+func flushHistogram(b *batcher, name, tags string, snapshot gometrics.Histogram) {
+	if snapshot.Count() == 0 {
+		return
+	}
+	flushGauge(b, name+".count", tags, strconv.FormatInt(snapshot.Count(), 10), false)
+	flushGauge(b, name+".min", tags, strconv.FormatInt(snapshot.Min(), 10), snapshot.Min() < 0)
+	flushGauge(b, name+".max", tags, strconv.FormatInt(snapshot.Max(), 10), snapshot.Max() < 0)
+	mean := snapshot.Mean()
+	flushGauge(b, name+".mean", tags, strconv.FormatFloat(mean, 'f', -1, 64), mean < 0)
+	for i, value := range snapshot.Percentiles(percentiles) {
+		suffix := ".p" + strconv.FormatFloat(percentiles[i]*100, 'f', -1, 64)
+		flushGauge(b, name+suffix, tags, strconv.FormatFloat(value, 'f', -1, 64), value < 0)
+	}
+}
+
+// batcher packs the lines into packets no more than mtu
+type batcher struct {
+	w   io.Writer
+	mtu int
+	buf types.IoBuffer
+}
+
+func (b *batcher) write(name, value, typ, tags string) {
+	size := len(name) + len(value) + len(typ) + len(tags) + 2
+	if b.buf.Len() > 0 && b.buf.Len()+size > b.mtu {
+		b.flush()
+	}
+	b.buf.WriteString(name)
+	b.buf.WriteString(":")
+	b.buf.WriteString(value)
+	b.buf.WriteString(typ)
+	b.buf.WriteString(tags)
+	b.buf.WriteString("\n")
+}
+
+func (b *batcher) flush() {
+	if b.buf.Len() == 0 {
+		return
+	}
+	b.w.Write(b.buf.Bytes())
+	b.buf.Reset()
+}
+
+// errorWriter keeps the first write error
+type errorWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errorWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.err = err
+	return n, err
+}
+
+var replacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+
+// sanitize replaces the characters reserved by statsd protocol
+func sanitize(s string) string {
+	return replacer.Replace(s)
+}
+
+var tagReplacer = strings.NewReplacer("|", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+
+// sanitizeTag keeps the ':' in tags, the first one is the separator of key and value
+func sanitizeTag(s string) string {
+	return tagReplacer.Replace(s)
+}
+
+// factory
+func builder(cfg map[string]interface{}) (types.MetricsSink, error) {
+	// parse config
+	statsdCfg := &statsdConfig{}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parsing statsd sink error, err: %v, cfg: %v", err, cfg)
+	}
+	if err := json.Unmarshal(data, statsdCfg); err != nil {
+		return nil, fmt.Errorf("parsing statsd sink error, err: %v, cfg: %v", err, cfg)
+	}
+
+	if statsdCfg.Address == "" {
+		return nil, fmt.Errorf("statsd sink's address is not specified")
+	}
+
+	switch statsdCfg.Network {
+	case "":
+		statsdCfg.Network = defaultNetwork
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("unsupported statsd network: %s", statsdCfg.Network)
+	}
+
+	switch statsdCfg.TagFormat {
+	case "":
+		statsdCfg.TagFormat = TagFormatStatsd
+	case TagFormatStatsd, TagFormatDogStatsd:
+	default:
+		return nil, fmt.Errorf("unsupported statsd tag format: %s", statsdCfg.TagFormat)
+	}
+
+	if statsdCfg.FlushInterval.Duration <= 0 {
+		statsdCfg.FlushInterval.Duration = defaultFlushInterval
+	}
+
+	if statsdCfg.MTU <= 0 {
+		statsdCfg.MTU = defaultUDPMTU
+		if statsdCfg.Network == "tcp" {
+			statsdCfg.MTU = defaultTCPMTU
+		}
+	}
+
+	return NewStatsdSink(statsdCfg), nil
+}
@@ -0,0 +1,189 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statsd
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+)
+
+// packetWriter records each write as a packet
+type packetWriter struct {
+	packets []string
+}
+
+func (w *packetWriter) Write(p []byte) (int, error) {
+	w.packets = append(w.packets, string(p))
+	return len(p), nil
+}
+
+func (w *packetWriter) lines() []string {
+	var lines []string
+	for _, p := range w.packets {
+		lines = append(lines, strings.Split(strings.TrimSuffix(p, "\n"), "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func newTestSink(tagFormat string, mtu int) *statsdSink {
+	return &statsdSink{
+		config: &statsdConfig{
+			Prefix:    "mosn",
+			TagFormat: tagFormat,
+			MTU:       mtu,
+		},
+		counters: make(map[string]int64),
+	}
+}
+
+func equalLines(t *testing.T, got, expected []string) {
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("expected %v, but got %v", expected, got)
+		}
+	}
+}
+
+func TestStatsdFlush(t *testing.T) {
+	metrics.ResetAll()
+	m, _ := metrics.NewMetrics("downstream", map[string]string{"listener": "l1"})
+	m.Counter("request_total").Inc(3)
+	m.Gauge("active").Update(-2)
+
+	s := newTestSink(TagFormatStatsd, 1432)
+	w := &packetWriter{}
+	s.Flush(w, []types.Metrics{m})
+	equalLines(t, w.lines(), []string{
+		"mosn.downstream.listener.l1.active:-2|g",
+		"mosn.downstream.listener.l1.active:0|g",
+		"mosn.downstream.listener.l1.request_total:3|c",
+	})
+
+	// counters are sent as deltas, unchanged counters are skipped
+	m.Counter("request_total").Inc(2)
+	w = &packetWriter{}
+	s.Flush(w, []types.Metrics{m})
+	equalLines(t, w.lines(), []string{
+		"mosn.downstream.listener.l1.active:-2|g",
+		"mosn.downstream.listener.l1.active:0|g",
+		"mosn.downstream.listener.l1.request_total:2|c",
+	})
+	w = &packetWriter{}
+	s.Flush(w, []types.Metrics{m})
+	equalLines(t, w.lines(), []string{
+		"mosn.downstream.listener.l1.active:-2|g",
+		"mosn.downstream.listener.l1.active:0|g",
+	})
+}
+
+func TestDogStatsdFlush(t *testing.T) {
+	metrics.ResetAll()
+	m, _ := metrics.NewMetrics("upstream", map[string]string{"cluster": "c1", "host": "127.0.0.1:80"})
+	// percentiles are interpolated in the bucket
+	h := m.Histogram("request_duration")
+	for i := int64(1); i <= 100; i++ {
+		h.Update(i)
+	}
+
+	s := newTestSink(TagFormatDogStatsd, 1432)
+	w := &packetWriter{}
+	s.Flush(w, []types.Metrics{m})
+	tags := "|#cluster:c1,host:127.0.0.1:80"
+	equalLines(t, w.lines(), []string{
+		"mosn.upstream.request_duration.count:100|g" + tags,
+		"mosn.upstream.request_duration.max:100|g" + tags,
+		"mosn.upstream.request_duration.mean:50.5|g" + tags,
+		"mosn.upstream.request_duration.min:1|g" + tags,
+		"mosn.upstream.request_duration.p50:50.5|g" + tags,
+		"mosn.upstream.request_duration.p90:90.1|g" + tags,
+		"mosn.upstream.request_duration.p99:99.01|g" + tags,
+	})
+}
+
+func TestStatsdBatchByMTU(t *testing.T) {
+	metrics.ResetAll()
+	m, _ := metrics.NewMetrics("mosn", map[string]string{})
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Counter(key).Inc(1)
+	}
+	// each line is "mosn.mosn.a:1|c\n", 16 bytes
+	s := newTestSink(TagFormatStatsd, 40)
+	w := &packetWriter{}
+	s.Flush(w, []types.Metrics{m})
+	if len(w.packets) != 2 {
+		t.Fatalf("expected 2 packets, but got %v", w.packets)
+	}
+	for _, p := range w.packets {
+		if len(p) > 40 {
+			t.Errorf("packet is larger than mtu: %s", p)
+		}
+	}
+}
+
+func TestStatsdUDP(t *testing.T) {
+	metrics.ResetAll()
+	m, _ := metrics.NewMetrics("test_udp", map[string]string{})
+	m.Counter("request_total").Inc(1)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := builder(map[string]interface{}{
+		"address":        conn.LocalAddr().String(),
+		"flush_interval": "100ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.(*statsdSink).ticker.Stop()
+
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf[:n]), "test_udp.request_total:1|c\n") {
+		t.Errorf("unexpected packet: %s", buf[:n])
+	}
+}
+
+func TestStatsdConfig(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{
+		{},
+		{"address": "127.0.0.1:8125", "network": "unix"},
+		{"address": "127.0.0.1:8125", "tag_format": "influx"},
+	} {
+		if _, err := builder(cfg); err == nil {
+			t.Errorf("config %v expected an error", cfg)
+		}
+	}
+}
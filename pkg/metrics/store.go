@@ -39,7 +39,8 @@ var (
 
 // stats memory store
 type store struct {
-	matcher *metricsMatcher
+	matcher   *metricsMatcher
+	histogram *histogramConfig
 
 	metrics map[string]types.Metrics
 	mutex   sync.RWMutex
@@ -61,7 +62,8 @@ func init() {
 	defaultMatcher = &metricsMatcher{}
 
 	defaultStore = &store{
-		matcher: defaultMatcher,
+		matcher:   defaultMatcher,
+		histogram: defaultHistogramConfig,
 		// TODO: default length configurable
 		metrics: make(map[string]types.Metrics, 100),
 	}
@@ -171,8 +173,7 @@ func (s *metrics) Histogram(key string) gometrics.Histogram {
 		return gometrics.NilHistogram{}
 	}
 
-	// TODO: notice the histogram only keeps 100 values as we set
-	return s.registry.GetOrRegister(key, func() gometrics.Histogram { return gometrics.NewHistogram(gometrics.NewUniformSample(100)) }).(gometrics.Histogram)
+	return s.registry.GetOrRegister(key, defaultStore.histogram.newHistogramFunc(s.typ, key, s.fullName(key))).(gometrics.Histogram)
 }
 
 func (s *metrics) Each(f func(string, interface{})) {
@@ -208,6 +209,7 @@ func ResetAll() {
 	}
 	defaultStore.metrics = make(map[string]types.Metrics, 100)
 	defaultStore.matcher = defaultMatcher
+	defaultStore.histogram = defaultHistogramConfig
 }
 
 func fullName(typ string, labels map[string]string) (fullName string, keys, values []string) {
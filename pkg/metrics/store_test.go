@@ -160,6 +160,53 @@ func TestExclusionKeys(t *testing.T) {
 	}
 }
 
+func TestHistogramConfig(t *testing.T) {
+	zone := shm.InitMetricsZone("TestHistogramConfig", 10*1024*1024)
+	defer func() {
+		zone.Detach()
+		shm.Reset()
+	}()
+
+	ResetAll()
+	defer ResetAll()
+	for _, buckets := range []map[string][]float64{
+		{"upstream": {}},
+		{"upstream": {10, 1}},
+	} {
+		if err := SetHistogramConfig(buckets, false); err == nil {
+			t.Errorf("buckets %v expected an error", buckets)
+		}
+	}
+	if err := SetHistogramConfig(map[string][]float64{
+		"upstream":               {10, 100},
+		"upstream.request_bytes": {1024},
+	}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := NewMetrics("upstream", map[string]string{"cluster": "c1"})
+	testCases := []struct {
+		key    string
+		bounds []float64
+	}{
+		{"request_time", []float64{10, 100}},
+		{"request_bytes", []float64{1024}},
+	}
+	for _, tc := range testCases {
+		h := m.Histogram(tc.key)
+		if _, ok := h.(*shm.ShmHistogram); !ok {
+			t.Fatalf("%s expected a shm histogram, but got %T", tc.key, h)
+		}
+		if bounds, _ := h.(*shm.ShmHistogram).Buckets(); !reflect.DeepEqual(bounds, tc.bounds) {
+			t.Errorf("%s expected buckets %v, but got %v", tc.key, tc.bounds, bounds)
+		}
+	}
+	m2, _ := NewMetrics("downstream", nil)
+	if bounds, _ := m2.Histogram("request_time").(*shm.ShmHistogram).Buckets(); !reflect.DeepEqual(bounds, DefaultHistogramBuckets) {
+		t.Errorf("expected default buckets, but got %v", bounds)
+	}
+}
+
 func BenchmarkNewMetrics_SameLabels(b *testing.B) {
 	ResetAll()
 	total := b.N
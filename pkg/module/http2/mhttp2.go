@@ -136,6 +136,34 @@ func (ms *MStream) SendResponse() error {
 	return err
 }
 
+// SendHeaders is Http2 Server send response headers without ending the stream, used by the CONNECT tunnel
+func (ms *MStream) SendHeaders() error {
+	ws := &writeResHeaders{
+		streamID:    ms.id,
+		httpResCode: ms.Response.StatusCode,
+		h:           ms.Response.Header,
+	}
+	return ms.conn.writeHeaders(ws)
+}
+
+// WriteData is Http2 Server send data frames, the stream is closed if endStream is true
+func (ms *MStream) WriteData(data []byte, endStream bool) error {
+	if endStream {
+		ms.conn.closeStream(ms.stream, nil)
+	}
+	return ms.conn.Framer.writeData(ms.id, endStream, data)
+}
+
+// ReleaseData returns the flow control of the consumed request data to the client
+func (ms *MStream) ReleaseData(n int) {
+	if n <= 0 {
+		return
+	}
+	ms.bodyBytes -= int64(n)
+	ms.conn.sendWindowUpdate(nil, n)
+	ms.conn.sendWindowUpdate(ms.stream, n)
+}
+
 func (ms *MStream) Reset() {
 	ev := streamError(ms.id, ErrCodeInternal)
 	ms.conn.resetStream(ev)
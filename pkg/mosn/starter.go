@@ -32,9 +32,11 @@ import (
 	"mosn.io/mosn/pkg/metrics/sink"
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/runtime"
 	"mosn.io/mosn/pkg/server"
 	"mosn.io/mosn/pkg/server/keeper"
 	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/trace/propagation"
 	"mosn.io/mosn/pkg/types"
 	"mosn.io/mosn/pkg/upstream/cluster"
 	"mosn.io/mosn/pkg/utils"
@@ -61,6 +63,7 @@ func NewMosn(c *config.MOSNConfig) *Mosn {
 	initializeDefaultPath(config.GetConfigPath())
 	initializePidFile(c.Pid)
 	initializeTracing(c.Tracing)
+	initializeRuntime(c.Runtime)
 
 	//get inherit fds
 	inheritListeners, reconfigure, err := server.GetInheritListeners()
@@ -294,6 +297,9 @@ func Start(c *config.MOSNConfig) {
 }
 
 func initializeTracing(config config.TracingConfig) {
+	if err := propagation.SetFormats(config.Propagation); err != nil {
+		log.StartLogger.Errorf("[mosn] [init tracing] set trace propagation formats failed: %v", err)
+	}
 	if config.Enable && config.Driver != "" {
 		err := trace.Init(config.Driver, config.Config)
 		if err != nil {
@@ -309,6 +315,17 @@ func initializeTracing(config config.TracingConfig) {
 	}
 }
 
+func initializeRuntime(config config.RuntimeConfig) {
+	if config.Path == "" {
+		return
+	}
+	if err := runtime.Init(config.Path, config.RefreshInterval.Duration); err != nil {
+		log.StartLogger.Errorf("[mosn] [init runtime] load runtime from %s failed: %v", config.Path, err)
+		return
+	}
+	log.StartLogger.Infof("[mosn] [init runtime] load runtime from %s", config.Path)
+}
+
 func initializeMetrics(config config.MetricsConfig) {
 	// init shm zone
 	if config.ShmZone != "" && config.ShmSize > 0 {
@@ -318,6 +335,9 @@ func initializeMetrics(config config.MetricsConfig) {
 	// set metrics package
 	statsMatcher := config.StatsMatcher
 	metrics.SetStatsMatcher(statsMatcher.RejectAll, statsMatcher.ExclusionLabels, statsMatcher.ExclusionKeys)
+	if err := metrics.SetHistogramConfig(config.Histogram.Buckets, config.Histogram.Shm); err != nil {
+		log.StartLogger.Errorf("[mosn] [init metrics] %v, use default histogram config", err)
+	}
 	// create sinks
 	for _, cfg := range config.SinkConfigs {
 		_, err := sink.CreateMetricsSink(cfg.Type, cfg.Config)
@@ -172,7 +172,8 @@ func (c *connection) ID() uint64 {
 
 func (c *connection) Start(lctx context.Context) {
 	c.startOnce.Do(func() {
-		if UseNetpollMode {
+		// the udp sessions have no fd to poll, they are always read in the read loop
+		if _, udp := c.rawConnection.(*udpConn); UseNetpollMode && !udp {
 			c.attachEventLoop(lctx)
 		} else {
 			c.startRWLoop(lctx)
@@ -938,6 +939,8 @@ type clientConnection struct {
 	connection
 
 	connectTimeout time.Duration
+	// preface is written before any other data, such as the PROXY protocol header
+	preface []byte
 
 	connectOnce sync.Once
 }
@@ -975,6 +978,14 @@ func NewClientConnection(sourceAddr net.Addr, connectTimeout time.Duration, tlsM
 	return conn
 }
 
+// NewClientConnectionWithPreface new a client connection that writes the preface to the raw connection
+// once it is connected, before the tls handshake and any other data, such as the PROXY protocol header
+func NewClientConnectionWithPreface(sourceAddr net.Addr, connectTimeout time.Duration, tlsMng types.TLSContextManager, remoteAddr net.Addr, stopChan chan struct{}, preface []byte) types.ClientConnection {
+	conn := NewClientConnection(sourceAddr, connectTimeout, tlsMng, remoteAddr, stopChan).(*clientConnection)
+	conn.preface = preface
+	return conn
+}
+
 func (cc *clientConnection) Connect() (err error) {
 	cc.connectOnce.Do(func() {
 		var event types.ConnectionEvent
@@ -986,7 +997,7 @@ func (cc *clientConnection) Connect() (err error) {
 
 		addr := cc.RemoteAddr()
 		if addr != nil {
-			cc.rawConnection, err = net.DialTimeout("tcp", cc.RemoteAddr().String(), timeout)
+			cc.rawConnection, err = net.DialTimeout(addr.Network(), addr.String(), timeout)
 		} else {
 			err = errors.New("ClientConnection RemoteAddr is nil")
 		}
@@ -1007,15 +1018,22 @@ func (cc *clientConnection) Connect() (err error) {
 			// ensure ioEnabled and UseNetpollMode
 			if UseNetpollMode {
 				// store fd
-				if tc, ok := cc.rawConnection.(*net.TCPConn); ok {
+				switch tc := cc.rawConnection.(type) {
+				case *net.TCPConn:
+					cc.file, err = tc.File()
+				case *net.UnixConn:
 					cc.file, err = tc.File()
-					if err != nil {
-						return
-					}
 				}
+				if err != nil {
+					return
+				}
+			}
+
+			if len(cc.preface) > 0 {
+				_, err = cc.rawConnection.Write(cc.preface)
 			}
 
-			if cc.tlsMng != nil {
+			if err == nil && cc.tlsMng != nil {
 				// usually, the client tls manager will never returns an error
 				cc.rawConnection, err = cc.tlsMng.Conn(cc.rawConnection)
 
@@ -19,12 +19,16 @@ package network
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"os"
 	"runtime/debug"
 	"sync"
+	"syscall"
 	"time"
 
+	"golang.org/x/sys/unix"
+
 	"mosn.io/mosn/pkg/api/v2"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/types"
@@ -43,6 +47,13 @@ const (
 	ListenerStopped
 )
 
+// fileListener is the stream listener, *net.TCPListener or *net.UnixListener
+type fileListener interface {
+	net.Listener
+	SetDeadline(t time.Time) error
+	File() (*os.File, error)
+}
+
 // listener impl based on golang net package
 type listener struct {
 	name                    string
@@ -52,11 +63,18 @@ type listener struct {
 	perConnBufferLimitBytes uint32
 	useOriginalDst          bool
 	cb                      types.ListenerEventListener
-	rawl                    *net.TCPListener
+	rawl                    fileListener
+	packetConn              *net.UDPConn
+	reusePort               bool
 	config                  *v2.Listener
 	mutex                   sync.Mutex
 	// listener state indicates the listener's running state. The listener state effects if a listener binded to a port
 	state ListenerState
+
+	// sessions of the udp listener, keyed by the remote address
+	sessions      map[string]*udpConn
+	sessionsMutex sync.Mutex
+	readBuffer    []byte
 }
 
 func NewListener(lc *v2.Listener) types.Listener {
@@ -68,12 +86,13 @@ func NewListener(lc *v2.Listener) types.Listener {
 		listenerTag:             lc.ListenerTag,
 		perConnBufferLimitBytes: lc.PerConnBufferLimitBytes,
 		useOriginalDst:          lc.UseOriginalDst,
+		reusePort:               lc.ReusePort,
 		config:                  lc,
 	}
 
 	if lc.InheritListener != nil {
 		//inherit old process's listener
-		l.rawl = lc.InheritListener
+		l.rawl = lc.InheritListener.(fileListener)
 	}
 	return l
 }
@@ -123,7 +142,7 @@ func (l *listener) Start(lctx context.Context, restart bool) {
 			default:
 				// try start listener
 				//call listen if not inherit
-				if l.rawl == nil {
+				if l.rawl == nil && l.packetConn == nil {
 					if err := l.listen(lctx); err != nil {
 						// TODO: notify listener callbacks
 						log.StartLogger.Fatalf("[network] [listener start] [listen] %s listen failed, %v", l.name, err)
@@ -138,7 +157,7 @@ func (l *listener) Start(lctx context.Context, restart bool) {
 		}
 
 		for {
-			if err := l.accept(lctx); err != nil {
+			if err := l.serve(lctx); err != nil {
 				if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
 					log.DefaultLogger.Infof("[network] [listener start] [accept] listener %s stop accepting connections by deadline", l.name)
 					return
@@ -147,7 +166,7 @@ func (l *listener) Start(lctx context.Context, restart bool) {
 					// stop accepting loop and log the event
 					if !(ope.Timeout() && ope.Temporary()) {
 						// accept error raised by sockets closing
-						if ope.Op == "accept" {
+						if ope.Op == "accept" || ope.Op == "read" {
 							log.DefaultLogger.Infof("[network] [listener start] [accept] listener %s %s closed", l.name, l.Addr())
 						} else {
 							log.DefaultLogger.Errorf("[network] [listener start] [accept] listener %s occurs non-recoverable error, stop listening and accepting:%s", l.name, err.Error())
@@ -163,6 +182,9 @@ func (l *listener) Start(lctx context.Context, restart bool) {
 }
 
 func (l *listener) Stop() error {
+	if l.packetConn != nil {
+		return l.packetConn.SetDeadline(time.Now())
+	}
 	return l.rawl.SetDeadline(time.Now())
 }
 
@@ -175,6 +197,13 @@ func (l *listener) SetListenerTag(tag uint64) {
 }
 
 func (l *listener) ListenerFile() (*os.File, error) {
+	if l.packetConn != nil {
+		return l.packetConn.File()
+	}
+	if ul, ok := l.rawl.(*net.UnixListener); ok {
+		// the socket path is inherited by the new process, keep it when the listener is closed
+		ul.SetUnlinkOnClose(false)
+	}
 	return l.rawl.File()
 }
 
@@ -210,22 +239,166 @@ func (l *listener) Close(lctx context.Context) error {
 		l.cb.OnClose()
 		return l.rawl.Close()
 	}
+	if l.packetConn != nil {
+		l.cb.OnClose()
+		return l.packetConn.Close()
+	}
 	return nil
 }
 
 func (l *listener) listen(lctx context.Context) error {
-	var err error
+	lc := net.ListenConfig{}
+	if l.reusePort {
+		lc.Control = reusePortControl
+	}
+
+	if l.localAddress.Network() == v2.NetworkUDP {
+		conn, err := lc.ListenPacket(context.Background(), v2.NetworkUDP, l.localAddress.String())
+		if err != nil {
+			return err
+		}
+		l.packetConn = conn.(*net.UDPConn)
+		l.sessions = make(map[string]*udpConn)
+		return nil
+	}
+
+	if addr, ok := l.localAddress.(*net.UnixAddr); ok {
+		return l.listenUnix(addr)
+	}
 
-	var rawl *net.TCPListener
-	if rawl, err = net.ListenTCP("tcp", l.localAddress.(*net.TCPAddr)); err != nil {
+	rawl, err := lc.Listen(context.Background(), "tcp", l.localAddress.String())
+	if err != nil {
 		return err
 	}
 
+	l.rawl = rawl.(*net.TCPListener)
+
+	return nil
+}
+
+// listenUnix listens the unix domain socket, and sets the permission and the owner of the socket path
+func (l *listener) listenUnix(addr *net.UnixAddr) error {
+	abstract := utils.IsAbstractUnixAddr(addr)
+	if !abstract {
+		if err := removeStaleUnixSocket(addr.Name); err != nil {
+			return err
+		}
+	}
+	rawl, err := net.ListenUnix("unix", addr)
+	if err != nil {
+		return err
+	}
+	if cfg := l.config.UnixSocket; cfg != nil && !abstract {
+		if err := setUnixSocketFile(addr.Name, cfg); err != nil {
+			rawl.Close()
+			return err
+		}
+	}
 	l.rawl = rawl
+	return nil
+}
 
+// removeStaleUnixSocket removes the socket file left by a stopped process, the socket
+// is in use if it can be connected, and the file is not removed
+func removeStaleUnixSocket(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a unix domain socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("unix domain socket %s is in use", path)
+	}
+	return os.Remove(path)
+}
+
+func setUnixSocketFile(path string, cfg *v2.UnixSocketConfig) error {
+	if cfg.Mode != "" {
+		mode, err := utils.ParseFileMode(cfg.Mode)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	if cfg.Owner != "" {
+		uid, gid, err := utils.LookupOwner(cfg.Owner)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(path, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reusePortControl sets SO_REUSEPORT, so that multiple sockets can bind the same port
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+func (l *listener) serve(lctx context.Context) error {
+	if l.packetConn != nil {
+		return l.readMsg(lctx)
+	}
+	return l.accept(lctx)
+}
+
+// readMsg reads a datagram from the udp listener, and dispatches it to the session of the remote address.
+// A new session is accepted as a connection if the remote address has no session.
+func (l *listener) readMsg(lctx context.Context) error {
+	if l.readBuffer == nil {
+		l.readBuffer = make([]byte, MaxDatagramSize)
+	}
+	n, addr, err := l.packetConn.ReadFromUDP(l.readBuffer)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, n)
+	copy(data, l.readBuffer)
+
+	key := addr.String()
+	l.sessionsMutex.Lock()
+	session, ok := l.sessions[key]
+	if !ok {
+		session = newUDPConn(l.packetConn, addr, l.removeSession)
+		l.sessions[key] = session
+	}
+	l.sessionsMutex.Unlock()
+
+	session.dispatch(data)
+
+	if !ok {
+		utils.GoWithRecover(func() {
+			l.cb.OnAccept(session, false, nil, nil, nil)
+		}, nil)
+	}
 	return nil
 }
 
+func (l *listener) removeSession(c *udpConn) {
+	key := c.remoteAddr.String()
+	l.sessionsMutex.Lock()
+	if l.sessions[key] == c {
+		delete(l.sessions, key)
+	}
+	l.sessionsMutex.Unlock()
+}
+
 func (l *listener) accept(lctx context.Context) error {
 	rawc, err := l.rawl.Accept()
 
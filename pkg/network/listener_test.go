@@ -19,7 +19,10 @@ package network
 
 import (
 	"context"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -98,3 +101,85 @@ func TestListenerStart(t *testing.T) {
 	}
 
 }
+
+func TestUnixListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn_unix_listener")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mosn.sock")
+	// a stale socket file left by a stopped process
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	cfg := &v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			Name:       "test_unix_listener",
+			BindToPort: true,
+			UnixSocket: &v2.UnixSocketConfig{
+				Mode: "0600",
+			},
+		},
+		Addr: &net.UnixAddr{Name: path, Net: "unix"},
+	}
+	ln := NewListener(cfg).(*listener)
+	ln.SetListenerCallbacks(&mockEventListener{})
+	if err := ln.listen(nil); err != nil {
+		t.Fatalf("listen unix socket failed: %v", err)
+	}
+	defer ln.Close(nil)
+
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModePerm != 0600 {
+		t.Fatalf("socket file mode unexpected: %v, %v", info, err)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial unix listener failed: %v", err)
+	}
+	conn.Close()
+
+	// the socket in use is not removed
+	if err := NewListener(cfg).(*listener).listen(nil); err == nil {
+		t.Fatal("listen the unix socket in use")
+	}
+
+	// the socket path is kept if the listener file is transferred
+	if _, err := ln.ListenerFile(); err != nil {
+		t.Fatalf("get listener file failed: %v", err)
+	}
+	ln.Close(nil)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("socket file is removed: %v", err)
+	}
+}
+
+func TestUnixClientConnection(t *testing.T) {
+	name := "@mosn_test_unix_client"
+	l, err := net.Listen("unix", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("hello"))
+		conn.Close()
+	}()
+	conn := NewClientConnection(nil, 0, nil, &net.UnixAddr{Name: name, Net: "unix"}, nil)
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("connect unix socket failed: %v", err)
+	}
+	defer conn.Close(types.NoFlush, types.LocalClose)
+	if _, ok := conn.RawConn().(*net.UnixConn); !ok {
+		t.Fatalf("unexpected connection %T", conn.RawConn())
+	}
+}
@@ -233,6 +233,11 @@ func transferGetFile(c *connection) (file *os.File, tlsConn *mtls.TLSConn, err e
 		if err != nil {
 			return nil, nil, fmt.Errorf("TCP File failed %v", err)
 		}
+	case *net.UnixConn:
+		file, err = conn.File()
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unix File failed %v", err)
+		}
 	case *mtls.Conn:
 		mtlsConn, ok := conn.Conn.(*net.TCPConn)
 		if !ok {
@@ -264,7 +269,7 @@ func transferGetFile(c *connection) (file *os.File, tlsConn *mtls.TLSConn, err e
 			return nil, nil, errors.New("unexpected Conn type")
 		}
 	default:
-		return nil, nil, fmt.Errorf("unexpected net.Conn type; expected TCPConn or mtls.TLSConn, got %T", conn)
+		return nil, nil, fmt.Errorf("unexpected net.Conn type; expected TCPConn, UnixConn or mtls.TLSConn, got %T", conn)
 	}
 	return
 }
@@ -569,7 +574,15 @@ func transferNewConn(conn net.Conn, dataBuf, tlsBuf []byte, handler types.Connec
 }
 
 func transferFindListen(addr net.Addr, handler types.ConnectionHandler) types.Listener {
-	address := addr.(*net.TCPAddr)
+	address, ok := addr.(*net.TCPAddr)
+	if !ok {
+		// the unix domain socket listener is found by the socket path
+		if listener := handler.FindListenerByAddress(addr); listener != nil {
+			return listener
+		}
+		log.DefaultLogger.Errorf("[network] [transfer] Find Listener failed %v", addr)
+		return nil
+	}
 	port := strconv.FormatInt(int64(address.Port), 10)
 	ipv4, _ := net.ResolveTCPAddr("tcp", "0.0.0.0:"+port)
 	ipv6, _ := net.ResolveTCPAddr("tcp", "[::]:"+port)
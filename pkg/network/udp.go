@@ -0,0 +1,164 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+)
+
+const (
+	// MaxDatagramSize is the max size of a udp datagram
+	MaxDatagramSize = 64 * 1024
+	// udpSessionQueueSize is the max datagrams queued in a session, the datagrams are dropped if the queue is full
+	udpSessionQueueSize = 64
+)
+
+var errUDPSessionClosed = errors.New("udp session closed")
+
+// udpTimeoutError is returned if the read deadline exceeded,
+// it is also used to stop reading at the end of a datagram
+type udpTimeoutError struct{}
+
+func (e udpTimeoutError) Error() string   { return "i/o timeout" }
+func (e udpTimeoutError) Timeout() bool   { return true }
+func (e udpTimeoutError) Temporary() bool { return true }
+
+// udpConn is the net.Conn of a udp session, which is identified by the remote address.
+// The datagrams are received by the listener socket and dispatched to the session,
+// the Reads never return the bytes of two datagrams in one buffer read, so each
+// read of the connection gets a whole datagram. Each Write sends a datagram to the
+// remote address through the listener socket.
+type udpConn struct {
+	conn       *net.UDPConn
+	remoteAddr *net.UDPAddr
+	onClose    func(c *udpConn)
+
+	packets      chan []byte
+	pending      []byte
+	boundary     bool
+	readDeadline atomic.Value
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newUDPConn(conn *net.UDPConn, remoteAddr *net.UDPAddr, onClose func(c *udpConn)) *udpConn {
+	return &udpConn{
+		conn:       conn,
+		remoteAddr: remoteAddr,
+		onClose:    onClose,
+		packets:    make(chan []byte, udpSessionQueueSize),
+		closed:     make(chan struct{}),
+	}
+}
+
+// dispatch queues a datagram received by the listener
+func (c *udpConn) dispatch(data []byte) {
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	select {
+	case c.packets <- data:
+	default:
+		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
+			log.DefaultLogger.Debugf("[network] [udp] session %s queue is full, drop the datagram", c.remoteAddr)
+		}
+	}
+}
+
+// Read reads a datagram. If the buffer is too small, the rest of the datagram is returned by the
+// following reads, and a timeout error is returned after the buffer is exactly filled by the end
+// of a datagram, so the IoBuffer stops reading at the datagram boundary
+func (c *udpConn) Read(b []byte) (int, error) {
+	if c.boundary {
+		c.boundary = false
+		return 0, udpTimeoutError{}
+	}
+	if len(c.pending) == 0 {
+		var timeout <-chan time.Time
+		if deadline, _ := c.readDeadline.Load().(time.Time); !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case c.pending = <-c.packets:
+		case <-timeout:
+			return 0, udpTimeoutError{}
+		case <-c.closed:
+			return 0, io.EOF
+		}
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	if len(c.pending) == 0 && n == len(b) {
+		c.boundary = true
+	}
+	return n, nil
+}
+
+func (c *udpConn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, errUDPSessionClosed
+	default:
+	}
+	return c.conn.WriteToUDP(b, c.remoteAddr)
+}
+
+// Close closes the session, the listener socket is not closed
+func (c *udpConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		if c.onClose != nil {
+			c.onClose(c)
+		}
+	})
+	return nil
+}
+
+func (c *udpConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *udpConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *udpConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *udpConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Store(t)
+	return nil
+}
+
+// SetWriteDeadline is ignored, the datagrams are written without blocking
+func (c *udpConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,191 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package network
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/types"
+)
+
+type udpEventListener struct {
+	conns chan net.Conn
+}
+
+func (e *udpEventListener) OnAccept(rawc net.Conn, useOriginalDst bool, oriRemoteAddr net.Addr, c chan types.Connection, buf []byte) {
+	e.conns <- rawc
+}
+
+func (e *udpEventListener) OnNewConnection(ctx context.Context, conn types.Connection) {}
+
+func (e *udpEventListener) OnClose() {}
+
+func startUDPListener(t *testing.T, address string, reusePort bool) (types.Listener, *udpEventListener) {
+	addr, _ := net.ResolveUDPAddr("udp", address)
+	cfg := &v2.Listener{
+		ListenerConfig: v2.ListenerConfig{
+			Name:       "test_udp_listener",
+			Network:    v2.NetworkUDP,
+			BindToPort: true,
+			ReusePort:  reusePort,
+		},
+		Addr: addr,
+	}
+	cb := &udpEventListener{conns: make(chan net.Conn, 8)}
+	ln := NewListener(cfg)
+	ln.SetListenerCallbacks(cb)
+	go ln.Start(nil, false)
+	time.Sleep(100 * time.Millisecond)
+	return ln, cb
+}
+
+func acceptUDPSession(t *testing.T, cb *udpEventListener) net.Conn {
+	select {
+	case conn := <-cb.conns:
+		return conn
+	case <-time.After(time.Second):
+		t.Fatal("no udp session accepted")
+	}
+	return nil
+}
+
+func TestUDPListenerSessions(t *testing.T) {
+	ln, cb := startUDPListener(t, "127.0.0.1:10102", false)
+	defer ln.Close(nil)
+
+	client, err := net.Dial("udp", "127.0.0.1:10102")
+	if err != nil {
+		t.Fatalf("dial udp listener failed: %v", err)
+	}
+	defer client.Close()
+	client.Write([]byte("hello"))
+	client.Write([]byte("world"))
+
+	session := acceptUDPSession(t, cb)
+	if session.RemoteAddr().String() != client.LocalAddr().String() {
+		t.Fatalf("session remote address is %s, expected %s", session.RemoteAddr(), client.LocalAddr())
+	}
+	// datagrams of the same client are dispatched to the same session, one read for one datagram
+	buf := make([]byte, 1024)
+	for _, expected := range []string{"hello", "world"} {
+		n, err := session.Read(buf)
+		if err != nil || string(buf[:n]) != expected {
+			t.Fatalf("read datagram got %q, %v, expected %q", buf[:n], err, expected)
+		}
+	}
+	select {
+	case <-cb.conns:
+		t.Fatal("a new session is accepted for the same client")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// writes are sent back to the client
+	if _, err := session.Write([]byte("pong")); err != nil {
+		t.Fatalf("write session failed: %v", err)
+	}
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := client.Read(buf)
+	if err != nil || string(buf[:n]) != "pong" {
+		t.Fatalf("client read got %q, %v", buf[:n], err)
+	}
+
+	// another client gets another session
+	other, err := net.Dial("udp", "127.0.0.1:10102")
+	if err != nil {
+		t.Fatalf("dial udp listener failed: %v", err)
+	}
+	defer other.Close()
+	other.Write([]byte("other"))
+	if s := acceptUDPSession(t, cb); s.RemoteAddr().String() != other.LocalAddr().String() {
+		t.Fatalf("session remote address is %s, expected %s", s.RemoteAddr(), other.LocalAddr())
+	}
+
+	// a closed session returns EOF, and the next datagram of the client creates a new session
+	session.Close()
+	if _, err := session.Read(buf); err == nil {
+		t.Fatal("read closed session succeed")
+	}
+	client.Write([]byte("again"))
+	session = acceptUDPSession(t, cb)
+	n, err = session.Read(buf)
+	if err != nil || string(buf[:n]) != "again" {
+		t.Fatalf("read datagram got %q, %v", buf[:n], err)
+	}
+}
+
+func TestUDPSessionDatagramBoundary(t *testing.T) {
+	ln, cb := startUDPListener(t, "127.0.0.1:10103", false)
+	defer ln.Close(nil)
+
+	client, err := net.Dial("udp", "127.0.0.1:10103")
+	if err != nil {
+		t.Fatalf("dial udp listener failed: %v", err)
+	}
+	defer client.Close()
+	large := bytes.Repeat([]byte("a"), 1<<15)
+	client.Write(large)
+	client.Write([]byte("small"))
+
+	session := acceptUDPSession(t, cb)
+	// the connection reads with a buffer of the same size as the large datagram,
+	// the read must stop at the end of the datagram instead of reading the next one
+	b := buffer.NewIoBuffer(1 << 15)
+	session.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := b.ReadOnce(session); err != nil {
+		if te, ok := err.(net.Error); !ok || !te.Timeout() {
+			t.Fatalf("read large datagram failed: %v", err)
+		}
+	}
+	if b.Len() != len(large) {
+		t.Fatalf("read %d bytes, expected %d", b.Len(), len(large))
+	}
+	b.Drain(b.Len())
+	session.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := b.ReadOnce(session); err != nil {
+		if te, ok := err.(net.Error); !ok || !te.Timeout() {
+			t.Fatalf("read small datagram failed: %v", err)
+		}
+	}
+	if b.String() != "small" {
+		t.Fatalf("read %q, expected small", b.String())
+	}
+}
+
+func TestUDPListenerReusePort(t *testing.T) {
+	ln1, _ := startUDPListener(t, "127.0.0.1:10104", true)
+	defer ln1.Close(nil)
+	ln2, cb2 := startUDPListener(t, "127.0.0.1:10104", true)
+	defer ln2.Close(nil)
+
+	// both listeners bind the port, the datagrams are received by the second one after the first is closed
+	ln1.Close(nil)
+	time.Sleep(100 * time.Millisecond)
+	client, err := net.Dial("udp", "127.0.0.1:10104")
+	if err != nil {
+		t.Fatalf("dial udp listener failed: %v", err)
+	}
+	defer client.Close()
+	client.Write([]byte("hello"))
+	acceptUDPSession(t, cb2)
+}
@@ -0,0 +1,231 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// Fields of the common OTLP messages, see opentelemetry/proto/common/v1/common.proto
+// and opentelemetry/proto/resource/v1/resource.proto
+const (
+	// KeyValue
+	fieldKeyValueKey   = 1
+	fieldKeyValueValue = 2
+	// AnyValue
+	fieldAnyValueString = 1
+	fieldAnyValueBool   = 2
+	fieldAnyValueInt    = 3
+	// Resource
+	fieldResourceAttributes = 1
+	// InstrumentationScope
+	fieldScopeName    = 1
+	fieldScopeVersion = 2
+)
+
+// Encoder is a minimal protobuf wire format encoder, which is enough to build the OTLP requests
+// without the generated codes. Fields with zero value are omitted as proto3 does.
+type Encoder struct {
+	buf *proto.Buffer
+}
+
+// NewEncoder returns an empty Encoder
+func NewEncoder() *Encoder {
+	return &Encoder{
+		buf: proto.NewBuffer(nil),
+	}
+}
+
+// Bytes returns the encoded message
+func (e *Encoder) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+func (e *Encoder) tag(field int, wire int) {
+	e.buf.EncodeVarint(uint64(field)<<3 | uint64(wire))
+}
+
+// String encodes a string field
+func (e *Encoder) String(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf.EncodeStringBytes(s)
+}
+
+// RawBytes encodes a bytes field
+func (e *Encoder) RawBytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf.EncodeRawBytes(b)
+}
+
+// Uint64 encodes a varint field, such as uint32, uint64 and enum
+func (e *Encoder) Uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf.EncodeVarint(v)
+}
+
+// Int64 encodes an int64 field
+func (e *Encoder) Int64(field int, v int64) {
+	e.Uint64(field, uint64(v))
+}
+
+// Bool encodes a bool field
+func (e *Encoder) Bool(field int, v bool) {
+	if v {
+		e.Uint64(field, 1)
+	}
+}
+
+// Fixed64 encodes a fixed64 or sfixed64 field
+func (e *Encoder) Fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireFixed64)
+	e.buf.EncodeFixed64(v)
+}
+
+// Double encodes a double field
+func (e *Encoder) Double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireFixed64)
+	e.buf.EncodeFixed64(math.Float64bits(v))
+}
+
+// OptionalFixed64 encodes a fixed64 or sfixed64 field with explicit presence, such as a oneof field.
+// zero value is kept
+func (e *Encoder) OptionalFixed64(field int, v uint64) {
+	e.tag(field, wireFixed64)
+	e.buf.EncodeFixed64(v)
+}
+
+// OptionalDouble encodes a double field with explicit presence, zero value is kept
+func (e *Encoder) OptionalDouble(field int, v float64) {
+	e.tag(field, wireFixed64)
+	e.buf.EncodeFixed64(math.Float64bits(v))
+}
+
+// PackedFixed64 encodes a packed repeated fixed64 field
+func (e *Encoder) PackedFixed64(field int, vs []uint64) {
+	if len(vs) == 0 {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf.EncodeVarint(uint64(len(vs) * 8))
+	for _, v := range vs {
+		e.buf.EncodeFixed64(v)
+	}
+}
+
+// PackedDouble encodes a packed repeated double field
+func (e *Encoder) PackedDouble(field int, vs []float64) {
+	if len(vs) == 0 {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf.EncodeVarint(uint64(len(vs) * 8))
+	for _, v := range vs {
+		e.buf.EncodeFixed64(math.Float64bits(v))
+	}
+}
+
+// Message encodes an embedded message field, the message is written by f.
+// an empty message is still encoded, because the presence of a message may be meaningful
+func (e *Encoder) Message(field int, f func(e *Encoder)) {
+	sub := NewEncoder()
+	f(sub)
+	e.tag(field, wireBytes)
+	e.buf.EncodeRawBytes(sub.Bytes())
+}
+
+// Attributes encodes a repeated KeyValue field with string values, in sorted key order
+func (e *Encoder) Attributes(field int, attrs map[string]string) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.StringAttribute(field, k, attrs[k])
+	}
+}
+
+// StringAttribute encodes a KeyValue field with a string value
+func (e *Encoder) StringAttribute(field int, key, value string) {
+	e.Message(field, func(kv *Encoder) {
+		kv.String(fieldKeyValueKey, key)
+		kv.Message(fieldKeyValueValue, func(v *Encoder) {
+			v.String(fieldAnyValueString, value)
+		})
+	})
+}
+
+// IntAttribute encodes a KeyValue field with an int value
+func (e *Encoder) IntAttribute(field int, key string, value int64) {
+	e.Message(field, func(kv *Encoder) {
+		kv.String(fieldKeyValueKey, key)
+		kv.Message(fieldKeyValueValue, func(v *Encoder) {
+			v.Int64(fieldAnyValueInt, value)
+		})
+	})
+}
+
+// BoolAttribute encodes a KeyValue field with a bool value
+func (e *Encoder) BoolAttribute(field int, key string, value bool) {
+	e.Message(field, func(kv *Encoder) {
+		kv.String(fieldKeyValueKey, key)
+		kv.Message(fieldKeyValueValue, func(v *Encoder) {
+			v.Bool(fieldAnyValueBool, value)
+		})
+	})
+}
+
+// Resource encodes a Resource message field
+func (e *Encoder) Resource(field int, attrs map[string]string) {
+	e.Message(field, func(r *Encoder) {
+		r.Attributes(fieldResourceAttributes, attrs)
+	})
+}
+
+// Scope encodes an InstrumentationScope message field
+func (e *Encoder) Scope(field int, name, version string) {
+	e.Message(field, func(s *Encoder) {
+		s.String(fieldScopeName, name)
+		s.String(fieldScopeVersion, version)
+	})
+}
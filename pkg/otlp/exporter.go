@@ -0,0 +1,266 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/mtls"
+)
+
+// export protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+var defaultTimeout = 10 * time.Second
+
+// Signal describes the OTLP endpoints of a kind of telemetry data
+type Signal struct {
+	// GRPCMethod is the full method name of the collector service
+	GRPCMethod string
+	// HTTPPath is the default url path of OTLP/HTTP
+	HTTPPath string
+}
+
+var (
+	// SignalMetrics is the OTLP metrics signal
+	SignalMetrics = Signal{
+		GRPCMethod: "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+		HTTPPath:   "/v1/metrics",
+	}
+	// SignalTraces is the OTLP traces signal
+	SignalTraces = Signal{
+		GRPCMethod: "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+		HTTPPath:   "/v1/traces",
+	}
+)
+
+// Config contains the config of an OTLP exporter
+type Config struct {
+	Protocol string            `json:"protocol"` // grpc or http, default is grpc
+	Endpoint string            `json:"endpoint"` // host:port for grpc, url for http
+	Headers  map[string]string `json:"headers"`
+	Timeout  v2.DurationConfig `json:"timeout"`
+	// TLS is used when the status is true, otherwise the connection is plaintext
+	TLS v2.TLSConfig `json:"tls"`
+}
+
+// Exporter sends the serialized OTLP export requests to the collector
+type Exporter struct {
+	config *Config
+	signal Signal
+
+	// grpc
+	conn *grpc.ClientConn
+	// http
+	client *http.Client
+	url    string
+}
+
+// NewExporter creates an exporter of the signal, the config defaults are filled in
+func NewExporter(config *Config, signal Signal) (*Exporter, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("otlp endpoint is not specified")
+	}
+	if config.Timeout.Duration <= 0 {
+		config.Timeout.Duration = defaultTimeout
+	}
+	tlsConfig, err := NewTLSConfig(&config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Exporter{
+		config: config,
+		signal: signal,
+	}
+	switch config.Protocol {
+	case "", ProtocolGRPC:
+		config.Protocol = ProtocolGRPC
+		opt := grpc.WithInsecure()
+		if tlsConfig != nil {
+			opt = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+		}
+		// the connection is established in background
+		conn, err := grpc.Dial(config.Endpoint, opt)
+		if err != nil {
+			return nil, fmt.Errorf("dial otlp endpoint %s failed: %v", config.Endpoint, err)
+		}
+		e.conn = conn
+	case ProtocolHTTP:
+		u, err := httpURL(config.Endpoint, tlsConfig != nil, signal)
+		if err != nil {
+			return nil, err
+		}
+		e.url = u
+		e.client = &http.Client{
+			Timeout: config.Timeout.Duration,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol: %s", config.Protocol)
+	}
+	return e, nil
+}
+
+// httpURL returns the url to post, the signal path is appended if the endpoint has no path
+func httpURL(endpoint string, secure bool, signal Signal) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		if secure {
+			endpoint = "https://" + endpoint
+		} else {
+			endpoint = "http://" + endpoint
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid otlp endpoint %s: %v", endpoint, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = signal.HTTPPath
+	}
+	return u.String(), nil
+}
+
+// Export sends a serialized export request
+func (e *Exporter) Export(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.Timeout.Duration)
+	defer cancel()
+	if e.conn != nil {
+		return e.exportGRPC(ctx, body)
+	}
+	return e.exportHTTP(ctx, body)
+}
+
+func (e *Exporter) exportGRPC(ctx context.Context, body []byte) error {
+	if len(e.config.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.config.Headers))
+	}
+	// the response is ignored, partial success is not reported
+	var resp []byte
+	return e.conn.Invoke(ctx, e.signal.GRPCMethod, &body, &resp, grpc.ForceCodec(RawCodec{}))
+}
+
+func (e *Exporter) exportHTTP(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp collector responses status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close releases the connections
+func (e *Exporter) Close() {
+	if e.conn != nil {
+		e.conn.Close()
+	}
+	if e.client != nil {
+		if t, ok := e.client.Transport.(*http.Transport); ok {
+			t.CloseIdleConnections()
+		}
+	}
+}
+
+// NewTLSConfig creates a client tls config, the certificates can be file paths or pem strings.
+// nil is returned if the tls is not enabled
+func NewTLSConfig(cfg *v2.TLSConfig) (*tls.Config, error) {
+	if !cfg.Status {
+		return nil, nil
+	}
+	hooks := mtls.DefaultConfigHooks()
+	pool, err := hooks.GetX509Pool(cfg.CACert)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.ServerName,
+		RootCAs:            pool,
+		InsecureSkipVerify: cfg.InsecureSkip,
+	}
+	if cfg.CertChain != "" || cfg.PrivateKey != "" {
+		var cert tls.Certificate
+		if strings.Contains(cfg.CertChain, "-----BEGIN") && strings.Contains(cfg.PrivateKey, "-----BEGIN") {
+			cert, err = tls.X509KeyPair([]byte(cfg.CertChain), []byte(cfg.PrivateKey))
+		} else {
+			cert, err = tls.LoadX509KeyPair(cfg.CertChain, cfg.PrivateKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate error: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// RawCodec sends and receives the serialized messages directly,
+// it is used to call the grpc services without the generated codes
+type RawCodec struct{}
+
+func (RawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec: unexpected type %T", v)
+	}
+	return *b, nil
+}
+
+func (RawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec: unexpected type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name returns proto, so the content-type is application/grpc+proto
+func (RawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,59 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"testing"
+)
+
+func TestHTTPURL(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		secure   bool
+		expected string
+	}{
+		{"127.0.0.1:4318", false, "http://127.0.0.1:4318/v1/metrics"},
+		{"collector:4318", true, "https://collector:4318/v1/metrics"},
+		{"http://collector:4318/", false, "http://collector:4318/v1/metrics"},
+		{"http://collector:4318/otlp/metrics", false, "http://collector:4318/otlp/metrics"},
+	}
+	for _, tc := range testCases {
+		u, err := httpURL(tc.endpoint, tc.secure, SignalMetrics)
+		if err != nil || u != tc.expected {
+			t.Errorf("endpoint %s expected %s, but got %s, error: %v", tc.endpoint, tc.expected, u, err)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	e := NewEncoder()
+	// zero values are omitted
+	e.String(1, "")
+	e.Uint64(2, 0)
+	e.Fixed64(3, 0)
+	if len(e.Bytes()) != 0 {
+		t.Fatalf("zero values should be omitted, but got %v", e.Bytes())
+	}
+	e.String(1, "a")
+	e.Uint64(2, 300)
+	e.OptionalFixed64(3, 0)
+	expected := []byte{0x0a, 0x01, 'a', 0x10, 0xac, 0x02, 0x19, 0, 0, 0, 0, 0, 0, 0, 0}
+	if string(e.Bytes()) != string(expected) {
+		t.Errorf("expected %v, but got %v", expected, e.Bytes())
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bridge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/protocol/rpc"
+	"mosn.io/mosn/pkg/protocol/rpc/sofarpc"
+	"mosn.io/mosn/pkg/protocol/sofarpc/models"
+	"mosn.io/mosn/pkg/types"
+)
+
+func init() {
+	protocol.RegisterMessageConv(protocol.HTTP1, protocol.SofaRPC, &http2bolt{})
+	protocol.RegisterMessageConv(protocol.SofaRPC, protocol.HTTP1, &bolt2http{})
+}
+
+// sofa rpc constants
+const (
+	SofaRequestClass  = "com.alipay.sofa.rpc.core.request.SofaRequest"
+	SofaResponseClass = "com.alipay.sofa.rpc.core.response.SofaResponse"
+
+	// DefaultSofaVersion is the default version of a sofa rpc service
+	DefaultSofaVersion = "1.0"
+)
+
+// http -> bolt converter, the http request is converted into a bolt v1 request
+// with the SofaRequest and the arguments serialized in hessian2
+type http2bolt struct{}
+
+func (c *http2bolt) ConvMessage(ctx context.Context, headerMap types.HeaderMap, data types.IoBuffer) (types.HeaderMap, types.IoBuffer, error) {
+	headers, ok := headerMap.(http.RequestHeader)
+	if !ok {
+		return nil, nil, protocol.ErrUnsupportedMessage
+	}
+	// the request has the bolt fields in headers, which is converted by the common path
+	if _, ok := headers.Get(sofarpc.SofaPropertyHeader(sofarpc.HeaderProtocolCode)); ok {
+		return nil, nil, protocol.ErrUnsupportedMessage
+	}
+
+	inv, err := parseInvocation(headers, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := encodeSofaRequest(inv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := &sofarpc.BoltRequest{
+		Protocol:     sofarpc.PROTOCOL_CODE_V1,
+		CmdType:      sofarpc.REQUEST,
+		CmdCode:      sofarpc.RPC_REQUEST,
+		Version:      sofarpc.PROTOCOL_VERSION_1,
+		Codec:        sofarpc.HESSIAN2_SERIALIZE,
+		Timeout:      -1,
+		ContentLen:   len(content),
+		RequestClass: SofaRequestClass,
+		RequestHeader: map[string]string{
+			models.SERVICE_KEY:        inv.uniqueName(),
+			models.TARGET_SERVICE_KEY: inv.uniqueName(),
+			models.TARGET_METHOD:      inv.method,
+		},
+	}
+	return request, buffer.NewIoBufferBytes(content), nil
+}
+
+// uniqueName returns the unique name of a sofa rpc service, the group is used as the unique id
+func (inv *invocation) uniqueName() string {
+	version := inv.version
+	if version == "" {
+		version = DefaultSofaVersion
+	}
+	name := inv.service + ":" + version
+	if inv.group != "" {
+		name += ":" + inv.group
+	}
+	return name
+}
+
+// encodeSofaRequest encodes the SofaRequest followed by the arguments
+func encodeSofaRequest(inv *invocation) ([]byte, error) {
+	e := newHessianEncoder()
+	request := &javaObject{
+		class:  SofaRequestClass,
+		fields: []string{"targetServiceUniqueName", "methodName", "methodArgSigs", "requestProps"},
+		values: []interface{}{
+			inv.uniqueName(),
+			inv.method,
+			typedValue{javaType: "java.lang.String[]", value: inv.types()},
+			nil,
+		},
+	}
+	if err := e.Encode(request); err != nil {
+		return nil, err
+	}
+	if err := inv.encodeArgs(e); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// bolt -> http converter, the SofaResponse is converted into json
+type bolt2http struct{}
+
+func (c *bolt2http) ConvMessage(ctx context.Context, headerMap types.HeaderMap, data types.IoBuffer) (types.HeaderMap, types.IoBuffer, error) {
+	cmd, ok := headerMap.(sofarpc.SofaRpcCmd)
+	if !ok || cmd.CommandType() != sofarpc.RESPONSE {
+		return nil, nil, protocol.ErrUnsupportedMessage
+	}
+	if data == nil {
+		data = cmd.Data()
+	}
+	var content []byte
+	if data != nil {
+		content = data.Bytes()
+	}
+
+	if status, ok := cmd.(rpc.RespStatus); ok && int16(status.RespStatus()) != sofarpc.RESPONSE_STATUS_SUCCESS {
+		code, _ := protocol.MappingHeaderStatusCode(protocol.SofaRPC, cmd)
+		message := fmt.Sprintf("rpc response status %d", status.RespStatus())
+		// the content of a failed response is the server exception if it exists
+		if len(content) > 0 {
+			if exception, err := newHessianDecoder(content).Decode(); err == nil && exception != nil {
+				message = exceptionMessage(exception)
+			}
+		}
+		return errorResponse(code, message)
+	}
+
+	value, err := newHessianDecoder(content).Decode()
+	if err != nil {
+		return errorResponse(fasthttp.StatusBadGateway, "decode rpc response failed: "+err.Error())
+	}
+	response, ok := value.(map[string]interface{})
+	if !ok || response[ClassKey] != SofaResponseClass {
+		return errorResponse(fasthttp.StatusBadGateway, fmt.Sprintf("unexpected rpc response %v", value))
+	}
+	if isError, _ := response["isError"].(bool); isError {
+		message, _ := response["errorMsg"].(string)
+		return errorResponse(fasthttp.StatusInternalServerError, message)
+	}
+	appResponse := response["appResponse"]
+	if isException(appResponse) {
+		return errorResponse(fasthttp.StatusInternalServerError, exceptionMessage(appResponse))
+	}
+	return jsonResponse(fasthttp.StatusOK, appResponse)
+}
@@ -0,0 +1,192 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bridge bridges http requests to the rpc protocols. The rpc service and method of a http request
+// are described in the headers or the path, and the arguments are a json array in the body. The request is
+// converted into a rpc invocation with the arguments serialized in hessian2, and the rpc response is converted
+// back to json with the rpc status mapped to the http status, so that the clients that do not speak the rpc
+// protocols can call the rpc services through mosn.
+//
+// The hessian2 values are encoded with the codec of dubbogo, the bridge only adds the java types of the
+// arguments and the class definitions of the generic invocation, and works around the doubles and strings
+// that dubbogo encodes wrongly. The responses are decoded by the bridge itself: dubbogo decodes the objects
+// into the registered go structs only, while the bridge must turn the objects of any class into json.
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/types"
+)
+
+// The headers of a http request that describe the rpc invocation, besides the
+// x-mosn-rpc-service and x-mosn-rpc-method headers
+const (
+	// HeaderRPCVersion is the version of the rpc service
+	HeaderRPCVersion = "x-mosn-rpc-version"
+	// HeaderRPCGroup is the group of the rpc service
+	HeaderRPCGroup = "x-mosn-rpc-group"
+	// HeaderRPCArgTypes is the comma separated java types of the arguments
+	HeaderRPCArgTypes = "x-mosn-rpc-arg-types"
+)
+
+const contentTypeJSON = "application/json"
+
+var (
+	ErrNoService        = errors.New("no rpc service and method in the http request")
+	ErrInvalidArguments = errors.New("the http body is not a json array of the arguments")
+	ErrArgTypesMismatch = errors.New("the argument types do not match the arguments")
+)
+
+// invocation is the rpc invocation described by a http request
+type invocation struct {
+	service  string
+	method   string
+	version  string
+	group    string
+	argTypes []string
+	args     []interface{}
+}
+
+// parseInvocation parses the invocation from a http request, the service and the method are in the
+// rpc headers or in the path as /{service}/{method}. The argument types are inferred from the json
+// values if they are not in the headers.
+func parseInvocation(headers http.RequestHeader, data types.IoBuffer) (*invocation, error) {
+	inv := &invocation{}
+	inv.service, _ = headers.Get(types.HeaderRPCService)
+	inv.method, _ = headers.Get(types.HeaderRPCMethod)
+	if inv.service == "" || inv.method == "" {
+		path, _ := headers.Get(protocol.MosnHeaderPathKey)
+		segments := strings.Split(strings.Trim(path, "/"), "/")
+		if len(segments) != 2 || segments[0] == "" || segments[1] == "" {
+			return nil, ErrNoService
+		}
+		inv.service, inv.method = segments[0], segments[1]
+	}
+	inv.version, _ = headers.Get(HeaderRPCVersion)
+	inv.group, _ = headers.Get(HeaderRPCGroup)
+
+	if data != nil && len(bytes.TrimSpace(data.Bytes())) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(data.Bytes()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&inv.args); err != nil || decoder.More() {
+			return nil, ErrInvalidArguments
+		}
+	}
+
+	if argTypes, ok := headers.Get(HeaderRPCArgTypes); ok && argTypes != "" {
+		for _, t := range strings.Split(argTypes, ",") {
+			inv.argTypes = append(inv.argTypes, strings.TrimSpace(t))
+		}
+		if len(inv.argTypes) != len(inv.args) {
+			return nil, ErrArgTypesMismatch
+		}
+	}
+	return inv, nil
+}
+
+// types returns the java types of the arguments
+func (inv *invocation) types() []string {
+	if inv.argTypes != nil {
+		return inv.argTypes
+	}
+	argTypes := make([]string, len(inv.args))
+	for i, arg := range inv.args {
+		argTypes[i] = inferJavaType(arg)
+	}
+	return argTypes
+}
+
+// encodeArgs encodes the arguments as their java types
+func (inv *invocation) encodeArgs(e *hessianEncoder) error {
+	argTypes := inv.types()
+	for i, arg := range inv.args {
+		if err := e.EncodeTyped(arg, argTypes[i]); err != nil {
+			return fmt.Errorf("encode argument %d failed: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// jsonResponse builds a http response with the value in json, the error is always nil
+// so that a converter can return it directly
+func jsonResponse(status int, value interface{}) (types.HeaderMap, types.IoBuffer, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		status = fasthttp.StatusBadGateway
+		body, _ = json.Marshal(errorBody("encode response failed: " + err.Error()))
+	}
+	headers := http.ResponseHeader{ResponseHeader: &fasthttp.ResponseHeader{}}
+	headers.Set(types.HeaderStatus, strconv.Itoa(status))
+	headers.Set("Content-Type", contentTypeJSON)
+	return headers, buffer.NewIoBufferBytes(body), nil
+}
+
+// errorResponse builds a http response with the error message in json
+func errorResponse(status int, message string) (types.HeaderMap, types.IoBuffer, error) {
+	return jsonResponse(status, errorBody(message))
+}
+
+func errorBody(message string) map[string]string {
+	return map[string]string{"error": message}
+}
+
+// isException returns true if the decoded value is a java exception, all the throwables have the stack trace
+func isException(value interface{}) bool {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = object["stackTrace"]
+	return ok && object[ClassKey] != nil
+}
+
+// exceptionMessage returns the message of a decoded java exception
+func exceptionMessage(exception interface{}) string {
+	switch value := exception.(type) {
+	case string:
+		return value
+	case map[string]interface{}:
+		class, _ := value[ClassKey].(string)
+		// the exception of a dubbo generic invocation
+		if message, ok := value["exceptionMessage"].(string); ok {
+			if exceptionClass, ok := value["exceptionClass"].(string); ok {
+				return exceptionClass + ": " + message
+			}
+			return message
+		}
+		if message, ok := value["detailMessage"].(string); ok && message != "" {
+			if class == "" {
+				return message
+			}
+			return class + ": " + message
+		}
+		if class != "" {
+			return class
+		}
+	}
+	return fmt.Sprint(exception)
+}
@@ -0,0 +1,441 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bridge
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/buffer"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/protocol/rpc/sofarpc"
+	"mosn.io/mosn/pkg/protocol/rpc/xprotocol/dubbo"
+	"mosn.io/mosn/pkg/protocol/sofarpc/models"
+	"mosn.io/mosn/pkg/types"
+)
+
+func newRequest(headers map[string]string, body string) (types.HeaderMap, types.IoBuffer) {
+	h := http.RequestHeader{RequestHeader: &fasthttp.RequestHeader{}}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	var data types.IoBuffer
+	if body != "" {
+		data = buffer.NewIoBufferString(body)
+	}
+	return h, data
+}
+
+// decodeAll decodes all the hessian values in data
+func decodeAll(t *testing.T, data []byte) []interface{} {
+	d := newHessianDecoder(data)
+	var values []interface{}
+	for !d.Done() {
+		value, err := d.Decode()
+		if err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// checkResponse checks the status and the json body of a converted response
+func checkResponse(t *testing.T, headers types.HeaderMap, data types.IoBuffer, status int, expected interface{}) {
+	code, _ := headers.Get(types.HeaderStatus)
+	if code != strconv.Itoa(status) {
+		t.Fatalf("response status is %s, expected %d, body: %s", code, status, data.String())
+	}
+	if contentType, _ := headers.Get("Content-Type"); contentType != contentTypeJSON {
+		t.Fatalf("unexpected content type %s", contentType)
+	}
+	var body interface{}
+	if err := json.Unmarshal(data.Bytes(), &body); err != nil {
+		t.Fatalf("response body %s is not json: %v", data.String(), err)
+	}
+	if !reflect.DeepEqual(body, expected) {
+		t.Fatalf("response body is %v, expected %v", body, expected)
+	}
+}
+
+func TestParseInvocation(t *testing.T) {
+	testCases := []struct {
+		headers  map[string]string
+		body     string
+		expected *invocation
+		err      error
+	}{
+		{
+			headers:  map[string]string{protocol.MosnHeaderPathKey: "/com.foo.HelloService/sayHello"},
+			body:     `["mosn", 1]`,
+			expected: &invocation{service: "com.foo.HelloService", method: "sayHello", args: []interface{}{"mosn", json.Number("1")}},
+		},
+		{
+			headers: map[string]string{
+				protocol.MosnHeaderPathKey: "/ignored",
+				types.HeaderRPCService:     "com.foo.HelloService",
+				types.HeaderRPCMethod:      "sayHello",
+				HeaderRPCVersion:           "2.0",
+				HeaderRPCGroup:             "gray",
+				HeaderRPCArgTypes:          "java.lang.String, long",
+			},
+			body: `["mosn", 1]`,
+			expected: &invocation{
+				service:  "com.foo.HelloService",
+				method:   "sayHello",
+				version:  "2.0",
+				group:    "gray",
+				argTypes: []string{"java.lang.String", "long"},
+				args:     []interface{}{"mosn", json.Number("1")},
+			},
+		},
+		{
+			headers:  map[string]string{protocol.MosnHeaderPathKey: "/com.foo.HelloService/ping"},
+			expected: &invocation{service: "com.foo.HelloService", method: "ping"},
+		},
+		{
+			headers: map[string]string{protocol.MosnHeaderPathKey: "/com.foo.HelloService"},
+			err:     ErrNoService,
+		},
+		{
+			headers: map[string]string{protocol.MosnHeaderPathKey: "/com.foo.HelloService/sayHello"},
+			body:    `{"name": "mosn"}`,
+			err:     ErrInvalidArguments,
+		},
+		{
+			headers: map[string]string{protocol.MosnHeaderPathKey: "/com.foo.HelloService/sayHello"},
+			body:    `[1] [2]`,
+			err:     ErrInvalidArguments,
+		},
+		{
+			headers: map[string]string{
+				protocol.MosnHeaderPathKey: "/com.foo.HelloService/sayHello",
+				HeaderRPCArgTypes:          "int,int",
+			},
+			body: `[1]`,
+			err:  ErrArgTypesMismatch,
+		},
+	}
+	for i, tc := range testCases {
+		headers, data := newRequest(tc.headers, tc.body)
+		inv, err := parseInvocation(headers.(http.RequestHeader), data)
+		if err != tc.err {
+			t.Fatalf("case %d: expected error %v, but got %v", i, tc.err, err)
+		}
+		if !reflect.DeepEqual(inv, tc.expected) {
+			t.Fatalf("case %d: parsed %+v, expected %+v", i, inv, tc.expected)
+		}
+	}
+}
+
+func TestHTTPToBolt(t *testing.T) {
+	headers, data := newRequest(map[string]string{
+		protocol.MosnHeaderPathKey: "/com.foo.HelloService/sayHello",
+		HeaderRPCGroup:             "gray",
+	}, `["mosn", 1, {"class": "com.foo.User", "name": "mosn"}]`)
+	cmd, content, err := protocol.ConvertMessage(context.Background(), protocol.HTTP1, protocol.SofaRPC, headers, data)
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+	request, ok := cmd.(*sofarpc.BoltRequest)
+	if !ok {
+		t.Fatalf("converted into %T, not a bolt request", cmd)
+	}
+	if request.CmdType != sofarpc.REQUEST || request.CmdCode != sofarpc.RPC_REQUEST ||
+		request.Codec != sofarpc.HESSIAN2_SERIALIZE || request.ContentLen != content.Len() ||
+		request.RequestClass != SofaRequestClass {
+		t.Fatalf("unexpected bolt request %+v", request)
+	}
+	if service := request.RequestHeader[models.SERVICE_KEY]; service != "com.foo.HelloService:1.0:gray" {
+		t.Fatalf("unexpected service %s", service)
+	}
+	if method := request.RequestHeader[models.TARGET_METHOD]; method != "sayHello" {
+		t.Fatalf("unexpected method %s", method)
+	}
+
+	values := decodeAll(t, content.Bytes())
+	expected := []interface{}{
+		map[string]interface{}{
+			ClassKey:                  SofaRequestClass,
+			"targetServiceUniqueName": "com.foo.HelloService:1.0:gray",
+			"methodName":              "sayHello",
+			"methodArgSigs":           []interface{}{"java.lang.String", "int", "com.foo.User"},
+			"requestProps":            nil,
+		},
+		"mosn",
+		int64(1),
+		map[string]interface{}{ClassKey: "com.foo.User", "name": "mosn"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("unexpected content %#v", values)
+	}
+}
+
+func TestHTTPToBoltUnsupported(t *testing.T) {
+	// the bolt request in headers is converted by the header converter
+	headers, data := newRequest(map[string]string{
+		sofarpc.SofaPropertyHeader(sofarpc.HeaderProtocolCode): "1",
+	}, "")
+	if _, _, err := protocol.ConvertMessage(context.Background(), protocol.HTTP1, protocol.SofaRPC, headers, data); err != protocol.ErrUnsupportedMessage {
+		t.Fatalf("expected unsupported message, but got %v", err)
+	}
+	headers, data = newRequest(map[string]string{protocol.MosnHeaderPathKey: "/"}, "")
+	if _, _, err := protocol.ConvertMessage(context.Background(), protocol.HTTP1, protocol.SofaRPC, headers, data); err != ErrNoService {
+		t.Fatalf("expected no service, but got %v", err)
+	}
+}
+
+func newBoltResponse(t *testing.T, status int16, content interface{}) *sofarpc.BoltResponse {
+	response := &sofarpc.BoltResponse{
+		Protocol:       sofarpc.PROTOCOL_CODE_V1,
+		CmdType:        sofarpc.RESPONSE,
+		CmdCode:        sofarpc.RPC_RESPONSE,
+		Codec:          sofarpc.HESSIAN2_SERIALIZE,
+		ResponseStatus: status,
+	}
+	if content != nil {
+		e := newHessianEncoder()
+		if err := e.Encode(content); err != nil {
+			t.Fatal(err)
+		}
+		response.Content = buffer.NewIoBufferBytes(e.Bytes())
+		response.ContentLen = len(e.Bytes())
+	}
+	return response
+}
+
+func sofaResponse(isError bool, errorMsg interface{}, appResponse interface{}) *javaObject {
+	return &javaObject{
+		class:  SofaResponseClass,
+		fields: []string{"isError", "errorMsg", "appResponse", "responseProps"},
+		values: []interface{}{isError, errorMsg, appResponse, nil},
+	}
+}
+
+func TestBoltToHTTP(t *testing.T) {
+	exception := map[string]interface{}{
+		ClassKey:        "java.lang.IllegalArgumentException",
+		"detailMessage": "bad name",
+		"stackTrace":    []interface{}{},
+	}
+	testCases := []struct {
+		response *sofarpc.BoltResponse
+		status   int
+		body     interface{}
+	}{
+		{
+			response: newBoltResponse(t, sofarpc.RESPONSE_STATUS_SUCCESS, sofaResponse(false, nil, "hello mosn")),
+			status:   fasthttp.StatusOK,
+			body:     "hello mosn",
+		},
+		{
+			response: newBoltResponse(t, sofarpc.RESPONSE_STATUS_SUCCESS, sofaResponse(false, nil,
+				map[string]interface{}{ClassKey: "com.foo.User", "name": "mosn", "age": json.Number("3")})),
+			status: fasthttp.StatusOK,
+			body:   map[string]interface{}{ClassKey: "com.foo.User", "name": "mosn", "age": float64(3)},
+		},
+		{
+			response: newBoltResponse(t, sofarpc.RESPONSE_STATUS_SUCCESS, sofaResponse(true, "no such method", nil)),
+			status:   fasthttp.StatusInternalServerError,
+			body:     map[string]interface{}{"error": "no such method"},
+		},
+		{
+			response: newBoltResponse(t, sofarpc.RESPONSE_STATUS_SUCCESS, sofaResponse(false, nil, exception)),
+			status:   fasthttp.StatusInternalServerError,
+			body:     map[string]interface{}{"error": "java.lang.IllegalArgumentException: bad name"},
+		},
+		{
+			response: newBoltResponse(t, sofarpc.RESPONSE_STATUS_TIMEOUT, nil),
+			status:   fasthttp.StatusGatewayTimeout,
+			body:     map[string]interface{}{"error": "rpc response status 7"},
+		},
+		{
+			response: newBoltResponse(t, sofarpc.RESPONSE_STATUS_SERVER_EXCEPTION, exception),
+			status:   fasthttp.StatusInternalServerError,
+			body:     map[string]interface{}{"error": "java.lang.IllegalArgumentException: bad name"},
+		},
+		{
+			response: newBoltResponse(t, sofarpc.RESPONSE_STATUS_SUCCESS, "not a sofa response"),
+			status:   fasthttp.StatusBadGateway,
+			body:     map[string]interface{}{"error": "unexpected rpc response not a sofa response"},
+		},
+	}
+	for i, tc := range testCases {
+		headers, data, err := protocol.ConvertMessage(context.Background(), protocol.SofaRPC, protocol.HTTP1, tc.response, tc.response.Content)
+		if err != nil {
+			t.Fatalf("case %d: convert failed: %v", i, err)
+		}
+		checkResponse(t, headers, data, tc.status, tc.body)
+	}
+}
+
+func dubboContext() context.Context {
+	return mosnctx.WithValue(context.Background(), types.ContextSubProtocol, DubboSubProtocol)
+}
+
+func TestHTTPToDubbo(t *testing.T) {
+	headers, data := newRequest(map[string]string{
+		types.HeaderRPCService: "com.foo.HelloService",
+		types.HeaderRPCMethod:  "sayHello",
+		HeaderRPCVersion:       "1.0.0",
+		HeaderRPCArgTypes:      "java.lang.String,int",
+	}, `["mosn", 1]`)
+	// the dubbo converter only works for the dubbo sub protocol
+	if _, _, err := protocol.ConvertMessage(context.Background(), protocol.HTTP1, protocol.Xprotocol, headers, data); err != protocol.ErrUnsupportedMessage {
+		t.Fatalf("expected unsupported message, but got %v", err)
+	}
+
+	converted, frame, err := protocol.ConvertMessage(dubboContext(), protocol.HTTP1, protocol.Xprotocol, headers, data)
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+	if service, _ := converted.Get(types.HeaderRPCService); service != "com.foo.HelloService" {
+		t.Fatalf("unexpected service %s", service)
+	}
+	if method, _ := converted.Get(types.HeaderRPCMethod); method != "sayHello" {
+		t.Fatalf("unexpected method %s", method)
+	}
+
+	b := frame.Bytes()
+	if b[0] != dubbo.DUBBO_MAGIC_TAG[0] || b[1] != dubbo.DUBBO_MAGIC_TAG[1] || b[dubbo.DUBBO_FLAG_IDX] != dubboRequestFlag {
+		t.Fatalf("unexpected dubbo header %v", b[:dubbo.DUBBO_HEADER_LEN])
+	}
+	if bodyLen := int(binary.BigEndian.Uint32(b[dubbo.DUBBO_DATA_LEN_IDX:])); bodyLen != len(b)-dubbo.DUBBO_HEADER_LEN {
+		t.Fatalf("unexpected body length %d", bodyLen)
+	}
+	values := decodeAll(t, b[dubbo.DUBBO_HEADER_LEN:])
+	expected := []interface{}{
+		DubboVersion,
+		"com.foo.HelloService",
+		"1.0.0",
+		DubboGenericMethod,
+		DubboGenericDesc,
+		"sayHello",
+		[]interface{}{"java.lang.String", "int"},
+		[]interface{}{"mosn", int64(1)},
+		map[string]interface{}{
+			"path":      "com.foo.HelloService",
+			"interface": "com.foo.HelloService",
+			"generic":   "true",
+			"version":   "1.0.0",
+		},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("unexpected dubbo body %#v", values)
+	}
+}
+
+func newDubboResponse(t *testing.T, status byte, values ...interface{}) types.IoBuffer {
+	header := make([]byte, dubbo.DUBBO_HEADER_LEN)
+	copy(header, dubbo.DUBBO_MAGIC_TAG)
+	header[dubbo.DUBBO_FLAG_IDX] = 0x02
+	header[dubbo.DUBBO_STATUS_IDX] = status
+	e := newHessianEncoder()
+	e.Append(header...)
+	for _, value := range values {
+		if err := e.Encode(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frame := e.Bytes()
+	binary.BigEndian.PutUint32(frame[dubbo.DUBBO_DATA_LEN_IDX:], uint32(len(frame)-dubbo.DUBBO_HEADER_LEN))
+	return buffer.NewIoBufferBytes(frame)
+}
+
+func TestDubboToHTTP(t *testing.T) {
+	testCases := []struct {
+		frame  types.IoBuffer
+		status int
+		body   interface{}
+	}{
+		{
+			frame:  newDubboResponse(t, dubboStatusOK, dubboResponseValue, "hello mosn"),
+			status: fasthttp.StatusOK,
+			body:   "hello mosn",
+		},
+		{
+			frame:  newDubboResponse(t, dubboStatusOK, dubboResponseValueWithAttachments, []interface{}{"a", "b"}, map[string]string{"k": "v"}),
+			status: fasthttp.StatusOK,
+			body:   []interface{}{"a", "b"},
+		},
+		{
+			frame:  newDubboResponse(t, dubboStatusOK, dubboResponseNullValue),
+			status: fasthttp.StatusOK,
+			body:   nil,
+		},
+		{
+			frame: newDubboResponse(t, dubboStatusOK, dubboResponseWithException, map[string]interface{}{
+				ClassKey:           "com.alibaba.dubbo.rpc.service.GenericException",
+				"exceptionClass":   "java.lang.IllegalStateException",
+				"exceptionMessage": "not ready",
+			}),
+			status: fasthttp.StatusInternalServerError,
+			body:   map[string]interface{}{"error": "java.lang.IllegalStateException: not ready"},
+		},
+		{
+			frame:  newDubboResponse(t, dubboStatusServiceNotFound, "service com.foo.HelloService not found"),
+			status: fasthttp.StatusNotFound,
+			body:   map[string]interface{}{"error": "service com.foo.HelloService not found"},
+		},
+		{
+			frame:  newDubboResponse(t, dubboStatusServerTimeout),
+			status: fasthttp.StatusGatewayTimeout,
+			body:   map[string]interface{}{"error": "dubbo response status 31"},
+		},
+		{
+			frame:  newDubboResponse(t, dubboStatusOK, 9),
+			status: fasthttp.StatusBadGateway,
+			body:   map[string]interface{}{"error": "unknown dubbo response type 9"},
+		},
+		{
+			frame:  buffer.NewIoBufferString("not a dubbo frame"),
+			status: fasthttp.StatusBadGateway,
+			body:   map[string]interface{}{"error": "invalid dubbo response"},
+		},
+	}
+	for i, tc := range testCases {
+		headers, data, err := protocol.ConvertMessage(dubboContext(), protocol.Xprotocol, protocol.HTTP1, protocol.CommonHeader{}, tc.frame)
+		if err != nil {
+			t.Fatalf("case %d: convert failed: %v", i, err)
+		}
+		checkResponse(t, headers, data, tc.status, tc.body)
+	}
+}
+
+func TestDubboStatusToHTTP(t *testing.T) {
+	for status, code := range map[byte]int{
+		dubboStatusOK:                      fasthttp.StatusOK,
+		dubboStatusClientTimeout:           fasthttp.StatusGatewayTimeout,
+		dubboStatusBadRequest:              fasthttp.StatusBadRequest,
+		dubboStatusBadResponse:             fasthttp.StatusBadGateway,
+		dubboStatusServiceError:            fasthttp.StatusInternalServerError,
+		dubboStatusServerThreadpoolExhaust: fasthttp.StatusServiceUnavailable,
+		1:                                  fasthttp.StatusBadGateway,
+	} {
+		if dubboStatusToHTTP(status) != code {
+			t.Fatalf("status %d is mapped to %d, expected %d", status, dubboStatusToHTTP(status), code)
+		}
+	}
+}
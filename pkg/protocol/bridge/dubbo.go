@@ -0,0 +1,226 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bridge
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/buffer"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/protocol/rpc/xprotocol/dubbo"
+	"mosn.io/mosn/pkg/types"
+)
+
+func init() {
+	protocol.RegisterMessageConv(protocol.HTTP1, protocol.Xprotocol, &http2dubbo{})
+	protocol.RegisterMessageConv(protocol.Xprotocol, protocol.HTTP1, &dubbo2http{})
+}
+
+// dubbo constants
+const (
+	DubboSubProtocol = "dubbo"
+	DubboVersion     = "2.0.2"
+
+	// the generic invocation, $invoke(String method, String[] parameterTypes, Object[] args)
+	DubboGenericMethod = "$invoke"
+	DubboGenericDesc   = "Ljava/lang/String;[Ljava/lang/String;[Ljava/lang/Object;"
+
+	// request, two way and hessian2 serialization
+	dubboRequestFlag byte = 0xc2
+)
+
+// dubbo response status
+const (
+	dubboStatusOK                      = 20
+	dubboStatusClientTimeout           = 30
+	dubboStatusServerTimeout           = 31
+	dubboStatusBadRequest              = 40
+	dubboStatusBadResponse             = 50
+	dubboStatusServiceNotFound         = 60
+	dubboStatusServiceError            = 70
+	dubboStatusServerError             = 80
+	dubboStatusClientError             = 90
+	dubboStatusServerThreadpoolExhaust = 100
+)
+
+// dubbo response types
+const (
+	dubboResponseWithException = iota
+	dubboResponseValue
+	dubboResponseNullValue
+	dubboResponseWithExceptionWithAttachments
+	dubboResponseValueWithAttachments
+	dubboResponseNullValueWithAttachments
+)
+
+func isDubbo(ctx context.Context) bool {
+	subProtocol, _ := mosnctx.Get(ctx, types.ContextSubProtocol).(string)
+	return subProtocol == DubboSubProtocol
+}
+
+// http -> dubbo converter, the http request is converted into a dubbo generic invocation
+type http2dubbo struct{}
+
+func (c *http2dubbo) ConvMessage(ctx context.Context, headerMap types.HeaderMap, data types.IoBuffer) (types.HeaderMap, types.IoBuffer, error) {
+	headers, ok := headerMap.(http.RequestHeader)
+	if !ok || !isDubbo(ctx) {
+		return nil, nil, protocol.ErrUnsupportedMessage
+	}
+
+	inv, err := parseInvocation(headers, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	frame, err := encodeDubboRequest(inv)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the headers are used by the xprotocol stream for the attachments
+	dubboHeaders := protocol.CommonHeader{
+		types.HeaderRPCService: inv.service,
+		types.HeaderRPCMethod:  inv.method,
+	}
+	return dubboHeaders, buffer.NewIoBufferBytes(frame), nil
+}
+
+// encodeDubboRequest encodes the generic invocation frame, the request id is set by the stream
+func encodeDubboRequest(inv *invocation) ([]byte, error) {
+	header := make([]byte, dubbo.DUBBO_HEADER_LEN)
+	copy(header, dubbo.DUBBO_MAGIC_TAG)
+	header[dubbo.DUBBO_FLAG_IDX] = dubboRequestFlag
+	e := newHessianEncoder()
+	e.Append(header...)
+
+	e.writeString(DubboVersion)
+	e.writeString(inv.service)
+	e.writeString(inv.version)
+	e.writeString(DubboGenericMethod)
+	e.writeString(DubboGenericDesc)
+
+	e.writeString(inv.method)
+	// the method is found by the name if the types are absent
+	if inv.argTypes == nil {
+		e.Encode(nil)
+	} else if err := e.EncodeTyped(inv.argTypes, "java.lang.String[]"); err != nil {
+		return nil, err
+	}
+	e.writeListHeader(hessianArrayType("java.lang.Object"), len(inv.args))
+	if inv.argTypes == nil {
+		for i, arg := range inv.args {
+			if err := e.Encode(arg); err != nil {
+				return nil, fmt.Errorf("encode argument %d failed: %v", i, err)
+			}
+		}
+	} else if err := inv.encodeArgs(e); err != nil {
+		return nil, err
+	}
+
+	attachments := map[string]string{
+		"path":      inv.service,
+		"interface": inv.service,
+		"generic":   "true",
+	}
+	if inv.version != "" {
+		attachments["version"] = inv.version
+	}
+	if inv.group != "" {
+		attachments["group"] = inv.group
+	}
+	if err := e.Encode(attachments); err != nil {
+		return nil, err
+	}
+
+	frame := e.Bytes()
+	binary.BigEndian.PutUint32(frame[dubbo.DUBBO_DATA_LEN_IDX:], uint32(len(frame)-dubbo.DUBBO_HEADER_LEN))
+	return frame, nil
+}
+
+// dubbo -> http converter, the dubbo response is converted into json
+type dubbo2http struct{}
+
+func (c *dubbo2http) ConvMessage(ctx context.Context, headerMap types.HeaderMap, data types.IoBuffer) (types.HeaderMap, types.IoBuffer, error) {
+	if _, ok := headerMap.(protocol.CommonHeader); !ok || !isDubbo(ctx) || data == nil {
+		return nil, nil, protocol.ErrUnsupportedMessage
+	}
+
+	frame := data.Bytes()
+	if len(frame) < dubbo.DUBBO_HEADER_LEN || frame[0] != dubbo.DUBBO_MAGIC_TAG[0] || frame[1] != dubbo.DUBBO_MAGIC_TAG[1] {
+		return errorResponse(fasthttp.StatusBadGateway, "invalid dubbo response")
+	}
+	payload := frame[dubbo.DUBBO_HEADER_LEN:]
+	if bodyLen := int(binary.BigEndian.Uint32(frame[dubbo.DUBBO_DATA_LEN_IDX:])); bodyLen < len(payload) {
+		payload = payload[:bodyLen]
+	}
+
+	decoder := newHessianDecoder(payload)
+	if status := frame[dubbo.DUBBO_STATUS_IDX]; status != dubboStatusOK {
+		// the body of a failed response is the error message
+		message := fmt.Sprintf("dubbo response status %d", status)
+		if value, err := decoder.Decode(); err == nil && value != nil {
+			message = exceptionMessage(value)
+		}
+		return errorResponse(dubboStatusToHTTP(status), message)
+	}
+
+	responseType, err := decoder.Decode()
+	if err != nil {
+		return errorResponse(fasthttp.StatusBadGateway, "decode dubbo response failed: "+err.Error())
+	}
+	var value interface{}
+	switch responseType {
+	case int64(dubboResponseNullValue), int64(dubboResponseNullValueWithAttachments):
+	case int64(dubboResponseValue), int64(dubboResponseValueWithAttachments),
+		int64(dubboResponseWithException), int64(dubboResponseWithExceptionWithAttachments):
+		if value, err = decoder.Decode(); err != nil {
+			return errorResponse(fasthttp.StatusBadGateway, "decode dubbo response failed: "+err.Error())
+		}
+	default:
+		return errorResponse(fasthttp.StatusBadGateway, fmt.Sprintf("unknown dubbo response type %v", responseType))
+	}
+
+	if responseType == int64(dubboResponseWithException) || responseType == int64(dubboResponseWithExceptionWithAttachments) {
+		return errorResponse(fasthttp.StatusInternalServerError, exceptionMessage(value))
+	}
+	return jsonResponse(fasthttp.StatusOK, value)
+}
+
+// dubboStatusToHTTP maps the dubbo response status to the http status
+func dubboStatusToHTTP(status byte) int {
+	switch status {
+	case dubboStatusOK:
+		return fasthttp.StatusOK
+	case dubboStatusClientTimeout, dubboStatusServerTimeout:
+		return fasthttp.StatusGatewayTimeout
+	case dubboStatusBadRequest:
+		return fasthttp.StatusBadRequest
+	case dubboStatusServiceNotFound:
+		return fasthttp.StatusNotFound
+	case dubboStatusServerThreadpoolExhaust:
+		return fasthttp.StatusServiceUnavailable
+	case dubboStatusBadResponse, dubboStatusClientError:
+		return fasthttp.StatusBadGateway
+	case dubboStatusServiceError, dubboStatusServerError:
+		return fasthttp.StatusInternalServerError
+	}
+	return fasthttp.StatusBadGateway
+}
@@ -0,0 +1,875 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bridge
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/AlexStocks/dubbogo/codec/hessian"
+)
+
+// The hessian2 serialization of the values decoded from json, and the values decoded
+// from hessian2 that can be encoded to json. Objects are represented as maps with the
+// java class name in the "class" key, which is the same as the dubbo generic invocation.
+
+const (
+	// ClassKey is the json key of the java class name of an object
+	ClassKey = "class"
+
+	maxStringChunk = 0x8000
+	maxDecodeDepth = 64
+)
+
+var (
+	ErrHessianIncomplete = errors.New("hessian data is incomplete")
+	ErrHessianTooDeep    = errors.New("hessian data is nested too deep")
+)
+
+// typedValue is a value encoded as the given java type
+type typedValue struct {
+	javaType string
+	value    interface{}
+}
+
+// javaObject is an object with the fields in order
+type javaObject struct {
+	class  string
+	fields []string
+	values []interface{}
+}
+
+// hessianEncoder adds the typed values and the class definitions of the dubbo generic
+// invocation to the hessian encoder of dubbogo, which encodes the plain values.
+type hessianEncoder struct {
+	enc     *hessian.Encoder
+	classes map[string]int
+}
+
+func newHessianEncoder() *hessianEncoder {
+	return &hessianEncoder{
+		enc:     hessian.NewEncoder(),
+		classes: make(map[string]int),
+	}
+}
+
+func (e *hessianEncoder) Bytes() []byte {
+	return e.enc.Buffer()
+}
+
+// Append appends the raw bytes, such as a protocol header
+func (e *hessianEncoder) Append(b ...byte) {
+	e.enc.Append(b)
+}
+
+// Encode encodes the value, the java types of the numbers are inferred from the values
+func (e *hessianEncoder) Encode(v interface{}) error {
+	switch value := v.(type) {
+	case nil, bool, int32, int, int64:
+		return e.enc.Encode(value)
+	case float64:
+		e.writeDouble(value)
+	case json.Number:
+		return e.EncodeTyped(value, inferJavaType(value))
+	case string:
+		e.writeString(value)
+	case []string:
+		e.writeListHeader("", len(value))
+		for _, s := range value {
+			e.writeString(s)
+		}
+	case []interface{}:
+		e.writeListHeader("", len(value))
+		for _, elem := range value {
+			if err := e.Encode(elem); err != nil {
+				return err
+			}
+		}
+	case map[string]string:
+		// the map is written here so that the keys are sorted and an empty map is not lost
+		e.Append('H')
+		for _, k := range sortedKeys(value) {
+			e.writeString(k)
+			e.writeString(value[k])
+		}
+		e.Append('Z')
+	case map[string]interface{}:
+		if class, ok := value[ClassKey].(string); ok {
+			return e.encodeObject(class, value)
+		}
+		e.Append('H')
+		for _, k := range sortedKeys(value) {
+			e.writeString(k)
+			if err := e.Encode(value[k]); err != nil {
+				return err
+			}
+		}
+		e.Append('Z')
+	case *javaObject:
+		e.writeObjectHeader(value.class, value.fields)
+		for _, fv := range value.values {
+			if err := e.Encode(fv); err != nil {
+				return err
+			}
+		}
+	case typedValue:
+		return e.EncodeTyped(value.value, value.javaType)
+	default:
+		return fmt.Errorf("unsupported hessian type %T", v)
+	}
+	return nil
+}
+
+// EncodeTyped encodes the value as the java type, it is encoded as Encode if the
+// java type is not a number, a boolean, a string or an array.
+func (e *hessianEncoder) EncodeTyped(v interface{}, javaType string) error {
+	if v == nil {
+		return e.enc.Encode(nil)
+	}
+	if elemType := strings.TrimSuffix(javaType, "[]"); elemType != javaType {
+		var elems []interface{}
+		switch value := v.(type) {
+		case []interface{}:
+			elems = value
+		case []string:
+			for _, s := range value {
+				elems = append(elems, s)
+			}
+		default:
+			return fmt.Errorf("%v is not a %s", v, javaType)
+		}
+		e.writeListHeader(hessianArrayType(elemType), len(elems))
+		for _, elem := range elems {
+			if err := e.EncodeTyped(elem, elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	number, isNumber := v.(json.Number)
+	switch javaType {
+	case "int", "short", "byte", "java.lang.Integer", "java.lang.Short", "java.lang.Byte":
+		if isNumber {
+			i, err := strconv.ParseInt(string(number), 10, 32)
+			if err != nil {
+				return fmt.Errorf("%s is not a %s", number, javaType)
+			}
+			return e.enc.Encode(int32(i))
+		}
+	case "long", "java.lang.Long":
+		if isNumber {
+			i, err := strconv.ParseInt(string(number), 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s is not a %s", number, javaType)
+			}
+			return e.enc.Encode(i)
+		}
+	case "float", "double", "java.lang.Float", "java.lang.Double":
+		if isNumber {
+			f, err := number.Float64()
+			if err != nil {
+				return fmt.Errorf("%s is not a %s", number, javaType)
+			}
+			e.writeDouble(f)
+			return nil
+		}
+	case "char", "java.lang.Character", "java.lang.String":
+		if isNumber {
+			e.writeString(string(number))
+			return nil
+		}
+	}
+	if isNumber {
+		// the type is an object or unknown
+		return e.EncodeTyped(number, inferJavaType(number))
+	}
+	return e.Encode(v)
+}
+
+func (e *hessianEncoder) encodeObject(class string, m map[string]interface{}) error {
+	fields := make([]string, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		if k != ClassKey {
+			fields = append(fields, k)
+		}
+	}
+	e.writeObjectHeader(class, fields)
+	for _, f := range fields {
+		if err := e.Encode(m[f]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeObjectHeader writes the class definition once and the reference to it, the
+// encoder of dubbogo can only write the objects of the registered go structs.
+func (e *hessianEncoder) writeObjectHeader(class string, fields []string) {
+	key := class + ":" + strings.Join(fields, ",")
+	ref, ok := e.classes[key]
+	if !ok {
+		ref = len(e.classes)
+		e.classes[key] = ref
+		e.Append('C')
+		e.writeString(class)
+		e.enc.Encode(int32(len(fields)))
+		for _, f := range fields {
+			e.writeString(f)
+		}
+	}
+	if ref <= 0x0f {
+		e.Append(byte(0x60 + ref))
+	} else {
+		e.Append('O')
+		e.enc.Encode(int32(ref))
+	}
+}
+
+// writeListHeader writes the header of a fixed length list, the list is untyped if typ is empty
+func (e *hessianEncoder) writeListHeader(typ string, length int) {
+	if typ == "" {
+		if length <= 7 {
+			e.Append(byte(0x78 + length))
+		} else {
+			e.Append('X')
+			e.enc.Encode(int32(length))
+		}
+		return
+	}
+	if length <= 7 {
+		e.Append(byte(0x70 + length))
+		e.writeString(typ)
+	} else {
+		e.Append('V')
+		e.writeString(typ)
+		e.enc.Encode(int32(length))
+	}
+}
+
+// writeDouble writes the doubles that dubbogo gets wrong: it writes the short doubles
+// with the byte tag and drops the sign of the negative zero.
+func (e *hessianEncoder) writeDouble(v float64) {
+	switch {
+	case v == 0 && math.Signbit(v):
+		e.Append('D', 0x80, 0, 0, 0, 0, 0, 0, 0)
+	case v == math.Trunc(v) && (v < math.MinInt8 || v > math.MaxInt8) &&
+		math.MinInt16 <= v && v <= math.MaxInt16:
+		e.Append(0x5e, byte(int16(v)>>8), byte(int16(v)))
+	default:
+		e.enc.Encode(v)
+	}
+}
+
+// writeString writes the string in chunks, the length of a chunk is the count of the java
+// chars. dubbogo counts the runes, which is wrong for the chars out of the BMP.
+func (e *hessianEncoder) writeString(s string) {
+	for {
+		chars, end := 0, 0
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			n := 1
+			if r >= 0x10000 {
+				n = 2
+			}
+			if chars+n > maxStringChunk {
+				break
+			}
+			chars += n
+			end += size
+		}
+		if end < len(s) {
+			e.Append('R', byte(chars>>8), byte(chars))
+			e.Append([]byte(s[:end])...)
+			s = s[end:]
+			continue
+		}
+		switch {
+		case chars <= 0x1f:
+			e.Append(byte(chars))
+		case chars <= 0x3ff:
+			e.Append(byte(0x30+(chars>>8)), byte(chars))
+		default:
+			e.Append('S', byte(chars>>8), byte(chars))
+		}
+		e.Append([]byte(s)...)
+		return
+	}
+}
+
+// hessianArrayType returns the hessian type name of a java array
+func hessianArrayType(elemType string) string {
+	switch elemType {
+	case "boolean", "byte", "short", "int", "long", "float", "double", "char":
+		return "[" + elemType
+	case "java.lang.String":
+		return "[string"
+	case "java.lang.Object":
+		return "[object"
+	}
+	return "[" + elemType
+}
+
+// inferJavaType returns the java type of a value decoded from json
+func inferJavaType(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "java.lang.Object"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			if math.MinInt32 <= i && i <= math.MaxInt32 {
+				return "int"
+			}
+			return "long"
+		}
+		return "double"
+	case string:
+		return "java.lang.String"
+	case []interface{}:
+		return "java.util.List"
+	case map[string]interface{}:
+		if class, ok := value[ClassKey].(string); ok {
+			return class
+		}
+		return "java.util.Map"
+	}
+	return "java.lang.Object"
+}
+
+type classDef struct {
+	name   string
+	fields []string
+}
+
+type hessianDecoder struct {
+	buf     []byte
+	pos     int
+	depth   int
+	refs    []interface{}
+	types   []string
+	classes []classDef
+}
+
+func newHessianDecoder(buf []byte) *hessianDecoder {
+	return &hessianDecoder{
+		buf: buf,
+	}
+}
+
+// Done returns true if all the data is decoded
+func (d *hessianDecoder) Done() bool {
+	return d.pos >= len(d.buf)
+}
+
+// Decode decodes a value. The integers are decoded as int64, the maps and the objects are
+// decoded as map[string]interface{}, so that the values can be encoded to json directly.
+func (d *hessianDecoder) Decode() (interface{}, error) {
+	if d.depth >= maxDecodeDepth {
+		return nil, ErrHessianTooDeep
+	}
+	d.depth++
+	defer func() {
+		d.depth--
+	}()
+
+	tag, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	// the class definitions are followed by the object
+	for tag == 'C' {
+		if err := d.readClassDef(); err != nil {
+			return nil, err
+		}
+		if tag, err = d.readByte(); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case tag <= 0x1f, 0x30 <= tag && tag <= 0x33, tag == 'R', tag == 'S':
+		return d.readString(tag)
+	case 0x20 <= tag && tag <= 0x2f, 0x34 <= tag && tag <= 0x37, tag == 'A', tag == 'B':
+		return d.readBinary(tag)
+	case 0x38 <= tag && tag <= 0x3f, 0xd8 <= tag, tag == 0x59, tag == 'L':
+		return d.readLong(tag)
+	case 0x80 <= tag && tag <= 0xd7, tag == 'I':
+		i, err := d.readInt(tag)
+		return int64(i), err
+	case 0x5b <= tag && tag <= 0x5f, tag == 'D':
+		return d.readDouble(tag)
+	case tag == 'N':
+		return nil, nil
+	case tag == 'T':
+		return true, nil
+	case tag == 'F':
+		return false, nil
+	case tag == 0x4a:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(0, int64(binary.BigEndian.Uint64(b))*int64(time.Millisecond)), nil
+	case tag == 0x4b:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(int64(int32(binary.BigEndian.Uint32(b)))*60, 0), nil
+	case tag == 'H':
+		return d.readMap()
+	case tag == 'M':
+		if _, err := d.readType(); err != nil {
+			return nil, err
+		}
+		return d.readMap()
+	case tag == 'U', tag == 'V', tag == 'W', tag == 'X', 0x70 <= tag && tag <= 0x7f:
+		return d.readList(tag)
+	case tag == 'O':
+		ref, err := d.readInt(0)
+		if err != nil {
+			return nil, err
+		}
+		return d.readObject(int(ref))
+	case 0x60 <= tag && tag <= 0x6f:
+		return d.readObject(int(tag - 0x60))
+	case tag == 'Q':
+		ref, err := d.readInt(0)
+		if err != nil {
+			return nil, err
+		}
+		if ref < 0 || int(ref) >= len(d.refs) {
+			return nil, fmt.Errorf("hessian reference %d is out of range", ref)
+		}
+		return d.refs[ref], nil
+	}
+	return nil, fmt.Errorf("unknown hessian tag 0x%02x", tag)
+}
+
+func (d *hessianDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, ErrHessianIncomplete
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *hessianDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, ErrHessianIncomplete
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readInt reads an int, the tag is read from the data if it is 0
+func (d *hessianDecoder) readInt(tag byte) (int32, error) {
+	if tag == 0 {
+		var err error
+		if tag, err = d.readByte(); err != nil {
+			return 0, err
+		}
+	}
+	switch {
+	case 0x80 <= tag && tag <= 0xbf:
+		return int32(tag) - 0x90, nil
+	case 0xc0 <= tag && tag <= 0xcf:
+		b, err := d.next(1)
+		if err != nil {
+			return 0, err
+		}
+		return (int32(tag)-0xc8)<<8 | int32(b[0]), nil
+	case 0xd0 <= tag && tag <= 0xd7:
+		b, err := d.next(2)
+		if err != nil {
+			return 0, err
+		}
+		return (int32(tag)-0xd4)<<16 | int32(b[0])<<8 | int32(b[1]), nil
+	case tag == 'I':
+		b, err := d.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int32(binary.BigEndian.Uint32(b)), nil
+	}
+	return 0, fmt.Errorf("hessian tag 0x%02x is not an int", tag)
+}
+
+func (d *hessianDecoder) readLong(tag byte) (int64, error) {
+	switch {
+	case 0xd8 <= tag && tag <= 0xef:
+		return int64(tag) - 0xe0, nil
+	case 0xf0 <= tag:
+		b, err := d.next(1)
+		if err != nil {
+			return 0, err
+		}
+		return (int64(tag)-0xf8)<<8 | int64(b[0]), nil
+	case 0x38 <= tag && tag <= 0x3f:
+		b, err := d.next(2)
+		if err != nil {
+			return 0, err
+		}
+		return (int64(tag)-0x3c)<<16 | int64(b[0])<<8 | int64(b[1]), nil
+	case tag == 0x59:
+		b, err := d.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int64(int32(binary.BigEndian.Uint32(b))), nil
+	}
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (d *hessianDecoder) readDouble(tag byte) (float64, error) {
+	switch tag {
+	case 0x5b:
+		return 0, nil
+	case 0x5c:
+		return 1, nil
+	case 0x5d:
+		b, err := d.next(1)
+		if err != nil {
+			return 0, err
+		}
+		return float64(int8(b[0])), nil
+	case 0x5e:
+		b, err := d.next(2)
+		if err != nil {
+			return 0, err
+		}
+		return float64(int16(binary.BigEndian.Uint16(b))), nil
+	case 0x5f:
+		// the double in milliseconds
+		b, err := d.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return float64(int32(binary.BigEndian.Uint32(b))) * 0.001, nil
+	}
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+// readString reads the chunks of a string, the length of a chunk is the count of the java chars
+func (d *hessianDecoder) readString(tag byte) (string, error) {
+	var chars []uint16
+	for {
+		var length int
+		final := true
+		switch {
+		case tag <= 0x1f:
+			length = int(tag)
+		case 0x30 <= tag && tag <= 0x33:
+			b, err := d.next(1)
+			if err != nil {
+				return "", err
+			}
+			length = int(tag-0x30)<<8 | int(b[0])
+		case tag == 'R', tag == 'S':
+			b, err := d.next(2)
+			if err != nil {
+				return "", err
+			}
+			length = int(binary.BigEndian.Uint16(b))
+			final = tag == 'S'
+		default:
+			return "", fmt.Errorf("hessian tag 0x%02x is not a string chunk", tag)
+		}
+		var err error
+		if chars, err = d.readChars(chars, length); err != nil {
+			return "", err
+		}
+		if final {
+			return string(utf16.Decode(chars)), nil
+		}
+		if tag, err = d.readByte(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// readChars reads the utf-8 encoded java chars, a surrogate pair may be encoded as two chars
+func (d *hessianDecoder) readChars(chars []uint16, length int) ([]uint16, error) {
+	for n := 0; n < length; {
+		c, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		var r rune
+		switch {
+		case c < 0x80:
+			r = rune(c)
+		case c&0xe0 == 0xc0:
+			b, err := d.next(1)
+			if err != nil {
+				return nil, err
+			}
+			r = rune(c&0x1f)<<6 | rune(b[0]&0x3f)
+		case c&0xf0 == 0xe0:
+			b, err := d.next(2)
+			if err != nil {
+				return nil, err
+			}
+			r = rune(c&0x0f)<<12 | rune(b[0]&0x3f)<<6 | rune(b[1]&0x3f)
+		case c&0xf8 == 0xf0:
+			b, err := d.next(3)
+			if err != nil {
+				return nil, err
+			}
+			r = rune(c&0x07)<<18 | rune(b[0]&0x3f)<<12 | rune(b[1]&0x3f)<<6 | rune(b[2]&0x3f)
+		default:
+			return nil, fmt.Errorf("invalid utf-8 byte 0x%02x in hessian string", c)
+		}
+		if r >= 0x10000 {
+			r1, r2 := utf16.EncodeRune(r)
+			chars = append(chars, uint16(r1), uint16(r2))
+			n += 2
+		} else {
+			chars = append(chars, uint16(r))
+			n++
+		}
+	}
+	return chars, nil
+}
+
+func (d *hessianDecoder) readBinary(tag byte) ([]byte, error) {
+	var data []byte
+	for {
+		var length int
+		final := true
+		switch {
+		case 0x20 <= tag && tag <= 0x2f:
+			length = int(tag - 0x20)
+		case 0x34 <= tag && tag <= 0x37:
+			b, err := d.next(1)
+			if err != nil {
+				return nil, err
+			}
+			length = int(tag-0x34)<<8 | int(b[0])
+		case tag == 'A', tag == 'B':
+			b, err := d.next(2)
+			if err != nil {
+				return nil, err
+			}
+			length = int(binary.BigEndian.Uint16(b))
+			final = tag == 'B'
+		default:
+			return nil, fmt.Errorf("hessian tag 0x%02x is not a binary chunk", tag)
+		}
+		b, err := d.next(length)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b...)
+		if final {
+			return data, nil
+		}
+		if tag, err = d.readByte(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readType reads the type of a list or a map, which is a string or a reference to a read type
+func (d *hessianDecoder) readType() (string, error) {
+	tag, err := d.readByte()
+	if err != nil {
+		return "", err
+	}
+	if tag <= 0x1f || 0x30 <= tag && tag <= 0x33 || tag == 'R' || tag == 'S' {
+		typ, err := d.readString(tag)
+		if err != nil {
+			return "", err
+		}
+		d.types = append(d.types, typ)
+		return typ, nil
+	}
+	ref, err := d.readInt(tag)
+	if err != nil {
+		return "", err
+	}
+	if ref < 0 || int(ref) >= len(d.types) {
+		return "", fmt.Errorf("hessian type reference %d is out of range", ref)
+	}
+	return d.types[ref], nil
+}
+
+func (d *hessianDecoder) readList(tag byte) (interface{}, error) {
+	length := -1
+	var err error
+	switch {
+	case tag == 'U':
+		_, err = d.readType()
+	case tag == 'V':
+		if _, err = d.readType(); err == nil {
+			var l int32
+			l, err = d.readInt(0)
+			length = int(l)
+		}
+	case tag == 'X':
+		var l int32
+		l, err = d.readInt(0)
+		length = int(l)
+	case 0x70 <= tag && tag <= 0x77:
+		_, err = d.readType()
+		length = int(tag - 0x70)
+	case 0x78 <= tag && tag <= 0x7f:
+		length = int(tag - 0x78)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// each element takes one byte at least
+	if length > len(d.buf)-d.pos {
+		return nil, ErrHessianIncomplete
+	}
+
+	ref := len(d.refs)
+	d.refs = append(d.refs, nil)
+	list := make([]interface{}, 0)
+	if length < 0 {
+		for {
+			if d.pos < len(d.buf) && d.buf[d.pos] == 'Z' {
+				d.pos++
+				break
+			}
+			elem, err := d.Decode()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, elem)
+		}
+	} else {
+		for i := 0; i < length; i++ {
+			elem, err := d.Decode()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, elem)
+		}
+	}
+	d.refs[ref] = list
+	return list, nil
+}
+
+func (d *hessianDecoder) readMap() (interface{}, error) {
+	m := make(map[string]interface{})
+	d.refs = append(d.refs, m)
+	for {
+		if d.pos < len(d.buf) && d.buf[d.pos] == 'Z' {
+			d.pos++
+			return m, nil
+		}
+		key, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := key.(string); ok {
+			m[s] = value
+		} else {
+			m[fmt.Sprint(key)] = value
+		}
+	}
+}
+
+func (d *hessianDecoder) readClassDef() error {
+	tag, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	name, err := d.readString(tag)
+	if err != nil {
+		return err
+	}
+	count, err := d.readInt(0)
+	if err != nil {
+		return err
+	}
+	if count < 0 || int(count) > len(d.buf)-d.pos {
+		return ErrHessianIncomplete
+	}
+	def := classDef{name: name, fields: make([]string, count)}
+	for i := range def.fields {
+		if tag, err = d.readByte(); err != nil {
+			return err
+		}
+		if def.fields[i], err = d.readString(tag); err != nil {
+			return err
+		}
+	}
+	d.classes = append(d.classes, def)
+	return nil
+}
+
+func (d *hessianDecoder) readObject(ref int) (interface{}, error) {
+	if ref < 0 || ref >= len(d.classes) {
+		return nil, fmt.Errorf("hessian class reference %d is out of range", ref)
+	}
+	def := d.classes[ref]
+	object := make(map[string]interface{}, len(def.fields)+1)
+	object[ClassKey] = def.name
+	d.refs = append(d.refs, object)
+	for _, f := range def.fields {
+		value, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		object[f] = value
+	}
+	return object, nil
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch value := m.(type) {
+	case map[string]string:
+		for k := range value {
+			keys = append(keys, k)
+		}
+	case map[string]interface{}:
+		for k := range value {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,199 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bridge
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHessianRoundTrip(t *testing.T) {
+	long := strings.Repeat("mosn", 0x3000) + "中\U0001f600"
+	testCases := []struct {
+		value    interface{}
+		javaType string
+		expected interface{}
+	}{
+		{nil, "", nil},
+		{true, "", true},
+		{false, "", false},
+		{json.Number("0"), "int", int64(0)},
+		{json.Number("-16"), "int", int64(-16)},
+		{json.Number("47"), "int", int64(47)},
+		{json.Number("-2048"), "int", int64(-2048)},
+		{json.Number("2047"), "int", int64(2047)},
+		{json.Number("-262144"), "int", int64(-262144)},
+		{json.Number("262143"), "int", int64(262143)},
+		{json.Number("2147483647"), "java.lang.Integer", int64(2147483647)},
+		{json.Number("-8"), "long", int64(-8)},
+		{json.Number("15"), "long", int64(15)},
+		{json.Number("-2048"), "long", int64(-2048)},
+		{json.Number("262143"), "long", int64(262143)},
+		{json.Number("-2147483648"), "long", int64(-2147483648)},
+		{json.Number("9223372036854775807"), "java.lang.Long", int64(9223372036854775807)},
+		{json.Number("3"), "", int64(3)},
+		{json.Number("0"), "double", float64(0)},
+		{json.Number("1"), "double", float64(1)},
+		{json.Number("-128"), "double", float64(-128)},
+		{json.Number("32767"), "double", float64(32767)},
+		{json.Number("3.14"), "", float64(3.14)},
+		{json.Number("42"), "java.lang.String", "42"},
+		{"", "", ""},
+		{"hello", "java.lang.String", "hello"},
+		{strings.Repeat("a", 1000), "", strings.Repeat("a", 1000)},
+		{long, "", long},
+		{"mosn\U0001F600", "", "mosn\U0001F600"},
+		{map[string]interface{}{}, "", map[string]interface{}{}},
+		{[]interface{}{json.Number("1"), "a", nil}, "", []interface{}{int64(1), "a", nil}},
+		{[]interface{}{"a", "b"}, "java.lang.String[]", []interface{}{"a", "b"}},
+		{[]interface{}{json.Number("1"), json.Number("2")}, "long[]", []interface{}{int64(1), int64(2)}},
+		{
+			map[string]interface{}{"a": json.Number("1"), "b": []interface{}{"c"}},
+			"",
+			map[string]interface{}{"a": int64(1), "b": []interface{}{"c"}},
+		},
+		{
+			map[string]interface{}{ClassKey: "com.foo.User", "name": "mosn", "age": json.Number("3")},
+			"com.foo.User",
+			map[string]interface{}{ClassKey: "com.foo.User", "name": "mosn", "age": int64(3)},
+		},
+	}
+	for i, tc := range testCases {
+		e := newHessianEncoder()
+		if err := e.EncodeTyped(tc.value, tc.javaType); err != nil {
+			t.Fatalf("case %d: encode failed: %v", i, err)
+		}
+		d := newHessianDecoder(e.Bytes())
+		value, err := d.Decode()
+		if err != nil {
+			t.Fatalf("case %d: decode failed: %v", i, err)
+		}
+		if !d.Done() {
+			t.Fatalf("case %d: data is not decoded totally", i)
+		}
+		if !reflect.DeepEqual(value, tc.expected) {
+			t.Fatalf("case %d: decoded %#v, expected %#v", i, value, tc.expected)
+		}
+	}
+}
+
+func TestHessianEncodeTypedError(t *testing.T) {
+	e := newHessianEncoder()
+	if err := e.EncodeTyped(json.Number("1.5"), "int"); err == nil {
+		t.Fatal("a double is encoded as an int")
+	}
+	if err := e.EncodeTyped("a", "java.lang.String[]"); err == nil {
+		t.Fatal("a string is encoded as an array")
+	}
+}
+
+func TestHessianEncodeObjects(t *testing.T) {
+	e := newHessianEncoder()
+	users := []interface{}{
+		map[string]interface{}{ClassKey: "com.foo.User", "name": "a"},
+		map[string]interface{}{ClassKey: "com.foo.User", "name": "b"},
+	}
+	if err := e.Encode(users); err != nil {
+		t.Fatal(err)
+	}
+	// the class definition is written once
+	if n := strings.Count(string(e.Bytes()), "com.foo.User"); n != 1 {
+		t.Fatalf("class definition is written %d times", n)
+	}
+	value, err := newHessianDecoder(e.Bytes()).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(value, []interface{}{
+		map[string]interface{}{ClassKey: "com.foo.User", "name": "a"},
+		map[string]interface{}{ClassKey: "com.foo.User", "name": "b"},
+	}) {
+		t.Fatalf("unexpected objects %v", value)
+	}
+}
+
+// the data written by the java hessian2 output
+func TestHessianDecodeJava(t *testing.T) {
+	testCases := []struct {
+		data     []byte
+		expected interface{}
+	}{
+		// double in milliseconds
+		{[]byte{0x5f, 0x00, 0x00, 0x30, 0x39}, 12.345},
+		// date in milliseconds
+		{[]byte{0x4a, 0x00, 0x00, 0x00, 0xd0, 0x4b, 0x92, 0x84, 0xb8}, time.Unix(894621091, 0)},
+		// date in minutes
+		{[]byte{0x4b, 0x00, 0xe3, 0x83, 0x8f}, time.Unix(894621060, 0)},
+		// chunked binary
+		{[]byte{'A', 0x00, 0x02, 'a', 'b', 0x21, 'c'}, []byte("abc")},
+		// typed fixed list, and a typed map with the type reference
+		{
+			[]byte{0x72, 0x04, '[', 'm', 'a', 'p', 'M', 0x07, 'j', 'a', 'v', 'a', '.', 'x', 'y', 0x91, 0x92, 'Z', 'M', 0x91, 0x93, 0x94, 'Z'},
+			[]interface{}{
+				map[string]interface{}{"1": int64(2)},
+				map[string]interface{}{"3": int64(4)},
+			},
+		},
+		// variable list and the references
+		{
+			[]byte{0x7a, 'W', 0x01, 'a', 'Z', 'Q', 0x91},
+			[]interface{}{[]interface{}{"a"}, []interface{}{"a"}},
+		},
+		// the exception object with the class definition
+		{
+			append(append([]byte{'C', 0x13}, "java.lang.Exception"...), 0x92, 0x0d, 'd', 'e', 't', 'a', 'i', 'l', 'M', 'e', 's', 's', 'a', 'g', 'e',
+				0x0a, 's', 't', 'a', 'c', 'k', 'T', 'r', 'a', 'c', 'e', 0x60, 0x04, 'o', 'o', 'p', 's', 'N'),
+			map[string]interface{}{ClassKey: "java.lang.Exception", "detailMessage": "oops", "stackTrace": nil},
+		},
+	}
+	for i, tc := range testCases {
+		value, err := newHessianDecoder(tc.data).Decode()
+		if err != nil {
+			t.Fatalf("case %d: decode failed: %v", i, err)
+		}
+		if expectedTime, ok := tc.expected.(time.Time); ok {
+			if decoded, ok := value.(time.Time); !ok || !decoded.Equal(expectedTime) {
+				t.Fatalf("case %d: decoded %v, expected %v", i, value, expectedTime)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(value, tc.expected) {
+			t.Fatalf("case %d: decoded %#v, expected %#v", i, value, tc.expected)
+		}
+	}
+}
+
+func TestHessianDecodeInvalid(t *testing.T) {
+	for i, data := range [][]byte{
+		{},
+		{'I', 0x00},
+		{0x05, 'a'},
+		{'X', 0x9f},
+		{0x60},
+		{'Q', 0x90},
+		{0x40},
+		[]byte(strings.Repeat("\x79", maxDecodeDepth+1)),
+	} {
+		if _, err := newHessianDecoder(data).Decode(); err == nil {
+			t.Fatalf("case %d: invalid data is decoded", i)
+		}
+	}
+}
@@ -27,8 +27,12 @@ import (
 var (
 	protoConvFactory = make(map[types.Protocol]map[types.Protocol]ProtocolConv)
 
+	messageConvFactory = make(map[types.Protocol]map[types.Protocol]ProtocolMessageConv)
+
 	ErrNotFound = errors.New("no convert function found for given protocol pair")
 
+	ErrUnsupportedMessage = errors.New("message is not supported by the convert function")
+
 	ErrHeaderDirection = errors.New("no header direction info")
 
 	// Only for internal usage. This protocol is represent for those non-protocol-related data structure, like CommonHeader.
@@ -66,6 +70,15 @@ type ProtocolConv interface {
 	ConvTrailer(ctx context.Context, headerMap types.HeaderMap) (types.HeaderMap, error)
 }
 
+// ProtocolMessageConv converts the headers and the data of a message together. It is used by the conversions
+// in which the headers depend on the data, e.g. bridging http and the rpc protocols, the rpc service and method
+// of a http request may be carried in the body, and the status of a rpc response is decoded from the data.
+type ProtocolMessageConv interface {
+	// ConvMessage convert the whole message, returns ErrUnsupportedMessage if the message should be converted
+	// by the ProtocolConv
+	ConvMessage(ctx context.Context, headerMap types.HeaderMap, buffer types.IoBuffer) (types.HeaderMap, types.IoBuffer, error)
+}
+
 // RegisterConv register concrete protocol convert function for specified source protocol and destination protocol
 func RegisterConv(src, dst types.Protocol, f ProtocolConv) {
 	if _, subOk := protoConvFactory[src]; !subOk {
@@ -82,6 +95,26 @@ func RegisterCommonConv(protocol types.Protocol, from, to ProtocolConv) {
 	RegisterConv(protocol, common, to)
 }
 
+// RegisterMessageConv register concrete message convert function for specified source protocol and destination protocol
+func RegisterMessageConv(src, dst types.Protocol, f ProtocolMessageConv) {
+	if _, subOk := messageConvFactory[src]; !subOk {
+		messageConvFactory[src] = make(map[types.Protocol]ProtocolMessageConv)
+	}
+
+	messageConvFactory[src][dst] = f
+}
+
+// ConvertMessage convert the headers and the data of a message from source protocol format to destination protocol format.
+// Only the direct path is tried, there is no common representation of a whole message.
+func ConvertMessage(ctx context.Context, src, dst types.Protocol, srcHeader types.HeaderMap, srcData types.IoBuffer) (types.HeaderMap, types.IoBuffer, error) {
+	if sub, subOk := messageConvFactory[src]; subOk {
+		if f, ok := sub[dst]; ok {
+			return f.ConvMessage(ctx, srcHeader, srcData)
+		}
+	}
+	return nil, nil, ErrNotFound
+}
+
 // ConvertHeader convert header from source protocol format to destination protocol format
 func ConvertHeader(ctx context.Context, src, dst types.Protocol, srcHeader types.HeaderMap) (types.HeaderMap, error) {
 	// 1. try direct path
@@ -0,0 +1,230 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package grpc contains the helpers to make the http2 proxy aware of the gRPC protocol,
+// see https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// gRPC headers
+const (
+	HeaderContentType = "content-type"
+	HeaderStatus      = "grpc-status"
+	HeaderMessage     = "grpc-message"
+	HeaderTimeout     = "grpc-timeout"
+
+	ContentType = "application/grpc"
+)
+
+// Status is the gRPC status code
+type Status int
+
+// gRPC status codes
+const (
+	OK Status = iota
+	Canceled
+	Unknown
+	InvalidArgument
+	DeadlineExceeded
+	NotFound
+	AlreadyExists
+	PermissionDenied
+	ResourceExhausted
+	FailedPrecondition
+	Aborted
+	OutOfRange
+	Unimplemented
+	Internal
+	Unavailable
+	DataLoss
+	Unauthenticated
+)
+
+var ErrInvalidTimeout = errors.New("invalid grpc timeout")
+
+// IsGrpcContentType checks the content type is application/grpc or application/grpc+{subtype}
+func IsGrpcContentType(contentType string) bool {
+	if !strings.HasPrefix(contentType, ContentType) {
+		return false
+	}
+	if len(contentType) == len(ContentType) {
+		return true
+	}
+	c := contentType[len(ContentType)]
+	return c == '+' || c == ';'
+}
+
+// IsGrpcRequest checks the request is a gRPC request by the content type
+func IsGrpcRequest(headers types.HeaderMap) bool {
+	if headers == nil {
+		return false
+	}
+	contentType, _ := headers.Get(HeaderContentType)
+	return IsGrpcContentType(contentType)
+}
+
+// GetStatus returns the grpc-status in the headers
+func GetStatus(headers types.HeaderMap) (Status, bool) {
+	if headers == nil {
+		return Unknown, false
+	}
+	value, _ := headers.Get(HeaderStatus)
+	if value == "" {
+		return Unknown, false
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 0 {
+		return Unknown, true
+	}
+	return Status(code), true
+}
+
+// ResponseStatus returns the gRPC status of a response.
+// The grpc-status is in the trailers normally, or in the headers for a trailers-only response,
+// a response without grpc-status is mapped from its http status.
+func ResponseStatus(headers types.HeaderMap, trailers types.HeaderMap) Status {
+	if status, ok := GetStatus(trailers); ok {
+		return status
+	}
+	if status, ok := GetStatus(headers); ok {
+		return status
+	}
+	if headers != nil {
+		if value, _ := headers.Get(types.HeaderStatus); value != "" {
+			if code, err := strconv.Atoi(value); err == nil && code != 200 {
+				return HTTPToStatus(code)
+			}
+		}
+	}
+	return Unknown
+}
+
+// HTTPToStatus maps the http status code to the gRPC status,
+// see https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func HTTPToStatus(code int) Status {
+	switch code {
+	case 200:
+		return OK
+	case 400:
+		return Internal
+	case 401:
+		return Unauthenticated
+	case 403:
+		return PermissionDenied
+	case 404:
+		return Unimplemented
+	case 429, 502, 503, 504:
+		return Unavailable
+	default:
+		return Unknown
+	}
+}
+
+// StatusToHTTP maps the gRPC status to the equivalent http status code,
+// which is used to decide a response is succeed or failed
+func StatusToHTTP(status Status) int {
+	switch status {
+	case OK:
+		return 200
+	case Canceled:
+		return 499
+	case InvalidArgument, FailedPrecondition, OutOfRange:
+		return 400
+	case DeadlineExceeded:
+		return 504
+	case NotFound:
+		return 404
+	case AlreadyExists, Aborted:
+		return 409
+	case PermissionDenied:
+		return 403
+	case ResourceExhausted:
+		return 429
+	case Unimplemented:
+		return 501
+	case Unavailable:
+		return 503
+	case Unauthenticated:
+		return 401
+	default:
+		// Unknown, Internal, DataLoss and the undefined status
+		return 500
+	}
+}
+
+// ParseTimeout parses the grpc-timeout value, which is at most 8 digits followed by a unit
+func ParseTimeout(value string) (time.Duration, error) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, ErrInvalidTimeout
+	}
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'H':
+		unit = time.Hour
+	case 'M':
+		unit = time.Minute
+	case 'S':
+		unit = time.Second
+	case 'm':
+		unit = time.Millisecond
+	case 'u':
+		unit = time.Microsecond
+	case 'n':
+		unit = time.Nanosecond
+	default:
+		return 0, ErrInvalidTimeout
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidTimeout
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// SetStatus sets the gRPC status into the headers, makes a trailers-only response
+func SetStatus(headers types.HeaderMap, status Status, message string) {
+	headers.Set(HeaderContentType, ContentType)
+	headers.Set(HeaderStatus, strconv.Itoa(int(status)))
+	if message != "" {
+		headers.Set(HeaderMessage, encodeMessage(message))
+	} else {
+		headers.Del(HeaderMessage)
+	}
+}
+
+// encodeMessage percent encodes the grpc-message
+func encodeMessage(message string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return sb.String()
+}
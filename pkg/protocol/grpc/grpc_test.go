@@ -0,0 +1,124 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestIsGrpcRequest(t *testing.T) {
+	testCases := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"application/grpc", true},
+		{"application/grpc+proto", true},
+		{"application/grpc;charset=utf-8", true},
+		{"application/grpc-web", false},
+		{"application/json", false},
+		{"", false},
+	}
+	for _, tc := range testCases {
+		headers := protocol.CommonHeader{HeaderContentType: tc.contentType}
+		if IsGrpcRequest(headers) != tc.expected {
+			t.Errorf("content type %s expected grpc %v", tc.contentType, tc.expected)
+		}
+	}
+	if IsGrpcRequest(nil) {
+		t.Error("nil headers should not be a grpc request")
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	testCases := []struct {
+		headers  types.HeaderMap
+		trailers types.HeaderMap
+		expected Status
+	}{
+		{protocol.CommonHeader{types.HeaderStatus: "200"}, protocol.CommonHeader{HeaderStatus: "0"}, OK},
+		{protocol.CommonHeader{types.HeaderStatus: "200"}, protocol.CommonHeader{HeaderStatus: "14"}, Unavailable},
+		{protocol.CommonHeader{types.HeaderStatus: "200", HeaderStatus: "5"}, nil, NotFound},
+		{protocol.CommonHeader{types.HeaderStatus: "200", HeaderStatus: "invalid"}, nil, Unknown},
+		{protocol.CommonHeader{types.HeaderStatus: "503"}, nil, Unavailable},
+		{protocol.CommonHeader{types.HeaderStatus: "401"}, nil, Unauthenticated},
+		{protocol.CommonHeader{types.HeaderStatus: "200"}, nil, Unknown},
+	}
+	for i, tc := range testCases {
+		if status := ResponseStatus(tc.headers, tc.trailers); status != tc.expected {
+			t.Errorf("#%d expected status %d, but got %d", i, tc.expected, status)
+		}
+	}
+}
+
+func TestStatusToHTTP(t *testing.T) {
+	for status, code := range map[Status]int{
+		OK:               200,
+		InvalidArgument:  400,
+		DeadlineExceeded: 504,
+		Unavailable:      503,
+		Internal:         500,
+		Status(100):      500,
+	} {
+		if StatusToHTTP(status) != code {
+			t.Errorf("status %d expected http code %d, but got %d", status, code, StatusToHTTP(status))
+		}
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"1H", time.Hour, true},
+		{"2M", 2 * time.Minute, true},
+		{"3S", 3 * time.Second, true},
+		{"100m", 100 * time.Millisecond, true},
+		{"10u", 10 * time.Microsecond, true},
+		{"99999999n", 99999999 * time.Nanosecond, true},
+		{"100000000n", 0, false},
+		{"10", 0, false},
+		{"S", 0, false},
+		{"-1S", 0, false},
+		{"1x", 0, false},
+	}
+	for _, tc := range testCases {
+		d, err := ParseTimeout(tc.value)
+		if tc.valid != (err == nil) || d != tc.expected {
+			t.Errorf("parse timeout %s expected %v, but got %v, error: %v", tc.value, tc.expected, d, err)
+		}
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	headers := protocol.CommonHeader{HeaderMessage: "old"}
+	SetStatus(headers, Unavailable, "no healthy upstream: 100%")
+	if headers[HeaderContentType] != ContentType || headers[HeaderStatus] != "14" ||
+		headers[HeaderMessage] != "no healthy upstream: 100%25" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+	SetStatus(headers, OK, "")
+	if _, ok := headers[HeaderMessage]; ok || headers[HeaderStatus] != "0" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+}
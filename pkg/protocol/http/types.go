@@ -25,8 +25,9 @@ import (
 type Code uint32
 
 const (
-	Continue Code = 100
-	OK            = 200
+	Continue           Code = 100
+	SwitchingProtocols      = 101
+	OK                      = 200
 
 	Created                     = 201
 	Accepted                    = 202
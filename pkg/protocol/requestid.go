@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"context"
+
+	v2 "mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+// HeaderRequestID is the request id header of the http requests
+const HeaderRequestID = "x-request-id"
+
+// InjectRequestID makes sure the http request has a request id by the listener's request id config,
+// the request id is saved in the context and returns the context.
+func InjectRequestID(ctx context.Context, headers types.HeaderMap) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	cfg, _ := mosnctx.Get(ctx, types.ContextKeyRequestIDConfig).(*v2.RequestIDConfig)
+	if cfg != nil && cfg.Disable {
+		return ctx
+	}
+	id, ok := headers.Get(HeaderRequestID)
+	if !ok || id == "" || (cfg != nil && cfg.Regenerate) {
+		id = utils.GenerateUUID()
+		headers.Set(HeaderRequestID, id)
+	}
+	return mosnctx.WithValue(ctx, types.ContextKeyRequestID, id)
+}
+
+// GetRequestID returns the request id saved in the context, empty if not exists
+func GetRequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := mosnctx.Get(ctx, types.ContextKeyRequestID).(string)
+	return id
+}
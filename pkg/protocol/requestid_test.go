@@ -0,0 +1,70 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protocol
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	v2 "mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/types"
+)
+
+var uuidPattern = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+
+func TestInjectRequestID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   *v2.RequestIDConfig
+		header   string
+		expected string // empty means a new uuid is expected
+		injected bool
+	}{
+		{"generate", nil, "", "", true},
+		{"trust", nil, "external-id", "external-id", true},
+		{"regenerate", &v2.RequestIDConfig{Regenerate: true}, "external-id", "", true},
+		{"disable", &v2.RequestIDConfig{Disable: true}, "", "", false},
+	}
+	for _, tc := range testCases {
+		ctx := mosnctx.WithValue(context.Background(), types.ContextKeyRequestIDConfig, tc.config)
+		headers := CommonHeader{}
+		if tc.header != "" {
+			headers.Set(HeaderRequestID, tc.header)
+		}
+		ctx = InjectRequestID(ctx, headers)
+		id := GetRequestID(ctx)
+		if !tc.injected {
+			if id != "" {
+				t.Errorf("%s: request id should not be injected, but got %s", tc.name, id)
+			}
+			continue
+		}
+		if v, _ := headers.Get(HeaderRequestID); v != id {
+			t.Errorf("%s: request id in headers %s is not equal to the context %s", tc.name, v, id)
+		}
+		if tc.expected != "" {
+			if id != tc.expected {
+				t.Errorf("%s: expected request id %s, but got %s", tc.name, tc.expected, id)
+			}
+		} else if !uuidPattern.MatchString(id) {
+			t.Errorf("%s: expected a uuid v4, but got %s", tc.name, id)
+		}
+	}
+}
@@ -248,7 +248,7 @@ func (c *boltCodecV2) Decode(ctx context.Context, data types.IoBuffer) (interfac
 					data.Drain(read)
 				} else {
 					// not enough data
-					if log.Proxy.GetLogLevel() >= log.DEBUG {
+					if log.DebugEnabled(ctx) {
 						log.Proxy.Debugf(ctx, "[protocol][decode] boltv2 decode request, no enough data for fully decode")
 					}
 					return cmd, nil
@@ -279,7 +279,7 @@ func (c *boltCodecV2) Decode(ctx context.Context, data types.IoBuffer) (interfac
 
 				sofarpc.DeserializeBoltRequest(ctx, &request.BoltRequest)
 
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(ctx) {
 					log.Proxy.Debugf(ctx, "[protocol][sofarpc] boltv2 decode request:%+v", request)
 				}
 
@@ -319,7 +319,7 @@ func (c *boltCodecV2) Decode(ctx context.Context, data types.IoBuffer) (interfac
 					data.Drain(read)
 				} else {
 					// not enough data
-					if log.Proxy.GetLogLevel() >= log.DEBUG {
+					if log.DebugEnabled(ctx) {
 						log.Proxy.Debugf(ctx, "[protocol][sofarpc] boltv2] boltv2 decode response, no enough data for fully decode")
 					}
 					return cmd, nil
@@ -256,6 +256,9 @@ func (b *BoltRequest) Get(key string) (value string, ok bool) {
 }
 
 func (b *BoltRequest) Set(key string, value string) {
+	if b.RequestHeader == nil {
+		b.RequestHeader = make(map[string]string)
+	}
 	b.RequestHeader[key] = value
 }
 
@@ -0,0 +1,144 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dubbo
+
+import (
+	"encoding/binary"
+
+	"github.com/AlexStocks/dubbogo/codec/hessian"
+)
+
+// hessian serialization id
+const hessian2SerializeID = 2
+
+/**
+ * Dubbo request payload in hessian2:
+ * dubbo version, service path, service version, method name, parameter types descriptor,
+ * arguments (the count is determined by the descriptor), attachments map
+ */
+
+// decodeAttachments decodes the attachments of a request frame,
+// returns nil if the frame is not a hessian2 request or the payload can not be decoded
+func decodeAttachments(data []byte) map[string]string {
+	body, ok := requestBody(data)
+	if !ok {
+		return nil
+	}
+	decoder := hessian.NewDecoder(body)
+	var desc string
+	for i := 0; i < 5; i++ {
+		field, err := decoder.Decode()
+		if err != nil {
+			return nil
+		}
+		if desc, ok = field.(string); !ok {
+			return nil
+		}
+	}
+	for i := 0; i < countParameters(desc); i++ {
+		if _, err := decoder.Decode(); err != nil {
+			return nil
+		}
+	}
+	field, err := decoder.Decode()
+	if err != nil {
+		return nil
+	}
+	m, ok := field.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	attachments := make(map[string]string, len(m))
+	for k, v := range m {
+		key, ok1 := k.(string)
+		value, ok2 := v.(string)
+		if ok1 && ok2 {
+			attachments[key] = value
+		}
+	}
+	return attachments
+}
+
+// requestBody returns the payload of a two way or oneway hessian2 request frame
+func requestBody(data []byte) ([]byte, bool) {
+	valid, bodyLen := isValidDubboData(data)
+	if !valid || bodyLen <= 0 {
+		return nil, false
+	}
+	flag := data[DUBBO_FLAG_IDX]
+	if getEventPing(flag) || !isReqFrame(flag) || getSerializeId(flag) != hessian2SerializeID {
+		return nil, false
+	}
+	return data[DUBBO_HEADER_LEN : DUBBO_HEADER_LEN+bodyLen], true
+}
+
+// countParameters counts the parameters in a jvm method descriptor, such as "Ljava/lang/String;[IJ"
+func countParameters(desc string) int {
+	count := 0
+	for i := 0; i < len(desc); i++ {
+		switch desc[i] {
+		case '[':
+			// the array prefix, the element type follows
+			continue
+		case 'L':
+			for i < len(desc) && desc[i] != ';' {
+				i++
+			}
+		}
+		count++
+	}
+	return count
+}
+
+func (d *rpcDubbo) GetAttachments(data []byte) map[string]string {
+	return decodeAttachments(data)
+}
+
+// SetAttachments adds the attachments into the request frame, the attachments with the same value are skipped.
+// the attachments map is the last object in the payload, so the new entries are inserted before the end tag of
+// the map, and the values in the end override the previous ones of the same key when decoding.
+func (d *rpcDubbo) SetAttachments(data []byte, attachments map[string]string) []byte {
+	body, ok := requestBody(data)
+	if !ok || body[len(body)-1] != hessian.BC_END {
+		return data
+	}
+	existing := decodeAttachments(data)
+	if existing == nil {
+		return data
+	}
+
+	encoder := hessian.NewEncoder()
+	for k, v := range attachments {
+		if old, ok := existing[k]; ok && old == v {
+			continue
+		}
+		encoder.Encode(k)
+		encoder.Encode(v)
+	}
+	entries := encoder.Buffer()
+	if len(entries) == 0 {
+		return data
+	}
+
+	end := DUBBO_HEADER_LEN + len(body) - 1
+	frame := make([]byte, 0, len(data)+len(entries))
+	frame = append(frame, data[:end]...)
+	frame = append(frame, entries...)
+	frame = append(frame, data[end:]...)
+	binary.BigEndian.PutUint32(frame[DUBBO_DATA_LEN_IDX:], uint32(len(body)+len(entries)))
+	return frame
+}
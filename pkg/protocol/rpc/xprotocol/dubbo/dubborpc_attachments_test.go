@@ -0,0 +1,96 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dubbo
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/AlexStocks/dubbogo/codec/hessian"
+)
+
+// newRequestFrame builds a two way hessian2 request frame
+func newRequestFrame(t *testing.T, attachments map[string]string) []byte {
+	encoder := hessian.NewEncoder()
+	for _, v := range []interface{}{"2.0.2", "com.foo.EchoService", "1.0.0", "echo", "Ljava/lang/String;[I", "mosn", []int32{1, 2}, attachments} {
+		if err := encoder.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	body := encoder.Buffer()
+	frame := []byte{0xda, 0xbb, 0xc2, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(frame[DUBBO_DATA_LEN_IDX:], uint32(len(body)))
+	return append(frame, body...)
+}
+
+func TestCountParameters(t *testing.T) {
+	for desc, expected := range map[string]int{
+		"":                                    0,
+		"I":                                   1,
+		"Ljava/lang/String;":                  1,
+		"Ljava/lang/String;[IJ":               3,
+		"[[Ljava/lang/String;Ljava/util/Map;": 2,
+	} {
+		if count := countParameters(desc); count != expected {
+			t.Errorf("%s: expected %d, but got %d", desc, expected, count)
+		}
+	}
+}
+
+func TestDubboAttachments(t *testing.T) {
+	rpc := &rpcDubbo{}
+	frame := newRequestFrame(t, map[string]string{"interface": "com.foo.EchoService", "uber-trace-id": "1:2:0:1"})
+	attachments := rpc.GetAttachments(frame)
+	if attachments["interface"] != "com.foo.EchoService" || attachments["uber-trace-id"] != "1:2:0:1" {
+		t.Fatalf("unexpected attachments: %v", attachments)
+	}
+
+	// the same value is not written again
+	if newFrame := rpc.SetAttachments(frame, map[string]string{"uber-trace-id": "1:2:0:1"}); len(newFrame) != len(frame) {
+		t.Errorf("the frame should not be changed")
+	}
+
+	newFrame := rpc.SetAttachments(frame, map[string]string{"uber-trace-id": "3:4:0:1", "sw8": "1-MQ==-Mg==-0-Mw==-NA==-NQ==-Ng=="})
+	if valid, bodyLen := isValidDubboData(newFrame); !valid || DUBBO_HEADER_LEN+bodyLen != len(newFrame) {
+		t.Fatalf("invalid frame")
+	}
+	if rpc.GetStreamID(newFrame) != rpc.GetStreamID(frame) || rpc.GetServiceName(newFrame) != "com.foo.EchoService" {
+		t.Errorf("the frame header is changed")
+	}
+	attachments = rpc.GetAttachments(newFrame)
+	if len(attachments) != 3 || attachments["uber-trace-id"] != "3:4:0:1" || attachments["sw8"] == "" {
+		t.Errorf("unexpected attachments: %v", attachments)
+	}
+}
+
+func TestDubboAttachmentsNotRequest(t *testing.T) {
+	rpc := &rpcDubbo{}
+	// response frame
+	frame := newRequestFrame(t, map[string]string{"k": "v"})
+	frame[DUBBO_FLAG_IDX] = 0x02
+	if attachments := rpc.GetAttachments(frame); attachments != nil {
+		t.Errorf("response frame should have no attachments: %v", attachments)
+	}
+	if newFrame := rpc.SetAttachments(frame, map[string]string{"k": "v2"}); len(newFrame) != len(frame) {
+		t.Errorf("response frame should not be changed")
+	}
+	// heartbeat frame
+	heartbeat := []byte{0xda, 0xbb, 0xe2, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 'N'}
+	if newFrame := rpc.SetAttachments(heartbeat, map[string]string{"k": "v"}); len(newFrame) != len(heartbeat) {
+		t.Errorf("heartbeat frame should not be changed")
+	}
+}
@@ -0,0 +1,297 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// binary protocol types
+const (
+	binaryStop   = 0
+	binaryBool   = 2
+	binaryByte   = 3
+	binaryDouble = 4
+	binaryI16    = 6
+	binaryI32    = 8
+	binaryI64    = 10
+	binaryString = 11
+	binaryStruct = 12
+	binaryMap    = 13
+	binarySet    = 14
+	binaryList   = 15
+	binaryUUID   = 16
+)
+
+// compact protocol types
+const (
+	compactStop      = 0
+	compactBoolTrue  = 1
+	compactBoolFalse = 2
+	compactByte      = 3
+	compactI16       = 4
+	compactI32       = 5
+	compactI64       = 6
+	compactDouble    = 7
+	compactBinary    = 8
+	compactList      = 9
+	compactSet       = 10
+	compactMap       = 11
+	compactStruct    = 12
+	compactUUID      = 13
+)
+
+// reader reads the thrift values from data, errIncomplete is returned if data is exhausted
+type reader struct {
+	data   []byte
+	offset int
+}
+
+func (r *reader) readBytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errInvalid
+	}
+	if len(r.data)-r.offset < n {
+		return nil, errIncomplete
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b, nil
+}
+
+func (r *reader) readByte() (byte, error) {
+	b, err := r.readBytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *reader) readI32() (int32, error) {
+	b, err := r.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *reader) readVarint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errInvalid
+}
+
+// readSize reads the size of a container, each element takes at least minElemLen bytes
+func (r *reader) readSize(size int64, minElemLen int) (int, error) {
+	if size < 0 {
+		return 0, errInvalid
+	}
+	if size*int64(minElemLen) > int64(len(r.data)-r.offset) {
+		return 0, errIncomplete
+	}
+	return int(size), nil
+}
+
+// skipBinary skips a value of the binary protocol
+func (r *reader) skipBinary(t byte, depth int) error {
+	if depth > maxDepth {
+		return errInvalid
+	}
+	var err error
+	switch t {
+	case binaryBool, binaryByte:
+		_, err = r.readBytes(1)
+	case binaryI16:
+		_, err = r.readBytes(2)
+	case binaryI32:
+		_, err = r.readBytes(4)
+	case binaryI64, binaryDouble:
+		_, err = r.readBytes(8)
+	case binaryUUID:
+		_, err = r.readBytes(16)
+	case binaryString:
+		var n int32
+		if n, err = r.readI32(); err == nil {
+			_, err = r.readBytes(int(n))
+		}
+	case binaryStruct:
+		for {
+			var ft byte
+			if ft, err = r.readByte(); err != nil || ft == binaryStop {
+				break
+			}
+			// field id
+			if _, err = r.readBytes(2); err != nil {
+				break
+			}
+			if err = r.skipBinary(ft, depth+1); err != nil {
+				break
+			}
+		}
+	case binaryMap:
+		var kv []byte
+		if kv, err = r.readBytes(2); err != nil {
+			break
+		}
+		var size int32
+		if size, err = r.readI32(); err != nil {
+			break
+		}
+		var n int
+		if n, err = r.readSize(int64(size), 1); err != nil {
+			break
+		}
+		for i := 0; i < n && err == nil; i++ {
+			if err = r.skipBinary(kv[0], depth+1); err == nil {
+				err = r.skipBinary(kv[1], depth+1)
+			}
+		}
+	case binarySet, binaryList:
+		var et byte
+		if et, err = r.readByte(); err != nil {
+			break
+		}
+		var size int32
+		if size, err = r.readI32(); err != nil {
+			break
+		}
+		var n int
+		if n, err = r.readSize(int64(size), 1); err != nil {
+			break
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = r.skipBinary(et, depth+1)
+		}
+	default:
+		err = errInvalid
+	}
+	return err
+}
+
+// skipCompact skips a value of the compact protocol
+func (r *reader) skipCompact(t byte, depth int) error {
+	if depth > maxDepth {
+		return errInvalid
+	}
+	var err error
+	switch t {
+	case compactBoolTrue, compactBoolFalse, compactByte:
+		// the bool in a container takes one byte, the bool field has no value which is not skipped here
+		_, err = r.readBytes(1)
+	case compactI16, compactI32, compactI64:
+		_, err = r.readVarint()
+	case compactDouble:
+		_, err = r.readBytes(8)
+	case compactUUID:
+		_, err = r.readBytes(16)
+	case compactBinary:
+		var n uint64
+		if n, err = r.readVarint(); err == nil {
+			if n > THRIFT_MAX_FRAME_LEN {
+				return errInvalid
+			}
+			_, err = r.readBytes(int(n))
+		}
+	case compactStruct:
+		for {
+			var header byte
+			if header, err = r.readByte(); err != nil || header == compactStop {
+				break
+			}
+			ft := header & 0x0f
+			if header>>4 == 0 {
+				// the field id is not a delta, a zigzag i16 follows
+				if _, err = r.readVarint(); err != nil {
+					break
+				}
+			}
+			if ft == compactBoolTrue || ft == compactBoolFalse {
+				// the value of a bool field is in the type
+				continue
+			}
+			if err = r.skipCompact(ft, depth+1); err != nil {
+				break
+			}
+		}
+	case compactList, compactSet:
+		var header byte
+		if header, err = r.readByte(); err != nil {
+			break
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.readVarint(); err != nil {
+				break
+			}
+		}
+		var n int
+		if n, err = r.readSize(compactSize(size), 1); err != nil {
+			break
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = r.skipCompact(header&0x0f, depth+1)
+		}
+	case compactMap:
+		var size uint64
+		if size, err = r.readVarint(); err != nil || size == 0 {
+			break
+		}
+		var kv byte
+		if kv, err = r.readByte(); err != nil {
+			break
+		}
+		var n int
+		if n, err = r.readSize(compactSize(size), 1); err != nil {
+			break
+		}
+		for i := 0; i < n && err == nil; i++ {
+			if err = r.skipCompact(kv>>4, depth+1); err == nil {
+				err = r.skipCompact(kv&0x0f, depth+1)
+			}
+		}
+	default:
+		err = errInvalid
+	}
+	return err
+}
+
+// compactSize converts the var int size of a container, a size out of the int32 range is invalid
+func compactSize(size uint64) int64 {
+	if size > math.MaxInt32 {
+		return -1
+	}
+	return int64(size)
+}
+
+func appendVarint(dst []byte, v uint64) []byte {
+	for v >= 0x80 {
+		dst = append(dst, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(dst, byte(v))
+}
@@ -0,0 +1,335 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol/rpc/xprotocol"
+)
+
+func init() {
+	xprotocol.Register("thrift", &pluginThriftFactory{})
+}
+
+type pluginThriftFactory struct{}
+
+func (ref *pluginThriftFactory) CreateSubProtocolCodec(context context.Context) xprotocol.Multiplexing {
+	return NewRPCThrift()
+}
+
+type rpcThrift struct{}
+
+// NewRPCThrift create thrift codec, it supports the framed and the unframed transports,
+// and the binary and the compact protocols, which are detected from the data
+func NewRPCThrift() xprotocol.Tracing {
+	return &rpcThrift{}
+}
+
+/**
+ * Thrift message
+ * Framed transport: 4 bytes frame length (big endian) + message
+ * Unframed transport: message
+ *
+ * Binary protocol (strict):
+ * +-----------+-----------+-----------+-----------+
+ * |1000 0000  | 0000 0001 | unused    | type      |  version 0x8001 + message type
+ * +-----------+-----------+-----------+-----------+
+ * |  name length (i32) + name bytes               |
+ * +-----------------------------------------------+
+ * |  seqid (i32)                                  |
+ * +-----------------------------------------------+
+ * |  struct (args or result)                      |
+ * +-----------------------------------------------+
+ *
+ * Compact protocol:
+ * +-----------+-----------+-----------+-----------+
+ * |1000 0010  | type(3 bits) version(5 bits)      |  protocol id 0x82 + type and version 1
+ * +-----------+-----------+-----------+-----------+
+ * |  seqid (var int) + name length (var int) + name bytes
+ * +-----------------------------------------------+
+ * |  struct (args or result)                      |
+ * +-----------------------------------------------+
+ *
+ * The name of a message is the method name, or "service:method" if the TMultiplexedProtocol is used.
+ */
+
+const (
+	THRIFT_FRAME_HEADER_LEN = 4
+	// THRIFT_MAX_FRAME_LEN is the same as the default max frame size of the thrift libraries
+	THRIFT_MAX_FRAME_LEN = 16384000
+
+	THRIFT_BINARY_VERSION_1    = 0x8001
+	THRIFT_COMPACT_PROTOCOL_ID = 0x82
+	THRIFT_COMPACT_VERSION     = 1
+
+	// THRIFT_MULTIPLEXED_SEPARATOR separates the service name and the method name in TMultiplexedProtocol
+	THRIFT_MULTIPLEXED_SEPARATOR = ":"
+)
+
+// Thrift message types
+const (
+	THRIFT_MESSAGE_CALL      = 1
+	THRIFT_MESSAGE_REPLY     = 2
+	THRIFT_MESSAGE_EXCEPTION = 3
+	THRIFT_MESSAGE_ONEWAY    = 4
+)
+
+// The keys of the metas for request routing
+const (
+	THRIFT_META_SERVICE = "service"
+	THRIFT_META_METHOD  = "method"
+)
+
+// maxDepth limits the nesting depth of the structs and the containers
+const maxDepth = 64
+
+var (
+	errIncomplete = errors.New("thrift message is incomplete")
+	errInvalid    = errors.New("thrift message is invalid")
+)
+
+// messageHeader is the header of a thrift message
+type messageHeader struct {
+	framed  bool
+	compact bool
+	name    string
+	msgType byte
+	seqID   uint32
+	// seqIDOffset and seqIDLen locate the seqid in the data, the seqid of the compact protocol is a var int
+	seqIDOffset int
+	seqIDLen    int
+	// bodyOffset is the offset of the struct after the header
+	bodyOffset int
+}
+
+// parseHeader parses the header of the first message in data
+func parseHeader(data []byte) (*messageHeader, error) {
+	h := &messageHeader{}
+	offset := 0
+	if len(data) < 2 {
+		return nil, errIncomplete
+	}
+	// the length of a frame never starts with 0x80 or 0x82, so the transport is detected by the first byte
+	if data[0] != 0x80 && data[0] != THRIFT_COMPACT_PROTOCOL_ID {
+		if len(data) < THRIFT_FRAME_HEADER_LEN+2 {
+			return nil, errIncomplete
+		}
+		h.framed = true
+		offset = THRIFT_FRAME_HEADER_LEN
+	}
+	switch {
+	case data[offset] == 0x80 && data[offset+1] == 0x01:
+		return h, parseBinaryHeader(data, offset, h)
+	case data[offset] == THRIFT_COMPACT_PROTOCOL_ID && data[offset+1]&0x1f == THRIFT_COMPACT_VERSION:
+		h.compact = true
+		return h, parseCompactHeader(data, offset, h)
+	}
+	return nil, errInvalid
+}
+
+func parseBinaryHeader(data []byte, offset int, h *messageHeader) error {
+	r := &reader{data: data, offset: offset}
+	version, err := r.readI32()
+	if err != nil {
+		return err
+	}
+	h.msgType = byte(version & 0xff)
+	nameLen, err := r.readI32()
+	if err != nil {
+		return err
+	}
+	name, err := r.readBytes(int(nameLen))
+	if err != nil {
+		return err
+	}
+	h.name = string(name)
+	h.seqIDOffset = r.offset
+	h.seqIDLen = 4
+	seqID, err := r.readI32()
+	if err != nil {
+		return err
+	}
+	h.seqID = uint32(seqID)
+	h.bodyOffset = r.offset
+	return nil
+}
+
+func parseCompactHeader(data []byte, offset int, h *messageHeader) error {
+	h.msgType = data[offset+1] >> 5
+	r := &reader{data: data, offset: offset + 2}
+	h.seqIDOffset = r.offset
+	seqID, err := r.readVarint()
+	if err != nil {
+		return err
+	}
+	h.seqID = uint32(seqID)
+	h.seqIDLen = r.offset - h.seqIDOffset
+	nameLen, err := r.readVarint()
+	if err != nil {
+		return err
+	}
+	name, err := r.readBytes(int(nameLen))
+	if err != nil {
+		return err
+	}
+	h.name = string(name)
+	h.bodyOffset = r.offset
+	return nil
+}
+
+// getThriftLen returns the length of the first message in data, or -1 if the message is incomplete or invalid
+func getThriftLen(data []byte) int {
+	h, err := parseHeader(data)
+	if err != nil {
+		return -1
+	}
+	if h.framed {
+		frameLen := binary.BigEndian.Uint32(data)
+		if frameLen > THRIFT_MAX_FRAME_LEN || len(data) < THRIFT_FRAME_HEADER_LEN+int(frameLen) {
+			return -1
+		}
+		return THRIFT_FRAME_HEADER_LEN + int(frameLen)
+	}
+	// the unframed message has no length, the struct is skipped to find the end of the message
+	r := &reader{data: data, offset: h.bodyOffset}
+	if h.compact {
+		err = r.skipCompact(compactStruct, 0)
+	} else {
+		err = r.skipBinary(binaryStruct, 0)
+	}
+	if err != nil {
+		return -1
+	}
+	return r.offset
+}
+
+func (d *rpcThrift) SplitFrame(data []byte) [][]byte {
+	var frames [][]byte
+	start := 0
+	dataLen := len(data)
+	for dataLen > 0 {
+		frameLen := getThriftLen(data[start:])
+		if frameLen <= 0 || dataLen < frameLen {
+			log.DefaultLogger.Tracef("[SplitFrame] over! frameLen=%d, dataLen=%d. frame_cnt=%d", frameLen, dataLen, len(frames))
+			break
+		}
+		frames = append(frames, data[start:(start+frameLen)])
+		start += frameLen
+		dataLen -= frameLen
+	}
+	return frames
+}
+
+// GetStreamID returns the seqid of the message, the seqid is formatted as an unsigned integer
+func (d *rpcThrift) GetStreamID(data []byte) string {
+	h, err := parseHeader(data)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(h.seqID), 10)
+}
+
+// SetStreamID rewrites the seqid of the message, the stream id is truncated to 32 bits.
+// The data is modified in place for the binary protocol, a new frame may be returned for
+// the compact protocol if the length of the var int seqid is changed.
+func (d *rpcThrift) SetStreamID(data []byte, streamID string) []byte {
+	h, err := parseHeader(data)
+	if err != nil {
+		return data
+	}
+	reqID, err := strconv.ParseUint(streamID, 10, 64)
+	if err != nil {
+		return data
+	}
+	seqID := uint32(reqID)
+	if !h.compact {
+		binary.BigEndian.PutUint32(data[h.seqIDOffset:], seqID)
+		return data
+	}
+	varint := appendVarint(nil, uint64(seqID))
+	if len(varint) == h.seqIDLen {
+		copy(data[h.seqIDOffset:], varint)
+		return data
+	}
+	newData := make([]byte, 0, len(data)-h.seqIDLen+len(varint))
+	newData = append(newData, data[:h.seqIDOffset]...)
+	newData = append(newData, varint...)
+	newData = append(newData, data[h.seqIDOffset+h.seqIDLen:]...)
+	if h.framed {
+		binary.BigEndian.PutUint32(newData, uint32(len(newData)-THRIFT_FRAME_HEADER_LEN))
+	}
+	return newData
+}
+
+// splitName splits the message name into the service name and the method name,
+// the service name is empty if the TMultiplexedProtocol is not used
+func splitName(name string) (string, string) {
+	if idx := strings.Index(name, THRIFT_MULTIPLEXED_SEPARATOR); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	return "", name
+}
+
+// requestName returns the name of a request message, the response has no name to route
+func requestName(data []byte) (string, bool) {
+	h, err := parseHeader(data)
+	if err != nil {
+		return "", false
+	}
+	if h.msgType != THRIFT_MESSAGE_CALL && h.msgType != THRIFT_MESSAGE_ONEWAY {
+		return "", false
+	}
+	return h.name, true
+}
+
+func (d *rpcThrift) GetServiceName(data []byte) string {
+	name, ok := requestName(data)
+	if !ok {
+		return ""
+	}
+	service, _ := splitName(name)
+	return service
+}
+
+func (d *rpcThrift) GetMethodName(data []byte) string {
+	name, ok := requestName(data)
+	if !ok {
+		return ""
+	}
+	_, method := splitName(name)
+	return method
+}
+
+// GetMetas returns the service name and the method name of a request for routing
+func (d *rpcThrift) GetMetas(data []byte) map[string]string {
+	name, ok := requestName(data)
+	if !ok {
+		return nil
+	}
+	service, method := splitName(name)
+	return map[string]string{
+		THRIFT_META_SERVICE: service,
+		THRIFT_META_METHOD:  method,
+	}
+}
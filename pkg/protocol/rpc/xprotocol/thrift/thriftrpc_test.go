@@ -0,0 +1,247 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package thrift
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func encodeString(s string) []byte {
+	b := make([]byte, 4, 4+len(s))
+	binary.BigEndian.PutUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func encodeI32(v int32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(v))
+	return b
+}
+
+// binaryArgs is struct {1: string, 2: i32, 3: list<i64>, 4: map<string, bool>, 5: bool, 6: struct {1: double}}
+func binaryArgs() []byte {
+	var b []byte
+	b = append(b, binaryString, 0, 1)
+	b = append(b, encodeString("hello")...)
+	b = append(b, binaryI32, 0, 2)
+	b = append(b, encodeI32(42)...)
+	b = append(b, binaryList, 0, 3, binaryI64)
+	b = append(b, encodeI32(2)...)
+	b = append(b, make([]byte, 16)...)
+	b = append(b, binaryMap, 0, 4, binaryString, binaryBool)
+	b = append(b, encodeI32(1)...)
+	b = append(b, encodeString("k")...)
+	b = append(b, 1)
+	b = append(b, binaryBool, 0, 5, 1)
+	b = append(b, binaryStruct, 0, 6, binaryDouble, 0, 1)
+	b = append(b, make([]byte, 8)...)
+	b = append(b, binaryStop)
+	return append(b, binaryStop)
+}
+
+func binaryMessage(name string, msgType byte, seqID int32, framed bool) []byte {
+	var b []byte
+	b = append(b, 0x80, 0x01, 0, msgType)
+	b = append(b, encodeString(name)...)
+	b = append(b, encodeI32(seqID)...)
+	b = append(b, binaryArgs()...)
+	if framed {
+		b = append(encodeI32(int32(len(b))), b...)
+	}
+	return b
+}
+
+func compactString(s string) []byte {
+	return append(appendVarint(nil, uint64(len(s))), s...)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+// compactArgs is the same struct as binaryArgs, with an additional field 100: i32 of the long form field header
+func compactArgs() []byte {
+	var b []byte
+	b = append(b, 1<<4|compactBinary)
+	b = append(b, compactString("hello")...)
+	b = append(b, 1<<4|compactI32)
+	b = appendVarint(b, zigzag(42))
+	b = append(b, 1<<4|compactList, 2<<4|compactI64)
+	b = appendVarint(b, zigzag(-1))
+	b = appendVarint(b, zigzag(math.MaxInt64))
+	b = append(b, 1<<4|compactMap)
+	b = appendVarint(b, 1)
+	b = append(b, compactBinary<<4|compactBoolTrue)
+	b = append(b, compactString("k")...)
+	b = append(b, compactBoolTrue)
+	b = append(b, 1<<4|compactBoolTrue)
+	b = append(b, 1<<4|compactStruct, 1<<4|compactDouble)
+	b = append(b, make([]byte, 8)...)
+	b = append(b, compactStop)
+	b = append(b, compactI32)
+	b = appendVarint(b, zigzag(100))
+	b = appendVarint(b, zigzag(-7))
+	return append(b, compactStop)
+}
+
+func compactMessage(name string, msgType byte, seqID uint32, framed bool) []byte {
+	var b []byte
+	b = append(b, THRIFT_COMPACT_PROTOCOL_ID, msgType<<5|THRIFT_COMPACT_VERSION)
+	b = appendVarint(b, uint64(seqID))
+	b = append(b, compactString(name)...)
+	b = append(b, compactArgs()...)
+	if framed {
+		b = append(encodeI32(int32(len(b))), b...)
+	}
+	return b
+}
+
+type testMessage func(name string, msgType byte, seqID uint32) []byte
+
+var testMessages = map[string]testMessage{
+	"framed binary": func(name string, msgType byte, seqID uint32) []byte {
+		return binaryMessage(name, msgType, int32(seqID), true)
+	},
+	"unframed binary": func(name string, msgType byte, seqID uint32) []byte {
+		return binaryMessage(name, msgType, int32(seqID), false)
+	},
+	"framed compact": func(name string, msgType byte, seqID uint32) []byte {
+		return compactMessage(name, msgType, seqID, true)
+	},
+	"unframed compact": func(name string, msgType byte, seqID uint32) []byte {
+		return compactMessage(name, msgType, seqID, false)
+	},
+}
+
+func Test_thrift_SplitFrame(t *testing.T) {
+	rpc := NewRPCThrift()
+	for desc, message := range testMessages {
+		first := message("add", THRIFT_MESSAGE_CALL, 1)
+		second := message("Calculator:sub", THRIFT_MESSAGE_ONEWAY, 2)
+		third := message("add", THRIFT_MESSAGE_CALL, 3)
+		var data []byte
+		data = append(data, first...)
+		data = append(data, second...)
+		data = append(data, third[:len(third)-1]...)
+		frames := rpc.SplitFrame(data)
+		if len(frames) != 2 || !bytes.Equal(frames[0], first) || !bytes.Equal(frames[1], second) {
+			t.Errorf("%s: split got %d frames", desc, len(frames))
+		}
+		// every prefix of a message is incomplete
+		for i := 0; i < len(first); i++ {
+			if frames := rpc.SplitFrame(first[:i]); len(frames) != 0 {
+				t.Errorf("%s: split %d bytes of %d got a frame", desc, i, len(first))
+				break
+			}
+		}
+	}
+}
+
+func Test_thrift_SplitFrame_Invalid(t *testing.T) {
+	rpc := NewRPCThrift()
+	cases := map[string][]byte{
+		"unknown protocol":     {0, 0, 0, 10, 0x81, 0x01, 0, 1},
+		"compact version":      {THRIFT_COMPACT_PROTOCOL_ID, 0x22, 1, 0},
+		"binary negative name": append([]byte{0x80, 0x01, 0, 1}, encodeI32(-1)...),
+		"frame too large":      append(encodeI32(THRIFT_MAX_FRAME_LEN+1), 0x80, 0x01, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1),
+		"unknown field type":   append(binaryMessage("add", THRIFT_MESSAGE_CALL, 1, false)[:15], 0x07, 0, 1, 0),
+		"too deep binary struct": func() []byte {
+			b := binaryMessage("add", THRIFT_MESSAGE_CALL, 1, false)[:15]
+			for i := 0; i <= maxDepth; i++ {
+				b = append(b, binaryStruct, 0, 1)
+			}
+			return append(b, bytes.Repeat([]byte{0}, maxDepth+2)...)
+		}(),
+	}
+	for desc, data := range cases {
+		if frames := rpc.SplitFrame(data); len(frames) != 0 {
+			t.Errorf("%s: expected no frame, but got %d", desc, len(frames))
+		}
+	}
+}
+
+func Test_thrift_StreamID(t *testing.T) {
+	rpc := NewRPCThrift()
+	for desc, message := range testMessages {
+		data := message("add", THRIFT_MESSAGE_CALL, 1)
+		if id := rpc.GetStreamID(data); id != "1" {
+			t.Errorf("%s: get stream id %s != 1", desc, id)
+		}
+		// the var int seqid of the compact protocol grows
+		data = rpc.SetStreamID(data, "300")
+		if id := rpc.GetStreamID(data); id != "300" {
+			t.Errorf("%s: get stream id %s != 300", desc, id)
+		}
+		if frames := rpc.SplitFrame(data); len(frames) != 1 || len(frames[0]) != len(data) {
+			t.Errorf("%s: split the message with new stream id failed", desc)
+		}
+		// the seqid is 32 bits
+		data = rpc.SetStreamID(data, "4294967295")
+		if id := rpc.GetStreamID(data); id != "4294967295" {
+			t.Errorf("%s: get stream id %s != 4294967295", desc, id)
+		}
+		data = rpc.SetStreamID(data, "2")
+		if id := rpc.GetStreamID(data); id != "2" {
+			t.Errorf("%s: get stream id %s != 2", desc, id)
+		}
+		if frames := rpc.SplitFrame(data); len(frames) != 1 || len(frames[0]) != len(data) {
+			t.Errorf("%s: split the message with new stream id failed", desc)
+		}
+		// the method name is not changed
+		if method := rpc.GetMethodName(data); method != "add" {
+			t.Errorf("%s: get method name %s != add", desc, method)
+		}
+		if id := rpc.GetStreamID(data[:3]); id != "" {
+			t.Errorf("%s: get stream id of incomplete message got %s", desc, id)
+		}
+	}
+}
+
+func Test_thrift_Names(t *testing.T) {
+	rpc := NewRPCThrift()
+	for desc, message := range testMessages {
+		data := message("Calculator:add", THRIFT_MESSAGE_CALL, 1)
+		if service := rpc.GetServiceName(data); service != "Calculator" {
+			t.Errorf("%s: get service name %s != Calculator", desc, service)
+		}
+		if method := rpc.GetMethodName(data); method != "add" {
+			t.Errorf("%s: get method name %s != add", desc, method)
+		}
+		metas := rpc.(*rpcThrift).GetMetas(data)
+		if metas[THRIFT_META_SERVICE] != "Calculator" || metas[THRIFT_META_METHOD] != "add" {
+			t.Errorf("%s: get metas %v", desc, metas)
+		}
+
+		data = message("add", THRIFT_MESSAGE_ONEWAY, 1)
+		if service, method := rpc.GetServiceName(data), rpc.GetMethodName(data); service != "" || method != "add" {
+			t.Errorf("%s: get names of not multiplexed message got %s %s", desc, service, method)
+		}
+
+		// the response has no name to route
+		data = message("Calculator:add", THRIFT_MESSAGE_REPLY, 1)
+		if service, method := rpc.GetServiceName(data), rpc.GetMethodName(data); service != "" || method != "" {
+			t.Errorf("%s: get names of reply got %s %s", desc, service, method)
+		}
+		if metas := rpc.(*rpcThrift).GetMetas(data); metas != nil {
+			t.Errorf("%s: get metas of reply got %v", desc, metas)
+		}
+	}
+}
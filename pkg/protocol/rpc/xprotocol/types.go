@@ -70,3 +70,11 @@ type ProtocolConvertor interface {
 	Multiplexing
 	Convert(data []byte) (map[string]string, []byte)
 }
+
+// Attachments reads and writes the key-value attachments carried in the request frame, such as the trace context,
+// base on Multiplexing
+type Attachments interface {
+	Multiplexing
+	GetAttachments(data []byte) map[string]string
+	SetAttachments(data []byte, attachments map[string]string) []byte
+}
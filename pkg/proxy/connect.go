@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/types"
+)
+
+const methodConnect = "CONNECT"
+
+func (s *downStream) isConnect() bool {
+	if s.downstreamReqHeaders == nil {
+		return false
+	}
+	method, _ := s.downstreamReqHeaders.Get(protocol.MosnHeaderMethod)
+	return method == methodConnect
+}
+
+// connect terminates the CONNECT request. The request is authorised by the route matching its authority,
+// and the target is chosen by the load balancer of the matched cluster, e.g. an ORIGINAL_DST cluster dials the authority.
+// The stream is switched into a tunnel after the connect response is sent, and ends when the tunnel is closed.
+func (s *downStream) connect() {
+	stream, ok := s.responseSender.GetStream().(types.ConnectStream)
+	if !ok {
+		log.Proxy.Errorf(s.context, "[proxy] [downstream] the stream does not support connect, proxyId = %d", s.ID)
+		s.sendHijackReply(http.NotImplemented, s.downstreamReqHeaders)
+		return
+	}
+	// the tunnel holds an upstream connection until it is closed
+	connections := s.cluster.ResourceManager().Connections()
+	if !connections.CanCreate() {
+		log.Proxy.Errorf(s.context, "[proxy] [downstream] connections overflow, proxyId = %d, cluster = %s", s.ID, s.cluster.Name())
+		s.requestInfo.SetResponseFlag(types.UpstreamOverflow)
+		s.sendHijackReply(types.UpstreamOverFlowCode, s.downstreamReqHeaders)
+		return
+	}
+	host := s.snapshot.LoadBalancer().ChooseHost(s)
+	if host == nil {
+		log.Proxy.Errorf(s.context, "[proxy] [downstream] no host to connect, proxyId = %d, cluster = %s", s.ID, s.cluster.Name())
+		s.requestInfo.SetResponseFlag(types.NoHealthyUpstream)
+		s.sendHijackReply(types.NoHealthUpstreamCode, s.downstreamReqHeaders)
+		return
+	}
+	s.requestInfo.OnUpstreamHostSelected(host)
+	s.requestInfo.SetUpstreamLocalAddress(host.AddressString())
+
+	connection := host.CreateConnection(s.context).Connection
+	connections.Increase()
+	s.tunnelConnections = connections
+	if err := stream.Tunnel(connection, s.connectIdleTimeout(), s); err != nil {
+		connections.Decrease()
+		s.tunnelConnections = nil
+		log.Proxy.Errorf(s.context, "[proxy] [downstream] connect to %s failed, proxyId = %d, error: %v", host.AddressString(), s.ID, err)
+		s.requestInfo.SetResponseFlag(types.UpstreamConnectionFailure)
+		s.sendHijackReply(types.NoHealthUpstreamCode, s.downstreamReqHeaders)
+		return
+	}
+	log.GetDebugTrace(s.context).Record("connect: %s", host.AddressString())
+	s.upgraded = true
+
+	// the connect response has no request headers echoed
+	s.requestInfo.SetResponseCode(http.OK)
+	atomic.StoreUint32(&s.reuseBuffer, 0)
+	s.downstreamRespHeaders = protocol.CommonHeader{
+		types.HeaderStatus: strconv.Itoa(http.OK),
+	}
+	s.downstreamRespDataBuf = nil
+	s.downstreamRespTrailers = nil
+	s.directResponse = true
+}
+
+func (s *downStream) connectIdleTimeout() time.Duration {
+	if s.proxy.config == nil || s.proxy.config.Connect == nil || s.proxy.config.Connect.IdleTimeout == nil {
+		return 0
+	}
+	return s.proxy.config.Connect.IdleTimeout.Duration
+}
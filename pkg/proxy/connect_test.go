@@ -0,0 +1,245 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockConnectSender struct {
+	mockResponseSender
+	mockStream
+	fail        bool
+	upstream    types.ClientConnection
+	idleTimeout time.Duration
+	listener    types.TunnelEventListener
+}
+
+func (s *mockConnectSender) GetStream() types.Stream {
+	return s
+}
+
+func (s *mockConnectSender) Tunnel(upstream types.ClientConnection, idleTimeout time.Duration, listener types.TunnelEventListener) error {
+	if s.fail {
+		return errors.New("connection refused")
+	}
+	s.upstream = upstream
+	s.idleTimeout = idleTimeout
+	s.listener = listener
+	return nil
+}
+
+type mockConnectSnapshot struct {
+	types.ClusterSnapshot
+	host types.Host
+}
+
+func (snapshot *mockConnectSnapshot) LoadBalancer() types.LoadBalancer {
+	return &mockConnectLoadBalancer{host: snapshot.host}
+}
+
+type mockConnectLoadBalancer struct {
+	types.LoadBalancer
+	host types.Host
+}
+
+func (lb *mockConnectLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	return lb.host
+}
+
+type mockConnectClusterInfo struct {
+	types.ClusterInfo
+	connections *mockConnectResource
+}
+
+func (ci *mockConnectClusterInfo) Name() string {
+	return "egress"
+}
+
+func (ci *mockConnectClusterInfo) ResourceManager() types.ResourceManager {
+	return &mockConnectResourceManager{connections: ci.connections}
+}
+
+type mockConnectResourceManager struct {
+	types.ResourceManager
+	connections *mockConnectResource
+}
+
+func (mgr *mockConnectResourceManager) Connections() types.Resource {
+	return mgr.connections
+}
+
+type mockConnectResource struct {
+	types.Resource
+	max    int
+	active int
+}
+
+func (r *mockConnectResource) CanCreate() bool {
+	return r.active < r.max
+}
+
+func (r *mockConnectResource) Increase() {
+	r.active++
+}
+
+func (r *mockConnectResource) Decrease() {
+	r.active--
+}
+
+type mockConnectHost struct {
+	types.Host
+	connection types.ClientConnection
+}
+
+func (h *mockConnectHost) AddressString() string {
+	return "example.com:443"
+}
+
+func (h *mockConnectHost) CreateConnection(context context.Context) types.CreateConnectionData {
+	return types.CreateConnectionData{
+		Connection: h.connection,
+		HostInfo:   h,
+	}
+}
+
+type mockConnectConnection struct {
+	types.ClientConnection
+}
+
+func newConnectStream(sender types.StreamSender, host types.Host) *downStream {
+	idleTimeout := v2.DurationConfig{Duration: time.Minute}
+	s := &downStream{
+		proxy: &proxy{
+			config: &v2.Proxy{
+				Connect: &v2.ConnectProxy{IdleTimeout: &idleTimeout},
+			},
+			clusterManager: &mockClusterManager{},
+			readCallbacks:  &mockReadFilterCallbacks{},
+			stats:          globalStats,
+			listenerStats:  newListenerStats("test"),
+		},
+		responseSender: sender,
+		requestInfo:    &network.RequestInfo{},
+		context:        context.Background(),
+		downstreamReqHeaders: protocol.CommonHeader{
+			protocol.MosnHeaderMethod:  "CONNECT",
+			protocol.MosnHeaderHostKey: "example.com:443",
+		},
+		route:    &mockRoute{},
+		cluster:  &mockConnectClusterInfo{connections: &mockConnectResource{max: 1}},
+		snapshot: &mockConnectSnapshot{host: host},
+	}
+	return s
+}
+
+func TestConnect(t *testing.T) {
+	initGlobalStats()
+	sender := &mockConnectSender{}
+	host := &mockConnectHost{connection: &mockConnectConnection{}}
+	s := newConnectStream(sender, host)
+	if !s.isConnect() {
+		t.Fatal("the request should be a connect request")
+	}
+	s.connect()
+	// the connect response is sent as a direct response
+	phase, _ := s.processError(0)
+	s.receive(s.context, 0, phase)
+
+	if sender.upstream != host.connection || sender.idleTimeout != time.Minute || sender.listener == nil {
+		t.Fatalf("the stream is not tunnelled: %+v", sender)
+	}
+	if status, _ := sender.headers.Get(types.HeaderStatus); status != "200" {
+		t.Errorf("connect response should be sent, but got %v", sender.headers)
+	}
+	if _, ok := sender.headers.Get(protocol.MosnHeaderHostKey); ok {
+		t.Error("connect response should not echo the request headers")
+	}
+	if s.requestInfo.UpstreamHost() != host {
+		t.Error("upstream host is not recorded")
+	}
+	if s.downstreamCleaned != 0 {
+		t.Error("connect stream should not be cleaned before the tunnel closed")
+	}
+	connections := s.cluster.(*mockConnectClusterInfo).connections
+	if connections.active != 1 {
+		t.Errorf("the tunnel should hold a connection, but got %d", connections.active)
+	}
+
+	sender.listener.OnTunnelClose(10, 20)
+	if s.downstreamCleaned != 1 {
+		t.Error("connect stream should be cleaned after the tunnel closed")
+	}
+	if connections.active != 0 {
+		t.Errorf("the connection should be released after the tunnel closed, but got %d", connections.active)
+	}
+	if s.requestInfo.BytesReceived() != 10 || s.requestInfo.BytesSent() != 20 {
+		t.Errorf("unexpected bytes: %d, %d", s.requestInfo.BytesReceived(), s.requestInfo.BytesSent())
+	}
+}
+
+func TestConnectFailed(t *testing.T) {
+	initGlobalStats()
+	host := &mockConnectHost{connection: &mockConnectConnection{}}
+	for _, tc := range []struct {
+		sender   types.StreamSender
+		host     types.Host
+		overflow bool
+		code     int
+		flag     types.ResponseFlag
+	}{
+		// no host is chosen
+		{&mockConnectSender{}, nil, false, types.NoHealthUpstreamCode, types.NoHealthyUpstream},
+		// the upstream cannot be connected
+		{&mockConnectSender{fail: true}, host, false, types.NoHealthUpstreamCode, types.UpstreamConnectionFailure},
+		// the connections of the cluster overflow
+		{&mockConnectSender{}, host, true, types.UpstreamOverFlowCode, types.UpstreamOverflow},
+		// not a connect stream
+		{&mockResponseSender{}, host, false, 501, 0},
+	} {
+		s := newConnectStream(tc.sender, tc.host)
+		connections := s.cluster.(*mockConnectClusterInfo).connections
+		if tc.overflow {
+			connections.max = 0
+		}
+		s.connect()
+		phase, _ := s.processError(0)
+		s.receive(s.context, 0, phase)
+
+		if s.upgraded {
+			t.Error("the stream should not be tunnelled")
+		}
+		if s.requestInfo.ResponseCode() != tc.code || s.downstreamCleaned != 1 {
+			t.Errorf("expected a %d response, but got %d", tc.code, s.requestInfo.ResponseCode())
+		}
+		if tc.flag != 0 && !s.requestInfo.GetResponseFlag(tc.flag) {
+			t.Errorf("expected the response flag %d", tc.flag)
+		}
+		if connections.active != 0 {
+			t.Errorf("no connection should be held, but got %d", connections.active)
+		}
+	}
+}
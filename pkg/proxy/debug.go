@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"reflect"
+	"strings"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	defaultDebugHeader         = "x-mosn-debug"
+	defaultDebugResponseHeader = "x-mosn-debug-trace"
+)
+
+// checkDebug turns on the debug mode of the request if it carries the debug header with the token,
+// the debug header is removed so that it is not forwarded to the upstream.
+// The debug mode is disabled if no token is configured.
+func (s *downStream) checkDebug(headers types.HeaderMap) {
+	if s.proxy.config == nil || s.proxy.config.Debug == nil || s.proxy.config.Debug.Token == "" || headers == nil {
+		return
+	}
+	cfg := s.proxy.config.Debug
+	name := cfg.Header
+	if name == "" {
+		name = defaultDebugHeader
+	}
+	if value, ok := headers.Get(name); !ok || value != cfg.Token {
+		return
+	}
+	headers.Del(name)
+	s.context = log.WithDebugTrace(s.context)
+	log.Proxy.Debugf(s.context, "[proxy] [downstream] debug mode is on, proxyId = %d", s.ID)
+}
+
+// writeDebugTrace returns the routing decisions of a request in debug mode to the downstream
+func (s *downStream) writeDebugTrace(headers types.HeaderMap) {
+	trace := log.GetDebugTrace(s.context)
+	if trace == nil || headers == nil {
+		return
+	}
+	name := s.proxy.config.Debug.ResponseHeader
+	if name == "" {
+		name = defaultDebugResponseHeader
+	}
+	headers.Set(name, trace.String())
+}
+
+// describeRoute returns the virtual host and path matcher of the route rule
+func describeRoute(rule types.RouteRule) string {
+	var desc []string
+	if vh := rule.VirtualHost(); vh != nil && !reflect.ValueOf(vh).IsNil() {
+		desc = append(desc, "virtual_host="+vh.Name())
+	}
+	if pm := rule.PathMatchCriterion(); pm != nil && !reflect.ValueOf(pm).IsNil() {
+		desc = append(desc, "path="+pm.Matcher())
+	}
+	return strings.Join(desc, ",")
+}
@@ -36,6 +36,7 @@ import (
 	"mosn.io/mosn/pkg/buffer"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/grpc"
 	"mosn.io/mosn/pkg/protocol/http"
 	"mosn.io/mosn/pkg/router"
 	"mosn.io/mosn/pkg/types"
@@ -90,6 +91,12 @@ type downStream struct {
 	noConvert bool
 	// direct response.  e.g. sendHijack
 	directResponse bool
+	// the request is converted with its data as a whole message, so is the response
+	messageConverted bool
+	// the connections are switched into a tunnel, e.g. WebSocket and CONNECT
+	upgraded bool
+	// the cluster connection resource held by the CONNECT tunnel, released when the tunnel is closed
+	tunnelConnections types.Resource
 	// oneway
 	oneway bool
 
@@ -325,7 +332,9 @@ func (s *downStream) OnReceive(ctx context.Context, headers types.HeaderMap, dat
 	}
 	s.downstreamReqTrailers = trailers
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	s.checkDebug(headers)
+
+	if log.DebugEnabled(s.context) {
 		log.Proxy.Debugf(s.context, "[proxy] [downstream] OnReceive headers:%+v, data:%+v, trailers:%+v", headers, data, trailers)
 	}
 
@@ -371,7 +380,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 
 			// downstream filter before route
 		case types.DownFilter:
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 			}
 			s.runReceiveFilters(phase, s.downstreamReqHeaders, s.downstreamReqDataBuf, s.downstreamReqTrailers)
@@ -383,7 +392,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 
 			// match route
 		case types.MatchRoute:
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 			}
 			s.matchRoute()
@@ -394,7 +403,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 
 			// downstream filter after route
 		case types.DownFilterAfterRoute:
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 			}
 			s.runReceiveFilters(phase, s.downstreamReqHeaders, s.downstreamReqDataBuf, s.downstreamReqTrailers)
@@ -407,7 +416,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 			// downstream receive header
 		case types.DownRecvHeader:
 			if s.downstreamReqHeaders != nil {
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(s.context) {
 					log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 				}
 				s.receiveHeaders(s.downstreamReqDataBuf == nil && s.downstreamReqTrailers == nil)
@@ -421,7 +430,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 			// downstream receive data
 		case types.DownRecvData:
 			if s.downstreamReqDataBuf != nil {
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(s.context) {
 					log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 				}
 				s.downstreamReqDataBuf.Count(1)
@@ -436,7 +445,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 			// downstream receive trailer
 		case types.DownRecvTrailer:
 			if s.downstreamReqTrailers != nil {
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(s.context) {
 					log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 				}
 				s.receiveTrailers()
@@ -450,7 +459,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 			// downstream oneway
 		case types.Oneway:
 			if s.oneway {
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(s.context) {
 					log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 				}
 				s.cleanStream()
@@ -466,7 +475,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 
 			// retry request
 		case types.Retry:
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 			}
 
@@ -481,14 +490,14 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 
 			// wait for upstreamRequest or reset
 		case types.WaitNofity:
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 			}
 			if p, err := s.waitNotify(id); err != nil {
 				return p
 			}
 
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] OnReceive send downstream response %+v", s.downstreamRespHeaders)
 			}
 
@@ -496,7 +505,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 
 			// upstream filter
 		case types.UpFilter:
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 			}
 			s.runAppendFilters(phase, s.downstreamRespHeaders, s.downstreamRespDataBuf, s.downstreamRespTrailers)
@@ -520,7 +529,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 		case types.UpRecvHeader:
 			// send downstream response
 			if s.downstreamRespHeaders != nil {
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(s.context) {
 					log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 				}
 				s.upstreamRequest.receiveHeaders(s.downstreamRespDataBuf == nil && s.downstreamRespTrailers == nil)
@@ -534,7 +543,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 			// upstream receive data
 		case types.UpRecvData:
 			if s.downstreamRespDataBuf != nil {
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(s.context) {
 					log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 				}
 				s.upstreamRequest.receiveData(s.downstreamRespTrailers == nil)
@@ -548,7 +557,7 @@ func (s *downStream) receive(ctx context.Context, id uint32, phase types.Phase)
 			// upstream receive triler
 		case types.UpRecvTrailer:
 			if s.downstreamRespTrailers != nil {
-				if log.Proxy.GetLogLevel() >= log.DEBUG {
+				if log.DebugEnabled(s.context) {
 					log.Proxy.Debugf(s.context, "[proxy] [downstream] enter phase %d, proxyId = %d  ", phase, id)
 				}
 				s.upstreamRequest.receiveTrailers()
@@ -643,6 +652,7 @@ func (s *downStream) receiveHeaders(endStream bool) {
 	// direct response will response now
 	if resp := s.route.DirectResponseRule(); !(resp == nil || reflect.ValueOf(resp).IsNil()) {
 		log.Proxy.Infof(s.context, "[proxy] [downstream] direct response, proxyId = %d", s.ID)
+		log.GetDebugTrace(s.context).Record("direct response: %d", resp.StatusCode())
 		if resp.Body() != "" {
 			s.sendHijackReplyWithBody(resp.StatusCode(), s.downstreamReqHeaders, resp.Body())
 		} else {
@@ -667,13 +677,21 @@ func (s *downStream) receiveHeaders(endStream bool) {
 	// as ClusterName has random factor when choosing weighted cluster,
 	// so need determination at the first time
 	clusterName := s.route.RouteRule().ClusterName()
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	log.GetDebugTrace(s.context).Record("route matched: %s", describeRoute(s.route.RouteRule()))
+	log.GetDebugTrace(s.context).Record("cluster: %s", clusterName)
+	if log.DebugEnabled(s.context) {
 		log.Proxy.Debugf(s.context, "[proxy] [downstream] route match result:%+v, clusterName=%v", s.route, clusterName)
 	}
 
 	s.cluster = s.snapshot.ClusterInfo()
 	s.requestInfo.SetRouteEntry(s.route.RouteRule())
 
+	// the CONNECT request is terminated here, there is no upstream request
+	if s.isConnect() {
+		s.connect()
+		return
+	}
+
 	pool, err := s.initializeUpstreamConnectionPool(s)
 	if err != nil {
 		log.Proxy.Alertf(s.context, types.ErrorKeyUpstreamConn, "initialize Upstream Connection Pool error, request can't be proxyed, error = %v", err)
@@ -683,7 +701,7 @@ func (s *downStream) receiveHeaders(endStream bool) {
 	}
 
 	parseProxyTimeout(&s.timeout, s.route, s.downstreamReqHeaders)
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(s.context) {
 		log.Proxy.Debugf(s.context, "[proxy] [downstream] timeout info: %+v", s.timeout)
 	}
 
@@ -714,7 +732,7 @@ func (s *downStream) receiveData(endStream bool) {
 		return
 	}
 	data := s.downstreamReqDataBuf
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(s.context) {
 		log.Proxy.Debugf(s.context, "[proxy] [downstream] receive data = %v", data)
 	}
 
@@ -778,7 +796,7 @@ func (s *downStream) onUpstreamRequestSent() {
 
 		// setup global timeout timer
 		if s.timeout.GlobalTimeout > 0 {
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(s.context) {
 				log.Proxy.Debugf(s.context, "[proxy] [downstream] start a request timeout timer")
 			}
 			if s.responseTimer != nil {
@@ -906,13 +924,30 @@ func (s *downStream) initializeUpstreamConnectionPool(lbCtx types.LoadBalancerCo
 
 func (s *downStream) appendHeaders(endStream bool) {
 	s.upstreamProcessDone = endStream
-	headers := s.convertHeader(s.downstreamRespHeaders)
+	s.writeDebugTrace(s.downstreamRespHeaders)
+	if id := protocol.GetRequestID(s.context); id != "" && s.downstreamRespHeaders != nil {
+		s.downstreamRespHeaders.Set(protocol.HeaderRequestID, id)
+	}
+	var headers types.HeaderMap
+	// the converted response may have the data that the upstream response does not have
+	appendData := false
+	if s.messageConverted {
+		headers = s.convertMessage(s.downstreamRespHeaders)
+		appendData = endStream && s.downstreamRespDataBuf != nil
+	} else {
+		headers = s.convertHeader(s.downstreamRespHeaders)
+	}
 	//Currently, just log the error
-	if err := s.responseSender.AppendHeaders(s.context, headers, endStream); err != nil {
+	if err := s.responseSender.AppendHeaders(s.context, headers, endStream && !appendData); err != nil {
 		log.Proxy.Alertf(s.context, types.ErrorKeyAppendHeader, "append headers error: %s", err)
 	}
+	if appendData {
+		s.appendData(true)
+		return
+	}
 
-	if endStream {
+	// the upgraded stream ends when the tunnel is closed
+	if endStream && !s.upgraded {
 		s.endStream()
 	}
 }
@@ -935,6 +970,24 @@ func (s *downStream) convertHeader(headers types.HeaderMap) types.HeaderMap {
 	return headers
 }
 
+// convertMessage converts the response headers with the data as a whole message,
+// the converted data replaces the response data
+func (s *downStream) convertMessage(headers types.HeaderMap) types.HeaderMap {
+	dp, up := s.convertProtocol()
+
+	convHeader, convData, err := protocol.ConvertMessage(s.context, up, dp, headers, s.downstreamRespDataBuf)
+	// the hijack reply is not an upstream response, converts it as before
+	if err == protocol.ErrUnsupportedMessage {
+		return s.convertHeader(headers)
+	}
+	if err != nil {
+		log.Proxy.Warnf(s.context, "[proxy] [downstream] convert message from %s to %s failed, %s", up, dp, err.Error())
+		return headers
+	}
+	s.downstreamRespDataBuf = convData
+	return convHeader
+}
+
 func (s *downStream) appendData(endStream bool) {
 	s.upstreamProcessDone = endStream
 
@@ -948,7 +1001,8 @@ func (s *downStream) appendData(endStream bool) {
 }
 
 func (s *downStream) convertData(data types.IoBuffer) types.IoBuffer {
-	if s.noConvert {
+	// the data is converted with the headers
+	if s.noConvert || s.messageConverted {
 		return data
 	}
 
@@ -996,7 +1050,7 @@ func (s *downStream) onUpstreamReset(reason types.StreamResetReason) {
 	// see if we need a retry
 	if reason != types.UpstreamGlobalTimeout &&
 		!s.downstreamResponseStarted && s.retryState != nil {
-		retryCheck := s.retryState.retry(nil, reason)
+		retryCheck := s.retryState.retry(nil, nil, reason)
 
 		if retryCheck == types.ShouldRetry && s.setupRetry(true) {
 			if s.upstreamRequest != nil && s.upstreamRequest.host != nil {
@@ -1007,6 +1061,7 @@ func (s *downStream) onUpstreamReset(reason types.StreamResetReason) {
 			// setup retry timer and return
 			// clear reset flag
 			log.Proxy.Infof(s.context, "[proxy] [downstream] onUpstreamReset, doRetry, reason %v", reason)
+			log.GetDebugTrace(s.context).Record("retry: %v", reason)
 			atomic.CompareAndSwapUint32(&s.upstreamReset, 1, 0)
 			return
 		} else if retryCheck == types.RetryOverflow {
@@ -1050,7 +1105,7 @@ func (s *downStream) onUpstreamHeaders(endStream bool) {
 
 	// check retry
 	if s.retryState != nil {
-		retryCheck := s.retryState.retry(headers, "")
+		retryCheck := s.retryState.retry(headers, s.downstreamRespTrailers, "")
 
 		if retryCheck == types.ShouldRetry && s.setupRetry(endStream) {
 			if s.upstreamRequest != nil && s.upstreamRequest.host != nil {
@@ -1075,6 +1130,13 @@ func (s *downStream) onUpstreamHeaders(endStream bool) {
 		s.route.RouteRule().FinalizeResponseHeaders(headers, s.requestInfo)
 	}
 
+	if s.requestInfo.ResponseCode() == http.SwitchingProtocols && !s.upgrade() {
+		// the tunnel cannot be set up, reply the downstream with an error instead
+		s.upstreamRequest.resetStream()
+		s.sendHijackReply(http.BadGateway, s.downstreamReqHeaders)
+		endStream = true
+	}
+
 	if endStream {
 		s.onUpstreamResponseRecvFinished()
 	}
@@ -1086,7 +1148,12 @@ func (s *downStream) onUpstreamHeaders(endStream bool) {
 func (s *downStream) handleUpstreamStatusCode() {
 	// todo: support config?
 	if s.upstreamRequest != nil && s.upstreamRequest.host != nil {
-		if s.requestInfo.ResponseCode() >= http.InternalServerError {
+		code := s.requestInfo.ResponseCode()
+		if grpc.IsGrpcRequest(s.downstreamReqHeaders) {
+			// the http status of a gRPC response is always 200, uses the grpc-status instead
+			code = grpc.StatusToHTTP(grpc.ResponseStatus(s.downstreamRespHeaders, s.downstreamRespTrailers))
+		}
+		if code >= http.InternalServerError {
 			s.upstreamRequest.host.HostStats().UpstreamResponseFailed.Inc(1)
 			s.upstreamRequest.host.ClusterInfo().Stats().UpstreamResponseFailed.Inc(1)
 		} else {
@@ -1214,6 +1281,7 @@ func (s *downStream) resetStream() {
 
 func (s *downStream) sendHijackReply(code int, headers types.HeaderMap) {
 	log.Proxy.Infof(s.context, "[proxy] [downstream] set hijack reply, proxyId = %d, code = %d", s.ID, code)
+	log.GetDebugTrace(s.context).Record("hijack: %d", code)
 	if headers == nil {
 		log.Proxy.Warnf(s.context, "[proxy] [downstream] hijack with no headers, proxyId = %d", s.ID)
 		raw := make(map[string]string, 5)
@@ -1222,6 +1290,9 @@ func (s *downStream) sendHijackReply(code int, headers types.HeaderMap) {
 	s.requestInfo.SetResponseCode(code)
 
 	headers.Set(types.HeaderStatus, strconv.Itoa(code))
+	if grpc.IsGrpcRequest(s.downstreamReqHeaders) {
+		setGrpcLocalReply(headers, code, "")
+	}
 	atomic.StoreUint32(&s.reuseBuffer, 0)
 	s.downstreamRespHeaders = headers
 	s.downstreamRespDataBuf = nil
@@ -1233,6 +1304,7 @@ func (s *downStream) sendHijackReply(code int, headers types.HeaderMap) {
 // TODO: rpc content(body) is not matched the headers, rpc should not hijack with body, use sendHijackReply instead
 func (s *downStream) sendHijackReplyWithBody(code int, headers types.HeaderMap, body string) {
 	log.Proxy.Infof(s.context, "[proxy] [downstream] set hijack reply with body, proxyId = %d, code = %d", s.ID, code)
+	log.GetDebugTrace(s.context).Record("hijack: %d", code)
 	if headers == nil {
 		log.Proxy.Warnf(s.context, "[proxy] [downstream] hijack with no headers, proxyId = %d", s.ID)
 		raw := make(map[string]string, 5)
@@ -1242,11 +1314,36 @@ func (s *downStream) sendHijackReplyWithBody(code int, headers types.HeaderMap,
 	headers.Set(types.HeaderStatus, strconv.Itoa(code))
 	atomic.StoreUint32(&s.reuseBuffer, 0)
 	s.downstreamRespHeaders = headers
-	s.downstreamRespDataBuf = buffer.NewIoBufferString(body)
+	if grpc.IsGrpcRequest(s.downstreamReqHeaders) {
+		// the body is not a gRPC message, sends it as the grpc-message
+		setGrpcLocalReply(headers, code, body)
+		s.downstreamRespDataBuf = nil
+	} else {
+		s.downstreamRespDataBuf = buffer.NewIoBufferString(body)
+	}
 	s.downstreamRespTrailers = nil
 	s.directResponse = true
 }
 
+// setGrpcLocalReply makes the local reply of a gRPC request a trailers-only response,
+// the http status is always 200 and the error is in the grpc-status
+func setGrpcLocalReply(headers types.HeaderMap, code int, message string) {
+	status := grpc.HTTPToStatus(code)
+	switch code {
+	case types.TimeoutExceptionCode:
+		status = grpc.DeadlineExceeded
+	case types.LimitExceededCode:
+		status = grpc.ResourceExhausted
+	case types.CodecExceptionCode, types.DeserialExceptionCode:
+		status = grpc.Internal
+	}
+	if message == "" && status != grpc.OK {
+		message = fmt.Sprintf("mosn local reply with code %d", code)
+	}
+	grpc.SetStatus(headers, status, message)
+	headers.Set(types.HeaderStatus, strconv.Itoa(http.OK))
+}
+
 func (s *downStream) cleanUp() {
 	// reset retry state
 	// if  a downstream filter ends downstream before send to upstream, retryState will be nil
@@ -1322,7 +1419,7 @@ func (s *downStream) giveStream() {
 		return
 	}
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(s.context) {
 		log.Proxy.Debugf(s.context, "[proxy] [downstream] giveStream %p %+v", s, s)
 	}
 
@@ -1363,7 +1460,7 @@ func (s *downStream) waitNotify(id uint32) (phase types.Phase, err error) {
 		return types.End, types.ErrExit
 	}
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(s.context) {
 		log.Proxy.Debugf(s.context, "[proxy] [downstream] waitNotify begin %p, proxyId = %d", s, s.ID)
 	}
 	select {
@@ -148,6 +148,125 @@ func TestDirectResponse(t *testing.T) {
 	}
 }
 
+func TestDebugHeader(t *testing.T) {
+	testCases := []struct {
+		token string
+		value string
+		debug bool
+	}{
+		{"token", "token", true},
+		{"token", "invalid", false},
+		// the debug mode is disabled without a token
+		{"", "", false},
+		{"", "any", false},
+	}
+	for _, tc := range testCases {
+		client := &mockResponseSender{}
+		s := &downStream{
+			proxy: &proxy{
+				config: &v2.Proxy{
+					Debug: &v2.ProxyDebug{
+						Token: tc.token,
+					},
+				},
+				routersWrapper: &mockRouterWrapper{
+					routers: &mockRouters{
+						route: &mockRoute{
+							direct: &mockDirectRule{
+								status: 200,
+							},
+						},
+					},
+				},
+				clusterManager: &mockClusterManager{},
+				readCallbacks:  &mockReadFilterCallbacks{},
+				stats:          globalStats,
+				listenerStats:  newListenerStats("test"),
+			},
+			responseSender: client,
+			requestInfo:    &network.RequestInfo{},
+			context:        context.Background(),
+		}
+		headers := protocol.CommonHeader{
+			defaultDebugHeader: tc.value,
+		}
+		s.OnReceive(context.Background(), headers, buffer.NewIoBuffer(1), nil)
+		time.Sleep(100 * time.Millisecond)
+		if client.headers == nil {
+			t.Fatal("want to receive a header response")
+		}
+		trace, ok := client.headers.Get(defaultDebugResponseHeader)
+		if tc.debug {
+			if !ok || trace != "direct response: 200; hijack: 200" {
+				t.Errorf("unexpected debug trace: %s", trace)
+			}
+			if _, ok := headers.Get(defaultDebugHeader); ok {
+				t.Error("debug header should not be forwarded")
+			}
+		} else if ok {
+			t.Errorf("request with invalid token should not be in debug mode, trace: %s", trace)
+		}
+	}
+}
+
+func TestGrpcDirectResponse(t *testing.T) {
+	testCases := []struct {
+		status  int
+		body    string
+		grpc    string
+		message string
+	}{
+		{503, "", "14", "mosn local reply with code 503"},
+		{404, "no route", "12", "no route"},
+		{504, "", "4", "mosn local reply with code 504"},
+	}
+	for _, tc := range testCases {
+		client := &mockResponseSender{}
+		s := &downStream{
+			proxy: &proxy{
+				config: &v2.Proxy{},
+				routersWrapper: &mockRouterWrapper{
+					routers: &mockRouters{
+						route: &mockRoute{
+							direct: &mockDirectRule{
+								status: tc.status,
+								body:   tc.body,
+							},
+						},
+					},
+				},
+				clusterManager: &mockClusterManager{},
+				readCallbacks:  &mockReadFilterCallbacks{},
+				stats:          globalStats,
+				listenerStats:  newListenerStats("test"),
+			},
+			responseSender: client,
+			requestInfo:    &network.RequestInfo{},
+			context:        context.Background(),
+		}
+		headers := protocol.CommonHeader{
+			"content-type": "application/grpc+proto",
+		}
+		s.OnReceive(context.Background(), headers, buffer.NewIoBuffer(1), nil)
+		time.Sleep(100 * time.Millisecond)
+		if client.headers == nil {
+			t.Fatal("want to receive a header response")
+		}
+		status, _ := client.headers.Get(types.HeaderStatus)
+		grpcStatus, _ := client.headers.Get("grpc-status")
+		message, _ := client.headers.Get("grpc-message")
+		if status != "200" || grpcStatus != tc.grpc || message != tc.message {
+			t.Errorf("unexpected grpc local reply: %v", client.headers)
+		}
+		if client.data != nil {
+			t.Errorf("grpc local reply should be trailers-only, but got body: %s", client.data.String())
+		}
+		if s.requestInfo.ResponseCode() != tc.status {
+			t.Errorf("response code should be %d, but got %d", tc.status, s.requestInfo.ResponseCode())
+		}
+	}
+}
+
 func TestOnewayHijack(t *testing.T) {
 	initGlobalStats()
 	proxy := &proxy{
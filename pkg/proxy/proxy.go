@@ -212,7 +212,7 @@ func (p *proxy) NewStreamDetect(ctx context.Context, responseSender types.Stream
 		ffs, ok := ff.Load().([]types.StreamFilterChainFactory)
 		if ok {
 
-			if log.Proxy.GetLogLevel() >= log.DEBUG {
+			if log.DebugEnabled(stream.context) {
 				log.Proxy.Debugf(stream.context, "[proxy][downstream] %d stream filters in config", len(ffs))
 			}
 
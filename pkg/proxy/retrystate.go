@@ -19,6 +19,7 @@ package proxy
 
 import (
 	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/grpc"
 	"mosn.io/mosn/pkg/protocol/http"
 	"mosn.io/mosn/pkg/types"
 )
@@ -30,6 +31,7 @@ type retryState struct {
 	retryOn          bool
 	retiesRemaining  uint32
 	upstreamProtocol types.Protocol
+	grpc             bool
 }
 
 func newRetryState(retryPolicy types.RetryPolicy,
@@ -41,6 +43,7 @@ func newRetryState(retryPolicy types.RetryPolicy,
 		retryOn:          retryPolicy.RetryOn(),
 		retiesRemaining:  3,
 		upstreamProtocol: proto,
+		grpc:             grpc.IsGrpcRequest(requestHeaders),
 	}
 
 	if retryPolicy.NumRetries() > rs.retiesRemaining {
@@ -50,10 +53,10 @@ func newRetryState(retryPolicy types.RetryPolicy,
 	return rs
 }
 
-func (r *retryState) retry(headers types.HeaderMap, reason types.StreamResetReason) types.RetryCheckStatus {
+func (r *retryState) retry(headers types.HeaderMap, trailers types.HeaderMap, reason types.StreamResetReason) types.RetryCheckStatus {
 	r.reset()
 
-	check := r.shouldRetry(headers, reason)
+	check := r.shouldRetry(headers, trailers, reason)
 
 	if check != 0 {
 		return check
@@ -65,14 +68,14 @@ func (r *retryState) retry(headers types.HeaderMap, reason types.StreamResetReas
 	return 0
 }
 
-func (r *retryState) shouldRetry(headers types.HeaderMap, reason types.StreamResetReason) types.RetryCheckStatus {
+func (r *retryState) shouldRetry(headers types.HeaderMap, trailers types.HeaderMap, reason types.StreamResetReason) types.RetryCheckStatus {
 	if r.retiesRemaining == 0 {
 		return types.NoRetry
 	}
 
 	r.retiesRemaining--
 
-	if !r.doRetryCheck(headers, reason) {
+	if !r.doRetryCheck(headers, trailers, reason) {
 		return types.NoRetry
 	}
 
@@ -85,13 +88,17 @@ func (r *retryState) shouldRetry(headers types.HeaderMap, reason types.StreamRes
 	return types.ShouldRetry
 }
 
-func (r *retryState) doRetryCheck(headers types.HeaderMap, reason types.StreamResetReason) bool {
+func (r *retryState) doRetryCheck(headers types.HeaderMap, trailers types.HeaderMap, reason types.StreamResetReason) bool {
 	if reason == types.StreamOverflow {
 		return false
 	}
 
 	if r.retryOn {
 		// TODO: add retry policy to decide retry or not. use default policy now
+		if headers != nil && r.grpc {
+			// the http status of a gRPC response is always 200, checks the grpc-status instead
+			return isGrpcRetriable(grpc.ResponseStatus(headers, trailers))
+		}
 		if headers != nil {
 			// default policy , mapping all headers to http status code
 			code, err := protocol.MappingHeaderStatusCode(r.upstreamProtocol, headers)
@@ -122,6 +129,16 @@ func (r *retryState) doRetryCheck(headers types.HeaderMap, reason types.StreamRe
 	return false
 }
 
+// isGrpcRetriable checks the gRPC status is a temporary failure
+func isGrpcRetriable(status grpc.Status) bool {
+	switch status {
+	case grpc.Canceled, grpc.DeadlineExceeded, grpc.ResourceExhausted, grpc.Internal, grpc.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
+
 func (r *retryState) reset() {
 	r.cluster.ResourceManager().Retries().Decrease()
 }
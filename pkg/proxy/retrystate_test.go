@@ -95,7 +95,7 @@ func TestRetryState(t *testing.T) {
 		{headerOK, "", types.NoRetry},
 	}
 	for i, tc := range testcases {
-		if rs.retry(tc.Header, tc.Reason) != tc.Expected {
+		if rs.retry(tc.Header, nil, tc.Reason) != tc.Expected {
 			t.Errorf("#%d retry state failed", i)
 		}
 	}
@@ -126,7 +126,49 @@ func TestRetryConnetionFailed(t *testing.T) {
 		{nil, types.StreamConnectionFailed, types.ShouldRetry},
 	}
 	for i, tc := range testcases {
-		if rs.retry(tc.Header, tc.Reason) != tc.Expected {
+		if rs.retry(tc.Header, nil, tc.Reason) != tc.Expected {
+			t.Errorf("#%d retry state failed", i)
+		}
+	}
+}
+
+func TestRetryGrpcStatus(t *testing.T) {
+	rcfg := &v2.Router{}
+	pcfg := &v2.RetryPolicy{
+		RetryPolicyConfig: v2.RetryPolicyConfig{
+			RetryOn:    true,
+			NumRetries: 10,
+		},
+		RetryTimeout: time.Second,
+	}
+	rcfg.Route = v2.RouteAction{}
+	rcfg.Route.RetryPolicy = pcfg
+	r, _ := router.NewRouteRuleImplBase(nil, rcfg)
+	policy := r.Policy().RetryPolicy()
+	clusterInfo := &fakeClusterInfo{
+		mgr: &fakeResourceManager{},
+	}
+	requestHeaders := protocol.CommonHeader{
+		"content-type": "application/grpc",
+	}
+	rs := newRetryState(policy, requestHeaders, clusterInfo, protocol.HTTP2)
+	headerOK := protocol.CommonHeader{
+		types.HeaderStatus: "200",
+	}
+	testcases := []struct {
+		Header   types.HeaderMap
+		Trailer  types.HeaderMap
+		Expected types.RetryCheckStatus
+	}{
+		{headerOK, protocol.CommonHeader{"grpc-status": "0"}, types.NoRetry},
+		{headerOK, protocol.CommonHeader{"grpc-status": "14"}, types.ShouldRetry},
+		{headerOK, protocol.CommonHeader{"grpc-status": "3"}, types.NoRetry},
+		// trailers-only response
+		{protocol.CommonHeader{types.HeaderStatus: "200", "grpc-status": "4"}, nil, types.ShouldRetry},
+		{protocol.CommonHeader{types.HeaderStatus: "503"}, nil, types.ShouldRetry},
+	}
+	for i, tc := range testcases {
+		if rs.retry(tc.Header, tc.Trailer, "") != tc.Expected {
 			t.Errorf("#%d retry state failed", i)
 		}
 	}
@@ -0,0 +1,66 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"errors"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+)
+
+// upgrade switches the downstream and the upstream connections into a tunnel on the upgrade response.
+// The stream ends when the tunnel is closed, so the access log covers the whole tunnel.
+func (s *downStream) upgrade() bool {
+	var err error
+	stream, ok := s.responseSender.GetStream().(types.UpgradableStream)
+	if !ok || s.upstreamRequest == nil || s.upstreamRequest.requestSender == nil {
+		err = errors.New("the stream is not upgradable")
+	} else {
+		err = stream.Upgrade(s.upstreamRequest.requestSender.GetStream(), s.tunnelIdleTimeout(), s)
+	}
+	if err != nil {
+		log.Proxy.Errorf(s.context, "[proxy] [downstream] upgrade failed, proxyId = %d, error: %v", s.ID, err)
+		return false
+	}
+	log.GetDebugTrace(s.context).Record("upgrade: tunnel")
+	s.upgraded = true
+	return true
+}
+
+func (s *downStream) tunnelIdleTimeout() time.Duration {
+	if s.proxy.config == nil || s.proxy.config.WebSocket == nil || s.proxy.config.WebSocket.IdleTimeout == nil {
+		return 0
+	}
+	return s.proxy.config.WebSocket.IdleTimeout.Duration
+}
+
+// types.TunnelEventListener
+func (s *downStream) OnTunnelClose(bytesReceived, bytesSent uint64) {
+	if log.DebugEnabled(s.context) {
+		log.Proxy.Debugf(s.context, "[proxy] [downstream] tunnel closed, proxyId = %d, bytes received = %d, bytes sent = %d",
+			s.ID, bytesReceived, bytesSent)
+	}
+	s.requestInfo.SetBytesReceived(s.requestInfo.BytesReceived() + bytesReceived)
+	s.requestInfo.SetBytesSent(s.requestInfo.BytesSent() + bytesSent)
+	if s.tunnelConnections != nil {
+		s.tunnelConnections.Decrease()
+	}
+	s.endStream()
+}
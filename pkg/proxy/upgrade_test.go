@@ -0,0 +1,149 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockUpgradableSender struct {
+	mockResponseSender
+	mockStream
+	fail        bool
+	upstream    types.Stream
+	idleTimeout time.Duration
+	listener    types.TunnelEventListener
+}
+
+func (s *mockUpgradableSender) GetStream() types.Stream {
+	return s
+}
+
+func (s *mockUpgradableSender) Upgrade(upstream types.Stream, idleTimeout time.Duration, listener types.TunnelEventListener) error {
+	if s.fail {
+		return errors.New("upgrade failed")
+	}
+	s.upstream = upstream
+	s.idleTimeout = idleTimeout
+	s.listener = listener
+	return nil
+}
+
+type mockRequestSender struct {
+	types.StreamSender
+	stream *mockUpstreamStream
+}
+
+func (s *mockRequestSender) GetStream() types.Stream {
+	return s.stream
+}
+
+type mockUpstreamStream struct {
+	types.Stream
+	reset bool
+}
+
+func (s *mockUpstreamStream) RemoveEventListener(listener types.StreamEventListener) {}
+
+func (s *mockUpstreamStream) ResetStream(reason types.StreamResetReason) {
+	s.reset = true
+}
+
+func newUpgradeStream(sender types.StreamSender, upstream *mockUpstreamStream) *downStream {
+	idleTimeout := v2.DurationConfig{Duration: time.Minute}
+	s := &downStream{
+		proxy: &proxy{
+			config: &v2.Proxy{
+				WebSocket: &v2.WebSocketProxy{IdleTimeout: &idleTimeout},
+			},
+			clusterManager: &mockClusterManager{},
+			readCallbacks:  &mockReadFilterCallbacks{},
+			stats:          globalStats,
+			listenerStats:  newListenerStats("test"),
+		},
+		responseSender:        sender,
+		requestInfo:           &network.RequestInfo{},
+		context:               context.Background(),
+		downstreamReqHeaders:  protocol.CommonHeader{"Connection": "Upgrade", "Upgrade": "websocket"},
+		downstreamRespHeaders: protocol.CommonHeader{types.HeaderStatus: "101"},
+		upstreamRequestSent:   true,
+	}
+	s.upstreamRequest = &upstreamRequest{
+		downStream:    s,
+		requestSender: &mockRequestSender{stream: upstream},
+	}
+	s.requestInfo.SetResponseCode(101)
+	return s
+}
+
+func TestUpgrade(t *testing.T) {
+	initGlobalStats()
+	sender := &mockUpgradableSender{}
+	upstream := &mockUpstreamStream{}
+	s := newUpgradeStream(sender, upstream)
+	s.onUpstreamHeaders(true)
+
+	if sender.upstream != upstream || sender.idleTimeout != time.Minute || sender.listener == nil {
+		t.Fatalf("the stream is not upgraded: %+v", sender)
+	}
+	if status, _ := sender.headers.Get(types.HeaderStatus); status != "101" {
+		t.Errorf("upgrade response should be sent, but got %v", sender.headers)
+	}
+	if s.downstreamCleaned != 0 {
+		t.Error("upgraded stream should not be cleaned before the tunnel closed")
+	}
+
+	sender.listener.OnTunnelClose(10, 20)
+	if s.downstreamCleaned != 1 {
+		t.Error("upgraded stream should be cleaned after the tunnel closed")
+	}
+	if s.requestInfo.BytesReceived() != 10 || s.requestInfo.BytesSent() != 20 {
+		t.Errorf("unexpected bytes: %d, %d", s.requestInfo.BytesReceived(), s.requestInfo.BytesSent())
+	}
+	if upstream.reset {
+		t.Error("upstream should not be reset")
+	}
+}
+
+func TestUpgradeFailed(t *testing.T) {
+	initGlobalStats()
+	for _, sender := range []types.StreamSender{
+		&mockUpgradableSender{fail: true},
+		// not an upgradable stream
+		&mockResponseSender{},
+	} {
+		upstream := &mockUpstreamStream{}
+		s := newUpgradeStream(sender, upstream)
+		s.onUpstreamHeaders(true)
+
+		if !upstream.reset {
+			t.Error("upstream should be reset")
+		}
+		if s.requestInfo.ResponseCode() != 502 || s.downstreamCleaned != 1 {
+			t.Errorf("expected a 502 response, but got %d", s.requestInfo.ResponseCode())
+		}
+	}
+}
@@ -20,12 +20,15 @@ package proxy
 import (
 	"container/list"
 	"context"
+	"net/http"
 	"time"
 
 	"sync/atomic"
 
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/trace/propagation"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -43,6 +46,10 @@ type upstreamRequest struct {
 	// ~~~ upstream response buf
 	upstreamRespHeaders types.HeaderMap
 
+	// the request converted as a whole message
+	convertedHeaders types.HeaderMap
+	convertedData    types.IoBuffer
+
 	//~~~ state
 	sendComplete bool
 	dataSent     bool
@@ -119,7 +126,7 @@ func (r *upstreamRequest) OnReceive(ctx context.Context, headers types.HeaderMap
 
 	r.downStream.downstreamRespTrailers = trailers
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(r.downStream.context) {
 		log.Proxy.Debugf(r.downStream.context, "[proxy] [upstream] OnReceive headers: %+v, data: %+v, trailers: %+v", headers, data, trailers)
 	}
 
@@ -162,11 +169,18 @@ func (r *upstreamRequest) appendHeaders(endStream bool) {
 	if r.downStream.processDone() {
 		return
 	}
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(r.downStream.context) {
 		log.Proxy.Debugf(r.downStream.context, "[proxy] [upstream] append headers: %+v", r.downStream.downstreamReqHeaders)
 	}
 	r.sendComplete = endStream
 
+	if err := r.convertMessage(); err != nil {
+		// the request can not be expressed in the upstream protocol, e.g. the arguments of a rpc bridged from http are invalid
+		log.Proxy.Warnf(r.downStream.context, "[proxy] [upstream] convert message failed, %s", err.Error())
+		r.downStream.sendHijackReplyWithBody(http.StatusBadRequest, r.downStream.downstreamReqHeaders, err.Error())
+		return
+	}
+
 	if r.downStream.oneway {
 		r.connPool.NewStream(r.downStream.context, nil, r)
 	} else {
@@ -196,14 +210,19 @@ func (r *upstreamRequest) appendData(endStream bool) {
 	if r.downStream.processDone() {
 		return
 	}
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(r.downStream.context) {
 		log.Proxy.Debugf(r.downStream.context, "[proxy] [upstream] append data:% +v", r.downStream.downstreamReqDataBuf)
 	}
 
-	data := r.downStream.downstreamReqDataBuf
+	var data types.IoBuffer
+	if r.downStream.messageConverted {
+		data = r.convertedData
+	} else {
+		data = r.convertData(r.downStream.downstreamReqDataBuf)
+	}
 	r.sendComplete = endStream
 	r.dataSent = true
-	r.requestSender.AppendData(r.downStream.context, r.convertData(data), endStream)
+	r.requestSender.AppendData(r.downStream.context, data, endStream)
 }
 
 func (r *upstreamRequest) convertData(data types.IoBuffer) types.IoBuffer {
@@ -224,11 +243,36 @@ func (r *upstreamRequest) convertData(data types.IoBuffer) types.IoBuffer {
 	return data
 }
 
+// convertMessage converts the request headers with the data as a whole message if the protocols support it
+func (r *upstreamRequest) convertMessage() error {
+	if r.downStream.noConvert {
+		return nil
+	}
+
+	dp, up := r.downStream.convertProtocol()
+	if dp == up {
+		return nil
+	}
+
+	headers, data, err := protocol.ConvertMessage(r.downStream.context, dp, up, r.downStream.downstreamReqHeaders, r.downStream.downstreamReqDataBuf)
+	switch err {
+	case nil:
+		r.convertedHeaders = headers
+		r.convertedData = data
+		r.downStream.messageConverted = true
+		return nil
+	case protocol.ErrNotFound, protocol.ErrUnsupportedMessage:
+		return nil
+	default:
+		return err
+	}
+}
+
 func (r *upstreamRequest) appendTrailers() {
 	if r.downStream.processDone() {
 		return
 	}
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(r.downStream.context) {
 		log.Proxy.Debugf(r.downStream.context, "[proxy] [upstream] append trailers:%+v", r.downStream.downstreamReqTrailers)
 	}
 	trailers := r.downStream.downstreamReqTrailers
@@ -274,18 +318,39 @@ func (r *upstreamRequest) OnFailure(reason types.PoolFailureReason, host types.H
 
 func (r *upstreamRequest) OnReady(sender types.StreamSender, host types.Host) {
 	// debug message for upstream
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(r.downStream.context) {
 		log.Proxy.Debugf(r.downStream.context, "[proxy] [upstream] connPool ready, proxyId = %v, host = %s", r.downStream.ID, host.AddressString())
 	}
 
 	r.requestSender = sender
 	r.host = host
+	log.GetDebugTrace(r.downStream.context).Record("host: %s", host.AddressString())
 	r.requestSender.GetStream().AddEventListener(r)
 	// start a upstream send
 	r.startTime = time.Now()
 
 	endStream := r.sendComplete && !r.dataSent && !r.trailerSent
-	r.requestSender.AppendHeaders(r.downStream.context, r.convertHeader(r.downStream.downstreamReqHeaders), endStream)
+	var headers types.HeaderMap
+	if r.downStream.messageConverted {
+		headers = r.convertedHeaders
+	} else {
+		headers = r.convertHeader(r.downStream.downstreamReqHeaders)
+	}
+	// propagate the trace context to the upstream
+	propagation.Propagate(r.downStream.downstreamReqHeaders, headers)
+	if trace.IsEnabled() {
+		if span := trace.SpanFromContext(r.downStream.context); span != nil {
+			span.InjectContext(headers)
+		}
+	}
+	if endStream && r.downStream.messageConverted && r.convertedData != nil {
+		// the converted request has the data that the downstream request does not have
+		r.requestSender.AppendHeaders(r.downStream.context, headers, false)
+		r.dataSent = true
+		r.requestSender.AppendData(r.downStream.context, r.convertedData, true)
+	} else {
+		r.requestSender.AppendHeaders(r.downStream.context, headers, endStream)
+	}
 
 	r.downStream.requestInfo.OnUpstreamHostSelected(host)
 	r.downStream.requestInfo.SetUpstreamLocalAddress(host.AddressString())
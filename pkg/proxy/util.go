@@ -21,6 +21,7 @@ import (
 	"strconv"
 	"time"
 
+	"mosn.io/mosn/pkg/protocol/grpc"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -33,6 +34,13 @@ func parseProxyTimeout(timeout *Timeout, route types.Route, headers types.Header
 	// todo: check global timeout in request headers
 	// todo: check per try timeout in request headers
 
+	// the grpc-timeout is the deadline of a gRPC request, honour it as the route timeout
+	if gto, _ := headers.Get(grpc.HeaderTimeout); gto != "" {
+		if grpctimeout, err := grpc.ParseTimeout(gto); err == nil && grpctimeout > 0 {
+			timeout.GlobalTimeout = grpctimeout
+		}
+	}
+
 	if tto, ok := headers.Get(types.HeaderTryTimeout); ok {
 		if trytimeout, err := strconv.ParseInt(tto, 10, bitSize64); err == nil {
 			timeout.TryTimeout = time.Duration(trytimeout) * time.Millisecond
@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/types"
+)
+
+func TestParseProxyTimeout(t *testing.T) {
+	rule, _ := router.NewRouteRuleImplBase(nil, &v2.Router{})
+	route := &mockRoute{
+		rule: &router.PathRouteRuleImpl{
+			RouteRuleImplBase: rule,
+		},
+	}
+	testCases := []struct {
+		headers types.HeaderMap
+		global  time.Duration
+	}{
+		{protocol.CommonHeader{}, types.GlobalTimeout},
+		{protocol.CommonHeader{"grpc-timeout": "200m"}, 200 * time.Millisecond},
+		{protocol.CommonHeader{"grpc-timeout": "2S"}, 2 * time.Second},
+		{protocol.CommonHeader{"grpc-timeout": "invalid"}, types.GlobalTimeout},
+		{protocol.CommonHeader{"grpc-timeout": "2S", types.HeaderGlobalTimeout: "100"}, 100 * time.Millisecond},
+	}
+	for i, tc := range testCases {
+		timeout := &Timeout{}
+		parseProxyTimeout(timeout, route, tc.headers)
+		if timeout.GlobalTimeout != tc.global {
+			t.Errorf("#%d expected global timeout %v, but got %v", i, tc.global, timeout.GlobalTimeout)
+		}
+	}
+}
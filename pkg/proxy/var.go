@@ -21,6 +21,7 @@ import (
 	"context"
 	"strconv"
 
+	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/variable"
 )
 
@@ -40,6 +41,7 @@ const (
 	VarDownstreamLocalAddress   string = "downstream_local_address"
 	VarDownstreamRemoteAddress  string = "downstream_remote_address"
 	VarUpstreamHost             string = "upstream_host"
+	VarRequestID                string = "request_id"
 
 	// ReqHeaderPrefix is the prefix of request header's formatter
 	reqHeaderPrefix string = "request_header_"
@@ -52,19 +54,20 @@ const (
 var (
 	builtinVariables = []variable.Variable{
 		variable.NewBasicVariable(VarStartTime, nil, startTimeGetter, nil, 0),
-		variable.NewBasicVariable(VarRequestReceivedDuration, nil, receivedDurationGetter, nil, 0),
-		variable.NewBasicVariable(VarResponseReceivedDuration, nil, responseReceivedDurationGetter, nil, 0),
-		variable.NewBasicVariable(VarRequestFinishedDuration, nil, requestFinishedDurationGetter, nil, 0),
-		variable.NewBasicVariable(VarBytesSent, nil, bytesSentGetter, nil, 0),
-		variable.NewBasicVariable(VarBytesReceived, nil, bytesReceivedGetter, nil, 0),
+		variable.NewTypedVariable(VarRequestReceivedDuration, nil, receivedDurationGetter, nil, 0, variable.ValueTypeDuration),
+		variable.NewTypedVariable(VarResponseReceivedDuration, nil, responseReceivedDurationGetter, nil, 0, variable.ValueTypeDuration),
+		variable.NewTypedVariable(VarRequestFinishedDuration, nil, requestFinishedDurationGetter, nil, 0, variable.ValueTypeDuration),
+		variable.NewTypedVariable(VarBytesSent, nil, bytesSentGetter, nil, 0, variable.ValueTypeNumber),
+		variable.NewTypedVariable(VarBytesReceived, nil, bytesReceivedGetter, nil, 0, variable.ValueTypeNumber),
 		variable.NewBasicVariable(VarProtocol, nil, protocolGetter, nil, 0),
-		variable.NewBasicVariable(VarResponseCode, nil, responseCodeGetter, nil, 0),
-		variable.NewBasicVariable(VarDuration, nil, durationGetter, nil, 0),
-		variable.NewBasicVariable(VarResponseFlag, nil, responseFlagGetter, nil, 0),
+		variable.NewTypedVariable(VarResponseCode, nil, responseCodeGetter, nil, 0, variable.ValueTypeNumber),
+		variable.NewTypedVariable(VarDuration, nil, durationGetter, nil, 0, variable.ValueTypeDuration),
+		variable.NewTypedVariable(VarResponseFlag, nil, responseFlagGetter, nil, 0, variable.ValueTypeBool),
 		variable.NewBasicVariable(VarUpstreamLocalAddress, nil, upstreamLocalAddressGetter, nil, 0),
 		variable.NewBasicVariable(VarDownstreamLocalAddress, nil, downstreamLocalAddressGetter, nil, 0),
 		variable.NewBasicVariable(VarDownstreamRemoteAddress, nil, downstreamRemoteAddressGetter, nil, 0),
 		variable.NewBasicVariable(VarUpstreamHost, nil, upstreamHostGetter, nil, 0),
+		variable.NewBasicVariable(VarRequestID, nil, requestIDGetter, nil, 0),
 	}
 
 	prefixVariables = []variable.Variable{
@@ -221,6 +224,14 @@ func upstreamHostGetter(ctx context.Context, value *variable.IndexedValue, data
 	return variable.ValueNotFound, nil
 }
 
+func requestIDGetter(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
+	if id := protocol.GetRequestID(ctx); id != "" {
+		return id, nil
+	}
+
+	return variable.ValueNotFound, nil
+}
+
 func requestHeaderMapGetter(ctx context.Context, value *variable.IndexedValue, data interface{}) (string, error) {
 	proxyBuffers := proxyBuffersByContext(ctx)
 	headers := proxyBuffers.stream.downstreamReqHeaders
@@ -27,6 +27,7 @@ import (
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
 	httpmosn "mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/runtime"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -77,6 +78,11 @@ func NewRouteRuleImplBase(vHost *VirtualHostImpl, route *v2.Router) (*RouteRuleI
 		},
 		lock: sync.Mutex{},
 	}
+	if fraction := route.Match.RuntimeFraction; fraction != nil {
+		if _, err := runtime.DenominatorValue(fraction.DefaultValue.Denominator); err != nil {
+			return nil, err
+		}
+	}
 	// add clusters
 	base.weightedClusters, base.totalClusterWeight = getWeightedClusterEntry(route.Route.WeightedClusters)
 	if len(route.Route.MetadataMatch) > 0 {
@@ -163,6 +169,13 @@ func (rri *RouteRuleImplBase) PerFilterConfig() map[string]interface{} {
 
 // matchRoute is a common matched for http
 func (rri *RouteRuleImplBase) matchRoute(headers types.HeaderMap, randomValue uint64) bool {
+	// 0. match the fraction of the requests
+	if fraction := rri.routerMatch.RuntimeFraction; fraction != nil {
+		if !runtime.FractionEnabled(fraction.RuntimeKey, fraction.DefaultValue, randomValue) {
+			log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match runtime fraction", randomValue)
+			return false
+		}
+	}
 	// 1. match headers' KV
 	if !ConfigUtilityInst.MatchHeaders(headers, rri.configHeaders) {
 		log.DefaultLogger.Debugf(RouterLogFormat, "routerule", "match header", headers)
@@ -20,6 +20,7 @@ package router
 import (
 	"context"
 	"fmt"
+	"math/rand"
 
 	"mosn.io/mosn/pkg/api/v2"
 	"mosn.io/mosn/pkg/log"
@@ -88,8 +89,9 @@ func (h *simpleHandler) Route() types.Route {
 
 func DefaultMakeHandlerChain(ctx context.Context, headers types.HeaderMap, routers types.Routers, clusterManager types.ClusterManager) *RouteHandlerChain {
 	var handlers []types.RouteHandler
-	if r := routers.MatchRoute(headers, 1); r != nil {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+	// the random value is used by the runtime fraction matching
+	if r := routers.MatchRoute(headers, rand.Uint64()); r != nil {
+		if log.DebugEnabled(ctx) {
 			log.Proxy.Debugf(ctx, RouterLogFormat, "DefaultHandklerChain", "MatchRoute", fmt.Sprintf("matched a route: %v", r))
 		}
 		handlers = append(handlers, &simpleHandler{route: r})
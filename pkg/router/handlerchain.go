@@ -51,6 +51,9 @@ func (hc *RouteHandlerChain) DoNextHandler() (types.ClusterSnapshot, types.Route
 	case types.HandlerAvailable:
 		return snapshot, handler.Route()
 	case types.HandlerNotAvailable:
+		if log.DebugEnabled(hc.ctx) {
+			log.Proxy.Debugf(hc.ctx, RouterLogFormat, "default handler chain", "handler not available", handler.Route())
+		}
 	case types.HandlerStop:
 		return nil, nil
 	default:
@@ -18,6 +18,7 @@
 package router
 
 import (
+	"net/http"
 	"regexp"
 	"strings"
 
@@ -150,3 +151,34 @@ func (rrei *RegexRouteRuleImpl) Match(headers types.HeaderMap, randomValue uint6
 	log.DefaultLogger.Debugf(RouterLogFormat, "regex route rule", "failed match", headers)
 	return nil
 }
+
+// ConnectRouteRuleImpl used to match the CONNECT requests, which have no path
+type ConnectRouteRuleImpl struct {
+	*RouteRuleImplBase
+}
+
+func (crri *ConnectRouteRuleImpl) PathMatchCriterion() types.PathMatchCriterion {
+	return crri
+}
+
+func (crri *ConnectRouteRuleImpl) RouteRule() types.RouteRule {
+	return crri
+}
+
+func (crri *ConnectRouteRuleImpl) Matcher() string {
+	return ""
+}
+
+func (crri *ConnectRouteRuleImpl) MatchType() types.PathMatchType {
+	return types.Connect
+}
+
+func (crri *ConnectRouteRuleImpl) Match(headers types.HeaderMap, randomValue uint64) types.Route {
+	if crri.matchRoute(headers, randomValue) {
+		if method, ok := headers.Get(protocol.MosnHeaderMethod); ok && method == http.MethodConnect {
+			return crri
+		}
+	}
+	log.DefaultLogger.Debugf(RouterLogFormat, "connect route rule", "failed match", headers)
+	return nil
+}
@@ -154,3 +154,40 @@ func TestRegexRouteRuleImpl(t *testing.T) {
 		}
 	}
 }
+
+func TestConnectRouteRuleImpl(t *testing.T) {
+	virtualHostImpl := &VirtualHostImpl{virtualHostName: "test"}
+	testCases := []struct {
+		headers  map[string]string
+		expected bool
+	}{
+		{map[string]string{protocol.MosnHeaderMethod: "CONNECT", protocol.MosnHeaderHostKey: "example.com:443"}, true},
+		{map[string]string{protocol.MosnHeaderMethod: "GET", protocol.MosnHeaderPathKey: "/"}, false},
+		{map[string]string{protocol.MosnHeaderPathKey: "/"}, false},
+	}
+	for i, tc := range testCases {
+		route := &v2.Router{
+			RouterConfig: v2.RouterConfig{
+				Match: v2.RouterMatch{ConnectMatcher: &v2.ConnectMatcher{}},
+				Route: v2.RouteAction{
+					RouterActionConfig: v2.RouterActionConfig{
+						ClusterName: "test",
+					},
+				},
+			},
+		}
+		if err := virtualHostImpl.addRouteBase(route); err != nil {
+			t.Fatal(err)
+		}
+		rr := virtualHostImpl.routes[len(virtualHostImpl.routes)-1]
+		result := rr.Match(protocol.CommonHeader(tc.headers), 1)
+		if (result != nil) != tc.expected {
+			t.Errorf("#%d want matched %v, but get matched %v\n", i, tc.expected, result)
+		}
+		if result != nil {
+			if result.RouteRule().PathMatchCriterion().MatchType() != types.Connect {
+				t.Errorf("#%d match type is not expected", i)
+			}
+		}
+	}
+}
@@ -57,6 +57,10 @@ func (vh *VirtualHostImpl) addRouteBase(route *v2.Router) error {
 			RouteRuleImplBase: base,
 			path:              route.Match.Path,
 		}
+	} else if route.Match.ConnectMatcher != nil {
+		router = &ConnectRouteRuleImpl{
+			RouteRuleImplBase: base,
+		}
 	} else if route.Match.Regex != "" {
 		regPattern, err := regexp.Compile(route.Match.Regex)
 		if err != nil {
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"mosn.io/mosn/pkg/utils"
+)
+
+const defaultRefreshInterval = 5 * time.Second
+
+// diskLayerLoader reloads the disk layer periodically
+type diskLayerLoader struct {
+	mux  sync.Mutex
+	path string
+	stop chan struct{}
+	err  error
+}
+
+var diskLoader = &diskLayerLoader{}
+
+// Init loads the disk layer from the directory, and reloads it in the interval.
+// The disk layer is cleared if the path is empty.
+func Init(path string, interval time.Duration) error {
+	return diskLoader.start(path, interval)
+}
+
+func (l *diskLayerLoader) start(path string, interval time.Duration) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.path = path
+	l.err = nil
+	if path == "" {
+		setDiskLayer(nil)
+		return nil
+	}
+	values, err := loadDirectory(path)
+	if err != nil {
+		return err
+	}
+	setDiskLayer(values)
+
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	stop := make(chan struct{})
+	l.stop = stop
+	utils.GoWithRecover(func() {
+		l.run(path, interval, stop)
+	}, nil)
+	return nil
+}
+
+func (l *diskLayerLoader) run(path string, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			l.reload(path)
+		}
+	}
+}
+
+// reload keeps the previous disk layer if the directory is failed to load
+func (l *diskLayerLoader) reload(path string) {
+	values, err := loadDirectory(path)
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.path != path {
+		return
+	}
+	l.err = err
+	if err != nil {
+		return
+	}
+	if !reflect.DeepEqual(values, load().disk) {
+		setDiskLayer(values)
+	}
+}
+
+func (l *diskLayerLoader) lastError() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.err
+}
+
+// loadDirectory reads the runtime values from the files in the directory, the hidden files are ignored
+func loadDirectory(root string) (map[string]string, error) {
+	// the directory may be a symbolic link, which is swapped for atomic updates
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	root = resolved
+	values := map[string]string{}
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		values[keyOf(filepath.ToSlash(rel))] = strings.TrimSpace(string(b))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
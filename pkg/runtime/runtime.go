@@ -0,0 +1,227 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package runtime is the runtime key-value layer, the values can be changed without restart.
+// The values are merged from the layers below, the latter overrides the former:
+//   - disk: the files in a directory, the relative path of a file with "/" replaced by "." is the key,
+//     and the trimmed content is the value. The directory is reloaded periodically.
+//   - admin: the overrides set by the admin api.
+package runtime
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	v2 "mosn.io/mosn/pkg/api/v2"
+)
+
+// the runtime layers, in the order of priority from low to high
+const (
+	LayerDisk  = "disk"
+	LayerAdmin = "admin"
+)
+
+// the denominators of a fractional percent
+const (
+	DenominatorHundred     = "HUNDRED"
+	DenominatorTenThousand = "TEN_THOUSAND"
+	DenominatorMillion     = "MILLION"
+)
+
+var ErrUnknownDenominator = errors.New("unknown fractional percent denominator")
+
+// snapshot is an immutable view of the runtime layers
+type snapshot struct {
+	disk   map[string]string
+	admin  map[string]string
+	values map[string]string
+}
+
+func newSnapshot(disk, admin map[string]string) *snapshot {
+	values := make(map[string]string, len(disk)+len(admin))
+	for k, v := range disk {
+		values[k] = v
+	}
+	for k, v := range admin {
+		values[k] = v
+	}
+	return &snapshot{
+		disk:   disk,
+		admin:  admin,
+		values: values,
+	}
+}
+
+var (
+	current atomic.Value // *snapshot
+	// updateMutex serializes the updates of the layers
+	updateMutex sync.Mutex
+)
+
+func init() {
+	current.Store(newSnapshot(nil, nil))
+}
+
+func load() *snapshot {
+	return current.Load().(*snapshot)
+}
+
+// Get returns the runtime value of the key
+func Get(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	v, ok := load().values[key]
+	return v, ok
+}
+
+// GetBool returns the runtime value of the key as a bool,
+// the default value is returned if the key does not exist or the value is not a bool
+func GetBool(key string, defaultValue bool) bool {
+	if v, ok := Get(key); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// GetInt returns the runtime value of the key as an unsigned integer,
+// the default value is returned if the key does not exist or the value is not an unsigned integer
+func GetInt(key string, defaultValue uint64) uint64 {
+	if v, ok := Get(key); ok {
+		if i, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+// FeatureEnabled returns true in the percent of the calls randomly,
+// the percent is the runtime value of the key in [0, 100], or the default percent
+func FeatureEnabled(key string, defaultPercent uint64) bool {
+	percent := GetInt(key, defaultPercent)
+	return uint64(rand.Intn(100)) < percent
+}
+
+// FractionEnabled returns true if the random value is in the fractional percent,
+// the numerator is the runtime value of the key, or the numerator of the default value.
+func FractionEnabled(key string, defaultValue v2.FractionalPercent, randomValue uint64) bool {
+	denominator, err := DenominatorValue(defaultValue.Denominator)
+	if err != nil {
+		return false
+	}
+	numerator := GetInt(key, uint64(defaultValue.Numerator))
+	return randomValue%denominator < numerator
+}
+
+// DenominatorValue returns the value of the fractional percent denominator, empty means HUNDRED
+func DenominatorValue(denominator string) (uint64, error) {
+	switch denominator {
+	case "", DenominatorHundred:
+		return 100, nil
+	case DenominatorTenThousand:
+		return 10000, nil
+	case DenominatorMillion:
+		return 1000000, nil
+	default:
+		return 0, ErrUnknownDenominator
+	}
+}
+
+// SetOverrides sets the values of the admin layer, an empty value removes the override of the key
+func SetOverrides(values map[string]string) {
+	updateMutex.Lock()
+	defer updateMutex.Unlock()
+	s := load()
+	admin := make(map[string]string, len(s.admin)+len(values))
+	for k, v := range s.admin {
+		admin[k] = v
+	}
+	for k, v := range values {
+		if v == "" {
+			delete(admin, k)
+		} else {
+			admin[k] = v
+		}
+	}
+	current.Store(newSnapshot(s.disk, admin))
+}
+
+func setDiskLayer(disk map[string]string) {
+	updateMutex.Lock()
+	defer updateMutex.Unlock()
+	current.Store(newSnapshot(disk, load().admin))
+}
+
+// Entry is a runtime key's value of each layer
+type Entry struct {
+	Key         string   `json:"key"`
+	FinalValue  string   `json:"final_value"`
+	LayerValues []string `json:"layer_values"`
+}
+
+// Dump is the runtime values sorted by key, the layer values are in the order of the layers
+type Dump struct {
+	Layers    []string `json:"layers"`
+	Entries   []Entry  `json:"entries"`
+	DiskError string   `json:"disk_error,omitempty"`
+}
+
+// Entry returns the entry of the key
+func (d Dump) Entry(key string) (Entry, bool) {
+	i := sort.Search(len(d.Entries), func(i int) bool {
+		return d.Entries[i].Key >= key
+	})
+	if i < len(d.Entries) && d.Entries[i].Key == key {
+		return d.Entries[i], true
+	}
+	return Entry{}, false
+}
+
+// GetDump returns the current runtime values
+func GetDump() Dump {
+	s := load()
+	d := Dump{
+		Layers:  []string{LayerDisk, LayerAdmin},
+		Entries: make([]Entry, 0, len(s.values)),
+	}
+	for k, v := range s.values {
+		d.Entries = append(d.Entries, Entry{
+			Key:         k,
+			FinalValue:  v,
+			LayerValues: []string{s.disk[k], s.admin[k]},
+		})
+	}
+	sort.Slice(d.Entries, func(i, j int) bool {
+		return d.Entries[i].Key < d.Entries[j].Key
+	})
+	if err := diskLoader.lastError(); err != nil {
+		d.DiskError = err.Error()
+	}
+	return d
+}
+
+// keyOf converts a relative file path into a runtime key
+func keyOf(path string) string {
+	return strings.Replace(strings.Trim(path, "/"), "/", ".", -1)
+}
@@ -0,0 +1,134 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/api/v2"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor waits the condition to be satisfied or timeout
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return false
+}
+
+func TestTypedValues(t *testing.T) {
+	SetOverrides(map[string]string{
+		"test.bool":    "true",
+		"test.int":     "30",
+		"test.invalid": "abc",
+	})
+	defer SetOverrides(map[string]string{"test.bool": "", "test.int": "", "test.invalid": ""})
+
+	if !GetBool("test.bool", false) || GetBool("test.invalid", false) || !GetBool("test.not_exists", true) {
+		t.Error("unexpected bool value")
+	}
+	if GetInt("test.int", 10) != 30 || GetInt("test.invalid", 10) != 10 || GetInt("test.not_exists", 10) != 10 {
+		t.Error("unexpected int value")
+	}
+	if !FeatureEnabled("test.not_exists", 100) || FeatureEnabled("test.not_exists", 0) {
+		t.Error("unexpected feature enabled")
+	}
+
+	fraction := v2.FractionalPercent{Numerator: 10, Denominator: DenominatorTenThousand}
+	if !FractionEnabled("test.not_exists", fraction, 10009) || FractionEnabled("test.not_exists", fraction, 10010) {
+		t.Error("unexpected fraction with default value")
+	}
+	// the runtime value is the numerator of the default denominator
+	if !FractionEnabled("test.int", fraction, 10029) || FractionEnabled("test.int", fraction, 10030) {
+		t.Error("unexpected fraction with runtime value")
+	}
+	if FractionEnabled("test.int", v2.FractionalPercent{Numerator: 10, Denominator: "unknown"}, 0) {
+		t.Error("fraction with unknown denominator should not be enabled")
+	}
+}
+
+func TestDiskLayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn_runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "health_check", "min_interval"), "10\n")
+	writeFile(t, filepath.Join(dir, "feature.enabled"), "true")
+	writeFile(t, filepath.Join(dir, ".hidden"), "1")
+	writeFile(t, filepath.Join(dir, ".git", "config"), "1")
+
+	if err := Init(dir, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	defer Init("", 0)
+
+	if GetInt("health_check.min_interval", 0) != 10 || !GetBool("feature.enabled", false) {
+		t.Errorf("unexpected disk layer: %v", load().disk)
+	}
+	if len(load().disk) != 2 {
+		t.Errorf("hidden files should be ignored: %v", load().disk)
+	}
+
+	// the admin layer overrides the disk layer
+	SetOverrides(map[string]string{"health_check.min_interval": "20"})
+	if GetInt("health_check.min_interval", 0) != 20 {
+		t.Error("admin layer should override the disk layer")
+	}
+	entry, _ := GetDump().Entry("health_check.min_interval")
+	if entry.FinalValue != "20" || entry.LayerValues[0] != "10" || entry.LayerValues[1] != "20" {
+		t.Errorf("unexpected dump entry: %+v", entry)
+	}
+	SetOverrides(map[string]string{"health_check.min_interval": ""})
+	if GetInt("health_check.min_interval", 0) != 10 {
+		t.Error("removed override should fallback to the disk layer")
+	}
+
+	// reload the changes
+	writeFile(t, filepath.Join(dir, "feature.enabled"), "false")
+	if !waitFor(func() bool { return !GetBool("feature.enabled", true) }) {
+		t.Error("disk layer is not reloaded")
+	}
+
+	// the previous values are kept if the directory is failed to load
+	os.RemoveAll(dir)
+	if !waitFor(func() bool { return GetDump().DiskError != "" }) || GetInt("health_check.min_interval", 0) != 10 {
+		t.Error("the previous values should be kept with the error")
+	}
+
+	Init("", 0)
+	if _, ok := Get("health_check.min_interval"); ok {
+		t.Error("disk layer should be cleared")
+	}
+}
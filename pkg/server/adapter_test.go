@@ -370,3 +370,50 @@ func TestFindListenerByName(t *testing.T) {
 		t.Fatal("expected find listener, but not")
 	}
 }
+
+type mockClosableAccessLog struct {
+	closed int
+}
+
+func (l *mockClosableAccessLog) Log(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) {
+}
+
+func (l *mockClosableAccessLog) Close() error {
+	l.closed++
+	return nil
+}
+
+type mockAccessLogFilter struct{}
+
+func (f *mockAccessLogFilter) Evaluate(ctx context.Context, reqHeaders types.HeaderMap, respHeaders types.HeaderMap, requestInfo types.RequestInfo) bool {
+	return true
+}
+
+func TestDeleteListenerCloseAccessLogs(t *testing.T) {
+	addrStr := "127.0.0.1:8084"
+	name := "listener5"
+	cfg := baseListenerConfig(addrStr, name)
+	if err := GetListenerAdapterInstance().AddOrUpdateListener(testServerName, cfg, nil, nil); err != nil {
+		t.Fatalf("add a new listener failed %v", err)
+	}
+	handler := listenerAdapterInstance.defaultConnHandler.(*connHandler)
+	al := handler.findActiveListenerByName(name)
+	if al == nil {
+		t.Fatal("no listener found")
+	}
+	accessLog := &mockClosableAccessLog{}
+	filteredAccessLog := &mockClosableAccessLog{}
+	al.accessLogs = []types.AccessLog{
+		accessLog,
+		log.NewFilteredAccessLog(filteredAccessLog, &mockAccessLogFilter{}),
+	}
+	if err := GetListenerAdapterInstance().DeleteListener(testServerName, name); err != nil {
+		t.Fatal("delete listener failed", err)
+	}
+	if handler.findActiveListenerByName(name) != nil {
+		t.Fatal("handler still have listener")
+	}
+	if accessLog.closed == 0 || filteredAccessLog.closed == 0 {
+		t.Fatalf("access logs are not closed, closed: %d, filtered closed: %d", accessLog.closed, filteredAccessLog.closed)
+	}
+}
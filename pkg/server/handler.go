@@ -36,7 +36,9 @@ import (
 	"mosn.io/mosn/pkg/api/v2"
 	mosnctx "mosn.io/mosn/pkg/context"
 	"mosn.io/mosn/pkg/filter/accept/originaldst"
+	"mosn.io/mosn/pkg/filter/accept/proxyprotocol"
 	"mosn.io/mosn/pkg/log"
+	grpcaccesslog "mosn.io/mosn/pkg/log/als"
 	"mosn.io/mosn/pkg/metrics"
 	"mosn.io/mosn/pkg/mtls"
 	"mosn.io/mosn/pkg/network"
@@ -154,6 +156,7 @@ func (ch *connHandler) AddOrUpdateListener(lc *v2.Listener, networkFiltersFactor
 		rawConfig.ListenerTag = lc.ListenerTag
 		al.listener.SetListenerTag(lc.ListenerTag)
 		rawConfig.UseOriginalDst = lc.UseOriginalDst
+		rawConfig.UseProxyProtocol = lc.UseProxyProtocol
 		al.listener.SetUseOriginalDst(lc.UseOriginalDst)
 		al.idleTimeout = lc.ConnectionIdleTimeout
 
@@ -172,17 +175,12 @@ func (ch *connHandler) AddOrUpdateListener(lc *v2.Listener, networkFiltersFactor
 		var als []types.AccessLog
 
 		for _, alConfig := range lc.AccessLogs {
-
-			//use default listener access log path
-			if alConfig.Path == "" {
-				alConfig.Path = types.MosnLogBasePath + string(os.PathSeparator) + lc.Name + "_access.log"
-			}
-
-			if al, err := log.NewAccessLog(alConfig.Path, alConfig.Format); err == nil {
-				als = append(als, al)
-			} else {
-				return nil, fmt.Errorf("initialize listener access logger %s failed: %v", alConfig.Path, err.Error())
+			accessLog, err := newAccessLog(lc, alConfig)
+			if err != nil {
+				closeAccessLogs(als)
+				return nil, err
 			}
+			als = append(als, accessLog)
 		}
 
 		l := network.NewListener(lc)
@@ -190,6 +188,7 @@ func (ch *connHandler) AddOrUpdateListener(lc *v2.Listener, networkFiltersFactor
 		var err error
 		al, err = newActiveListener(l, lc, als, networkFiltersFactories, streamFiltersFactories, ch, listenerStopChan)
 		if err != nil {
+			closeAccessLogs(als)
 			return al, err
 		}
 		l.SetListenerCallbacks(al)
@@ -216,6 +215,67 @@ func (ch *connHandler) StartListeners(lctx context.Context) {
 	}
 }
 
+// newAccessLog creates an access log of the listener, the access log only logs the requests
+// accepted by the filter if the filter is configured
+func newAccessLog(lc *v2.Listener, alConfig v2.AccessLog) (types.AccessLog, error) {
+	var filter log.AccessLogFilter
+	if alConfig.Filter != nil {
+		f, err := log.NewAccessLogFilter(alConfig.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("initialize listener access log filter failed: %v", err)
+		}
+		filter = f
+	}
+
+	var accessLog types.AccessLog
+	if alConfig.GRPCService != nil {
+		al, err := grpcaccesslog.NewAccessLog(alConfig.GRPCService)
+		if err != nil {
+			return nil, fmt.Errorf("initialize listener grpc access logger %s failed: %v", alConfig.GRPCService.LogName, err)
+		}
+		accessLog = al
+	} else {
+		//use default listener access log path
+		if alConfig.Path == "" {
+			alConfig.Path = types.MosnLogBasePath + string(os.PathSeparator) + lc.Name + "_access.log"
+		}
+
+		var al types.AccessLog
+		var err error
+		if len(alConfig.JSONFormat) > 0 {
+			al, err = log.NewJSONAccessLog(alConfig.Path, alConfig.JSONFormat)
+		} else {
+			al, err = log.NewAccessLog(alConfig.Path, alConfig.Format)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("initialize listener access logger %s failed: %v", alConfig.Path, err.Error())
+		}
+		accessLog = al
+	}
+
+	if filter != nil {
+		accessLog = log.NewFilteredAccessLog(accessLog, filter)
+	}
+	return accessLog, nil
+}
+
+// closeAccessLogs releases the access logs of a listener that is torn down
+func closeAccessLogs(als []types.AccessLog) {
+	for _, al := range als {
+		if err := log.CloseAccessLog(al); err != nil {
+			log.DefaultLogger.Errorf("[server] [conn handler] close access log failed: %v", err)
+		}
+	}
+}
+
+// closeAllAccessLogs releases the access logs of all listeners
+func (ch *connHandler) closeAllAccessLogs() error {
+	for _, l := range ch.listeners {
+		closeAccessLogs(l.accessLogs)
+	}
+	return nil
+}
+
 func (ch *connHandler) FindListenerByAddress(addr net.Addr) types.Listener {
 	l := ch.findActiveListenerByAddress(addr)
 
@@ -241,6 +301,7 @@ func (ch *connHandler) RemoveListeners(name string) {
 		if l.listener.Name() == name {
 			log.DefaultLogger.Infof("[server] [conn handler] remove listener name: %s", name)
 			ch.listeners = append(ch.listeners[:i], ch.listeners[i+1:]...)
+			closeAccessLogs(l.accessLogs)
 		}
 	}
 }
@@ -268,6 +329,7 @@ func (ch *connHandler) StopListeners(lctx context.Context, close bool) error {
 			if err := l.listener.Close(lctx); err != nil {
 				errGlobal = err
 			}
+			closeAccessLogs(l.accessLogs)
 		} else {
 			if err := l.listener.Stop(); err != nil {
 				errGlobal = err
@@ -279,15 +341,19 @@ func (ch *connHandler) StopListeners(lctx context.Context, close bool) error {
 }
 
 func (ch *connHandler) ListListenersFile(lctx context.Context) []*os.File {
-	files := make([]*os.File, len(ch.listeners))
+	files := make([]*os.File, 0, len(ch.listeners))
 
-	for idx, l := range ch.listeners {
+	for _, l := range ch.listeners {
+		// the udp listeners are not inherited, the new process binds the port with reuse_port
+		if l.listener.Addr().Network() == v2.NetworkUDP {
+			continue
+		}
 		file, err := l.listener.ListenerFile()
 		if err != nil {
 			log.DefaultLogger.Errorf("[server] [conn handler] fail to get listener %s file descriptor: %v", l.listener.Name(), err)
 			return nil //stop reconfigure
 		}
-		files[idx] = file
+		files = append(files, file)
 	}
 	return files
 }
@@ -398,22 +464,13 @@ func (al *activeListener) OnAccept(rawc net.Conn, useOriginalDst bool, oriRemote
 	if !useOriginalDst {
 		if network.UseNetpollMode {
 			// store fd for further usage
-			if tc, ok := rawc.(*net.TCPConn); ok {
+			switch tc := rawc.(type) {
+			case *net.TCPConn:
+				rawf, _ = tc.File()
+			case *net.UnixConn:
 				rawf, _ = tc.File()
 			}
 		}
-		// if ch is not nil, the conn has been initialized in func transferNewConn
-		if al.tlsMng != nil && ch == nil {
-			conn, err := al.tlsMng.Conn(rawc)
-			if err != nil {
-				if log.DefaultLogger.GetLogLevel() >= log.INFO {
-					log.DefaultLogger.Infof("[server] [listener] accept connection failed, error: %v", err)
-				}
-				rawc.Close()
-				return
-			}
-			rawc = conn
-		}
 	}
 
 	arc := newActiveRawConn(rawc, al)
@@ -425,6 +482,14 @@ func (al *activeListener) OnAccept(rawc net.Conn, useOriginalDst bool, oriRemote
 		if log.DefaultLogger.GetLogLevel() >= log.DEBUG {
 			log.DefaultLogger.Debugf("[server] [listener] use original dst from %v, remote addr:%v, origin remote addr:%v", al.listener.Addr(), rawc.RemoteAddr(), oriRemoteAddr)
 		}
+	} else if ch == nil {
+		// if ch is not nil, the conn has been initialized in func transferNewConn.
+		// the PROXY protocol header is sent before the tls handshake, so the tls conn
+		// is created after the listener filters
+		if al.listener.Config().UseProxyProtocol {
+			arc.acceptedFilters = append(arc.acceptedFilters, proxyprotocol.NewProxyProtocol())
+		}
+		arc.tlsMng = al.tlsMng
 	}
 
 	ctx := mosnctx.WithValue(context.Background(), types.ContextKeyListenerPort, al.listenPort)
@@ -433,6 +498,7 @@ func (al *activeListener) OnAccept(rawc net.Conn, useOriginalDst bool, oriRemote
 	ctx = mosnctx.WithValue(ctx, types.ContextKeyNetworkFilterChainFactories, al.networkFiltersFactories)
 	ctx = mosnctx.WithValue(ctx, types.ContextKeyStreamFilterChainFactories, &al.streamFiltersFactoriesStore)
 	ctx = mosnctx.WithValue(ctx, types.ContextKeyAccessLogs, al.accessLogs)
+	ctx = mosnctx.WithValue(ctx, types.ContextKeyRequestIDConfig, al.listener.Config().RequestID)
 	if rawf != nil {
 		ctx = mosnctx.WithValue(ctx, types.ContextKeyConnectionFd, rawf)
 	}
@@ -520,6 +586,8 @@ type activeRawConn struct {
 	originalDstPort     int
 	oriRemoteAddr       net.Addr
 	useOriginalDst      bool
+	remoteAddr          net.Addr
+	tlsMng              types.TLSContextManager
 	rawcElement         *list.Element
 	activeListener      *activeListener
 	acceptedFilters     []types.ListenerFilter
@@ -542,6 +610,13 @@ func (arc *activeRawConn) SetOriginalAddr(ip string, port int) {
 	}
 }
 
+func (arc *activeRawConn) SetRemoteAddr(addr net.Addr) {
+	arc.remoteAddr = addr
+	if log.DefaultLogger.GetLogLevel() >= log.INFO {
+		log.DefaultLogger.Infof("[server] [conn] conn set remote addr:%s", addr)
+	}
+}
+
 func (arc *activeRawConn) UseOriginalDst(ctx context.Context) {
 	var listener, localListener *activeListener
 
@@ -596,7 +671,22 @@ func (arc *activeRawConn) ContinueFilterChain(ctx context.Context, success bool)
 	if arc.useOriginalDst {
 		arc.UseOriginalDst(ctx)
 	} else {
-		arc.activeListener.newConnection(ctx, arc.rawc)
+		rawc := arc.rawc
+		if arc.tlsMng != nil {
+			conn, err := arc.tlsMng.Conn(rawc)
+			if err != nil {
+				if log.DefaultLogger.GetLogLevel() >= log.INFO {
+					log.DefaultLogger.Infof("[server] [listener] accept connection failed, error: %v", err)
+				}
+				rawc.Close()
+				return
+			}
+			rawc = conn
+		}
+		if arc.remoteAddr != nil {
+			ctx = mosnctx.WithValue(ctx, types.ContextOriRemoteAddr, arc.remoteAddr)
+		}
+		arc.activeListener.newConnection(ctx, rawc)
 	}
 
 }
@@ -769,11 +859,12 @@ func GetInheritListeners() ([]net.Listener, net.Conn, error) {
 			log.StartLogger.Errorf("[server] recover listener from fd %d failed: %s", fd, err)
 			return nil, nil, err
 		}
-		if listener, ok := fileListener.(*net.TCPListener); ok {
+		switch listener := fileListener.(type) {
+		case *net.TCPListener, *net.UnixListener:
 			listeners[i] = listener
-		} else {
-			log.StartLogger.Errorf("[server] listener recovered from fd %d is not a tcp listener", fd)
-			return nil, nil, errors.New("not a tcp listener")
+		default:
+			log.StartLogger.Errorf("[server] listener recovered from fd %d is not a tcp or unix listener", fd)
+			return nil, nil, errors.New("not a tcp or unix listener")
 		}
 	}
 
@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keeper
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrWaitTimeout is returned if the running mosn does not finish in time
+var ErrWaitTimeout = errors.New("wait timeout")
+
+// waitInterval is the interval to check the running mosn
+var waitInterval = 500 * time.Millisecond
+
+// RunningPid returns the pid in the pid file if the process is running
+func RunningPid(path string) (int, error) {
+	pid, err := ReadPidFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("read pid file failed: %v", err)
+	}
+	if !ProcessAlive(pid) {
+		return 0, fmt.Errorf("process %d in pid file %s is not running", pid, path)
+	}
+	return pid, nil
+}
+
+// ProcessAlive checks whether the process exists, a zombie process is treated as exited
+func ProcessAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil && err != syscall.EPERM {
+		return false
+	}
+	// the new mosn is forked by the old one, it keeps as a zombie if it exits before the old one
+	if stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		if idx := strings.LastIndexByte(string(stat), ')'); idx > 0 && idx+2 < len(stat) {
+			return stat[idx+2] != 'Z'
+		}
+	}
+	return true
+}
+
+// WaitProcessExit waits the process exits, returns false if it is still running after timeout
+func WaitProcessExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !ProcessAlive(pid) {
+			return true
+		}
+		time.Sleep(waitInterval)
+	}
+	return !ProcessAlive(pid)
+}
+
+// WaitReload waits the new mosn writes its pid into the pid file, and then
+// waits the old mosn exits after the listeners and connections are transferred
+func WaitReload(path string, oldPid int, timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	newPid := 0
+	for time.Now().Before(deadline) {
+		if newPid == 0 {
+			if pid, err := ReadPidFile(path); err == nil && pid != oldPid {
+				newPid = pid
+			}
+		}
+		oldAlive := ProcessAlive(oldPid)
+		if newPid != 0 {
+			if !ProcessAlive(newPid) {
+				return 0, fmt.Errorf("new process %d exited", newPid)
+			}
+			if !oldAlive {
+				return newPid, nil
+			}
+		} else if !oldAlive {
+			return 0, fmt.Errorf("process %d exited before new process started", oldPid)
+		}
+		time.Sleep(waitInterval)
+	}
+	return 0, ErrWaitTimeout
+}
+
+// RestorePidFile writes the pid of the old mosn back into the pid file after a failed reload,
+// the pid file is left unchanged if the old mosn is not running either
+func RestorePidFile(path string, pid int) error {
+	if !ProcessAlive(pid) {
+		return nil
+	}
+	return WritePidFile(path, pid)
+}
@@ -0,0 +1,205 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keeper
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func init() {
+	waitInterval = 10 * time.Millisecond
+}
+
+func writeTestPidFile(t *testing.T, path string, pid int) {
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startProcess starts a process that runs until it is killed
+func startProcess(t *testing.T) *exec.Cmd {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start process failed: %v", err)
+	}
+	return cmd
+}
+
+// stopProcess kills the process, it keeps as a zombie until it is waited
+func stopProcess(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
+
+func TestRunningPid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn-keeper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mosn.pid")
+
+	// missing pid file
+	if _, err := RunningPid(path); err == nil {
+		t.Fatal("expected error for missing pid file")
+	}
+	// invalid pid file
+	if err := ioutil.WriteFile(path, []byte("mosn"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunningPid(path); err == nil {
+		t.Fatal("expected error for invalid pid file")
+	}
+	// running process
+	cmd := startProcess(t)
+	writeTestPidFile(t, path, cmd.Process.Pid)
+	if pid, err := RunningPid(path); err != nil || pid != cmd.Process.Pid {
+		t.Fatalf("get running pid failed, pid: %d, error: %v", pid, err)
+	}
+	// stale pid, the process exited
+	stopProcess(cmd)
+	cmd.Wait()
+	if _, err := RunningPid(path); err == nil {
+		t.Fatal("expected error for stale pid")
+	}
+}
+
+func TestWaitProcessExit(t *testing.T) {
+	cmd := startProcess(t)
+	defer cmd.Wait()
+	if !ProcessAlive(cmd.Process.Pid) {
+		t.Fatal("process should be alive")
+	}
+	if WaitProcessExit(cmd.Process.Pid, 50*time.Millisecond) {
+		t.Fatal("process should not exit")
+	}
+	time.AfterFunc(50*time.Millisecond, func() {
+		stopProcess(cmd)
+	})
+	// the zombie process is treated as exited
+	if !WaitProcessExit(cmd.Process.Pid, time.Second) {
+		t.Fatal("process should exit")
+	}
+}
+
+func TestWaitReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn-keeper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mosn.pid")
+
+	t.Run("success", func(t *testing.T) {
+		oldCmd, newCmd := startProcess(t), startProcess(t)
+		defer oldCmd.Wait()
+		defer func() {
+			stopProcess(newCmd)
+			newCmd.Wait()
+		}()
+		writeTestPidFile(t, path, oldCmd.Process.Pid)
+		// the new mosn writes the pid file, and then the old one exits
+		time.AfterFunc(50*time.Millisecond, func() {
+			writeTestPidFile(t, path, newCmd.Process.Pid)
+			time.AfterFunc(50*time.Millisecond, func() {
+				stopProcess(oldCmd)
+			})
+		})
+		pid, err := WaitReload(path, oldCmd.Process.Pid, time.Second)
+		if err != nil || pid != newCmd.Process.Pid {
+			t.Fatalf("wait reload failed, pid: %d, error: %v", pid, err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		oldCmd := startProcess(t)
+		defer func() {
+			stopProcess(oldCmd)
+			oldCmd.Wait()
+		}()
+		writeTestPidFile(t, path, oldCmd.Process.Pid)
+		if _, err := WaitReload(path, oldCmd.Process.Pid, 100*time.Millisecond); err != ErrWaitTimeout {
+			t.Fatalf("expected timeout, but got %v", err)
+		}
+	})
+
+	t.Run("old exited", func(t *testing.T) {
+		oldCmd := startProcess(t)
+		defer oldCmd.Wait()
+		writeTestPidFile(t, path, oldCmd.Process.Pid)
+		stopProcess(oldCmd)
+		if _, err := WaitReload(path, oldCmd.Process.Pid, time.Second); err == nil || err == ErrWaitTimeout {
+			t.Fatalf("expected the old process exited, but got %v", err)
+		}
+	})
+
+	t.Run("new exited", func(t *testing.T) {
+		oldCmd, newCmd := startProcess(t), startProcess(t)
+		defer func() {
+			stopProcess(oldCmd)
+			oldCmd.Wait()
+		}()
+		defer newCmd.Wait()
+		writeTestPidFile(t, path, newCmd.Process.Pid)
+		stopProcess(newCmd)
+		if _, err := WaitReload(path, oldCmd.Process.Pid, time.Second); err == nil || err == ErrWaitTimeout {
+			t.Fatalf("expected the new process exited, but got %v", err)
+		}
+	})
+}
+
+func TestRestorePidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mosn-keeper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mosn.pid")
+
+	cmd := startProcess(t)
+	defer cmd.Wait()
+	// the running process takes the pid file back
+	writeTestPidFile(t, path, 1<<22+1)
+	if err := RestorePidFile(path, cmd.Process.Pid); err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := ReadPidFile(path); err != nil || pid != cmd.Process.Pid {
+		t.Fatalf("pid file is not restored, pid: %d, error: %v", pid, err)
+	}
+	// the failure is reported
+	if err := RestorePidFile(filepath.Join(dir, "missing", "mosn.pid"), cmd.Process.Pid); err == nil {
+		t.Error("expected error for unwritable pid file")
+	}
+	// the exited process does not take the pid file back
+	stopProcess(cmd)
+	if !WaitProcessExit(cmd.Process.Pid, time.Second) {
+		t.Fatal("process should exit")
+	}
+	writeTestPidFile(t, path, 1<<22+1)
+	if err := RestorePidFile(path, cmd.Process.Pid); err != nil {
+		t.Fatal(err)
+	}
+	if pid, _ := ReadPidFile(path); pid != 1<<22+1 {
+		t.Errorf("pid file should not be changed, but got %d", pid)
+	}
+}
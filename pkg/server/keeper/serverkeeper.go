@@ -18,12 +18,14 @@
 package keeper
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -36,14 +38,19 @@ func init() {
 	catchSignals()
 
 	onProcessExit = append(onProcessExit, func() {
+		// the pid file may be taken over by a new mosn after reconfigure,
+		// only remove the file written by ourselves
 		if pidFile != "" {
-			os.Remove(pidFile)
+			if pid, err := ReadPidFile(pidFile); err == nil && pid == os.Getpid() {
+				os.Remove(pidFile)
+			}
 		}
 	})
 }
 
 var (
 	pidFile               string
+	stopOnce              sync.Once
 	onProcessExit         []func()
 	shutdownCallbacksOnce sync.Once
 	shutdownCallbacks     []func() error
@@ -64,14 +71,30 @@ func SetPid(pid string) {
 }
 
 func writePidFile() (err error) {
-	pid := []byte(strconv.Itoa(os.Getpid()) + "\n")
-
-	if err = ioutil.WriteFile(pidFile, pid, 0644); err != nil {
+	if err = WritePidFile(pidFile, os.Getpid()); err != nil {
 		log.DefaultLogger.Errorf("write pid file error: %v", err)
 	}
 	return err
 }
 
+// WritePidFile writes the pid into the pid file
+func WritePidFile(path string, pid int) error {
+	return ioutil.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0644)
+}
+
+// ReadPidFile returns the pid stored in the pid file
+func ReadPidFile(path string) (int, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %v", path, err)
+	}
+	return pid, nil
+}
+
 func catchSignals() {
 	catchSignalsCrossPlatform()
 	catchSignalsPosix()
@@ -93,20 +116,11 @@ func catchSignalsCrossPlatform() {
 				}
 				os.Exit(0)
 			case syscall.SIGTERM:
-				// stop to quit
-				exitCode := ExecuteShutdownCallbacks("SIGTERM")
-				for _, f := range onProcessExit {
-					f() // only perform important cleanup actions
-				}
-				//Stop()
-
-				if cbs, ok := signalCallback[syscall.SIGTERM]; ok {
-					for _, cb := range cbs {
-						cb()
-					}
-				}
-
-				os.Exit(exitCode)
+				// stop to quit, the graceful stop runs in another goroutine,
+				// so a SIGQUIT can still quit immediately
+				stopOnce.Do(func() {
+					utils.GoWithRecover(gracefulStop, nil)
+				})
 			case syscall.SIGUSR1:
 				// reopen
 				log.Reopen()
@@ -123,6 +137,22 @@ func catchSignalsCrossPlatform() {
 	}, nil)
 }
 
+// gracefulStop calls the SIGTERM callbacks to drain the connections, and then exit
+func gracefulStop() {
+	if cbs, ok := signalCallback[syscall.SIGTERM]; ok {
+		for _, cb := range cbs {
+			cb()
+		}
+	}
+
+	exitCode := ExecuteShutdownCallbacks("SIGTERM")
+	for _, f := range onProcessExit {
+		f() // only perform important cleanup actions
+	}
+
+	os.Exit(exitCode)
+}
+
 func catchSignalsPosix() {
 	go func() {
 		defer func() {
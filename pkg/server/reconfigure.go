@@ -37,6 +37,10 @@ func init() {
 		// reload, fork new mosn
 		reconfigure(true)
 	})
+	keeper.AddSignalCallback(syscall.SIGTERM, func() {
+		// stop, drain connections before exit
+		gracefulStop()
+	})
 }
 
 var GracefulTimeout = time.Second * 30 //default 30s
@@ -111,6 +115,22 @@ func reconfigure(start bool) {
 	os.Exit(0)
 }
 
+func gracefulStop() {
+	// no more reconfigure is accepted
+	StopReconfigureHandler()
+
+	// stop other services
+	store.StopService()
+
+	// Stop accepting requests
+	StopAccept()
+
+	// Wait for all connections to be finished
+	WaitConnectionsDone(GracefulTimeout)
+
+	log.DefaultLogger.Infof("[server] [stop] process %d gracefully shutdown", os.Getpid())
+}
+
 func ReconfigureHandler() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -77,6 +77,8 @@ func NewServer(config *Config, cmFilter types.ClusterManagerFilter, clMng types.
 
 	runtime.GOMAXPROCS(config.Processor)
 
+	// the access logs may log through the loggers, close them first
+	keeper.OnProcessShutDown(closeServerAccessLogs)
 	keeper.OnProcessShutDown(log.CloseAll)
 
 	server := &server{
@@ -133,6 +135,15 @@ func Stop() {
 	}
 }
 
+func closeServerAccessLogs() error {
+	for _, server := range servers {
+		if ch, ok := server.handler.(*connHandler); ok {
+			ch.closeAllAccessLogs()
+		}
+	}
+	return nil
+}
+
 func StopAccept() {
 	for _, server := range servers {
 		server.handler.StopListeners(nil, false)
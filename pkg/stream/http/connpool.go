@@ -188,9 +188,9 @@ func (p *connPool) onStreamDestroy(client *activeClient) {
 	p.host.ClusterInfo().Stats().UpstreamRequestActive.Dec(1)
 	p.host.ClusterInfo().ResourceManager().Requests().Decrease()
 
-	// return to pool
+	// return to pool, the client is not available if the connection is upgraded
 	p.clientMux.Lock()
-	if !client.closed {
+	if !client.closed && client.client.ActiveRequestsNum() == 0 {
 		p.availableClients = append(p.availableClients, client)
 	}
 	p.clientMux.Unlock()
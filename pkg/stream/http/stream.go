@@ -180,6 +180,7 @@ type clientStreamConnection struct {
 
 	stream                        *clientStream
 	requestSent                   chan bool
+	tunnelChan                    chan *tunnel
 	mutex                         sync.RWMutex
 	connectionEventListener       types.ConnectionEventListener
 	streamConnectionEventListener types.StreamConnectionEventListener
@@ -199,6 +200,7 @@ func newClientStreamConnection(ctx context.Context, connection types.ClientConne
 		connectionEventListener:       connCallbacks,
 		streamConnectionEventListener: streamConnCallbacks,
 		requestSent:                   make(chan bool, 1),
+		tunnelChan:                    make(chan *tunnel, 1),
 	}
 
 	csc.br = bufio.NewReader(csc)
@@ -237,11 +239,12 @@ func (conn *clientStreamConnection) serve() {
 			return
 		}
 
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(s.stream.ctx) {
 			log.Proxy.Debugf(s.stream.ctx, "[stream] [http] receive response, requestId = %v", s.stream.id)
 		}
 
 		// 2. response processing
+		upgraded := s.response.StatusCode() == fasthttp.StatusSwitchingProtocols
 		resetConn := false
 		if s.response.ConnectionClose() {
 			resetConn = true
@@ -256,6 +259,17 @@ func (conn *clientStreamConnection) serve() {
 		if atomic.LoadInt32(&s.readDisableCount) <= 0 {
 			s.handleResponse()
 		}
+
+		// 4. the connection is taken by the tunnel after upgraded,
+		// relay the upstream bytes once the upgrade response is sent to the downstream
+		if upgraded {
+			select {
+			case t := <-conn.tunnelChan:
+				t.relay(&conn.streamConnection, &t.downstream.streamConnection, &t.bytesSent)
+			case <-conn.connClosed:
+			}
+			return
+		}
 	}
 }
 
@@ -306,6 +320,9 @@ type serverStreamConnection struct {
 	stream                   *serverStream
 	mutex                    sync.RWMutex
 	serverStreamConnListener types.ServerStreamConnectionEventListener
+
+	// tunnel is set after the connection is upgraded
+	tunnel *tunnel
 }
 
 func newServerStreamConnection(ctx context.Context, connection types.Connection,
@@ -398,7 +415,10 @@ func (conn *serverStreamConnection) serve() {
 		}
 		s.connection = conn
 		s.responseDoneChan = make(chan bool, 1)
+		s.tunnel = nil
 		s.header = mosnhttp.RequestHeader{&s.request.Header, nil}
+		// the request id should be injected before tracing, it is used by sampling
+		s.stream.ctx = protocol.InjectRequestID(s.stream.ctx, s.header)
 
 		var span types.Span
 		if trace.IsEnabled() {
@@ -409,7 +429,7 @@ func (conn *serverStreamConnection) serve() {
 		}
 		s.stream.ctx = s.connection.contextManager.InjectTrace(ctx, span)
 
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(s.stream.ctx) {
 			log.Proxy.Debugf(s.stream.ctx, "[stream] [http] new stream detect, requestId = %v", s.stream.id)
 		}
 
@@ -430,6 +450,12 @@ func (conn *serverStreamConnection) serve() {
 			return
 		}
 
+		// 6. relay the downstream bytes if the connection is switched into a tunnel
+		if t := conn.tunnel; t != nil {
+			t.relay(&conn.streamConnection, t.upstream, &t.bytesReceived)
+			return
+		}
+
 		conn.contextManager.Next()
 	}
 }
@@ -531,7 +557,7 @@ func (s *clientStream) endStream() {
 		return
 	}
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(s.stream.ctx) {
 		log.Proxy.Debugf(s.stream.ctx, "[stream] [http] send client request, requestId = %v", s.stream.id)
 	}
 	s.connection.requestSent <- true
@@ -568,9 +594,12 @@ func (s *clientStream) handleResponse() {
 			hasData = false
 		}
 
-		s.connection.mutex.Lock()
-		s.connection.stream = nil
-		s.connection.mutex.Unlock()
+		// the upgraded connection keeps the stream active, so it will not be reused by the connection pool
+		if statusCode != fasthttp.StatusSwitchingProtocols {
+			s.connection.mutex.Lock()
+			s.connection.stream = nil
+			s.connection.mutex.Unlock()
+		}
 
 		if hasData {
 			s.receiver.OnReceive(s.ctx, header, buffer.NewIoBufferBytes(s.response.Body()), nil)
@@ -595,6 +624,7 @@ type serverStream struct {
 	header           mosnhttp.RequestHeader
 	connection       *serverStreamConnection
 	responseDoneChan chan bool
+	tunnel           *tunnel
 }
 
 // types.StreamSender
@@ -663,6 +693,9 @@ func (s *serverStream) endStream() {
 	defer s.DestroyStream()
 
 	s.doSend()
+	if s.tunnel != nil {
+		s.tunnel.start()
+	}
 	s.responseDoneChan <- true
 
 	if resetConn {
@@ -692,7 +725,7 @@ func (s *serverStream) doSend() {
 	if _, err := s.response.WriteTo(s.connection); err != nil {
 		log.Proxy.Errorf(s.stream.ctx, "[stream] [http] send server response error: %+v", err)
 	} else {
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(s.stream.ctx) {
 			log.Proxy.Debugf(s.stream.ctx, "[stream] [http] send server response, requestId = %v", s.stream.id)
 		}
 	}
@@ -722,6 +755,14 @@ func (s *serverStream) GetStream() types.Stream {
 
 // consider host, method, path are necessary, but check querystring
 func injectInternalHeaders(headers mosnhttp.RequestHeader, uri *fasthttp.URI) {
+	// the request-target of CONNECT is the authority of the tunnel target, and there is no path
+	if headers.IsConnect() {
+		authority := string(headers.RequestURI())
+		headers.Set(protocol.MosnHeaderHostKey, authority)
+		headers.Set(protocol.IstioHeaderHostKey, authority)
+		headers.Set(protocol.MosnHeaderMethod, string(headers.Method()))
+		return
+	}
 	// 1. host
 	headers.Set(protocol.MosnHeaderHostKey, string(uri.Host()))
 	// 2. :authority
@@ -18,6 +18,8 @@
 package http
 
 import (
+	"bufio"
+	"strings"
 	"testing"
 
 	"net"
@@ -220,6 +222,36 @@ func Test_internal_header(t *testing.T) {
 	}
 }
 
+func Test_internal_header_authority(t *testing.T) {
+	for _, tc := range []struct {
+		raw       string
+		authority string
+		path      string
+	}{
+		// CONNECT is routed by the authority of the request-target
+		{"CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n", "example.com:443", ""},
+		// the absolute-URI of the forward proxy request overrides the host header
+		{"GET http://example.com:8080/a?b=c HTTP/1.1\r\nHost: proxy.local\r\n\r\n", "example.com:8080", "/a"},
+		{"GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n", "example.com", "/a"},
+	} {
+		request := &fasthttp.Request{}
+		if err := request.Read(bufio.NewReader(strings.NewReader(tc.raw))); err != nil {
+			t.Fatal(err)
+		}
+		header := http.RequestHeader{&request.Header, nil}
+		injectInternalHeaders(header, request.URI())
+		if host, _ := header.Get(protocol.MosnHeaderHostKey); host != tc.authority {
+			t.Errorf("%q: unexpected host: %s", tc.raw, host)
+		}
+		if authority, _ := header.Get(protocol.IstioHeaderHostKey); authority != tc.authority {
+			t.Errorf("%q: unexpected authority: %s", tc.raw, authority)
+		}
+		if path, _ := header.Get(protocol.MosnHeaderPathKey); path != tc.path {
+			t.Errorf("%q: unexpected path: %s", tc.raw, path)
+		}
+	}
+}
+
 func Test_serverStream_handleRequest(t *testing.T) {
 	type fields struct {
 		stream           stream
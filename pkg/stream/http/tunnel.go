@@ -0,0 +1,259 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+const defaultTunnelBufferSize = 16 * 1024
+
+var (
+	errUpgradeNotSupported = errors.New("upgrade is only supported between http1 streams")
+	errNotConnectRequest   = errors.New("not a connect request")
+)
+
+// tunnel relays the raw bytes between the downstream and the upstream connections
+// after the HTTP/1.1 Upgrade, such as WebSocket, or after the HTTP CONNECT request is accepted.
+// The downstream side is relayed in the server stream connection's serve goroutine.
+// The upstream side of an upgraded tunnel is relayed in the client stream connection's serve goroutine,
+// and the upstream side of a CONNECT tunnel is relayed by the read filter of the raw upstream connection.
+type tunnel struct {
+	ctx         context.Context
+	downstream  *serverStreamConnection
+	upstream    *streamConnection
+	peer        *clientStreamConnection
+	filter      *connectFilter
+	listener    types.TunnelEventListener
+	idleTimeout time.Duration
+
+	mutex     sync.Mutex
+	idleTimer *utils.Timer
+	started   bool
+
+	lastActive    int64
+	bytesReceived uint64
+	bytesSent     uint64
+	closed        uint32
+}
+
+func newTunnel(s *serverStream, upstream *streamConnection, idleTimeout time.Duration, listener types.TunnelEventListener) *tunnel {
+	return &tunnel{
+		ctx:         s.ctx,
+		downstream:  s.connection,
+		upstream:    upstream,
+		listener:    listener,
+		idleTimeout: idleTimeout,
+		lastActive:  time.Now().UnixNano(),
+	}
+}
+
+// isUpgradeRequest checks the request has 'Connection: Upgrade' and 'Upgrade' headers
+func isUpgradeRequest(header *fasthttp.RequestHeader) bool {
+	return header.ConnectionUpgrade() && len(header.Peek("Upgrade")) > 0
+}
+
+// Upgrade implements types.UpgradableStream, the tunnel starts after the upgrade response is sent.
+func (s *serverStream) Upgrade(upstream types.Stream, idleTimeout time.Duration, listener types.TunnelEventListener) error {
+	peer, ok := upstream.(*clientStream)
+	if !ok {
+		return errUpgradeNotSupported
+	}
+	if !isUpgradeRequest(&s.request.Header) {
+		return errors.New("not an upgrade request")
+	}
+	t := newTunnel(s, &peer.connection.streamConnection, idleTimeout, listener)
+	t.peer = peer.connection
+	s.tunnel = t
+	return nil
+}
+
+// Tunnel implements types.ConnectStream, the upstream connection is connected here,
+// and the tunnel starts after the connect response is sent.
+func (s *serverStream) Tunnel(upstream types.ClientConnection, idleTimeout time.Duration, listener types.TunnelEventListener) error {
+	if !s.request.Header.IsConnect() {
+		return errNotConnectRequest
+	}
+	t := newTunnel(s, &streamConnection{context: s.ctx, conn: upstream}, idleTimeout, listener)
+	t.filter = &connectFilter{tunnel: t}
+	// the filter is added before connected, so no upstream bytes is missed
+	upstream.FilterManager().AddReadFilter(t.filter)
+	if err := upstream.Connect(); err != nil {
+		return err
+	}
+	upstream.AddConnectionEventListener(t.filter)
+	// a successful connect response has no body
+	s.response.SkipBody = true
+	s.tunnel = t
+	return nil
+}
+
+// start is called after the upgrade or connect response is sent to the downstream
+func (t *tunnel) start() {
+	if log.DebugEnabled(t.ctx) {
+		log.Proxy.Debugf(t.ctx, "[stream] [http] [tunnel] start tunnel, downstream connection = %d, upstream connection = %d",
+			t.downstream.conn.ID(), t.upstream.conn.ID())
+	}
+	t.mutex.Lock()
+	t.started = true
+	closed := atomic.LoadUint32(&t.closed) == 1
+	if !closed && t.idleTimeout > 0 {
+		t.idleTimer = utils.NewTimer(t.idleTimeout, t.onIdleTimeout)
+	}
+	t.mutex.Unlock()
+
+	// the upstream is closed before the response is sent
+	if closed {
+		t.downstream.conn.Close(types.FlushWrite, types.LocalClose)
+		t.onClose()
+		return
+	}
+	t.downstream.tunnel = t
+	if t.peer != nil {
+		t.peer.tunnelChan <- t
+	} else {
+		t.filter.start()
+	}
+}
+
+// relay copies the bytes read from src to dst until any side is closed
+func (t *tunnel) relay(src, dst *streamConnection, counter *uint64) {
+	buf := make([]byte, defaultTunnelBufferSize)
+	for {
+		n, err := src.br.Read(buf)
+		if n > 0 {
+			if werr := t.write(dst, buf[:n], counter); werr != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	t.close()
+}
+
+func (t *tunnel) write(dst *streamConnection, p []byte, counter *uint64) error {
+	atomic.AddUint64(counter, uint64(len(p)))
+	atomic.StoreInt64(&t.lastActive, time.Now().UnixNano())
+	_, err := dst.Write(p)
+	return err
+}
+
+func (t *tunnel) onIdleTimeout() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if atomic.LoadUint32(&t.closed) == 1 {
+		return
+	}
+	idle := time.Duration(time.Now().UnixNano() - atomic.LoadInt64(&t.lastActive))
+	if idle < t.idleTimeout {
+		t.idleTimer = utils.NewTimer(t.idleTimeout-idle, t.onIdleTimeout)
+		return
+	}
+	if log.DebugEnabled(t.ctx) {
+		log.Proxy.Debugf(t.ctx, "[stream] [http] [tunnel] close the idle tunnel, downstream connection = %d", t.downstream.conn.ID())
+	}
+	utils.GoWithRecover(t.close, nil)
+}
+
+// close closes both connections, the listener is notified once the tunnel is started
+func (t *tunnel) close() {
+	t.mutex.Lock()
+	if !atomic.CompareAndSwapUint32(&t.closed, 0, 1) {
+		t.mutex.Unlock()
+		return
+	}
+	t.idleTimer.Stop()
+	started := t.started
+	t.mutex.Unlock()
+
+	t.upstream.conn.Close(types.FlushWrite, types.LocalClose)
+	if started {
+		t.downstream.conn.Close(types.FlushWrite, types.LocalClose)
+		t.onClose()
+	}
+}
+
+func (t *tunnel) onClose() {
+	received, sent := atomic.LoadUint64(&t.bytesReceived), atomic.LoadUint64(&t.bytesSent)
+	if log.DebugEnabled(t.ctx) {
+		log.Proxy.Debugf(t.ctx, "[stream] [http] [tunnel] tunnel closed, bytes received = %d, bytes sent = %d", received, sent)
+	}
+	t.listener.OnTunnelClose(received, sent)
+}
+
+// connectFilter relays the bytes of the raw upstream connection of a CONNECT tunnel to the downstream,
+// the bytes received before the connect response is sent are held until the tunnel starts.
+type connectFilter struct {
+	tunnel *tunnel
+
+	mutex   sync.Mutex
+	started bool
+	pending []byte
+}
+
+func (f *connectFilter) start() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.started = true
+	if len(f.pending) > 0 {
+		if err := f.tunnel.write(&f.tunnel.downstream.streamConnection, f.pending, &f.tunnel.bytesSent); err != nil {
+			utils.GoWithRecover(f.tunnel.close, nil)
+		}
+		f.pending = nil
+	}
+}
+
+// types.ReadFilter
+func (f *connectFilter) OnData(buf types.IoBuffer) types.FilterStatus {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.started {
+		f.pending = append(f.pending, buf.Bytes()...)
+	} else if err := f.tunnel.write(&f.tunnel.downstream.streamConnection, buf.Bytes(), &f.tunnel.bytesSent); err != nil {
+		utils.GoWithRecover(f.tunnel.close, nil)
+	}
+	buf.Drain(buf.Len())
+	return types.Stop
+}
+
+func (f *connectFilter) OnNewConnection() types.FilterStatus {
+	return types.Continue
+}
+
+func (f *connectFilter) InitializeReadFilterCallbacks(cb types.ReadFilterCallbacks) {}
+
+// types.ConnectionEventListener
+func (f *connectFilter) OnEvent(event types.ConnectionEvent) {
+	if event.IsClose() {
+		f.tunnel.close()
+	}
+}
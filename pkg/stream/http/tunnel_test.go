@@ -0,0 +1,305 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockTunnelConnection struct {
+	types.Connection
+	id      uint64
+	mutex   sync.Mutex
+	written bytes.Buffer
+	once    sync.Once
+	onClose func()
+}
+
+func (c *mockTunnelConnection) ID() uint64 {
+	return c.id
+}
+
+func (c *mockTunnelConnection) Write(bufs ...types.IoBuffer) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, buf := range bufs {
+		c.written.Write(buf.Bytes())
+	}
+	return nil
+}
+
+func (c *mockTunnelConnection) Close(ccType types.ConnectionCloseType, eventType types.ConnectionEvent) error {
+	c.once.Do(c.onClose)
+	return nil
+}
+
+func (c *mockTunnelConnection) String() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.written.String()
+}
+
+type mockTunnelListener struct {
+	closed        chan struct{}
+	bytesReceived uint64
+	bytesSent     uint64
+}
+
+func (l *mockTunnelListener) OnTunnelClose(bytesReceived, bytesSent uint64) {
+	l.bytesReceived = bytesReceived
+	l.bytesSent = bytesSent
+	close(l.closed)
+}
+
+// newTestTunnelStreams creates an upgrade request stream and an upstream stream on the mock connections
+func newTestTunnelStreams() (*serverStream, *clientStream) {
+	newConn := func(id uint64) streamConnection {
+		sc := streamConnection{
+			context:    context.Background(),
+			bufChan:    make(chan types.IoBuffer),
+			connClosed: make(chan bool, 1),
+		}
+		sc.conn = &mockTunnelConnection{
+			id: id,
+			onClose: func() {
+				close(sc.bufChan)
+				close(sc.connClosed)
+			},
+		}
+		return sc
+	}
+	down := &serverStreamConnection{streamConnection: newConn(1)}
+	down.br = bufio.NewReader(down)
+	up := &clientStreamConnection{streamConnection: newConn(2), tunnelChan: make(chan *tunnel, 1)}
+	up.br = bufio.NewReader(up)
+
+	request := &fasthttp.Request{}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	server := &serverStream{
+		stream:     stream{ctx: context.Background(), request: request},
+		connection: down,
+	}
+	client := &clientStream{connection: up}
+	return server, client
+}
+
+func startTestTunnel(t *testing.T, server *serverStream, client *clientStream, idleTimeout time.Duration) *mockTunnelListener {
+	listener := &mockTunnelListener{closed: make(chan struct{})}
+	if err := server.Upgrade(client, idleTimeout, listener); err != nil {
+		t.Fatal(err)
+	}
+	server.tunnel.start()
+	down, up := server.connection, client.connection
+	go server.tunnel.relay(&down.streamConnection, &up.streamConnection, &server.tunnel.bytesReceived)
+	go func() {
+		tun := <-up.tunnelChan
+		tun.relay(&up.streamConnection, &down.streamConnection, &tun.bytesSent)
+	}()
+	return listener
+}
+
+func waitTunnelClose(t *testing.T, listener *mockTunnelListener) {
+	select {
+	case <-listener.closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("tunnel is not closed")
+	}
+}
+
+func TestTunnelRelay(t *testing.T) {
+	server, client := newTestTunnelStreams()
+	listener := startTestTunnel(t, server, client, 0)
+	down := server.connection.conn.(*mockTunnelConnection)
+	up := client.connection.conn.(*mockTunnelConnection)
+
+	server.connection.Dispatch(buffer.NewIoBufferString("hello"))
+	client.connection.Dispatch(buffer.NewIoBufferString("world!"))
+	server.connection.Dispatch(buffer.NewIoBufferString(" tunnel"))
+	// the downstream is closed
+	down.Close(types.NoFlush, types.RemoteClose)
+	waitTunnelClose(t, listener)
+
+	if up.String() != "hello tunnel" || down.String() != "world!" {
+		t.Errorf("unexpected relayed data: %q, %q", up.String(), down.String())
+	}
+	if listener.bytesReceived != 12 || listener.bytesSent != 6 {
+		t.Errorf("unexpected bytes: %d, %d", listener.bytesReceived, listener.bytesSent)
+	}
+}
+
+func TestTunnelIdleTimeout(t *testing.T) {
+	server, client := newTestTunnelStreams()
+	listener := startTestTunnel(t, server, client, 100*time.Millisecond)
+
+	// the activity delays the idle timeout
+	time.Sleep(60 * time.Millisecond)
+	client.connection.Dispatch(buffer.NewIoBufferString("ping"))
+	start := time.Now()
+	waitTunnelClose(t, listener)
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("the tunnel is closed before idle timeout")
+	}
+	if listener.bytesSent != 4 {
+		t.Errorf("unexpected bytes sent: %d", listener.bytesSent)
+	}
+}
+
+func TestUpgradeNotSupported(t *testing.T) {
+	server, client := newTestTunnelStreams()
+	listener := &mockTunnelListener{closed: make(chan struct{})}
+	if err := server.Upgrade(nil, 0, listener); err == nil {
+		t.Error("upgrade to a non http1 stream should be failed")
+	}
+	server.request.Header.Del("Upgrade")
+	if err := server.Upgrade(client, 0, listener); err == nil {
+		t.Error("upgrade a non upgrade request should be failed")
+	}
+	if server.tunnel != nil {
+		t.Error("tunnel should not be set")
+	}
+}
+
+type mockFilterManager struct {
+	types.FilterManager
+	filters []types.ReadFilter
+}
+
+func (fm *mockFilterManager) AddReadFilter(rf types.ReadFilter) {
+	fm.filters = append(fm.filters, rf)
+}
+
+type mockConnectConnection struct {
+	mockTunnelConnection
+	filterManager mockFilterManager
+	connectErr    error
+	listeners     []types.ConnectionEventListener
+}
+
+func (c *mockConnectConnection) FilterManager() types.FilterManager {
+	return &c.filterManager
+}
+
+func (c *mockConnectConnection) AddConnectionEventListener(listener types.ConnectionEventListener) {
+	c.listeners = append(c.listeners, listener)
+}
+
+func (c *mockConnectConnection) Connect() error {
+	return c.connectErr
+}
+
+func (c *mockConnectConnection) Close(ccType types.ConnectionCloseType, eventType types.ConnectionEvent) error {
+	c.mockTunnelConnection.Close(ccType, eventType)
+	for _, listener := range c.listeners {
+		listener.OnEvent(eventType)
+	}
+	return nil
+}
+
+func (c *mockConnectConnection) receive(s string) {
+	for _, rf := range c.filterManager.filters {
+		rf.OnData(buffer.NewIoBufferString(s))
+	}
+}
+
+// newTestConnectStream creates a CONNECT request stream and the raw upstream connection
+func newTestConnectStream() (*serverStream, *mockConnectConnection) {
+	server, _ := newTestTunnelStreams()
+	server.request.Header.SetMethod("CONNECT")
+	server.request.SetRequestURI("example.com:443")
+	server.response = &fasthttp.Response{}
+	up := &mockConnectConnection{}
+	up.id = 2
+	up.onClose = func() {}
+	return server, up
+}
+
+func TestConnectTunnel(t *testing.T) {
+	server, up := newTestConnectStream()
+	listener := &mockTunnelListener{closed: make(chan struct{})}
+	if err := server.Tunnel(up, 0, listener); err != nil {
+		t.Fatal(err)
+	}
+	if !server.response.SkipBody {
+		t.Error("the connect response should have no body")
+	}
+	down := server.connection.conn.(*mockTunnelConnection)
+	// the upstream bytes are held until the connect response is sent
+	up.receive("banner")
+	if down.String() != "" {
+		t.Fatalf("the upstream bytes are relayed before the tunnel starts: %q", down.String())
+	}
+	server.tunnel.start()
+	go server.tunnel.relay(&server.connection.streamConnection, server.tunnel.upstream, &server.tunnel.bytesReceived)
+
+	server.connection.Dispatch(buffer.NewIoBufferString("hello"))
+	up.receive(" world")
+	// the downstream is closed
+	down.Close(types.NoFlush, types.RemoteClose)
+	waitTunnelClose(t, listener)
+
+	if up.String() != "hello" || down.String() != "banner world" {
+		t.Errorf("unexpected relayed data: %q, %q", up.String(), down.String())
+	}
+	if listener.bytesReceived != 5 || listener.bytesSent != 12 {
+		t.Errorf("unexpected bytes: %d, %d", listener.bytesReceived, listener.bytesSent)
+	}
+}
+
+func TestConnectTunnelClosedBeforeStart(t *testing.T) {
+	server, up := newTestConnectStream()
+	listener := &mockTunnelListener{closed: make(chan struct{})}
+	if err := server.Tunnel(up, 0, listener); err != nil {
+		t.Fatal(err)
+	}
+	up.Close(types.NoFlush, types.RemoteClose)
+	select {
+	case <-listener.closed:
+		t.Fatal("the listener is notified before the tunnel starts")
+	default:
+	}
+	server.tunnel.start()
+	waitTunnelClose(t, listener)
+}
+
+func TestConnectTunnelFailed(t *testing.T) {
+	server, up := newTestConnectStream()
+	listener := &mockTunnelListener{closed: make(chan struct{})}
+	up.connectErr = errors.New("connection refused")
+	if err := server.Tunnel(up, 0, listener); err == nil {
+		t.Error("tunnel should be failed if the upstream is not connected")
+	}
+	server, up = newTestConnectStream()
+	server.request.Header.SetMethod("GET")
+	if err := server.Tunnel(up, 0, listener); err != errNotConnectRequest {
+		t.Errorf("tunnel a non connect request should be failed, but got: %v", err)
+	}
+	if server.tunnel != nil {
+		t.Error("tunnel should not be set")
+	}
+}
@@ -39,7 +39,7 @@ const (
 var (
 	builtinVariables = []variable.Variable{
 		variable.NewBasicVariable(VarRequestMethod, nil, requestMethodGetter, nil, 0),
-		variable.NewBasicVariable(VarRequestLength, nil, requestLengthGetter, nil, 0),
+		variable.NewTypedVariable(VarRequestLength, nil, requestLengthGetter, nil, 0, variable.ValueTypeNumber),
 	}
 
 	prefixVariables = []variable.Variable{
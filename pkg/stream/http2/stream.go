@@ -27,6 +27,7 @@ import (
 	"reflect"
 	"strconv"
 	"sync"
+	"time"
 
 	"mosn.io/mosn/pkg/buffer"
 	mosnctx "mosn.io/mosn/pkg/context"
@@ -36,6 +37,7 @@ import (
 	"mosn.io/mosn/pkg/protocol"
 	mhttp2 "mosn.io/mosn/pkg/protocol/http2"
 	str "mosn.io/mosn/pkg/stream"
+	"mosn.io/mosn/pkg/trace"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -157,6 +159,9 @@ type serverStreamConnection struct {
 	mutex   sync.RWMutex
 	streams map[uint32]*serverStream
 	sc      *http2.MServerConn
+	// the tunnels of the CONNECT streams, they are closed with the connection
+	tunnels map[uint32]*tunnel
+	closed  bool
 
 	serverCallbacks types.ServerStreamConnectionEventListener
 }
@@ -187,6 +192,8 @@ func newServerStreamConnection(ctx context.Context, connection types.Connection,
 	})
 
 	sc.streams = make(map[uint32]*serverStream, 32)
+	sc.tunnels = make(map[uint32]*tunnel)
+	connection.AddConnectionEventListener(sc)
 	log.Proxy.Debugf(ctx, "new http2 server stream connection")
 
 	return sc
@@ -248,6 +255,12 @@ func (conn *serverStreamConnection) handleFrame(ctx context.Context, i interface
 		return
 	}
 
+	// the client resets the CONNECT stream
+	if _, ok := f.(*http2.RSTStreamFrame); ok {
+		conn.onStreamReset(f.Header().StreamID)
+		return
+	}
+
 	if h2s == nil && data == nil && !hasTrailer && !endStream {
 		return
 	}
@@ -280,7 +293,10 @@ func (conn *serverStreamConnection) handleFrame(ctx context.Context, i interface
 
 		header.Set(protocol.MosnHeaderMethod, h2s.Request.Method)
 		header.Set(protocol.MosnHeaderHostKey, h2s.Request.Host)
-		header.Set(protocol.MosnHeaderPathKey, h2s.Request.URL.Path)
+		// the CONNECT request is routed by the authority, and has no path
+		if h2s.Request.Method != http.MethodConnect {
+			header.Set(protocol.MosnHeaderPathKey, h2s.Request.URL.Path)
+		}
 		if h2s.Request.URL.RawQuery != "" {
 			header.Set(protocol.MosnHeaderQueryStringKey, h2s.Request.URL.RawQuery)
 		}
@@ -289,6 +305,11 @@ func (conn *serverStreamConnection) handleFrame(ctx context.Context, i interface
 
 		if endStream {
 			stream.receiver.OnReceive(ctx, header, nil, nil)
+		} else if h2s.Request.Method == http.MethodConnect {
+			// the payload of the CONNECT request is relayed by the tunnel,
+			// so the request is received without waiting for the end of stream
+			stream.tunnel = newTunnel(stream)
+			stream.receiver.OnReceive(ctx, header, nil, nil)
 		} else {
 			stream.header = header
 		}
@@ -301,6 +322,12 @@ func (conn *serverStreamConnection) handleFrame(ctx context.Context, i interface
 		return
 	}
 
+	// the payload of the CONNECT request
+	if stream.tunnel != nil {
+		stream.tunnel.onDownstreamData(data)
+		return
+	}
+
 	// data
 	if data != nil {
 		log.DefaultLogger.Debugf("http2 server receive data: %d", id)
@@ -370,7 +397,17 @@ func (conn *serverStreamConnection) onNewStreamDetect(ctx context.Context, h2s *
 		conn.mutex.Unlock()
 	}
 
-	stream.receiver = conn.serverCallbacks.NewStreamDetect(stream.ctx, stream, nil)
+	// the request id should be injected before tracing, it is used by sampling
+	stream.ctx = protocol.InjectRequestID(stream.ctx, mhttp2.NewReqHeader(h2s.Request))
+
+	var span types.Span
+	if trace.IsEnabled() {
+		tracer := trace.Tracer(protocol.HTTP2)
+		if tracer != nil {
+			span = tracer.Start(ctx, mhttp2.NewReqHeader(h2s.Request), time.Now())
+		}
+	}
+	stream.receiver = conn.serverCallbacks.NewStreamDetect(stream.ctx, stream, span)
 	return stream, nil
 }
 
@@ -392,6 +429,8 @@ type serverStream struct {
 	stream
 	h2s *http2.MStream
 	sc  *serverStreamConnection
+	// tunnel is set if the stream is a CONNECT request
+	tunnel *tunnel
 }
 
 // types.StreamSender
@@ -466,6 +505,11 @@ func (s *serverStream) AppendTrailers(context context.Context, trailers types.He
 func (s *serverStream) endStream() {
 	defer s.DestroyStream()
 
+	if s.tunnel != nil && s.tunnel.accepted() {
+		s.sendConnectResponse()
+		return
+	}
+
 	_, err := s.sc.codecEngine.Encode(s.ctx, s.h2s)
 	if err != nil {
 		// todo: other error scenes
@@ -482,6 +526,9 @@ func (s *serverStream) ResetStream(reason types.StreamResetReason) {
 	log.Proxy.Errorf(s.ctx, "http2 server reset stream id = %d, error = %v", s.id, reason)
 	s.h2s.Reset()
 	s.stream.ResetStream(reason)
+	if s.tunnel != nil {
+		s.tunnel.close()
+	}
 }
 
 func (s *serverStream) GetStream() types.Stream {
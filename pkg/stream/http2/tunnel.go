@@ -0,0 +1,300 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mosn.io/mosn/pkg/buffer"
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+var (
+	errNotConnectRequest = errors.New("not a connect request")
+	errConnectionClosed  = errors.New("connection closed")
+)
+
+// tunnel relays the payload of a CONNECT stream and the raw upstream connection.
+// The DATA frames of the stream are relayed in the server stream connection's dispatch goroutine,
+// and the upstream bytes are relayed by the read filter of the upstream connection.
+// The bytes received before the connect response is sent are held until the tunnel starts.
+type tunnel struct {
+	ctx         context.Context
+	stream      *serverStream
+	upstream    types.ClientConnection
+	listener    types.TunnelEventListener
+	idleTimeout time.Duration
+
+	mutex             sync.Mutex
+	idleTimer         *utils.Timer
+	started           bool
+	downstreamPending []byte
+	upstreamPending   []byte
+
+	lastActive    int64
+	bytesReceived uint64
+	bytesSent     uint64
+	closed        uint32
+}
+
+func newTunnel(s *serverStream) *tunnel {
+	return &tunnel{
+		ctx:    s.ctx,
+		stream: s,
+	}
+}
+
+// Tunnel implements types.ConnectStream, the upstream connection is connected here,
+// and the tunnel starts after the connect response is sent.
+func (s *serverStream) Tunnel(upstream types.ClientConnection, idleTimeout time.Duration, listener types.TunnelEventListener) error {
+	t := s.tunnel
+	if t == nil {
+		return errNotConnectRequest
+	}
+	if !s.sc.addTunnel(s.id, t) {
+		return errConnectionClosed
+	}
+	// the filter is added before connected, so no upstream bytes is missed
+	upstream.FilterManager().AddReadFilter(t)
+	if err := upstream.Connect(); err != nil {
+		s.sc.removeTunnel(s.id)
+		return err
+	}
+	upstream.AddConnectionEventListener(t)
+
+	t.mutex.Lock()
+	t.upstream = upstream
+	t.listener = listener
+	t.idleTimeout = idleTimeout
+	t.lastActive = time.Now().UnixNano()
+	closed := atomic.LoadUint32(&t.closed) == 1
+	t.mutex.Unlock()
+
+	// the downstream connection is closed while connecting
+	if closed {
+		upstream.Close(types.NoFlush, types.LocalClose)
+		return errConnectionClosed
+	}
+	return nil
+}
+
+// sendConnectResponse sends the response headers of the accepted CONNECT request and starts the tunnel,
+// the stream is not ended until the tunnel is closed.
+func (s *serverStream) sendConnectResponse() {
+	if err := s.h2s.SendHeaders(); err != nil {
+		log.Proxy.Errorf(s.ctx, "http2 server send connect response error: %v", err)
+		s.tunnel.close()
+	}
+	s.tunnel.start()
+}
+
+// onStreamReset closes the tunnel of the CONNECT stream reset by the client
+func (conn *serverStreamConnection) onStreamReset(id uint32) {
+	conn.mutex.Lock()
+	s := conn.streams[id]
+	if s != nil && s.tunnel != nil {
+		delete(conn.streams, id)
+	}
+	conn.mutex.Unlock()
+
+	if s != nil && s.tunnel != nil {
+		log.Proxy.Debugf(s.ctx, "http2 server connect stream reset by client, id = %d", id)
+		s.tunnel.close()
+	}
+}
+
+func (conn *serverStreamConnection) addTunnel(id uint32, t *tunnel) bool {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.closed {
+		return false
+	}
+	conn.tunnels[id] = t
+	return true
+}
+
+func (conn *serverStreamConnection) removeTunnel(id uint32) {
+	conn.mutex.Lock()
+	delete(conn.tunnels, id)
+	conn.mutex.Unlock()
+}
+
+// OnEvent implements types.ConnectionEventListener, the tunnels left are closed with the connection
+func (conn *serverStreamConnection) OnEvent(event types.ConnectionEvent) {
+	if !event.IsClose() {
+		return
+	}
+	conn.mutex.Lock()
+	conn.closed = true
+	tunnels := conn.tunnels
+	conn.tunnels = make(map[uint32]*tunnel)
+	conn.mutex.Unlock()
+
+	for _, t := range tunnels {
+		t.close()
+	}
+}
+
+func (t *tunnel) accepted() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.upstream != nil
+}
+
+func (t *tunnel) start() {
+	t.mutex.Lock()
+	t.started = true
+	closed := atomic.LoadUint32(&t.closed) == 1
+	if !closed {
+		log.Proxy.Debugf(t.ctx, "http2 server start tunnel, id = %d, upstream connection = %d", t.stream.id, t.upstream.ID())
+		if t.idleTimeout > 0 {
+			t.idleTimer = utils.NewTimer(t.idleTimeout, t.onIdleTimeout)
+		}
+		// the pending bytes are relayed with the lock held, so the order is kept
+		if len(t.upstreamPending) > 0 {
+			t.sendDownstream(t.upstreamPending)
+		}
+		if len(t.downstreamPending) > 0 {
+			t.sendUpstream(t.downstreamPending)
+		}
+	}
+	t.upstreamPending, t.downstreamPending = nil, nil
+	t.mutex.Unlock()
+
+	// the tunnel is closed before the response is sent
+	if closed {
+		t.upstream.Close(types.NoFlush, types.LocalClose)
+		t.stream.h2s.WriteData(nil, true)
+		t.onClose()
+	}
+}
+
+// onDownstreamData is called with the DATA frames of the stream, the end of stream is not relayed,
+// the tunnel is kept until the upstream is closed.
+func (t *tunnel) onDownstreamData(data []byte) {
+	t.stream.h2s.ReleaseData(len(data))
+	if len(data) == 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.started {
+		t.downstreamPending = append(t.downstreamPending, data...)
+	} else if atomic.LoadUint32(&t.closed) == 0 {
+		t.sendUpstream(data)
+	}
+}
+
+func (t *tunnel) sendUpstream(data []byte) {
+	atomic.AddUint64(&t.bytesReceived, uint64(len(data)))
+	atomic.StoreInt64(&t.lastActive, time.Now().UnixNano())
+	buf := buffer.GetIoBuffer(len(data))
+	buf.Write(data)
+	if err := t.upstream.Write(buf); err != nil {
+		utils.GoWithRecover(t.close, nil)
+	}
+}
+
+func (t *tunnel) sendDownstream(data []byte) {
+	atomic.AddUint64(&t.bytesSent, uint64(len(data)))
+	atomic.StoreInt64(&t.lastActive, time.Now().UnixNano())
+	if err := t.stream.h2s.WriteData(data, false); err != nil {
+		utils.GoWithRecover(t.close, nil)
+	}
+}
+
+// types.ReadFilter
+func (t *tunnel) OnData(buf types.IoBuffer) types.FilterStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.started {
+		t.upstreamPending = append(t.upstreamPending, buf.Bytes()...)
+	} else if atomic.LoadUint32(&t.closed) == 0 {
+		t.sendDownstream(buf.Bytes())
+	}
+	buf.Drain(buf.Len())
+	return types.Stop
+}
+
+func (t *tunnel) OnNewConnection() types.FilterStatus {
+	return types.Continue
+}
+
+func (t *tunnel) InitializeReadFilterCallbacks(cb types.ReadFilterCallbacks) {}
+
+// types.ConnectionEventListener, the upstream connection is listened,
+// the tunnel is closed by the server stream connection if the downstream connection is closed
+func (t *tunnel) OnEvent(event types.ConnectionEvent) {
+	if event.IsClose() {
+		t.close()
+	}
+}
+
+func (t *tunnel) onIdleTimeout() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if atomic.LoadUint32(&t.closed) == 1 {
+		return
+	}
+	idle := time.Duration(time.Now().UnixNano() - atomic.LoadInt64(&t.lastActive))
+	if idle < t.idleTimeout {
+		t.idleTimer = utils.NewTimer(t.idleTimeout-idle, t.onIdleTimeout)
+		return
+	}
+	log.Proxy.Debugf(t.ctx, "http2 server close the idle tunnel, id = %d", t.stream.id)
+	utils.GoWithRecover(t.close, nil)
+}
+
+// close closes the upstream connection and ends the stream, the listener is notified once the tunnel is started
+func (t *tunnel) close() {
+	t.mutex.Lock()
+	if !atomic.CompareAndSwapUint32(&t.closed, 0, 1) {
+		t.mutex.Unlock()
+		return
+	}
+	t.idleTimer.Stop()
+	started := t.started
+	upstream := t.upstream
+	t.mutex.Unlock()
+
+	t.stream.sc.removeTunnel(t.stream.id)
+
+	if upstream != nil {
+		upstream.Close(types.FlushWrite, types.LocalClose)
+	}
+	if started {
+		t.stream.h2s.WriteData(nil, true)
+		t.onClose()
+	}
+}
+
+func (t *tunnel) onClose() {
+	received, sent := atomic.LoadUint64(&t.bytesReceived), atomic.LoadUint64(&t.bytesSent)
+	log.Proxy.Debugf(t.ctx, "http2 server tunnel closed, id = %d, bytes received = %d, bytes sent = %d", t.stream.id, received, sent)
+	t.listener.OnTunnelClose(received, sent)
+}
@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http2
+
+import (
+	"context"
+	"testing"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockFilterManager struct {
+	types.FilterManager
+}
+
+func (fm *mockFilterManager) AddReadFilter(rf types.ReadFilter) {}
+
+type mockUpstreamConnection struct {
+	types.ClientConnection
+	closed int
+}
+
+func (c *mockUpstreamConnection) FilterManager() types.FilterManager {
+	return &mockFilterManager{}
+}
+
+func (c *mockUpstreamConnection) Connect() error {
+	return nil
+}
+
+func (c *mockUpstreamConnection) AddConnectionEventListener(listener types.ConnectionEventListener) {}
+
+func (c *mockUpstreamConnection) Close(ccType types.ConnectionCloseType, eventType types.ConnectionEvent) error {
+	c.closed++
+	return nil
+}
+
+func newTestConnectStream(conn *serverStreamConnection, id uint32) *serverStream {
+	s := &serverStream{
+		stream: stream{ctx: context.Background(), id: id},
+		sc:     conn,
+	}
+	s.tunnel = newTunnel(s)
+	return s
+}
+
+func TestTunnelClosedWithConnection(t *testing.T) {
+	conn := &serverStreamConnection{tunnels: make(map[uint32]*tunnel)}
+	s1, s2 := newTestConnectStream(conn, 1), newTestConnectStream(conn, 3)
+	up1, up2 := &mockUpstreamConnection{}, &mockUpstreamConnection{}
+	if err := s1.Tunnel(up1, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.Tunnel(up2, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn.tunnels) != 2 {
+		t.Fatalf("expected 2 tunnels, but got %d", len(conn.tunnels))
+	}
+
+	// the ended tunnel is removed from the connection
+	s1.tunnel.close()
+	if _, ok := conn.tunnels[s1.id]; ok || len(conn.tunnels) != 1 {
+		t.Fatalf("the closed tunnel should be removed, tunnels: %d", len(conn.tunnels))
+	}
+
+	// the tunnels left are closed with the connection
+	conn.OnEvent(types.RemoteClose)
+	if len(conn.tunnels) != 0 || up2.closed == 0 {
+		t.Fatalf("the tunnel should be closed with the connection, tunnels: %d, upstream closed: %d", len(conn.tunnels), up2.closed)
+	}
+	if up1.closed != 1 {
+		t.Errorf("the closed tunnel should not be closed again, upstream closed: %d", up1.closed)
+	}
+
+	// no tunnel is created on the closed connection
+	s3 := newTestConnectStream(conn, 5)
+	if err := s3.Tunnel(&mockUpstreamConnection{}, 0, nil); err != errConnectionClosed {
+		t.Errorf("expected connection closed error, but got %v", err)
+	}
+	if len(conn.tunnels) != 0 {
+		t.Errorf("unexpected tunnels on the closed connection: %d", len(conn.tunnels))
+	}
+}
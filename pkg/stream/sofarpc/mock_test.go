@@ -146,3 +146,7 @@ func (ci *mockClusterInfo) SourceAddress() net.Addr {
 func (ci *mockClusterInfo) ConnectTimeout() time.Duration {
 	return network.DefaultConnectTimeout
 }
+
+func (ci *mockClusterInfo) ProxyProtocolVersion() int {
+	return 0
+}
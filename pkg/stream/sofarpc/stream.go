@@ -288,7 +288,7 @@ func (conn *streamConnection) onNewStreamDetect(ctx context.Context, cmd sofarpc
 	stream.direction = ServerStream
 	stream.sc = conn
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(stream.ctx) {
 		log.Proxy.Debugf(stream.ctx, "[stream] [sofarpc] new stream detect, requestId = %v", stream.id)
 	}
 
@@ -314,7 +314,7 @@ func (conn *streamConnection) onStreamRecv(ctx context.Context, cmd sofarpc.Sofa
 		// transmit buffer ctx
 		buffer.TransmitBufferPoolContext(stream.ctx, ctx)
 
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(stream.ctx) {
 			log.Proxy.Debugf(stream.ctx, "[stream] [sofarpc] receive response, requestId = %v", stream.id)
 		}
 		return stream
@@ -378,7 +378,7 @@ func (s *stream) AppendHeaders(ctx context.Context, headers types.HeaderMap, end
 		}
 	}
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(s.ctx) {
 		log.Proxy.Debugf(s.ctx, "[stream] [sofarpc] %s appendHeaders, requestId = %d", directionText[s.direction], s.id)
 	}
 
@@ -410,7 +410,7 @@ func (s *stream) AppendData(context context.Context, data types.IoBuffer, endStr
 		s.sendCmd.SetData(data)
 	}
 
-	if log.Proxy.GetLogLevel() >= log.DEBUG {
+	if log.DebugEnabled(s.ctx) {
 		log.Proxy.Debugf(s.ctx, "[stream] [sofarpc] %s appendData, requestId = %d", directionText[s.direction], s.id)
 	}
 
@@ -457,7 +457,7 @@ func (s *stream) endStream() {
 			err = s.sc.conn.Write(buf)
 		}
 
-		if log.Proxy.GetLogLevel() >= log.DEBUG {
+		if log.DebugEnabled(s.ctx) {
 			log.Proxy.Debugf(s.ctx, "[stream] [sofarpc] send %s, requestId = %v", directionText[s.direction], s.id)
 		}
 
@@ -30,7 +30,9 @@ import (
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/protocol/rpc/xprotocol"
 	_ "mosn.io/mosn/pkg/protocol/rpc/xprotocol/dubbo"
+	_ "mosn.io/mosn/pkg/protocol/rpc/xprotocol/thrift"
 	str "mosn.io/mosn/pkg/stream"
+	"mosn.io/mosn/pkg/trace/propagation"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -152,6 +154,18 @@ func (conn *streamConnection) Dispatch(buffer types.IoBuffer) {
 				}
 				log.DefaultLogger.Tracef("xprotocol handle request route ,headers = %v", headers)
 			}
+
+			// trace context carried in the attachments
+			if attachmentsCodec, ok := conn.codec.(xprotocol.Attachments); ok {
+				if keys := propagation.Keys(); len(keys) > 0 {
+					attachments := attachmentsCodec.GetAttachments(request)
+					for _, k := range keys {
+						if v, ok := attachments[k]; ok {
+							headers[k] = v
+						}
+					}
+				}
+			}
 		}
 		// tracing
 		tracingCodec, ok := conn.codec.(xprotocol.Tracing)
@@ -253,6 +267,7 @@ type stream struct {
 	context          context.Context
 	connection       *streamConnection
 	streamReceiver   types.StreamReceiveListener
+	headers          types.HeaderMap
 	encodedHeaders   types.IoBuffer
 	encodedData      types.IoBuffer
 }
@@ -278,6 +293,7 @@ func (s *stream) BufferLimit() uint32 {
 // types.StreamEncoder
 func (s *stream) AppendHeaders(context context.Context, headers types.HeaderMap, endStream bool) error {
 	log.DefaultLogger.Tracef("EncodeHeaders,request id = %s, direction = %d", s.streamID, s.direction)
+	s.headers = headers
 	if endStream {
 		s.endStream()
 	}
@@ -286,8 +302,12 @@ func (s *stream) AppendHeaders(context context.Context, headers types.HeaderMap,
 
 // AppendData process upstream request data
 func (s *stream) AppendData(context context.Context, data types.IoBuffer, endStream bool) error {
+	buf := data.Bytes()
+	if s.direction == ClientStream {
+		buf = s.setAttachments(buf)
+	}
 	// replace request id
-	newData := s.connection.codec.SetStreamID(data.Bytes(), s.streamID)
+	newData := s.connection.codec.SetStreamID(buf, s.streamID)
 	s.encodedData = networkbuffer.NewIoBufferBytes(newData)
 
 	if endStream {
@@ -296,6 +316,24 @@ func (s *stream) AppendData(context context.Context, data types.IoBuffer, endStr
 	return nil
 }
 
+// setAttachments writes the trace context in the request headers into the frame attachments
+func (s *stream) setAttachments(data []byte) []byte {
+	codec, ok := s.connection.codec.(xprotocol.Attachments)
+	if !ok || s.headers == nil {
+		return data
+	}
+	attachments := make(map[string]string)
+	for _, k := range propagation.Keys() {
+		if v, ok := s.headers.Get(k); ok && v != "" {
+			attachments[k] = v
+		}
+	}
+	if len(attachments) == 0 {
+		return data
+	}
+	return codec.SetAttachments(data, attachments)
+}
+
 // AppendTrailers process upstream request trailers
 func (s *stream) AppendTrailers(context context.Context, trailers types.HeaderMap) error {
 	log.DefaultLogger.Tracef("EncodeTrailers,request id = %s, direction = %d", s.streamID, s.direction)
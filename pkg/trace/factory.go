@@ -34,3 +34,9 @@ func RegisterTracerBuilder(typ string, protocol types.Protocol, builder types.Tr
 		driver.Register(protocol, builder)
 	}
 }
+
+// HasDriver returns whether the driver is registered
+func HasDriver(typ string) bool {
+	_, ok := drivers[typ]
+	return ok
+}
@@ -0,0 +1,132 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package opentelemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/otlp"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/types"
+)
+
+// DriverName is the tracing driver name in the config
+const DriverName = "OpenTelemetry"
+
+var (
+	defaultServiceName   = "mosn"
+	defaultSampleRate    = 1.0
+	defaultBatchSize     = 512
+	defaultQueueSize     = 2048
+	defaultFlushInterval = 5 * time.Second
+)
+
+// Config is the config of the opentelemetry driver
+type Config struct {
+	// the OTLP exporter config
+	otlp.Config
+	ServiceName        string            `json:"service_name"`
+	ResourceAttributes map[string]string `json:"resource_attributes"`
+	// the ratio of the root spans to be sampled, 0 to 1.
+	// the spans with a remote parent follow the sampling decision of the parent
+	SampleRate *float64 `json:"sample_rate"`
+	// the max number of spans in an export request
+	BatchSize int `json:"batch_size"`
+	// the max number of spans waiting to be exported, the new spans are dropped if the queue is full
+	QueueSize     int               `json:"queue_size"`
+	FlushInterval v2.DurationConfig `json:"flush_interval"`
+}
+
+func init() {
+	trace.RegisterDriver(DriverName, defaultDriver)
+	for _, proto := range []types.Protocol{protocol.HTTP1, protocol.HTTP2, protocol.SofaRPC} {
+		trace.RegisterTracerBuilder(DriverName, proto, newTracerBuilder(proto))
+	}
+}
+
+// driver shares one span processor between the tracers of all protocols
+type driver struct {
+	types.Driver
+	config    *Config
+	processor *processor
+}
+
+var defaultDriver = &driver{
+	Driver: trace.NewDefaultDriverImpl(),
+}
+
+func (d *driver) Init(config map[string]interface{}) error {
+	cfg, err := parseConfig(config)
+	if err != nil {
+		return err
+	}
+	exporter, err := otlp.NewExporter(&cfg.Config, otlp.SignalTraces)
+	if err != nil {
+		return err
+	}
+	if d.processor != nil {
+		d.processor.Close()
+	}
+	d.config = cfg
+	d.processor = newProcessor(cfg, exporter)
+	return d.Driver.Init(config)
+}
+
+func newTracerBuilder(proto types.Protocol) types.TracerBuilder {
+	return func(config map[string]interface{}) (types.Tracer, error) {
+		if defaultDriver.processor == nil {
+			return nil, errors.New("opentelemetry span processor is not initialized")
+		}
+		return newTracer(proto, defaultDriver.config, defaultDriver.processor), nil
+	}
+}
+
+func parseConfig(config map[string]interface{}) (*Config, error) {
+	cfg := &Config{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("parsing opentelemetry config error, err: %v, cfg: %v", err, config)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing opentelemetry config error, err: %v, cfg: %v", err, config)
+	}
+
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	if cfg.SampleRate == nil {
+		cfg.SampleRate = &defaultSampleRate
+	}
+	if *cfg.SampleRate < 0 || *cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("opentelemetry sample_rate should be in [0, 1], but got %v", *cfg.SampleRate)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.FlushInterval.Duration <= 0 {
+		cfg.FlushInterval.Duration = defaultFlushInterval
+	}
+	return cfg, nil
+}
@@ -0,0 +1,162 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package opentelemetry
+
+import (
+	"sync"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/otlp"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+const scopeName = "mosn.io/mosn/pkg/trace/opentelemetry"
+
+// Fields of the OTLP trace export request, see opentelemetry/proto/collector/trace/v1/trace_service.proto
+const (
+	// ExportTraceServiceRequest
+	fieldRequestResourceSpans = 1
+	// ResourceSpans
+	fieldResourceSpansResource   = 1
+	fieldResourceSpansScopeSpans = 2
+	// ScopeSpans
+	fieldScopeSpansScope = 1
+	fieldScopeSpansSpans = 2
+)
+
+// exporter sends the serialized export requests
+type exporter interface {
+	Export(body []byte) error
+	Close()
+}
+
+// processor is a batch span processor, the finished spans are queued in a bounded channel,
+// and dropped if the queue is full, so the exporting never blocks the requests
+type processor struct {
+	exporter      exporter
+	resource      map[string]string
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan *Span
+	stats         types.Metrics
+	stop          chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func newProcessor(cfg *Config, exporter exporter) *processor {
+	resource := map[string]string{
+		"service.name": cfg.ServiceName,
+	}
+	for k, v := range cfg.ResourceAttributes {
+		resource[k] = v
+	}
+	p := &processor{
+		exporter:      exporter,
+		resource:      resource,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval.Duration,
+		queue:         make(chan *Span, cfg.QueueSize),
+		stats:         metrics.NewTraceStats(DriverName),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	utils.GoWithRecover(p.run, nil)
+	return p
+}
+
+// OnEnd queues a finished span, the span is dropped if the queue is full
+func (p *processor) OnEnd(span *Span) {
+	select {
+	case p.queue <- span:
+	default:
+		p.stats.Counter(metrics.TraceSpanDropped).Inc(1)
+	}
+}
+
+// Close exports the queued spans and stops the processor
+func (p *processor) Close() {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+		<-p.done
+		p.exporter.Close()
+	})
+}
+
+func (p *processor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, p.batchSize)
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= p.batchSize {
+				batch = p.flush(batch)
+			}
+		case <-ticker.C:
+			batch = p.flush(batch)
+		case <-p.stop:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+					if len(batch) >= p.batchSize {
+						batch = p.flush(batch)
+					}
+				default:
+					p.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush exports the batch and returns the reset batch
+func (p *processor) flush(batch []*Span) []*Span {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := p.exporter.Export(p.encode(batch)); err != nil {
+		p.stats.Counter(metrics.TraceSpanReportFailed).Inc(int64(len(batch)))
+		log.DefaultLogger.Errorf("[trace] [opentelemetry] export %d spans failed: %v", len(batch), err)
+	} else {
+		p.stats.Counter(metrics.TraceSpanReported).Inc(int64(len(batch)))
+	}
+	return batch[:0]
+}
+
+// encode returns a serialized ExportTraceServiceRequest
+func (p *processor) encode(batch []*Span) []byte {
+	e := otlp.NewEncoder()
+	e.Message(fieldRequestResourceSpans, func(rs *otlp.Encoder) {
+		rs.Resource(fieldResourceSpansResource, p.resource)
+		rs.Message(fieldResourceSpansScopeSpans, func(ss *otlp.Encoder) {
+			ss.Scope(fieldScopeSpansScope, scopeName, "")
+			for _, span := range batch {
+				ss.Message(fieldScopeSpansSpans, span.encode)
+			}
+		})
+	})
+	return e.Bytes()
+}
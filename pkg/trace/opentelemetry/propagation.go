@@ -0,0 +1,160 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package opentelemetry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// W3C Trace Context and Baggage headers, see https://www.w3.org/TR/trace-context/ and https://www.w3.org/TR/baggage/
+const (
+	headerTraceParent = "traceparent"
+	headerTraceState  = "tracestate"
+	headerBaggage     = "baggage"
+
+	flagSampled = 0x01
+
+	maxTraceStateMembers = 32
+)
+
+type traceID [16]byte
+
+type spanID [8]byte
+
+func (t traceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (t traceID) isValid() bool {
+	return t != traceID{}
+}
+
+func (s spanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s spanID) isValid() bool {
+	return s != spanID{}
+}
+
+// spanContext is the trace context propagated by the W3C headers
+type spanContext struct {
+	traceID    traceID
+	spanID     spanID
+	traceFlags byte
+	traceState string
+	baggage    string
+}
+
+func (sc spanContext) isSampled() bool {
+	return sc.traceFlags&flagSampled != 0
+}
+
+func getHeader(headers types.HeaderMap, key string) string {
+	v, _ := headers.Get(key)
+	return strings.TrimSpace(v)
+}
+
+// extract reads the trace context from the headers, returns false if the traceparent is missing or invalid.
+// the baggage is extracted even if there is no valid traceparent
+func extract(headers types.HeaderMap) (spanContext, bool) {
+	sc := spanContext{
+		baggage: getHeader(headers, headerBaggage),
+	}
+	if !parseTraceParent(getHeader(headers, headerTraceParent), &sc) {
+		return sc, false
+	}
+	sc.traceState = parseTraceState(getHeader(headers, headerTraceState))
+	return sc, true
+}
+
+// parseTraceParent parses traceparent: {version}-{trace-id}-{parent-id}-{trace-flags}
+func parseTraceParent(v string, sc *spanContext) bool {
+	if len(v) < 55 {
+		return false
+	}
+	v = strings.ToLower(v)
+	version, err := hex.DecodeString(v[:2])
+	if err != nil || version[0] == 0xff || v[2] != '-' {
+		return false
+	}
+	// the future versions may append fields
+	if version[0] == 0 && len(v) != 55 || len(v) > 55 && v[55] != '-' {
+		return false
+	}
+	if v[35] != '-' || v[52] != '-' {
+		return false
+	}
+	var tid traceID
+	var sid spanID
+	if _, err := hex.Decode(tid[:], []byte(v[3:35])); err != nil || !tid.isValid() {
+		return false
+	}
+	if _, err := hex.Decode(sid[:], []byte(v[36:52])); err != nil || !sid.isValid() {
+		return false
+	}
+	flags, err := hex.DecodeString(v[53:55])
+	if err != nil {
+		return false
+	}
+	sc.traceID, sc.spanID, sc.traceFlags = tid, sid, flags[0]
+	return true
+}
+
+// parseTraceState drops the tracestate if it has too many members or any malformed member, as the spec suggests
+func parseTraceState(v string) string {
+	if v == "" {
+		return ""
+	}
+	members := strings.Split(v, ",")
+	if len(members) > maxTraceStateMembers {
+		return ""
+	}
+	kept := make([]string, 0, len(members))
+	for _, m := range members {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		if i := strings.IndexByte(m, '='); i <= 0 || i == len(m)-1 {
+			return ""
+		}
+		kept = append(kept, m)
+	}
+	return strings.Join(kept, ",")
+}
+
+func formatTraceParent(sc spanContext) string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.traceID, sc.spanID, sc.traceFlags)
+}
+
+// inject writes the trace context into the headers
+func inject(headers types.HeaderMap, sc spanContext) {
+	headers.Set(headerTraceParent, formatTraceParent(sc))
+	if sc.traceState != "" {
+		headers.Set(headerTraceState, sc.traceState)
+	} else {
+		headers.Del(headerTraceState)
+	}
+	if sc.baggage != "" {
+		headers.Set(headerBaggage, sc.baggage)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package opentelemetry
+
+import (
+	"testing"
+
+	"mosn.io/mosn/pkg/protocol"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	testCases := []struct {
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-00", true, false},
+		// future version with extra fields
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", false, false},
+		{"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"", false, false},
+	}
+	for _, tc := range testCases {
+		sc := spanContext{}
+		ok := parseTraceParent(tc.value, &sc)
+		if ok != tc.ok {
+			t.Errorf("%s: expected %v, but got %v", tc.value, tc.ok, ok)
+			continue
+		}
+		if ok && (sc.traceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+			sc.spanID.String() != "00f067aa0ba902b7" || sc.isSampled() != tc.sampled) {
+			t.Errorf("%s: unexpected span context %+v", tc.value, sc)
+		}
+	}
+}
+
+func TestParseTraceState(t *testing.T) {
+	if s := parseTraceState("rojo=00f067aa0ba902b7, congo=t61rcWkgMzE,"); s != "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE" {
+		t.Errorf("unexpected trace state: %s", s)
+	}
+	if s := parseTraceState("rojo=00f067aa0ba902b7,invalid"); s != "" {
+		t.Errorf("malformed trace state should be dropped: %s", s)
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	headers := protocol.CommonHeader{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"tracestate":  "congo=t61rcWkgMzE",
+		"baggage":     "userId=alice,isProduction=false",
+	}
+	sc, ok := extract(headers)
+	if !ok || sc.traceState != "congo=t61rcWkgMzE" || sc.baggage != "userId=alice,isProduction=false" {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+
+	sc.spanID = newSpanID()
+	upstream := protocol.CommonHeader{"tracestate": "stale=1"}
+	inject(upstream, sc)
+	if upstream["traceparent"] != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+sc.spanID.String()+"-01" ||
+		upstream["tracestate"] != sc.traceState || upstream["baggage"] != sc.baggage {
+		t.Errorf("unexpected headers: %v", upstream)
+	}
+
+	// baggage is kept without traceparent
+	sc, ok = extract(protocol.CommonHeader{"baggage": "k=v"})
+	if ok || sc.baggage != "k=v" {
+		t.Errorf("unexpected span context %+v", sc)
+	}
+}
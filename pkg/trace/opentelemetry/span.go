@@ -0,0 +1,197 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package opentelemetry
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mosn.io/mosn/pkg/otlp"
+	"mosn.io/mosn/pkg/types"
+)
+
+// Fields of the OTLP span message, see opentelemetry/proto/trace/v1/trace.proto
+const (
+	fieldSpanTraceID      = 1
+	fieldSpanSpanID       = 2
+	fieldSpanTraceState   = 3
+	fieldSpanParentSpanID = 4
+	fieldSpanName         = 5
+	fieldSpanKind         = 6
+	fieldSpanStartTime    = 7
+	fieldSpanEndTime      = 8
+	fieldSpanAttributes   = 9
+	fieldSpanStatus       = 15
+	// Status
+	fieldStatusMessage = 2
+	fieldStatusCode    = 3
+
+	statusCodeError = 2
+)
+
+// Span is an opentelemetry span, it is exported when finished if sampled
+type Span struct {
+	tracer    *Tracer
+	context   spanContext
+	parentID  spanID
+	name      string
+	kind      uint64
+	startTime time.Time
+	endTime   time.Time
+	attrs     map[string]string
+	intAttrs  map[string]int64
+	// tags set by SetTag
+	extTags map[uint64]string
+	// the error status message, the status is unset if it is empty
+	errorMessage string
+}
+
+func (s *Span) TraceId() string {
+	return s.context.traceID.String()
+}
+
+func (s *Span) SpanId() string {
+	return s.context.spanID.String()
+}
+
+func (s *Span) ParentSpanId() string {
+	if !s.parentID.isValid() {
+		return ""
+	}
+	return s.parentID.String()
+}
+
+// Sampled returns whether the span will be exported
+func (s *Span) Sampled() bool {
+	return s.context.isSampled()
+}
+
+func (s *Span) SetOperation(operation string) {
+	s.name = operation
+}
+
+func (s *Span) SetTag(key uint64, value string) {
+	if s.extTags == nil {
+		s.extTags = make(map[uint64]string)
+	}
+	s.extTags[key] = value
+}
+
+func (s *Span) Tag(key uint64) string {
+	return s.extTags[key]
+}
+
+func (s *Span) SetRequestInfo(reqinfo types.RequestInfo) {
+	code := reqinfo.ResponseCode()
+	s.intAttrs["mosn.response_code"] = int64(code)
+	s.intAttrs["mosn.request_size"] = int64(reqinfo.BytesReceived())
+	s.intAttrs["mosn.response_size"] = int64(reqinfo.BytesSent())
+	if isHTTP(s.tracer.protocol) {
+		s.intAttrs["http.response.status_code"] = int64(code)
+	}
+
+	flags := types.ResponseFlagNames(reqinfo)
+	if len(flags) > 0 {
+		s.attrs["mosn.response_flags"] = strings.Join(flags, ",")
+	}
+
+	if host := reqinfo.UpstreamHost(); host != nil {
+		s.attrs["mosn.upstream_host"] = host.AddressString()
+		if cluster := host.ClusterInfo(); cluster != nil {
+			s.attrs["mosn.upstream_cluster"] = cluster.Name()
+		}
+	}
+	if addr := reqinfo.DownstreamRemoteAddress(); addr != nil {
+		s.attrs["client.address"] = addr.String()
+	}
+
+	if code >= 500 || len(flags) > 0 {
+		s.errorMessage = "response code " + strconv.Itoa(code)
+		if len(flags) > 0 {
+			s.errorMessage += ", response flags " + s.attrs["mosn.response_flags"]
+		}
+	}
+}
+
+func (s *Span) FinishSpan() {
+	s.endTime = time.Now()
+	if s.Sampled() {
+		s.tracer.processor.OnEnd(s)
+	}
+}
+
+// InjectContext writes the W3C headers into the upstream request, the span of mosn is the parent of the upstream span
+func (s *Span) InjectContext(requestHeaders types.HeaderMap) {
+	inject(requestHeaders, s.context)
+}
+
+func (s *Span) SpawnChild(operationName string, startTime time.Time) types.Span {
+	child := &Span{
+		tracer:    s.tracer,
+		context:   s.context,
+		parentID:  s.context.spanID,
+		name:      operationName,
+		kind:      spanKindInternal,
+		startTime: startTime,
+		attrs: map[string]string{
+			"mosn.protocol": string(s.tracer.protocol),
+		},
+		intAttrs: map[string]int64{},
+	}
+	child.context.spanID = newSpanID()
+	return child
+}
+
+// encode writes the OTLP span message
+func (s *Span) encode(e *otlp.Encoder) {
+	e.RawBytes(fieldSpanTraceID, s.context.traceID[:])
+	e.RawBytes(fieldSpanSpanID, s.context.spanID[:])
+	e.String(fieldSpanTraceState, s.context.traceState)
+	if s.parentID.isValid() {
+		e.RawBytes(fieldSpanParentSpanID, s.parentID[:])
+	}
+	e.String(fieldSpanName, s.name)
+	e.Uint64(fieldSpanKind, s.kind)
+	e.Fixed64(fieldSpanStartTime, uint64(s.startTime.UnixNano()))
+	e.Fixed64(fieldSpanEndTime, uint64(s.endTime.UnixNano()))
+
+	attrs := make(map[string]string, len(s.attrs)+len(s.extTags))
+	for k, v := range s.attrs {
+		attrs[k] = v
+	}
+	for k, v := range s.extTags {
+		attrs["mosn.tag."+strconv.FormatUint(k, 10)] = v
+	}
+	e.Attributes(fieldSpanAttributes, attrs)
+	keys := make([]string, 0, len(s.intAttrs))
+	for k := range s.intAttrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.IntAttribute(fieldSpanAttributes, k, s.intAttrs[k])
+	}
+
+	if s.errorMessage != "" {
+		e.Message(fieldSpanStatus, func(status *otlp.Encoder) {
+			status.String(fieldStatusMessage, s.errorMessage)
+			status.Uint64(fieldStatusCode, statusCodeError)
+		})
+	}
+}
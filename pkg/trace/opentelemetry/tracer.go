@@ -0,0 +1,181 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package opentelemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	mhttp2 "mosn.io/mosn/pkg/protocol/http2"
+	"mosn.io/mosn/pkg/protocol/rpc/sofarpc"
+	"mosn.io/mosn/pkg/protocol/sofarpc/models"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/types"
+)
+
+// span kinds, see opentelemetry/proto/trace/v1/trace.proto
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+// Tracer starts the spans of a protocol, the trace context is extracted from the W3C headers
+type Tracer struct {
+	protocol  types.Protocol
+	config    *Config
+	processor *processor
+}
+
+// newTracer returns an opentelemetry tracer of the protocol
+func newTracer(proto types.Protocol, config *Config, processor *processor) *Tracer {
+	return &Tracer{
+		protocol:  proto,
+		config:    config,
+		processor: processor,
+	}
+}
+
+func (t *Tracer) Start(ctx context.Context, request interface{}, startTime time.Time) types.Span {
+	headers, ok := request.(types.HeaderMap)
+	if !ok || headers == nil {
+		return nil
+	}
+	// ignore heartbeat
+	if cmd, ok := request.(sofarpc.SofaRpcCmd); ok && cmd.CommandCode() == sofarpc.HEARTBEAT {
+		return nil
+	}
+
+	span := &Span{
+		tracer:    t,
+		kind:      spanKindInternal,
+		startTime: startTime,
+		attrs: map[string]string{
+			"mosn.protocol": string(t.protocol),
+		},
+		intAttrs: map[string]int64{},
+	}
+
+	sc, hasParent := extract(headers)
+	if hasParent {
+		// parent based sampling, follows the remote parent
+		span.parentID = sc.spanID
+	} else {
+		sc.traceID = newTraceID()
+		sc.traceFlags = 0
+		// the request id makes the decision deterministic if it exists
+		sampled, ok := trace.SampleByRequestID(headers, *t.config.SampleRate)
+		if !ok {
+			sampled = t.sample(sc.traceID)
+		}
+		if sampled {
+			sc.traceFlags |= flagSampled
+		}
+	}
+	sc.spanID = newSpanID()
+	span.context = sc
+
+	switch mosnctx.Get(ctx, types.ContextKeyListenerType) {
+	case v2.INGRESS:
+		span.kind = spanKindServer
+	case v2.EGRESS:
+		span.kind = spanKindClient
+	}
+	t.setRequestAttributes(span, request)
+	return span
+}
+
+// sample is the trace id ratio based sampler, the decision is made by the lower 63 bits of the trace id,
+// so it is consistent with the other opentelemetry implementations
+func (t *Tracer) sample(id traceID) bool {
+	rate := *t.config.SampleRate
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	bound := uint64(rate * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:16])>>1 < bound
+}
+
+func (t *Tracer) setRequestAttributes(span *Span, request interface{}) {
+	switch req := request.(type) {
+	case http.RequestHeader:
+		if req.RequestHeader == nil {
+			return
+		}
+		method := string(req.Method())
+		path := string(req.RequestURI())
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i]
+		}
+		span.name = method + " " + path
+		span.attrs["http.request.method"] = method
+		span.attrs["url.path"] = path
+	case *mhttp2.ReqHeader:
+		span.name = req.Req.Method + " " + req.Req.URL.Path
+		span.attrs["http.request.method"] = req.Req.Method
+		span.attrs["url.path"] = req.Req.URL.Path
+	case sofarpc.SofaRpcCmd:
+		service, _ := req.Get(models.SERVICE_KEY)
+		method, _ := req.Get(models.TARGET_METHOD)
+		span.name = service + "/" + method
+		span.attrs["rpc.system"] = "sofarpc"
+		span.attrs["rpc.service"] = service
+		span.attrs["rpc.method"] = method
+	default:
+		span.name = string(t.protocol)
+	}
+}
+
+func isHTTP(proto types.Protocol) bool {
+	return proto == protocol.HTTP1 || proto == protocol.HTTP2
+}
+
+var (
+	randMutex  sync.Mutex
+	randSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func newTraceID() traceID {
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	var id traceID
+	for !id.isValid() {
+		randSource.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() spanID {
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	var id spanID
+	for !id.isValid() {
+		randSource.Read(id[:])
+	}
+	return id
+}
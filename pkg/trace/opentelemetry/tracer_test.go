@@ -0,0 +1,325 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package opentelemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	mosnhttp "mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/types"
+)
+
+// message is a decoded protobuf message, for test only
+type message map[int][]interface{}
+
+func decode(t *testing.T, data []byte) message {
+	m := message{}
+	for len(data) > 0 {
+		key, n := proto.DecodeVarint(data)
+		if n == 0 {
+			t.Fatalf("decode key error")
+		}
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			v, n := proto.DecodeVarint(data)
+			m[field] = append(m[field], v)
+			data = data[n:]
+		case 1:
+			m[field] = append(m[field], binary.LittleEndian.Uint64(data))
+			data = data[8:]
+		case 2:
+			l, n := proto.DecodeVarint(data)
+			data = data[n:]
+			m[field] = append(m[field], data[:l])
+			data = data[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return m
+}
+
+func (m message) sub(t *testing.T, fields ...int) []message {
+	msgs := []message{m}
+	for _, f := range fields {
+		var next []message
+		for _, msg := range msgs {
+			for _, v := range msg[f] {
+				next = append(next, decode(t, v.([]byte)))
+			}
+		}
+		msgs = next
+	}
+	return msgs
+}
+
+func (m message) bytes(field int) []byte {
+	if len(m[field]) == 0 {
+		return nil
+	}
+	return m[field][0].([]byte)
+}
+
+func (m message) varint(field int) uint64 {
+	if len(m[field]) == 0 {
+		return 0
+	}
+	return m[field][0].(uint64)
+}
+
+// attrs decodes the string and int attributes
+func (m message) attrs(t *testing.T, field int) map[string]interface{} {
+	attrs := map[string]interface{}{}
+	for _, kv := range m.sub(t, field) {
+		value := kv.sub(t, 2)[0]
+		if _, ok := value[3]; ok {
+			attrs[string(kv.bytes(1))] = int64(value.varint(3))
+		} else {
+			attrs[string(kv.bytes(1))] = string(value.bytes(1))
+		}
+	}
+	return attrs
+}
+
+// mockExporter records the export requests
+type mockExporter struct {
+	ch  chan []byte
+	err error
+}
+
+func (e *mockExporter) Export(body []byte) error {
+	select {
+	case e.ch <- body:
+	default:
+	}
+	return e.err
+}
+
+func (e *mockExporter) Close() {}
+
+func newTestTracer(rate float64, exp exporter) *Tracer {
+	cfg, _ := parseConfig(map[string]interface{}{
+		"sample_rate":    rate,
+		"flush_interval": "50ms",
+	})
+	return newTracer(protocol.HTTP1, cfg, newProcessor(cfg, exp))
+}
+
+func newHTTPHeader(headers map[string]string) mosnhttp.RequestHeader {
+	h := mosnhttp.RequestHeader{RequestHeader: &fasthttp.RequestHeader{}}
+	h.SetMethod("POST")
+	h.SetRequestURI("/api/echo?x=1")
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+type mockHost struct {
+	types.Host
+}
+
+func (h *mockHost) AddressString() string {
+	return "127.0.0.1:8080"
+}
+
+func (h *mockHost) ClusterInfo() types.ClusterInfo {
+	return nil
+}
+
+func TestOpenTelemetryTracer(t *testing.T) {
+	exp := &mockExporter{ch: make(chan []byte, 1)}
+	tracer := newTestTracer(0, exp)
+	defer tracer.processor.Close()
+
+	ctx := mosnctx.WithValue(context.Background(), types.ContextKeyListenerType, v2.INGRESS)
+	header := newHTTPHeader(map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"tracestate":  "congo=t61rcWkgMzE",
+	})
+	// the remote parent is sampled, so the sample rate 0 is ignored
+	span := tracer.Start(ctx, header, time.Now())
+	if span.TraceId() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentSpanId() != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected span %s %s", span.TraceId(), span.ParentSpanId())
+	}
+
+	upstream := protocol.CommonHeader{}
+	span.InjectContext(upstream)
+	if upstream["traceparent"] != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+span.SpanId()+"-01" ||
+		upstream["tracestate"] != "congo=t61rcWkgMzE" {
+		t.Errorf("unexpected upstream headers: %v", upstream)
+	}
+
+	info := network.NewRequestInfo()
+	info.SetResponseCode(502)
+	info.SetBytesReceived(10)
+	info.SetBytesSent(20)
+	info.SetResponseFlag(types.UpstreamConnectionFailure)
+	info.OnUpstreamHostSelected(&mockHost{})
+	info.SetDownstreamRemoteAddress(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 12345})
+	span.SetRequestInfo(info)
+	span.FinishSpan()
+
+	select {
+	case body := <-exp.ch:
+		req := decode(t, body)
+		resource := req.sub(t, 1, 1)[0].attrs(t, 1)
+		if resource["service.name"] != "mosn" {
+			t.Errorf("unexpected resource: %v", resource)
+		}
+		spans := req.sub(t, 1, 2, 2)
+		if len(spans) != 1 {
+			t.Fatalf("expected 1 span, but got %d", len(spans))
+		}
+		s := spans[0]
+		if string(s.bytes(5)) != "POST /api/echo" || s.varint(6) != spanKindServer || string(s.bytes(3)) != "congo=t61rcWkgMzE" {
+			t.Errorf("unexpected span name %s kind %d", s.bytes(5), s.varint(6))
+		}
+		if len(s.bytes(1)) != 16 || len(s.bytes(2)) != 8 || len(s.bytes(4)) != 8 {
+			t.Errorf("unexpected span ids")
+		}
+		if s.varint(7) == 0 || s.varint(8) < s.varint(7) {
+			t.Errorf("unexpected span time")
+		}
+		attrs := s.attrs(t, 9)
+		expected := map[string]interface{}{
+			"http.request.method":       "POST",
+			"url.path":                  "/api/echo",
+			"http.response.status_code": int64(502),
+			"mosn.request_size":         int64(10),
+			"mosn.response_size":        int64(20),
+			"mosn.response_flags":       "UF",
+			"mosn.upstream_host":        "127.0.0.1:8080",
+			"client.address":            "10.0.0.1:12345",
+		}
+		for k, v := range expected {
+			if attrs[k] != v {
+				t.Errorf("attribute %s expected %v, but got %v", k, v, attrs[k])
+			}
+		}
+		if status := s.sub(t, 15); len(status) != 1 || status[0].varint(3) != statusCodeError {
+			t.Errorf("unexpected status")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing exported")
+	}
+
+	// root span is not sampled by the rate 0
+	span = tracer.Start(ctx, newHTTPHeader(nil), time.Now())
+	if span.(*Span).Sampled() || span.ParentSpanId() != "" {
+		t.Errorf("unexpected root span")
+	}
+	upstream = protocol.CommonHeader{}
+	span.InjectContext(upstream)
+	if upstream["traceparent"] != "00-"+span.TraceId()+"-"+span.SpanId()+"-00" {
+		t.Errorf("unexpected upstream headers: %v", upstream)
+	}
+	span.FinishSpan()
+	select {
+	case <-exp.ch:
+		t.Error("not sampled span is exported")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestRatioSampler(t *testing.T) {
+	tracer := newTestTracer(0.5, &mockExporter{})
+	defer tracer.processor.Close()
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if tracer.sample(newTraceID()) {
+			sampled++
+		}
+	}
+	if sampled < 400 || sampled > 600 {
+		t.Errorf("unexpected sampled count %d", sampled)
+	}
+	var id traceID
+	id[8] = 0x7f
+	if !tracer.sample(id) {
+		t.Error("trace id below the bound should be sampled")
+	}
+	id[8] = 0x81
+	if tracer.sample(id) {
+		t.Error("trace id above the bound should not be sampled")
+	}
+}
+
+func TestProcessor(t *testing.T) {
+	metrics.ResetAll()
+	exp := &mockExporter{ch: make(chan []byte, 10), err: errors.New("unavailable")}
+	cfg, _ := parseConfig(map[string]interface{}{
+		"batch_size": 2,
+		"queue_size": 3,
+	})
+	p := &processor{
+		exporter:  exp,
+		batchSize: cfg.BatchSize,
+		queue:     make(chan *Span, cfg.QueueSize),
+		stats:     metrics.NewTraceStats(DriverName),
+	}
+	for i := 0; i < 5; i++ {
+		p.OnEnd(&Span{})
+	}
+	if dropped := p.stats.Counter(metrics.TraceSpanDropped).Count(); dropped != 2 {
+		t.Errorf("expected 2 dropped spans, but got %d", dropped)
+	}
+
+	// start the processor, close flushes all the queued spans
+	p.flushInterval = time.Hour
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run()
+	p.Close()
+	if failed := p.stats.Counter(metrics.TraceSpanReportFailed).Count(); failed != 3 {
+		t.Errorf("expected 3 failed spans, but got %d", failed)
+	}
+	if len(exp.ch) != 2 {
+		t.Errorf("expected 2 export requests, but got %d", len(exp.ch))
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig(map[string]interface{}{"endpoint": "127.0.0.1:4317"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServiceName != defaultServiceName || *cfg.SampleRate != 1 || cfg.BatchSize != defaultBatchSize ||
+		cfg.QueueSize != defaultQueueSize || cfg.FlushInterval.Duration != defaultFlushInterval {
+		t.Errorf("unexpected default config: %+v", cfg)
+	}
+	if _, err := parseConfig(map[string]interface{}{"sample_rate": -1}); err == nil {
+		t.Error("invalid sample rate expected an error")
+	}
+	if err := defaultDriver.Init(map[string]interface{}{}); err == nil {
+		t.Error("missing endpoint expected an error")
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package propagation
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// FormatJaeger is the jaeger propagation format, see https://www.jaegertracing.io/docs/latest/client-libraries/#propagation-format
+const FormatJaeger = "uber-trace-id"
+
+const (
+	headerUberTraceID = "uber-trace-id"
+
+	jaegerFlagSampled = 0x01
+	jaegerFlagDebug   = 0x02
+)
+
+func init() {
+	Register(&jaegerPropagator{})
+}
+
+// jaegerPropagator propagates uber-trace-id: {trace-id}:{span-id}:{parent-span-id}:{flags}
+type jaegerPropagator struct{}
+
+func (p *jaegerPropagator) Name() string {
+	return FormatJaeger
+}
+
+func (p *jaegerPropagator) Keys() []string {
+	return []string{headerUberTraceID}
+}
+
+func (p *jaegerPropagator) Extract(headers types.HeaderMap) (SpanContext, bool) {
+	v := getHeader(headers, headerUberTraceID)
+	if v == "" {
+		return SpanContext{}, false
+	}
+	// the value may be url encoded
+	if strings.Contains(v, "%") {
+		unescaped, err := url.QueryUnescape(v)
+		if err != nil {
+			return SpanContext{}, false
+		}
+		v = unescaped
+	}
+	parts := strings.Split(strings.ToLower(v), ":")
+	if len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	traceID, spanID, parentID := parts[0], parts[1], parts[2]
+	if !isJaegerID(traceID, 32) || !isJaegerID(spanID, 16) || !isJaegerID(parentID, 16) {
+		return SpanContext{}, false
+	}
+	// the trace id and span id must not be zero
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return SpanContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flags&jaegerFlagSampled != 0,
+		Debug:   flags&jaegerFlagDebug != 0,
+	}
+	if strings.Trim(parentID, "0") != "" {
+		sc.ParentSpanID = parentID
+	}
+	return sc, true
+}
+
+func (p *jaegerPropagator) Inject(headers types.HeaderMap, sc SpanContext) {
+	parentID := sc.ParentSpanID
+	if parentID == "" {
+		parentID = "0"
+	}
+	var flags uint64
+	if sc.Sampled {
+		flags |= jaegerFlagSampled
+	}
+	if sc.Debug {
+		flags |= jaegerFlagDebug
+	}
+	setHeader(headers, headerUberTraceID, fmt.Sprintf("%s:%s:%s:%x", sc.TraceID, sc.SpanID, parentID, flags))
+}
+
+// isJaegerID checks the id is a hex string, the leading zeros may be omitted
+func isJaegerID(s string, maxLen int) bool {
+	if len(s) == 0 || len(s) > maxLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package propagation reads and writes the trace context in the request headers with the formats
+// of the popular tracing systems, so a trace continues through mosn even if the protocols of the
+// downstream and upstream are different.
+package propagation
+
+import (
+	"fmt"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// SpanContext is the trace context carried by the headers
+type SpanContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Sampled      bool
+	Debug        bool
+
+	// SkyWalking only, the span id is the index in the segment
+	SegmentID             string
+	ParentService         string
+	ParentServiceInstance string
+	ParentEndpoint        string
+	TargetAddress         string
+
+	// the raw baggage header of the format, propagated as it is
+	Baggage string
+}
+
+// Propagator extracts and injects the trace context in a format
+type Propagator interface {
+	// Name is the format name in the config
+	Name() string
+	// Keys returns the header keys of the format
+	Keys() []string
+	// Extract reads the trace context, returns false if the headers are missing or invalid
+	Extract(headers types.HeaderMap) (SpanContext, bool)
+	// Inject writes the trace context
+	Inject(headers types.HeaderMap, sc SpanContext)
+}
+
+var (
+	propagators = make(map[string]Propagator)
+
+	mutex   sync.RWMutex
+	enabled []Propagator
+	keys    []string
+)
+
+// Register registers a propagator by its name
+func Register(p Propagator) {
+	propagators[p.Name()] = p
+}
+
+// Get returns the propagator of the format
+func Get(name string) Propagator {
+	return propagators[name]
+}
+
+// Validate checks the formats are registered
+func Validate(names []string) error {
+	for _, name := range names {
+		if _, ok := propagators[name]; !ok {
+			return fmt.Errorf("unsupported trace propagation format: %s", name)
+		}
+	}
+	return nil
+}
+
+// SetFormats sets the formats propagated by the proxy, an empty formats disables the propagation
+func SetFormats(names []string) error {
+	if err := Validate(names); err != nil {
+		return err
+	}
+	ps := make([]Propagator, 0, len(names))
+	var ks []string
+	for _, name := range names {
+		p := propagators[name]
+		ps = append(ps, p)
+		ks = append(ks, p.Keys()...)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	enabled = ps
+	keys = ks
+	return nil
+}
+
+func enabledPropagators() []Propagator {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return enabled
+}
+
+// Keys returns the header keys of the enabled formats
+func Keys() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return keys
+}
+
+// Propagate copies the trace context of the enabled formats from the downstream request to the upstream request.
+// the upstream headers are written in the canonical keys of the format,
+// so the context is kept even if the header keys are changed by the protocol conversion
+func Propagate(downstream, upstream types.HeaderMap) {
+	if downstream == nil || upstream == nil {
+		return
+	}
+	for _, p := range enabledPropagators() {
+		if sc, ok := p.Extract(downstream); ok {
+			p.Inject(upstream, sc)
+		}
+	}
+}
+
+// Extract returns the trace context of the first enabled format found in the headers
+func Extract(headers types.HeaderMap) (SpanContext, bool) {
+	for _, p := range enabledPropagators() {
+		if sc, ok := p.Extract(headers); ok {
+			return sc, true
+		}
+	}
+	return SpanContext{}, false
+}
+
+// getHeader gets the header case-insensitively, the http headers are case-insensitive but the rpc headers are not.
+func getHeader(headers types.HeaderMap, key string) string {
+	for _, k := range []string{key, textproto.CanonicalMIMEHeaderKey(key)} {
+		if v, ok := headers.Get(k); ok && v != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// setHeader sets the header in the lower case key, the header in the other case is removed
+func setHeader(headers types.HeaderMap, key, value string) {
+	if canonical := textproto.CanonicalMIMEHeaderKey(key); canonical != key {
+		headers.Del(canonical)
+	}
+	headers.Set(key, value)
+}
@@ -0,0 +1,129 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package propagation
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+)
+
+func TestJaeger(t *testing.T) {
+	p := Get(FormatJaeger)
+	testCases := []struct {
+		value   string
+		ok      bool
+		traceID string
+		parent  string
+		sampled bool
+		debug   bool
+	}{
+		{"463ac35c9f6413ad48485a3953bb6124:a2fb4a1d1a96d312:0:1", true, "463ac35c9f6413ad48485a3953bb6124", "", true, false},
+		{"3e8:7b:1c8:3", true, "3e8", "1c8", true, true},
+		{"3e8%3A7b%3A0%3A0", true, "3e8", "", false, false},
+		{"0:7b:0:1", false, "", "", false, false},
+		{"3e8:7b:0", false, "", "", false, false},
+		{"xyz:7b:0:1", false, "", "", false, false},
+		{"3e8:7b:0:zz", false, "", "", false, false},
+	}
+	for _, tc := range testCases {
+		sc, ok := p.Extract(protocol.CommonHeader{"uber-trace-id": tc.value})
+		if ok != tc.ok {
+			t.Errorf("%s: expected %v, but got %v", tc.value, tc.ok, ok)
+			continue
+		}
+		if ok && (sc.TraceID != tc.traceID || sc.ParentSpanID != tc.parent || sc.Sampled != tc.sampled || sc.Debug != tc.debug) {
+			t.Errorf("%s: unexpected span context %+v", tc.value, sc)
+		}
+	}
+
+	headers := protocol.CommonHeader{"Uber-Trace-Id": "stale"}
+	p.Inject(headers, SpanContext{TraceID: "3e8", SpanID: "7b", Sampled: true, Debug: true})
+	if len(headers) != 1 || headers["uber-trace-id"] != "3e8:7b:0:3" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+}
+
+func TestSkyWalking(t *testing.T) {
+	p := Get(FormatSkyWalking)
+	value := "1-NDkzYjY1YzUtNmM2Ny00NjAx-ZjQ1Y2U3NjQ=-3-c2VydmljZQ==-aW5zdGFuY2U=-L2FwaS92MQ==-MTI3LjAuMC4xOjgwODA="
+	headers := protocol.CommonHeader{"sw8": value, "sw8-correlation": "dGVzdA==:dHJ1ZQ=="}
+	sc, ok := p.Extract(headers)
+	if !ok {
+		t.Fatal("extract sw8 failed")
+	}
+	if !sc.Sampled || sc.TraceID != "493b65c5-6c67-4601" || sc.SegmentID != "f45ce764" || sc.SpanID != "3" ||
+		sc.ParentService != "service" || sc.ParentServiceInstance != "instance" || sc.ParentEndpoint != "/api/v1" ||
+		sc.TargetAddress != "127.0.0.1:8080" || sc.Baggage != "dGVzdA==:dHJ1ZQ==" {
+		t.Errorf("unexpected span context %+v", sc)
+	}
+
+	upstream := protocol.CommonHeader{}
+	p.Inject(upstream, sc)
+	if upstream["sw8"] != value || upstream["sw8-correlation"] != "dGVzdA==:dHJ1ZQ==" {
+		t.Errorf("unexpected headers: %v", upstream)
+	}
+
+	for _, invalid := range []string{
+		"2-NDkz-ZjQ1-3-c2Vy-aW5z-L2Fw-MTI3",
+		"1-NDkz-ZjQ1-x-c2Vy-aW5z-L2Fw-MTI3",
+		"1-NDkz-ZjQ1-3-c2Vy-aW5z-L2Fw",
+		"1-!!!!-ZjQ1-3-c2Vy-aW5z-L2Fw-MTI3",
+	} {
+		if _, ok := p.Extract(protocol.CommonHeader{"sw8": invalid}); ok {
+			t.Errorf("%s should be invalid", invalid)
+		}
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	if err := SetFormats([]string{"unknown"}); err == nil {
+		t.Error("unknown format expected an error")
+	}
+	if err := SetFormats([]string{FormatJaeger, FormatSkyWalking}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetFormats(nil)
+	if keys := Keys(); len(keys) != 3 {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	// the http header keys are normalized by fasthttp
+	downstream := http.RequestHeader{RequestHeader: &fasthttp.RequestHeader{}}
+	downstream.Set("uber-trace-id", "3e8:7b:0:1")
+	downstream.Set("x-custom", "1")
+	// the header keys are changed by the protocol conversion
+	upstream := protocol.CommonHeader{"Uber-Trace-Id": "3e8:7b:0:1"}
+	Propagate(downstream, upstream)
+	if len(upstream) != 1 || upstream["uber-trace-id"] != "3e8:7b:0:1" {
+		t.Errorf("unexpected upstream headers: %v", upstream)
+	}
+
+	sc, ok := Extract(upstream)
+	if !ok || sc.TraceID != "3e8" || sc.SpanID != "7b" {
+		t.Errorf("unexpected span context %+v", sc)
+	}
+
+	SetFormats(nil)
+	upstream = protocol.CommonHeader{}
+	Propagate(downstream, upstream)
+	if len(upstream) != 0 {
+		t.Errorf("propagation should be disabled: %v", upstream)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package propagation
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// FormatSkyWalking is the SkyWalking cross process propagation format v3,
+// see https://skywalking.apache.org/docs/main/latest/en/api/x-process-propagation-headers-v3/
+const FormatSkyWalking = "sw8"
+
+const (
+	headerSW8            = "sw8"
+	headerSW8Correlation = "sw8-correlation"
+)
+
+func init() {
+	Register(&skywalkingPropagator{})
+}
+
+// skywalkingPropagator propagates
+// sw8: {sample}-{trace id}-{parent segment id}-{parent span id}-{parent service}-{parent service instance}-{parent endpoint}-{target address},
+// the fields except sample and parent span id are base64 encoded
+type skywalkingPropagator struct{}
+
+func (p *skywalkingPropagator) Name() string {
+	return FormatSkyWalking
+}
+
+func (p *skywalkingPropagator) Keys() []string {
+	return []string{headerSW8, headerSW8Correlation}
+}
+
+func (p *skywalkingPropagator) Extract(headers types.HeaderMap) (SpanContext, bool) {
+	v := getHeader(headers, headerSW8)
+	if v == "" {
+		return SpanContext{}, false
+	}
+	parts := strings.Split(v, "-")
+	if len(parts) != 8 {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{}
+	switch parts[0] {
+	case "1":
+		sc.Sampled = true
+	case "0":
+	default:
+		return SpanContext{}, false
+	}
+	if _, err := strconv.ParseInt(parts[3], 10, 32); err != nil {
+		return SpanContext{}, false
+	}
+	sc.SpanID = parts[3]
+
+	fields := []*string{nil, &sc.TraceID, &sc.SegmentID, nil, &sc.ParentService, &sc.ParentServiceInstance, &sc.ParentEndpoint, &sc.TargetAddress}
+	for i, field := range fields {
+		if field == nil {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(parts[i])
+		if err != nil || len(decoded) == 0 {
+			return SpanContext{}, false
+		}
+		*field = string(decoded)
+	}
+	sc.Baggage = getHeader(headers, headerSW8Correlation)
+	return sc, true
+}
+
+func (p *skywalkingPropagator) Inject(headers types.HeaderMap, sc SpanContext) {
+	sample := "0"
+	if sc.Sampled {
+		sample = "1"
+	}
+	spanID := sc.SpanID
+	if spanID == "" {
+		spanID = "0"
+	}
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	v := strings.Join([]string{
+		sample,
+		encode(sc.TraceID),
+		encode(sc.SegmentID),
+		spanID,
+		encode(sc.ParentService),
+		encode(sc.ParentServiceInstance),
+		encode(sc.ParentEndpoint),
+		encode(sc.TargetAddress),
+	}, "-")
+	setHeader(headers, headerSW8, v)
+	if sc.Baggage != "" {
+		setHeader(headers, headerSW8Correlation, sc.Baggage)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trace
+
+import (
+	"hash/fnv"
+
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+// SampleByRequestID makes the sampling decision of a new trace by the request id of the request,
+// so the decision of a request is the same in all the proxies it passes through.
+// ok is false if the request has no request id.
+func SampleByRequestID(headers types.HeaderMap, rate float64) (sampled bool, ok bool) {
+	if headers == nil {
+		return false, false
+	}
+	id, found := headers.Get(protocol.HeaderRequestID)
+	if !found || id == "" {
+		return false, false
+	}
+	if rate >= 1 {
+		return true, true
+	}
+	if rate <= 0 {
+		return false, true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return h.Sum64()%10000 < uint64(rate*10000), true
+}
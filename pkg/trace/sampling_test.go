@@ -0,0 +1,60 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trace
+
+import (
+	"strconv"
+	"testing"
+
+	"mosn.io/mosn/pkg/protocol"
+)
+
+func TestSampleByRequestID(t *testing.T) {
+	if _, ok := SampleByRequestID(protocol.CommonHeader{}, 0.5); ok {
+		t.Error("request without request id should not be sampled by request id")
+	}
+	headers := protocol.CommonHeader{
+		protocol.HeaderRequestID: "3b6e5a5c-6f5e-4a8e-9d1a-7c1f3f5d2e4b",
+	}
+	if sampled, ok := SampleByRequestID(headers, 1); !ok || !sampled {
+		t.Error("request should always be sampled if the rate is 1")
+	}
+	if sampled, ok := SampleByRequestID(headers, 0); !ok || sampled {
+		t.Error("request should never be sampled if the rate is 0")
+	}
+	// the decision is deterministic
+	first, _ := SampleByRequestID(headers, 0.5)
+	for i := 0; i < 10; i++ {
+		if sampled, _ := SampleByRequestID(headers, 0.5); sampled != first {
+			t.Fatal("the sampling decision of the same request id should be the same")
+		}
+	}
+	// the ratio is near the rate
+	count := 0
+	for i := 0; i < 10000; i++ {
+		h := protocol.CommonHeader{
+			protocol.HeaderRequestID: "request-" + strconv.Itoa(i),
+		}
+		if sampled, _ := SampleByRequestID(h, 0.2); sampled {
+			count++
+		}
+	}
+	if count < 1800 || count > 2200 {
+		t.Errorf("expected about 2000 requests sampled, but got %d", count)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zipkin
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// B3 propagation headers, see https://github.com/openzipkin/b3-propagation
+const (
+	b3Single       = "b3"
+	b3TraceID      = "X-B3-TraceId"
+	b3SpanID       = "X-B3-SpanId"
+	b3ParentSpanID = "X-B3-ParentSpanId"
+	b3Sampled      = "X-B3-Sampled"
+	b3Flags        = "X-B3-Flags"
+)
+
+// spanContext is the trace context propagated by the b3 headers
+type spanContext struct {
+	traceID  string
+	spanID   string
+	parentID string
+	// the sampling decision, nil means deferred
+	sampled *bool
+	debug   bool
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// getHeader gets the header in canonical or lower case,
+// the http headers are case-insensitive but the rpc headers are not.
+func getHeader(headers types.HeaderMap, key string) (string, bool) {
+	if v, ok := headers.Get(key); ok {
+		return v, true
+	}
+	return headers.Get(strings.ToLower(key))
+}
+
+// extractB3 reads the trace context from the headers, the single b3 header takes precedence.
+// returns false if no valid b3 header is found
+func extractB3(headers types.HeaderMap) (spanContext, bool) {
+	if v, ok := getHeader(headers, b3Single); ok && v != "" {
+		return parseB3Single(v)
+	}
+
+	sc := spanContext{}
+	found := false
+	if v, ok := getHeader(headers, b3Sampled); ok {
+		switch strings.ToLower(v) {
+		case "1", "true":
+			sc.sampled = boolPtr(true)
+			found = true
+		case "0", "false":
+			sc.sampled = boolPtr(false)
+			found = true
+		}
+	}
+	if v, ok := getHeader(headers, b3Flags); ok && v == "1" {
+		sc.debug = true
+		sc.sampled = boolPtr(true)
+		found = true
+	}
+
+	traceID, _ := getHeader(headers, b3TraceID)
+	spanID, _ := getHeader(headers, b3SpanID)
+	if isTraceID(traceID) && isSpanID(spanID) {
+		sc.traceID = strings.ToLower(traceID)
+		sc.spanID = strings.ToLower(spanID)
+		if parentID, _ := getHeader(headers, b3ParentSpanID); isSpanID(parentID) {
+			sc.parentID = strings.ToLower(parentID)
+		}
+		found = true
+	}
+	return sc, found
+}
+
+// parseB3Single parses b3: {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId},
+// or b3: {SamplingState} which only contains the sampling decision
+func parseB3Single(v string) (spanContext, bool) {
+	sc := spanContext{}
+	parts := strings.Split(strings.ToLower(v), "-")
+	if len(parts) == 1 {
+		return sc, parseSamplingState(&sc, parts[0])
+	}
+	if len(parts) > 4 || !isTraceID(parts[0]) || !isSpanID(parts[1]) {
+		return sc, false
+	}
+	sc.traceID, sc.spanID = parts[0], parts[1]
+	if len(parts) > 2 && !parseSamplingState(&sc, parts[2]) {
+		return sc, false
+	}
+	if len(parts) > 3 {
+		if !isSpanID(parts[3]) {
+			return sc, false
+		}
+		sc.parentID = parts[3]
+	}
+	return sc, true
+}
+
+func parseSamplingState(sc *spanContext, s string) bool {
+	switch s {
+	case "1":
+		sc.sampled = boolPtr(true)
+	case "0":
+		sc.sampled = boolPtr(false)
+	case "d":
+		sc.debug = true
+		sc.sampled = boolPtr(true)
+	default:
+		return false
+	}
+	return true
+}
+
+// injectB3 writes the trace context into the headers,
+// the headers in the other format are removed, so the upstream will not see conflicting contexts
+func injectB3(headers types.HeaderMap, sc spanContext, single bool) {
+	if single {
+		for _, key := range []string{b3TraceID, b3SpanID, b3ParentSpanID, b3Sampled, b3Flags} {
+			headers.Del(key)
+		}
+		v := sc.traceID + "-" + sc.spanID
+		switch {
+		case sc.debug:
+			v += "-d"
+		case sc.sampled != nil && *sc.sampled:
+			v += "-1"
+		case sc.sampled != nil:
+			v += "-0"
+		}
+		if sc.parentID != "" && sc.sampled != nil {
+			v += "-" + sc.parentID
+		}
+		headers.Set(b3Single, v)
+		return
+	}
+
+	headers.Del(b3Single)
+	headers.Set(b3TraceID, sc.traceID)
+	headers.Set(b3SpanID, sc.spanID)
+	if sc.parentID != "" {
+		headers.Set(b3ParentSpanID, sc.parentID)
+	} else {
+		headers.Del(b3ParentSpanID)
+	}
+	headers.Del(b3Sampled)
+	headers.Del(b3Flags)
+	if sc.debug {
+		headers.Set(b3Flags, "1")
+	} else if sc.sampled != nil {
+		if *sc.sampled {
+			headers.Set(b3Sampled, "1")
+		} else {
+			headers.Set(b3Sampled, "0")
+		}
+	}
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func isTraceID(s string) bool {
+	return (len(s) == 16 || len(s) == 32) && isHex(s)
+}
+
+func isSpanID(s string) bool {
+	return len(s) == 16 && isHex(s)
+}
+
+var (
+	randMutex  sync.Mutex
+	randSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func randomUint64() uint64 {
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	return randSource.Uint64()
+}
+
+// newTraceID returns a 128 bits trace id
+func newTraceID() string {
+	return fmt.Sprintf("%016x%016x", randomUint64(), randomUint64())
+}
+
+// newSpanID returns a 64 bits span id
+func newSpanID() string {
+	return fmt.Sprintf("%016x", randomUint64())
+}
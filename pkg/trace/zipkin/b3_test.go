@@ -0,0 +1,130 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zipkin
+
+import (
+	"testing"
+
+	"mosn.io/mosn/pkg/protocol"
+)
+
+func TestExtractB3Multi(t *testing.T) {
+	headers := protocol.CommonHeader{
+		"x-b3-traceid":      "463ac35c9f6413ad48485a3953bb6124",
+		"x-b3-spanid":       "a2fb4a1d1a96d312",
+		"x-b3-parentspanid": "0020000000000001",
+		"x-b3-sampled":      "1",
+	}
+	sc, ok := extractB3(headers)
+	if !ok || sc.traceID != "463ac35c9f6413ad48485a3953bb6124" || sc.spanID != "a2fb4a1d1a96d312" ||
+		sc.parentID != "0020000000000001" || sc.sampled == nil || !*sc.sampled {
+		t.Errorf("unexpected span context: %+v", sc)
+	}
+
+	// debug implies sampled
+	sc, ok = extractB3(protocol.CommonHeader{"X-B3-Flags": "1"})
+	if !ok || !sc.debug || !*sc.sampled || sc.traceID != "" {
+		t.Errorf("unexpected span context: %+v", sc)
+	}
+
+	// invalid ids
+	if _, ok := extractB3(protocol.CommonHeader{"X-B3-TraceId": "xyz", "X-B3-SpanId": "a2fb4a1d1a96d312"}); ok {
+		t.Error("invalid trace id should be ignored")
+	}
+}
+
+func TestExtractB3Single(t *testing.T) {
+	testCases := []struct {
+		value    string
+		ok       bool
+		traceID  string
+		parentID string
+		sampled  string
+		debug    bool
+	}{
+		{"80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90", true, "80f198ee56343ba864fe8b2a57d3eff7", "05e3ac9a4f6e3b90", "1", false},
+		{"80f198ee56343ba8-e457b5a2e4d86bd1", true, "80f198ee56343ba8", "", "", false},
+		{"80f198ee56343ba8-e457b5a2e4d86bd1-d", true, "80f198ee56343ba8", "", "1", true},
+		{"0", true, "", "", "0", false},
+		{"80f198ee56343ba8-e457b5a2e4d86bd1-x", false, "", "", "", false},
+		{"80f198ee56343ba8", false, "", "", "", false},
+	}
+	for _, tc := range testCases {
+		sc, ok := extractB3(protocol.CommonHeader{"b3": tc.value})
+		if ok != tc.ok {
+			t.Errorf("%s: expected %v, but got %v", tc.value, tc.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		sampled := ""
+		if sc.sampled != nil {
+			sampled = "0"
+			if *sc.sampled {
+				sampled = "1"
+			}
+		}
+		if sc.traceID != tc.traceID || sc.parentID != tc.parentID || sampled != tc.sampled || sc.debug != tc.debug {
+			t.Errorf("%s: unexpected span context: %+v", tc.value, sc)
+		}
+	}
+
+	// the single header takes precedence
+	sc, _ := extractB3(protocol.CommonHeader{
+		"b3":           "80f198ee56343ba8-e457b5a2e4d86bd1-0",
+		"X-B3-TraceId": "463ac35c9f6413ad",
+		"X-B3-SpanId":  "a2fb4a1d1a96d312",
+	})
+	if sc.traceID != "80f198ee56343ba8" {
+		t.Errorf("unexpected span context: %+v", sc)
+	}
+}
+
+func TestInjectB3(t *testing.T) {
+	sc := spanContext{
+		traceID:  "80f198ee56343ba864fe8b2a57d3eff7",
+		spanID:   "e457b5a2e4d86bd1",
+		parentID: "05e3ac9a4f6e3b90",
+		sampled:  boolPtr(true),
+	}
+
+	headers := protocol.CommonHeader{"b3": "0"}
+	injectB3(headers, sc, false)
+	if _, ok := headers["b3"]; ok {
+		t.Error("the single header should be removed")
+	}
+	if headers[b3TraceID] != sc.traceID || headers[b3SpanID] != sc.spanID ||
+		headers[b3ParentSpanID] != sc.parentID || headers[b3Sampled] != "1" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+	// round trip
+	if extracted, ok := extractB3(headers); !ok || extracted.traceID != sc.traceID || extracted.spanID != sc.spanID {
+		t.Errorf("unexpected span context: %+v", extracted)
+	}
+
+	headers = protocol.CommonHeader{b3TraceID: "463ac35c9f6413ad"}
+	injectB3(headers, sc, true)
+	if len(headers) != 1 || headers["b3"] != "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+	sc.debug = true
+	injectB3(headers, sc, false)
+	if headers[b3Flags] != "1" || headers[b3Sampled] != "" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zipkin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"mosn.io/mosn/pkg/log"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
+)
+
+// reporter sends the finished spans to the zipkin collector in batches.
+// the spans are queued in a bounded channel, and dropped if the queue is full,
+// so the reporting never blocks the requests
+type reporter struct {
+	url           string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan *spanModel
+	stats         types.Metrics
+	stop          chan struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func newReporter(cfg *Config) *reporter {
+	r := &reporter{
+		url: cfg.CollectorURL,
+		client: &http.Client{
+			Timeout: cfg.Timeout.Duration,
+		},
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval.Duration,
+		queue:         make(chan *spanModel, cfg.QueueSize),
+		stats:         metrics.NewTraceStats(DriverName),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	utils.GoWithRecover(r.run, nil)
+	return r
+}
+
+// Report queues a span, the span is dropped if the queue is full
+func (r *reporter) Report(span *spanModel) {
+	select {
+	case r.queue <- span:
+	default:
+		r.stats.Counter(metrics.TraceSpanDropped).Inc(1)
+	}
+}
+
+// Close flushes the queued spans and stops the reporter
+func (r *reporter) Close() {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+	})
+}
+
+func (r *reporter) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*spanModel, 0, r.batchSize)
+	for {
+		select {
+		case span := <-r.queue:
+			batch = append(batch, span)
+			if len(batch) >= r.batchSize {
+				batch = r.flush(batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+		case <-r.stop:
+			for {
+				select {
+				case span := <-r.queue:
+					batch = append(batch, span)
+					if len(batch) >= r.batchSize {
+						batch = r.flush(batch)
+					}
+				default:
+					r.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush sends the batch and returns the reset batch
+func (r *reporter) flush(batch []*spanModel) []*spanModel {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := r.send(batch); err != nil {
+		r.stats.Counter(metrics.TraceSpanReportFailed).Inc(int64(len(batch)))
+		log.DefaultLogger.Errorf("[trace] [zipkin] report %d spans to %s failed: %v", len(batch), r.url, err)
+	} else {
+		r.stats.Counter(metrics.TraceSpanReported).Inc(int64(len(batch)))
+	}
+	return batch[:0]
+}
+
+func (r *reporter) send(batch []*spanModel) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(r.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
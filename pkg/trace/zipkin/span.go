@@ -0,0 +1,197 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zipkin
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"mosn.io/mosn/pkg/types"
+)
+
+// Span is a zipkin span, it is reported when finished if sampled
+type Span struct {
+	tracer    *Tracer
+	context   spanContext
+	name      string
+	kind      string
+	startTime time.Time
+	endTime   time.Time
+	// zipkin tags
+	tags map[string]string
+	// tags set by SetTag
+	extTags        map[uint64]string
+	localEndpoint  *endpoint
+	remoteEndpoint *endpoint
+}
+
+func (s *Span) TraceId() string {
+	return s.context.traceID
+}
+
+func (s *Span) SpanId() string {
+	return s.context.spanID
+}
+
+func (s *Span) ParentSpanId() string {
+	return s.context.parentID
+}
+
+// Sampled returns whether the span will be reported
+func (s *Span) Sampled() bool {
+	return s.context.sampled != nil && *s.context.sampled
+}
+
+func (s *Span) SetOperation(operation string) {
+	s.name = operation
+}
+
+func (s *Span) SetTag(key uint64, value string) {
+	if s.extTags == nil {
+		s.extTags = make(map[uint64]string)
+	}
+	s.extTags[key] = value
+}
+
+func (s *Span) Tag(key uint64) string {
+	return s.extTags[key]
+}
+
+func (s *Span) SetRequestInfo(reqinfo types.RequestInfo) {
+	code := reqinfo.ResponseCode()
+	s.tags["mosn.response_code"] = strconv.Itoa(code)
+	s.tags["mosn.request_size"] = strconv.FormatUint(reqinfo.BytesReceived(), 10)
+	s.tags["mosn.response_size"] = strconv.FormatUint(reqinfo.BytesSent(), 10)
+	if isHTTP(s.tracer.protocol) {
+		s.tags["http.status_code"] = strconv.Itoa(code)
+	}
+	if code >= 500 {
+		s.tags["error"] = strconv.Itoa(code)
+	}
+
+	if addr := reqinfo.DownstreamLocalAddress(); addr != nil {
+		s.localEndpoint = newEndpoint(addr.String())
+	}
+	if host := reqinfo.UpstreamHost(); host != nil {
+		s.tags["mosn.upstream_host"] = host.AddressString()
+		if s.kind == "CLIENT" {
+			s.remoteEndpoint = newEndpoint(host.AddressString())
+		}
+	}
+	if addr := reqinfo.DownstreamRemoteAddress(); addr != nil && s.kind == "SERVER" {
+		s.remoteEndpoint = newEndpoint(addr.String())
+	}
+}
+
+func (s *Span) FinishSpan() {
+	s.endTime = time.Now()
+	if s.Sampled() {
+		s.tracer.reporter.Report(s.model())
+	}
+}
+
+// InjectContext writes the b3 headers into the upstream request, the span of mosn is the parent of the upstream span
+func (s *Span) InjectContext(requestHeaders types.HeaderMap) {
+	injectB3(requestHeaders, s.context, s.tracer.config.B3Single)
+}
+
+func (s *Span) SpawnChild(operationName string, startTime time.Time) types.Span {
+	child := &Span{
+		tracer:    s.tracer,
+		context:   s.context,
+		name:      operationName,
+		startTime: startTime,
+		tags: map[string]string{
+			"mosn.protocol": string(s.tracer.protocol),
+		},
+	}
+	child.context.parentID = s.context.spanID
+	child.context.spanID = newSpanID()
+	return child
+}
+
+// model returns the span in zipkin v2 json model
+func (s *Span) model() *spanModel {
+	m := &spanModel{
+		TraceID:        s.context.traceID,
+		ID:             s.context.spanID,
+		ParentID:       s.context.parentID,
+		Name:           s.name,
+		Kind:           s.kind,
+		Timestamp:      s.startTime.UnixNano() / int64(time.Microsecond),
+		Duration:       int64(s.endTime.Sub(s.startTime) / time.Microsecond),
+		Debug:          s.context.debug,
+		LocalEndpoint:  &endpoint{},
+		RemoteEndpoint: s.remoteEndpoint,
+		Tags:           make(map[string]string, len(s.tags)+len(s.extTags)),
+	}
+	// zipkin requires the duration to be positive
+	if m.Duration <= 0 {
+		m.Duration = 1
+	}
+	if s.localEndpoint != nil {
+		*m.LocalEndpoint = *s.localEndpoint
+	}
+	m.LocalEndpoint.ServiceName = s.tracer.config.ServiceName
+	for k, v := range s.tags {
+		m.Tags[k] = v
+	}
+	for k, v := range s.extTags {
+		m.Tags["mosn.tag."+strconv.FormatUint(k, 10)] = v
+	}
+	return m
+}
+
+type spanModel struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId,omitempty"`
+	Name           string            `json:"name,omitempty"`
+	Kind           string            `json:"kind,omitempty"`
+	Timestamp      int64             `json:"timestamp"`
+	Duration       int64             `json:"duration"`
+	Debug          bool              `json:"debug,omitempty"`
+	LocalEndpoint  *endpoint         `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *endpoint         `json:"remoteEndpoint,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+type endpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int    `json:"port,omitempty"`
+}
+
+// newEndpoint parses the host:port address
+func newEndpoint(address string) *endpoint {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	e := &endpoint{}
+	e.Port, _ = strconv.Atoi(portStr)
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			e.IPv4 = ip.String()
+		} else {
+			e.IPv6 = ip.String()
+		}
+	}
+	return e
+}
@@ -0,0 +1,148 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zipkin
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/protocol/http"
+	mhttp2 "mosn.io/mosn/pkg/protocol/http2"
+	"mosn.io/mosn/pkg/protocol/rpc/sofarpc"
+	"mosn.io/mosn/pkg/protocol/sofarpc/models"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/types"
+)
+
+// Tracer starts the spans of a protocol, the trace context is extracted from the b3 headers
+type Tracer struct {
+	protocol types.Protocol
+	config   *Config
+	reporter *reporter
+}
+
+// newTracer returns a zipkin tracer of the protocol
+func newTracer(proto types.Protocol, config *Config, reporter *reporter) *Tracer {
+	return &Tracer{
+		protocol: proto,
+		config:   config,
+		reporter: reporter,
+	}
+}
+
+func (t *Tracer) Start(ctx context.Context, request interface{}, startTime time.Time) types.Span {
+	headers, ok := request.(types.HeaderMap)
+	if !ok || headers == nil {
+		return nil
+	}
+	// ignore heartbeat
+	if cmd, ok := request.(sofarpc.SofaRpcCmd); ok && cmd.CommandCode() == sofarpc.HEARTBEAT {
+		return nil
+	}
+
+	span := &Span{
+		tracer:    t,
+		startTime: startTime,
+		tags: map[string]string{
+			"mosn.protocol": string(t.protocol),
+		},
+	}
+
+	if sc, ok := extractB3(headers); ok {
+		span.context = sc
+	}
+	if span.context.traceID == "" {
+		span.context.traceID = newTraceID()
+		span.context.spanID = ""
+	}
+	// the span of mosn is the child of the downstream span
+	span.context.parentID = span.context.spanID
+	span.context.spanID = newSpanID()
+	if span.context.sampled == nil {
+		// the request id makes the decision deterministic if it exists
+		sampled, ok := trace.SampleByRequestID(headers, *t.config.SampleRate)
+		if !ok {
+			sampled = t.sample(span.context.traceID)
+		}
+		span.context.sampled = boolPtr(sampled)
+	}
+
+	switch mosnctx.Get(ctx, types.ContextKeyListenerType) {
+	case v2.INGRESS:
+		span.kind = "SERVER"
+	case v2.EGRESS:
+		span.kind = "CLIENT"
+	}
+	t.setRequestTags(span, request)
+	return span
+}
+
+// sample makes the sampling decision of a new trace by the lower 64 bits of the trace id,
+// so the decision is consistent for the same trace
+func (t *Tracer) sample(traceID string) bool {
+	rate := *t.config.SampleRate
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	low, err := strconv.ParseUint(traceID[len(traceID)-16:], 16, 64)
+	if err != nil {
+		return false
+	}
+	return low < uint64(rate*math.MaxUint64)
+}
+
+func (t *Tracer) setRequestTags(span *Span, request interface{}) {
+	switch req := request.(type) {
+	case http.RequestHeader:
+		if req.RequestHeader == nil {
+			return
+		}
+		method := string(req.Method())
+		path := string(req.RequestURI())
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i]
+		}
+		span.name = method + " " + path
+		span.tags["http.method"] = method
+		span.tags["http.path"] = path
+	case *mhttp2.ReqHeader:
+		span.name = req.Req.Method + " " + req.Req.URL.Path
+		span.tags["http.method"] = req.Req.Method
+		span.tags["http.path"] = req.Req.URL.Path
+	case sofarpc.SofaRpcCmd:
+		service, _ := req.Get(models.SERVICE_KEY)
+		method, _ := req.Get(models.TARGET_METHOD)
+		span.name = service + ":" + method
+		span.tags["rpc.service"] = service
+		span.tags["rpc.method"] = method
+	default:
+		span.name = string(t.protocol)
+	}
+}
+
+func isHTTP(proto types.Protocol) bool {
+	return proto == protocol.HTTP1 || proto == protocol.HTTP2
+}
@@ -0,0 +1,232 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package zipkin
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/metrics"
+	"mosn.io/mosn/pkg/network"
+	"mosn.io/mosn/pkg/protocol"
+	mosnhttp "mosn.io/mosn/pkg/protocol/http"
+	"mosn.io/mosn/pkg/trace"
+	"mosn.io/mosn/pkg/types"
+)
+
+func startCollector(t *testing.T) (*httptest.Server, chan []spanModel) {
+	ch := make(chan []spanModel, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/spans" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var spans []spanModel
+		if err := json.Unmarshal(body, &spans); err != nil {
+			t.Errorf("unmarshal spans error: %v", err)
+		}
+		ch <- spans
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return server, ch
+}
+
+func newHTTPHeader(headers map[string]string) mosnhttp.RequestHeader {
+	h := mosnhttp.RequestHeader{RequestHeader: &fasthttp.RequestHeader{}}
+	h.SetMethod("GET")
+	h.SetRequestURI("/hello?name=mosn")
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+func TestZipkinTracer(t *testing.T) {
+	server, ch := startCollector(t)
+	defer server.Close()
+
+	if err := trace.Init(DriverName, map[string]interface{}{
+		"service_name":   "test-mosn",
+		"collector_url":  server.URL + "/api/v2/spans",
+		"flush_interval": "50ms",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer defaultDriver.reporter.Close()
+
+	for _, proto := range []types.Protocol{protocol.HTTP1, protocol.HTTP2, protocol.SofaRPC} {
+		if trace.Tracer(proto) == nil {
+			t.Errorf("tracer of %s is not registered", proto)
+		}
+	}
+
+	ctx := mosnctx.WithValue(context.Background(), types.ContextKeyListenerType, v2.INGRESS)
+	header := newHTTPHeader(map[string]string{
+		"X-B3-TraceId": "463ac35c9f6413ad48485a3953bb6124",
+		"X-B3-SpanId":  "a2fb4a1d1a96d312",
+		"X-B3-Sampled": "1",
+	})
+	span := trace.Tracer(protocol.HTTP1).Start(ctx, header, time.Now())
+	if span.TraceId() != "463ac35c9f6413ad48485a3953bb6124" || span.ParentSpanId() != "a2fb4a1d1a96d312" ||
+		span.SpanId() == "a2fb4a1d1a96d312" {
+		t.Fatalf("unexpected span %s %s %s", span.TraceId(), span.SpanId(), span.ParentSpanId())
+	}
+
+	// inject into the upstream request
+	upstream := protocol.CommonHeader{}
+	span.InjectContext(upstream)
+	if upstream[b3TraceID] != span.TraceId() || upstream[b3SpanID] != span.SpanId() ||
+		upstream[b3ParentSpanID] != span.ParentSpanId() || upstream[b3Sampled] != "1" {
+		t.Errorf("unexpected upstream headers: %v", upstream)
+	}
+
+	info := network.NewRequestInfo()
+	info.SetResponseCode(503)
+	info.SetDownstreamRemoteAddress(&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 12345})
+	span.SetRequestInfo(info)
+	span.FinishSpan()
+
+	select {
+	case spans := <-ch:
+		if len(spans) != 1 {
+			t.Fatalf("expected 1 span, but got %d", len(spans))
+		}
+		s := spans[0]
+		if s.TraceID != span.TraceId() || s.ID != span.SpanId() || s.ParentID != "a2fb4a1d1a96d312" ||
+			s.Name != "GET /hello" || s.Kind != "SERVER" || s.Duration <= 0 {
+			t.Errorf("unexpected span: %+v", s)
+		}
+		if s.LocalEndpoint == nil || s.LocalEndpoint.ServiceName != "test-mosn" {
+			t.Errorf("unexpected local endpoint: %+v", s.LocalEndpoint)
+		}
+		if s.RemoteEndpoint == nil || s.RemoteEndpoint.IPv4 != "10.0.0.1" || s.RemoteEndpoint.Port != 12345 {
+			t.Errorf("unexpected remote endpoint: %+v", s.RemoteEndpoint)
+		}
+		if s.Tags["http.status_code"] != "503" || s.Tags["error"] != "503" || s.Tags["http.path"] != "/hello" {
+			t.Errorf("unexpected tags: %v", s.Tags)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector received nothing")
+	}
+
+	// not sampled spans are propagated but not reported
+	span = trace.Tracer(protocol.HTTP1).Start(ctx, newHTTPHeader(map[string]string{"b3": "0"}), time.Now())
+	upstream = protocol.CommonHeader{}
+	span.InjectContext(upstream)
+	if upstream[b3Sampled] != "0" || upstream[b3TraceID] == "" {
+		t.Errorf("unexpected upstream headers: %v", upstream)
+	}
+	span.FinishSpan()
+	select {
+	case spans := <-ch:
+		t.Errorf("not sampled span is reported: %+v", spans)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSample(t *testing.T) {
+	rate := 0.0
+	tracer := newTracer(protocol.HTTP1, &Config{SampleRate: &rate}, nil)
+	for i := 0; i < 100; i++ {
+		if tracer.sample(newTraceID()) {
+			t.Fatal("no trace should be sampled")
+		}
+	}
+	rate = 0.5
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if tracer.sample(newTraceID()) {
+			sampled++
+		}
+	}
+	if sampled < 400 || sampled > 600 {
+		t.Errorf("unexpected sampled count %d", sampled)
+	}
+	// the decision is consistent
+	id := newTraceID()
+	decision := tracer.sample(id)
+	for i := 0; i < 10; i++ {
+		if tracer.sample(id) != decision {
+			t.Fatal("inconsistent sampling decision")
+		}
+	}
+}
+
+func TestReporterDrop(t *testing.T) {
+	metrics.ResetAll()
+	r := &reporter{
+		queue: make(chan *spanModel, 1),
+		stats: metrics.NewTraceStats(DriverName),
+	}
+	r.Report(&spanModel{})
+	r.Report(&spanModel{})
+	r.Report(&spanModel{})
+	if dropped := r.stats.Counter(metrics.TraceSpanDropped).Count(); dropped != 2 {
+		t.Errorf("expected 2 dropped spans, but got %d", dropped)
+	}
+}
+
+func TestReporterFailed(t *testing.T) {
+	metrics.ResetAll()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg, _ := parseConfig(map[string]interface{}{
+		"collector_url": server.URL,
+		"batch_size":    2,
+	})
+	r := newReporter(cfg)
+	for i := 0; i < 3; i++ {
+		r.Report(&spanModel{})
+	}
+	// close flushes all the queued spans
+	r.Close()
+	if failed := r.stats.Counter(metrics.TraceSpanReportFailed).Count(); failed != 3 {
+		t.Errorf("expected 3 failed spans, but got %d", failed)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig(map[string]interface{}{"collector_url": "http://127.0.0.1:9411/api/v2/spans"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServiceName != defaultServiceName || *cfg.SampleRate != 1 || cfg.BatchSize != defaultBatchSize ||
+		cfg.QueueSize != defaultQueueSize || cfg.FlushInterval.Duration != defaultFlushInterval {
+		t.Errorf("unexpected default config: %+v", cfg)
+	}
+	for _, c := range []map[string]interface{}{
+		{},
+		{"collector_url": "http://127.0.0.1:9411/api/v2/spans", "sample_rate": 1.5},
+	} {
+		if _, err := parseConfig(c); err == nil {
+			t.Errorf("config %v expected an error", c)
+		}
+	}
+}
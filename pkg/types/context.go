@@ -41,6 +41,10 @@ const (
 	ContextKeyActiveSpan
 	ContextKeyTraceId
 	ContextKeyVariables
+	ContextKeyDebugTrace
+	ContextKeyRequestIDConfig
+	ContextKeyRequestID
+	ContextKeyDownstreamConnection
 	ContextKeyEnd
 )
 
@@ -54,6 +54,15 @@ type Metrics interface {
 	UnregisterAll()
 }
 
+// BucketHistogram is a histogram with fixed buckets, which can be aggregated across instances
+type BucketHistogram interface {
+	metrics.Histogram
+
+	// Buckets returns the upper bounds and the value count of each bucket,
+	// counts has one more element than bounds, which is the +Inf bucket
+	Buckets() (bounds []float64, counts []int64)
+}
+
 // MetricsSink flush metrics to backend storage
 type MetricsSink interface {
 	// Flush flush given metrics
@@ -53,6 +53,55 @@ const (
 	ReqEntityTooLarge ResponseFlag = 0x1000
 )
 
+// responseFlagNames is the short names of the response flags, used by the access log and the tracing
+var responseFlagNames = []struct {
+	flag ResponseFlag
+	name string
+}{
+	{NoHealthyUpstream, "UH"},
+	{UpstreamRequestTimeout, "UT"},
+	{UpstreamLocalReset, "LR"},
+	{UpstreamRemoteReset, "UR"},
+	{UpstreamConnectionFailure, "UF"},
+	{UpstreamConnectionTermination, "UC"},
+	{UpstreamOverflow, "UO"},
+	{NoRouteFound, "NR"},
+	{DelayInjected, "DI"},
+	{FaultInjected, "FI"},
+	{RateLimited, "RL"},
+	{ReqEntityTooLarge, "RE"},
+}
+
+// AllResponseFlags returns all the response flags
+func AllResponseFlags() []ResponseFlag {
+	flags := make([]ResponseFlag, 0, len(responseFlagNames))
+	for _, f := range responseFlagNames {
+		flags = append(flags, f.flag)
+	}
+	return flags
+}
+
+// ResponseFlagByName returns the response flag of the short name
+func ResponseFlagByName(name string) (ResponseFlag, bool) {
+	for _, f := range responseFlagNames {
+		if f.name == name {
+			return f.flag, true
+		}
+	}
+	return 0, false
+}
+
+// ResponseFlagNames returns the short names of the response flags set in the request info
+func ResponseFlagNames(info RequestInfo) []string {
+	var names []string
+	for _, f := range responseFlagNames {
+		if info.GetResponseFlag(f.flag) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
 // RequestInfo has information for a request, include the basic information,
 // the request's downstream information, ,the request's upstream information and the router information.
 type RequestInfo interface {
@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+type flagRequestInfo struct {
+	RequestInfo
+	flags ResponseFlag
+}
+
+func (info *flagRequestInfo) GetResponseFlag(flag ResponseFlag) bool {
+	return info.flags&flag != 0
+}
+
+func TestResponseFlagNames(t *testing.T) {
+	for _, flag := range AllResponseFlags() {
+		names := ResponseFlagNames(&flagRequestInfo{flags: flag})
+		if len(names) != 1 {
+			t.Fatalf("flag %d has names %v", flag, names)
+		}
+		if f, ok := ResponseFlagByName(names[0]); !ok || f != flag {
+			t.Fatalf("name %s is parsed as flag %d", names[0], f)
+		}
+	}
+	names := ResponseFlagNames(&flagRequestInfo{flags: NoRouteFound | NoHealthyUpstream})
+	if !reflect.DeepEqual(names, []string{"UH", "NR"}) {
+		t.Fatalf("unexpected names %v", names)
+	}
+	if names := ResponseFlagNames(&flagRequestInfo{}); len(names) != 0 {
+		t.Fatalf("unexpected names %v", names)
+	}
+	if _, ok := ResponseFlagByName("XX"); ok {
+		t.Fatal("unknown name is parsed")
+	}
+}
@@ -230,6 +230,7 @@ const (
 	Exact
 	Regex
 	SofaHeader
+	Connect
 )
 
 // QueryParams is a string-string map
@@ -19,6 +19,7 @@ package types
 
 import (
 	"context"
+	"time"
 )
 
 //
@@ -136,6 +137,30 @@ type StreamEventListener interface {
 	OnDestroyStream()
 }
 
+// UpgradableStream is implemented by the server streams whose connection can be switched into a tunnel
+// after a protocol upgrade, such as the HTTP/1.1 Upgrade used by WebSocket.
+type UpgradableStream interface {
+	// Upgrade switches the connection of the stream and the connection of the upstream stream into
+	// raw bidirectional bytes relaying after the upgrade response is sent.
+	// The tunnel is closed if no bytes are relayed in idleTimeout, zero means no idle timeout.
+	Upgrade(upstream Stream, idleTimeout time.Duration, listener TunnelEventListener) error
+}
+
+// ConnectStream is implemented by the server streams which can terminate the HTTP CONNECT request
+type ConnectStream interface {
+	// Tunnel connects the upstream connection, the payload of the stream and the upstream connection
+	// are relayed to each other after the connect response is sent.
+	// The tunnel is closed if no bytes are relayed in idleTimeout, zero means no idle timeout.
+	Tunnel(upstream ClientConnection, idleTimeout time.Duration, listener TunnelEventListener) error
+}
+
+// TunnelEventListener is a listener of the tunnel of an upgraded stream
+type TunnelEventListener interface {
+	// OnTunnelClose is called when the tunnel is closed, with the bytes relayed
+	// from the downstream to the upstream and from the upstream to the downstream
+	OnTunnelClose(bytesReceived, bytesSent uint64)
+}
+
 // StreamSender encodes and sends protocol stream
 // On server scenario, StreamSender sends response
 // On client scenario, StreamSender sends request
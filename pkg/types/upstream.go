@@ -232,6 +232,9 @@ type ClusterInfo interface {
 
 	// ConectTimeout returns the connect timeout
 	ConnectTimeout() time.Duration
+
+	// ProxyProtocolVersion returns the version of the PROXY protocol header sent to the upstream, 0 means disabled
+	ProxyProtocolVersion() int
 }
 
 // ResourceManager manages different types of Resource
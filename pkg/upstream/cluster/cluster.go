@@ -22,6 +22,7 @@ import (
 	"time"
 
 	v2 "mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/filter/accept/proxyprotocol"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/mtls"
 	"mosn.io/mosn/pkg/network"
@@ -53,7 +54,7 @@ func newSimpleCluster(clusterConfig v2.Cluster) *simpleCluster {
 		stats:                newClusterStats(clusterConfig.Name),
 		lbSubsetInfo:         NewLBSubsetInfo(&clusterConfig.LBSubSetConfig), // new subset load balancer info
 		lbType:               types.LoadBalancerType(clusterConfig.LbType),
-		resourceManager:      NewResourceManager(clusterConfig.CirBreThresholds),
+		resourceManager:      NewResourceManager(clusterConfig.Name, clusterConfig.CirBreThresholds),
 	}
 
 	// set ConnectTimeout
@@ -63,6 +64,18 @@ func newSimpleCluster(clusterConfig v2.Cluster) *simpleCluster {
 		info.connectTimeout = network.DefaultConnectTimeout
 	}
 
+	// set PROXY protocol version
+	if clusterConfig.ProxyProtocol != nil {
+		switch clusterConfig.ProxyProtocol.Version {
+		case v2.ProxyProtocolV1:
+			info.proxyProtocolVersion = proxyprotocol.Version1
+		case v2.ProxyProtocolV2:
+			info.proxyProtocolVersion = proxyprotocol.Version2
+		default:
+			log.DefaultLogger.Errorf("[upstream] [cluster] [new cluster] unknown proxy protocol version %s", clusterConfig.ProxyProtocol.Version)
+		}
+	}
+
 	// tls mng
 	mgr, err := mtls.NewTLSClientContextManager(&clusterConfig.TLS)
 	if err != nil {
@@ -74,10 +87,18 @@ func newSimpleCluster(clusterConfig v2.Cluster) *simpleCluster {
 	}
 	// init a empty
 	hostSet := &hostSet{}
+	var lb types.LoadBalancer
+	if info.clusterType == v2.ORIGINALDST_CLUSTER {
+		// the hosts of the original dst cluster are kept in the load balancer
+		cluster.lbInstance = newOriginalDstLoadBalancer(info)
+		lb = cluster.lbInstance
+	} else {
+		lb = NewLoadBalancer(info.lbType, hostSet)
+	}
 	cluster.snapshot.Store(&clusterSnapshot{
 		info:    info,
 		hostSet: hostSet,
-		lb:      NewLoadBalancer(info.lbType, hostSet),
+		lb:      lb,
 	})
 	if clusterConfig.HealthCheck.ServiceName != "" {
 		log.DefaultLogger.Infof("[upstream] [cluster] [new cluster] cluster %s have health check", clusterConfig.Name)
@@ -99,7 +120,9 @@ func (sc *simpleCluster) UpdateHosts(newHosts []types.Host) {
 	hostSet.setFinalHost(newHosts)
 	// load balance
 	var lb types.LoadBalancer
-	if info.lbSubsetInfo.IsEnabled() {
+	if info.clusterType == v2.ORIGINALDST_CLUSTER {
+		lb = sc.lbInstance
+	} else if info.lbSubsetInfo.IsEnabled() {
 		lb = NewSubsetLoadBalancer(info, hostSet)
 	} else {
 		lb = NewLoadBalancer(info.lbType, hostSet)
@@ -144,6 +167,7 @@ type clusterInfo struct {
 	lbSubsetInfo         types.LBSubsetInfo
 	tlsMng               types.TLSContextManager
 	connectTimeout       time.Duration
+	proxyProtocolVersion int
 }
 
 func (ci *clusterInfo) Name() string {
@@ -186,6 +210,10 @@ func (ci *clusterInfo) ConnectTimeout() time.Duration {
 	return ci.connectTimeout
 }
 
+func (ci *clusterInfo) ProxyProtocolVersion() int {
+	return ci.proxyProtocolVersion
+}
+
 type clusterSnapshot struct {
 	info    types.ClusterInfo
 	hostSet types.HostSet
@@ -250,7 +250,11 @@ func (cm *clusterManager) TCPConnForCluster(lbCtx types.LoadBalancerContext, sna
 	if host == nil {
 		return types.CreateConnectionData{}
 	}
-	return host.CreateConnection(context.Background())
+	ctx := lbCtx.DownstreamContext()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return host.CreateConnection(ctx)
 }
 
 func (cm *clusterManager) ConnPoolForCluster(balancerContext types.LoadBalancerContext, snapshot types.ClusterSnapshot, protocol types.Protocol) types.ConnectionPool {
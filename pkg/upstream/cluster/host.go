@@ -23,15 +23,19 @@ import (
 	"sync"
 
 	v2 "mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/filter/accept/proxyprotocol"
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
 )
 
 // simpleHost is an implement of types.Host and types.HostInfo
 type simpleHost struct {
 	hostname      string
 	addressString string
+	address       net.Addr // the resolved address, it is got from AddrStore if nil
 	clusterInfo   types.ClusterInfo
 	stats         types.HostStats
 	metaData      v2.Metadata
@@ -69,6 +73,9 @@ func (sh *simpleHost) ClusterInfo() types.ClusterInfo {
 }
 
 func (sh *simpleHost) Address() net.Addr {
+	if sh.address != nil {
+		return sh.address
+	}
 	return GetOrCreateAddr(sh.addressString)
 }
 
@@ -106,7 +113,13 @@ func (sh *simpleHost) CreateConnection(context context.Context) types.CreateConn
 	if !sh.tlsDisable {
 		tlsMng = sh.clusterInfo.TLSMng()
 	}
-	clientConn := network.NewClientConnection(nil, sh.clusterInfo.ConnectTimeout(), tlsMng, sh.Address(), nil)
+	var clientConn types.ClientConnection
+	if version := sh.clusterInfo.ProxyProtocolVersion(); version != 0 {
+		clientConn = network.NewClientConnectionWithPreface(nil, sh.clusterInfo.ConnectTimeout(), tlsMng, sh.Address(), nil,
+			proxyProtocolHeader(context, version))
+	} else {
+		clientConn = network.NewClientConnection(nil, sh.clusterInfo.ConnectTimeout(), tlsMng, sh.Address(), nil)
+	}
 	clientConn.SetBufferLimit(sh.clusterInfo.ConnBufferLimitBytes())
 
 	return types.CreateConnectionData{
@@ -115,6 +128,21 @@ func (sh *simpleHost) CreateConnection(context context.Context) types.CreateConn
 	}
 }
 
+// proxyProtocolHeader returns the PROXY protocol header that carries the addresses of the downstream connection
+// in the context, the LOCAL header is returned if the connection is not created for a downstream connection
+func proxyProtocolHeader(ctx context.Context, version int) []byte {
+	var src, dst net.Addr
+	if conn, ok := mosnctx.Get(ctx, types.ContextKeyDownstreamConnection).(types.Connection); ok {
+		src, dst = conn.RemoteAddr(), conn.LocalAddr()
+	}
+	header, err := proxyprotocol.NewHeader(version, src, dst).Format()
+	if err != nil {
+		log.DefaultLogger.Errorf("[upstream] [host] format proxy protocol header failed: %v, send the LOCAL header instead", err)
+		header, _ = proxyprotocol.NewHeader(version, nil, nil).Format()
+	}
+	return header
+}
+
 func (sh *simpleHost) ClearHealthFlag(flag types.HealthFlag) {
 	sh.healthFlags &= ^uint64(flag)
 }
@@ -142,10 +170,16 @@ func GetOrCreateAddr(addrstr string) net.Addr {
 	if addr, ok := AddrStore.Load(addrstr); ok {
 		return addr.(net.Addr)
 	}
-	addr, err := net.ResolveTCPAddr("tcp", addrstr)
-	if err != nil {
-		log.DefaultLogger.Errorf("[upstream] resolve addr %s failed: %v", addrstr, err)
-		return nil
+	var addr net.Addr
+	if unixAddr, ok := utils.ParseUnixAddr(addrstr); ok {
+		addr = unixAddr
+	} else {
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addrstr)
+		if err != nil {
+			log.DefaultLogger.Errorf("[upstream] resolve addr %s failed: %v", addrstr, err)
+			return nil
+		}
+		addr = tcpAddr
 	}
 	AddrStore.Store(addrstr, addr)
 	return addr
@@ -0,0 +1,99 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	v2 "mosn.io/mosn/pkg/api/v2"
+	mosnctx "mosn.io/mosn/pkg/context"
+	"mosn.io/mosn/pkg/filter/accept/proxyprotocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockDownstreamConnection struct {
+	types.Connection
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *mockDownstreamConnection) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *mockDownstreamConnection) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func TestHostProxyProtocol(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	cluster := newSimpleCluster(v2.Cluster{
+		Name:          "proxy_protocol",
+		ClusterType:   v2.SIMPLE_CLUSTER,
+		LbType:        v2.LB_RANDOM,
+		ProxyProtocol: &v2.ProxyProtocol{Version: v2.ProxyProtocolV2},
+	})
+	if v := cluster.info.ProxyProtocolVersion(); v != proxyprotocol.Version2 {
+		t.Fatalf("expected proxy protocol version 2, but got %d", v)
+	}
+	host := NewSimpleHost(v2.Host{HostConfig: v2.HostConfig{Address: ln.Addr().String()}}, cluster.info)
+
+	downstream := &mockDownstreamConnection{
+		local:  &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 443},
+		remote: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 56324},
+	}
+	testCases := []struct {
+		ctx     context.Context
+		command proxyprotocol.Command
+		src     string
+	}{
+		{context.Background(), proxyprotocol.Local, ""},
+		{mosnctx.WithValue(context.Background(), types.ContextKeyDownstreamConnection, downstream), proxyprotocol.Proxy, "10.0.0.1:56324"},
+	}
+	for i, tc := range testCases {
+		conn := host.CreateConnection(tc.ctx).Connection
+		if err := conn.Connect(); err != nil {
+			t.Fatalf("#%d connect failed: %v", i, err)
+		}
+		rawc, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawc.SetReadDeadline(time.Now().Add(time.Second))
+		header, err := proxyprotocol.ReadHeader(rawc)
+		if err != nil {
+			t.Fatalf("#%d read header failed: %v", i, err)
+		}
+		if header.Version != proxyprotocol.Version2 || header.Command != tc.command {
+			t.Errorf("#%d unexpected header: %+v", i, header)
+		}
+		if tc.src != "" && (header.SourceAddr == nil || header.SourceAddr.String() != tc.src) {
+			t.Errorf("#%d expected source %s, but got %v", i, tc.src, header.SourceAddr)
+		}
+		conn.Close(types.NoFlush, types.LocalClose)
+		rawc.Close()
+	}
+}
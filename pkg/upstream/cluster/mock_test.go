@@ -20,6 +20,7 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"net"
 
 	v2 "mosn.io/mosn/pkg/api/v2"
 	"mosn.io/mosn/pkg/network"
@@ -135,6 +136,7 @@ type mockLbContext struct {
 	types.LoadBalancerContext
 	mmc    types.MetadataMatchCriteria
 	header types.HeaderMap
+	conn   net.Conn
 }
 
 func newMockLbContext(m map[string]string) types.LoadBalancerContext {
@@ -163,6 +165,10 @@ func (ctx *mockLbContext) DownstreamHeaders() types.HeaderMap {
 	return ctx.header
 }
 
+func (ctx *mockLbContext) DownstreamConnection() net.Conn {
+	return ctx.conn
+}
+
 func (ctx *mockLbContext) DownstreamContext() context.Context {
 	return nil
 }
@@ -0,0 +1,146 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"container/list"
+	"net"
+	"strings"
+	"sync"
+
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+const (
+	defaultOriginalDstPort = "80"
+	// the host label of the stats shared by the hosts of an ORIGINAL_DST cluster
+	originalDstHostStatsName = "original_dst"
+)
+
+// originalDstMaxHosts is the max number of hosts kept by an ORIGINAL_DST cluster,
+// the least recently used host is removed if the limit is exceeded
+var originalDstMaxHosts = 1024
+
+// originalDstLoadBalancer is the load balancer of the ORIGINAL_DST cluster.
+// The cluster has no configured hosts, the upstream host is the request authority (x-mosn-host),
+// such as the target of a CONNECT request or an absolute-URI forward proxy request,
+// or the local address of the downstream connection if the request has no authority.
+// The authority is resolved by DNS, and the hosts are created on demand and reused by the address.
+// The targets are unbounded, so the hosts are kept in a LRU cache and share the stats of the cluster.
+type originalDstLoadBalancer struct {
+	info     types.ClusterInfo
+	stats    types.HostStats
+	maxHosts int
+
+	mux   sync.Mutex
+	hosts map[string]*list.Element // address -> element of lru
+	lru   *list.List               // the hosts, the front is the most recently used
+}
+
+func newOriginalDstLoadBalancer(info types.ClusterInfo) types.LoadBalancer {
+	return &originalDstLoadBalancer{
+		info:     info,
+		stats:    newHostStats(info.Name(), originalDstHostStatsName),
+		maxHosts: originalDstMaxHosts,
+		hosts:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (lb *originalDstLoadBalancer) ChooseHost(context types.LoadBalancerContext) types.Host {
+	if context == nil {
+		return nil
+	}
+	address := originalDstAddress(context)
+	if address == "" {
+		return nil
+	}
+	if host := lb.getHost(address); host != nil {
+		return host
+	}
+	// resolve the address without the lock held
+	addr := GetOrCreateAddr(address)
+	if addr == nil {
+		return nil
+	}
+	return lb.addHost(&simpleHost{
+		addressString: address,
+		address:       addr,
+		clusterInfo:   lb.info,
+		stats:         lb.stats,
+	})
+}
+
+func (lb *originalDstLoadBalancer) getHost(address string) types.Host {
+	lb.mux.Lock()
+	defer lb.mux.Unlock()
+	if elem, ok := lb.hosts[address]; ok {
+		lb.lru.MoveToFront(elem)
+		return elem.Value.(types.Host)
+	}
+	return nil
+}
+
+// addHost adds the host if there is no host of the address yet, and removes the least recently used hosts
+// if the limit is exceeded. The removed hosts still work for the requests using them, as they keep the resolved address.
+func (lb *originalDstLoadBalancer) addHost(host types.Host) types.Host {
+	lb.mux.Lock()
+	defer lb.mux.Unlock()
+	if elem, ok := lb.hosts[host.AddressString()]; ok {
+		lb.lru.MoveToFront(elem)
+		return elem.Value.(types.Host)
+	}
+	lb.hosts[host.AddressString()] = lb.lru.PushFront(host)
+	for lb.lru.Len() > lb.maxHosts {
+		elem := lb.lru.Back()
+		address := lb.lru.Remove(elem).(types.Host).AddressString()
+		delete(lb.hosts, address)
+		AddrStore.Delete(address)
+	}
+	return host
+}
+
+// IsExistsHosts always returns true, as the hosts are created on demand
+func (lb *originalDstLoadBalancer) IsExistsHosts(metadata types.MetadataMatchCriteria) bool {
+	return true
+}
+
+func (lb *originalDstLoadBalancer) HostNum(metadata types.MetadataMatchCriteria) int {
+	return 1
+}
+
+func originalDstAddress(context types.LoadBalancerContext) string {
+	if headers := context.DownstreamHeaders(); headers != nil {
+		if authority, ok := headers.Get(protocol.MosnHeaderHostKey); ok && authority != "" {
+			return withDefaultPort(strings.ToLower(authority))
+		}
+	}
+	if conn := context.DownstreamConnection(); conn != nil && conn.LocalAddr() != nil {
+		return conn.LocalAddr().String()
+	}
+	return ""
+}
+
+// withDefaultPort adds the default http port to the authority without port
+func withDefaultPort(authority string) string {
+	if _, _, err := net.SplitHostPort(authority); err == nil {
+		return authority
+	}
+	return net.JoinHostPort(strings.Trim(authority, "[]"), defaultOriginalDstPort)
+}
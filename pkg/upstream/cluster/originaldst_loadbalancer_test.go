@@ -0,0 +1,155 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	v2 "mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/pkg/types"
+)
+
+type mockOriginalDstConn struct {
+	net.Conn
+	local net.Addr
+}
+
+func (c *mockOriginalDstConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func TestOriginalDstLoadBalancer(t *testing.T) {
+	info := &clusterInfo{
+		name:  "egress",
+		stats: newClusterStats("egress"),
+	}
+	lb := newOriginalDstLoadBalancer(info)
+	for _, tc := range []struct {
+		authority string
+		address   string
+	}{
+		{"127.0.0.1:8080", "127.0.0.1:8080"},
+		// the default port is added
+		{"127.0.0.1", "127.0.0.1:80"},
+		{"[::1]", "[::1]:80"},
+		// invalid port
+		{"127.0.0.1:99999", ""},
+	} {
+		ctx := newMockLbContextWithHeader(nil, protocol.CommonHeader{protocol.MosnHeaderHostKey: tc.authority})
+		host := lb.ChooseHost(ctx)
+		if tc.address == "" {
+			if host != nil {
+				t.Errorf("%s: expected no host, but got %s", tc.authority, host.AddressString())
+			}
+			continue
+		}
+		if host == nil || host.AddressString() != tc.address {
+			t.Errorf("%s: expected host %s, but got %v", tc.authority, tc.address, host)
+			continue
+		}
+		if host.ClusterInfo() != info {
+			t.Errorf("%s: unexpected cluster info", tc.authority)
+		}
+		// the host is reused
+		if lb.ChooseHost(ctx) != host {
+			t.Errorf("%s: the host should be reused", tc.authority)
+		}
+	}
+
+	// the request without authority goes to the original destination of the connection
+	local, _ := net.ResolveTCPAddr("tcp", "10.0.0.1:443")
+	ctx := &mockLbContext{
+		conn: &mockOriginalDstConn{local: local},
+	}
+	if host := lb.ChooseHost(ctx); host == nil || host.AddressString() != "10.0.0.1:443" {
+		t.Errorf("expected the original destination host, but got %v", host)
+	}
+	if !lb.IsExistsHosts(nil) || lb.HostNum(nil) == 0 {
+		t.Error("original dst load balancer should always have hosts")
+	}
+}
+
+func TestOriginalDstLoadBalancerLimit(t *testing.T) {
+	info := &clusterInfo{
+		name:  "egress_limit",
+		stats: newClusterStats("egress_limit"),
+	}
+	lb := newOriginalDstLoadBalancer(info).(*originalDstLoadBalancer)
+	lb.maxHosts = 10
+	var first, last *simpleHost
+	for i := 0; i < 100; i++ {
+		address := fmt.Sprintf("127.0.0.1:%d", 10000+i)
+		ctx := newMockLbContextWithHeader(nil, protocol.CommonHeader{protocol.MosnHeaderHostKey: address})
+		host := lb.ChooseHost(ctx)
+		if host == nil || host.AddressString() != address {
+			t.Fatalf("expected host %s, but got %v", address, host)
+		}
+		if first == nil {
+			first = host.(*simpleHost)
+		}
+		last = host.(*simpleHost)
+		if len(lb.hosts) > lb.maxHosts || lb.lru.Len() > lb.maxHosts {
+			t.Fatalf("the hosts exceed the limit: %d", len(lb.hosts))
+		}
+	}
+	if len(lb.hosts) != lb.maxHosts {
+		t.Errorf("expected %d hosts, but got %d", lb.maxHosts, len(lb.hosts))
+	}
+	// the removed host still works, and its address is removed from the store
+	if _, ok := lb.hosts[first.AddressString()]; ok {
+		t.Error("the least recently used host should be removed")
+	}
+	if _, ok := AddrStore.Load(first.AddressString()); ok {
+		t.Error("the address of the removed host should be removed from the store")
+	}
+	if addr := first.Address(); addr == nil || addr.String() != first.AddressString() {
+		t.Errorf("unexpected address of the removed host: %v", addr)
+	}
+	if _, ok := AddrStore.Load(first.AddressString()); ok {
+		t.Error("the removed host should not add its address to the store again")
+	}
+	// the hosts share the stats of the cluster
+	if first.HostStats().UpstreamRequestTotal != last.HostStats().UpstreamRequestTotal {
+		t.Error("the hosts should share the stats")
+	}
+	// the recently used host is kept
+	ctx := newMockLbContextWithHeader(nil, protocol.CommonHeader{protocol.MosnHeaderHostKey: last.AddressString()})
+	if lb.ChooseHost(ctx) != types.Host(last) {
+		t.Error("the recently used host should be reused")
+	}
+}
+
+func TestOriginalDstCluster(t *testing.T) {
+	cluster := NewCluster(v2.Cluster{
+		Name:        "egress",
+		ClusterType: v2.ORIGINALDST_CLUSTER,
+		LbType:      v2.LB_RANDOM,
+	})
+	lb := cluster.Snapshot().LoadBalancer()
+	if _, ok := lb.(*originalDstLoadBalancer); !ok {
+		t.Fatalf("unexpected load balancer: %T", lb)
+	}
+	// the load balancer keeps the hosts after the hosts are updated
+	cluster.UpdateHosts(nil)
+	if cluster.Snapshot().LoadBalancer() != lb {
+		t.Error("the load balancer of the original dst cluster should not be changed")
+	}
+}
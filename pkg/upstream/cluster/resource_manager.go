@@ -21,6 +21,7 @@ import (
 	"sync/atomic"
 
 	v2 "mosn.io/mosn/pkg/api/v2"
+	"mosn.io/mosn/pkg/runtime"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -40,7 +41,9 @@ type resourcemanager struct {
 	retries         *resource
 }
 
-func NewResourceManager(circuitBreakers v2.CircuitBreakers) types.ResourceManager {
+// NewResourceManager creates the resource manager of the cluster, the max values can be changed by the runtime keys
+// circuit_breakers.{cluster name}.max_connections/max_pending_requests/max_requests/max_retries
+func NewResourceManager(clusterName string, circuitBreakers v2.CircuitBreakers) types.ResourceManager {
 	maxConnections := DefaultMaxConnections
 	maxPendingRequests := DefaultMaxPendingRequests
 	maxRequests := DefaultMaxRequests
@@ -54,18 +57,23 @@ func NewResourceManager(circuitBreakers v2.CircuitBreakers) types.ResourceManage
 		maxRetries = uint64(circuitBreakers.Thresholds[0].MaxRetries)
 	}
 
+	runtimePrefix := "circuit_breakers." + clusterName + "."
 	return &resourcemanager{
 		connections: &resource{
-			max: maxConnections,
+			max:        maxConnections,
+			runtimeKey: runtimePrefix + "max_connections",
 		},
 		pendingRequests: &resource{
-			max: maxPendingRequests,
+			max:        maxPendingRequests,
+			runtimeKey: runtimePrefix + "max_pending_requests",
 		},
 		requests: &resource{
-			max: maxRequests,
+			max:        maxRequests,
+			runtimeKey: runtimePrefix + "max_requests",
 		},
 		retries: &resource{
-			max: maxRetries,
+			max:        maxRetries,
+			runtimeKey: runtimePrefix + "max_retries",
 		},
 	}
 }
@@ -87,13 +95,16 @@ func (rm *resourcemanager) Retries() types.Resource {
 }
 
 // Resource
+// the current value is always counted, as the max value may be changed by the runtime
 type resource struct {
-	current int64
-	max     uint64
+	current    int64
+	max        uint64
+	runtimeKey string
 }
 
 func (r *resource) CanCreate() bool {
-	if r.max == 0 {
+	max := r.Max()
+	if max == 0 {
 		return true
 	}
 	curValue := atomic.LoadInt64(&r.current)
@@ -102,21 +113,17 @@ func (r *resource) CanCreate() bool {
 		return true
 	}
 
-	return uint64(curValue) < r.Max()
+	return uint64(curValue) < max
 }
 
 func (r *resource) Increase() {
-	if r.max != 0 {
-		atomic.AddInt64(&r.current, 1)
-	}
+	atomic.AddInt64(&r.current, 1)
 }
 
 func (r *resource) Decrease() {
-	if r.max != 0 {
-		atomic.AddInt64(&r.current, -1)
-	}
+	atomic.AddInt64(&r.current, -1)
 }
 
 func (r *resource) Max() uint64 {
-	return r.max
+	return runtime.GetInt(r.runtimeKey, r.max)
 }
@@ -23,25 +23,32 @@ import (
 
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/types"
+	"mosn.io/mosn/pkg/utils"
 )
 
 type TCPDialSessionFactory struct{}
 
 func (f *TCPDialSessionFactory) NewSession(cfg map[string]interface{}, host types.Host) types.HealthCheckSession {
+	network, addr := "tcp", host.AddressString()
+	if unixAddr, ok := utils.ParseUnixAddr(addr); ok {
+		network, addr = unixAddr.Network(), unixAddr.Name
+	}
 	return &TCPDialSession{
-		addr: host.AddressString(),
+		network: network,
+		addr:    addr,
 	}
 }
 
 type TCPDialSession struct {
-	addr string
+	network string
+	addr    string
 }
 
 func (s *TCPDialSession) CheckHealth() bool {
 	// default dial timeout, maybe already timeout by checker
-	conn, err := net.DialTimeout("tcp", s.addr, 30*time.Second)
+	conn, err := net.DialTimeout(s.network, s.addr, 30*time.Second)
 	if err != nil {
-		log.DefaultLogger.Infof("[upstream] [health check] [tcpdial session] dial tcp for host %s error: %v", s.addr, err)
+		log.DefaultLogger.Infof("[upstream] [health check] [tcpdial session] dial %s for host %s error: %v", s.network, s.addr, err)
 		return false
 	}
 	conn.Close()
@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// UnixAddrPrefix is the prefix of the unix domain socket addresses, such as
+// unix:///var/run/mosn.sock, or unix://@mosn in the abstract namespace
+const UnixAddrPrefix = "unix://"
+
+// ParseUnixAddr returns the unix domain socket address if the address starts with unix://
+func ParseUnixAddr(address string) (*net.UnixAddr, bool) {
+	if !strings.HasPrefix(address, UnixAddrPrefix) {
+		return nil, false
+	}
+	return &net.UnixAddr{
+		Name: strings.TrimPrefix(address, UnixAddrPrefix),
+		Net:  "unix",
+	}, true
+}
+
+// IsAbstractUnixAddr returns true if the unix domain socket is in the abstract namespace,
+// which has no file in the file system
+func IsAbstractUnixAddr(addr *net.UnixAddr) bool {
+	return strings.HasPrefix(addr.Name, "@")
+}
+
+// ParseFileMode parses an octal file mode, such as 0660
+func ParseFileMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > uint64(os.ModePerm) {
+		return 0, fmt.Errorf("invalid file mode %s", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// LookupOwner parses the owner "user" or "user:group", the user and the group can be names or ids.
+// The gid is -1 if the group is not set, so that it is not changed by os.Chown
+func LookupOwner(owner string) (uid, gid int, err error) {
+	name, group := owner, ""
+	if i := strings.IndexByte(owner, ':'); i >= 0 {
+		name, group = owner[:i], owner[i+1:]
+	}
+	if uid, err = strconv.Atoi(name); err != nil {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return 0, 0, err
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return 0, 0, err
+		}
+	}
+	gid = -1
+	if group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, err
+			}
+			if gid, err = strconv.Atoi(g.Gid); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseUnixAddr(t *testing.T) {
+	for _, tc := range []struct {
+		address  string
+		name     string
+		unix     bool
+		abstract bool
+	}{
+		{"unix:///var/run/mosn.sock", "/var/run/mosn.sock", true, false},
+		{"unix://@mosn", "@mosn", true, true},
+		{"127.0.0.1:2045", "", false, false},
+	} {
+		addr, ok := ParseUnixAddr(tc.address)
+		if ok != tc.unix {
+			t.Fatalf("%s: expected unix %v", tc.address, tc.unix)
+		}
+		if !ok {
+			continue
+		}
+		if addr.Name != tc.name || addr.Network() != "unix" || IsAbstractUnixAddr(addr) != tc.abstract {
+			t.Errorf("%s: unexpected address %+v", tc.address, addr)
+		}
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	if mode, err := ParseFileMode("0660"); err != nil || mode != os.FileMode(0660) {
+		t.Errorf("parse file mode got %v, %v", mode, err)
+	}
+	for _, mode := range []string{"", "rw", "0980", "17777"} {
+		if _, err := ParseFileMode(mode); err == nil {
+			t.Errorf("invalid file mode %s is parsed", mode)
+		}
+	}
+}
+
+func TestLookupOwner(t *testing.T) {
+	for _, tc := range []struct {
+		owner    string
+		uid, gid int
+	}{
+		{"0", 0, -1},
+		{"0:0", 0, 0},
+		{"root:root", 0, 0},
+	} {
+		uid, gid, err := LookupOwner(tc.owner)
+		if err != nil || uid != tc.uid || gid != tc.gid {
+			t.Errorf("lookup owner %s got %d:%d, %v", tc.owner, uid, gid, err)
+		}
+	}
+	if _, _, err := LookupOwner("mosn-not-exists"); err == nil {
+		t.Error("unknown user is found")
+	}
+}
@@ -53,6 +53,26 @@ type Variable interface {
 	Setter() SetterFunc
 }
 
+// ValueType is the type of the variable value. The value is always got as a string,
+// the type helps the structured outputs such as the json access log to render the value.
+type ValueType uint8
+
+const (
+	// ValueTypeString is the default type
+	ValueTypeString ValueType = iota
+	// ValueTypeNumber is a decimal number, such as the bytes and the response code
+	ValueTypeNumber
+	// ValueTypeDuration is a time.Duration string, such as 1.5ms
+	ValueTypeDuration
+	// ValueTypeBool is true or false
+	ValueTypeBool
+)
+
+// Typed is implemented by the variables whose value is not a plain string
+type Typed interface {
+	ValueType() ValueType
+}
+
 // IndexedValue used to store result value
 type IndexedValue struct {
 	Valid       bool
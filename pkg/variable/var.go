@@ -86,3 +86,37 @@ func NewIndexedVariable(name string, data interface{}, getter GetterFunc, setter
 		},
 	}
 }
+
+// variable.Variable
+// variable.Typed
+type TypedVariable struct {
+	BasicVariable
+
+	valueType ValueType
+}
+
+func (tv *TypedVariable) ValueType() ValueType {
+	return tv.valueType
+}
+
+// NewTypedVariable creates a variable whose value is in the value type
+func NewTypedVariable(name string, data interface{}, getter GetterFunc, setter SetterFunc, flags uint32, valueType ValueType) Variable {
+	return &TypedVariable{
+		BasicVariable: BasicVariable{
+			getter: getter,
+			setter: setter,
+			name:   name,
+			data:   data,
+			flags:  flags,
+		},
+		valueType: valueType,
+	}
+}
+
+// GetValueType returns the value type of the variable, ValueTypeString is returned if the variable is not typed
+func GetValueType(v Variable) ValueType {
+	if typed, ok := v.(Typed); ok {
+		return typed.ValueType()
+	}
+	return ValueTypeString
+}
@@ -45,6 +45,7 @@ import (
 	"mosn.io/mosn/pkg/log"
 	"mosn.io/mosn/pkg/protocol"
 	"mosn.io/mosn/pkg/router"
+	"mosn.io/mosn/pkg/utils"
 	payloadlimit "mosn.io/mosn/pkg/xds/model/filter/http/payloadlimit/v2"
 	xdsxproxy "mosn.io/mosn/pkg/xds/model/filter/network/x_proxy/v2"
 	"mosn.io/mosn/pkg/xds/v2/rds"
@@ -80,11 +81,12 @@ func ConvertListenerConfig(xdsListener *xdsapi.Listener) *v2.Listener {
 
 	listenerConfig := &v2.Listener{
 		ListenerConfig: v2.ListenerConfig{
-			Name:           xdsListener.GetName(),
-			BindToPort:     convertBindToPort(xdsListener.GetDeprecatedV1()),
-			Inspector:      true,
-			UseOriginalDst: xdsListener.GetUseOriginalDst().GetValue(),
-			AccessLogs:     convertAccessLogs(xdsListener),
+			Name:             xdsListener.GetName(),
+			BindToPort:       convertBindToPort(xdsListener.GetDeprecatedV1()),
+			Inspector:        true,
+			UseOriginalDst:   xdsListener.GetUseOriginalDst().GetValue(),
+			AccessLogs:       convertAccessLogs(xdsListener),
+			UseProxyProtocol: convertUseProxyProtocol(xdsListener),
 		},
 		Addr: convertAddress(&xdsListener.Address),
 		PerConnBufferLimitBytes: xdsListener.GetPerConnectionBufferLimitBytes().GetValue(),
@@ -106,6 +108,22 @@ func ConvertListenerConfig(xdsListener *xdsapi.Listener) *v2.Listener {
 	return listenerConfig
 }
 
+// convertUseProxyProtocol returns true if the listener has the PROXY protocol listener filter,
+// or any filter chain sets the use_proxy_proto
+func convertUseProxyProtocol(xdsListener *xdsapi.Listener) bool {
+	for _, lf := range xdsListener.GetListenerFilters() {
+		if lf.GetName() == xdsutil.ProxyProtocol {
+			return true
+		}
+	}
+	for _, fc := range xdsListener.GetFilterChains() {
+		if fc.GetUseProxyProto().GetValue() {
+			return true
+		}
+	}
+	return false
+}
+
 func ConvertClustersConfig(xdsClusters []*xdsapi.Cluster) []*v2.Cluster {
 	if xdsClusters == nil {
 		return nil
@@ -151,7 +169,7 @@ func ConvertEndpointsConfig(xdsEndpoint *xdsendpoint.LocalityLbEndpoints) []v2.H
 			}
 
 		} else if xdsAddress, ok := xdsHost.GetEndpoint().GetAddress().GetAddress().(*xdscore.Address_Pipe); ok {
-			address = xdsAddress.Pipe.GetPath()
+			address = utils.UnixAddrPrefix + xdsAddress.Pipe.GetPath()
 		} else {
 			log.DefaultLogger.Warnf("unsupported address type")
 			continue
@@ -918,8 +936,10 @@ func convertAddress(xdsAddress *xdscore.Address) net.Addr {
 			log.DefaultLogger.Warnf("only port value supported")
 			return nil
 		}
+	} else if pipe, ok := xdsAddress.GetAddress().(*xdscore.Address_Pipe); ok {
+		return &net.UnixAddr{Name: pipe.Pipe.GetPath(), Net: "unix"}
 	} else {
-		log.DefaultLogger.Errorf("only SocketAddress supported")
+		log.DefaultLogger.Errorf("only SocketAddress and Pipe supported")
 		return nil
 	}
 
@@ -940,6 +960,7 @@ func convertClusterType(xdsClusterType xdsapi.Cluster_DiscoveryType) v2.ClusterT
 	case xdsapi.Cluster_EDS:
 		return v2.EDS_CLUSTER
 	case xdsapi.Cluster_ORIGINAL_DST:
+		return v2.ORIGINALDST_CLUSTER
 	}
 	//log.DefaultLogger.Fatalf("unsupported cluster type: %s, exchange to SIMPLE_CLUSTER", xdsClusterType.String())
 	return v2.SIMPLE_CLUSTER
@@ -1072,9 +1093,13 @@ func convertClusterHosts(xdsHosts []*xdscore.Address) []v2.Host {
 	}
 	hostsWithMetaData := make([]v2.Host, 0, len(xdsHosts))
 	for _, xdsHost := range xdsHosts {
+		address := convertAddress(xdsHost).String()
+		if xdsHost.GetPipe() != nil {
+			address = utils.UnixAddrPrefix + address
+		}
 		hostWithMetaData := v2.Host{
 			HostConfig: v2.HostConfig{
-				Address: convertAddress(xdsHost).String(),
+				Address: address,
 			},
 		}
 		hostsWithMetaData = append(hostsWithMetaData, hostWithMetaData)
@@ -94,6 +94,30 @@ func Test_convertEndpointsConfig(t *testing.T) {
 	}
 }
 
+func Test_convertPipeAddress(t *testing.T) {
+	pipe := &xdscore.Address{
+		Address: &xdscore.Address_Pipe{
+			Pipe: &xdscore.Pipe{Path: "/var/run/mosn.sock"},
+		},
+	}
+	if addr := convertAddress(pipe); addr == nil || addr.Network() != "unix" || addr.String() != "/var/run/mosn.sock" {
+		t.Errorf("convert pipe address unexpected: %v", addr)
+	}
+	if hosts := convertClusterHosts([]*xdscore.Address{pipe}); len(hosts) != 1 || hosts[0].Address != "unix:///var/run/mosn.sock" {
+		t.Errorf("convert pipe host unexpected: %v", hosts)
+	}
+	endpoints := &xdsendpoint.LocalityLbEndpoints{
+		LbEndpoints: []xdsendpoint.LbEndpoint{{
+			HostIdentifier: &xdsendpoint.LbEndpoint_Endpoint{
+				Endpoint: &xdsendpoint.Endpoint{Address: pipe},
+			},
+		}},
+	}
+	if hosts := ConvertEndpointsConfig(endpoints); len(hosts) != 1 || hosts[0].Address != "unix:///var/run/mosn.sock" {
+		t.Errorf("convert pipe endpoint unexpected: %v", hosts)
+	}
+}
+
 func Test_convertHeaders(t *testing.T) {
 	type args struct {
 		xdsHeaders []*xdsroute.HeaderMatcher
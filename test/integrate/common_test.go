@@ -102,6 +102,10 @@ func TestXprotocol(t *testing.T) {
 			TestCase:    NewTestCase(t, protocol.Xprotocol, protocol.Xprotocol, util.NewXProtocolServer(t, appaddr, util.XExample)),
 			subProtocol: util.XExample,
 		},
+		{
+			TestCase:    NewTestCase(t, protocol.Xprotocol, protocol.Xprotocol, util.NewXProtocolServer(t, appaddr, util.XThrift)),
+			subProtocol: util.XThrift,
+		},
 	}
 	for i, tc := range testCases {
 		t.Logf("start case #%d\n", i)
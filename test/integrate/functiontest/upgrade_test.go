@@ -0,0 +1,92 @@
+package functiontest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"mosn.io/mosn/pkg/protocol"
+	"mosn.io/mosn/test/util"
+)
+
+// UpgradeHTTPHandler switches the connection to an echo protocol for the upgrade requests
+type UpgradeHTTPHandler struct{}
+
+func (h *UpgradeHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") != "echo" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	conn, brw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+	// the data sent right after the upgrade response
+	brw.WriteString("welcome")
+	brw.Flush()
+	io.Copy(conn, brw)
+}
+
+func upgradeEcho(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("GET /echo HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")); err != nil {
+		return err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != "echo" {
+		return fmt.Errorf("unexpected upgrade response: %d %v", resp.StatusCode, resp.Header)
+	}
+	expected := "welcome"
+	for i := 0; i < 3; i++ {
+		msg := fmt.Sprintf("message-%d", i)
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			return err
+		}
+		expected += msg
+	}
+	buf := make([]byte, len(expected))
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return err
+	}
+	if string(buf) != expected {
+		return fmt.Errorf("expected %s, but got %s", expected, buf)
+	}
+	return nil
+}
+
+func TestHTTP1Upgrade(t *testing.T) {
+	c := NewHTTPCase(t, protocol.HTTP1, protocol.HTTP1, util.NewHTTPServer(t, &UpgradeHTTPHandler{}))
+	c.StartProxy()
+	defer c.FinishCase()
+
+	// tunnels should not affect each other
+	for i := 0; i < 2; i++ {
+		if err := upgradeEcho(c.ClientMeshAddr); err != nil {
+			t.Fatalf("upgrade #%d failed: %v", i, err)
+		}
+	}
+	// the normal requests still work
+	resp, err := http.Get(fmt.Sprintf("http://%s/", c.ClientMeshAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"mosn.io/mosn/pkg/network"
 	"mosn.io/mosn/pkg/protocol/rpc/xprotocol"
 	"mosn.io/mosn/pkg/protocol/rpc/xprotocol/example"
+	"mosn.io/mosn/pkg/protocol/rpc/xprotocol/thrift"
 	"mosn.io/mosn/pkg/types"
 )
 
@@ -29,8 +30,15 @@ type XProtocolClient struct {
 // support SubProtocol
 const (
 	XExample = "rpc-example"
+	XThrift  = "thrift"
 )
 
+// thriftEchoCall is a framed binary thrift call of method echo of service Echo, with an empty args struct
+var thriftEchoCall = []byte{0, 0, 0, 22, 0x80, 0x01, 0, thrift.THRIFT_MESSAGE_CALL, 0, 0, 0, 9, 'E', 'c', 'h', 'o', ':', 'e', 'c', 'h', 'o', 0, 0, 0, 0, 0}
+
+// thriftEchoReply is the reply of thriftEchoCall, with an empty result struct
+var thriftEchoReply = []byte{0, 0, 0, 17, 0x80, 0x01, 0, thrift.THRIFT_MESSAGE_REPLY, 0, 0, 0, 4, 'e', 'c', 'h', 'o', 0, 0, 0, 0, 0}
+
 func NewXClient(t *testing.T, id string, subproto string) *XProtocolClient {
 	return &XProtocolClient{
 		t:           t,
@@ -62,6 +70,9 @@ func (c *XProtocolClient) RequestAndWaitReponse() error {
 		req = make([]byte, 16)
 		data := []byte{14, 1, 0, 8, 0, 0, 3, 0}
 		copy(req, data)
+	case XThrift:
+		req = make([]byte, len(thriftEchoCall))
+		copy(req, thriftEchoCall)
 	default:
 		return fmt.Errorf("unsupport sub protocol")
 	}
@@ -108,6 +119,8 @@ func NewXProtocolServer(t *testing.T, addr string, subproto string) UpstreamServ
 	switch subproto {
 	case XExample:
 		s.UpstreamServer = NewUpstreamServer(t, addr, s.ServeXExample)
+	case XThrift:
+		s.UpstreamServer = NewUpstreamServer(t, addr, s.ServeXThrift)
 	default:
 		t.Errorf("unsupport sub protocol")
 		return nil
@@ -129,3 +142,19 @@ func (s *XProtocolServer) ServeXExample(t *testing.T, conn net.Conn) {
 	// can reuse
 	ServeSofaRPC(t, conn, response)
 }
+
+func (s *XProtocolServer) ServeXThrift(t *testing.T, conn net.Conn) {
+	response := func(iobuf types.IoBuffer) ([]byte, bool) {
+		codec := thrift.NewRPCThrift()
+		frames := codec.SplitFrame(iobuf.Bytes())
+		if len(frames) == 0 {
+			return nil, false
+		}
+		streamID := codec.GetStreamID(frames[0])
+		iobuf.Drain(len(frames[0]))
+		resp := make([]byte, len(thriftEchoReply))
+		copy(resp, thriftEchoReply)
+		return codec.SetStreamID(resp, streamID), true
+	}
+	ServeSofaRPC(t, conn, response)
+}